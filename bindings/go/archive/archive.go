@@ -0,0 +1,107 @@
+// Package archive opens GZDoom resource archives (.pk3 and .wad files, or
+// plain directories) as file systems that the project loader can read
+// ZScript lumps from without extracting them.
+package archive
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/project"
+)
+
+// ErrUnknownFormat is returned for files that are neither zip nor WAD
+// archives.
+var ErrUnknownFormat = errors.New("archive: unknown archive format")
+
+// Archive is an open resource archive.
+type Archive struct {
+	fs.FS
+	// Path is the path the archive was opened from.
+	Path   string
+	closer io.Closer
+}
+
+// Close closes the underlying file, if any.
+func (a *Archive) Close() error {
+	if a.closer == nil {
+		return nil
+	}
+	return a.closer.Close()
+}
+
+// Open opens the archive at path. Directories are opened as-is; files are
+// identified by their contents, so .pk3, .ipk3, .zip, .wad, and .iwad files
+// all work regardless of extension.
+func Open(path string) (*Archive, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		return &Archive{FS: os.DirFS(path), Path: path}, nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	fsys, err := New(f, info.Size())
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &Archive{FS: fsys, Path: path, closer: f}, nil
+}
+
+// New reads an archive of the given size from r, identifying its format
+// from its first bytes.
+func New(r io.ReaderAt, size int64) (fs.FS, error) {
+	var magic [4]byte
+	if _, err := r.ReadAt(magic[:], 0); err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, ErrUnknownFormat
+		}
+		return nil, err
+	}
+	switch {
+	case bytes.Equal(magic[:], []byte("PK\x03\x04")), bytes.Equal(magic[:], []byte("PK\x05\x06")):
+		return zip.NewReader(r, size)
+	case bytes.Equal(magic[:], []byte("IWAD")), bytes.Equal(magic[:], []byte("PWAD")):
+		return NewWAD(r, size)
+	}
+	return nil, ErrUnknownFormat
+}
+
+// LoadProject opens the archive at path and loads the ZScript project
+// rooted at its ZSCRIPT lumps. The archive is closed before returning,
+// since the project holds all the source it needs.
+func LoadProject(ctx context.Context, path string) (*project.Project, error) {
+	a, err := Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer a.Close()
+
+	var roots []string
+	if w, ok := a.FS.(*WAD); ok {
+		// The lumps are loaded in directory order, as GZDoom loads them.
+		for _, name := range w.Names() {
+			if project.IsRoot(name) {
+				roots = append(roots, name)
+			}
+		}
+	} else if roots, err = project.FindRoots(a); err != nil {
+		return nil, err
+	}
+	if len(roots) == 0 {
+		return nil, fmt.Errorf("archive: no zscript lump in %s", path)
+	}
+	return project.Load(ctx, a, roots...)
+}
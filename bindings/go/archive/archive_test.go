@@ -0,0 +1,138 @@
+package archive_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/archive"
+)
+
+// buildWAD returns a WAD of the given lumps, names alternating with their
+// contents, in order.
+func buildWAD(lumps ...string) []byte {
+	var data bytes.Buffer
+	data.Write(make([]byte, 12))
+	var dir bytes.Buffer
+	for i := 0; i < len(lumps); i += 2 {
+		name, content := lumps[i], lumps[i+1]
+		offset := data.Len()
+		data.WriteString(content)
+		binary.Write(&dir, binary.LittleEndian, int32(offset))
+		binary.Write(&dir, binary.LittleEndian, int32(len(content)))
+		var n [8]byte
+		copy(n[:], name)
+		dir.Write(n[:])
+	}
+	out := data.Bytes()
+	copy(out, "PWAD")
+	binary.LittleEndian.PutUint32(out[4:], uint32(len(lumps)/2))
+	binary.LittleEndian.PutUint32(out[8:], uint32(len(out)))
+	return append(out, dir.Bytes()...)
+}
+
+func buildZip(t *testing.T, files map[string]string) []byte {
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	for name, content := range files {
+		f, err := w.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		f.Write([]byte(content))
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestWAD(t *testing.T) {
+	data := buildWAD("ZSCRIPT", `#include "zsactors"`, "ZSACTORS", "class A : Actor {}")
+
+	w, err := archive.NewWAD(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := fstest.TestFS(w, "ZSCRIPT", "ZSACTORS"); err != nil {
+		t.Fatal(err)
+	}
+	if len(w.Lumps()) != 2 {
+		t.Errorf("Lumps() = %v", w.Lumps())
+	}
+}
+
+func TestWADDuplicateLumps(t *testing.T) {
+	data := buildWAD("ZSCRIPT", "class A {}", "MAP01", "", "zscript", "class B {}", "ZSCRIPT", "class C {}")
+	w, err := archive.NewWAD(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := fstest.TestFS(w, "ZSCRIPT#1", "zscript#2", "ZSCRIPT", "MAP01"); err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(w.Names(), " "); got != "ZSCRIPT#1 MAP01 zscript#2 ZSCRIPT" {
+		t.Errorf("Names() = %s", got)
+	}
+	// Opening the name opens the last lump of that name.
+	if data, err := fs.ReadFile(w, "zscript"); err != nil || string(data) != "class C {}" {
+		t.Errorf("ReadFile(zscript) = %q, %v", data, err)
+	}
+
+	path := filepath.Join(t.TempDir(), "mod.wad")
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	p, err := archive.LoadProject(context.Background(), path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+	var paths []string
+	for _, f := range p.Files {
+		paths = append(paths, f.Path)
+	}
+	if got := strings.Join(paths, " "); got != "ZSCRIPT#1 zscript#2 ZSCRIPT" {
+		t.Errorf("files = %s", got)
+	}
+}
+
+func TestLoadProject(t *testing.T) {
+	dir := t.TempDir()
+	archives := map[string][]byte{
+		"mod.pk3": buildZip(t, map[string]string{
+			"zscript.zs":        `#include "zscript/actors.zs"`,
+			"zscript/actors.zs": "class A : Actor {}",
+		}),
+		"mod.wad": buildWAD("ZSCRIPT", `#include "zsactors"`, "ZSACTORS", "class A : Actor {}"),
+	}
+
+	for name, data := range archives {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		p, err := archive.LoadProject(context.Background(), path)
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		if len(p.Files) != 2 || len(p.Diagnostics) != 0 {
+			t.Errorf("%s: %d files, diagnostics %v", name, len(p.Files), p.Diagnostics)
+		}
+		p.Close()
+	}
+}
+
+func TestUnknownFormat(t *testing.T) {
+	if _, err := archive.New(bytes.NewReader([]byte("not an archive")), 14); err != archive.ErrUnknownFormat {
+		t.Errorf("err = %v", err)
+	}
+}
@@ -0,0 +1,183 @@
+package archive
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Lump is an entry in a WAD directory.
+type Lump struct {
+	Name   string
+	Offset int64
+	Size   int64
+}
+
+// WAD is a read-only file system over the lumps of a WAD file. WADs have
+// a flat namespace, so every lump is a file at the root. When several
+// lumps share a name, opening the name opens the last of them, as GZDoom
+// lookups do, and the others are files of the name followed by # and
+// their number among them, counting from 1: a WAD with two ZSCRIPT lumps
+// has the files ZSCRIPT#1, the first, and ZSCRIPT, the second. GZDoom
+// loads every ZSCRIPT lump, so LoadProject takes all of them as roots.
+type WAD struct {
+	r     io.ReaderAt
+	lumps []Lump
+	// names are the names of the files of the lumps.
+	names []string
+	index map[string]int
+}
+
+var errCorruptWAD = errors.New("archive: corrupt WAD directory")
+
+// NewWAD reads the directory of a WAD file of the given size from r.
+func NewWAD(r io.ReaderAt, size int64) (*WAD, error) {
+	var header [12]byte
+	if _, err := r.ReadAt(header[:], 0); err != nil {
+		return nil, err
+	}
+	count := int64(int32(binary.LittleEndian.Uint32(header[4:8])))
+	offset := int64(int32(binary.LittleEndian.Uint32(header[8:12])))
+	if count < 0 || offset < 0 || offset+count*16 > size {
+		return nil, errCorruptWAD
+	}
+
+	dir := make([]byte, count*16)
+	if _, err := r.ReadAt(dir, offset); err != nil {
+		return nil, err
+	}
+
+	w := &WAD{r: r, index: map[string]int{}}
+	counts := map[string]int{}
+	for i := int64(0); i < count; i++ {
+		entry := dir[i*16 : i*16+16]
+		lump := Lump{
+			Offset: int64(int32(binary.LittleEndian.Uint32(entry[0:4]))),
+			Size:   int64(int32(binary.LittleEndian.Uint32(entry[4:8]))),
+			Name:   string(bytes.TrimRight(entry[8:16], "\x00")),
+		}
+		if lump.Offset < 0 || lump.Size < 0 || lump.Offset+lump.Size > size {
+			return nil, fmt.Errorf("%w: lump %q out of bounds", errCorruptWAD, lump.Name)
+		}
+		counts[strings.ToUpper(lump.Name)]++
+		w.lumps = append(w.lumps, lump)
+	}
+	seen := map[string]int{}
+	for i, lump := range w.lumps {
+		key := strings.ToUpper(lump.Name)
+		seen[key]++
+		name := lump.Name
+		if seen[key] < counts[key] {
+			name += "#" + strconv.Itoa(seen[key])
+		}
+		w.names = append(w.names, name)
+		w.index[strings.ToUpper(name)] = i
+	}
+	return w, nil
+}
+
+// Lumps returns every lump in directory order.
+func (w *WAD) Lumps() []Lump {
+	return w.lumps
+}
+
+// Names returns the names of the files of the lumps, in directory order.
+func (w *WAD) Names() []string {
+	return w.names
+}
+
+// Open opens the named lump. Names are matched case-insensitively.
+func (w *WAD) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+	if name == "." {
+		return &wadDir{w: w}, nil
+	}
+	i, ok := w.index[strings.ToUpper(name)]
+	if !ok {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	lump := w.lumps[i]
+	return &wadFile{info: lumpInfo{lump, w.names[i]}, SectionReader: io.NewSectionReader(w.r, lump.Offset, lump.Size)}, nil
+}
+
+// ReadDir lists the lumps as the entries of the root directory.
+func (w *WAD) ReadDir(name string) ([]fs.DirEntry, error) {
+	if name != "." {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrNotExist}
+	}
+	entries := make([]fs.DirEntry, 0, len(w.lumps))
+	for i, lump := range w.lumps {
+		entries = append(entries, fs.FileInfoToDirEntry(lumpInfo{lump, w.names[i]}))
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+	return entries, nil
+}
+
+type lumpInfo struct {
+	lump Lump
+	name string
+}
+
+func (i lumpInfo) Name() string       { return i.name }
+func (i lumpInfo) Size() int64        { return i.lump.Size }
+func (i lumpInfo) Mode() fs.FileMode  { return 0o444 }
+func (i lumpInfo) ModTime() time.Time { return time.Time{} }
+func (i lumpInfo) IsDir() bool        { return false }
+func (i lumpInfo) Sys() any           { return nil }
+
+type wadFile struct {
+	info lumpInfo
+	*io.SectionReader
+}
+
+func (f *wadFile) Stat() (fs.FileInfo, error) { return f.info, nil }
+func (f *wadFile) Close() error               { return nil }
+
+type wadDir struct {
+	w       *WAD
+	entries []fs.DirEntry
+	read    bool
+}
+
+func (d *wadDir) Stat() (fs.FileInfo, error) { return rootInfo{}, nil }
+func (d *wadDir) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: ".", Err: fs.ErrInvalid}
+}
+func (d *wadDir) Close() error { return nil }
+
+func (d *wadDir) ReadDir(n int) ([]fs.DirEntry, error) {
+	if !d.read {
+		d.entries, _ = d.w.ReadDir(".")
+		d.read = true
+	}
+	if n <= 0 {
+		entries := d.entries
+		d.entries = nil
+		return entries, nil
+	}
+	if len(d.entries) == 0 {
+		return nil, io.EOF
+	}
+	n = min(n, len(d.entries))
+	entries := d.entries[:n]
+	d.entries = d.entries[n:]
+	return entries, nil
+}
+
+type rootInfo struct{}
+
+func (rootInfo) Name() string       { return "." }
+func (rootInfo) Size() int64        { return 0 }
+func (rootInfo) Mode() fs.FileMode  { return fs.ModeDir | 0o555 }
+func (rootInfo) ModTime() time.Time { return time.Time{} }
+func (rootInfo) IsDir() bool        { return true }
+func (rootInfo) Sys() any           { return nil }
@@ -0,0 +1,226 @@
+// Package assets collects the sprites, sounds and textures that ZScript
+// code refers to by name, so that references to lumps missing from a mod
+// can be found without loading it in GZDoom.
+package assets
+
+import (
+	"sort"
+	"strings"
+
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+
+	zscript "github.com/jlcrochet/tree-sitter-zscript/bindings/go"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/project"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/zscriptast"
+)
+
+// Kind identifies the type of asset a reference names.
+type Kind int
+
+const (
+	KindSprite Kind = iota + 1
+	KindSound
+	KindTexture
+)
+
+var kindNames = [...]string{
+	KindSprite:  "sprite",
+	KindSound:   "sound",
+	KindTexture: "texture",
+}
+
+func (k Kind) String() string {
+	if k > 0 && int(k) < len(kindNames) {
+		return kindNames[k]
+	}
+	return "unknown"
+}
+
+// Reference is a use of an asset name.
+type Reference struct {
+	Kind Kind
+	// Name is the asset name as written. Sprites are the four-character
+	// sprite prefix, without frame letters.
+	Name string
+	Path string
+	// Range spans the name in the source: the string literal, or the
+	// sprite and frames of a state line.
+	Range tree_sitter.Range
+}
+
+// parameter identifies an argument of a function that names an asset, by
+// position and by name for named arguments.
+type parameter struct {
+	kind  Kind
+	index int
+	name  string
+}
+
+// functions maps the lowercased names of engine functions to the
+// parameter that names an asset.
+var functions = map[string]parameter{
+	"a_startsound":        {KindSound, 0, "whattoplay"},
+	"a_playsound":         {KindSound, 0, "whattoplay"},
+	"a_playsoundex":       {KindSound, 0, "whattoplay"},
+	"s_startsound":        {KindSound, 0, "sound_id"},
+	"s_getlength":         {KindSound, 0, "sound_id"},
+	"isactorplayingsound": {KindSound, 1, "snd"},
+	"checkfortexture":     {KindTexture, 0, "name"},
+	"drawimage":           {KindTexture, 0, "texture"},
+	"getspriteindex":      {KindSprite, 0, "sprt"},
+}
+
+// properties maps the lowercased names of actor properties that name
+// assets to the kind of asset.
+var properties = map[string]Kind{
+	"seesound":              KindSound,
+	"attacksound":           KindSound,
+	"painsound":             KindSound,
+	"deathsound":            KindSound,
+	"activesound":           KindSound,
+	"howlsound":             KindSound,
+	"bouncesound":           KindSound,
+	"wallbouncesound":       KindSound,
+	"crushpainsound":        KindSound,
+	"ripsound":              KindSound,
+	"inventory.pickupsound": KindSound,
+	"inventory.usesound":    KindSound,
+	"weapon.upsound":        KindSound,
+	"weapon.readysound":     KindSound,
+	"inventory.icon":        KindTexture,
+	"inventory.althudicon":  KindTexture,
+	"player.scoreicon":      KindTexture,
+	"player.crouchsprite":   KindSprite,
+}
+
+// Extract returns the asset references in tree, in source order.
+func Extract(tree *zscript.Tree) []Reference {
+	e := extractor{tree: tree}
+	var v zscript.Visitor
+	v.On(zscript.NodeStateLine, func(node *tree_sitter.Node) zscript.WalkAction {
+		e.stateLine(node)
+		return zscript.WalkContinue
+	})
+	v.On(zscript.NodeCallExpression, func(node *tree_sitter.Node) zscript.WalkAction {
+		fn := node.ChildByFieldName(zscript.FieldFunction)
+		if fn != nil && fn.Kind() == zscript.NodeFieldExpression {
+			fn = fn.ChildByFieldName(zscript.FieldField)
+		}
+		e.call(fn, node.ChildByFieldName(zscript.FieldArguments))
+		return zscript.WalkContinue
+	})
+	v.On(zscript.NodeStateActionCall, func(node *tree_sitter.Node) zscript.WalkAction {
+		e.call(node.ChildByFieldName(zscript.FieldFunction), node.ChildByFieldName(zscript.FieldArguments))
+		return zscript.WalkContinue
+	})
+	v.On(zscript.NodePropertyAssignment, func(node *tree_sitter.Node) zscript.WalkAction {
+		e.property(zscriptast.PropertyAssignment{Node: zscriptast.Wrap(node, tree.Source)})
+		return zscript.WalkSkipChildren
+	})
+	zscript.Walk(tree.RootNode(), &v)
+	return e.refs
+}
+
+// FromProject returns the asset references in every file of p, in file
+// order.
+func FromProject(p *project.Project) []Reference {
+	var refs []Reference
+	for _, f := range p.Files {
+		refs = append(refs, Extract(f.Tree)...)
+	}
+	return refs
+}
+
+// Names returns the distinct names of the references of the given kind,
+// sorted. Names are compared case-insensitively and returned uppercased,
+// the way GZDoom looks up lumps.
+func Names(refs []Reference, kind Kind) []string {
+	seen := map[string]bool{}
+	var names []string
+	for _, r := range refs {
+		name := strings.ToUpper(r.Name)
+		if r.Kind == kind && !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+type extractor struct {
+	tree *zscript.Tree
+	refs []Reference
+}
+
+func (e *extractor) add(kind Kind, name string, r tree_sitter.Range) {
+	if name == "" {
+		return
+	}
+	e.refs = append(e.refs, Reference{Kind: kind, Name: name, Path: e.tree.Path, Range: r})
+}
+
+func (e *extractor) stateLine(node *tree_sitter.Node) {
+	frames := node.ChildByFieldName(zscript.FieldSpriteFrames)
+	if frames == nil {
+		return
+	}
+	sprite, _, _ := strings.Cut(frames.Utf8Text(e.tree.Source), " ")
+	sprite = strings.Trim(sprite, `"`)
+	// "####" and "----" keep the previous sprite rather than naming one.
+	if sprite == "####" || sprite == "----" {
+		return
+	}
+	e.add(KindSprite, sprite, frames.Range())
+}
+
+func (e *extractor) call(fn, args *tree_sitter.Node) {
+	if fn == nil || args == nil {
+		return
+	}
+	param, ok := functions[strings.ToLower(fn.Utf8Text(e.tree.Source))]
+	if !ok {
+		return
+	}
+	cursor := args.Walk()
+	defer cursor.Close()
+	for i, arg := range args.NamedChildren(cursor) {
+		if arg.Kind() == zscript.NodeNamedArgument {
+			name := arg.ChildByFieldName(zscript.FieldName)
+			if name == nil || !strings.EqualFold(name.Utf8Text(e.tree.Source), param.name) {
+				continue
+			}
+			value := arg.ChildByFieldName(zscript.FieldValue)
+			if value == nil {
+				return
+			}
+			arg = *value
+		} else if i != param.index {
+			continue
+		}
+		e.literal(param.kind, &arg)
+		return
+	}
+}
+
+func (e *extractor) property(p zscriptast.PropertyAssignment) {
+	kind, ok := properties[strings.ToLower(strings.Join(strings.Fields(p.Name()), ""))]
+	if !ok {
+		return
+	}
+	for _, v := range p.Values() {
+		e.literal(kind, v.Raw)
+	}
+}
+
+// literal records a reference for a string or name literal. Other
+// expressions name assets only at run time.
+func (e *extractor) literal(kind Kind, node *tree_sitter.Node) {
+	switch node.Kind() {
+	case zscript.NodeStringLiteral, zscript.NodeNameLiteral:
+		text := node.Utf8Text(e.tree.Source)
+		if len(text) >= 2 {
+			e.add(kind, text[1:len(text)-1], node.Range())
+		}
+	}
+}
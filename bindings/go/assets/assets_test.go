@@ -0,0 +1,80 @@
+package assets_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	zscript "github.com/jlcrochet/tree-sitter-zscript/bindings/go"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/assets"
+)
+
+const source = `class Imp : Actor {
+	Default {
+		SeeSound "imp/sight";
+		Inventory.Icon "ARM1A0";
+		Obituary "%o was burned by an imp.";
+	}
+	void Attack(Sound snd) {
+		A_StartSound("imp/attack", CHAN_WEAPON);
+		S_StartSound(channel: CHAN_BODY, sound_id: "misc/chat");
+		A_StartSound(snd);
+		TexMan.CheckForTexture("WALL01", TexMan.Type_Any);
+	}
+	States {
+	Spawn:
+		TROO AB 10 A_StartSound("imp/active");
+		#### # 1;
+		Stop;
+	}
+}
+`
+
+func TestExtract(t *testing.T) {
+	tree, err := zscript.Parse(context.Background(), []byte(source))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tree.Close()
+	tree.Path = "imp.zs"
+
+	type ref struct {
+		Kind assets.Kind
+		Name string
+		Line uint
+	}
+	var got []ref
+	for _, r := range assets.Extract(tree) {
+		if r.Path != "imp.zs" {
+			t.Errorf("%s: Path = %q", r.Name, r.Path)
+		}
+		got = append(got, ref{r.Kind, r.Name, r.Range.StartPoint.Row + 1})
+	}
+	want := []ref{
+		{assets.KindSound, "imp/sight", 3},
+		{assets.KindTexture, "ARM1A0", 4},
+		{assets.KindSound, "imp/attack", 8},
+		{assets.KindSound, "misc/chat", 9},
+		{assets.KindTexture, "WALL01", 11},
+		{assets.KindSprite, "TROO", 15},
+		{assets.KindSound, "imp/active", 15},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Extract() =\n%v\nwant\n%v", got, want)
+	}
+}
+
+func TestNames(t *testing.T) {
+	refs := []assets.Reference{
+		{Kind: assets.KindSound, Name: "imp/sight"},
+		{Kind: assets.KindSprite, Name: "troo"},
+		{Kind: assets.KindSound, Name: "IMP/SIGHT"},
+		{Kind: assets.KindSound, Name: "imp/attack"},
+	}
+	if got, want := assets.Names(refs, assets.KindSound), []string{"IMP/ATTACK", "IMP/SIGHT"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Names(sound) = %q, want %q", got, want)
+	}
+	if got, want := assets.Names(refs, assets.KindSprite), []string{"TROO"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Names(sprite) = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,217 @@
+package tree_sitter_zscript_test
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+
+	tree_sitter_zscript "github.com/jlcrochet/tree-sitter-zscript/bindings/go"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/engine"
+)
+
+// baselinePath holds the throughput TestThroughput compares against. The
+// numbers depend on the machine; regenerate them with
+// ZSCRIPT_BENCH=update before comparing changes on another one.
+const baselinePath = "testdata/bench/baseline.txt"
+
+// fixture is a source file to benchmark.
+type fixture struct {
+	name   string
+	source []byte
+}
+
+// fixtures returns the files of testdata/bench, the engine declarations,
+// and all of them joined into one large file. The files of testdata/bench
+// were written for these benchmarks in the style of gameplay mods, since
+// the code of published mods is not ours to vendor; they stand in for it
+// and use the same constructs, not the same code.
+func fixtures(tb testing.TB) []fixture {
+	tb.Helper()
+	paths, err := filepath.Glob("testdata/bench/*.zs")
+	if err != nil || len(paths) == 0 {
+		tb.Fatalf("no fixtures: %v", err)
+	}
+	var result []fixture
+	var all bytes.Buffer
+	for _, path := range paths {
+		source, err := os.ReadFile(path)
+		if err != nil {
+			tb.Fatal(err)
+		}
+		result = append(result, fixture{strings.TrimSuffix(filepath.Base(path), ".zs"), source})
+		all.Write(source)
+	}
+	result = append(result, fixture{"engine", engine.Source()})
+	all.Write(engine.Source())
+	// The joined file repeats the classes, which the parser does not mind,
+	// to approach the size of the largest files of big mods.
+	large := bytes.Repeat(all.Bytes(), 4)
+	return append(result, fixture{"large", large})
+}
+
+func benchmarkParse(source []byte) func(b *testing.B) {
+	return func(b *testing.B) {
+		b.SetBytes(int64(len(source)))
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			tree, err := tree_sitter_zscript.Parse(context.Background(), source)
+			if err != nil {
+				b.Fatal(err)
+			}
+			tree.Close()
+		}
+	}
+}
+
+func benchmarkWalk(tree *tree_sitter_zscript.Tree) func(b *testing.B) {
+	return func(b *testing.B) {
+		b.SetBytes(int64(len(tree.Source)))
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			var v tree_sitter_zscript.Visitor
+			v.Enter = func(*tree_sitter.Node) tree_sitter_zscript.WalkAction { return tree_sitter_zscript.WalkContinue }
+			tree_sitter_zscript.Walk(tree.RootNode(), &v)
+		}
+	}
+}
+
+// benchmarks returns the benchmarks by name, and a function releasing
+// what they hold.
+func benchmarks(tb testing.TB) (map[string]func(*testing.B), func()) {
+	result := map[string]func(*testing.B){}
+	var trees []func()
+	for _, f := range fixtures(tb) {
+		result["Parse/"+f.name] = benchmarkParse(f.source)
+		tree, err := tree_sitter_zscript.Parse(context.Background(), f.source)
+		if err != nil {
+			tb.Fatal(err)
+		}
+		trees = append(trees, tree.Close)
+		result["Walk/"+f.name] = benchmarkWalk(tree)
+	}
+	return result, func() {
+		for _, close := range trees {
+			close()
+		}
+	}
+}
+
+func BenchmarkParse(b *testing.B) {
+	for _, f := range fixtures(b) {
+		b.Run(f.name, benchmarkParse(f.source))
+	}
+}
+
+func BenchmarkWalk(b *testing.B) {
+	for _, f := range fixtures(b) {
+		tree, err := tree_sitter_zscript.Parse(context.Background(), f.source)
+		if err != nil {
+			b.Fatal(err)
+		}
+		defer tree.Close()
+		b.Run(f.name, benchmarkWalk(tree))
+	}
+}
+
+// result is a line of the baseline file.
+type result struct {
+	mbPerSec float64
+	allocs   int64
+}
+
+func readBaseline(t *testing.T) map[string]result {
+	t.Helper()
+	f, err := os.Open(baselinePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	baseline := map[string]result{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 3 {
+			t.Fatalf("%s: bad line %q", baselinePath, line)
+		}
+		mb, err1 := strconv.ParseFloat(fields[1], 64)
+		allocs, err2 := strconv.ParseInt(fields[2], 10, 64)
+		if err1 != nil || err2 != nil {
+			t.Fatalf("%s: bad line %q", baselinePath, line)
+		}
+		baseline[fields[0]] = result{mb, allocs}
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return baseline
+}
+
+// TestThroughput runs the benchmarks and fails if one is more than 30%
+// slower than the baseline or allocates over 10% more. Timings are too
+// noisy for every test run, so it runs only with ZSCRIPT_BENCH=1;
+// ZSCRIPT_BENCH=update writes the baseline instead.
+func TestThroughput(t *testing.T) {
+	mode := os.Getenv("ZSCRIPT_BENCH")
+	if mode == "" {
+		t.Skip("set ZSCRIPT_BENCH=1 to compare throughput with the baseline")
+	}
+	benches, release := benchmarks(t)
+	defer release()
+	names := make([]string, 0, len(benches))
+	for name := range benches {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	got := map[string]result{}
+	for _, name := range names {
+		r := testing.Benchmark(benches[name])
+		mb := 0.0
+		if s := r.T.Seconds(); s > 0 {
+			mb = float64(r.Bytes) * float64(r.N) / 1e6 / s
+		}
+		got[name] = result{mb, r.AllocsPerOp()}
+	}
+
+	if mode == "update" {
+		var buf bytes.Buffer
+		buf.WriteString("# name MB/s allocs/op\n")
+		for _, name := range names {
+			fmt.Fprintf(&buf, "%s %.2f %d\n", name, got[name].mbPerSec, got[name].allocs)
+		}
+		if err := os.WriteFile(baselinePath, buf.Bytes(), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+
+	baseline := readBaseline(t)
+	for _, name := range names {
+		want, ok := baseline[name]
+		if !ok {
+			t.Errorf("%s: not in %s", name, baselinePath)
+			continue
+		}
+		g := got[name]
+		t.Logf("%s: %.2f MB/s (baseline %.2f), %d allocs/op (baseline %d)", name, g.mbPerSec, want.mbPerSec, g.allocs, want.allocs)
+		if g.mbPerSec < want.mbPerSec*0.7 {
+			t.Errorf("%s: %.2f MB/s, baseline %.2f", name, g.mbPerSec, want.mbPerSec)
+		}
+		if g.allocs > want.allocs+max(want.allocs/10, 2) {
+			t.Errorf("%s: %d allocs/op, baseline %d", name, g.allocs, want.allocs)
+		}
+	}
+}
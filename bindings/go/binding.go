@@ -1,5 +1,7 @@
 package tree_sitter_zscript
 
+//go:generate go run ./internal/genkinds -i ../../src/node-types.json -o kinds.go -v visit_kinds.go
+
 // #cgo CFLAGS: -std=c11 -fPIC
 // #include "../../src/parser.c"
 // #if __has_include("../../src/scanner.c")
@@ -13,3 +13,44 @@ func TestCanLoadGrammar(t *testing.T) {
 		t.Errorf("Error loading ZScript grammar")
 	}
 }
+
+func TestNodeKindsExist(t *testing.T) {
+	language := tree_sitter.NewLanguage(tree_sitter_zscript.Language())
+	for _, kind := range []string{
+		tree_sitter_zscript.NodeSourceFile,
+		tree_sitter_zscript.NodeClassDefinition,
+		tree_sitter_zscript.NodeStatesBlock,
+		tree_sitter_zscript.NodeTypeIdentifier,
+	} {
+		if language.IdForNodeKind(kind, true) == 0 {
+			t.Errorf("Unknown node kind %q", kind)
+		}
+	}
+	for _, field := range []string{
+		tree_sitter_zscript.FieldName,
+		tree_sitter_zscript.FieldParent,
+		tree_sitter_zscript.FieldBody,
+	} {
+		if language.FieldIdForName(field) == 0 {
+			t.Errorf("Unknown field %q", field)
+		}
+	}
+}
+
+func TestQueriesCompile(t *testing.T) {
+	language := tree_sitter.NewLanguage(tree_sitter_zscript.Language())
+	queries := map[string][]byte{
+		"highlights": tree_sitter_zscript.HighlightsQuery(),
+		"locals":     tree_sitter_zscript.LocalsQuery(),
+		"injections": tree_sitter_zscript.InjectionsQuery(),
+		"tags":       tree_sitter_zscript.TagsQuery(),
+	}
+	for name, source := range queries {
+		query, err := tree_sitter.NewQuery(language, string(source))
+		if err != nil {
+			t.Errorf("Error compiling %s query: %v", name, err)
+			continue
+		}
+		query.Close()
+	}
+}
@@ -0,0 +1,147 @@
+// Package cache stores the symbol tables, syntax diagnostics and syntax
+// trees of parsed files on disk, keyed by a hash of their content, so that
+// an unchanged file need not be parsed again: its declarations, outline
+// and layout are restored from the entry. The trees are stored as Syntax,
+// the kinds, ranges and structure of their nodes, in a few bytes a node.
+//
+// Entries are written atomically, so several processes may share a cache
+// directory. Entries that cannot be decoded are treated as missing.
+package cache
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/gob"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	zscript "github.com/jlcrochet/tree-sitter-zscript/bindings/go"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/symbols"
+)
+
+// format names the subdirectory entries are written to. It changes
+// whenever the encoding of an Entry, or what the parser and symbol
+// extraction produce for a given input, changes.
+const format = "v3"
+
+// Entry is what the cache stores for a file.
+type Entry struct {
+	Table       *symbols.Table
+	Diagnostics []zscript.Diagnostic
+	Syntax      *Syntax
+}
+
+// Cache is a cache directory.
+type Cache struct {
+	dir string
+}
+
+// DefaultDir returns the directory for the cache in the user's cache
+// directory.
+func DefaultDir() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "zscript"), nil
+}
+
+// Open opens the cache in dir, creating the directory if needed.
+func Open(dir string) (*Cache, error) {
+	dir = filepath.Join(dir, format)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("cache: %w", err)
+	}
+	return &Cache{dir: dir}, nil
+}
+
+// Key returns the key under which the entry for source is stored.
+func Key(source []byte) string {
+	sum := sha256.Sum256(source)
+	return hex.EncodeToString(sum[:])
+}
+
+func (c *Cache) path(key string) string {
+	return filepath.Join(c.dir, key[:2], key)
+}
+
+// Get returns the entry for source, if there is one.
+func (c *Cache) Get(source []byte) (*Entry, bool) {
+	data, err := os.ReadFile(c.path(Key(source)))
+	if err != nil {
+		return nil, false
+	}
+	var e Entry
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&e); err != nil || e.Table == nil || e.Syntax == nil {
+		return nil, false
+	}
+	return &e, true
+}
+
+// Put stores the entry for source.
+func (c *Cache) Put(source []byte, e *Entry) error {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(e); err != nil {
+		return fmt.Errorf("cache: %w", err)
+	}
+	name := c.path(Key(source))
+	if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+		return fmt.Errorf("cache: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(name), "tmp-*")
+	if err != nil {
+		return fmt.Errorf("cache: %w", err)
+	}
+	_, err = tmp.Write(buf.Bytes())
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), name)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("cache: %w", err)
+	}
+	return nil
+}
+
+// Load returns the entry for source, parsing it and storing the result if
+// it is not cached. The table's Path is set to path, which is not part of
+// the key. A failure to store the entry is not an error.
+func (c *Cache) Load(ctx context.Context, path string, source []byte) (*Entry, error) {
+	if e, ok := c.Get(source); ok {
+		e.Table.Path = path
+		return e, nil
+	}
+	tree, err := zscript.Parse(ctx, source)
+	if err != nil {
+		return nil, err
+	}
+	defer tree.Close()
+	tree.Path = path
+	e := &Entry{
+		Table:       symbols.Extract(tree),
+		Diagnostics: zscript.Diagnostics(tree.Tree, source),
+		Syntax:      NewSyntax(tree),
+	}
+	c.Put(source, e)
+	return e, nil
+}
+
+// Clear removes every entry.
+func (c *Cache) Clear() error {
+	entries, err := os.ReadDir(c.dir)
+	if err != nil {
+		return fmt.Errorf("cache: %w", err)
+	}
+	for _, entry := range entries {
+		if err := os.RemoveAll(filepath.Join(c.dir, entry.Name())); err != nil {
+			return fmt.Errorf("cache: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,190 @@
+package cache_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	zscript "github.com/jlcrochet/tree-sitter-zscript/bindings/go"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/cache"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/symbols"
+)
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	c, err := cache.Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	source := []byte("class Imp : Actor { int x }")
+
+	if _, ok := c.Get(source); ok {
+		t.Fatal("Get() found an entry in an empty cache")
+	}
+	first, err := c.Load(context.Background(), "a.zs", source)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.Table.Path != "a.zs" || len(first.Table.Classes) != 1 || len(first.Diagnostics) != 1 {
+		t.Fatalf("Load() = %+v", first)
+	}
+
+	cached, ok := c.Get(source)
+	if !ok {
+		t.Fatal("Get() found no entry after Load")
+	}
+	second, err := c.Load(context.Background(), "b.zs", source)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if second.Table.Path != "b.zs" {
+		t.Errorf("Path = %q, want b.zs", second.Table.Path)
+	}
+	second.Table.Path = "a.zs"
+	if !reflect.DeepEqual(first, second) || !reflect.DeepEqual(cached.Table.Classes, first.Table.Classes) {
+		t.Errorf("cached entry = %+v, want %+v", second, first)
+	}
+
+	if err := c.Clear(); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := c.Get(source); ok {
+		t.Error("Get() found an entry after Clear")
+	}
+}
+
+func TestCorruptEntry(t *testing.T) {
+	dir := t.TempDir()
+	c, err := cache.Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	source := []byte("const X = 1;")
+	if _, err := c.Load(context.Background(), "x.zs", source); err != nil {
+		t.Fatal(err)
+	}
+	matches, _ := filepath.Glob(filepath.Join(dir, "*", "*", cache.Key(source)))
+	if len(matches) != 1 {
+		t.Fatalf("entry files = %v", matches)
+	}
+	if err := os.WriteFile(matches[0], []byte("garbage"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := c.Get(source); ok {
+		t.Error("Get() decoded a corrupt entry")
+	}
+	if e, err := c.Load(context.Background(), "x.zs", source); err != nil || len(e.Table.Consts) != 1 {
+		t.Errorf("Load() = %+v, %v", e, err)
+	}
+}
+
+func TestSyntax(t *testing.T) {
+	source := []byte("class Imp : Actor {\n\tint x; @@\n\tvoid F() {}\n}\n")
+	tree, err := zscript.Parse(context.Background(), source)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tree.Close()
+
+	s := cache.NewSyntax(tree)
+	root := s.Nodes[0]
+	if root.Kind != zscript.NodeSourceFile || root.Parent != -1 || root.Next != len(s.Nodes) || root.Range != tree.RootNode().Range() {
+		t.Fatalf("root = %+v", root)
+	}
+	var errors []string
+	for _, n := range s.Nodes {
+		if n.Error {
+			errors = append(errors, n.Kind)
+		}
+	}
+	if len(errors) == 0 {
+		t.Error("no ERROR nodes")
+	}
+	var children []string
+	for i := range s.Children(0) {
+		children = append(children, s.Nodes[i].Kind)
+		if s.Nodes[i].Parent != 0 {
+			t.Errorf("parent of %s = %d", s.Nodes[i].Kind, s.Nodes[i].Parent)
+		}
+	}
+	if !reflect.DeepEqual(children, []string{zscript.NodeClassDefinition}) {
+		t.Errorf("children of the root = %v", children)
+	}
+	if got, want := s.Folding(), zscript.Folding(tree.Tree); !reflect.DeepEqual(got, want) || len(want) == 0 {
+		t.Errorf("Folding() = %v, want %v", got, want)
+	}
+
+	data, err := s.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded cache.Syntax
+	if err := decoded.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(&decoded, s) {
+		t.Errorf("decoded = %+v, want %+v", decoded.Nodes, s.Nodes)
+	}
+	for _, bad := range [][]byte{nil, data[:len(data)-1], append(data, 0), append([]byte{9}, data[1:]...)} {
+		if err := new(cache.Syntax).UnmarshalBinary(bad); err == nil {
+			t.Errorf("UnmarshalBinary(%q) succeeded", bad)
+		}
+	}
+}
+
+// BenchmarkLoad compares parsing the benchmark fixtures and extracting
+// their symbols with loading them from a warm cache.
+func BenchmarkLoad(b *testing.B) {
+	paths, err := filepath.Glob("../testdata/bench/*.zs")
+	if err != nil || len(paths) == 0 {
+		b.Fatalf("no fixtures: %v", err)
+	}
+	var sources [][]byte
+	size := 0
+	for _, path := range paths {
+		source, err := os.ReadFile(path)
+		if err != nil {
+			b.Fatal(err)
+		}
+		sources = append(sources, source)
+		size += len(source)
+	}
+	c, err := cache.Open(b.TempDir())
+	if err != nil {
+		b.Fatal(err)
+	}
+	for i, source := range sources {
+		if _, err := c.Load(context.Background(), paths[i], source); err != nil {
+			b.Fatal(err)
+		}
+	}
+
+	b.Run("Parse", func(b *testing.B) {
+		b.SetBytes(int64(size))
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			for _, source := range sources {
+				tree, err := zscript.Parse(context.Background(), source)
+				if err != nil {
+					b.Fatal(err)
+				}
+				symbols.Extract(tree)
+				cache.NewSyntax(tree)
+				tree.Close()
+			}
+		}
+	})
+	b.Run("Cached", func(b *testing.B) {
+		b.SetBytes(int64(size))
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			for _, source := range sources {
+				if _, ok := c.Get(source); !ok {
+					b.Fatal("Get() found no entry")
+				}
+			}
+		}
+	})
+}
@@ -0,0 +1,251 @@
+package cache
+
+import (
+	"encoding/binary"
+	"errors"
+	"iter"
+
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+
+	zscript "github.com/jlcrochet/tree-sitter-zscript/bindings/go"
+)
+
+// Syntax is the shape of a parse tree without the parser: the kinds,
+// ranges and structure of its named nodes, as Dump writes them. It is
+// what the cache keeps of a tree, for tools that look at the layout of a
+// file, such as its folds or the extent of its declarations, to do so
+// without parsing it again.
+type Syntax struct {
+	// Nodes are the named nodes in depth-first order, the root first.
+	Nodes []SyntaxNode
+}
+
+// SyntaxNode is a node of a Syntax.
+type SyntaxNode struct {
+	Kind  string
+	Range tree_sitter.Range
+	// Error and Missing report whether the node is an ERROR node, or one
+	// the parser inserted to recover from an error.
+	Error, Missing bool
+	// Parent is the index of the parent of the node, -1 for the root,
+	// and Next that of the first node after its descendants.
+	Parent, Next int
+}
+
+// NewSyntax returns the syntax of tree.
+func NewSyntax(tree *zscript.Tree) *Syntax {
+	s := &Syntax{}
+	var open []int
+	v := zscript.Visitor{
+		Enter: func(node *tree_sitter.Node) zscript.WalkAction {
+			if !node.IsNamed() {
+				return zscript.WalkSkipChildren
+			}
+			parent := -1
+			if len(open) > 0 {
+				parent = open[len(open)-1]
+			}
+			open = append(open, len(s.Nodes))
+			s.Nodes = append(s.Nodes, SyntaxNode{
+				Kind:    node.Kind(),
+				Range:   node.Range(),
+				Error:   node.IsError(),
+				Missing: node.IsMissing(),
+				Parent:  parent,
+			})
+			return zscript.WalkContinue
+		},
+		Leave: func(node *tree_sitter.Node) {
+			if node.IsNamed() {
+				s.Nodes[open[len(open)-1]].Next = len(s.Nodes)
+				open = open[:len(open)-1]
+			}
+		},
+	}
+	zscript.Walk(tree.RootNode(), &v)
+	return s
+}
+
+// Children returns the indexes of the children of the node at index i.
+func (s *Syntax) Children(i int) iter.Seq[int] {
+	return func(yield func(int) bool) {
+		for j := i + 1; j < s.Nodes[i].Next; j = s.Nodes[j].Next {
+			if !yield(j) {
+				return
+			}
+		}
+	}
+}
+
+// Folding returns the foldable regions of the tree, as zscript.Folding
+// finds them.
+func (s *Syntax) Folding() []zscript.FoldingRange {
+	var f zscript.Folder
+	for _, n := range s.Nodes {
+		f.Add(n.Kind, n.Range)
+	}
+	return f.Ranges()
+}
+
+// syntaxVersion is the first byte of an encoded Syntax.
+const syntaxVersion = 1
+
+const (
+	flagError = 1 << iota
+	flagMissing
+)
+
+var errSyntax = errors.New("cache: malformed syntax")
+
+// MarshalBinary encodes s compactly: its kinds once, and then each node as
+// varints relative to the node before it, so that a tree takes a few
+// bytes a node. Gob uses it to store s in an Entry.
+func (s *Syntax) MarshalBinary() ([]byte, error) {
+	buf := []byte{syntaxVersion}
+	kinds := map[string]uint64{}
+	var names []string
+	for _, n := range s.Nodes {
+		if _, ok := kinds[n.Kind]; !ok {
+			kinds[n.Kind] = uint64(len(names))
+			names = append(names, n.Kind)
+		}
+	}
+	buf = binary.AppendUvarint(buf, uint64(len(names)))
+	for _, name := range names {
+		buf = binary.AppendUvarint(buf, uint64(len(name)))
+		buf = append(buf, name...)
+	}
+
+	buf = binary.AppendUvarint(buf, uint64(len(s.Nodes)))
+	var prev tree_sitter.Range
+	for i, n := range s.Nodes {
+		var flags byte
+		if n.Error {
+			flags |= flagError
+		}
+		if n.Missing {
+			flags |= flagMissing
+		}
+		r := n.Range
+		buf = binary.AppendUvarint(buf, kinds[n.Kind])
+		buf = append(buf, flags)
+		buf = binary.AppendVarint(buf, int64(r.StartByte)-int64(prev.StartByte))
+		buf = binary.AppendUvarint(buf, uint64(r.EndByte-r.StartByte))
+		buf = binary.AppendVarint(buf, int64(r.StartPoint.Row)-int64(prev.StartPoint.Row))
+		buf = binary.AppendUvarint(buf, uint64(r.StartPoint.Column))
+		buf = binary.AppendUvarint(buf, uint64(r.EndPoint.Row-r.StartPoint.Row))
+		buf = binary.AppendUvarint(buf, uint64(r.EndPoint.Column))
+		buf = binary.AppendUvarint(buf, uint64(n.Next-i-1))
+		prev = r
+	}
+	return buf, nil
+}
+
+// UnmarshalBinary decodes data, as MarshalBinary encodes it, into s.
+func (s *Syntax) UnmarshalBinary(data []byte) error {
+	if len(data) == 0 || data[0] != syntaxVersion {
+		return errSyntax
+	}
+	d := decoder{data: data[1:]}
+	names := make([]string, d.count())
+	for i := range names {
+		n := d.count()
+		if d.err != nil || n > len(d.data) {
+			return errSyntax
+		}
+		names[i] = string(d.data[:n])
+		d.data = d.data[n:]
+	}
+
+	nodes := make([]SyntaxNode, d.count())
+	var open []int
+	var prev tree_sitter.Range
+	for i := range nodes {
+		kind := d.uvarint()
+		flags := d.byte()
+		var r tree_sitter.Range
+		r.StartByte = uint(int64(prev.StartByte) + d.varint())
+		r.EndByte = r.StartByte + uint(d.uvarint())
+		r.StartPoint.Row = uint(int64(prev.StartPoint.Row) + d.varint())
+		r.StartPoint.Column = uint(d.uvarint())
+		r.EndPoint.Row = r.StartPoint.Row + uint(d.uvarint())
+		r.EndPoint.Column = uint(d.uvarint())
+		next := i + 1 + int(d.uvarint())
+		if d.err != nil || kind >= uint64(len(names)) || next > len(nodes) {
+			return errSyntax
+		}
+		for len(open) > 0 && nodes[open[len(open)-1]].Next <= i {
+			open = open[:len(open)-1]
+		}
+		parent := -1
+		if len(open) > 0 {
+			parent = open[len(open)-1]
+			if next > nodes[parent].Next {
+				return errSyntax
+			}
+		} else if i > 0 {
+			return errSyntax
+		}
+		nodes[i] = SyntaxNode{
+			Kind:    names[kind],
+			Range:   r,
+			Error:   flags&flagError != 0,
+			Missing: flags&flagMissing != 0,
+			Parent:  parent,
+			Next:    next,
+		}
+		open = append(open, i)
+		prev = r
+	}
+	if len(d.data) > 0 {
+		return errSyntax
+	}
+	s.Nodes = nodes
+	return nil
+}
+
+// decoder reads the varints of an encoded Syntax, keeping the first
+// error.
+type decoder struct {
+	data []byte
+	err  error
+}
+
+func (d *decoder) uvarint() uint64 {
+	v, n := binary.Uvarint(d.data)
+	if n <= 0 {
+		d.err, n = errSyntax, 0
+	}
+	d.data = d.data[n:]
+	return v
+}
+
+func (d *decoder) varint() int64 {
+	v, n := binary.Varint(d.data)
+	if n <= 0 {
+		d.err, n = errSyntax, 0
+	}
+	d.data = d.data[n:]
+	return v
+}
+
+func (d *decoder) byte() byte {
+	if len(d.data) == 0 {
+		d.err = errSyntax
+		return 0
+	}
+	b := d.data[0]
+	d.data = d.data[1:]
+	return b
+}
+
+// count reads a number of elements that follow, each of at least one
+// byte, failing if there are not so many bytes left.
+func (d *decoder) count() int {
+	v := d.uvarint()
+	if v > uint64(len(d.data)) {
+		d.err = errSyntax
+		return 0
+	}
+	return int(v)
+}
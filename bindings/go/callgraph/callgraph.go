@@ -0,0 +1,359 @@
+// Package callgraph builds the graph of which methods call which in a
+// ZScript project.
+//
+// The nodes are the methods of the project's classes and structs, a
+// pseudo-function for the States block of each class, which calls the
+// action functions of its state lines, and the functions that are called
+// but not declared in the project, such as engine action functions. Calls
+// are bound with the resolve package; a call that may dispatch to
+// overrides also gets an edge to every override in a subclass.
+package callgraph
+
+import (
+	"sort"
+	"strings"
+
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+
+	zscript "github.com/jlcrochet/tree-sitter-zscript/bindings/go"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/hierarchy"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/project"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/resolve"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/symbols"
+)
+
+// FuncKind classifies the nodes of the graph.
+type FuncKind int
+
+const (
+	// FuncMethod is a method declared in the project.
+	FuncMethod FuncKind = iota
+	// FuncStates stands for the States block of a class.
+	FuncStates
+	// FuncExternal is a function the project calls but does not declare.
+	FuncExternal
+)
+
+func (k FuncKind) String() string {
+	switch k {
+	case FuncStates:
+		return "states"
+	case FuncExternal:
+		return "external"
+	}
+	return "method"
+}
+
+// Func is a node of the graph.
+type Func struct {
+	Kind FuncKind
+	// Class is the class or struct that declares the method, or "" for
+	// an external function.
+	Class string
+	// Name is the method name; for a States node it is "States".
+	Name string
+	// Method is the declaration of a FuncMethod node.
+	Method *symbols.Method
+	// Path is the file that declares the method or States block.
+	Path string
+}
+
+// String returns the qualified name, such as "Imp.Tick".
+func (f *Func) String() string {
+	if f.Class == "" {
+		return f.Name
+	}
+	return f.Class + "." + f.Name
+}
+
+// EdgeKind classifies calls.
+type EdgeKind int
+
+const (
+	// EdgeDirect is a call bound to the callee by name.
+	EdgeDirect EdgeKind = iota
+	// EdgeSuper is a call through Super, which never dispatches.
+	EdgeSuper
+	// EdgeVirtual is a possible dispatch of a call to a virtual method
+	// to an override in a subclass.
+	EdgeVirtual
+	// EdgeAmbiguous is a call to one of several methods of the same name,
+	// made through an expression whose type is not known.
+	EdgeAmbiguous
+	// EdgeAction is an action function called from a state line.
+	EdgeAction
+)
+
+func (k EdgeKind) String() string {
+	switch k {
+	case EdgeSuper:
+		return "super"
+	case EdgeVirtual:
+		return "virtual"
+	case EdgeAmbiguous:
+		return "ambiguous"
+	case EdgeAction:
+		return "action"
+	}
+	return "direct"
+}
+
+// Edge is a call from one function to another.
+type Edge struct {
+	Caller, Callee *Func
+	Kind           EdgeKind
+	// Path and Range locate the name of the called function at the call
+	// site.
+	Path  string
+	Range tree_sitter.Range
+}
+
+// Graph is a call graph.
+type Graph struct {
+	funcs   map[string]*Func
+	callees map[*Func][]*Edge
+	callers map[*Func][]*Edge
+}
+
+func key(class, name string) string {
+	return strings.ToLower(class) + "." + strings.ToLower(name)
+}
+
+// statesKey is the key of the States node of class, which cannot clash
+// with a method name.
+func statesKey(class string) string {
+	return strings.ToLower(class) + "#states"
+}
+
+// ForProject builds the call graph of p.
+func ForProject(p *project.Project) *Graph {
+	trees := make([]*zscript.Tree, len(p.Files))
+	for i, f := range p.Files {
+		trees[i] = f.Tree
+	}
+	return Build(trees...)
+}
+
+// Build builds the call graph of the given files.
+func Build(trees ...*zscript.Tree) *Graph {
+	tables := symbols.ExtractAll(0, trees...)
+	b := &builder{
+		g: &Graph{
+			funcs:   map[string]*Func{},
+			callees: map[*Func][]*Edge{},
+			callers: map[*Func][]*Edge{},
+		},
+		resolver:  resolve.New(tables...),
+		hierarchy: hierarchy.Build(tables...),
+	}
+	for _, t := range tables {
+		for _, c := range t.Classes {
+			for _, m := range c.Methods {
+				b.declare(c.Name, m, t.Path)
+			}
+			if len(c.States) > 0 {
+				b.states(c.Name, t.Path)
+			}
+		}
+		for _, s := range t.Structs {
+			for _, m := range s.Methods {
+				b.declare(s.Name, m, t.Path)
+			}
+		}
+	}
+	for _, tree := range trees {
+		b.calls(tree)
+	}
+	return b.g
+}
+
+type builder struct {
+	g         *Graph
+	resolver  *resolve.Resolver
+	hierarchy *hierarchy.Hierarchy
+}
+
+func (b *builder) declare(class string, m *symbols.Method, path string) {
+	k := key(class, m.Name)
+	if _, ok := b.g.funcs[k]; !ok {
+		b.g.funcs[k] = &Func{Kind: FuncMethod, Class: class, Name: m.Name, Method: m, Path: path}
+	}
+}
+
+func (b *builder) states(class, path string) *Func {
+	k := statesKey(class)
+	f := b.g.funcs[k]
+	if f == nil {
+		f = &Func{Kind: FuncStates, Class: class, Name: "States", Path: path}
+		b.g.funcs[k] = f
+	}
+	return f
+}
+
+func (b *builder) external(name string) *Func {
+	k := key("", name)
+	f := b.g.funcs[k]
+	if f == nil {
+		f = &Func{Kind: FuncExternal, Name: name}
+		b.g.funcs[k] = f
+	}
+	return f
+}
+
+func (b *builder) add(e *Edge) {
+	for _, old := range b.g.callees[e.Caller] {
+		if old.Callee == e.Callee && old.Kind == e.Kind && old.Range == e.Range {
+			return
+		}
+	}
+	b.g.callees[e.Caller] = append(b.g.callees[e.Caller], e)
+	b.g.callers[e.Callee] = append(b.g.callers[e.Callee], e)
+}
+
+// calls adds the edges for the calls in tree.
+func (b *builder) calls(tree *zscript.Tree) {
+	var caller *Func
+	var v zscript.Visitor
+	v.On(zscript.NodeMethodDefinition, func(node *tree_sitter.Node) zscript.WalkAction {
+		class := resolve.EnclosingType(node, tree.Source)
+		if name := node.ChildByFieldName(zscript.FieldName); name != nil && class != "" {
+			caller = b.g.funcs[key(class, name.Utf8Text(tree.Source))]
+		}
+		return zscript.WalkContinue
+	})
+	v.On(zscript.NodeStatesBlock, func(node *tree_sitter.Node) zscript.WalkAction {
+		if class := resolve.EnclosingType(node, tree.Source); class != "" {
+			caller = b.states(class, tree.Path)
+		}
+		return zscript.WalkContinue
+	})
+	v.Leave = func(node *tree_sitter.Node) {
+		if k := node.Kind(); k == zscript.NodeMethodDefinition || k == zscript.NodeStatesBlock {
+			caller = nil
+		}
+	}
+	v.On(zscript.NodeCallExpression, func(node *tree_sitter.Node) zscript.WalkAction {
+		if caller == nil {
+			return zscript.WalkContinue
+		}
+		fn := node.ChildByFieldName(zscript.FieldFunction)
+		super := false
+		if fn != nil && fn.Kind() == zscript.NodeFieldExpression {
+			receiver := fn.ChildByFieldName(zscript.FieldArgument)
+			super = receiver != nil && receiver.Kind() == zscript.NodeSuperExpression
+			fn = fn.ChildByFieldName(zscript.FieldField)
+		}
+		if fn != nil && (fn.Kind() == zscript.NodeIdentifier || fn.Kind() == zscript.NodeFieldIdentifier) {
+			b.call(tree, caller, fn, super, EdgeDirect)
+		}
+		return zscript.WalkContinue
+	})
+	v.On(zscript.NodeStateActionCall, func(node *tree_sitter.Node) zscript.WalkAction {
+		if fn := node.ChildByFieldName(zscript.FieldFunction); caller != nil && fn != nil {
+			b.call(tree, caller, fn, false, EdgeAction)
+		}
+		return zscript.WalkContinue
+	})
+	zscript.Walk(tree.RootNode(), &v)
+}
+
+// call adds the edges for a call of the function named by name.
+func (b *builder) call(tree *zscript.Tree, caller *Func, name *tree_sitter.Node, super bool, kind EdgeKind) {
+	site := func(callee *Func, kind EdgeKind) {
+		b.add(&Edge{Caller: caller, Callee: callee, Kind: kind, Path: tree.Path, Range: name.Range()})
+	}
+
+	if super {
+		kind = EdgeSuper
+	}
+	var methods []*Func
+	decls := b.resolver.ResolveAll(tree, name)
+	for _, d := range decls {
+		if d.Kind != symbols.KindMethod {
+			continue
+		}
+		if f := b.g.funcs[key(d.Owner, d.Name)]; f != nil {
+			methods = append(methods, f)
+		}
+	}
+	if len(decls) == 0 {
+		site(b.external(name.Utf8Text(tree.Source)), kind)
+		return
+	}
+	if len(methods) > 1 {
+		for _, f := range methods {
+			site(f, EdgeAmbiguous)
+		}
+		return
+	}
+	if len(methods) == 0 {
+		return
+	}
+
+	callee := methods[0]
+	site(callee, kind)
+	if super || !callee.Method.HasModifier("virtual") && !callee.Method.HasModifier("override") {
+		return
+	}
+	for _, sub := range b.hierarchy.Subclasses(callee.Class) {
+		if f := b.g.funcs[key(sub.Name, callee.Name)]; f != nil && f.Method.HasModifier("override") {
+			site(f, EdgeVirtual)
+		}
+	}
+}
+
+// Funcs returns every node of the graph, sorted by qualified name.
+func (g *Graph) Funcs() []*Func {
+	funcs := make([]*Func, 0, len(g.funcs))
+	for _, f := range g.funcs {
+		funcs = append(funcs, f)
+	}
+	sortFuncs(funcs)
+	return funcs
+}
+
+// Func returns the method named name of class, or the States node of
+// class if name is "States", matched case-insensitively. With an empty
+// class it returns the external function named name.
+func (g *Graph) Func(class, name string) *Func {
+	if class != "" && strings.EqualFold(name, "States") {
+		return g.funcs[statesKey(class)]
+	}
+	return g.funcs[key(class, name)]
+}
+
+// Callees returns the calls made by f, in source order.
+func (g *Graph) Callees(f *Func) []*Edge {
+	return g.callees[f]
+}
+
+// Callers returns the calls of f.
+func (g *Graph) Callers(f *Func) []*Edge {
+	return g.callers[f]
+}
+
+// Reachable returns the functions that can be reached from roots by
+// following calls, including the roots.
+func (g *Graph) Reachable(roots ...*Func) map[*Func]bool {
+	seen := map[*Func]bool{}
+	stack := append([]*Func(nil), roots...)
+	for len(stack) > 0 {
+		f := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if f == nil || seen[f] {
+			continue
+		}
+		seen[f] = true
+		for _, e := range g.callees[f] {
+			stack = append(stack, e.Callee)
+		}
+	}
+	return seen
+}
+
+func sortFuncs(funcs []*Func) {
+	sort.Slice(funcs, func(i, j int) bool {
+		a, b := strings.ToLower(funcs[i].String()), strings.ToLower(funcs[j].String())
+		return a < b
+	})
+}
@@ -0,0 +1,103 @@
+package callgraph_test
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"testing"
+
+	zscript "github.com/jlcrochet/tree-sitter-zscript/bindings/go"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/callgraph"
+)
+
+const source = `class Base : Actor {
+	virtual void Think() { Helper(); }
+	void Helper() {}
+	override void Tick() { Think(); Super.Tick(); }
+}
+class Imp : Base {
+	override void Think() { Super.Think(); Roar(); }
+	void Roar() { A_StartSound("imp/sight"); }
+	void Unused() {}
+	action void A_Bite() { invoker.Roar(); }
+	States {
+	Spawn:
+		TROO A 10 A_Look;
+		TROO B 10 { A_Bite(); }
+		Loop;
+	}
+}
+`
+
+func build(t *testing.T) *callgraph.Graph {
+	t.Helper()
+	tree, err := zscript.Parse(context.Background(), []byte(source))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(tree.Close)
+	tree.Path = "a.zs"
+	return callgraph.Build(tree)
+}
+
+func edges(es []*callgraph.Edge) []string {
+	var result []string
+	for _, e := range es {
+		result = append(result, fmt.Sprintf("%s -> %s (%s) %d:%d", e.Caller, e.Callee, e.Kind, e.Range.StartPoint.Row+1, e.Range.StartPoint.Column+1))
+	}
+	return result
+}
+
+func TestBuild(t *testing.T) {
+	g := build(t)
+
+	tests := []struct {
+		class, name string
+		want        []string
+	}{
+		{"Base", "Think", []string{"Base.Think -> Base.Helper (direct) 2:25"}},
+		{"Base", "Tick", []string{
+			"Base.Tick -> Base.Think (direct) 4:25",
+			"Base.Tick -> Imp.Think (virtual) 4:25",
+			"Base.Tick -> Tick (super) 4:40",
+		}},
+		{"Imp", "Think", []string{
+			"Imp.Think -> Base.Think (super) 7:32",
+			"Imp.Think -> Imp.Roar (direct) 7:41",
+		}},
+		{"Imp", "Roar", []string{"Imp.Roar -> A_StartSound (direct) 8:16"}},
+		{"Imp", "A_Bite", []string{"Imp.A_Bite -> Imp.Roar (direct) 10:33"}},
+		{"Imp", "States", []string{
+			"Imp.States -> A_Look (action) 13:13",
+			"Imp.States -> Imp.A_Bite (direct) 14:15",
+		}},
+	}
+	for _, tt := range tests {
+		f := g.Func(tt.class, tt.name)
+		if f == nil {
+			t.Errorf("no node for %s.%s", tt.class, tt.name)
+			continue
+		}
+		if got := edges(g.Callees(f)); !slices.Equal(got, tt.want) {
+			t.Errorf("Callees(%s) =\n%q\nwant\n%q", f, got, tt.want)
+		}
+	}
+
+	if got := edges(g.Callers(g.Func("Imp", "roar"))); len(got) != 2 {
+		t.Errorf("Callers(Imp.Roar) = %q, want 2 calls", got)
+	}
+	if f := g.Func("", "A_Look"); f == nil || f.Kind != callgraph.FuncExternal {
+		t.Errorf("A_Look node = %+v, want external", f)
+	}
+
+	reached := g.Reachable(g.Func("Imp", "States"))
+	var names []string
+	for _, f := range g.Funcs() {
+		if reached[f] && f.Kind == callgraph.FuncMethod {
+			names = append(names, f.String())
+		}
+	}
+	if want := []string{"Imp.A_Bite", "Imp.Roar"}; !slices.Equal(names, want) {
+		t.Errorf("reachable from Imp.States = %q, want %q", names, want)
+	}
+}
@@ -0,0 +1,327 @@
+// Package clones finds duplicated code in a ZScript project: methods,
+// state sequences and whole classes that were copied and pasted, with or
+// without small edits.
+//
+// Fragments are compared by the shape of their trees. Names and literal
+// values are ignored, so that a method copied and then given other
+// variable names or constants is still found, but the kinds of nodes,
+// their operators and keywords, and the flow of states, such as Loop or
+// Stop, must match. Two fragments are exact clones if their normalized
+// trees are the same. Otherwise their similarity is the Dice coefficient
+// of their subtrees: twice the number of normalized subtrees they share,
+// over the number of subtrees in both.
+package clones
+
+import (
+	"bufio"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"sort"
+	"strings"
+
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+
+	zscript "github.com/jlcrochet/tree-sitter-zscript/bindings/go"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/project"
+)
+
+// Kind is the kind of a fragment.
+type Kind int
+
+const (
+	// Method is a method or function with a body.
+	Method Kind = iota
+	// States is a state label with the states following it.
+	States
+	// Class is a class definition.
+	Class
+)
+
+func (k Kind) String() string {
+	switch k {
+	case States:
+		return "state sequence"
+	case Class:
+		return "class"
+	}
+	return "method"
+}
+
+// Options configures Find.
+type Options struct {
+	// Threshold is the similarity, from 0 to 1, at which two fragments
+	// are reported as near clones. Zero means DefaultThreshold, and 1
+	// reports exact clones only.
+	Threshold float64
+	// MinNodes is the number of nodes below which fragments are too small
+	// to report. Zero means DefaultMinNodes.
+	MinNodes int
+}
+
+// The defaults of Options.
+const (
+	DefaultThreshold = 0.8
+	DefaultMinNodes  = 20
+)
+
+// Fragment is a method, state sequence or class of the project.
+type Fragment struct {
+	Kind Kind
+	// Name is the class name, followed by a dot and the method name or
+	// state label for those kinds.
+	Name  string
+	Path  string
+	Range tree_sitter.Range
+	// Nodes is the number of nodes in the fragment.
+	Nodes int
+
+	class  *Fragment
+	hash   uint64
+	hashes map[uint64]int
+}
+
+// String returns the location and name of f, as in:
+//
+//	actors/imp.zs:12:2: Imp.Tick
+func (f *Fragment) String() string {
+	return fmt.Sprintf("%s:%d:%d: %s", f.Path, f.Range.StartPoint.Row+1, f.Range.StartPoint.Column+1, f.Name)
+}
+
+// Group is a set of fragments of one kind that are clones of each other:
+// all the exact clones of a fragment, or a pair of near clones.
+type Group struct {
+	Kind Kind
+	// Similarity is 1 for exact clones, or the similarity of the pair.
+	Similarity float64
+	Fragments  []*Fragment
+}
+
+// Find returns the clones in p, most similar and then largest first.
+// Clones inside fragments that are themselves clones of each other, such
+// as the methods of two copies of a class, are not reported separately.
+func Find(p *project.Project, opts Options) []Group {
+	if opts.Threshold <= 0 {
+		opts.Threshold = DefaultThreshold
+	}
+	if opts.MinNodes <= 0 {
+		opts.MinNodes = DefaultMinNodes
+	}
+	var all []*Fragment
+	for _, f := range p.Files {
+		h := &hasher{path: f.Path, source: f.Tree.Source}
+		h.node(f.Tree.RootNode(), "")
+		all = append(all, h.fragments...)
+	}
+	byKind := map[Kind][]*Fragment{}
+	for _, f := range all {
+		if f.Nodes >= opts.MinNodes {
+			byKind[f.Kind] = append(byKind[f.Kind], f)
+		}
+	}
+
+	// Classes come first, so that the clones inside them can be left out.
+	var groups []Group
+	together := map[[2]*Fragment]bool{}
+	for _, kind := range []Kind{Class, Method, States} {
+		for _, g := range find(byKind[kind], opts.Threshold) {
+			if inside(g, together) {
+				continue
+			}
+			if kind == Class {
+				for _, a := range g.Fragments {
+					for _, b := range g.Fragments {
+						together[[2]*Fragment{a, b}] = true
+					}
+				}
+			}
+			groups = append(groups, g)
+		}
+	}
+	sort.SliceStable(groups, func(i, j int) bool {
+		a, b := groups[i], groups[j]
+		if a.Similarity != b.Similarity {
+			return a.Similarity > b.Similarity
+		}
+		return a.Fragments[0].Nodes > b.Fragments[0].Nodes
+	})
+	return groups
+}
+
+// inside reports whether the fragments of g all lie in classes reported
+// together as clones.
+func inside(g Group, together map[[2]*Fragment]bool) bool {
+	for _, f := range g.Fragments[1:] {
+		a, b := g.Fragments[0].class, f.class
+		if a == nil || b == nil || a == b || !together[[2]*Fragment{a, b}] {
+			return false
+		}
+	}
+	return true
+}
+
+// find groups fragments, all of one kind, into exact clones, and pairs the
+// remaining fragments whose similarity reaches threshold.
+func find(fragments []*Fragment, threshold float64) []Group {
+	var groups []Group
+	byHash := map[uint64][]*Fragment{}
+	var order []uint64
+	for _, f := range fragments {
+		if byHash[f.hash] == nil {
+			order = append(order, f.hash)
+		}
+		byHash[f.hash] = append(byHash[f.hash], f)
+	}
+	for _, h := range order {
+		if members := byHash[h]; len(members) > 1 {
+			groups = append(groups, Group{Kind: members[0].Kind, Similarity: 1, Fragments: members})
+		}
+	}
+	if threshold >= 1 {
+		return groups
+	}
+
+	// One fragment of each exact group stands for the rest, so that near
+	// clones of a group are reported once.
+	for i, a := range order {
+		for _, b := range order[i+1:] {
+			fa, fb := byHash[a][0], byHash[b][0]
+			small, large := min(fa.Nodes, fb.Nodes), max(fa.Nodes, fb.Nodes)
+			if float64(2*small)/float64(small+large) < threshold {
+				continue
+			}
+			if s := similarity(fa, fb); s >= threshold {
+				groups = append(groups, Group{Kind: fa.Kind, Similarity: s, Fragments: []*Fragment{fa, fb}})
+			}
+		}
+	}
+	return groups
+}
+
+// similarity returns the Dice coefficient of the subtrees of a and b.
+func similarity(a, b *Fragment) float64 {
+	shared := 0
+	for h, n := range a.hashes {
+		shared += min(n, b.hashes[h])
+	}
+	return float64(2*shared) / float64(a.Nodes+b.Nodes)
+}
+
+// normalized are the kinds of leaf whose text is ignored.
+var normalized = map[string]bool{
+	zscript.NodeIdentifier:        true,
+	zscript.NodeTypeIdentifier:    true,
+	zscript.NodeFieldIdentifier:   true,
+	zscript.NodeNumberLiteral:     true,
+	zscript.NodeStringContent:     true,
+	zscript.NodeNameLiteral:       true,
+	zscript.NodeStateSpriteFrames: true,
+}
+
+// hasher computes the normalized hashes of the nodes of a file and
+// collects its fragments.
+type hasher struct {
+	path      string
+	source    []byte
+	fragments []*Fragment
+	// open are the fragments enclosing the node being hashed.
+	open  []*Fragment
+	class *Fragment
+}
+
+// node returns the normalized hash of n, whose field in its parent is
+// field, and adds it to the subtrees of the enclosing fragments.
+func (h *hasher) node(n *tree_sitter.Node, field string) uint64 {
+	f := h.fragment(n)
+	if f != nil {
+		h.open = append(h.open, f)
+		if f.Kind == Class {
+			outer := h.class
+			h.class = f
+			defer func() { h.class = outer }()
+		}
+	}
+
+	sum := fnv.New64a()
+	fmt.Fprintf(sum, "%s:%s(", field, n.Kind())
+	switch {
+	case n.ChildCount() == 0 && normalized[n.Kind()]:
+	case n.ChildCount() == 0:
+		fmt.Fprintf(sum, "%s", strings.ToLower(n.Utf8Text(h.source)))
+	default:
+		for i := uint(0); i < n.ChildCount(); i++ {
+			child := n.Child(i)
+			if child.IsExtra() {
+				continue
+			}
+			fmt.Fprintf(sum, "%x,", h.node(child, n.FieldNameForChild(uint32(i))))
+		}
+	}
+	sum.Write([]byte(")"))
+	hash := sum.Sum64()
+
+	if n.IsNamed() {
+		for _, open := range h.open {
+			open.hashes[hash]++
+			open.Nodes++
+		}
+	}
+	if f != nil {
+		f.hash = hash
+		h.open = h.open[:len(h.open)-1]
+	}
+	return hash
+}
+
+// fragment returns a new fragment if n is one.
+func (h *hasher) fragment(n *tree_sitter.Node) *Fragment {
+	var kind Kind
+	var name string
+	switch n.Kind() {
+	case zscript.NodeClassDefinition:
+		kind, name = Class, h.text(n.ChildByFieldName(zscript.FieldName))
+	case zscript.NodeMethodDefinition, zscript.NodeFunctionDefinition:
+		if n.ChildByFieldName(zscript.FieldBody) == nil {
+			return nil
+		}
+		kind, name = Method, h.text(n.ChildByFieldName(zscript.FieldName))
+	case zscript.NodeStateLabel:
+		kind, name = States, h.text(n.ChildByFieldName(zscript.FieldName))
+	default:
+		return nil
+	}
+	f := &Fragment{Kind: kind, Name: name, Path: h.path, Range: n.Range(), hashes: map[uint64]int{}}
+	if kind != Class && h.class != nil {
+		f.class = h.class
+		f.Name = h.class.Name + "." + name
+	}
+	h.fragments = append(h.fragments, f)
+	return f
+}
+
+func (h *hasher) text(n *tree_sitter.Node) string {
+	if n == nil {
+		return ""
+	}
+	return n.Utf8Text(h.source)
+}
+
+// Write writes a listing of groups to w, as in:
+//
+//	2 methods, 100% similar, 48 nodes:
+//		actors/imp.zs:12:2: Imp.Tick
+//		actors/zombie.zs:30:2: Zombie.Tick
+func Write(w io.Writer, groups []Group) error {
+	bw := bufio.NewWriter(w)
+	for _, g := range groups {
+		kind := g.Kind.String() + "s"
+		if g.Kind == Class {
+			kind = "classes"
+		}
+		fmt.Fprintf(bw, "%d %s, %d%% similar, %d nodes:\n", len(g.Fragments), kind, int(g.Similarity*100), g.Fragments[0].Nodes)
+		for _, f := range g.Fragments {
+			fmt.Fprintf(bw, "\t%s\n", f)
+		}
+	}
+	return bw.Flush()
+}
@@ -0,0 +1,121 @@
+package clones_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/clones"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/project"
+)
+
+const actors = `#include "copies.zs"
+class Imp : Actor {
+	void Burst(int count) {
+		for (int i = 0; i < count; i++) {
+			let mo = Spawn("Spark", pos);
+			if (mo) { mo.vel.x = frandom(-1, 1); mo.vel.y = frandom(-1, 1); }
+		}
+	}
+	States {
+	Death:
+		TROO I 8;
+		TROO J 8 A_Scream;
+		TROO K 6;
+		TROO L 6 A_NoBlocking;
+		TROO M -1;
+		Stop;
+	}
+}
+class Zombie : Actor {
+	void Scatter(int amount) {
+		for (int n = 0; n < amount; n++) {
+			let spark = Spawn("Smoke", pos);
+			if (spark) { spark.vel.x = frandom(-2, 2); spark.vel.y = frandom(-2, 2); }
+		}
+	}
+	void Scatter2(int amount) {
+		for (int n = 0; n < amount; n++) {
+			let spark = Spawn("Smoke", pos);
+			if (spark) { spark.vel.x = frandom(-2, 2); spark.vel.y = frandom(-2, 2) * 2; }
+		}
+	}
+	States {
+	Death:
+		POSS H 5;
+		POSS I 5 A_Scream;
+		POSS J 5;
+		POSS K 5 A_NoBlocking;
+		POSS L -1;
+		Loop;
+	}
+}
+`
+
+const copies = `class Imp2 : Actor {
+	int health;
+	void Tick() { if (health > 0) { health--; A_Log("tick"); } else { Destroy(); } }
+	void Tock() { if (health < 0) { health++; A_Log("tock"); } else { Destroy(); } }
+}
+class Imp3 : Actor {
+	int strength;
+	void Tick() { if (strength > 0) { strength--; A_Log("tick"); } else { Destroy(); } }
+	void Tock() { if (strength < 0) { strength++; A_Log("tock"); } else { Destroy(); } }
+}
+`
+
+func find(t *testing.T, opts clones.Options) string {
+	t.Helper()
+	fsys := fstest.MapFS{
+		"actors.zs": {Data: []byte(actors)},
+		"copies.zs": {Data: []byte(copies)},
+	}
+	p, err := project.Load(context.Background(), fsys, "actors.zs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+	var b strings.Builder
+	if err := clones.Write(&b, clones.Find(p, opts)); err != nil {
+		t.Fatal(err)
+	}
+	return b.String()
+}
+
+func TestFind(t *testing.T) {
+	// The state sequences differ in how they end, and the methods of Imp2
+	// and Imp3 are left out since the classes are clones.
+	want := `2 classes, 100% similar, 57 nodes:
+	copies.zs:1:1: Imp2
+	copies.zs:6:1: Imp3
+2 methods, 100% similar, 53 nodes:
+	actors.zs:3:2: Imp.Burst
+	actors.zs:20:2: Zombie.Scatter
+2 state sequences, 88% similar, 26 nodes:
+	actors.zs:10:2: Imp.Death
+	actors.zs:33:2: Zombie.Death
+2 methods, 81% similar, 53 nodes:
+	actors.zs:3:2: Imp.Burst
+	actors.zs:26:2: Zombie.Scatter2
+`
+	if got := find(t, clones.Options{}); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestFindExact(t *testing.T) {
+	got := find(t, clones.Options{Threshold: 1})
+	if strings.Contains(got, "Scatter2") || !strings.Contains(got, "Zombie.Scatter") {
+		t.Errorf("got\n%s\nwant exact clones only", got)
+	}
+}
+
+func TestFindMinNodes(t *testing.T) {
+	if got := find(t, clones.Options{MinNodes: 30}); strings.Contains(got, "Death") {
+		t.Errorf("got\n%s\nwant no state sequences of under 30 nodes", got)
+	}
+	if got := find(t, clones.Options{MinNodes: 100}); got != "" {
+		t.Errorf("got\n%s\nwant no fragments of 100 nodes", got)
+	}
+}
@@ -0,0 +1,54 @@
+// Command zscript-langserver is a Language Server Protocol server for
+// ZScript. It communicates over standard input and output.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/cache"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/lsp"
+)
+
+var (
+	cacheDir = flag.String("cache", "", "cache indexed files in this directory (default: the user cache directory)")
+	noCache  = flag.Bool("nocache", false, "do not cache indexed files")
+	timeout  = flag.Duration("parse-timeout", lsp.DefaultParseTimeout, "give up parsing a file after this long (0 for no limit)")
+)
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: zscript-langserver\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	server := lsp.NewServer()
+	server.ParseTimeout = *timeout
+	if !*noCache {
+		server.Cache = openCache()
+	}
+	if err := server.Serve(context.Background(), os.Stdin, os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, "zscript-langserver:", err)
+		os.Exit(1)
+	}
+}
+
+// openCache opens the cache directory, or returns nil if it cannot.
+func openCache() *cache.Cache {
+	dir := *cacheDir
+	if dir == "" {
+		var err error
+		if dir, err = cache.DefaultDir(); err != nil {
+			return nil
+		}
+	}
+	c, err := cache.Open(dir)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "zscript-langserver:", err)
+		return nil
+	}
+	return c
+}
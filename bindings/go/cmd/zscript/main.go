@@ -0,0 +1,1200 @@
+// Command zscript checks and inspects ZScript source files.
+//
+// Usage:
+//
+//	zscript <command> [flags] [path ...]
+//
+// The commands are:
+//
+//	check    report syntax errors; exit with status 1 if there are any
+//	dump     print the parse tree
+//	explore  browse the parse tree of a file interactively
+//	symbols  print the outline of declarations
+//	stats    print a summary of a project: classes, states, functions, parse times
+//	decorate convert DECORATE files to ZScript
+//	metrics  print method complexity and class and line counts
+//	search   print the code matching a structural pattern
+//	rewrite  apply structural rewrite rules and print the changes as a diff
+//	init     create the files of a new mod
+//	graph    print the include, inheritance and reference graph of a project
+//	tags     write a ctags file of the declarations
+//	events   list the event handlers of a project and check they are registered
+//	minify   strip comments and whitespace and shorten local variable names
+//	clones   print the duplicated methods, state sequences and classes of a project
+//	compat   print the engine features a project uses that its targeted version lacks
+//	version  print the grammar and tree-sitter ABI versions
+//
+// Paths may be files or directories, which are searched for files with a
+// .zs, .zsc or .zc extension and for lumps named zscript. With no paths,
+// standard input is read. The commands read the .zscriptrc or zscript.toml
+// file of the mod they work on, described in package config, and the
+// commands that load a project follow its roots and archives.
+//
+// The check command takes its dialect from the version in the
+// configuration and runs the lint rules its [lint] table names over the
+// files. It prints its errors as JSON or as a SARIF log with -format json
+// or -format sarif, and with -format pretty under excerpts of the source,
+// as lint.Renderer draws them, colored when printing to a terminal unless
+// NO_COLOR is set. With -watch it keeps running, checking each file again
+// when it changes, with the rules and the dialect it would check it with
+// otherwise, and printing text only; with -dialect it also reports the syntax an older
+// version of ZScript lacks; -rules runs the lint rules written as query
+// files in the directory given, described in package lint, and counts
+// their findings as errors. For fast checks of a change to a large mod,
+// -changed takes a file holding a diff, or the list of paths git diff
+// --name-only prints, and checks only the files it changes and those whose
+// classes depend on theirs, reading the rest for their classes; -since
+// takes a git revision instead, checks the files changed since then the
+// same way and reports only the findings that are new since then.
+//
+// The dump command prints the tree as JSON with -format json or ndjson,
+// and with -format protobuf writes the File message of each file,
+// described in package zscriptpb, preceded by its length as a varint.
+//
+// The explore command takes one file and runs the explorer of package
+// explore on it, reading commands from standard input; type help for the
+// commands.
+//
+// The stats command prints the totals of the files together, the classes
+// counted by the engine class they build on and a line per file, then node
+// counts; with -json it prints the summary of package metrics as JSON.
+//
+// The decorate command takes DECORATE files and prints the converted
+// ZScript; with no paths it converts standard input.
+//
+// The metrics command exits with status 1 if a method or class is over one
+// of the limits given by its flags.
+//
+// The search command takes the pattern, described in package search,
+// before the paths and exits with status 1 if nothing matches.
+//
+// The rewrite command takes its rules, described in package rewrite, from
+// -e and -f flags and with -w writes the files instead of printing a diff.
+//
+// The init command takes the directory to create the mod in, the current
+// one by default, and names the mod after it unless given -name.
+//
+// The graph command takes the directory of a project, the current one by
+// default, and prints its graph in Graphviz DOT, or in JSON with -format
+// json; -only files or -only classes limits it to the include graph or the
+// class graph.
+//
+// The tags command writes the file named by -f, "tags" by default, or
+// standard output if it is "-".
+//
+// The events command takes the directory of a project, the current one by
+// default, and lists its event handlers with their registrations in the
+// MAPINFO lump at the directory's root and the callbacks they override. It
+// reports the handlers never registered and exits with status 1 if there
+// are any. With -manifest it also counts the handlers the named file
+// lists, one class per line, as registered.
+//
+// The minify command prints the minified files, or with -d writes each
+// under the directory given, at the path it was found by; -keep-names
+// leaves locals their names. It exits with status 1 if a file has syntax
+// errors.
+//
+// The clones command takes the directory of a project, the current one by
+// default, and exits with status 1 if it finds clones; -threshold sets the
+// similarity, from 0 to 1, at which near clones are reported, and
+// -min-nodes the size of the smallest fragment compared.
+//
+// The compat command takes the directory of a project, the current one by
+// default, and lists the uses of engine functions, fields, classes and
+// syntax newer than the GZDoom version given by -target, by default the
+// one the project declares, with what to do about each, and the version
+// the project really requires; with -shims it writes the mixin class of
+// the shims of package compat to the file given. It exits with status 1 if
+// there are any such uses.
+//
+// The version command exits with status 1 if the parser cannot be loaded
+// by the linked tree-sitter runtime.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+
+	zscript "github.com/jlcrochet/tree-sitter-zscript/bindings/go"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/clones"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/compat"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/config"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/decorate"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/depgraph"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/diff"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/events"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/explore"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/format"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/lint"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/mapinfo"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/metrics"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/minify"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/project"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/rewrite"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/scaffold"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/search"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/symbols"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/tags"
+	zversion "github.com/jlcrochet/tree-sitter-zscript/bindings/go/version"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/watch"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/zscriptpb"
+)
+
+var commands = map[string]func(args []string) int{
+	"check":    check,
+	"dump":     dump,
+	"explore":  browse,
+	"symbols":  outline,
+	"stats":    stats,
+	"decorate": convert,
+	"metrics":  measure,
+	"search":   find,
+	"rewrite":  transform,
+	"init":     initMod,
+	"graph":    graph,
+	"tags":     writeTags,
+	"events":   listEvents,
+	"minify":   shrink,
+	"clones":   findClones,
+	"compat":   checkCompat,
+	"version":  version,
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+	cmd := commands[os.Args[1]]
+	if cmd == nil {
+		fmt.Fprintf(os.Stderr, "zscript: unknown command %q\n", os.Args[1])
+		usage()
+	}
+	os.Exit(cmd(os.Args[2:]))
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "usage: zscript <check|dump|explore|symbols|stats|decorate|metrics|search|rewrite|init|graph|tags|events|minify|clones|compat|version> [flags] [path ...]\n")
+	os.Exit(2)
+}
+
+func newFlags(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: zscript %s [flags] [path ...]\n", name)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+func initMod(args []string) int {
+	flags := newFlags("init")
+	name := flags.String("name", "", "the mod name, which prefixes its class names (default: the directory name)")
+	ver := flags.String("version", "4.10", "the ZScript version to declare")
+	noHandler := flags.Bool("nohandler", false, "do not create an event handler")
+	noActor := flags.Bool("noactor", false, "do not create an example actor")
+	spaces := flags.Int("spaces", 0, "indent with this many spaces instead of tabs")
+	brace := flags.String("brace", "next", `opening brace placement: "same" or "next" line`)
+	flags.Parse(args)
+	if flags.NArg() > 1 {
+		flags.Usage()
+		return 2
+	}
+	dir := "."
+	if flags.NArg() == 1 {
+		dir = flags.Arg(0)
+	}
+	if *name == "" {
+		abs, err := filepath.Abs(dir)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		*name = filepath.Base(abs)
+	}
+
+	c := scaffold.DefaultConfig(*name)
+	c.Version = *ver
+	c.EventHandler = !*noHandler
+	c.Actor = !*noActor
+	if *spaces > 0 {
+		c.Format.UseSpaces, c.Format.IndentWidth = true, *spaces
+	}
+	switch *brace {
+	case "same":
+		c.Format.BraceStyle = format.BraceSameLine
+	case "next":
+		c.Format.BraceStyle = format.BraceNextLine
+	default:
+		fmt.Fprintf(os.Stderr, "zscript: invalid -brace %q\n", *brace)
+		return 2
+	}
+	paths, err := c.Write(dir)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	for _, p := range paths {
+		fmt.Println(p)
+	}
+	return 0
+}
+
+func graph(args []string) int {
+	flags := newFlags("graph")
+	formatName := flags.String("format", "dot", `output format: "dot" or "json"`)
+	only := flags.String("only", "", `limit the graph to "files" or "classes"`)
+	flags.Parse(args)
+	if flags.NArg() > 1 {
+		flags.Usage()
+		return 2
+	}
+	dir := "."
+	if flags.NArg() == 1 {
+		dir = flags.Arg(0)
+	}
+	p, err := loadProject(dir)
+	if err != nil {
+		return exit(err, 1)
+	}
+	defer p.Close()
+	g := depgraph.Build(p)
+	switch *only {
+	case "":
+	case "files":
+		g = g.Only(depgraph.NodeFile)
+	case "classes":
+		g = g.Only(depgraph.NodeClass)
+	default:
+		fmt.Fprintf(os.Stderr, "zscript: invalid -only %q\n", *only)
+		return 2
+	}
+	switch *formatName {
+	case "dot":
+		err = g.WriteDOT(os.Stdout)
+	case "json":
+		err = g.WriteJSON(os.Stdout)
+	default:
+		fmt.Fprintf(os.Stderr, "zscript: invalid -format %q\n", *formatName)
+		return 2
+	}
+	return exit(err, 0)
+}
+
+func writeTags(args []string) int {
+	flags := newFlags("tags")
+	out := flags.String("f", "tags", `the file to write, or "-" for standard output`)
+	flags.Parse(args)
+	var all []tags.Tag
+	if err := eachFile(flags.Args(), func(tree *zscript.Tree, _ time.Duration) {
+		all = append(all, tags.Extract(tree)...)
+	}); err != nil {
+		return exit(err, 1)
+	}
+	if *out == "-" {
+		return exit(tags.Write(os.Stdout, all), 0)
+	}
+	f, err := os.Create(*out)
+	if err != nil {
+		return exit(err, 1)
+	}
+	err = tags.Write(f, all)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return exit(err, 0)
+}
+
+func listEvents(args []string) int {
+	flags := newFlags("events")
+	manifest := flags.String("manifest", "", "a file listing handlers registered elsewhere, one per line")
+	flags.Parse(args)
+	if flags.NArg() > 1 {
+		flags.Usage()
+		return 2
+	}
+	dir := "."
+	if flags.NArg() == 1 {
+		dir = flags.Arg(0)
+	}
+	var opts events.Options
+	if *manifest != "" {
+		source, err := os.ReadFile(*manifest)
+		if err != nil {
+			return exit(err, 1)
+		}
+		opts.Registered = events.ParseManifest(source)
+	}
+	p, err := loadProject(dir)
+	if err != nil {
+		return exit(err, 1)
+	}
+	defer p.Close()
+	files, err := mapinfo.Load(os.DirFS(dir))
+	if err != nil {
+		return exit(err, 1)
+	}
+	if err := events.Write(os.Stdout, events.Find(p, opts, files...)); err != nil {
+		return exit(err, 1)
+	}
+	findings := events.Check(p, opts, files...)
+	for _, f := range findings {
+		fmt.Fprintln(os.Stderr, f)
+	}
+	if len(findings) > 0 {
+		return 1
+	}
+	return 0
+}
+
+func shrink(args []string) int {
+	flags := newFlags("minify")
+	keepNames := flags.Bool("keep-names", false, "leave local variables their names")
+	dir := flags.String("d", "", "write the minified files under this directory instead of printing them")
+	flags.Parse(args)
+
+	status := 0
+	fail := func(err error) {
+		fmt.Fprintln(os.Stderr, "zscript:", err)
+		status = 1
+	}
+	err := eachFile(flags.Args(), func(tree *zscript.Tree, _ time.Duration) {
+		out, err := minify.Minify(tree, minify.Options{KeepNames: *keepNames})
+		switch {
+		case err != nil:
+			fail(err)
+		case *dir == "" || tree.Path == "<stdin>":
+			os.Stdout.Write(out)
+		default:
+			path := filepath.Join(*dir, tree.Path)
+			if err := os.MkdirAll(filepath.Dir(path), 0o777); err != nil {
+				fail(err)
+			} else if err := os.WriteFile(path, out, 0o666); err != nil {
+				fail(err)
+			}
+		}
+	})
+	return exit(err, status)
+}
+
+func findClones(args []string) int {
+	flags := newFlags("clones")
+	threshold := flags.Float64("threshold", clones.DefaultThreshold, "the similarity, from 0 to 1, at which near clones are reported")
+	minNodes := flags.Int("min-nodes", clones.DefaultMinNodes, "the number of nodes of the smallest fragment compared")
+	flags.Parse(args)
+	if flags.NArg() > 1 {
+		flags.Usage()
+		return 2
+	}
+	dir := "."
+	if flags.NArg() == 1 {
+		dir = flags.Arg(0)
+	}
+	p, err := loadProject(dir)
+	if err != nil {
+		return exit(err, 1)
+	}
+	defer p.Close()
+	groups := clones.Find(p, clones.Options{Threshold: *threshold, MinNodes: *minNodes})
+	if err := clones.Write(os.Stdout, groups); err != nil {
+		return exit(err, 1)
+	}
+	if len(groups) > 0 {
+		return 1
+	}
+	return 0
+}
+
+func checkCompat(args []string) int {
+	flags := newFlags("compat")
+	targetName := flags.String("target", "", "the oldest GZDoom `version` the mod is to run on, by default the one it declares")
+	shimsPath := flags.String("shims", "", "write the mixin class of the shims to `file`, - for standard output")
+	mixin := flags.String("mixin", "CompatShims", "the `name` of the mixin class of the shims")
+	flags.Parse(args)
+	if flags.NArg() > 1 {
+		flags.Usage()
+		return 2
+	}
+	dir := "."
+	if flags.NArg() == 1 {
+		dir = flags.Arg(0)
+	}
+	p, err := loadProject(dir)
+	if err != nil {
+		return exit(err, 1)
+	}
+	defer p.Close()
+	target := zversion.ForProject(p)
+	if *targetName != "" {
+		if target, err = zversion.Parse(*targetName); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 2
+		}
+	}
+	trees := make([]*zscript.Tree, len(p.Files))
+	for i, f := range p.Files {
+		trees[i] = f.Tree
+	}
+	r := compat.Analyze(target, trees...)
+
+	// The report goes to standard error when the shims go to standard
+	// output.
+	out := os.Stdout
+	if *shimsPath == "-" {
+		out = os.Stderr
+	}
+	for _, u := range r.Uses {
+		fmt.Fprintln(out, u)
+	}
+	fmt.Fprintf(out, "requires GZDoom %s; targets %s\n", r.Required, r.Target)
+	switch *shimsPath {
+	case "":
+	case "-":
+		_, err = io.WriteString(os.Stdout, r.Mixin(*mixin))
+	default:
+		if len(r.Shims) > 0 {
+			err = os.WriteFile(*shimsPath, []byte(r.Mixin(*mixin)), 0o666)
+		}
+	}
+	status := 0
+	if len(r.Uses) > 0 {
+		status = 1
+	}
+	return exit(err, status)
+}
+
+func version(args []string) int {
+	newFlags("version").Parse(args)
+	fmt.Printf("grammar    %s\n", zscript.Version())
+	fmt.Printf("abi        %d (runtime supports %d to %d)\n", zscript.ABIVersion(), tree_sitter.MIN_COMPATIBLE_LANGUAGE_VERSION, tree_sitter.LANGUAGE_VERSION)
+	fmt.Printf("node kinds %d\n", zscript.NodeKindCount())
+	if err := zscript.CheckCompatibility(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
+
+func check(args []string) int {
+	flags := newFlags("check")
+	quiet := flags.Bool("q", false, "print only the number of errors")
+	formatName := flags.String("format", "text", `output format: "text", "pretty", "json" or "sarif"`)
+	watching := flags.Bool("watch", false, "check the files again whenever they change")
+	dialectName := flags.String("dialect", "", `report the syntax this ZScript version lacks, such as "2.8" or "4.x"`)
+	rulesDir := flags.String("rules", "", "also report the matches of the lint rules of the .scm query files in `dir`")
+	changedList := flags.String("changed", "", "check only the files named by the diff or list of paths in `file`, - for standard input, and the files depending on them")
+	since := flags.String("since", "", "check only the files changed since the git `revision` and the files depending on them, reporting only the findings they did not have")
+	flags.Parse(args)
+	dir := "."
+	if flags.NArg() > 0 {
+		dir = flags.Arg(0)
+		if info, err := os.Stat(dir); err == nil && !info.IsDir() {
+			dir = filepath.Dir(dir)
+		}
+	}
+	cfg, err := config.Find(dir)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	dialect := cfg.Dialect()
+	if *dialectName != "" {
+		if dialect, err = zscript.LanguageForVersion(*dialectName); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 2
+		}
+	}
+	linter, err := cfg.Linter()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	if *rulesDir != "" {
+		rules, err := lint.LoadQueryRules(os.DirFS(*rulesDir))
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 2
+		}
+		if linter == nil {
+			linter = &lint.Linter{}
+		}
+		linter.Rules = append(linter.Rules, rules...)
+	}
+	sources := map[string][]byte{}
+	renderer := &lint.Renderer{
+		Source: func(path string) []byte { return sources[path] },
+		Color:  isTerminal(os.Stdout) && os.Getenv("NO_COLOR") == "",
+	}
+	write, ok := map[string]func(io.Writer, []lint.Finding) error{
+		"text":   nil,
+		"pretty": renderer.Render,
+		"json":   lint.WriteJSON,
+		"sarif":  func(w io.Writer, f []lint.Finding) error { return lint.WriteSARIF(w, nil, f) },
+	}[*formatName]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "zscript: invalid -format %q\n", *formatName)
+		return 2
+	}
+	if *watching {
+		switch {
+		case write != nil:
+			fmt.Fprintln(os.Stderr, "zscript: -watch prints only text")
+			return 2
+		case *quiet:
+			fmt.Fprintln(os.Stderr, "zscript: -watch prints every error and cannot be used with -q")
+			return 2
+		case *changedList != "" || *since != "":
+			fmt.Fprintln(os.Stderr, "zscript: -watch checks the files that change and cannot be used with -changed or -since")
+			return 2
+		}
+		return exit(watchFiles(flags.Args(), dialect, linter), 0)
+	}
+	var changed []string
+	switch {
+	case *changedList != "" && *since != "":
+		fmt.Fprintln(os.Stderr, "zscript: -changed and -since cannot be used together")
+		return 2
+	case *changedList != "":
+		var text []byte
+		if *changedList == "-" {
+			text, err = io.ReadAll(os.Stdin)
+		} else {
+			text, err = os.ReadFile(*changedList)
+		}
+		if err != nil {
+			return exit(err, 0)
+		}
+		changed = diff.Files(text)
+	case *since != "":
+		if changed, err = gitChanged(*since); err != nil {
+			return exit(err, 0)
+		}
+	}
+	incremental := *changedList != "" || *since != ""
+
+	status, count := 0, 0
+	var findings []lint.Finding
+	var trees []*zscript.Tree
+	err = eachFile(flags.Args(), func(tree *zscript.Tree, _ time.Duration) {
+		tree.Dialect = dialect
+		sources[tree.Path] = tree.Source
+		if incremental {
+			trees = append(trees, tree.Clone())
+			return
+		}
+		for _, f := range lint.SyntaxErrors(tree) {
+			count++
+			switch {
+			case write != nil:
+				findings = append(findings, f)
+			case !*quiet:
+				p := f.Range.StartPoint
+				fmt.Printf("%s:%d:%d: %s\n", f.Path, p.Row+1, p.Column+1, f.Message)
+			}
+		}
+		if linter != nil && len(linter.Rules) > 0 {
+			// The files are linted together once all are read, so that
+			// the rules see the classes each declares.
+			trees = append(trees, tree.Clone())
+		}
+	})
+	if len(trees) > 0 {
+		var found []lint.Finding
+		if incremental {
+			var ferr error
+			if found, ferr = changedFindings(trees, linter, changed, *since); err == nil {
+				err = ferr
+			}
+		} else {
+			found = linter.Trees(trees...)
+		}
+		for _, f := range found {
+			count++
+			switch {
+			case write != nil:
+				findings = append(findings, f)
+			case *quiet:
+			case f.Rule == lint.RuleSyntax:
+				p := f.Range.StartPoint
+				fmt.Printf("%s:%d:%d: %s\n", f.Path, p.Row+1, p.Column+1, f.Message)
+			default:
+				fmt.Println(f)
+			}
+		}
+		for _, tree := range trees {
+			tree.Close()
+		}
+	}
+	if write != nil {
+		if werr := write(os.Stdout, findings); err == nil {
+			err = werr
+		}
+	}
+	if count > 0 {
+		status = 1
+		if *quiet && write == nil {
+			fmt.Println(count)
+		}
+	}
+	return exit(err, status)
+}
+
+// isTerminal reports whether f is a terminal rather than a file or pipe.
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// changedFindings returns the syntax errors and the lint findings of the
+// files of trees among changed and of those depending on them, as
+// package depgraph finds them, linting them with the rest of trees read
+// for the classes they declare; linter may be nil. The files are not
+// loaded through their includes, so only the dependencies of classes
+// count. If since is set, the findings the files had in that revision of
+// the git repository holding the current directory are left out, matched
+// as lint.NewFindings matches them.
+func changedFindings(trees []*zscript.Tree, linter *lint.Linter, changed []string, since string) ([]lint.Finding, error) {
+	byPath := map[string]*zscript.Tree{}
+	files := make([]*project.File, len(trees))
+	for i, tree := range trees {
+		byPath[filepath.Clean(tree.Path)] = tree
+		files[i] = &project.File{Path: tree.Path, Tree: tree}
+	}
+	var paths []string
+	for _, p := range changed {
+		if tree := byPath[filepath.Clean(filepath.FromSlash(p))]; tree != nil {
+			paths = append(paths, tree.Path)
+		}
+	}
+	affected := depgraph.Build(project.New(files...)).Affected(paths...)
+
+	run := func(trees []*zscript.Tree) []lint.Finding {
+		var findings []lint.Finding
+		for _, tree := range trees {
+			if slices.Contains(affected, tree.Path) {
+				findings = append(findings, lint.SyntaxErrors(tree)...)
+			}
+		}
+		if linter != nil && len(linter.Rules) > 0 {
+			findings = append(findings, linter.Only(affected, trees...)...)
+		}
+		return findings
+	}
+	findings := run(trees)
+	if since == "" || len(findings) == 0 {
+		return findings, nil
+	}
+
+	// The files as they were: those changed since the revision are read
+	// from it, or left out if they are new.
+	var base []*zscript.Tree
+	for _, tree := range trees {
+		if !slices.Contains(paths, tree.Path) {
+			base = append(base, tree)
+			continue
+		}
+		source, err := exec.Command("git", "show", since+":./"+filepath.ToSlash(filepath.Clean(tree.Path))).Output()
+		if err != nil {
+			continue
+		}
+		old, err := zscript.Parse(context.Background(), source)
+		if err != nil {
+			return nil, err
+		}
+		defer old.Close()
+		old.Path, old.Dialect = tree.Path, tree.Dialect
+		base = append(base, old)
+	}
+	return lint.NewFindings(run(base), findings), nil
+}
+
+// gitChanged returns the paths, relative to the current directory, of
+// the files under it that differ from those of revision in the git
+// repository holding it, including the files git does not track yet.
+func gitChanged(revision string) ([]string, error) {
+	var paths []string
+	for _, args := range [][]string{
+		{"diff", "--name-only", "--relative", revision, "--"},
+		{"ls-files", "--others", "--exclude-standard"},
+	} {
+		out, err := exec.Command("git", args...).Output()
+		if err != nil {
+			var exitErr *exec.ExitError
+			if errors.As(err, &exitErr) && len(exitErr.Stderr) > 0 {
+				message, _, _ := strings.Cut(string(exitErr.Stderr), "\n")
+				return nil, fmt.Errorf("git %s: %s", args[0], message)
+			}
+			return nil, fmt.Errorf("git %s: %w", args[0], err)
+		}
+		paths = append(paths, diff.Files(out)...)
+	}
+	return paths, nil
+}
+
+// watchFiles prints the syntax errors of the files under paths in
+// dialect and the findings of the rules of linter, which may be nil, and
+// those of each file again when it changes, until interrupted. Every file
+// stays loaded, so that the rules see the classes all of them declare.
+func watchFiles(paths []string, dialect zscript.Dialect, linter *lint.Linter) error {
+	if len(paths) == 0 {
+		paths = []string{"."}
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	trees := map[string]*zscript.Tree{}
+	defer func() {
+		for _, tree := range trees {
+			tree.Close()
+		}
+	}()
+	counts := map[string]int{}
+	err := watch.New(paths...).Run(ctx, func(changes []watch.Change) error {
+		var changed []string
+		for _, change := range changes {
+			delete(counts, change.Path)
+			if tree := trees[change.Path]; tree != nil {
+				tree.Close()
+				delete(trees, change.Path)
+			}
+			if change.Removed {
+				continue
+			}
+			source, err := os.ReadFile(change.Path)
+			if err != nil {
+				// The file may be gone before it is read; the next scan
+				// reports that.
+				continue
+			}
+			tree, err := zscript.Parse(ctx, source)
+			if err != nil {
+				return err
+			}
+			tree.Path, tree.Dialect = change.Path, dialect
+			trees[change.Path] = tree
+			changed = append(changed, change.Path)
+		}
+
+		var findings []lint.Finding
+		for _, path := range changed {
+			findings = append(findings, lint.SyntaxErrors(trees[path])...)
+		}
+		if linter != nil && len(linter.Rules) > 0 && len(changed) > 0 {
+			all := make([]*zscript.Tree, 0, len(trees))
+			for _, tree := range trees {
+				all = append(all, tree)
+			}
+			findings = append(findings, linter.Only(changed, all...)...)
+		}
+		for _, f := range findings {
+			if f.Rule == lint.RuleSyntax {
+				p := f.Range.StartPoint
+				fmt.Printf("%s:%d:%d: %s\n", f.Path, p.Row+1, p.Column+1, f.Message)
+			} else {
+				fmt.Println(f)
+			}
+			counts[f.Path]++
+		}
+		total := 0
+		for _, n := range counts {
+			total += n
+		}
+		fmt.Fprintf(os.Stderr, "%s: %d changed, %d errors in %d files\n", time.Now().Format(time.TimeOnly), len(changes), total, len(counts))
+		return nil
+	})
+	if ctx.Err() != nil {
+		return nil
+	}
+	return err
+}
+
+func dump(args []string) int {
+	flags := newFlags("dump")
+	formatName := flags.String("format", "sexp", `output format: "sexp", "json", "ndjson" or "protobuf"`)
+	flags.Parse(args)
+
+	if *formatName == "protobuf" {
+		var failed error
+		err := eachFile(flags.Args(), func(tree *zscript.Tree, _ time.Duration) {
+			if err := zscriptpb.WriteDelimited(os.Stdout, zscriptpb.File(tree)); err != nil && failed == nil {
+				failed = err
+			}
+		})
+		if err == nil {
+			err = failed
+		}
+		return exit(err, 0)
+	}
+	formats := map[string]zscript.DumpFormat{"sexp": zscript.DumpSexp, "json": zscript.DumpJSON, "ndjson": zscript.DumpNDJSON}
+	format, ok := formats[*formatName]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "zscript: invalid -format %q\n", *formatName)
+		return 2
+	}
+	err := eachFile(flags.Args(), func(tree *zscript.Tree, _ time.Duration) {
+		os.Stdout.Write(zscript.Dump(tree.Tree, tree.Source, format))
+	})
+	return exit(err, 0)
+}
+
+func browse(args []string) int {
+	flags := newFlags("explore")
+	flags.Parse(args)
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return 2
+	}
+	source, err := os.ReadFile(flags.Arg(0))
+	if err != nil {
+		return exit(err, 1)
+	}
+	tree, err := zscript.Parse(context.Background(), source)
+	if err != nil {
+		return exit(err, 1)
+	}
+	defer tree.Close()
+	return exit(explore.New(tree).Run(os.Stdin, os.Stdout), 0)
+}
+
+func outline(args []string) int {
+	flags := newFlags("symbols")
+	flags.Parse(args)
+
+	err := eachFile(flags.Args(), func(tree *zscript.Tree, _ time.Duration) {
+		var write func(items []symbols.OutlineItem, indent string)
+		write = func(items []symbols.OutlineItem, indent string) {
+			for _, item := range items {
+				pos := item.SelectionRange.StartPoint
+				line := fmt.Sprintf("%s%s %s", indent, item.Kind, item.Name)
+				if item.Detail != "" {
+					line += " : " + item.Detail
+				}
+				fmt.Printf("%s:%d:%d: %s\n", tree.Path, pos.Row+1, pos.Column+1, line)
+				write(item.Children, indent+"  ")
+			}
+		}
+		write(symbols.Outline(tree), "")
+	})
+	return exit(err, 0)
+}
+
+func stats(args []string) int {
+	flags := newFlags("stats")
+	top := flags.Int("top", 10, "number of node kinds to list")
+	asJSON := flags.Bool("json", false, "print the project summary as JSON")
+	flags.Parse(args)
+
+	var nodes, bytes int
+	var trees []*zscript.Tree
+	var elapsed []time.Duration
+	kinds := map[string]int{}
+	err := eachFile(flags.Args(), func(tree *zscript.Tree, d time.Duration) {
+		bytes += len(tree.Source)
+		var v zscript.Visitor
+		v.Enter = func(node *tree_sitter.Node) zscript.WalkAction {
+			nodes++
+			if node.IsNamed() {
+				kinds[node.Kind()]++
+			}
+			return zscript.WalkContinue
+		}
+		zscript.Walk(tree.RootNode(), &v)
+		trees = append(trees, tree.Clone())
+		elapsed = append(elapsed, d)
+	})
+	defer func() {
+		for _, tree := range trees {
+			tree.Close()
+		}
+	}()
+	if err != nil {
+		return exit(err, 0)
+	}
+
+	s := metrics.Summarize(trees...)
+	for i, f := range s.Files {
+		f.ParseTime = elapsed[i]
+	}
+	if *asJSON {
+		return exit(s.WriteJSON(os.Stdout), 0)
+	}
+	if err := s.WriteText(os.Stdout); err != nil {
+		return exit(err, 0)
+	}
+
+	fmt.Printf("\nbytes:      %d\n", bytes)
+	fmt.Printf("nodes:      %d\n", nodes)
+	names := make([]string, 0, len(kinds))
+	for k := range kinds {
+		names = append(names, k)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if kinds[names[i]] != kinds[names[j]] {
+			return kinds[names[i]] > kinds[names[j]]
+		}
+		return names[i] < names[j]
+	})
+	for i, k := range names {
+		if i == *top {
+			break
+		}
+		fmt.Printf("  %-28s %d\n", k, kinds[k])
+	}
+	return 0
+}
+
+func convert(args []string) int {
+	flags := newFlags("decorate")
+	mapinfo := flags.Bool("mapinfo", false, "print a MAPINFO DoomEdNums block for the editor numbers")
+	flags.Parse(args)
+
+	type input struct {
+		path   string
+		source []byte
+	}
+	var inputs []input
+	if flags.NArg() == 0 {
+		source, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return exit(err, 0)
+		}
+		inputs = append(inputs, input{"<stdin>", source})
+	}
+	for _, path := range flags.Args() {
+		source, err := os.ReadFile(path)
+		if err != nil {
+			return exit(err, 0)
+		}
+		inputs = append(inputs, input{path, source})
+	}
+
+	var nums []decorate.DoomEdNum
+	for i, in := range inputs {
+		r, err := decorate.Convert(in.source)
+		if err != nil {
+			return exit(fmt.Errorf("%s: %w", in.path, err), 0)
+		}
+		for _, n := range r.Notes {
+			fmt.Fprintf(os.Stderr, "%s:%d: %s\n", in.path, n.Line, n.Message)
+		}
+		if i > 0 {
+			fmt.Println()
+		}
+		os.Stdout.Write(r.Source)
+		nums = append(nums, r.DoomEdNums...)
+	}
+	if *mapinfo {
+		r := decorate.Result{DoomEdNums: nums}
+		if block := r.MapInfo(); block != "" {
+			fmt.Print("\n/* MAPINFO:\n" + block + "*/\n")
+		}
+	}
+	return 0
+}
+
+func measure(args []string) int {
+	flags := newFlags("metrics")
+	asJSON := flags.Bool("json", false, "print the report as JSON")
+	var limits metrics.Limits
+	flags.IntVar(&limits.Complexity, "max-complexity", 0, "fail if a method's cyclomatic complexity is over `n`")
+	flags.IntVar(&limits.Nesting, "max-nesting", 0, "fail if a method nests control statements deeper than `n`")
+	flags.IntVar(&limits.Lines, "max-lines", 0, "fail if a method has more than `n` lines of code")
+	flags.IntVar(&limits.Methods, "max-methods", 0, "fail if a class has more than `n` methods")
+	flags.Parse(args)
+
+	r := &metrics.Report{}
+	err := eachFile(flags.Args(), func(tree *zscript.Tree, _ time.Duration) {
+		r.Add(tree)
+	})
+	if err != nil {
+		return exit(err, 0)
+	}
+	if *asJSON {
+		err = r.WriteJSON(os.Stdout)
+	} else {
+		err = r.WriteText(os.Stdout)
+	}
+	status := 0
+	for _, v := range r.Check(limits) {
+		fmt.Fprintln(os.Stderr, v)
+		status = 1
+	}
+	return exit(err, status)
+}
+
+func find(args []string) int {
+	flags := newFlags("search")
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: zscript search [flags] pattern [path ...]\n")
+		flags.PrintDefaults()
+	}
+	count := flags.Bool("c", false, "print only the number of matches")
+	flags.Parse(args)
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return 2
+	}
+	pattern, err := search.Compile(flags.Arg(0))
+	if err != nil {
+		return exit(err, 0)
+	}
+
+	matches := 0
+	err = eachFile(flags.Args()[1:], func(tree *zscript.Tree, _ time.Duration) {
+		for _, r := range pattern.Find(tree) {
+			matches++
+			if !*count {
+				pos := r.Node.StartPosition()
+				line, _, _ := strings.Cut(r.Node.Utf8Text(tree.Source), "\n")
+				fmt.Printf("%s:%d:%d: %s\n", tree.Path, pos.Row+1, pos.Column+1, line)
+			}
+		}
+	})
+	if *count {
+		fmt.Println(matches)
+	}
+	status := 0
+	if matches == 0 {
+		status = 1
+	}
+	return exit(err, status)
+}
+
+// ruleFlags collects the rules given by repeated -e flags.
+type ruleFlags []string
+
+func (r *ruleFlags) String() string     { return strings.Join(*r, "\n") }
+func (r *ruleFlags) Set(v string) error { *r = append(*r, v); return nil }
+
+func transform(args []string) int {
+	flags := newFlags("rewrite")
+	var exprs ruleFlags
+	flags.Var(&exprs, "e", "add the `rule` \"pattern => replacement\"; may be repeated")
+	file := flags.String("f", "", "read rules from `file`, one per line")
+	write := flags.Bool("w", false, "write the rewritten files instead of printing a diff")
+	flags.Parse(args)
+
+	text := strings.Join(exprs, "\n")
+	if *file != "" {
+		data, err := os.ReadFile(*file)
+		if err != nil {
+			return exit(err, 0)
+		}
+		text += "\n" + string(data)
+	}
+	rules, err := rewrite.ParseRules(text)
+	if err != nil {
+		return exit(err, 0)
+	}
+	if len(rules) == 0 {
+		flags.Usage()
+		return 2
+	}
+	rw, err := rewrite.New(rules...)
+	if err != nil {
+		return exit(err, 0)
+	}
+
+	status := 0
+	fail := func(err error) {
+		fmt.Fprintln(os.Stderr, "zscript:", err)
+		status = 1
+	}
+	err = eachFile(flags.Args(), func(tree *zscript.Tree, _ time.Duration) {
+		f, err := rw.Tree(tree)
+		switch {
+		case err != nil:
+			fail(err)
+		case f == nil:
+		case !*write:
+			os.Stdout.Write(f.Diff())
+		case tree.Path == "<stdin>":
+			os.Stdout.Write(f.New)
+		default:
+			if err := os.WriteFile(tree.Path, f.New, 0o666); err != nil {
+				fail(err)
+			}
+		}
+	})
+	return exit(err, status)
+}
+
+func exit(err error, status int) int {
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "zscript:", err)
+		return 1
+	}
+	return status
+}
+
+// loadProject loads the project of dir as its configuration file, if it
+// has one, describes it.
+func loadProject(dir string) (*project.Project, error) {
+	cfg, err := config.Find(dir)
+	if err != nil {
+		return nil, err
+	}
+	return cfg.LoadProject(context.Background())
+}
+
+// eachFile parses each file named by paths, searching directories, and
+// calls fn with the tree and the time parsing took. With no paths it
+// parses standard input.
+func eachFile(paths []string, fn func(tree *zscript.Tree, elapsed time.Duration)) error {
+	parse := func(path string, source []byte) error {
+		start := time.Now()
+		tree, err := zscript.Parse(context.Background(), source)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		defer tree.Close()
+		tree.Path = path
+		fn(tree, time.Since(start))
+		return nil
+	}
+
+	if len(paths) == 0 {
+		source, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return err
+		}
+		return parse("<stdin>", source)
+	}
+	for _, root := range paths {
+		err := filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if entry.IsDir() {
+				if path != root && strings.HasPrefix(entry.Name(), ".") {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if path != root && !watch.IsSource(path) {
+				return nil
+			}
+			source, err := os.ReadFile(path)
+			if err != nil {
+				return err
+			}
+			return parse(path, source)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+// Command zscriptd serves the analyses of package daemon for the mod in a
+// directory, the current one by default, keeping its files parsed between
+// requests. It communicates over standard input and output or, with
+// -listen, accepts connections on a Unix socket, if the address contains
+// a slash, or on a TCP address such as localhost:7777, until interrupted.
+// The daemon does not authenticate its clients, and reads and formats the
+// files of the mod for anyone who connects, so a TCP address must be on
+// the loopback interface: localhost or a loopback IP address.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"strings"
+
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/daemon"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/watch"
+)
+
+var (
+	listen  = flag.String("listen", "", "accept connections on this Unix socket or loopback TCP address instead of using standard input and output")
+	timeout = flag.Duration("parse-timeout", daemon.DefaultParseTimeout, "give up parsing a file after this long (0 for no limit)")
+	scan    = flag.Duration("scan-interval", watch.DefaultInterval, "look for changes on disk at most this often (0 before every request)")
+)
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: zscriptd [-listen address] [dir]\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	dir := "."
+	switch flag.NArg() {
+	case 0:
+	case 1:
+		dir = flag.Arg(0)
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	server, err := daemon.NewServer(dir)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "zscriptd:", err)
+		os.Exit(2)
+	}
+	defer server.Close()
+	server.ParseTimeout = *timeout
+	server.ScanInterval = *scan
+
+	if *listen == "" {
+		err = server.Serve(context.Background(), os.Stdin, os.Stdout)
+	} else {
+		err = serve(server, *listen)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "zscriptd:", err)
+		os.Exit(1)
+	}
+}
+
+// serve serves the connections to address until interrupted.
+func serve(server *daemon.Server, address string) error {
+	network := "tcp"
+	if !strings.Contains(address, "/") {
+		if err := loopback(address); err != nil {
+			return err
+		}
+	} else {
+		network = "unix"
+		// A socket left by a daemon that did not exit cleanly would make
+		// Listen fail.
+		if info, err := os.Stat(address); err == nil && info.Mode()&os.ModeSocket != 0 {
+			os.Remove(address)
+		}
+	}
+	l, err := net.Listen(network, address)
+	if err != nil {
+		return err
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	if err := server.ServeListener(ctx, l); err != nil && ctx.Err() == nil {
+		return err
+	}
+	return nil
+}
+
+// loopback returns an error unless the TCP address is on the loopback
+// interface.
+func loopback(address string) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	if ip := net.ParseIP(host); host != "localhost" && (ip == nil || !ip.IsLoopback()) {
+		return fmt.Errorf("%s is not a loopback address; the daemon does not authenticate its clients", address)
+	}
+	return nil
+}
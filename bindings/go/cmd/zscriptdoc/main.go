@@ -0,0 +1,73 @@
+// Command zscriptdoc generates API documentation for a ZScript mod from
+// the documentation comments in its source.
+//
+// Usage:
+//
+//	zscriptdoc [flags] path
+//
+// The path is a directory, PK3 or WAD containing a ZSCRIPT lump.
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/archive"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/docs"
+)
+
+var (
+	htmlOutput = flag.Bool("html", false, "write HTML instead of Markdown")
+	title      = flag.String("title", "", "document title")
+	output     = flag.String("o", "", "write to this file instead of stdout")
+)
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: zscriptdoc [flags] path\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	if err := run(flag.Arg(0)); err != nil {
+		fmt.Fprintln(os.Stderr, "zscriptdoc:", err)
+		os.Exit(1)
+	}
+}
+
+func run(path string) error {
+	p, err := archive.LoadProject(context.Background(), path)
+	if err != nil {
+		return err
+	}
+	defer p.Close()
+	for _, d := range p.Diagnostics {
+		fmt.Fprintln(os.Stderr, d)
+	}
+
+	out := os.Stdout
+	if *output != "" {
+		if out, err = os.Create(*output); err != nil {
+			return err
+		}
+		defer out.Close()
+	}
+	w := bufio.NewWriter(out)
+	pkg := docs.FromProject(p)
+	opts := docs.Options{Title: *title}
+	if *htmlOutput {
+		err = docs.WriteHTML(w, pkg, opts)
+	} else {
+		err = docs.WriteMarkdown(w, pkg, opts)
+	}
+	if err != nil {
+		return err
+	}
+	return w.Flush()
+}
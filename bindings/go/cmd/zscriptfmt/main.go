@@ -0,0 +1,128 @@
+// Command zscriptfmt formats ZScript source files.
+//
+// Usage:
+//
+//	zscriptfmt [flags] [path ...]
+//
+// With no paths, it formats standard input to standard output. The style
+// is that of the .zscriptrc or zscript.toml file of the mod holding each
+// file, described in package config, with the flags given overriding it.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/config"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/format"
+)
+
+var (
+	write    = flag.Bool("w", false, "write result to source file instead of stdout")
+	list     = flag.Bool("l", false, "list files whose formatting differs")
+	spaces   = flag.Int("spaces", 0, "indent with this many spaces instead of tabs")
+	brace    = flag.String("brace", "same", `opening brace placement: "same" or "next" line`)
+	noAlign  = flag.Bool("noalign", false, "do not align columns in States blocks")
+	exitCode = 0
+)
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: zscriptfmt [flags] [path ...]\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if *brace != "same" && *brace != "next" {
+		fmt.Fprintf(os.Stderr, "zscriptfmt: invalid -brace %q\n", *brace)
+		os.Exit(2)
+	}
+
+	if flag.NArg() == 0 {
+		if *write {
+			fmt.Fprintln(os.Stderr, "zscriptfmt: cannot use -w with standard input")
+			os.Exit(2)
+		}
+		src, err := io.ReadAll(os.Stdin)
+		var opts format.Options
+		if err == nil {
+			opts, err = options(".")
+		}
+		if err == nil {
+			err = process("<stdin>", src, opts)
+		}
+		report(err)
+	}
+	for _, path := range flag.Args() {
+		src, err := os.ReadFile(path)
+		var opts format.Options
+		if err == nil {
+			opts, err = options(filepath.Dir(path))
+		}
+		if err == nil {
+			err = process(path, src, opts)
+		}
+		report(err)
+	}
+	os.Exit(exitCode)
+}
+
+// options returns the options of the configuration file of the mod
+// holding dir, overridden by the flags given.
+func options(dir string) (format.Options, error) {
+	cfg, err := config.Find(dir)
+	if err != nil {
+		return format.Options{}, err
+	}
+	opts := cfg.Format
+	flag.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "spaces":
+			// -spaces 0 indents with tabs.
+			opts.UseSpaces = *spaces > 0
+			if *spaces > 0 {
+				opts.IndentWidth = *spaces
+			}
+		case "brace":
+			opts.BraceStyle = format.BraceSameLine
+			if *brace == "next" {
+				opts.BraceStyle = format.BraceNextLine
+			}
+		case "noalign":
+			opts.AlignStates = !*noAlign
+		}
+	})
+	return opts, nil
+}
+
+func process(path string, src []byte, opts format.Options) error {
+	out, err := format.Source(src, opts)
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	changed := !bytes.Equal(src, out)
+	if *list && changed {
+		fmt.Println(path)
+	}
+	if *write {
+		if changed {
+			return os.WriteFile(path, out, 0o644)
+		}
+		return nil
+	}
+	if !*list {
+		_, err = os.Stdout.Write(out)
+	}
+	return err
+}
+
+func report(err error) {
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		exitCode = 1
+	}
+}
@@ -0,0 +1,288 @@
+// Package codeaction gathers the changes an editor offers for a selection
+// in a file: the fixes of lint findings there, refactorings such as
+// renaming the declaration there or extracting the selected statements
+// into a method, and generated code. The actions are shaped after the
+// code actions of the Language Server Protocol, with kinds such as
+// "quickfix" and "refactor.extract", and each carries the edits that
+// perform it.
+//
+// Each source of actions is a Provider; Actions asks a set of them.
+package codeaction
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+
+	zscript "github.com/jlcrochet/tree-sitter-zscript/bindings/go"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/lint"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/project"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/refactor"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/resolve"
+)
+
+// Kind is the kind of an action, a code action kind of the Language
+// Server Protocol: a dot-separated hierarchy of names.
+type Kind string
+
+// The kinds of action offered by the providers of this package.
+const (
+	QuickFix        Kind = "quickfix"
+	Refactor        Kind = "refactor"
+	RefactorExtract Kind = "refactor.extract"
+	Source          Kind = "source"
+)
+
+// Contains reports whether k is kind or one of its sub-kinds, as
+// "refactor.extract" is of "refactor".
+func (k Kind) Contains(kind Kind) bool {
+	return kind == k || strings.HasPrefix(string(kind), string(k)+".")
+}
+
+// Action is a change offered for a selection.
+type Action struct {
+	// Title describes the change, such as "Add override".
+	Title string
+	Kind  Kind
+	// Finding is the lint finding the action fixes, or nil.
+	Finding *lint.Finding
+	// Preferred is set on the action an editor may apply without asking,
+	// such as the only fix of a finding.
+	Preferred bool
+	// Edits perform the change; they may span several files.
+	Edits []refactor.Edit
+}
+
+// Request describes the selection actions are wanted for.
+type Request struct {
+	Project *project.Project
+	// Path is the file of Project holding the selection.
+	Path string
+	// Start and End are the byte offsets of the selection; they are equal
+	// for a cursor.
+	Start, End uint
+	// NewName is the name for actions that rename a declaration or
+	// create one, as an editor asks the user for it before applying them.
+	// Renames are not offered without one; new declarations are given a
+	// name of their own.
+	NewName string
+	// Only, if not empty, limits the actions to those of these kinds and
+	// their sub-kinds.
+	Only []Kind
+}
+
+// File returns the file of r.Path, or nil.
+func (r *Request) File() *project.File {
+	return r.Project.File(r.Path)
+}
+
+// wants reports whether r asks for actions of kind.
+func (r *Request) wants(kind Kind) bool {
+	if len(r.Only) == 0 {
+		return true
+	}
+	for _, k := range r.Only {
+		if k.Contains(kind) {
+			return true
+		}
+	}
+	return false
+}
+
+// Provider offers actions.
+type Provider interface {
+	// Actions returns the actions for the selection of req.
+	Actions(req *Request) []Action
+}
+
+// ProviderFunc is a Provider that is a function.
+type ProviderFunc func(req *Request) []Action
+
+// Actions calls f.
+func (f ProviderFunc) Actions(req *Request) []Action {
+	return f(req)
+}
+
+// DefaultProviders returns the providers of this package: the fixes of
+// the built-in lint rules, renames, method extraction and override stubs.
+func DefaultProviders() []Provider {
+	return []Provider{Fixes(nil), ProviderFunc(Rename), ProviderFunc(ExtractMethod), ProviderFunc(Overrides)}
+}
+
+// Actions returns the actions that providers, or the default providers if
+// none are given, offer for the selection of req, leaving out those of
+// kinds req does not ask for. Actions are ordered by provider, quick fixes
+// first.
+func Actions(req *Request, providers ...Provider) []Action {
+	if req.File() == nil {
+		return nil
+	}
+	if len(providers) == 0 {
+		providers = DefaultProviders()
+	}
+	var actions []Action
+	for _, p := range providers {
+		for _, a := range p.Actions(req) {
+			if req.wants(a.Kind) {
+				actions = append(actions, a)
+			}
+		}
+	}
+	sort.SliceStable(actions, func(i, j int) bool {
+		return QuickFix.Contains(actions[i].Kind) && !QuickFix.Contains(actions[j].Kind)
+	})
+	return actions
+}
+
+// Fixes returns a provider of the fixes of the findings of linter, or of a
+// linter running the built-in rules if it is nil, whose ranges overlap
+// the selection. The project is linted on every request.
+func Fixes(linter *lint.Linter) Provider {
+	if linter == nil {
+		linter = lint.New()
+	}
+	return ProviderFunc(func(req *Request) []Action {
+		if !req.wants(QuickFix) {
+			return nil
+		}
+		var actions []Action
+		for _, f := range linter.Project(req.Project) {
+			if f.Fix == nil || f.Path != req.File().Path || !overlaps(f.Range, req.Start, req.End) {
+				continue
+			}
+			f := f
+			a := Action{Title: capitalize(f.Fix.Message), Kind: QuickFix, Finding: &f, Preferred: true}
+			for _, e := range f.Fix.Edits {
+				a.Edits = append(a.Edits, refactor.Edit{Path: f.Path, Range: e.Range, NewText: e.NewText})
+			}
+			actions = append(actions, a)
+		}
+		return actions
+	})
+}
+
+// Rename offers to rename the declaration of the name at the start of the
+// selection, and every use of it, to req.NewName.
+func Rename(req *Request) []Action {
+	if req.NewName == "" || !req.wants(Refactor) {
+		return nil
+	}
+	f := req.File()
+	node := f.Tree.RootNode().NamedDescendantForByteRange(req.Start, req.Start)
+	if node == nil {
+		return nil
+	}
+	switch node.Kind() {
+	case zscript.NodeIdentifier, zscript.NodeTypeIdentifier, zscript.NodeFieldIdentifier:
+	default:
+		return nil
+	}
+	decl, ok := resolve.ForProject(req.Project).Resolve(f.Tree, node)
+	if !ok || req.Project.File(decl.Path) == nil {
+		return nil
+	}
+	edits, err := refactor.Rename(req.Project, decl, req.NewName)
+	if err != nil || len(edits) == 0 {
+		return nil
+	}
+	return []Action{{Title: fmt.Sprintf("Rename %s %s to %s", decl.Kind, decl.Name, req.NewName), Kind: Refactor, Edits: edits}}
+}
+
+// ExtractMethod offers to extract the selected statements into a method of
+// their class, named req.NewName or, without one, "Extracted" with the
+// first number that makes the name unique in the class.
+func ExtractMethod(req *Request) []Action {
+	if req.Start == req.End || !req.wants(RefactorExtract) {
+		return nil
+	}
+	name := req.NewName
+	for i := 1; name == ""; i++ {
+		name = "Extracted"
+		if i > 1 {
+			name += fmt.Sprint(i)
+		}
+		if taken(req, name) {
+			name = ""
+		}
+	}
+	edits, err := refactor.ExtractMethod(req.Project, req.Path, req.Start, req.End, name)
+	if err != nil {
+		return nil
+	}
+	return []Action{{Title: "Extract method " + name, Kind: RefactorExtract, Edits: edits}}
+}
+
+// Overrides offers, when the selection starts in the head of a class
+// before its opening brace, to override each virtual method the class
+// inherits, those of the project's classes first.
+func Overrides(req *Request) []Action {
+	if !req.wants(Source) {
+		return nil
+	}
+	f := req.File()
+	class := f.Tree.RootNode().NamedDescendantForByteRange(req.Start, req.Start)
+	for class != nil && class.Kind() != zscript.NodeClassDefinition {
+		class = class.Parent()
+	}
+	if class == nil {
+		return nil
+	}
+	for i := uint(0); i < class.ChildCount(); i++ {
+		if c := class.Child(i); c.Kind() == "{" && req.Start > c.StartByte() {
+			return nil
+		}
+	}
+	name := class.ChildByFieldName(zscript.FieldName).Utf8Text(f.Tree.Source)
+	var own, engine []Action
+	for _, decl := range refactor.Overridable(req.Project, name) {
+		edits, err := refactor.OverrideStubs(req.Project, req.Path, name, decl.Name)
+		if err != nil {
+			continue
+		}
+		a := Action{Title: fmt.Sprintf("Override %s.%s", decl.Owner, decl.Name), Kind: Source, Edits: edits}
+		if req.Project.File(decl.Path) != nil {
+			own = append(own, a)
+		} else {
+			engine = append(engine, a)
+		}
+	}
+	return append(own, engine...)
+}
+
+// taken reports whether the class around the selection of req, or one of
+// its ancestors, has a member with the name.
+func taken(req *Request, name string) bool {
+	f := req.File()
+	node := f.Tree.RootNode().NamedDescendantForByteRange(req.Start, req.Start)
+	for node != nil && node.Kind() != zscript.NodeClassDefinition {
+		node = node.Parent()
+	}
+	if node == nil {
+		return false
+	}
+	r := resolve.ForProject(req.Project)
+	for _, decl := range r.Members(node.ChildByFieldName(zscript.FieldName).Utf8Text(f.Tree.Source), false) {
+		if strings.EqualFold(decl.Name, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// overlaps reports whether r overlaps the range from start to end, or, if
+// that is empty, contains start.
+func overlaps(r tree_sitter.Range, start, end uint) bool {
+	if start == end {
+		return r.StartByte <= start && start <= r.EndByte
+	}
+	return r.StartByte < end && start < r.EndByte
+}
+
+func capitalize(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToUpper(s[:1]) + s[1:]
+}
@@ -0,0 +1,127 @@
+package codeaction_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/codeaction"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/project"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/refactor"
+)
+
+const source = `class Imp : Actor {
+	int rage;
+	void Tick() {
+		rage++;
+		A_Log("grr");
+	}
+	void Extracted() {}
+}
+`
+
+func load(t *testing.T) *project.Project {
+	t.Helper()
+	p, err := project.Load(context.Background(), fstest.MapFS{"zscript.zs": {Data: []byte(source)}}, "zscript.zs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(p.Close)
+	return p
+}
+
+func request(p *project.Project, from, to string) *codeaction.Request {
+	start := strings.Index(source, from)
+	end := start
+	if to != "" {
+		end = strings.Index(source[start:], to) + start + len(to)
+	}
+	return &codeaction.Request{Project: p, Path: "zscript.zs", Start: uint(start), End: uint(end)}
+}
+
+func titles(actions []codeaction.Action) string {
+	var t []string
+	for _, a := range actions {
+		t = append(t, string(a.Kind)+": "+a.Title)
+	}
+	return strings.Join(t, "\n")
+}
+
+func TestQuickFix(t *testing.T) {
+	p := load(t)
+	actions := codeaction.Actions(request(p, "Tick", ""))
+	if got, want := titles(actions), "quickfix: Add override"; got != want {
+		t.Fatalf("got\n%s\nwant\n%s", got, want)
+	}
+	a := actions[0]
+	if a.Finding == nil || a.Finding.Rule != "missing-override" || !a.Preferred {
+		t.Errorf("action = %+v", a)
+	}
+	if got := string(refactor.Apply([]byte(source), "zscript.zs", a.Edits)); !strings.Contains(got, "override void Tick()") {
+		t.Errorf("fixed:\n%s", got)
+	}
+}
+
+func TestExtract(t *testing.T) {
+	p := load(t)
+	req := request(p, "rage++", `"grr");`)
+	actions := codeaction.Actions(req)
+	if got, want := titles(actions), "refactor.extract: Extract method Extracted2"; got != want {
+		t.Fatalf("got\n%s\nwant\n%s", got, want)
+	}
+	want := "\t\tExtracted2();\n\t}\n\tvoid Extracted() {}\n\n\tvoid Extracted2() {\n\t\trage++;\n\t\tA_Log(\"grr\");\n\t}\n}\n"
+	if got := string(refactor.Apply([]byte(source), "zscript.zs", actions[0].Edits)); !strings.HasSuffix(got, want) {
+		t.Errorf("got\n%s\nwant suffix\n%s", got, want)
+	}
+
+	req.Only = []codeaction.Kind{codeaction.QuickFix}
+	if actions := codeaction.Actions(req); len(actions) != 0 {
+		t.Errorf("quick fixes only = %s", titles(actions))
+	}
+}
+
+func TestRename(t *testing.T) {
+	p := load(t)
+	req := request(p, "rage++", "")
+	if actions := codeaction.Actions(req, codeaction.ProviderFunc(codeaction.Rename)); len(actions) != 0 {
+		t.Errorf("rename without a name = %s", titles(actions))
+	}
+	req.NewName = "fury"
+	req.Only = []codeaction.Kind{codeaction.Refactor}
+	actions := codeaction.Actions(req)
+	if got, want := titles(actions), "refactor: Rename field rage to fury"; got != want {
+		t.Fatalf("got\n%s\nwant\n%s", got, want)
+	}
+	if got := string(refactor.Apply([]byte(source), "zscript.zs", actions[0].Edits)); strings.Count(got, "fury") != 2 {
+		t.Errorf("renamed:\n%s", got)
+	}
+}
+
+func TestOverrides(t *testing.T) {
+	p := load(t)
+	actions := codeaction.Actions(request(p, "Imp", ""), codeaction.ProviderFunc(codeaction.Overrides))
+	if len(actions) == 0 || actions[0].Kind != codeaction.Source {
+		t.Fatalf("actions = %s", titles(actions))
+	}
+	// Imp declares Tick, which is not offered.
+	var begin *codeaction.Action
+	for i, a := range actions {
+		if a.Title == "Override Actor.Tick" {
+			t.Errorf("Tick offered")
+		}
+		if a.Title == "Override Actor.BeginPlay" {
+			begin = &actions[i]
+		}
+	}
+	if begin == nil {
+		t.Fatalf("no override of BeginPlay in\n%s", titles(actions))
+	}
+	want := "\tvoid Extracted() {}\n\n\toverride void BeginPlay() {\n\t\tSuper.BeginPlay();\n\t}\n}\n"
+	if got := string(refactor.Apply([]byte(source), "zscript.zs", begin.Edits)); !strings.HasSuffix(got, want) {
+		t.Errorf("got\n%s\nwant suffix\n%s", got, want)
+	}
+	if actions := codeaction.Actions(request(p, "rage++", ""), codeaction.ProviderFunc(codeaction.Overrides)); len(actions) != 0 {
+		t.Errorf("actions in a method = %s", titles(actions))
+	}
+}
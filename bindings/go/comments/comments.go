@@ -0,0 +1,215 @@
+// Package comments attaches the comments of a parse tree to the
+// declarations and statements they describe.
+//
+// Tree-sitter parses comments as extras that may appear anywhere, often
+// inside the node before the one they describe, as a comment above a
+// state label ends up in the previous label's body. Attach places each
+// comment by where it is written instead:
+//
+//   - A comment that starts on the line where the code before it ends
+//     trails that code.
+//   - Otherwise it leads the declaration or statement that starts after
+//     it, unless that code begins inside an enclosing node that started
+//     before the comment, as for a comment between two arguments.
+//   - A comment that neither trails nor leads code, such as one at the
+//     end of a block, is inside the nearest enclosing declaration or
+//     statement.
+package comments
+
+import (
+	"strings"
+
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+
+	zscript "github.com/jlcrochet/tree-sitter-zscript/bindings/go"
+)
+
+// Placement is where a comment lies relative to its owner.
+type Placement int
+
+const (
+	// Leading comments come before their owner.
+	Leading Placement = iota
+	// Trailing comments follow their owner on the line it ends.
+	Trailing
+	// Inner comments are inside their owner but do not lead or trail any
+	// of its declarations or statements.
+	Inner
+)
+
+var placementNames = [...]string{"leading", "trailing", "inner"}
+
+func (p Placement) String() string {
+	return placementNames[p]
+}
+
+// Comment is a comment and the node it is attached to.
+type Comment struct {
+	Node *tree_sitter.Node
+	// Owner is the declaration or statement the comment is attached to.
+	// It is nil for an inner comment outside every declaration, such as
+	// one at the end of the file.
+	Owner     *tree_sitter.Node
+	Placement Placement
+	// Detached is set for a leading comment followed by a blank line,
+	// which separates it from its owner's documentation.
+	Detached bool
+}
+
+// Map is the attachment of every comment of a tree.
+type Map struct {
+	// Comments are in source order.
+	Comments []Comment
+
+	byOwner   map[uintptr][]int
+	byComment map[uintptr]int
+	source    []byte
+}
+
+// Attachable reports whether comments can attach to nodes of kind: the
+// kinds of declarations, statements and lines of Default and States
+// blocks.
+func Attachable(kind string) bool {
+	switch kind {
+	case zscript.NodeClassDefinition, zscript.NodeStructDefinition, zscript.NodeEnumDefinition,
+		zscript.NodeEnumerator, zscript.NodeConstDefinition, zscript.NodeStaticConstArray,
+		zscript.NodeMethodDefinition, zscript.NodeFunctionDefinition, zscript.NodeFieldDeclaration,
+		zscript.NodePropertyDefinition, zscript.NodeFlagDefinition, zscript.NodeMixinStatement,
+		zscript.NodeIncludeDirective, zscript.NodeDeclaration,
+		zscript.NodeDefaultBlock, zscript.NodeDefaultProperty, zscript.NodeFlagStatement,
+		zscript.NodeStatesBlock, zscript.NodeStateLabel, zscript.NodeStateLine, zscript.NodeStateFlow:
+		return true
+	}
+	return strings.HasSuffix(kind, "_statement") || strings.HasSuffix(kind, "_directive")
+}
+
+// Attach attaches the comments under root, a node of a tree parsed from
+// source.
+func Attach(root *tree_sitter.Node, source []byte) *Map {
+	m := &Map{byOwner: map[uintptr][]int{}, byComment: map[uintptr]int{}, source: source}
+	var v zscript.Visitor
+	v.On(zscript.NodeComment, func(node *tree_sitter.Node) zscript.WalkAction {
+		m.Comments = append(m.Comments, place(node))
+		return zscript.WalkSkipChildren
+	})
+	zscript.Walk(root, &v)
+
+	for i := range m.Comments {
+		c := &m.Comments[i]
+		m.byComment[c.Node.Id()] = i
+		if c.Owner != nil {
+			m.byOwner[c.Owner.Id()] = append(m.byOwner[c.Owner.Id()], i)
+		}
+		if c.Placement != Leading {
+			continue
+		}
+		// The line after a leading comment starts the next comment of the
+		// run, or the owner.
+		next := c.Owner.StartPosition().Row
+		if i+1 < len(m.Comments) {
+			if n := m.Comments[i+1]; n.Placement == Leading && n.Owner.Id() == c.Owner.Id() {
+				next = n.Node.StartPosition().Row
+			}
+		}
+		c.Detached = next > c.Node.EndPosition().Row+1
+	}
+	return m
+}
+
+// place attaches the comment node.
+func place(node *tree_sitter.Node) Comment {
+	c := Comment{Node: node, Placement: Inner, Owner: enclosing(node.Parent())}
+	if prev := sibling(node, (*tree_sitter.Node).PrevSibling); prev != nil && prev.EndPosition().Row == node.StartPosition().Row {
+		if owner := enclosing(prev); owner != nil {
+			c.Owner, c.Placement = owner, Trailing
+		}
+		return c
+	}
+	if next := sibling(node, (*tree_sitter.Node).NextSibling); next != nil {
+		if owner := enclosing(next); owner != nil && owner.StartByte() >= node.EndByte() {
+			c.Owner, c.Placement = owner, Leading
+		}
+	}
+	return c
+}
+
+// sibling returns the nearest node before or after node, as step moves,
+// that is not a comment. When node is the first or last child of its
+// parent, the search continues from the parent.
+func sibling(node *tree_sitter.Node, step func(*tree_sitter.Node) *tree_sitter.Node) *tree_sitter.Node {
+	for n := node; n != nil; n = n.Parent() {
+		for s := step(n); s != nil; s = step(s) {
+			if s.Kind() != zscript.NodeComment {
+				return s
+			}
+		}
+	}
+	return nil
+}
+
+// enclosing returns the nearest attachable node that is n or contains
+// it, or nil.
+func enclosing(n *tree_sitter.Node) *tree_sitter.Node {
+	for ; n != nil; n = n.Parent() {
+		if Attachable(n.Kind()) {
+			return n
+		}
+	}
+	return nil
+}
+
+// Of returns the attachment of the comment node.
+func (m *Map) Of(comment *tree_sitter.Node) (Comment, bool) {
+	i, ok := m.byComment[comment.Id()]
+	if !ok {
+		return Comment{}, false
+	}
+	return m.Comments[i], true
+}
+
+// Attached returns the comments attached to node with the placement p, in
+// source order.
+func (m *Map) Attached(node *tree_sitter.Node, p Placement) []Comment {
+	var result []Comment
+	for _, i := range m.byOwner[node.Id()] {
+		if m.Comments[i].Placement == p {
+			result = append(result, m.Comments[i])
+		}
+	}
+	return result
+}
+
+// Doc returns the documentation comment of node: the text of the leading
+// comments after the last detached one, with the markers removed by Text.
+func (m *Map) Doc(node *tree_sitter.Node) string {
+	leading := m.Attached(node, Leading)
+	for i := len(leading) - 1; i >= 0; i-- {
+		if leading[i].Detached {
+			leading = leading[i+1:]
+			break
+		}
+	}
+	parts := make([]string, len(leading))
+	for i, c := range leading {
+		parts[i] = Text(c.Node.Utf8Text(m.source))
+	}
+	return strings.TrimSpace(strings.Join(parts, "\n"))
+}
+
+// Text removes the comment markers from comment, a leading "*" from the
+// lines of a block comment, and one space after each marker.
+func Text(comment string) string {
+	if text, ok := strings.CutPrefix(comment, "//"); ok {
+		text = strings.TrimLeft(text, "/")
+		return strings.TrimSuffix(strings.TrimPrefix(text, " "), "\r")
+	}
+	text := strings.TrimSuffix(strings.TrimPrefix(comment, "/*"), "*/")
+	text = strings.TrimLeft(text, "*")
+	lines := strings.Split(text, "\n")
+	for i, l := range lines {
+		l = strings.TrimSpace(l)
+		l = strings.TrimPrefix(l, "*")
+		lines[i] = strings.TrimPrefix(l, " ")
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
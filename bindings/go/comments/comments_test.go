@@ -0,0 +1,103 @@
+package comments_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	zscript "github.com/jlcrochet/tree-sitter-zscript/bindings/go"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/comments"
+)
+
+const source = `// License header.
+
+// A friendly imp.
+class Imp : Actor {
+	int anger; // Trailing.
+	/* Block. */
+	void Rage() {
+		// Leading a statement.
+		anger++;
+		Call(1, // Inside a call.
+			2);
+		// End of the block.
+	}
+	States {
+	Spawn:
+		TNT1 A 1;
+		Loop;
+	// Above the label.
+	See:
+		TNT1 A 1; // After a state.
+		Stop;
+	}
+}
+// End of the file.
+`
+
+func TestAttach(t *testing.T) {
+	tree, err := zscript.Parse(context.Background(), []byte(source))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tree.Close()
+	m := comments.Attach(tree.RootNode(), tree.Source)
+
+	want := []string{
+		"// License header. leading class_definition 4 detached",
+		"// A friendly imp. leading class_definition 4",
+		"// Trailing. trailing field_declaration 5",
+		"/* Block. */ leading method_definition 7",
+		"// Leading a statement. leading expression_statement 9",
+		"// Inside a call. trailing expression_statement 10",
+		"// End of the block. inner compound_statement 7",
+		"// Above the label. leading state_label 19",
+		"// After a state. trailing state_line 20",
+		"// End of the file. inner <nil> 0",
+	}
+	if len(m.Comments) != len(want) {
+		t.Fatalf("got %d comments, want %d", len(m.Comments), len(want))
+	}
+	for i, c := range m.Comments {
+		owner, row := "<nil>", uint(0)
+		if c.Owner != nil {
+			owner, row = c.Owner.Kind(), c.Owner.StartPosition().Row+1
+		}
+		got := fmt.Sprintf("%s %s %s %d", c.Node.Utf8Text(tree.Source), c.Placement, owner, row)
+		if c.Detached {
+			got += " detached"
+		}
+		if got != want[i] {
+			t.Errorf("comment %d = %q, want %q", i, got, want[i])
+		}
+		if of, ok := m.Of(c.Node); !ok || of.Node.Id() != c.Node.Id() {
+			t.Errorf("Of(%s) = %v, %v", c.Node.Utf8Text(tree.Source), of, ok)
+		}
+	}
+
+	class := m.Comments[0].Owner
+	if got := m.Doc(class); got != "A friendly imp." {
+		t.Errorf("Doc(class) = %q", got)
+	}
+	if got := len(m.Attached(class, comments.Leading)); got != 2 {
+		t.Errorf("Attached(class, Leading) has %d comments", got)
+	}
+	if got := m.Doc(m.Comments[2].Owner); got != "" {
+		t.Errorf("Doc(anger) = %q", got)
+	}
+}
+
+func TestText(t *testing.T) {
+	tests := []struct{ comment, want string }{
+		{"// Line.", "Line."},
+		{"/// Triple.", "Triple."},
+		{"//No space.", "No space."},
+		{"/* Block. */", "Block."},
+		{"/**\n * First.\n * Second.\n */", "First.\nSecond."},
+	}
+	for _, tt := range tests {
+		if got := comments.Text(tt.comment); got != tt.want {
+			t.Errorf("Text(%q) = %q, want %q", tt.comment, got, tt.want)
+		}
+	}
+}
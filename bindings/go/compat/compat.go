@@ -0,0 +1,232 @@
+// Package compat finds what a mod takes from GZDoom versions newer than
+// the oldest one it means to run on: the engine functions, fields,
+// classes and syntax its code uses that the targeted version lacks, and
+// the version its code really requires.
+//
+// Some engine functions replaced an older one that takes its leading
+// arguments the same way, as A_StartSound replaced A_PlaySound; for those
+// of class Actor, package compat writes shims, methods of a mixin class
+// that call the older function, and suggests that the calls be changed to
+// call the shims instead. GZDoom has no way to compile code for one
+// version only, so the other uses can only be avoided, or the targeted
+// version raised.
+package compat
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+
+	zscript "github.com/jlcrochet/tree-sitter-zscript/bindings/go"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/deprecations"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/engine"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/symbols"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/version"
+)
+
+// Prefix is the prefix of the names of shims, as in Compat_A_StartSound.
+const Prefix = "Compat_"
+
+// Use is a use of something the targeted version lacks.
+type Use struct {
+	Path  string
+	Range tree_sitter.Range
+	// What is what is used: a name as it is spelled, a syntax or a
+	// version("x") qualifier, as version.Problem has it.
+	What string
+	// Needs is the version that introduced it.
+	Needs version.Version
+	// Shim is the shim that can stand in for it, or nil if there is none.
+	Shim *Shim
+}
+
+// Suggestion says what to do about u.
+func (u *Use) Suggestion() string {
+	if u.Shim != nil {
+		return fmt.Sprintf("call %s instead, which calls %s", u.Shim.Name, u.Shim.Calls)
+	}
+	return fmt.Sprintf("avoid it, or target GZDoom %s", u.Needs)
+}
+
+func (u *Use) String() string {
+	p := u.Range.StartPoint
+	return fmt.Sprintf("%s:%d:%d: %s needs GZDoom %s; %s", u.Path, p.Row+1, p.Column+1, u.What, u.Needs, u.Suggestion())
+}
+
+// Shim is a method that stands in for an engine function of a newer
+// version, calling the older function it replaced.
+type Shim struct {
+	// Name is the name of the shim: Prefix followed by that of Function.
+	Name string
+	// Function is the engine function the shim stands in for, declared
+	// since Needs.
+	Function *symbols.Method
+	Needs    version.Version
+	// Calls is the older function the shim calls, and Args the number of
+	// the parameters of Function passed on to it; the others are ignored.
+	Calls string
+	Args  int
+}
+
+// Source returns the declaration of the shim, indented with a tab, as a
+// member of a class.
+func (s *Shim) Source() string {
+	var sb strings.Builder
+	ignored := s.Function.Params[s.Args:]
+	fmt.Fprintf(&sb, "\t// %s needs GZDoom %s; this calls %s", s.Function.Name, s.Needs, s.Calls)
+	if len(ignored) > 0 {
+		var names []string
+		for _, p := range ignored {
+			names = append(names, p.Name)
+		}
+		fmt.Fprintf(&sb, ", ignoring %s", strings.Join(names, ", "))
+	}
+	sb.WriteString(".\n\t")
+	for _, m := range s.Function.Modifiers {
+		if m == "action" || m == "static" {
+			sb.WriteString(m + " ")
+		}
+	}
+	var params, args []string
+	for i, p := range s.Function.Params {
+		param := p.Type + " " + p.Name
+		if len(p.Modifiers) > 0 {
+			param = strings.Join(p.Modifiers, " ") + " " + param
+		}
+		if p.Default != "" {
+			param += " = " + p.Default
+		}
+		params = append(params, param)
+		if i < s.Args {
+			args = append(args, p.Name)
+		}
+	}
+	fmt.Fprintf(&sb, "%s %s(%s)\n\t{\n\t\t", s.Function.ReturnType, s.Name, strings.Join(params, ", "))
+	if !strings.EqualFold(s.Function.ReturnType, "void") {
+		sb.WriteString("return ")
+	}
+	fmt.Fprintf(&sb, "%s(%s);\n\t}\n", s.Calls, strings.Join(args, ", "))
+	return sb.String()
+}
+
+// Report is what a mod takes from versions newer than Target.
+type Report struct {
+	Target version.Version
+	// Required is the oldest version the code works with as it is: the
+	// newest of those its uses need, and no older than version.Default.
+	Required version.Version
+	// Uses are the uses of what Target lacks, by path and position.
+	Uses []*Use
+	// Shims are the shims of Uses, by name.
+	Shims []*Shim
+}
+
+// Analyze reports what the code of trees, taken as one program, uses of
+// the versions newer than target.
+func Analyze(target version.Version, trees ...*zscript.Tree) *Report {
+	r := &Report{Target: target, Required: version.Default}
+	tables := append(symbols.ExtractAll(0, trees...), engine.Table())
+	shims := map[string]*Shim{}
+	for _, tree := range trees {
+		// Checking against the oldest version possible finds every use of
+		// a version, so that the version required is known too.
+		for _, p := range version.Check(tree, version.Version{}, tables...) {
+			if p.Needs.Compare(r.Required) > 0 {
+				r.Required = p.Needs
+			}
+			if p.Needs.Compare(target) <= 0 {
+				continue
+			}
+			u := &Use{Path: p.Path, Range: p.Range, What: p.What, Needs: p.Needs}
+			key := strings.ToLower(p.What)
+			s, ok := shims[key]
+			if !ok {
+				s = shim(p.What, target)
+				shims[key] = s
+			}
+			u.Shim = s
+			r.Uses = append(r.Uses, u)
+		}
+	}
+	for _, s := range shims {
+		if s != nil {
+			r.Shims = append(r.Shims, s)
+		}
+	}
+	sort.Slice(r.Shims, func(i, j int) bool { return r.Shims[i].Name < r.Shims[j].Name })
+	sort.SliceStable(r.Uses, func(i, j int) bool {
+		a, b := r.Uses[i], r.Uses[j]
+		if a.Path != b.Path {
+			return a.Path < b.Path
+		}
+		return a.Range.StartByte < b.Range.StartByte
+	})
+	return r
+}
+
+// shim returns the shim of the Actor method named name, if it is newer
+// than target and replaced a function that target has, or nil.
+func shim(name string, target version.Version) *Shim {
+	actor := engine.Table().Class("Actor")
+	if actor == nil {
+		return nil
+	}
+	m := actor.Method(name)
+	if m == nil || len(m.Params) > 0 && m.Params[len(m.Params)-1].Variadic {
+		return nil
+	}
+	needs, ok := since(m.Modifiers)
+	if !ok || needs.Compare(target) <= 0 {
+		return nil
+	}
+	for _, e := range deprecations.Default().Entries() {
+		if e.Kind != deprecations.Function || !strings.EqualFold(e.Replacement, m.Name) || e.RenameArgs == 0 {
+			continue
+		}
+		old := actor.Method(e.Member())
+		if old == nil {
+			continue
+		}
+		if v, ok := since(old.Modifiers); ok && v.Compare(target) > 0 {
+			continue
+		}
+		args := len(m.Params)
+		if e.RenameArgs > 0 {
+			args = min(e.RenameArgs, args)
+		}
+		return &Shim{Name: Prefix + m.Name, Function: m, Needs: needs, Calls: old.Name, Args: args}
+	}
+	return nil
+}
+
+// since returns the version of a version("x") modifier.
+func since(modifiers []string) (version.Version, bool) {
+	for _, m := range modifiers {
+		if inner, ok := strings.CutPrefix(m, "version("); ok {
+			v, err := version.Parse(strings.Trim(strings.TrimSuffix(inner, ")"), `" `))
+			return v, err == nil
+		}
+	}
+	return version.Version{}, false
+}
+
+// Mixin returns the source of a mixin class named name holding the shims
+// of r, for the actor classes of the mod to mix in with "mixin name;", or
+// "" if r has no shims.
+func (r *Report) Mixin(name string) string {
+	if len(r.Shims) == 0 {
+		return ""
+	}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "// Shims of the engine functions newer than GZDoom %s.\nmixin class %s\n{\n", r.Target, name)
+	for i, s := range r.Shims {
+		if i > 0 {
+			sb.WriteString("\n")
+		}
+		sb.WriteString(s.Source())
+	}
+	sb.WriteString("}\n")
+	return sb.String()
+}
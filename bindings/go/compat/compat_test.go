@@ -0,0 +1,78 @@
+package compat_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	zscript "github.com/jlcrochet/tree-sitter-zscript/bindings/go"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/compat"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/version"
+)
+
+const source = `version "4.10"
+class Imp : Actor {
+	void Roar() {
+		A_StartSound("imp/roar", CHAN_VOICE);
+		A_StopSounds(0, 7);
+		String s = "a";
+		s.MakeUpper();
+	}
+}
+class Handler : EventHandler {}
+`
+
+func TestAnalyze(t *testing.T) {
+	tree, err := zscript.Parse(context.Background(), []byte(source))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tree.Close()
+	tree.Path = "imp.zs"
+
+	r := compat.Analyze(version.MustParse("4.0"), tree)
+	if r.Required != version.MustParse("4.10") {
+		t.Errorf("required version %v, want 4.10", r.Required)
+	}
+	var got []string
+	for _, u := range r.Uses {
+		got = append(got, u.String())
+	}
+	want := []string{
+		"imp.zs:4:3: A_StartSound needs GZDoom 4.5; call Compat_A_StartSound instead, which calls A_PlaySound",
+		"imp.zs:5:3: A_StopSounds needs GZDoom 4.5; avoid it, or target GZDoom 4.5",
+		"imp.zs:7:5: MakeUpper needs GZDoom 4.10; avoid it, or target GZDoom 4.10",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("uses:\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	mixin := `// Shims of the engine functions newer than GZDoom 4.0.
+mixin class ModShims
+{
+	// A_StartSound needs GZDoom 4.5; this calls A_PlaySound, ignoring flags, volume, attenuation, pitch, startTime.
+	void Compat_A_StartSound(sound whattoplay, int slot = CHAN_BODY, int flags = 0, double volume = 1.0, double attenuation = ATTN_NORM, double pitch = 0.0, double startTime = 0.0)
+	{
+		A_PlaySound(whattoplay, slot);
+	}
+}
+`
+	if got := r.Mixin("ModShims"); got != mixin {
+		t.Errorf("mixin:\n%s\nwant\n%s", got, mixin)
+	}
+	shims, err := zscript.Parse(context.Background(), []byte(mixin))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer shims.Close()
+	if shims.RootNode().HasError() {
+		t.Errorf("the mixin does not parse: %s", shims.RootNode().ToSexp())
+	}
+
+	if r := compat.Analyze(version.MustParse("4.10"), tree); len(r.Uses) != 0 || r.Mixin("ModShims") != "" {
+		t.Errorf("uses at the version required: %v", r.Uses)
+	}
+	if r := compat.Analyze(version.MustParse("2.3"), tree); len(r.Uses) != 4 || r.Uses[3].What != "EventHandler" {
+		t.Errorf("uses at 2.3: %v", r.Uses)
+	}
+}
@@ -0,0 +1,297 @@
+// Package complete computes the completions an editor offers at a cursor
+// position: class names after "class X :", the members of an expression
+// after ".", state keywords and labels inside States blocks, and
+// properties and flags inside Default blocks.
+//
+// The context is read from the tokens before the cursor rather than from
+// the syntax tree, since code being typed rarely parses.
+package complete
+
+import (
+	"sort"
+	"strings"
+
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+
+	zscript "github.com/jlcrochet/tree-sitter-zscript/bindings/go"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/defaults"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/resolve"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/symbols"
+)
+
+// Item is a completion.
+type Item struct {
+	Label string
+	// Kind is the kind of declaration the item names, or 0 for a keyword.
+	Kind symbols.Kind
+	// Detail describes the item: the type of a field, the signature of a
+	// method, or the class that declares a state label.
+	Detail string
+}
+
+// Result holds the completions at a position.
+type Result struct {
+	// Prefix is the text before the position that the completions
+	// replace, such as "A_Sp" or "Inventory.Am"; it may be empty.
+	Prefix string
+	// Items are the completions that start with Prefix, ignoring case,
+	// sorted by label.
+	Items []Item
+}
+
+// The keywords of state lines: those that start a line and those that
+// follow the duration of a frame.
+var (
+	flowKeywords  = []string{"Goto", "Loop", "Stop", "Wait", "Fail"}
+	frameKeywords = []string{"Bright", "CanRaise", "Fast", "Slow", "NoDelay", "Offset", "Light"}
+)
+
+// Complete returns the completions at offset, a byte offset in tree, with
+// declarations looked up through r. Default block properties and flags
+// come from schema, which should declare those of the project, or from
+// defaults.NewSchema if schema is nil. The result has no items if offset
+// is in none of the contexts the package knows.
+func Complete(r *resolve.Resolver, schema *defaults.Schema, tree *zscript.Tree, offset uint) Result {
+	if schema == nil {
+		schema = defaults.NewSchema()
+	}
+	toks := tokens(tree.RootNode(), offset)
+	var res Result
+	if n := len(toks); n > 0 && toks[n-1].EndByte() == offset && isWord(text(tree, toks[n-1])) {
+		res.Prefix = text(tree, toks[n-1])
+		toks = toks[:n-1]
+	}
+
+	var items []Item
+	switch block := enclosingBlock(tree, toks); {
+	case isParentSpecifier(tree, toks):
+		for _, decl := range r.Types() {
+			if decl.Kind == symbols.KindClass {
+				items = append(items, Item{Label: decl.Name, Kind: symbols.KindClass})
+			}
+		}
+	case block == "default":
+		// Properties and flags may be qualified, as in Inventory.Amount.
+		start := offset - uint(len(res.Prefix))
+		for len(toks) >= 2 && text(tree, toks[len(toks)-1]) == "." && isWord(text(tree, toks[len(toks)-2])) {
+			dot, word := toks[len(toks)-1], toks[len(toks)-2]
+			if dot.EndByte() != start || word.EndByte() != dot.StartByte() {
+				break
+			}
+			res.Prefix = text(tree, word) + "." + res.Prefix
+			start = word.StartByte()
+			toks = toks[:len(toks)-2]
+		}
+		switch prev := last(tree, toks); prev {
+		case "+", "-":
+			for _, name := range schema.FlagNames() {
+				items = append(items, Item{Label: name, Kind: symbols.KindFlag})
+			}
+		case "{", ";":
+			for _, name := range schema.PropertyNames() {
+				items = append(items, Item{Label: name, Kind: symbols.KindProperty})
+			}
+		}
+	case block == "states":
+		items = stateItems(r, tree, toks)
+	case last(tree, toks) == "." && len(toks) >= 2:
+		items = memberItems(r, tree, toks[len(toks)-2])
+	}
+
+	seen := map[string]bool{}
+	for _, item := range items {
+		key := strings.ToLower(item.Label)
+		if !seen[key] && strings.HasPrefix(key, strings.ToLower(res.Prefix)) {
+			seen[key] = true
+			res.Items = append(res.Items, item)
+		}
+	}
+	sort.SliceStable(res.Items, func(i, j int) bool {
+		return strings.ToLower(res.Items[i].Label) < strings.ToLower(res.Items[j].Label)
+	})
+	return res
+}
+
+// isParentSpecifier reports whether toks end with "class X :".
+func isParentSpecifier(tree *zscript.Tree, toks []*tree_sitter.Node) bool {
+	n := len(toks)
+	return n >= 3 && text(tree, toks[n-1]) == ":" && isWord(text(tree, toks[n-2])) &&
+		strings.EqualFold(text(tree, toks[n-3]), "class")
+}
+
+// enclosingBlock returns "default" or "states" if the innermost unclosed
+// brace of toks opens a Default or States block, and "" otherwise.
+func enclosingBlock(tree *zscript.Tree, toks []*tree_sitter.Node) string {
+	depth := 0
+	for i := len(toks) - 1; i >= 0; i-- {
+		switch text(tree, toks[i]) {
+		case "}":
+			depth++
+		case "{":
+			if depth > 0 {
+				depth--
+				continue
+			}
+			j := i - 1
+			if j >= 0 && text(tree, toks[j]) == ")" {
+				// States(Actor) and the like.
+				for j >= 0 && text(tree, toks[j]) != "(" {
+					j--
+				}
+				j--
+			}
+			if j < 0 {
+				return ""
+			}
+			switch word := strings.ToLower(text(tree, toks[j])); word {
+			case "default", "states":
+				return word
+			}
+			return ""
+		}
+	}
+	return ""
+}
+
+// stateItems returns the completions inside a States block: state labels
+// after Goto, flow keywords at the start of a line and frame keywords
+// elsewhere.
+func stateItems(r *resolve.Resolver, tree *zscript.Tree, toks []*tree_sitter.Node) []Item {
+	class := enclosingClass(tree, toks)
+	super := false
+	n := len(toks)
+	if n >= 3 && text(tree, toks[n-1]) == "::" {
+		if q := text(tree, toks[n-2]); strings.EqualFold(q, "super") {
+			super = true
+		} else {
+			class = q
+		}
+		n -= 2
+	}
+	var items []Item
+	switch prev := strings.ToLower(last(tree, toks[:n])); prev {
+	case "goto":
+		for _, decl := range r.StateLabels(class, super) {
+			items = append(items, Item{Label: decl.Name, Kind: symbols.KindStateLabel, Detail: decl.Owner})
+		}
+	case "{", ";", ":":
+		for _, k := range flowKeywords {
+			items = append(items, Item{Label: k})
+		}
+	default:
+		for _, k := range frameKeywords {
+			items = append(items, Item{Label: k})
+		}
+	}
+	return items
+}
+
+// memberItems returns the members of the expression that ends with tok,
+// the token before a ".".
+func memberItems(r *resolve.Resolver, tree *zscript.Tree, tok *tree_sitter.Node) []Item {
+	var typ string
+	var super, ok bool
+	if word := text(tree, tok); strings.EqualFold(word, "super") || strings.EqualFold(word, "self") {
+		// Unfinished code may leave these as plain identifiers.
+		typ, super, ok = enclosingClass(tree, []*tree_sitter.Node{tok}), strings.EqualFold(word, "super"), true
+	} else {
+		typ, super, ok = r.TypeOf(tree, receiver(tok))
+	}
+	if !ok {
+		return nil
+	}
+	var items []Item
+	for _, decl := range r.Members(typ, super) {
+		item := Item{Label: decl.Name, Kind: decl.Kind, Detail: decl.Type}
+		if decl.Method != nil {
+			item.Detail = resolve.SignatureOf(decl).Label
+		}
+		items = append(items, item)
+	}
+	return items
+}
+
+// receiver returns the largest expression that ends with tok.
+func receiver(tok *tree_sitter.Node) *tree_sitter.Node {
+	n := tok
+	for p := n.Parent(); p != nil && p.EndByte() == tok.EndByte() && expressions[p.Kind()]; p = p.Parent() {
+		n = p
+	}
+	return n
+}
+
+// expressions are the kinds of node that receiver climbs through. The
+// argument list of a call ends with the call.
+var expressions = map[string]bool{
+	zscript.NodeArgumentList:            true,
+	zscript.NodeFieldExpression:         true,
+	zscript.NodeCallExpression:          true,
+	zscript.NodeParenthesizedExpression: true,
+	zscript.NodeSelfExpression:          true,
+	zscript.NodeSuperExpression:         true,
+}
+
+// enclosingClass returns the name of the class or struct that contains
+// the last of toks, or "".
+func enclosingClass(tree *zscript.Tree, toks []*tree_sitter.Node) string {
+	if len(toks) == 0 {
+		return ""
+	}
+	for n := toks[len(toks)-1].Parent(); n != nil; n = n.Parent() {
+		switch n.Kind() {
+		case zscript.NodeClassDefinition, zscript.NodeStructDefinition:
+			if name := n.ChildByFieldName(zscript.FieldName); name != nil {
+				return text(tree, name)
+			}
+		}
+	}
+	return ""
+}
+
+// tokens returns the leaves of the tree under root that end at or before
+// offset, in source order, leaving out comments. String literals are
+// single tokens.
+func tokens(root *tree_sitter.Node, offset uint) []*tree_sitter.Node {
+	var leaves []*tree_sitter.Node
+	var v zscript.Visitor
+	v.Enter = func(node *tree_sitter.Node) zscript.WalkAction {
+		switch {
+		case node.StartByte() >= offset:
+			return zscript.WalkStop
+		case node.Kind() == zscript.NodeComment:
+			return zscript.WalkSkipChildren
+		case node.Kind() == zscript.NodeStringLiteral || node.ChildCount() == 0:
+			if node.EndByte() <= offset && !node.IsMissing() {
+				leaves = append(leaves, node)
+			}
+			return zscript.WalkSkipChildren
+		}
+		return zscript.WalkContinue
+	}
+	zscript.Walk(root, &v)
+	return leaves
+}
+
+func text(tree *zscript.Tree, n *tree_sitter.Node) string {
+	return n.Utf8Text(tree.Source)
+}
+
+// last returns the text of the last of toks, or "".
+func last(tree *zscript.Tree, toks []*tree_sitter.Node) string {
+	if len(toks) == 0 {
+		return ""
+	}
+	return text(tree, toks[len(toks)-1])
+}
+
+func isWord(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if !(r == '_' || r >= '0' && r <= '9' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z') {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,115 @@
+package complete_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	zscript "github.com/jlcrochet/tree-sitter-zscript/bindings/go"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/complete"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/defaults"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/resolve"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/symbols"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/version"
+)
+
+const base = `class Imp : Actor {
+	int charge;
+	property Charge: charge;
+	flagdef Charged: charge, 0;
+	Actor victim;
+	void Fire(int count) {}
+	States {
+	Spawn:
+		TROO A 1;
+		Loop;
+	Missile:
+		TROO B 1;
+		Stop;
+	}
+}
+`
+
+func TestComplete(t *testing.T) {
+	tests := []struct {
+		name string
+		// source is appended to base; | marks the position.
+		source string
+		prefix string
+		// want are labels that must be offered and absent labels that
+		// must not.
+		want, absent []string
+	}{
+		{"parent class", "class Demon : Im|", "Im", []string{"Imp"}, []string{"Actor", "Inventory"}},
+		{"parent class, no prefix", "class Demon : |", "", []string{"Actor", "Imp", "Inventory"}, []string{"Console"}},
+		{"self member", "class Demon : Imp { void F() { self.| } }", "", []string{"Fire", "charge", "victim", "A_StartSound"}, nil},
+		{"member prefix", "class Demon : Imp { void F() { victim.A_St| } }", "A_St", []string{"A_StartSound", "A_Stop"}, []string{"Fire", "charge"}},
+		{"super member", "class Demon : Imp { override void Tick() { Super.| } }", "", []string{"Tick", "Fire"}, nil},
+		{"struct member", "class Demon : Imp { void F() { Console.| } }", "", []string{"Printf"}, []string{"Fire"}},
+		{"call result", `class Demon : Imp { void F() { FindInventory("Clip").Am| } }`, "Am", []string{"Amount"}, []string{"MaxAmount", "Fire"}},
+		{"unknown receiver", "class Demon : Imp { void F() { nothing.| } }", "", nil, []string{"Fire"}},
+		{"property", "class Demon : Imp { Default { Hea| } }", "Hea", []string{"Health"}, []string{"Height", "NOGRAVITY"}},
+		{"qualified property", "class Demon : Imp { Default { Health 10; Imp.Ch| } }", "Imp.Ch", []string{"Imp.Charge"}, nil},
+		{"flag", "class Demon : Imp { Default { +NOG| } }", "NOG", []string{"NOGRAVITY"}, []string{"Health"}},
+		{"qualified flag", "class Demon : Imp { Default { -Imp.| } }", "Imp.", []string{"Imp.Charged"}, nil},
+		{"property argument", "class Demon : Imp { Default { Health | } }", "", nil, []string{"Health"}},
+		{"flow keyword", "class Demon : Imp { States { Spawn: TROO A 1; | } }", "", []string{"Goto", "Loop", "Stop"}, []string{"Bright"}},
+		{"frame keyword", "class Demon : Imp { States { Spawn: TROO A 1 Br| } }", "Br", []string{"Bright"}, []string{"Goto"}},
+		{"goto label", "class Demon : Imp { States { See: TROO A 1; Goto | } }", "", []string{"Spawn", "Missile", "See"}, nil},
+		{"super label", "class Demon : Imp { States { See: TROO A 1; Goto Super::Mi| } }", "Mi", []string{"Missile"}, []string{"See"}},
+		{"method body", "class Demon : Imp { void F() { Fi| } }", "Fi", nil, []string{"Fire"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			source := base + tt.source
+			offset := uint(strings.Index(source, "|"))
+			source = strings.Replace(source, "|", "", 1)
+			tree, err := zscript.Parse(context.Background(), []byte(source))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer tree.Close()
+			table := symbols.Extract(tree)
+			r := resolve.NewWithEngine(version.MustParse("4.12"), table)
+			schema := defaults.NewSchema()
+			schema.Declare(table)
+
+			res := complete.Complete(r, schema, tree, offset)
+			if res.Prefix != tt.prefix {
+				t.Errorf("prefix = %q, want %q", res.Prefix, tt.prefix)
+			}
+			labels := map[string]bool{}
+			for _, item := range res.Items {
+				labels[item.Label] = true
+			}
+			for _, l := range tt.want {
+				if !labels[l] {
+					t.Errorf("%q is not offered in %v", l, res.Items)
+				}
+			}
+			for _, l := range tt.absent {
+				if labels[l] {
+					t.Errorf("%q is offered", l)
+				}
+			}
+		})
+	}
+}
+
+func TestCompleteDetail(t *testing.T) {
+	source := base + "class Demon : Imp { void F() { self.Fi } }"
+	tree, err := zscript.Parse(context.Background(), []byte(source))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tree.Close()
+	r := resolve.New(symbols.Extract(tree))
+	res := complete.Complete(r, nil, tree, uint(strings.Index(source, "Fi }")+2))
+	if len(res.Items) != 1 {
+		t.Fatalf("items = %+v", res.Items)
+	}
+	want := complete.Item{Label: "Fire", Kind: symbols.KindMethod, Detail: "void Imp.Fire(int count)"}
+	if res.Items[0] != want {
+		t.Errorf("item = %+v, want %+v", res.Items[0], want)
+	}
+}
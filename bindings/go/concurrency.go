@@ -0,0 +1,91 @@
+package tree_sitter_zscript
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+)
+
+// ErrParserClosed is returned by a SafeParser after Close.
+var ErrParserClosed = errors.New("zscript: parser closed")
+
+// LeaseParser returns a parser for this grammar from the pool Parse uses,
+// and a function that returns it. The parser belongs to the caller until
+// release is called, and must not be used after. A caller that changes
+// the parser's settings, such as its included ranges or logger, must
+// restore them before releasing it.
+func LeaseParser() (parser *tree_sitter.Parser, release func(), err error) {
+	p := parserPool.Get().(*pooledParser)
+	if p.err != nil {
+		return nil, nil, p.err
+	}
+	var once sync.Once
+	return p.parser, func() {
+		once.Do(func() {
+			p.parser.Reset()
+			parserPool.Put(p)
+		})
+	}, nil
+}
+
+// SafeParser is a parser that may be used from several goroutines; each
+// call waits for the one before it to finish. It suits callers that keep
+// a parser for a long time, such as one per open document, where a
+// pooled parser would be taken from other callers.
+type SafeParser struct {
+	mu     sync.Mutex
+	parser *tree_sitter.Parser
+}
+
+// NewSafeParser returns a parser for this grammar.
+func NewSafeParser() (*SafeParser, error) {
+	parser, err := newParser()
+	if err != nil {
+		return nil, err
+	}
+	return &SafeParser{parser: parser}, nil
+}
+
+// Parse parses source. If old is not nil, the parts of it that have not
+// been edited are reused; old must already have been edited to match
+// source, and must not be edited by another goroutine during the call.
+// Like Parse, it returns ctx.Err() if ctx ends the parse.
+func (p *SafeParser) Parse(ctx context.Context, source []byte, old *Tree) (*Tree, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.parser == nil {
+		return nil, ErrParserClosed
+	}
+	var oldTree *tree_sitter.Tree
+	if old != nil {
+		oldTree = old.Tree
+	}
+	tree, err := parseWith(ctx, p.parser, readSource(source), oldTree)
+	if err != nil {
+		return nil, err
+	}
+	return &Tree{Tree: tree, Source: source}, nil
+}
+
+// Close releases the parser, waiting for a parse in progress. Later calls
+// of Parse return ErrParserClosed.
+func (p *SafeParser) Close() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.parser != nil {
+		p.parser.Close()
+		p.parser = nil
+	}
+}
+
+// Clone returns a copy of t that shares its nodes and source but may be
+// edited and closed independently, so that another goroutine can keep
+// reading t.
+func (t *Tree) Clone() *Tree {
+	return &Tree{Tree: t.Tree.Clone(), Source: t.Source, Path: t.Path, SkipExtras: t.SkipExtras, Dialect: t.Dialect}
+}
@@ -0,0 +1,138 @@
+package tree_sitter_zscript_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+
+	tree_sitter_zscript "github.com/jlcrochet/tree-sitter-zscript/bindings/go"
+)
+
+const goroutines = 8
+
+// parallel runs fn in goroutines at once and waits for them.
+func parallel(fn func(i int)) {
+	var wg sync.WaitGroup
+	for i := range goroutines {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			fn(i)
+		}()
+	}
+	wg.Wait()
+}
+
+// classSource returns a small class that differs for each i.
+func classSource(i int) []byte {
+	return fmt.Appendf(nil, "class C%d : Actor { int x; void F() { x = %d; } }", i, i)
+}
+
+func TestConcurrentParse(t *testing.T) {
+	parallel(func(i int) {
+		for range 20 {
+			src := classSource(i)
+			tree, err := tree_sitter_zscript.Parse(context.Background(), src)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			name := tree.RootNode().NamedChild(0).ChildByFieldName("name").Utf8Text(src)
+			if want := fmt.Sprintf("C%d", i); name != want {
+				t.Errorf("parsed class %s, want %s", name, want)
+			}
+			tree.Close()
+		}
+	})
+}
+
+func TestLeaseParser(t *testing.T) {
+	parallel(func(i int) {
+		parser, release, err := tree_sitter_zscript.LeaseParser()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer release()
+		tree := parser.Parse(classSource(i), nil)
+		if tree.RootNode().HasError() {
+			t.Errorf("goroutine %d: parse error", i)
+		}
+		tree.Close()
+	})
+}
+
+func TestSafeParser(t *testing.T) {
+	parser, err := tree_sitter_zscript.NewSafeParser()
+	if err != nil {
+		t.Fatal(err)
+	}
+	parallel(func(i int) {
+		tree, err := parser.Parse(context.Background(), classSource(i), nil)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		if tree.RootNode().HasError() {
+			t.Errorf("goroutine %d: parse error", i)
+		}
+		tree.Close()
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := parser.Parse(ctx, classSource(0), nil); !errors.Is(err, context.Canceled) {
+		t.Errorf("cancelled parse: got %v", err)
+	}
+
+	parser.Close()
+	if _, err := parser.Parse(context.Background(), classSource(0), nil); !errors.Is(err, tree_sitter_zscript.ErrParserClosed) {
+		t.Errorf("parse after Close: got %v", err)
+	}
+}
+
+func TestConcurrentReads(t *testing.T) {
+	src := classSource(0)
+	tree, err := tree_sitter_zscript.Parse(context.Background(), src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tree.Close()
+	q := tree_sitter_zscript.MustQuery(`(identifier) @id`)
+
+	var counts [goroutines]int
+	var v tree_sitter_zscript.Visitor
+	v.On(tree_sitter_zscript.NodeIdentifier, func(*tree_sitter.Node) tree_sitter_zscript.WalkAction {
+		return tree_sitter_zscript.WalkContinue
+	})
+	parallel(func(i int) {
+		tree_sitter_zscript.Walk(tree.RootNode(), &v)
+		for range q.Captures(tree.RootNode(), src) {
+			counts[i]++
+		}
+	})
+	for i, n := range counts {
+		if n != counts[0] || n == 0 {
+			t.Errorf("goroutine %d found %d identifiers, goroutine 0 found %d", i, n, counts[0])
+		}
+	}
+
+	// A clone can be edited while the original is read.
+	clone := tree.Clone()
+	defer clone.Close()
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		clone.Edit(&tree_sitter.InputEdit{StartByte: 0, OldEndByte: 0, NewEndByte: 1})
+	}()
+	tree_sitter_zscript.Walk(tree.RootNode(), &v)
+	wg.Wait()
+	if tree.RootNode().StartByte() != 0 {
+		t.Error("editing the clone changed the original")
+	}
+}
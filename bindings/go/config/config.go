@@ -0,0 +1,396 @@
+// Package config loads the settings that the tools of this module share
+// from a file at the top of a mod, so that the command line tools, the
+// language server and programs using the library agree on them:
+//
+//	# .zscriptrc
+//	version = "4.10"          # the GZDoom version the mod targets
+//	roots = ["zscript.zs"]    # the lumps includes are followed from
+//	archives = ["../lib.pk3"] # archives whose classes the mod builds on
+//
+//	[format]
+//	indent = 4                # spaces, or "tab"
+//	brace = "next"            # "same" or "next"
+//	align-states = false
+//
+//	[lint]
+//	rules = ["unused-local", "missing-override"]
+//	disable = ["state-fallthrough"]
+//	queries = "lint"          # a directory of query rules
+//
+// The file is named .zscriptrc, or zscript.toml, which has the same
+// syntax: the part of TOML that the settings need. Every setting is
+// optional and paths are relative to the directory of the file. Without
+// a [lint] table the tools lint nothing beyond syntax; with one, the
+// rules listed are run, or every built-in rule if none are, less those
+// disabled, together with the query rules of the directory given, as
+// package lint loads them.
+//
+// GZDoom loads a file named zscript.toml at the top of a mod as ZScript,
+// as it does any lump named zscript, so a mod run from its directory
+// should keep its configuration in .zscriptrc or in a directory above,
+// where Find looks too. Find prefers .zscriptrc when a directory has
+// both, and the tools of this module do not take zscript.toml for
+// ZScript.
+package config
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	zscript "github.com/jlcrochet/tree-sitter-zscript/bindings/go"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/archive"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/format"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/lint"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/project"
+)
+
+// Names are the names of configuration files, in the order Find prefers
+// them.
+var Names = []string{".zscriptrc", "zscript.toml"}
+
+// Config is the configuration of a mod.
+type Config struct {
+	// Path is the file the configuration was read from, or "" if it is
+	// the default one.
+	Path string
+	// Dir is the directory relative paths are resolved against: that of
+	// Path, or the one Find searched from.
+	Dir string
+	// Version is the GZDoom version the mod targets, such as "4.10" or
+	// "4.x", or "" for the newest.
+	Version string
+	// Roots are the files of Dir that includes are followed from, or
+	// nil for the ZSCRIPT lumps at its top.
+	Roots []string
+	// Archives are the archives, or directories, whose ZScript the mod
+	// builds on, loaded ahead of it.
+	Archives []string
+	Format   format.Options
+	// Lint is nil if the file has no [lint] table.
+	Lint *Lint
+}
+
+// Lint is the [lint] table of a configuration.
+type Lint struct {
+	// Rules are the names of the built-in rules to run, or nil for all of
+	// them.
+	Rules []string
+	// Disable are the names of rules not to run, built-in or query rules.
+	Disable []string
+	// Queries is the directory of query rules to run besides, or "".
+	Queries string
+}
+
+// Default returns the configuration of a mod without a configuration
+// file, resolving paths against dir.
+func Default(dir string) *Config {
+	return &Config{Dir: dir, Format: format.DefaultOptions()}
+}
+
+// Parse parses the configuration file data, read from the file at path.
+func Parse(path string, data []byte) (*Config, error) {
+	t, err := parseTOML(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("config: %s:%w", path, err)
+	}
+	c := Default(filepath.Dir(path))
+	c.Path = path
+	d := &decoder{path: path}
+	d.object(t, "", map[string]func(any){
+		"version":  func(v any) { c.Version = d.string("version", v) },
+		"roots":    func(v any) { c.Roots = d.strings("roots", v) },
+		"archives": func(v any) { c.Archives = d.strings("archives", v) },
+		"format": func(v any) {
+			d.object(v, "format", map[string]func(any){
+				"indent": func(v any) {
+					switch v := v.(type) {
+					case string:
+						if v != "tab" {
+							d.fail("format.indent must be a number of spaces or \"tab\", not %q", v)
+						}
+						c.Format.UseSpaces = false
+					case int64:
+						if v <= 0 {
+							d.fail("format.indent must be a positive number of spaces")
+						}
+						c.Format.UseSpaces, c.Format.IndentWidth = true, int(v)
+					default:
+						d.fail("format.indent must be a number of spaces or \"tab\"")
+					}
+				},
+				"brace": func(v any) {
+					switch s := d.string("format.brace", v); s {
+					case "same":
+						c.Format.BraceStyle = format.BraceSameLine
+					case "next":
+						c.Format.BraceStyle = format.BraceNextLine
+					default:
+						d.fail("format.brace must be \"same\" or \"next\", not %q", s)
+					}
+				},
+				"align-states": func(v any) { c.Format.AlignStates = d.bool("format.align-states", v) },
+			})
+		},
+		"lint": func(v any) {
+			c.Lint = &Lint{}
+			d.object(v, "lint", map[string]func(any){
+				"rules":   func(v any) { c.Lint.Rules = d.strings("lint.rules", v) },
+				"disable": func(v any) { c.Lint.Disable = d.strings("lint.disable", v) },
+				"queries": func(v any) { c.Lint.Queries = d.string("lint.queries", v) },
+			})
+		},
+	})
+	if d.err != nil {
+		return nil, d.err
+	}
+	if c.Version != "" {
+		if _, err := zscript.LanguageForVersion(c.Version); err != nil {
+			return nil, fmt.Errorf("config: %s: %w", path, err)
+		}
+	}
+	if c.Lint != nil {
+		known := map[string]bool{}
+		for _, r := range lint.DefaultRules() {
+			known[r.Name()] = true
+		}
+		for _, name := range c.Lint.Rules {
+			if !known[name] {
+				return nil, fmt.Errorf("config: %s: no lint rule %s", path, name)
+			}
+		}
+	}
+	return c, nil
+}
+
+// Load reads the configuration file at path.
+func Load(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("config: %w", err)
+	}
+	return Parse(path, data)
+}
+
+// Find reads the configuration file of the mod holding dir: the first
+// file of Names in dir or, failing that, in the nearest directory above
+// it that has one. It returns Default(dir) if there is none.
+func Find(dir string) (*Config, error) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, fmt.Errorf("config: %w", err)
+	}
+	for d := abs; ; d = filepath.Dir(d) {
+		for _, name := range Names {
+			p := filepath.Join(d, name)
+			if info, err := os.Stat(p); err == nil && !info.IsDir() {
+				return Load(p)
+			}
+		}
+		if filepath.Dir(d) == d {
+			return Default(dir), nil
+		}
+	}
+}
+
+// path resolves p, a path of the configuration, against c.Dir.
+func (c *Config) path(p string) string {
+	if filepath.IsAbs(p) {
+		return p
+	}
+	return filepath.Join(c.Dir, p)
+}
+
+// Dialect returns the dialect of the version the mod targets, the newest
+// one if c names none.
+func (c *Config) Dialect() zscript.Dialect {
+	if c.Version == "" {
+		return zscript.Dialect{}
+	}
+	// Parse has checked the version.
+	d, _ := zscript.LanguageForVersion(c.Version)
+	return d
+}
+
+// Linter returns the linter of c.Lint, or nil if c has no [lint] table.
+func (c *Config) Linter() (*lint.Linter, error) {
+	if c.Lint == nil {
+		return nil, nil
+	}
+	wanted, disabled := map[string]bool{}, map[string]bool{}
+	for _, name := range c.Lint.Rules {
+		wanted[name] = true
+	}
+	for _, name := range c.Lint.Disable {
+		disabled[name] = true
+	}
+	rules := []lint.Rule{}
+	for _, r := range lint.DefaultRules() {
+		if (len(wanted) == 0 || wanted[r.Name()]) && !disabled[r.Name()] {
+			rules = append(rules, r)
+		}
+	}
+	if c.Lint.Queries != "" {
+		queries, err := lint.LoadQueryRules(os.DirFS(c.path(c.Lint.Queries)))
+		if err != nil {
+			return nil, fmt.Errorf("config: %s: %w", c.Path, err)
+		}
+		for _, r := range queries {
+			if !disabled[r.Name()] {
+				rules = append(rules, r)
+			}
+		}
+	}
+	return &lint.Linter{Rules: rules}, nil
+}
+
+// LoadProject loads the mod in c.Dir from c.Roots, or from the ZSCRIPT
+// lumps at its top, and the ZScript of c.Archives ahead of it. The files
+// of an archive have paths prefixed with the archive's as it is written
+// in the configuration and a colon, as in "../lib.pk3:zscript.zs", so
+// that they cannot be mistaken for the mod's; the project's FS is that of
+// the mod.
+func (c *Config) LoadProject(ctx context.Context) (*project.Project, error) {
+	fsys := os.DirFS(c.Dir)
+	roots := c.Roots
+	if len(roots) == 0 {
+		var err error
+		if roots, err = project.FindRoots(fsys); err != nil {
+			return nil, err
+		}
+		if len(roots) == 0 {
+			return nil, fmt.Errorf("project: no zscript lump in %s", c.Dir)
+		}
+	}
+	mod, err := project.Load(ctx, fsys, roots...)
+	if err != nil {
+		return nil, err
+	}
+	if len(c.Archives) == 0 {
+		return mod, nil
+	}
+
+	var files []*project.File
+	var diagnostics []project.Diagnostic
+	for _, name := range c.Archives {
+		dep, err := archive.LoadProject(ctx, c.path(name))
+		if err != nil {
+			mod.Close()
+			for _, f := range files {
+				f.Tree.Close()
+			}
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil, fmt.Errorf("config: %s: no archive %s", c.Path, name)
+			}
+			return nil, err
+		}
+		prefix := func(p string) string { return name + ":" + p }
+		for _, f := range dep.Files {
+			f.Path = prefix(f.Path)
+			f.Tree.Path = f.Path
+			for i, inc := range f.Includes {
+				f.Includes[i] = prefix(inc)
+			}
+			files = append(files, f)
+		}
+		for _, d := range dep.Diagnostics {
+			d.Path = prefix(d.Path)
+			diagnostics = append(diagnostics, d)
+		}
+	}
+	p := project.New(append(files, mod.Files...)...)
+	p.FS = mod.FS
+	p.Diagnostics = append(diagnostics, mod.Diagnostics...)
+	return p, nil
+}
+
+// IsArchived reports whether path, a path of a file of a project that
+// LoadProject returned, is that of a file of one of c.Archives rather
+// than of the mod.
+func (c *Config) IsArchived(p string) bool {
+	for _, name := range c.Archives {
+		if strings.HasPrefix(p, name+":") {
+			return true
+		}
+	}
+	return false
+}
+
+// decoder checks the values of a parsed configuration file, keeping the
+// first error.
+type decoder struct {
+	path string
+	err  error
+}
+
+func (d *decoder) fail(format string, args ...any) {
+	if d.err == nil {
+		d.err = fmt.Errorf("config: %s: %s", d.path, fmt.Sprintf(format, args...))
+	}
+}
+
+// object calls the field of each key of v, a table, failing on keys with
+// none.
+func (d *decoder) object(v any, name string, fields map[string]func(any)) {
+	t, ok := v.(table)
+	if !ok {
+		d.fail("%s must be a table", name)
+		return
+	}
+	keys := make([]string, 0, len(t))
+	for key := range t {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		value := t[key]
+		field, ok := fields[key]
+		if !ok {
+			if name != "" {
+				key = name + "." + key
+			}
+			d.fail("unknown setting %s", key)
+			continue
+		}
+		field(value)
+	}
+}
+
+func (d *decoder) string(name string, v any) string {
+	s, ok := v.(string)
+	if !ok {
+		d.fail("%s must be a string", name)
+	}
+	return s
+}
+
+func (d *decoder) bool(name string, v any) bool {
+	b, ok := v.(bool)
+	if !ok {
+		d.fail("%s must be true or false", name)
+	}
+	return b
+}
+
+func (d *decoder) strings(name string, v any) []string {
+	values, ok := v.([]any)
+	if !ok {
+		d.fail("%s must be an array of strings", name)
+		return nil
+	}
+	var ss []string
+	for _, v := range values {
+		s, ok := v.(string)
+		if !ok {
+			d.fail("%s must be an array of strings", name)
+			return nil
+		}
+		ss = append(ss, s)
+	}
+	return ss
+}
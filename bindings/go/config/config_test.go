@@ -0,0 +1,192 @@
+package config_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/config"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/format"
+)
+
+func write(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, text := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(text), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	c, err := config.Parse("mod/zscript.toml", []byte(`# The mod's settings.
+version = "4.10"
+roots = ["zscript.zs", 'more.zs'] # trailing comment
+archives = [
+	"../lib.pk3",
+]
+
+[format]
+indent = 2
+brace = "next"
+align-states = false
+
+[lint]
+rules = ["unused-local", "missing-override"]
+disable = ["missing-override"]
+queries = "lint"
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &config.Config{
+		Path:     "mod/zscript.toml",
+		Dir:      "mod",
+		Version:  "4.10",
+		Roots:    []string{"zscript.zs", "more.zs"},
+		Archives: []string{"../lib.pk3"},
+		Format:   format.Options{UseSpaces: true, IndentWidth: 2, BraceStyle: format.BraceNextLine},
+		Lint:     &config.Lint{Rules: []string{"unused-local", "missing-override"}, Disable: []string{"missing-override"}, Queries: "lint"},
+	}
+	if !reflect.DeepEqual(c, want) {
+		t.Errorf("got %+v\nwant %+v", c, want)
+	}
+	if d := c.Dialect(); d.String() != "4.10" {
+		t.Errorf("got dialect %v", d)
+	}
+
+	c, err = config.Parse("zscript.toml", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Format != format.DefaultOptions() || c.Lint != nil {
+		t.Errorf("an empty file gives %+v", c)
+	}
+	if l, err := c.Linter(); l != nil || err != nil {
+		t.Errorf("got linter %v, error %v without a [lint] table", l, err)
+	}
+
+	for _, tc := range []struct{ text, err string }{
+		{"version = 4", "config: z.toml: version must be a string"},
+		{`version = "five"`, "config: z.toml: "},
+		{`colour = "red"`, "config: z.toml: unknown setting colour"},
+		{"[format]\ntabs = true", "config: z.toml: unknown setting format.tabs"},
+		{"[format]\nindent = \"wide\"", `config: z.toml: format.indent must be a number of spaces or "tab", not "wide"`},
+		{"[format]\nbrace = \"left\"", `config: z.toml: format.brace must be "same" or "next", not "left"`},
+		{"[lint]\nrules = [\"no-such-rule\"]", "config: z.toml: no lint rule no-such-rule"},
+		{"[lint]\nrules = \"unused-local\"", "config: z.toml: lint.rules must be an array of strings"},
+		{"version = \"4.10\"\nversion = \"4.11\"", "config: z.toml:2: key version is defined twice"},
+		{"[lint]\n[lint]", "config: z.toml:2: table lint is defined twice"},
+		{"version = \"4.10", "config: z.toml:1: unterminated string"},
+		{"roots = [\"a\" \"b\"]", "config: z.toml:1: expected , or ] in array"},
+		{"version", "config: z.toml:1: expected = after key version"},
+	} {
+		if _, err := config.Parse("z.toml", []byte(tc.text)); err == nil || !strings.HasPrefix(err.Error(), tc.err) {
+			t.Errorf("Parse(%q): got error %v, want %s", tc.text, err, tc.err)
+		}
+	}
+}
+
+func TestFind(t *testing.T) {
+	dir := t.TempDir()
+	write(t, dir, map[string]string{
+		".zscriptrc":         "[format]\nindent = \"tab\"\nbrace = \"next\"",
+		"sub/deeper/x.zs":    "",
+		"other/zscript.toml": "version = \"2.8\"",
+		"other/.zscriptrc":   "version = \"4.x\"",
+	})
+	c, err := config.Find(filepath.Join(dir, "sub", "deeper"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Path != filepath.Join(dir, ".zscriptrc") || c.Dir != dir || c.Format.BraceStyle != format.BraceNextLine || c.Format.UseSpaces {
+		t.Errorf("got %+v", c)
+	}
+	if c, err := config.Find(filepath.Join(dir, "other")); err != nil || c.Version != "4.x" {
+		t.Errorf("got %+v, %v; want .zscriptrc preferred", c, err)
+	}
+}
+
+func TestLinter(t *testing.T) {
+	dir := t.TempDir()
+	write(t, dir, map[string]string{
+		"zscript.toml":      "[lint]\nrules = [\"unused-local\", \"missing-override\"]\ndisable = [\"missing-override\", \"skipped\"]\nqueries = \"rules\"",
+		"rules/no-goto.scm": "; message: avoid Goto\n(state_goto_target) @goto",
+		"rules/skipped.scm": "; message: skipped\n(identifier) @id",
+	})
+	c, err := config.Find(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l, err := c.Linter()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, r := range l.Rules {
+		names = append(names, r.Name())
+	}
+	if want := []string{"unused-local", "no-goto"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("got rules %v, want %v", names, want)
+	}
+
+	write(t, dir, map[string]string{"zscript.toml": "[lint]\nqueries = \"missing\""})
+	if c, err = config.Find(dir); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Linter(); err == nil {
+		t.Error("got no error for a missing queries directory")
+	}
+}
+
+func TestLoadProject(t *testing.T) {
+	dir := t.TempDir()
+	write(t, dir, map[string]string{
+		"lib/zscript.zs":      "#include \"base.zs\"",
+		"lib/base.zs":         "class LibBase : Actor {}",
+		"mod/zscript.toml":    "roots = [\"main.zs\"]\narchives = [\"../lib\"]",
+		"mod/main.zs":         "#include \"imp.zs\"",
+		"mod/imp.zs":          "class Imp : LibBase {}",
+		"mod/zscript.ignored": "class Ignored {}",
+	})
+	c, err := config.Find(filepath.Join(dir, "mod"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := c.LoadProject(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+	var paths []string
+	for _, f := range p.Files {
+		paths = append(paths, f.Path)
+		if f.Tree.Path != f.Path {
+			t.Errorf("tree of %s has path %s", f.Path, f.Tree.Path)
+		}
+	}
+	if want := []string{"../lib:base.zs", "../lib:zscript.zs", "imp.zs", "main.zs"}; !reflect.DeepEqual(paths, want) {
+		t.Errorf("got files %v, want %v", paths, want)
+	}
+	if f := p.File("../lib:zscript.zs"); f == nil || !reflect.DeepEqual(f.Includes, []string{"../lib:base.zs"}) {
+		t.Errorf("got archive root %+v", f)
+	}
+	if !c.IsArchived("../lib:base.zs") || c.IsArchived("imp.zs") {
+		t.Error("IsArchived does not tell the archive's files from the mod's")
+	}
+
+	write(t, dir, map[string]string{"mod/zscript.toml": "archives = [\"../nowhere.pk3\"]"})
+	if c, err = config.Find(filepath.Join(dir, "mod")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.LoadProject(context.Background()); err == nil || !strings.Contains(err.Error(), "no archive ../nowhere.pk3") {
+		t.Errorf("got error %v for a missing archive", err)
+	}
+}
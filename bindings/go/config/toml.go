@@ -0,0 +1,320 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// table is a TOML table: its keys map to strings, int64s, bools, []any
+// and tables.
+type table map[string]any
+
+// parseTOML parses the subset of TOML that configuration files use:
+// tables and dotted table headers, bare and quoted keys, basic and literal
+// strings, integers, booleans and arrays of them, which may span lines,
+// and comments.
+func parseTOML(text string) (table, error) {
+	p := &tomlParser{text: text, line: 1}
+	root := table{}
+	current := root
+	for {
+		p.skipBlank()
+		if p.done() {
+			return root, nil
+		}
+		if p.peek() == '[' {
+			p.pos++
+			if p.peek() == '[' {
+				return nil, p.errorf("arrays of tables are not supported")
+			}
+			keys, err := p.keys()
+			if err != nil {
+				return nil, err
+			}
+			if !p.consume(']') {
+				return nil, p.errorf("expected ] after table name")
+			}
+			if current, err = p.define(root, keys); err != nil {
+				return nil, err
+			}
+		} else {
+			keys, err := p.keys()
+			if err != nil {
+				return nil, err
+			}
+			if !p.consume('=') {
+				return nil, p.errorf("expected = after key %s", strings.Join(keys, "."))
+			}
+			value, err := p.value()
+			if err != nil {
+				return nil, err
+			}
+			t := current
+			for _, k := range keys[:len(keys)-1] {
+				if t, err = p.subtable(t, k); err != nil {
+					return nil, err
+				}
+			}
+			last := keys[len(keys)-1]
+			if _, ok := t[last]; ok {
+				return nil, p.errorf("key %s is defined twice", strings.Join(keys, "."))
+			}
+			t[last] = value
+		}
+		p.skipSpace()
+		if !p.done() && p.peek() != '\n' && p.peek() != '\r' {
+			return nil, p.errorf("expected the end of the line")
+		}
+	}
+}
+
+type tomlParser struct {
+	text string
+	pos  int
+	line int
+	// defined holds the tables given a header, by their dotted names.
+	defined map[string]bool
+}
+
+func (p *tomlParser) errorf(format string, args ...any) error {
+	return fmt.Errorf("%d: %s", p.line, fmt.Sprintf(format, args...))
+}
+
+func (p *tomlParser) done() bool { return p.pos >= len(p.text) }
+
+func (p *tomlParser) peek() byte {
+	if p.done() {
+		return 0
+	}
+	return p.text[p.pos]
+}
+
+// skipSpace skips spaces, tabs and a comment up to the end of the line.
+func (p *tomlParser) skipSpace() {
+	for !p.done() {
+		switch p.peek() {
+		case ' ', '\t':
+			p.pos++
+		case '#':
+			for !p.done() && p.peek() != '\n' {
+				p.pos++
+			}
+		default:
+			return
+		}
+	}
+}
+
+// skipBlank skips white space, comments and line breaks.
+func (p *tomlParser) skipBlank() {
+	for {
+		p.skipSpace()
+		switch p.peek() {
+		case '\n':
+			p.line++
+			p.pos++
+		case '\r':
+			p.pos++
+		default:
+			return
+		}
+	}
+}
+
+// consume skips space and then c, reporting whether c was there.
+func (p *tomlParser) consume(c byte) bool {
+	p.skipSpace()
+	if p.peek() != c {
+		return false
+	}
+	p.pos++
+	return true
+}
+
+// keys parses a dotted key.
+func (p *tomlParser) keys() ([]string, error) {
+	var keys []string
+	for {
+		p.skipSpace()
+		var key string
+		switch c := p.peek(); {
+		case c == '"' || c == '\'':
+			s, err := p.str()
+			if err != nil {
+				return nil, err
+			}
+			key = s
+		case bareKey(c):
+			start := p.pos
+			for !p.done() && bareKey(p.peek()) {
+				p.pos++
+			}
+			key = p.text[start:p.pos]
+		default:
+			return nil, p.errorf("expected a key")
+		}
+		keys = append(keys, key)
+		p.skipSpace()
+		if p.peek() != '.' {
+			return keys, nil
+		}
+		p.pos++
+	}
+}
+
+func bareKey(c byte) bool {
+	return c == '_' || c == '-' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+// define returns the table of a header naming keys, creating it.
+func (p *tomlParser) define(root table, keys []string) (table, error) {
+	name := strings.Join(keys, ".")
+	if p.defined == nil {
+		p.defined = map[string]bool{}
+	}
+	if p.defined[name] {
+		return nil, p.errorf("table %s is defined twice", name)
+	}
+	p.defined[name] = true
+	t := root
+	for _, k := range keys {
+		var err error
+		if t, err = p.subtable(t, k); err != nil {
+			return nil, err
+		}
+	}
+	return t, nil
+}
+
+// subtable returns the table under key in t, creating it.
+func (p *tomlParser) subtable(t table, key string) (table, error) {
+	switch v := t[key].(type) {
+	case nil:
+		sub := table{}
+		t[key] = sub
+		return sub, nil
+	case table:
+		return v, nil
+	}
+	return nil, p.errorf("key %s is not a table", key)
+}
+
+// value parses a string, integer, boolean or array.
+func (p *tomlParser) value() (any, error) {
+	p.skipSpace()
+	switch c := p.peek(); {
+	case c == '"' || c == '\'':
+		return p.str()
+	case c == '[':
+		p.pos++
+		values := []any{}
+		for {
+			p.skipBlank()
+			if p.peek() == ']' {
+				p.pos++
+				return values, nil
+			}
+			v, err := p.value()
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, v)
+			p.skipBlank()
+			if p.peek() == ',' {
+				p.pos++
+			} else if p.peek() != ']' {
+				return nil, p.errorf("expected , or ] in array")
+			}
+		}
+	case c == '{':
+		return nil, p.errorf("inline tables are not supported")
+	}
+	start := p.pos
+	for !p.done() && strings.IndexByte(" \t\r\n#,]", p.peek()) < 0 {
+		p.pos++
+	}
+	word := p.text[start:p.pos]
+	switch word {
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	case "":
+		return nil, p.errorf("expected a value")
+	}
+	n, err := strconv.ParseInt(strings.ReplaceAll(word, "_", ""), 0, 64)
+	if err != nil {
+		return nil, p.errorf("invalid value %s", word)
+	}
+	return n, nil
+}
+
+// str parses a basic or a literal string on one line.
+func (p *tomlParser) str() (string, error) {
+	quote := p.peek()
+	p.pos++
+	if strings.HasPrefix(p.text[p.pos:], string([]byte{quote, quote})) {
+		return "", p.errorf("multi-line strings are not supported")
+	}
+	var b strings.Builder
+	for {
+		if p.done() || p.peek() == '\n' {
+			return "", p.errorf("unterminated string")
+		}
+		c := p.peek()
+		p.pos++
+		switch {
+		case c == quote:
+			return b.String(), nil
+		case c == '\\' && quote == '"':
+			r, err := p.escape()
+			if err != nil {
+				return "", err
+			}
+			b.WriteRune(r)
+		default:
+			b.WriteByte(c)
+		}
+	}
+}
+
+// escape parses the escape sequence of a basic string after its
+// backslash.
+func (p *tomlParser) escape() (rune, error) {
+	if p.done() {
+		return 0, p.errorf("unterminated string")
+	}
+	c := p.peek()
+	p.pos++
+	switch c {
+	case 'b':
+		return '\b', nil
+	case 't':
+		return '\t', nil
+	case 'n':
+		return '\n', nil
+	case 'f':
+		return '\f', nil
+	case 'r':
+		return '\r', nil
+	case '"', '\\':
+		return rune(c), nil
+	case 'u', 'U':
+		n := 4
+		if c == 'U' {
+			n = 8
+		}
+		if p.pos+n > len(p.text) {
+			return 0, p.errorf("invalid escape \\%c", c)
+		}
+		code, err := strconv.ParseUint(p.text[p.pos:p.pos+n], 16, 32)
+		if err != nil || !utf8.ValidRune(rune(code)) {
+			return 0, p.errorf("invalid escape \\%c%s", c, p.text[p.pos:p.pos+n])
+		}
+		p.pos += n
+		return rune(code), nil
+	}
+	return 0, p.errorf("invalid escape \\%c", c)
+}
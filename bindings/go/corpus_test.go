@@ -0,0 +1,72 @@
+package tree_sitter_zscript_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	tree_sitter_zscript "github.com/jlcrochet/tree-sitter-zscript/bindings/go"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/internal/corpus"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/zscripttest"
+)
+
+// TestCorpus parses every example in test/corpus and compares the tree with
+// the expected S-expression, as "tree-sitter test" does.
+func TestCorpus(t *testing.T) {
+	examples, err := corpus.Load("../../test/corpus")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(examples) == 0 {
+		t.Fatal("no corpus examples found")
+	}
+	for _, e := range examples {
+		t.Run(e.File+"/"+e.Name, func(t *testing.T) {
+			if e.HasAttribute("skip") {
+				t.Skip("marked :skip")
+			}
+			tree, err := tree_sitter_zscript.Parse(context.Background(), []byte(e.Input))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer tree.Close()
+
+			root := tree.RootNode()
+			if e.HasAttribute("error") {
+				if !root.HasError() {
+					t.Errorf("expected a syntax error in:\n%s", e.Input)
+				}
+				return
+			}
+			if root.HasError() && !strings.Contains(e.Expected, "ERROR") && !strings.Contains(e.Expected, "MISSING") {
+				// A grammar bug: shrink the input to the smallest one that
+				// fails the same way before comparing the trees.
+				zscripttest.CheckParses(t, []byte(e.Input))
+			}
+			got, want := corpus.NormalizeSexp(root.ToSexp()), corpus.NormalizeSexp(e.Expected)
+			if got != want {
+				t.Errorf("tree mismatch for:\n%s\ngot:\n%s\nwant:\n%s", e.Input, got, want)
+			}
+		})
+	}
+}
+
+// TestFixturesParse parses the benchmark fixtures, which are real scripts,
+// reporting the smallest input that reproduces any syntax error.
+func TestFixturesParse(t *testing.T) {
+	paths, err := filepath.Glob("testdata/bench/*.zs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range paths {
+		t.Run(filepath.Base(path), func(t *testing.T) {
+			source, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			zscripttest.CheckParses(t, source)
+		})
+	}
+}
@@ -0,0 +1,152 @@
+package cvarinfo
+
+import (
+	"fmt"
+	"strings"
+
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+
+	zscript "github.com/jlcrochet/tree-sitter-zscript/bindings/go"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/lint"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/project"
+)
+
+// The rule names of the findings.
+const (
+	// RuleUndefinedCvar reports a cvar looked up by name that no lump
+	// declares.
+	RuleUndefinedCvar = "undefined-cvar"
+	// RuleUnusedCvar reports a declared cvar that nothing uses.
+	RuleUnusedCvar = "unused-cvar"
+)
+
+// Options configures CheckWithOptions.
+type Options struct {
+	// Known are cvars declared outside the lumps checked, such as by
+	// another archive.
+	Known []string
+	// Used are names used outside ZScript, such as the words returned by
+	// ScanLumps.
+	Used []string
+}
+
+// EnginePrefixes are the name prefixes of the engine's own cvars. A lookup
+// of a name with one of them is never reported undefined.
+var EnginePrefixes = []string{
+	"am_", "cl_", "con_", "compat_", "crosshair", "dmflags", "g_", "gl_",
+	"hud_", "i_", "in_", "m_", "r_", "snd_", "st_", "sv_", "ui_", "vid_",
+	"vr_", "autoaim", "developer", "fov", "freelook", "gender", "name",
+	"neverswitchonpickup", "playerclass", "screenblocks", "skill", "team",
+}
+
+// Reference is a cvar named by a string or name literal in a call of
+// CVar.FindCVar or CVar.GetCVar.
+type Reference struct {
+	Name  string
+	Path  string
+	Range tree_sitter.Range
+}
+
+// lookups are the lowercased CVar methods that take a cvar name first.
+var lookups = map[string]bool{"findcvar": true, "getcvar": true}
+
+// References returns the cvar lookups in tree, in source order.
+func References(tree *zscript.Tree) []Reference {
+	var refs []Reference
+	var v zscript.Visitor
+	v.On(zscript.NodeCallExpression, func(node *tree_sitter.Node) zscript.WalkAction {
+		fn := node.ChildByFieldName(zscript.FieldFunction)
+		args := node.ChildByFieldName(zscript.FieldArguments)
+		if fn == nil || args == nil || fn.Kind() != zscript.NodeFieldExpression || args.NamedChildCount() == 0 {
+			return zscript.WalkContinue
+		}
+		receiver, method := fn.ChildByFieldName(zscript.FieldArgument), fn.ChildByFieldName(zscript.FieldField)
+		if receiver == nil || method == nil || !strings.EqualFold(receiver.Utf8Text(tree.Source), "CVar") ||
+			!lookups[strings.ToLower(method.Utf8Text(tree.Source))] {
+			return zscript.WalkContinue
+		}
+		arg := args.NamedChild(0)
+		if k := arg.Kind(); k == zscript.NodeStringLiteral || k == zscript.NodeNameLiteral {
+			refs = append(refs, Reference{
+				Name:  strings.Trim(arg.Utf8Text(tree.Source), `"'`),
+				Path:  tree.Path,
+				Range: arg.Range(),
+			})
+		}
+		return zscript.WalkContinue
+	})
+	zscript.Walk(tree.RootNode(), &v)
+	return refs
+}
+
+// Check reports the cvar lookups in p that files do not declare, and the
+// cvars of files that nothing uses. A cvar is used when it is looked up,
+// or when an identifier in ZScript names it, as server cvars can be read
+// directly. ZScript findings come first, in file order, then those of the
+// lumps.
+func Check(p *project.Project, files ...*File) []lint.Finding {
+	return CheckWithOptions(p, Options{}, files...)
+}
+
+// CheckWithOptions is like Check but takes options.
+func CheckWithOptions(p *project.Project, opts Options, files ...*File) []lint.Finding {
+	declared := map[string]bool{}
+	for _, name := range opts.Known {
+		declared[strings.ToLower(name)] = true
+	}
+	for _, f := range files {
+		for _, c := range f.Cvars {
+			declared[strings.ToLower(c.Name)] = true
+		}
+	}
+	used := map[string]bool{}
+	for _, name := range opts.Used {
+		used[strings.ToLower(name)] = true
+	}
+
+	var findings []lint.Finding
+	for _, f := range p.Files {
+		for _, r := range References(f.Tree) {
+			name := strings.ToLower(r.Name)
+			used[name] = true
+			if !declared[name] && !isEngineCvar(name) {
+				findings = append(findings, lint.Finding{
+					Rule:     RuleUndefinedCvar,
+					Severity: zscript.SeverityWarning,
+					Path:     r.Path,
+					Range:    r.Range,
+					Message:  fmt.Sprintf("cvar %q is not declared in CVARINFO", r.Name),
+				})
+			}
+		}
+		var v zscript.Visitor
+		v.On(zscript.NodeIdentifier, func(node *tree_sitter.Node) zscript.WalkAction {
+			used[strings.ToLower(node.Utf8Text(f.Tree.Source))] = true
+			return zscript.WalkContinue
+		})
+		zscript.Walk(f.Tree.RootNode(), &v)
+	}
+	for _, f := range files {
+		for _, c := range f.Cvars {
+			if !used[strings.ToLower(c.Name)] {
+				findings = append(findings, lint.Finding{
+					Rule:     RuleUnusedCvar,
+					Severity: zscript.SeverityWarning,
+					Path:     f.Path,
+					Range:    c.Range,
+					Message:  fmt.Sprintf("cvar %q is never used", c.Name),
+				})
+			}
+		}
+	}
+	return findings
+}
+
+func isEngineCvar(name string) bool {
+	for _, prefix := range EnginePrefixes {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
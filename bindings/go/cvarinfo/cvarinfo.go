@@ -0,0 +1,291 @@
+// Package cvarinfo reads CVARINFO lumps and checks them against the
+// console variables a ZScript project uses.
+//
+// A CVARINFO lump declares one variable per statement:
+//
+//	server noarchive int mymod_spawnrate = 3;
+//
+// that is, a scope ("server", "user" or "nosave"), optional flags, a type,
+// a name and an optional default, ended by a semicolon.
+package cvarinfo
+
+import (
+	"fmt"
+	"io/fs"
+	"path"
+	"strings"
+
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+)
+
+// Cvar is a declared console variable.
+type Cvar struct {
+	Name string
+	// Scope is "server", "user" or "nosave", in lower case.
+	Scope string
+	// Flags are the words between the scope and the type, such as
+	// "noarchive" or "cheat", in lower case.
+	Flags []string
+	// Type is "int", "float", "bool", "color" or "string", in lower case.
+	Type string
+	// Default is the text of the default value, without the quotes of a
+	// string, or "" if there is none.
+	Default string
+	// Range locates the name.
+	Range tree_sitter.Range
+}
+
+// File is a parsed lump.
+type File struct {
+	Path  string
+	Cvars []*Cvar
+}
+
+// Lookup returns the cvar named name, ignoring case, or nil.
+func (f *File) Lookup(name string) *Cvar {
+	for _, c := range f.Cvars {
+		if strings.EqualFold(c.Name, name) {
+			return c
+		}
+	}
+	return nil
+}
+
+var (
+	scopes = map[string]bool{"server": true, "user": true, "nosave": true}
+	types  = map[string]bool{"int": true, "float": true, "bool": true, "color": true, "string": true}
+)
+
+// Error is a syntax error in a lump.
+type Error struct {
+	Path    string
+	Point   tree_sitter.Point
+	Message string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("cvarinfo: %s:%d:%d: %s", e.Path, e.Point.Row+1, e.Point.Column+1, e.Message)
+}
+
+// Parse parses the lump source read from path.
+func Parse(path string, source []byte) (*File, error) {
+	tokens, err := scan(path, source)
+	if err != nil {
+		return nil, err
+	}
+	f := &File{Path: path}
+	errorAt := func(t word, format string, args ...any) error {
+		return &Error{Path: path, Point: t.Range.StartPoint, Message: fmt.Sprintf(format, args...)}
+	}
+	for len(tokens) > 0 {
+		end := 0
+		for end < len(tokens) && tokens[end].text != ";" {
+			end++
+		}
+		if end == len(tokens) {
+			return nil, errorAt(tokens[len(tokens)-1], "missing ;")
+		}
+		stmt := tokens[:end]
+		tokens = tokens[end+1:]
+		if len(stmt) == 0 {
+			continue
+		}
+
+		c := &Cvar{}
+		first := stmt[0]
+		var def []word
+		if i := indexOf(stmt, "="); i >= 0 {
+			if len(stmt[i+1:]) != 1 {
+				return nil, errorAt(stmt[i], "default value must be a single word or string")
+			}
+			stmt, def = stmt[:i], stmt[i+1:]
+			c.Default = def[0].text
+		}
+		if len(stmt) < 3 {
+			return nil, errorAt(first, "expected scope, type and name")
+		}
+		c.Scope = strings.ToLower(stmt[0].text)
+		if !scopes[c.Scope] || stmt[0].quoted {
+			return nil, errorAt(stmt[0], "unknown scope %q", stmt[0].text)
+		}
+		typ, name := stmt[len(stmt)-2], stmt[len(stmt)-1]
+		c.Type = strings.ToLower(typ.text)
+		if !types[c.Type] || typ.quoted {
+			return nil, errorAt(typ, "unknown type %q", typ.text)
+		}
+		if name.quoted {
+			return nil, errorAt(name, "expected a name")
+		}
+		c.Name, c.Range = name.text, name.Range
+		for _, flag := range stmt[1 : len(stmt)-2] {
+			c.Flags = append(c.Flags, strings.ToLower(flag.text))
+		}
+		f.Cvars = append(f.Cvars, c)
+	}
+	return f, nil
+}
+
+func indexOf(tokens []word, text string) int {
+	for i, t := range tokens {
+		if !t.quoted && t.text == text {
+			return i
+		}
+	}
+	return -1
+}
+
+// word is a token of a lump.
+type word struct {
+	text   string
+	quoted bool
+	tree_sitter.Range
+}
+
+// scan splits source into words, strings and the punctuation "=" and ";",
+// skipping comments.
+func scan(path string, source []byte) ([]word, error) {
+	var words []word
+	var point tree_sitter.Point
+	i := 0
+	advance := func() {
+		if source[i] == '\n' {
+			point.Row++
+			point.Column = 0
+		} else {
+			point.Column++
+		}
+		i++
+	}
+	for i < len(source) {
+		c := source[i]
+		start, startPoint := i, point
+		switch {
+		case c == ' ' || c == '\t' || c == '\r' || c == '\n':
+			advance()
+			continue
+		case c == '/' && i+1 < len(source) && source[i+1] == '/':
+			for i < len(source) && source[i] != '\n' {
+				advance()
+			}
+			continue
+		case c == '/' && i+1 < len(source) && source[i+1] == '*':
+			advance()
+			advance()
+			for i < len(source) && !(source[i] == '*' && i+1 < len(source) && source[i+1] == '/') {
+				advance()
+			}
+			if i >= len(source) {
+				return nil, &Error{Path: path, Point: startPoint, Message: "unterminated comment"}
+			}
+			advance()
+			advance()
+			continue
+		}
+
+		w := word{}
+		switch {
+		case c == '"':
+			w.quoted = true
+			advance()
+			var text strings.Builder
+			for i < len(source) && source[i] != '"' {
+				if source[i] == '\\' && i+1 < len(source) {
+					advance()
+				}
+				text.WriteByte(source[i])
+				advance()
+			}
+			if i >= len(source) {
+				return nil, &Error{Path: path, Point: startPoint, Message: "unterminated string"}
+			}
+			advance()
+			w.text = text.String()
+		case c == '=' || c == ';':
+			advance()
+			w.text = string(c)
+		default:
+			for i < len(source) && !strings.ContainsRune(" \t\r\n\"=;", rune(source[i])) &&
+				!(source[i] == '/' && i+1 < len(source) && (source[i+1] == '/' || source[i+1] == '*')) {
+				advance()
+			}
+			w.text = string(source[start:i])
+		}
+		w.Range = tree_sitter.Range{StartByte: uint(start), EndByte: uint(i), StartPoint: startPoint, EndPoint: point}
+		words = append(words, w)
+	}
+	return words, nil
+}
+
+// Load reads every CVARINFO lump at the root of fsys. A lump may have an
+// extension, as in "cvarinfo.txt".
+func Load(fsys fs.FS) ([]*File, error) {
+	names, err := rootLumps(fsys, "cvarinfo")
+	if err != nil {
+		return nil, err
+	}
+	var files []*File
+	for _, name := range names {
+		source, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return nil, err
+		}
+		f, err := Parse(name, source)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, f)
+	}
+	return files, nil
+}
+
+// usageLumps are the lumps whose words ScanLumps collects.
+var usageLumps = []string{"keyconf", "menudef"}
+
+// ScanLumps returns the words and strings of the KEYCONF and MENUDEF lumps
+// at the root of fsys, which name the cvars that key bindings and menus
+// use. Passed as Options.Used, they keep those cvars from being reported
+// unused.
+func ScanLumps(fsys fs.FS) ([]string, error) {
+	var used []string
+	for _, lump := range usageLumps {
+		names, err := rootLumps(fsys, lump)
+		if err != nil {
+			return nil, err
+		}
+		for _, name := range names {
+			source, err := fs.ReadFile(fsys, name)
+			if err != nil {
+				return nil, err
+			}
+			words, err := scan(name, source)
+			if err != nil {
+				return nil, err
+			}
+			for _, w := range words {
+				// Commands such as "toggle mymod_x; wait" come as one
+				// string.
+				used = append(used, strings.FieldsFunc(w.text, func(r rune) bool {
+					return r == ';' || r == ' ' || r == '\t'
+				})...)
+			}
+		}
+	}
+	return used, nil
+}
+
+// rootLumps returns the files at the root of fsys named lump, ignoring
+// case and extension.
+func rootLumps(fsys fs.FS, lump string) ([]string, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, e := range entries {
+		name := strings.ToLower(e.Name())
+		if !e.IsDir() && strings.TrimSuffix(name, path.Ext(name)) == lump {
+			names = append(names, e.Name())
+		}
+	}
+	return names, nil
+}
@@ -0,0 +1,124 @@
+package cvarinfo_test
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/cvarinfo"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/project"
+)
+
+const lump = `// Gameplay.
+server int mymod_spawnrate = 3;
+server noarchive bool mymod_hardcore = false;
+user string mymod_name = "Marine \"Doom\" Guy";
+user float mymod_unused;
+nosave color mymod_tint = "ff 00 00"; /* bound in KEYCONF */
+server bool mymod_direct;
+`
+
+const source = `class Spawner : Actor {
+	override void BeginPlay() {
+		let rate = CVar.FindCVar("mymod_spawnrate").GetInt();
+		let hard = CVar.GetCVar('MyMod_Hardcore', players[0]).GetBool();
+		let fov = CVar.FindCVar("fov").GetFloat();
+		let name = CVar.GetCVar("mymod_nmae", players[0]).GetString();
+		if (mymod_direct) Destroy();
+	}
+}
+`
+
+func TestParse(t *testing.T) {
+	f, err := cvarinfo.Parse("cvarinfo", []byte(lump))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, c := range f.Cvars {
+		got = append(got, strings.Join([]string{c.Scope, strings.Join(c.Flags, ","), c.Type, c.Name, c.Default}, "|"))
+	}
+	want := []string{
+		"server||int|mymod_spawnrate|3",
+		"server|noarchive|bool|mymod_hardcore|false",
+		`user||string|mymod_name|Marine "Doom" Guy`,
+		"user||float|mymod_unused|",
+		"nosave||color|mymod_tint|ff 00 00",
+		"server||bool|mymod_direct|",
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("got:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+	if c := f.Lookup("MYMOD_SPAWNRATE"); c == nil || c.Range.StartPoint.Row != 1 || c.Range.StartPoint.Column != 11 {
+		t.Errorf("Lookup = %+v", c)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct{ source, want string }{
+		{"server int x", `cvarinfo: cvarinfo:1:12: missing ;`},
+		{"global int x;", `cvarinfo: cvarinfo:1:1: unknown scope "global"`},
+		{"server integer x;", `cvarinfo: cvarinfo:1:8: unknown type "integer"`},
+		{"server int;", `cvarinfo: cvarinfo:1:1: expected scope, type and name`},
+		{"server int x = 1 2;", `cvarinfo: cvarinfo:1:14: default value must be a single word or string`},
+		{`user string x = "open;`, `cvarinfo: cvarinfo:1:17: unterminated string`},
+	}
+	for _, test := range tests {
+		_, err := cvarinfo.Parse("cvarinfo", []byte(test.source))
+		if err == nil || err.Error() != test.want {
+			t.Errorf("Parse(%q) = %v, want %s", test.source, err, test.want)
+		}
+	}
+}
+
+func TestCheck(t *testing.T) {
+	fsys := fstest.MapFS{
+		"zscript.zs":   {Data: []byte(source)},
+		"CVARINFO.txt": {Data: []byte(lump)},
+		"KEYCONF":      {Data: []byte(`addkeysection "My Mod" mymod` + "\n" + `alias mymod_redder "set mymod_tint ff0000; echo red"` + "\n")},
+		"MENUDEF":      {Data: []byte(`OptionMenu "MyModOptions" { TextField "Name", "mymod_name" }`)},
+	}
+	p, err := project.Load(context.Background(), fsys, "zscript.zs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+	files, err := cvarinfo.Load(fsys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 || files[0].Path != "CVARINFO.txt" {
+		t.Fatalf("Load read %d files", len(files))
+	}
+	used, err := cvarinfo.ScanLumps(fsys)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	for _, f := range cvarinfo.CheckWithOptions(p, cvarinfo.Options{Used: used}, files...) {
+		got = append(got, f.String())
+	}
+	want := []string{
+		`zscript.zs:6:27: warning: cvar "mymod_nmae" is not declared in CVARINFO (undefined-cvar)`,
+		`CVARINFO.txt:5:12: warning: cvar "mymod_unused" is never used (unused-cvar)`,
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("got:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	got = nil
+	for _, f := range cvarinfo.CheckWithOptions(p, cvarinfo.Options{Known: []string{"mymod_nmae"}}, files...) {
+		got = append(got, f.Rule+" "+f.Message)
+	}
+	want = []string{
+		`unused-cvar cvar "mymod_name" is never used`,
+		`unused-cvar cvar "mymod_unused" is never used`,
+		`unused-cvar cvar "mymod_tint" is never used`,
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("with Known and without Used:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
@@ -0,0 +1,478 @@
+// Package daemon serves the analyses of this module over JSON-RPC 2.0,
+// framed as the Language Server Protocol frames it, for build systems and
+// editors that do not speak LSP. A daemon keeps every ZScript file of its
+// workspace parsed, and the symbols lint reads of them, between requests,
+// parsing again only those that changed on disk, so that its clients need
+// not start the zscript command and read the whole mod once per check.
+//
+// The methods take their parameters as an object and are:
+//
+//	parse    {path, text?, tree?}  the syntax errors of a file, and its parse
+//	                               tree as Dump writes it as JSON if tree is set
+//	symbols  {path, text?}         the outline of the declarations of a file
+//	lint     {paths?}              the syntax errors and lint findings of the
+//	                               given files, or of all of them
+//	format   {path?, text?}        the formatted text of a file
+//	exit                           close the connection
+//
+// Paths are relative to the workspace, or absolute paths inside it, and
+// are reported relative to it; lines and columns count from 1, as the
+// JSON output of package lint has them. A request giving the text of a
+// file takes it in place of the file on disk, as an editor's unsaved
+// buffer, until the file changes on disk; format with a text and no path
+// formats the text alone. The lint rules, the dialect and the format
+// options are those of the .zscriptrc or zscript.toml file of the
+// workspace, as package config reads it.
+package daemon
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	zscript "github.com/jlcrochet/tree-sitter-zscript/bindings/go"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/config"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/engine"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/format"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/hierarchy"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/internal/jsonrpc"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/lint"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/symbols"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/watch"
+)
+
+// Server is a daemon for the workspace in one directory. It may serve
+// several connections at once; their requests are handled one at a time.
+type Server struct {
+	// ParseTimeout bounds the time spent parsing one file. Zero means no
+	// limit. A file whose parse times out is left out of the index.
+	ParseTimeout time.Duration
+	// ScanInterval is the least time between two scans of the workspace
+	// for changes on disk; the requests in between use the files as the
+	// last scan left them. Zero means a scan before every request.
+	ScanInterval time.Duration
+
+	root    string
+	config  *config.Config
+	linter  *lint.Linter
+	watcher *watch.Watcher
+
+	mu sync.Mutex
+	// scanned is when the workspace was last scanned.
+	scanned time.Time
+	// files are the parsed files of the workspace, by path relative to
+	// root.
+	files map[string]*zscript.Tree
+	// tables are the symbol tables of files, built as lint needs them,
+	// and hierarchy that of every file, or nil if a file changed since
+	// it was built.
+	tables    map[string]*symbols.Table
+	hierarchy *hierarchy.Hierarchy
+}
+
+// DefaultParseTimeout is the ParseTimeout of a server returned by
+// NewServer.
+const DefaultParseTimeout = 5 * time.Second
+
+// NewServer returns a server for the workspace in dir, reading its
+// configuration file. The files are parsed on the first request.
+func NewServer(dir string) (*Server, error) {
+	cfg, err := config.Find(dir)
+	if err != nil {
+		return nil, err
+	}
+	linter, err := cfg.Linter()
+	if err != nil {
+		return nil, err
+	}
+	return &Server{
+		ParseTimeout: DefaultParseTimeout,
+		ScanInterval: watch.DefaultInterval,
+		root:         dir,
+		config:       cfg,
+		linter:       linter,
+		watcher:      watch.New(dir),
+		files:        map[string]*zscript.Tree{},
+		tables:       map[string]*symbols.Table{},
+	}, nil
+}
+
+// Serve serves one connection, reading requests from r and writing
+// responses to w, until the client sends "exit" or r is exhausted.
+func (s *Server) Serve(ctx context.Context, r io.Reader, w io.Writer) error {
+	return jsonrpc.Serve(ctx, jsonrpc.NewConn(r, w), s.handle)
+}
+
+// ServeListener serves the connections l accepts, each as Serve does,
+// until ctx is done or l fails. It closes l.
+func (s *Server) ServeListener(ctx context.Context, l net.Listener) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		<-ctx.Done()
+		l.Close()
+	}()
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return err
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			stop := context.AfterFunc(ctx, func() { conn.Close() })
+			defer stop()
+			defer conn.Close()
+			// A connection that ends badly concerns its client only.
+			s.Serve(ctx, conn, conn)
+		}()
+	}
+}
+
+// Close releases the parsed files.
+func (s *Server) Close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, tree := range s.files {
+		tree.Close()
+	}
+	s.files = map[string]*zscript.Tree{}
+	s.tables = map[string]*symbols.Table{}
+	s.hierarchy = nil
+}
+
+// ParseParams are the parameters of parse.
+type ParseParams struct {
+	Path string  `json:"path"`
+	Text *string `json:"text,omitempty"`
+	Tree bool    `json:"tree,omitempty"`
+}
+
+// ParseResult is the result of parse.
+type ParseResult struct {
+	// Diagnostics are the syntax errors, as lint findings without a rule.
+	Diagnostics []lint.Finding `json:"diagnostics"`
+	// Tree is the tree as Dump writes it with DumpJSON.
+	Tree json.RawMessage `json:"tree,omitempty"`
+}
+
+// SymbolsParams are the parameters of symbols.
+type SymbolsParams struct {
+	Path string  `json:"path"`
+	Text *string `json:"text,omitempty"`
+}
+
+// Symbol is a declaration of the outline symbols returns, as
+// symbols.Outline has it. Its range is that of its name.
+type Symbol struct {
+	Name      string   `json:"name"`
+	Detail    string   `json:"detail,omitempty"`
+	Kind      string   `json:"kind"`
+	Line      uint     `json:"line"`
+	Column    uint     `json:"column"`
+	EndLine   uint     `json:"endLine"`
+	EndColumn uint     `json:"endColumn"`
+	Children  []Symbol `json:"children,omitempty"`
+}
+
+// LintParams are the parameters of lint.
+type LintParams struct {
+	Paths []string `json:"paths,omitempty"`
+}
+
+// FormatParams are the parameters of format.
+type FormatParams struct {
+	Path string  `json:"path,omitempty"`
+	Text *string `json:"text,omitempty"`
+}
+
+// FormatResult is the result of format.
+type FormatResult struct {
+	Text string `json:"text"`
+}
+
+func (s *Server) handle(ctx context.Context, method string, params json.RawMessage) (any, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.refresh(ctx); err != nil {
+		return nil, err
+	}
+
+	switch method {
+	case "parse":
+		var p ParseParams
+		if err := unmarshal(params, &p); err != nil {
+			return nil, err
+		}
+		tree, err := s.file(ctx, p.Path, p.Text)
+		if err != nil {
+			return nil, err
+		}
+		r := &ParseResult{Diagnostics: lint.SyntaxErrors(tree)}
+		if r.Diagnostics == nil {
+			r.Diagnostics = []lint.Finding{}
+		}
+		if p.Tree {
+			r.Tree = zscript.Dump(tree.Tree, tree.Source, zscript.DumpJSON)
+		}
+		return r, nil
+	case "symbols":
+		var p SymbolsParams
+		if err := unmarshal(params, &p); err != nil {
+			return nil, err
+		}
+		tree, err := s.file(ctx, p.Path, p.Text)
+		if err != nil {
+			return nil, err
+		}
+		return outline(symbols.Outline(tree)), nil
+	case "lint":
+		var p LintParams
+		if err := unmarshal(params, &p); err != nil {
+			return nil, err
+		}
+		return s.lint(p.Paths)
+	case "format":
+		var p FormatParams
+		if err := unmarshal(params, &p); err != nil {
+			return nil, err
+		}
+		return s.format(ctx, p)
+	case "exit":
+		return nil, jsonrpc.ErrStop
+	}
+	return nil, jsonrpc.Errorf(jsonrpc.CodeMethodNotFound, "method not found: %s", method)
+}
+
+func unmarshal(params json.RawMessage, v any) error {
+	if len(params) == 0 {
+		params = json.RawMessage("{}")
+	}
+	if err := json.Unmarshal(params, v); err != nil {
+		return jsonrpc.Errorf(jsonrpc.CodeInvalidParams, "invalid params: %v", err)
+	}
+	return nil
+}
+
+// refresh parses the files of the workspace that changed on disk since
+// the last scan, if it is ScanInterval old, and forgets those removed. A
+// file given by a request stays in place of that on disk until it changes
+// there.
+func (s *Server) refresh(ctx context.Context) error {
+	if !s.scanned.IsZero() && time.Since(s.scanned) < s.ScanInterval {
+		return nil
+	}
+	s.scanned = time.Now()
+	changes, err := s.watcher.Scan()
+	if err != nil {
+		return fmt.Errorf("daemon: %w", err)
+	}
+	for _, c := range changes {
+		rel, err := filepath.Rel(s.root, c.Path)
+		if err != nil {
+			continue
+		}
+		s.replace(rel, nil)
+		if c.Removed {
+			continue
+		}
+		// A file that cannot be read or parsed is skipped, as it may be
+		// in the middle of being written.
+		source, err := os.ReadFile(c.Path)
+		if err != nil {
+			continue
+		}
+		if tree, err := s.parse(ctx, rel, source); err == nil {
+			s.replace(rel, tree)
+		}
+	}
+	return nil
+}
+
+// replace puts tree in place of the file at rel, or removes the file if
+// tree is nil, forgetting the symbols of the file it replaces.
+func (s *Server) replace(rel string, tree *zscript.Tree) {
+	if old := s.files[rel]; old != nil {
+		old.Close()
+		delete(s.files, rel)
+		delete(s.tables, rel)
+		s.hierarchy = nil
+	}
+	if tree != nil {
+		s.files[rel] = tree
+		s.hierarchy = nil
+	}
+}
+
+func (s *Server) parse(ctx context.Context, path string, source []byte) (*zscript.Tree, error) {
+	if s.ParseTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.ParseTimeout)
+		defer cancel()
+	}
+	tree, err := zscript.Parse(ctx, source)
+	if err != nil {
+		return nil, fmt.Errorf("daemon: %s: %w", path, err)
+	}
+	tree.Path = path
+	tree.Dialect = s.config.Dialect()
+	return tree, nil
+}
+
+// path returns p, a path of a request, relative to the workspace.
+func (s *Server) path(p string) (string, error) {
+	if p == "" {
+		return "", jsonrpc.Errorf(jsonrpc.CodeInvalidParams, "no path")
+	}
+	root, err := filepath.Abs(s.root)
+	if err != nil {
+		return "", fmt.Errorf("daemon: %w", err)
+	}
+	abs := p
+	if !filepath.IsAbs(p) {
+		abs = filepath.Join(root, p)
+	}
+	rel, err := filepath.Rel(root, abs)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", jsonrpc.Errorf(jsonrpc.CodeInvalidParams, "%s is outside the workspace", p)
+	}
+	return rel, nil
+}
+
+// file returns the tree of the file at path, first parsing text in place
+// of the file if it is not nil.
+func (s *Server) file(ctx context.Context, path string, text *string) (*zscript.Tree, error) {
+	rel, err := s.path(path)
+	if err != nil {
+		return nil, err
+	}
+	old := s.files[rel]
+	if text != nil && (old == nil || string(old.Source) != *text) {
+		tree, err := s.parse(ctx, rel, []byte(*text))
+		if err != nil {
+			return nil, err
+		}
+		s.replace(rel, tree)
+		return tree, nil
+	}
+	if old == nil {
+		return nil, jsonrpc.Errorf(jsonrpc.CodeInvalidParams, "no file %s in the workspace", path)
+	}
+	return old, nil
+}
+
+// lint returns the syntax errors and the findings of the configured lint
+// rules of the files at paths, or of every file, linting them with the
+// rest of the workspace.
+func (s *Server) lint(paths []string) ([]lint.Finding, error) {
+	trees := make([]*zscript.Tree, 0, len(s.files))
+	for _, tree := range s.files {
+		trees = append(trees, tree)
+	}
+	sort.Slice(trees, func(i, j int) bool { return trees[i].Path < trees[j].Path })
+
+	var only []string
+	wanted := map[string]bool{}
+	for _, p := range paths {
+		rel, err := s.path(p)
+		if err != nil {
+			return nil, err
+		}
+		if s.files[rel] == nil {
+			return nil, jsonrpc.Errorf(jsonrpc.CodeInvalidParams, "no file %s in the workspace", p)
+		}
+		only = append(only, rel)
+		wanted[rel] = true
+	}
+
+	findings := []lint.Finding{}
+	for _, tree := range trees {
+		if len(wanted) == 0 || wanted[tree.Path] {
+			findings = append(findings, lint.SyntaxErrors(tree)...)
+		}
+	}
+	if s.linter != nil && len(s.linter.Rules) > 0 {
+		tables := make([]*symbols.Table, len(trees))
+		for i, tree := range trees {
+			if tables[i] = s.tables[tree.Path]; tables[i] == nil {
+				tables[i] = symbols.Extract(tree)
+				s.tables[tree.Path] = tables[i]
+			}
+		}
+		if s.hierarchy == nil {
+			s.hierarchy = engine.Hierarchy(tables...)
+		}
+		findings = append(findings, s.linter.Tables(tables, s.hierarchy, only, trees...)...)
+	}
+	sort.SliceStable(findings, func(i, j int) bool {
+		a, b := findings[i], findings[j]
+		if a.Path != b.Path {
+			return a.Path < b.Path
+		}
+		return a.Range.StartByte < b.Range.StartByte
+	})
+	return findings, nil
+}
+
+func (s *Server) format(ctx context.Context, p FormatParams) (*FormatResult, error) {
+	var tree *zscript.Tree
+	switch {
+	case p.Path != "":
+		var err error
+		if tree, err = s.file(ctx, p.Path, p.Text); err != nil {
+			return nil, err
+		}
+	case p.Text != nil:
+		var err error
+		if tree, err = s.parse(ctx, "", []byte(*p.Text)); err != nil {
+			return nil, err
+		}
+		defer tree.Close()
+	default:
+		return nil, jsonrpc.Errorf(jsonrpc.CodeInvalidParams, "no path or text")
+	}
+	out, err := format.Tree(tree, s.config.Format)
+	if errors.Is(err, format.ErrSyntax) {
+		return nil, jsonrpc.Errorf(jsonrpc.CodeInvalidParams, "%v", err)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &FormatResult{Text: string(out)}, nil
+}
+
+// outline converts items to symbols.
+func outline(items []symbols.OutlineItem) []Symbol {
+	result := []Symbol{}
+	for _, item := range items {
+		start, end := item.SelectionRange.StartPoint, item.SelectionRange.EndPoint
+		sym := Symbol{
+			Name:      item.Name,
+			Detail:    item.Detail,
+			Kind:      item.Kind.String(),
+			Line:      start.Row + 1,
+			Column:    start.Column + 1,
+			EndLine:   end.Row + 1,
+			EndColumn: end.Column + 1,
+		}
+		if len(item.Children) > 0 {
+			sym.Children = outline(item.Children)
+		}
+		result = append(result, sym)
+	}
+	return result
+}
@@ -0,0 +1,252 @@
+package daemon_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/daemon"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/internal/jsonrpc"
+)
+
+type client struct {
+	conn   *jsonrpc.Conn
+	nextID int
+}
+
+// call sends a request and decodes its result into result, returning the
+// error of the response.
+func (c *client) call(t *testing.T, method string, params, result any) *jsonrpc.Error {
+	t.Helper()
+	c.nextID++
+	id := json.RawMessage(strconv.Itoa(c.nextID))
+	raw, _ := json.Marshal(params)
+	if err := c.conn.Write(&jsonrpc.Message{ID: id, Method: method, Params: raw}); err != nil {
+		t.Fatal(err)
+	}
+	m, err := c.conn.Read()
+	if err != nil {
+		t.Fatalf("%s: %v", method, err)
+	}
+	if m.Error != nil {
+		return m.Error
+	}
+	if result != nil {
+		if err := json.Unmarshal(m.Result, result); err != nil {
+			t.Fatalf("%s: %v", method, err)
+		}
+	}
+	return nil
+}
+
+func write(t *testing.T, dir, name, text string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(text), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestServer(t *testing.T) {
+	dir := t.TempDir()
+	write(t, dir, "zscript.toml", "[lint]\nrules = [\"missing-override\"]\n")
+	write(t, dir, "base.zs", "class Base : Actor { virtual void Tick() {} }\n")
+	write(t, dir, "derived.zs", "class Derived : Base { void Tick() {} }\n")
+
+	s, err := daemon.NewServer(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	// The files written during the test are seen by the next request.
+	s.ScanInterval = 0
+	c, done := serve(t, s)
+
+	t.Run("parse", func(t *testing.T) {
+		var r struct {
+			Diagnostics []struct{ Path, Message string }
+			Tree        *struct{ Type string }
+		}
+		if err := c.call(t, "parse", daemon.ParseParams{Path: "base.zs", Tree: true}, &r); err != nil {
+			t.Fatal(err)
+		}
+		if len(r.Diagnostics) != 0 || r.Tree == nil || r.Tree.Type != "source_file" {
+			t.Errorf("parse = %+v", r)
+		}
+		text := "class Base : Actor { int x }\n"
+		r.Tree = nil
+		if err := c.call(t, "parse", daemon.ParseParams{Path: filepath.Join(dir, "base.zs"), Text: &text}, &r); err != nil {
+			t.Fatal(err)
+		}
+		if len(r.Diagnostics) != 1 || r.Diagnostics[0].Path != "base.zs" || r.Tree != nil {
+			t.Errorf("parse of an unsaved text = %+v", r)
+		}
+		if err := c.call(t, "parse", daemon.ParseParams{Path: "none.zs"}, nil); err == nil || err.Code != jsonrpc.CodeInvalidParams {
+			t.Errorf("parse of a missing file: error %v", err)
+		}
+		for _, path := range []string{filepath.Dir(dir), "../base.zs", "sub/../../base.zs"} {
+			if err := c.call(t, "parse", daemon.ParseParams{Path: path}, nil); err == nil || !strings.Contains(err.Message, "outside the workspace") {
+				t.Errorf("parse of %s, outside the workspace: error %v", path, err)
+			}
+		}
+	})
+
+	t.Run("symbols", func(t *testing.T) {
+		var got []daemon.Symbol
+		if err := c.call(t, "symbols", daemon.SymbolsParams{Path: "derived.zs"}, &got); err != nil {
+			t.Fatal(err)
+		}
+		want := []daemon.Symbol{{
+			Name: "Derived", Detail: "Base", Kind: "class", Line: 1, Column: 7, EndLine: 1, EndColumn: 14,
+			Children: []daemon.Symbol{{Name: "Tick", Detail: "void", Kind: "method", Line: 1, Column: 29, EndLine: 1, EndColumn: 33}},
+		}}
+		if raw, _ := json.Marshal(got); string(raw) != mustMarshal(want) {
+			t.Errorf("symbols = %s, want %s", raw, mustMarshal(want))
+		}
+	})
+
+	t.Run("lint", func(t *testing.T) {
+		// The unsaved text of base.zs, without Tick, is still in place.
+		var findings []struct{ Rule, Path, Message string }
+		if err := c.call(t, "lint", daemon.LintParams{}, &findings); err != nil {
+			t.Fatal(err)
+		}
+		if len(findings) != 2 || findings[0].Rule != "syntax-error" || findings[0].Path != "base.zs" ||
+			findings[1].Message != `method "Tick" overrides Actor.Tick but is not marked override` {
+			t.Errorf("lint with base.zs unsaved = %+v", findings)
+		}
+		// Changing the file on disk replaces the text.
+		write(t, dir, "base.zs", "class Base : Actor { virtual void Tick() {} }  \n")
+		if err := c.call(t, "lint", daemon.LintParams{Paths: []string{"derived.zs"}}, &findings); err != nil {
+			t.Fatal(err)
+		}
+		if len(findings) != 1 || findings[0].Path != "derived.zs" || findings[0].Message != `method "Tick" overrides Base.Tick but is not marked override` {
+			t.Errorf("lint = %+v", findings)
+		}
+		// So does a text given by a request, whose symbols replace those
+		// of the file.
+		text := "class Derived : Base { override void Tick() {} }\n"
+		if err := c.call(t, "parse", daemon.ParseParams{Path: "derived.zs", Text: &text}, nil); err != nil {
+			t.Fatal(err)
+		}
+		if err := c.call(t, "lint", daemon.LintParams{Paths: []string{"derived.zs"}}, &findings); err != nil {
+			t.Fatal(err)
+		}
+		if len(findings) != 0 {
+			t.Errorf("lint with derived.zs unsaved = %+v", findings)
+		}
+	})
+
+	t.Run("format", func(t *testing.T) {
+		var r daemon.FormatResult
+		text := "class A{int x;}"
+		if err := c.call(t, "format", daemon.FormatParams{Text: &text}, &r); err != nil {
+			t.Fatal(err)
+		}
+		if r.Text != "class A {\n\tint x;\n}\n" {
+			t.Errorf("format = %q", r.Text)
+		}
+		broken := "class A {"
+		if err := c.call(t, "format", daemon.FormatParams{Text: &broken}, nil); err == nil || err.Code != jsonrpc.CodeInvalidParams {
+			t.Errorf("format of a broken text: error %v", err)
+		}
+	})
+
+	if err := c.call(t, "exit", nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-done; err != nil {
+		t.Errorf("Serve = %v", err)
+	}
+}
+
+func TestScanInterval(t *testing.T) {
+	dir := t.TempDir()
+	write(t, dir, "a.zs", "class A {}\n")
+	s, err := daemon.NewServer(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	s.ScanInterval = time.Hour
+	c, _ := serve(t, s)
+	names := func() []string {
+		t.Helper()
+		var got []daemon.Symbol
+		if err := c.call(t, "symbols", daemon.SymbolsParams{Path: "a.zs"}, &got); err != nil {
+			t.Fatal(err)
+		}
+		var names []string
+		for _, sym := range got {
+			names = append(names, sym.Name)
+		}
+		return names
+	}
+	names()
+	write(t, dir, "a.zs", "class B {}\n")
+	if got := names(); len(got) != 1 || got[0] != "A" {
+		t.Errorf("symbols before the next scan = %v, want [A]", got)
+	}
+}
+
+func TestServeListener(t *testing.T) {
+	dir := t.TempDir()
+	write(t, dir, "a.zs", "class A {}\n")
+	s, err := daemon.NewServer(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	l, err := net.Listen("unix", filepath.Join(t.TempDir(), "zscriptd.sock"))
+	if err != nil {
+		t.Skip(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- s.ServeListener(ctx, l) }()
+
+	for i := 0; i < 2; i++ {
+		conn, err := net.Dial("unix", l.Addr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+		c := &client{conn: jsonrpc.NewConn(conn, conn)}
+		var got []daemon.Symbol
+		if err := c.call(t, "symbols", daemon.SymbolsParams{Path: "a.zs"}, &got); err != nil || len(got) != 1 || got[0].Name != "A" {
+			t.Errorf("symbols on connection %d = %+v, %v", i, got, err)
+		}
+	}
+	cancel()
+	if err := <-done; err != context.Canceled {
+		t.Errorf("ServeListener = %v, want context.Canceled", err)
+	}
+}
+
+// serve serves s over a pipe until the test ends, returning a client and
+// the result of Serve.
+func serve(t *testing.T, s *daemon.Server) (*client, <-chan error) {
+	clientR, serverW := io.Pipe()
+	serverR, clientW := io.Pipe()
+	done := make(chan error, 1)
+	go func() {
+		done <- s.Serve(context.Background(), serverR, serverW)
+		serverW.Close()
+	}()
+	t.Cleanup(func() { clientW.Close() })
+	return &client{conn: jsonrpc.NewConn(clientR, clientW)}, done
+}
+
+func mustMarshal(v any) string {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		panic(err)
+	}
+	return string(raw)
+}
@@ -0,0 +1,327 @@
+// Package deadcode finds declarations of a ZScript project that nothing
+// uses: private methods that are never called, fields that are never
+// referenced, state labels that cannot be entered and classes that are
+// never spawned, replaced or referenced.
+//
+// The analysis sees only ZScript. Names used by other lumps, such as the
+// classes in MAPINFO DoomEdNums or the labels a DECORATE actor jumps to,
+// can be passed in Options; a declaration can also be kept with a comment
+// containing "deadcode:ignore" that leads or trails it, as package
+// comments attaches them. The comment may be followed by the names of the rules it
+// silences, as in "deadcode:ignore unused-field"; without names it
+// silences every rule.
+package deadcode
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+
+	zscript "github.com/jlcrochet/tree-sitter-zscript/bindings/go"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/callgraph"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/comments"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/hierarchy"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/lint"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/project"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/states"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/symbols"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/zscriptast"
+)
+
+// The rule names of the findings.
+const (
+	RuleUnusedMethod     = "unused-method"
+	RuleUnusedField      = "unused-field"
+	RuleUnreachableState = "unreachable-state"
+	RuleUnusedClass      = "unused-class"
+)
+
+// Directive is the comment text that suppresses findings.
+const Directive = "deadcode:ignore"
+
+// Options configures the analysis.
+type Options struct {
+	// Classes are class names referenced outside ZScript, such as by
+	// MAPINFO, KEYCONF or map things.
+	Classes []string
+	// Labels are state labels referenced outside ZScript.
+	Labels []string
+	// Roots are the classes whose descendants the engine creates by
+	// itself; those descendants are never reported unused. Nil means
+	// DefaultRoots.
+	Roots []string
+}
+
+// DefaultRoots are the engine classes whose subclasses are registered
+// through other lumps rather than spawned by ZScript code.
+var DefaultRoots = []string{
+	"EventHandler", "StaticEventHandler", "BaseStatusBar",
+	"Menu", "MenuItemBase", "MenuDelegateBase",
+}
+
+// engineLabels are the labels the engine enters by itself. A label such as
+// "Death.Fire" counts as its first component.
+var engineLabels = map[string]bool{
+	"spawn": true, "idle": true, "see": true, "melee": true, "missile": true,
+	"pain": true, "death": true, "xdeath": true, "burn": true, "ice": true,
+	"disintegrate": true, "raise": true, "heal": true, "crash": true,
+	"crush": true, "wound": true, "greet": true, "yes": true, "no": true,
+	"active": true, "inactive": true, "bounce": true, "genericfreezedeath": true,
+	"genericcrush": true, "deadlowered": true,
+	"pickup": true, "use": true, "drop": true, "held": true, "holdanddestroy": true,
+	"ready": true, "select": true, "deselect": true, "fire": true, "altfire": true,
+	"hold": true, "althold": true, "flash": true, "altflash": true,
+	"reload": true, "zoom": true, "user1": true, "user2": true, "user3": true,
+	"user4": true,
+}
+
+// ForProject analyzes every file of p with the default options.
+func ForProject(p *project.Project) []lint.Finding {
+	trees := make([]*zscript.Tree, len(p.Files))
+	for i, f := range p.Files {
+		trees[i] = f.Tree
+	}
+	return Find(trees...)
+}
+
+// Find analyzes the given files as a single program with the default
+// options. Findings are sorted by path and position.
+func Find(trees ...*zscript.Tree) []lint.Finding {
+	return FindWithOptions(Options{}, trees...)
+}
+
+// FindWithOptions is like Find but takes options.
+func FindWithOptions(opts Options, trees ...*zscript.Tree) []lint.Finding {
+	roots := opts.Roots
+	if roots == nil {
+		roots = DefaultRoots
+	}
+	tables := symbols.ExtractAll(0, trees...)
+	a := &analysis{
+		hierarchy: hierarchy.Build(tables...),
+		graph:     callgraph.Build(trees...),
+		names:     map[string]int{},
+		labels:    map[string]bool{},
+		declared:  map[position]bool{},
+	}
+	for _, name := range opts.Classes {
+		a.names[strings.ToLower(name)]++
+	}
+	for _, name := range opts.Labels {
+		a.labels[strings.ToLower(name)] = true
+	}
+	for _, t := range tables {
+		for _, c := range t.Classes {
+			a.declare(t.Path, c.NameRange)
+			for _, f := range c.Fields {
+				a.declare(t.Path, f.NameRange)
+			}
+		}
+		for _, s := range t.Structs {
+			for _, f := range s.Fields {
+				a.declare(t.Path, f.NameRange)
+			}
+		}
+	}
+	for _, tree := range trees {
+		a.references(tree)
+	}
+
+	var findings []lint.Finding
+	for i, tree := range trees {
+		r := &reporter{tree: tree, suppressed: suppressions(tree), findings: &findings}
+		t := tables[i]
+		for _, c := range t.Classes {
+			a.methods(r, c.Name, c.Methods)
+			a.fields(r, c.Fields)
+			if !c.Extend && !c.Mixin && !a.classUsed(c, roots) {
+				r.report(RuleUnusedClass, c.Range, c.NameRange, "class %q is never spawned, replaced or referenced", c.Name)
+			}
+		}
+		for _, s := range t.Structs {
+			a.methods(r, s.Name, s.Methods)
+			a.fields(r, s.Fields)
+		}
+		for _, c := range zscriptast.NewFile(tree.Tree, tree.Source).Classes() {
+			a.states(r, c)
+		}
+	}
+	sort.SliceStable(findings, func(i, j int) bool {
+		a, b := findings[i], findings[j]
+		if a.Path != b.Path {
+			return a.Path < b.Path
+		}
+		return a.Range.StartByte < b.Range.StartByte
+	})
+	return findings
+}
+
+type position struct {
+	path  string
+	start uint
+}
+
+type analysis struct {
+	hierarchy *hierarchy.Hierarchy
+	graph     *callgraph.Graph
+	// names counts the references to each lowercased name, outside the
+	// declarations of classes and fields.
+	names map[string]int
+	// labels holds the lowercased state labels named by gotos and
+	// strings.
+	labels   map[string]bool
+	declared map[position]bool
+}
+
+func (a *analysis) declare(path string, r tree_sitter.Range) {
+	a.declared[position{path, r.StartByte}] = true
+}
+
+// references records the names used in tree.
+func (a *analysis) references(tree *zscript.Tree) {
+	var v zscript.Visitor
+	name := func(node *tree_sitter.Node) zscript.WalkAction {
+		if !a.declared[position{tree.Path, node.StartByte()}] {
+			a.names[strings.ToLower(node.Utf8Text(tree.Source))]++
+		}
+		return zscript.WalkContinue
+	}
+	v.On(zscript.NodeIdentifier, name)
+	v.On(zscript.NodeTypeIdentifier, name)
+	v.On(zscript.NodeFieldIdentifier, name)
+	literal := func(node *tree_sitter.Node) zscript.WalkAction {
+		text := strings.ToLower(strings.Trim(node.Utf8Text(tree.Source), `"'`))
+		a.names[text]++
+		a.labels[text] = true
+		return zscript.WalkSkipChildren
+	}
+	v.On(zscript.NodeStringLiteral, literal)
+	v.On(zscript.NodeNameLiteral, literal)
+	v.On(zscript.NodeStateGotoTarget, func(node *tree_sitter.Node) zscript.WalkAction {
+		cursor := node.Walk()
+		defer cursor.Close()
+		for _, child := range node.NamedChildren(cursor) {
+			if child.Kind() == zscript.NodeStateLabelName {
+				label := strings.Join(strings.Fields(child.Utf8Text(tree.Source)), "")
+				a.labels[strings.ToLower(label)] = true
+			}
+		}
+		return zscript.WalkSkipChildren
+	})
+	zscript.Walk(tree.RootNode(), &v)
+}
+
+// methods reports the private methods of class that nothing else calls.
+func (a *analysis) methods(r *reporter, class string, methods []*symbols.Method) {
+	for _, m := range methods {
+		if !m.HasModifier("private") {
+			continue
+		}
+		f := a.graph.Func(class, m.Name)
+		if f == nil {
+			continue
+		}
+		called := false
+		for _, e := range a.graph.Callers(f) {
+			if e.Caller != f {
+				called = true
+				break
+			}
+		}
+		if !called {
+			r.report(RuleUnusedMethod, m.Range, m.NameRange, "private method %q is never called", m.Name)
+		}
+	}
+}
+
+// fields reports the fields that are never referenced. Native fields and
+// user variables, which maps may set, are skipped.
+func (a *analysis) fields(r *reporter, fields []*symbols.Field) {
+	for _, f := range fields {
+		name := strings.ToLower(f.Name)
+		if f.HasModifier("native") || strings.HasPrefix(name, "user_") || a.names[name] > 0 {
+			continue
+		}
+		r.report(RuleUnusedField, f.Range, f.NameRange, "field %q is never used", f.Name)
+	}
+}
+
+// classUsed reports whether c is referenced by name, replaces a class, or
+// descends from one of roots.
+func (a *analysis) classUsed(c *symbols.Class, roots []string) bool {
+	if c.Replaces != "" || a.names[strings.ToLower(c.Name)] > 0 {
+		return true
+	}
+	for _, root := range roots {
+		if a.hierarchy.IsSubclassOf(c.Name, root) {
+			return true
+		}
+	}
+	return false
+}
+
+// states reports the labels of c that the engine does not enter, that no
+// goto or string names, and that no reachable label falls through to.
+func (a *analysis) states(r *reporter, c zscriptast.ClassDecl) {
+	for _, b := range states.ForClass(c) {
+		reachable := false
+		for i, l := range b.Labels {
+			name := strings.ToLower(l.Name)
+			first, _, _ := strings.Cut(name, ".")
+			fallsInto := i > 0 && reachable && b.Labels[i-1].Flow == nil
+			reachable = fallsInto || engineLabels[first] || a.labels[name]
+			if !reachable {
+				r.report(RuleUnreachableState, l.Range, l.NameRange, "state label %q is never entered", l.Name)
+			}
+		}
+	}
+}
+
+// reporter collects the findings for one file.
+type reporter struct {
+	tree *zscript.Tree
+	// suppressed maps the start of a declaration to the rules a directive
+	// attached to it suppresses; an empty list suppresses all of them.
+	suppressed map[uint][]string
+	findings   *[]lint.Finding
+}
+
+// report records a finding at name unless a directive in a comment that
+// leads or trails decl suppresses it.
+func (r *reporter) report(rule string, decl, name tree_sitter.Range, format string, args ...any) {
+	if r.suppresses(decl.StartByte, rule) {
+		return
+	}
+	*r.findings = append(*r.findings, lint.Finding{
+		Rule:     rule,
+		Severity: zscript.SeverityWarning,
+		Path:     r.tree.Path,
+		Range:    name,
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
+
+func (r *reporter) suppresses(start uint, rule string) bool {
+	rules, ok := r.suppressed[start]
+	if !ok {
+		return false
+	}
+	return len(rules) == 0 || slices.Contains(rules, rule)
+}
+
+// suppressions returns the starts of the declarations of tree with a
+// suppression directive attached, and the rules each names.
+func suppressions(tree *zscript.Tree) map[uint][]string {
+	result := map[uint][]string{}
+	for _, c := range comments.Attach(tree.RootNode(), tree.Source).Comments {
+		text := strings.TrimSuffix(c.Node.Utf8Text(tree.Source), "*/")
+		_, rest, ok := strings.Cut(text, Directive)
+		if ok && c.Owner != nil && c.Placement != comments.Inner {
+			result[c.Owner.StartByte()] = strings.Fields(rest)
+		}
+	}
+	return result
+}
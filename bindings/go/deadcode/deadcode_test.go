@@ -0,0 +1,81 @@
+package deadcode_test
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	zscript "github.com/jlcrochet/tree-sitter-zscript/bindings/go"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/deadcode"
+)
+
+const source = `class Imp : Actor {
+	int used, spare;
+	int kept; // deadcode:ignore unused-field
+	int user_health;
+
+	private void Helper() { used++; }
+	private void Recurse() { Recurse(); }
+	private void Unused() {}
+	// deadcode:ignore
+	private void Kept() {}
+	void Tick() { Helper(); Spawn("Ball", pos); }
+
+	States {
+	Spawn:
+		TROO A 10;
+	Linger:
+		TROO B 10;
+		Loop;
+	Orphan:
+		TROO C 10;
+	Tail:
+		TROO D 10;
+		Stop;
+	Jumped:
+		TROO E 10;
+		Stop;
+	Death.Fire:
+		TROO F -1;
+		Stop;
+	Goner:
+		TROO G 1 A_Jump(128, "Jumped");
+		Goto Super::Spawn;
+	}
+}
+class Ball : Actor {}
+class Lonely : Actor {}
+class Mapped : Actor {}
+class Replacement : Actor replaces Ball {}
+class Handler : EventHandler {}
+class Parent : Actor {}
+class Child : Parent {}
+`
+
+func TestFind(t *testing.T) {
+	tree, err := zscript.Parse(context.Background(), []byte(source))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tree.Close()
+	tree.Path = "a.zs"
+
+	var got []string
+	for _, f := range deadcode.FindWithOptions(deadcode.Options{Classes: []string{"mapped"}}, tree) {
+		got = append(got, f.String())
+	}
+	want := []string{
+		`a.zs:1:7: warning: class "Imp" is never spawned, replaced or referenced (unused-class)`,
+		`a.zs:2:12: warning: field "spare" is never used (unused-field)`,
+		`a.zs:7:15: warning: private method "Recurse" is never called (unused-method)`,
+		`a.zs:8:15: warning: private method "Unused" is never called (unused-method)`,
+		`a.zs:19:2: warning: state label "Orphan" is never entered (unreachable-state)`,
+		`a.zs:21:2: warning: state label "Tail" is never entered (unreachable-state)`,
+		`a.zs:30:2: warning: state label "Goner" is never entered (unreachable-state)`,
+		`a.zs:36:7: warning: class "Lonely" is never spawned, replaced or referenced (unused-class)`,
+		`a.zs:41:7: warning: class "Child" is never spawned, replaced or referenced (unused-class)`,
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("findings =\n%q\nwant\n%q", got, want)
+	}
+}
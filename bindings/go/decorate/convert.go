@@ -0,0 +1,327 @@
+package decorate
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/format"
+)
+
+// Result is the output of a conversion.
+type Result struct {
+	// Source is the ZScript text, formatted with the default options
+	// unless it failed to parse (see Notes).
+	Source []byte
+	// DoomEdNums are the editor numbers from actor headers. ZScript
+	// classes cannot declare them; they belong in a MAPINFO DoomEdNums
+	// block (see MapInfo).
+	DoomEdNums []DoomEdNum
+	// Notes describe what the conversion could not translate or changed
+	// in ways worth reviewing, in line order.
+	Notes []Note
+}
+
+// DoomEdNum maps an editor number to a class.
+type DoomEdNum struct {
+	Number int
+	Class  string
+}
+
+// Note is a remark about a line of the DECORATE source.
+type Note struct {
+	Line    int
+	Message string
+}
+
+func (n Note) String() string {
+	return fmt.Sprintf("line %d: %s", n.Line, n.Message)
+}
+
+// MapInfo returns a MAPINFO DoomEdNums block for the editor numbers of
+// the converted actors, or "" if there are none.
+func (r *Result) MapInfo() string {
+	if len(r.DoomEdNums) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString("DoomEdNums\n{\n")
+	for _, n := range r.DoomEdNums {
+		fmt.Fprintf(&b, "\t%d = %s\n", n.Number, n.Class)
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+// Convert parses DECORATE source and converts it to ZScript.
+func Convert(src []byte) (*Result, error) {
+	f, err := Parse(src)
+	if err != nil {
+		return nil, err
+	}
+	return ConvertFile(f), nil
+}
+
+// ConvertFile converts a parsed DECORATE file to ZScript.
+func ConvertFile(f *File) *Result {
+	c := &converter{r: &Result{}}
+	for _, s := range f.Skipped {
+		c.note(s.Line, "%s definitions are not converted", s.Kind)
+	}
+	for i, item := range f.Items {
+		if i > 0 {
+			c.b.WriteString("\n")
+		}
+		switch item := item.(type) {
+		case *Actor:
+			c.actor(item)
+		case *Const:
+			c.constant(item)
+		case *Enum:
+			c.enum(item)
+		case *Include:
+			c.leading(item.Comments)
+			fmt.Fprintf(&c.b, "// #include %q\n", item.Path)
+			c.note(item.Line, "include of %q left as a comment; convert that file and include the result", item.Path)
+		}
+	}
+
+	out := []byte(c.b.String())
+	if formatted, err := format.Source(out, format.DefaultOptions()); err == nil {
+		out = formatted
+	} else {
+		c.note(0, "output was not formatted: %v", err)
+	}
+	c.r.Source = out
+	sort.SliceStable(c.r.Notes, func(i, j int) bool { return c.r.Notes[i].Line < c.r.Notes[j].Line })
+	return c.r
+}
+
+type converter struct {
+	b strings.Builder
+	r *Result
+}
+
+func (c *converter) note(line int, format string, args ...any) {
+	c.r.Notes = append(c.r.Notes, Note{Line: line, Message: fmt.Sprintf(format, args...)})
+}
+
+func (c *converter) leading(cm Comments) {
+	for _, text := range cm.Leading {
+		c.b.WriteString(text)
+		c.b.WriteString("\n")
+	}
+}
+
+// line writes a line of output followed by its trailing comment.
+func (c *converter) line(cm Comments, text string) {
+	c.leading(cm)
+	c.b.WriteString(text)
+	if cm.Trailing != "" {
+		c.b.WriteString(" ")
+		c.b.WriteString(cm.Trailing)
+	}
+	c.b.WriteString("\n")
+}
+
+func (c *converter) actor(a *Actor) {
+	if a.Native {
+		c.note(a.Line, "native actor %s skipped", a.Name)
+		return
+	}
+	if a.DoomEdNum >= 0 {
+		c.r.DoomEdNums = append(c.r.DoomEdNums, DoomEdNum{Number: a.DoomEdNum, Class: a.Name})
+	}
+	for _, line := range a.Actions {
+		c.note(line, "action function declaration in %s removed", a.Name)
+	}
+
+	header := "class " + a.Name
+	parent := a.Parent
+	if parent == "" {
+		// A ZScript class without a parent derives from Object.
+		parent = "Actor"
+	}
+	header += " : " + parent
+	if a.Replaces != "" {
+		header += " replaces " + a.Replaces
+	}
+	c.line(a.Comments, header+" {")
+
+	section := false
+	gap := func() {
+		if section {
+			c.b.WriteString("\n")
+		}
+		section = true
+	}
+	if len(a.Consts) > 0 || len(a.Enums) > 0 {
+		gap()
+		for i := range a.Consts {
+			c.constant(&a.Consts[i])
+		}
+		for i := range a.Enums {
+			c.enum(&a.Enums[i])
+		}
+	}
+	if len(a.Vars) > 0 {
+		gap()
+		for _, v := range a.Vars {
+			text := v.Type + " " + v.Name
+			if v.Size != "" {
+				text += "[" + v.Size + "]"
+			}
+			c.line(v.Comments, text+";")
+		}
+	}
+	if len(a.Defaults) > 0 {
+		gap()
+		c.b.WriteString("Default {\n")
+		for _, d := range a.Defaults {
+			c.line(d.Comments, c.property(d)+";")
+		}
+		c.b.WriteString("}\n")
+	}
+	if a.HasStates {
+		gap()
+		c.b.WriteString("States {\n")
+		for _, s := range a.States {
+			c.state(s)
+		}
+		c.b.WriteString("}\n")
+	}
+	c.leading(a.End)
+	c.line(Comments{Trailing: a.End.Trailing}, "}")
+}
+
+func (c *converter) property(d Default) string {
+	if d.Flag != 0 {
+		return string(d.Flag) + d.Name
+	}
+	if d.Value == "" {
+		return d.Name
+	}
+	name := d.Name
+	// "Damage (expr)" computes the damage from an expression; ZScript
+	// spells that DamageFunction.
+	if strings.EqualFold(name, "damage") && strings.HasPrefix(d.Value, "(") {
+		name = "DamageFunction"
+		c.note(d.Line, "Damage expression converted to DamageFunction")
+	}
+	return name + " " + c.expr(d.Value, d.Line)
+}
+
+// flowNames are the flow keywords as they are written in ZScript.
+var flowNames = map[string]string{
+	"goto": "Goto", "loop": "Loop", "stop": "Stop", "wait": "Wait", "fail": "Fail",
+}
+
+func (c *converter) state(s State) {
+	switch {
+	case s.Label != "":
+		c.line(s.Comments, s.Label+":")
+	case s.Flow == "goto":
+		c.line(s.Comments, "Goto "+s.Target+";")
+	case s.Flow != "":
+		c.line(s.Comments, flowNames[s.Flow]+";")
+	case s.Frame != nil:
+		f := s.Frame
+		parts := []string{f.Sprite, f.Frames, f.Duration}
+		parts = append(parts, f.Keywords...)
+		end := ";"
+		if f.Action != "" {
+			c.checkJumps(f.Action, s.Line)
+			parts = append(parts, c.expr(f.Action, s.Line))
+			if strings.HasPrefix(f.Action, "{") {
+				end = ""
+			}
+		}
+		c.line(s.Comments, strings.Join(parts, " ")+end)
+	default:
+		c.leading(s.Comments)
+	}
+}
+
+func (c *converter) constant(k *Const) {
+	c.line(k.Comments, "const "+k.Name+" = "+c.expr(k.Value, k.Line)+";")
+}
+
+// enum writes an enum as constants, since ZScript enums must be named.
+func (c *converter) enum(e *Enum) {
+	c.leading(e.Comments)
+	prev := ""
+	for _, m := range e.Members {
+		value := m.Value
+		switch {
+		case value != "":
+			value = c.expr(value, e.Line)
+		case prev == "":
+			value = "0"
+		default:
+			value = prev + " + 1"
+		}
+		c.b.WriteString("const " + m.Name + " = " + value + ";\n")
+		prev = m.Name
+	}
+	if e.Trailing != "" {
+		c.b.WriteString(e.Trailing + "\n")
+	}
+}
+
+// renamed maps DECORATE actor variables to their ZScript spelling.
+var renamed = map[string]string{
+	"momx": "vel.x",
+	"momy": "vel.y",
+	"momz": "vel.z",
+}
+
+// expr rewrites the DECORATE expression text s for ZScript.
+func (c *converter) expr(s string, line int) string {
+	toks, err := lex([]byte(s))
+	if err != nil {
+		return s
+	}
+	var b strings.Builder
+	last := 0
+	for i, t := range toks {
+		if t.kind != tokIdent || i > 0 && toks[i-1].is(".") {
+			continue
+		}
+		if to, ok := renamed[strings.ToLower(t.text)]; ok {
+			b.WriteString(s[last:t.start])
+			b.WriteString(to)
+			last = t.end
+			c.note(line+t.line-1, "%s replaced with %s", t.text, to)
+		}
+	}
+	b.WriteString(s[last:])
+	return b.String()
+}
+
+// checkJumps notes numeric jump offsets in A_Jump calls, which ZScript
+// does not support.
+func (c *converter) checkJumps(action string, line int) {
+	toks, err := lex([]byte(action))
+	if err != nil || len(toks) < 3 || !toks[0].is("A_Jump") || !toks[1].is("(") {
+		return
+	}
+	depth, arg := 0, 0
+	for i := 2; i < len(toks); i++ {
+		t := toks[i]
+		switch {
+		case t.is("("):
+			depth++
+		case t.is(")"):
+			if depth == 0 {
+				return
+			}
+			depth--
+		case t.is(",") && depth == 0:
+			arg++
+		case t.kind == tokNumber && arg > 0 && depth == 0 && toks[i-1].is(","):
+			if next := toks[i+1]; next.is(",") || next.is(")") {
+				c.note(line, "numeric jump offset %s in A_Jump must be replaced with a state label", t.text)
+			}
+		}
+	}
+}
@@ -0,0 +1,737 @@
+// Package decorate reads legacy DECORATE actor definitions and converts
+// them to ZScript.
+//
+// Parse reads the subset of DECORATE that maps onto ZScript: actor
+// definitions with their properties, flags, user variables, constants and
+// States blocks, and top-level constants, enums and includes. Convert
+// turns the result into ZScript classes and formats them; anything it
+// cannot translate is reported as a Note rather than dropped silently.
+package decorate
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Error is a syntax error in DECORATE source.
+type Error struct {
+	Line    int
+	Message string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("decorate: line %d: %s", e.Line, e.Message)
+}
+
+func errorf(line int, format string, args ...any) *Error {
+	return &Error{Line: line, Message: fmt.Sprintf(format, args...)}
+}
+
+// File is a parsed DECORATE lump.
+type File struct {
+	Items []Item
+	// Skipped lists the top-level definitions that were not parsed, such
+	// as damagetype blocks and old-style pickup definitions.
+	Skipped []Skipped
+}
+
+// Item is a top-level definition: *Actor, *Const, *Enum or *Include.
+type Item interface {
+	item()
+}
+
+// Comments are the comments preceding a definition, and the comment on
+// the same line after it.
+type Comments struct {
+	Leading  []string
+	Trailing string
+}
+
+// Actor is an actor definition.
+type Actor struct {
+	Comments
+	Line     int
+	Name     string
+	Parent   string
+	Replaces string
+	// DoomEdNum is the editor number from the header, or -1.
+	DoomEdNum int
+	Native    bool
+	Defaults  []Default
+	Vars      []Var
+	Consts    []Const
+	Enums     []Enum
+	States    []State
+	// HasStates is set when the actor has a States block, even an empty
+	// one.
+	HasStates bool
+	// Actions are the lines of the "action native" declarations in the
+	// body, which ZScript does not allow in user code.
+	Actions []int
+	// End holds the comments before the closing brace.
+	End Comments
+}
+
+// Default is a property or flag in an actor body.
+type Default struct {
+	Comments
+	Line int
+	// Flag is '+' or '-' for a flag, and 0 for a property.
+	Flag byte
+	Name string
+	// Value is the source text of the property's arguments.
+	Value string
+}
+
+// Var is a user variable.
+type Var struct {
+	Comments
+	Line int
+	Type string
+	Name string
+	// Size is the source text of the array size, if any.
+	Size string
+}
+
+// Const is a constant definition.
+type Const struct {
+	Comments
+	Line  int
+	Name  string
+	Value string
+}
+
+// Enum is an enumeration. DECORATE enums have no name.
+type Enum struct {
+	Comments
+	Line    int
+	Members []Enumerator
+}
+
+// Enumerator is a member of an Enum.
+type Enumerator struct {
+	Name string
+	// Value is the source text of the explicit value, if any.
+	Value string
+}
+
+// Include is an #include directive.
+type Include struct {
+	Comments
+	Line int
+	Path string
+}
+
+func (*Actor) item()   {}
+func (*Const) item()   {}
+func (*Enum) item()    {}
+func (*Include) item() {}
+
+// State is an entry of a States block: a label, a frame line or a flow
+// control keyword.
+type State struct {
+	Comments
+	Line int
+	// Label is set for a label, without the colon.
+	Label string
+	// Flow is the lower-cased flow keyword: "goto", "loop", "stop",
+	// "wait" or "fail".
+	Flow string
+	// Target is the destination of a goto.
+	Target string
+	// Frame is set for a frame line.
+	Frame *Frame
+}
+
+// Frame is a state line.
+type Frame struct {
+	Sprite   string
+	Frames   string
+	Duration string
+	// Keywords are the frame keywords after the duration, such as
+	// "Bright" and "Offset(1, 2)", as written.
+	Keywords []string
+	// Action is the source text of the action call or anonymous
+	// function, if any.
+	Action string
+}
+
+// Skipped is a top-level definition Parse did not read.
+type Skipped struct {
+	Line int
+	Kind string
+}
+
+// Parse parses DECORATE source.
+func Parse(src []byte) (*File, error) {
+	toks, err := lex(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{src: src, toks: toks}
+	f := &File{}
+	for p.peek().kind != tokEOF {
+		t := p.peek()
+		switch {
+		case t.is("actor"):
+			a, err := p.actor()
+			if err != nil {
+				return nil, err
+			}
+			f.Items = append(f.Items, a)
+		case t.is("const"):
+			c, err := p.constant()
+			if err != nil {
+				return nil, err
+			}
+			f.Items = append(f.Items, c)
+		case t.is("enum"):
+			e, err := p.enum()
+			if err != nil {
+				return nil, err
+			}
+			f.Items = append(f.Items, e)
+		case t.is("#"):
+			inc, err := p.include()
+			if err != nil {
+				return nil, err
+			}
+			f.Items = append(f.Items, inc)
+		case t.kind == tokIdent:
+			if err := p.skipDefinition(); err != nil {
+				return nil, err
+			}
+			f.Skipped = append(f.Skipped, Skipped{Line: t.line, Kind: strings.ToLower(t.text)})
+		default:
+			return nil, errorf(t.line, "unexpected %q", t.text)
+		}
+	}
+	return f, nil
+}
+
+type parser struct {
+	src  []byte
+	toks []token
+	pos  int
+}
+
+func (p *parser) peek() *token { return &p.toks[p.pos] }
+
+func (p *parser) next() *token {
+	t := &p.toks[p.pos]
+	if t.kind != tokEOF {
+		p.pos++
+	}
+	return t
+}
+
+// prev returns the last token consumed.
+func (p *parser) prev() *token { return &p.toks[p.pos-1] }
+
+func (p *parser) expect(s string) (*token, error) {
+	t := p.next()
+	if !t.is(s) {
+		return nil, errorf(t.line, "expected %q, found %q", s, describe(t))
+	}
+	return t, nil
+}
+
+func (p *parser) ident() (*token, error) {
+	t := p.next()
+	if t.kind != tokIdent {
+		return nil, errorf(t.line, "expected a name, found %q", describe(t))
+	}
+	return t, nil
+}
+
+func describe(t *token) string {
+	if t.kind == tokEOF {
+		return "end of file"
+	}
+	return t.text
+}
+
+// comments returns the comments around the tokens first through the
+// last token consumed.
+func (p *parser) comments(first int) Comments {
+	return Comments{Leading: p.toks[first].comments, Trailing: p.prev().trailing}
+}
+
+// text returns the source from the start of token first to the end of
+// the last token consumed.
+func (p *parser) text(first int) string {
+	if p.pos <= first {
+		return ""
+	}
+	return string(p.src[p.toks[first].start:p.prev().end])
+}
+
+// name reads a name, which may be quoted or dotted.
+func (p *parser) name() (string, error) {
+	t := p.next()
+	switch t.kind {
+	case tokString:
+		return unquote(t.text), nil
+	case tokIdent:
+		name := t.text
+		for p.peek().is(".") && p.toks[p.pos+1].kind == tokIdent {
+			p.next()
+			name += "." + p.next().text
+		}
+		return name, nil
+	}
+	return "", errorf(t.line, "expected a name, found %q", describe(t))
+}
+
+func unquote(s string) string {
+	if u, err := strconv.Unquote(s); err == nil {
+		return u
+	}
+	return strings.Trim(s, `"'`)
+}
+
+// balanced consumes tokens up to and including the token closing the
+// bracket just consumed.
+func (p *parser) balanced(open string) error {
+	closing := map[string]string{"(": ")", "[": "]", "{": "}"}[open]
+	start := p.prev()
+	for {
+		t := p.next()
+		switch {
+		case t.kind == tokEOF:
+			return errorf(start.line, "unclosed %q", open)
+		case t.is(closing):
+			return nil
+		case t.is("(") || t.is("[") || t.is("{"):
+			if err := p.balanced(t.text); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// restOfLine consumes the tokens up to the end of the line, treating
+// bracketed groups and lines ending in a comma as one line, and stops
+// before a semicolon or a closing brace.
+func (p *parser) restOfLine() error {
+	for {
+		t := p.peek()
+		if t.kind == tokEOF || t.is(";") || t.is("}") || t.newline && !p.prev().is(",") {
+			return nil
+		}
+		p.next()
+		if t.is("(") || t.is("[") || t.is("{") {
+			if err := p.balanced(t.text); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// skipDefinition skips a top-level definition DECORATE allows but Parse
+// does not read: everything up to the end of its braced body.
+func (p *parser) skipDefinition() error {
+	for {
+		t := p.next()
+		switch {
+		case t.kind == tokEOF:
+			return nil
+		case t.is(";"):
+			return nil
+		case t.is("{"):
+			return p.balanced("{")
+		}
+	}
+}
+
+func (p *parser) include() (*Include, error) {
+	first := p.pos
+	p.next()
+	if _, err := p.expect("include"); err != nil {
+		return nil, err
+	}
+	t := p.next()
+	if t.kind != tokString {
+		return nil, errorf(t.line, "expected a file name, found %q", describe(t))
+	}
+	return &Include{Comments: p.comments(first), Line: p.toks[first].line, Path: unquote(t.text)}, nil
+}
+
+// constant reads "const int Name = value;".
+func (p *parser) constant() (*Const, error) {
+	first := p.pos
+	p.next()
+	if t := p.peek(); t.is("int") || t.is("float") {
+		p.next()
+	}
+	name, err := p.ident()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := p.expect("="); err != nil {
+		return nil, err
+	}
+	value := p.pos
+	if err := p.restOfLine(); err != nil {
+		return nil, err
+	}
+	c := &Const{Line: p.toks[first].line, Name: name.text, Value: p.text(value)}
+	if _, err := p.expect(";"); err != nil {
+		return nil, err
+	}
+	c.Comments = p.comments(first)
+	return c, nil
+}
+
+// enum reads "enum { A, B = 2 };".
+func (p *parser) enum() (*Enum, error) {
+	first := p.pos
+	p.next()
+	if _, err := p.expect("{"); err != nil {
+		return nil, err
+	}
+	e := &Enum{Line: p.toks[first].line}
+	for !p.peek().is("}") {
+		name, err := p.ident()
+		if err != nil {
+			return nil, err
+		}
+		m := Enumerator{Name: name.text}
+		if p.peek().is("=") {
+			p.next()
+			value := p.pos
+			for t := p.peek(); !t.is(",") && !t.is("}") && t.kind != tokEOF; t = p.peek() {
+				p.next()
+				if t.is("(") {
+					if err := p.balanced("("); err != nil {
+						return nil, err
+					}
+				}
+			}
+			m.Value = p.text(value)
+		}
+		e.Members = append(e.Members, m)
+		if !p.peek().is(",") {
+			break
+		}
+		p.next()
+	}
+	if _, err := p.expect("}"); err != nil {
+		return nil, err
+	}
+	if p.peek().is(";") {
+		p.next()
+	}
+	e.Comments = p.comments(first)
+	return e, nil
+}
+
+// actor reads an actor definition:
+//
+//	actor Name [: Parent] [replaces Other] [doomednum] [native] { ... }
+func (p *parser) actor() (*Actor, error) {
+	first := p.pos
+	p.next()
+	a := &Actor{Line: p.toks[first].line, DoomEdNum: -1}
+	var err error
+	if a.Name, err = p.name(); err != nil {
+		return nil, err
+	}
+	if p.peek().is(":") {
+		p.next()
+		if a.Parent, err = p.name(); err != nil {
+			return nil, err
+		}
+	}
+	if p.peek().is("replaces") {
+		p.next()
+		if a.Replaces, err = p.name(); err != nil {
+			return nil, err
+		}
+	}
+	if t := p.peek(); t.kind == tokNumber {
+		p.next()
+		n, err := strconv.Atoi(t.text)
+		if err != nil {
+			return nil, errorf(t.line, "invalid editor number %q", t.text)
+		}
+		a.DoomEdNum = n
+	}
+	if p.peek().is("native") {
+		p.next()
+		a.Native = true
+	}
+	if _, err := p.expect("{"); err != nil {
+		return nil, err
+	}
+	a.Comments = p.comments(first)
+
+	for {
+		t := p.peek()
+		switch {
+		case t.kind == tokEOF:
+			return nil, errorf(a.Line, "actor %s is not closed", a.Name)
+		case t.is("}"):
+			a.End.Leading = t.comments
+			p.next()
+			a.End.Trailing = t.trailing
+			return a, nil
+		case t.is(";"):
+			p.next()
+		case t.is("+") || t.is("-"):
+			d, err := p.flag()
+			if err != nil {
+				return nil, err
+			}
+			a.Defaults = append(a.Defaults, d)
+		case t.is("states"):
+			a.HasStates = true
+			states, err := p.states()
+			if err != nil {
+				return nil, err
+			}
+			a.States = append(a.States, states...)
+		case t.is("var"):
+			v, err := p.variable()
+			if err != nil {
+				return nil, err
+			}
+			a.Vars = append(a.Vars, v)
+		case t.is("const"):
+			c, err := p.constant()
+			if err != nil {
+				return nil, err
+			}
+			a.Consts = append(a.Consts, *c)
+		case t.is("enum"):
+			e, err := p.enum()
+			if err != nil {
+				return nil, err
+			}
+			a.Enums = append(a.Enums, *e)
+		case t.is("action") || t.is("native"):
+			a.Actions = append(a.Actions, t.line)
+			for !p.peek().is(";") && p.peek().kind != tokEOF {
+				if p.next().is("(") {
+					if err := p.balanced("("); err != nil {
+						return nil, err
+					}
+				}
+			}
+			p.next()
+		case t.kind == tokIdent:
+			d, err := p.property()
+			if err != nil {
+				return nil, err
+			}
+			a.Defaults = append(a.Defaults, d)
+		default:
+			return nil, errorf(t.line, "unexpected %q in actor %s", t.text, a.Name)
+		}
+	}
+}
+
+func (p *parser) flag() (Default, error) {
+	first := p.pos
+	sign := p.next()
+	name, err := p.name()
+	if err != nil {
+		return Default{}, err
+	}
+	return Default{Comments: p.comments(first), Line: sign.line, Flag: sign.text[0], Name: name}, nil
+}
+
+func (p *parser) property() (Default, error) {
+	first := p.pos
+	name, err := p.name()
+	if err != nil {
+		return Default{}, err
+	}
+	value := p.pos
+	if err := p.restOfLine(); err != nil {
+		return Default{}, err
+	}
+	d := Default{Line: p.toks[first].line, Name: name, Value: p.text(value)}
+	if p.peek().is(";") {
+		p.next()
+	}
+	d.Comments = p.comments(first)
+	return d, nil
+}
+
+// variable reads "var int user_name;" or "var int user_name[size];".
+func (p *parser) variable() (Var, error) {
+	first := p.pos
+	p.next()
+	typ, err := p.ident()
+	if err != nil {
+		return Var{}, err
+	}
+	name, err := p.ident()
+	if err != nil {
+		return Var{}, err
+	}
+	v := Var{Line: p.toks[first].line, Type: strings.ToLower(typ.text), Name: name.text}
+	if p.peek().is("[") {
+		p.next()
+		size := p.pos
+		for !p.peek().is("]") && p.peek().kind != tokEOF {
+			p.next()
+		}
+		v.Size = p.text(size)
+		if _, err := p.expect("]"); err != nil {
+			return Var{}, err
+		}
+	}
+	if _, err := p.expect(";"); err != nil {
+		return Var{}, err
+	}
+	v.Comments = p.comments(first)
+	return v, nil
+}
+
+// frameKeywords are the keywords that may follow a frame's duration.
+var frameKeywords = map[string]bool{
+	"bright": true, "fast": true, "slow": true, "nodelay": true,
+	"canraise": true, "offset": true, "light": true,
+}
+
+func (p *parser) states() ([]State, error) {
+	p.next()
+	if _, err := p.expect("{"); err != nil {
+		return nil, err
+	}
+	var states []State
+	for {
+		t := p.peek()
+		first := p.pos
+		line := t.line
+		switch {
+		case t.kind == tokEOF:
+			return nil, errorf(line, "States block is not closed")
+		case t.is("}"):
+			if len(t.comments) > 0 {
+				states = append(states, State{Comments: Comments{Leading: t.comments}, Line: line})
+			}
+			p.next()
+			return states, nil
+		case t.is(";"):
+			p.next()
+			continue
+		case t.is("goto"):
+			p.next()
+			target := p.pos
+			if err := p.restOfLine(); err != nil {
+				return nil, err
+			}
+			if p.pos == target {
+				return nil, errorf(line, "goto without a target")
+			}
+			states = append(states, State{Comments: p.comments(first), Line: line, Flow: "goto", Target: p.text(target)})
+			continue
+		case t.is("loop") || t.is("stop") || t.is("wait") || t.is("fail"):
+			p.next()
+			states = append(states, State{Comments: p.comments(first), Line: line, Flow: strings.ToLower(t.text)})
+			continue
+		}
+
+		if label, ok := p.label(); ok {
+			states = append(states, State{Comments: p.comments(first), Line: line, Label: label})
+			continue
+		}
+		f, err := p.frame()
+		if err != nil {
+			return nil, err
+		}
+		states = append(states, State{Comments: p.comments(first), Line: line, Frame: f})
+	}
+}
+
+// label reads a state label if one is next.
+func (p *parser) label() (string, bool) {
+	i := p.pos
+	if p.toks[i].kind != tokIdent {
+		return "", false
+	}
+	for p.toks[i+1].is(".") && p.toks[i+2].kind == tokIdent {
+		i += 2
+	}
+	if !p.toks[i+1].is(":") {
+		return "", false
+	}
+	first := p.pos
+	p.pos = i + 1
+	name := p.text(first)
+	p.next()
+	return name, true
+}
+
+// frame reads a state line: sprite, frames, duration, keywords and
+// action.
+func (p *parser) frame() (*Frame, error) {
+	sprite := p.next()
+	if sprite.kind != tokIdent && sprite.kind != tokNumber && sprite.kind != tokString {
+		return nil, errorf(sprite.line, "expected a state, found %q", describe(sprite))
+	}
+	frames := p.next()
+	if frames.newline || frames.kind != tokIdent && frames.kind != tokNumber && frames.kind != tokString {
+		return nil, errorf(sprite.line, "expected frames after sprite %s", sprite.text)
+	}
+	f := &Frame{Sprite: sprite.text, Frames: frames.text}
+
+	duration := p.pos
+	if t := p.peek(); t.newline {
+		return nil, errorf(sprite.line, "expected a duration after %s %s", sprite.text, frames.text)
+	}
+	if p.peek().is("-") {
+		p.next()
+	}
+	switch t := p.next(); {
+	case t.kind == tokNumber:
+	case t.is("random") && p.peek().is("("):
+		p.next()
+		if err := p.balanced("("); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, errorf(t.line, "expected a duration, found %q", describe(t))
+	}
+	f.Duration = p.text(duration)
+
+	for {
+		t := p.peek()
+		if t.newline || t.kind != tokIdent || !frameKeywords[strings.ToLower(t.text)] {
+			break
+		}
+		keyword := p.pos
+		p.next()
+		if p.peek().is("(") {
+			p.next()
+			if err := p.balanced("("); err != nil {
+				return nil, err
+			}
+		}
+		f.Keywords = append(f.Keywords, p.text(keyword))
+	}
+
+	action := p.pos
+	switch t := p.peek(); {
+	case t.newline:
+	case t.is("{"):
+		p.next()
+		if err := p.balanced("{"); err != nil {
+			return nil, err
+		}
+	case t.kind == tokIdent && flowNames[strings.ToLower(t.text)] == "":
+		p.next()
+		if t := p.peek(); t.is("(") {
+			p.next()
+			if err := p.balanced("("); err != nil {
+				return nil, err
+			}
+		}
+	}
+	f.Action = p.text(action)
+	return f, nil
+}
@@ -0,0 +1,203 @@
+package decorate_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	zscript "github.com/jlcrochet/tree-sitter-zscript/bindings/go"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/decorate"
+)
+
+const source = `// A faster imp.
+const int FASTSPEED = 12;
+enum { MODE_A, MODE_B = 4, MODE_C };
+
+ACTOR FastImp : DoomImp replaces DoomImp 3101
+{
+	Health 80 // weaker
+	Speed FASTSPEED
+	Monster
+	+FLOAT +NOGRAVITY
+	-COUNTKILL
+	Damage (random(1,8)*3)
+	var int user_count;
+	States
+	{
+	Spawn:
+		TROO AB 10 A_Look
+		Loop
+	See:
+		TROO A 0 A_Jump(128, 2)
+		TROO B 3 ThrustThingZ(0, momz + 4,
+			0, 1)
+		Goto Super::See+1
+	Death.Fire:
+		TROO I -1 Bright
+		Stop
+	}
+}
+
+actor Spark { states { Spawn: TNT1 A 1 Offset(0, 2) { A_FadeOut(0.1); } Stop } }
+damagetype Burn { Factor 2 }
+`
+
+func TestParse(t *testing.T) {
+	f, err := decorate.Parse([]byte(source))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(f.Items) != 4 {
+		t.Fatalf("got %d items, want 4", len(f.Items))
+	}
+	if got := f.Skipped; !reflect.DeepEqual(got, []decorate.Skipped{{Line: 31, Kind: "damagetype"}}) {
+		t.Errorf("Skipped = %v", got)
+	}
+
+	a := f.Items[2].(*decorate.Actor)
+	if a.Name != "FastImp" || a.Parent != "DoomImp" || a.Replaces != "DoomImp" || a.DoomEdNum != 3101 {
+		t.Errorf("header = %s : %s replaces %s %d", a.Name, a.Parent, a.Replaces, a.DoomEdNum)
+	}
+	var defaults []string
+	for _, d := range a.Defaults {
+		s := d.Name
+		if d.Flag != 0 {
+			s = string(d.Flag) + s
+		}
+		if d.Value != "" {
+			s += " " + d.Value
+		}
+		defaults = append(defaults, s)
+	}
+	want := []string{"Health 80", "Speed FASTSPEED", "Monster", "+FLOAT", "+NOGRAVITY", "-COUNTKILL", "Damage (random(1,8)*3)"}
+	if !reflect.DeepEqual(defaults, want) {
+		t.Errorf("defaults = %q, want %q", defaults, want)
+	}
+	if got := a.Defaults[0].Trailing; got != "// weaker" {
+		t.Errorf("Health trailing comment = %q", got)
+	}
+
+	var states []string
+	for _, s := range a.States {
+		switch {
+		case s.Label != "":
+			states = append(states, s.Label+":")
+		case s.Frame != nil:
+			fr := s.Frame
+			states = append(states, strings.Join(append([]string{fr.Sprite, fr.Frames, fr.Duration}, append(fr.Keywords, fr.Action)...), "|"))
+		default:
+			states = append(states, s.Flow+" "+s.Target)
+		}
+	}
+	want = []string{
+		"Spawn:", "TROO|AB|10|A_Look", "loop ",
+		"See:", "TROO|A|0|A_Jump(128, 2)", "TROO|B|3|ThrustThingZ(0, momz + 4,\n\t\t\t0, 1)", "goto Super::See+1",
+		"Death.Fire:", "TROO|I|-1|Bright|", "stop ",
+	}
+	if !reflect.DeepEqual(states, want) {
+		t.Errorf("states =\n%q\nwant\n%q", states, want)
+	}
+
+	spark := f.Items[3].(*decorate.Actor)
+	if len(spark.States) != 3 || spark.States[1].Frame.Action != "{ A_FadeOut(0.1); }" || spark.States[2].Flow != "stop" {
+		t.Errorf("Spark states = %+v", spark.States)
+	}
+}
+
+func TestConvert(t *testing.T) {
+	r, err := decorate.Convert([]byte(source))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `// A faster imp.
+const FASTSPEED = 12;
+
+const MODE_A = 0;
+const MODE_B = 4;
+const MODE_C = MODE_B + 1;
+
+class FastImp : DoomImp replaces DoomImp {
+	int user_count;
+
+	Default {
+		Health 80; // weaker
+		Speed FASTSPEED;
+		Monster;
+		+FLOAT;
+		+NOGRAVITY;
+		-COUNTKILL;
+		DamageFunction (random(1, 8) * 3);
+	}
+
+	States {
+		Spawn:
+			TROO AB 10 A_Look;
+			Loop;
+		See:
+			TROO A  0  A_Jump(128, 2);
+			TROO B  3  ThrustThingZ(0, vel.z + 4,
+				0, 1);
+			Goto Super::See+1;
+		Death.Fire:
+			TROO I  -1 Bright;
+			Stop;
+	}
+}
+
+class Spark : Actor {
+	States {
+		Spawn:
+			TNT1 A 1 Offset(0, 2) {
+				A_FadeOut(0.1);
+			}
+			Stop;
+	}
+}
+`
+	if got := string(r.Source); got != want {
+		t.Errorf("Source =\n%s\nwant\n%s", got, want)
+	}
+
+	tree, err := zscript.Parse(context.Background(), r.Source)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tree.Close()
+	if d := zscript.Diagnostics(tree.Tree, tree.Source); len(d) > 0 {
+		t.Errorf("output has syntax errors: %v", d)
+	}
+
+	var notes []string
+	for _, n := range r.Notes {
+		notes = append(notes, n.String())
+	}
+	wantNotes := []string{
+		"line 12: Damage expression converted to DamageFunction",
+		"line 20: numeric jump offset 2 in A_Jump must be replaced with a state label",
+		"line 21: momz replaced with vel.z",
+		"line 31: damagetype definitions are not converted",
+	}
+	if !reflect.DeepEqual(notes, wantNotes) {
+		t.Errorf("notes =\n%q\nwant\n%q", notes, wantNotes)
+	}
+
+	if got, want := r.MapInfo(), "DoomEdNums\n{\n\t3101 = FastImp\n}\n"; got != want {
+		t.Errorf("MapInfo = %q, want %q", got, want)
+	}
+}
+
+func TestParseError(t *testing.T) {
+	for _, src := range []string{
+		"actor Imp {\n\tStates {\n\tSpawn:\n\t\tTROO\n\t}\n}\n",
+		"actor Imp {\n\tHealth 60\n",
+		"actor Imp {\n\tStates {\n\t\tGoto\n\t}\n}\n",
+	} {
+		_, err := decorate.Parse([]byte(src))
+		var e *decorate.Error
+		if err == nil || !errors.As(err, &e) || e.Line == 0 {
+			t.Errorf("Parse(%q) = %v, want a positioned error", src, err)
+		}
+	}
+}
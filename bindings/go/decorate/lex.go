@@ -0,0 +1,125 @@
+package decorate
+
+import "strings"
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokIdent
+	tokNumber
+	tokString
+	tokPunct
+)
+
+// A token is a lexical token of DECORATE source. Comments are not tokens;
+// each one is attached to the token it precedes, or to the token it
+// follows when it is on the same line.
+type token struct {
+	kind       tokenKind
+	text       string
+	start, end int
+	line       int
+	// newline is set when a line break separates the token from the
+	// previous one. DECORATE ends properties and state lines at line
+	// breaks.
+	newline  bool
+	comments []string
+	trailing string
+}
+
+// is reports whether t is the identifier or punctuation s, ignoring case.
+func (t *token) is(s string) bool {
+	return (t.kind == tokIdent || t.kind == tokPunct) && strings.EqualFold(t.text, s)
+}
+
+func lex(src []byte) ([]token, error) {
+	var toks []token
+	var comments []string
+	line, newline := 1, true
+	i := 0
+	for i < len(src) {
+		c := src[i]
+		switch {
+		case c == '\n':
+			line++
+			newline = true
+			i++
+			continue
+		case c == ' ' || c == '\t' || c == '\r' || c == '\f' || c == '\v':
+			i++
+			continue
+		case c == '/' && i+1 < len(src) && (src[i+1] == '/' || src[i+1] == '*'):
+			start := i
+			if src[i+1] == '/' {
+				for i < len(src) && src[i] != '\n' {
+					i++
+				}
+			} else {
+				end := strings.Index(string(src[i+2:]), "*/")
+				if end < 0 {
+					return nil, errorf(line, "unterminated comment")
+				}
+				i += 2 + end + 2
+			}
+			text := strings.TrimRight(string(src[start:i]), "\r")
+			if !newline && len(toks) > 0 && len(comments) == 0 && toks[len(toks)-1].trailing == "" {
+				toks[len(toks)-1].trailing = text
+			} else {
+				comments = append(comments, text)
+			}
+			line += strings.Count(text, "\n")
+			continue
+		}
+
+		t := token{start: i, line: line, newline: newline, comments: comments}
+		comments, newline = nil, false
+		switch {
+		case isIdentStart(c):
+			t.kind = tokIdent
+			for i < len(src) && isIdentPart(src[i]) {
+				i++
+			}
+		case c >= '0' && c <= '9' || c == '.' && i+1 < len(src) && src[i+1] >= '0' && src[i+1] <= '9':
+			t.kind = tokNumber
+			for i < len(src) && (isIdentPart(src[i]) || src[i] == '.') {
+				i++
+			}
+		case c == '"' || c == '\'':
+			t.kind = tokString
+			i++
+			for i < len(src) && src[i] != c {
+				if src[i] == '\\' {
+					i++
+				}
+				if i < len(src) && src[i] == '\n' {
+					line++
+				}
+				i++
+			}
+			if i >= len(src) {
+				return nil, errorf(t.line, "unterminated string")
+			}
+			i++
+		case c == ':' && i+1 < len(src) && src[i+1] == ':':
+			t.kind = tokPunct
+			i += 2
+		default:
+			t.kind = tokPunct
+			i++
+		}
+		t.end = i
+		t.text = string(src[t.start:t.end])
+		toks = append(toks, t)
+	}
+	toks = append(toks, token{kind: tokEOF, start: len(src), end: len(src), line: line, newline: true, comments: comments})
+	return toks, nil
+}
+
+func isIdentStart(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+func isIdentPart(c byte) bool {
+	return isIdentStart(c) || c >= '0' && c <= '9'
+}
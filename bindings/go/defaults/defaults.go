@@ -0,0 +1,120 @@
+// Package defaults interprets the Default blocks of actor classes and
+// checks them against a schema of the properties and flags GZDoom knows.
+package defaults
+
+import (
+	"strings"
+
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+
+	zscript "github.com/jlcrochet/tree-sitter-zscript/bindings/go"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/zscriptast"
+)
+
+// Defaults holds the property assignments and flag statements of a class,
+// in source order.
+type Defaults struct {
+	Properties []*Property
+	Flags      []*Flag
+}
+
+// Property is a property assignment such as "Health 100;".
+type Property struct {
+	// Name is the property name, including any "Class." prefix.
+	Name      string
+	Values    []zscriptast.Node
+	Range     tree_sitter.Range
+	NameRange tree_sitter.Range
+}
+
+// Args returns the source text of the values.
+func (p *Property) Args() []string {
+	args := make([]string, len(p.Values))
+	for i, v := range p.Values {
+		args[i] = v.Text()
+	}
+	return args
+}
+
+// Flag is a flag statement such as "+NOGRAVITY".
+type Flag struct {
+	// Name is the flag name, including any "Class." prefix.
+	Name string
+	// Set is true for "+" and false for "-".
+	Set       bool
+	Range     tree_sitter.Range
+	NameRange tree_sitter.Range
+}
+
+// Parse interprets the given Default blocks as one, in order.
+func Parse(blocks ...zscriptast.DefaultBlock) *Defaults {
+	d := &Defaults{}
+	for _, b := range blocks {
+		for _, p := range b.Properties() {
+			d.Properties = append(d.Properties, &Property{
+				Name:      normalize(p.Name()),
+				Values:    p.Values(),
+				Range:     p.Range(),
+				NameRange: p.Field(zscript.FieldProperty).Range(),
+			})
+		}
+		for _, f := range b.Flags() {
+			d.Flags = append(d.Flags, &Flag{
+				Name:      normalize(f.Name()),
+				Set:       f.Set(),
+				Range:     f.Range(),
+				NameRange: f.Field(zscript.FieldFlag).Range(),
+			})
+		}
+	}
+	return d
+}
+
+// ForClass interprets every Default block of class c.
+func ForClass(c zscriptast.ClassDecl) *Defaults {
+	return Parse(c.Defaults()...)
+}
+
+// Property returns the last assignment of the named property, matched
+// case-insensitively, or nil.
+func (d *Defaults) Property(name string) *Property {
+	for i := len(d.Properties) - 1; i >= 0; i-- {
+		if strings.EqualFold(d.Properties[i].Name, name) {
+			return d.Properties[i]
+		}
+	}
+	return nil
+}
+
+// Flag reports whether the named flag is set or cleared by the last
+// statement that mentions it. Names are matched case-insensitively, and a
+// name without a "Class." prefix matches a statement with one and vice
+// versa. ok is false if no statement mentions the flag.
+func (d *Defaults) Flag(name string) (set, ok bool) {
+	for i := len(d.Flags) - 1; i >= 0; i-- {
+		if flagMatch(d.Flags[i].Name, name) {
+			return d.Flags[i].Set, true
+		}
+	}
+	return false, false
+}
+
+func flagMatch(a, b string) bool {
+	if strings.EqualFold(a, b) {
+		return true
+	}
+	if strings.Contains(a, ".") && strings.Contains(b, ".") {
+		return false
+	}
+	return strings.EqualFold(bare(a), bare(b))
+}
+
+// bare strips the "Class." prefix from a flag or property name.
+func bare(name string) string {
+	return name[strings.LastIndexByte(name, '.')+1:]
+}
+
+// normalize removes any whitespace around the dots of a qualified name.
+func normalize(name string) string {
+	return strings.Join(strings.Fields(name), "")
+}
@@ -0,0 +1,205 @@
+package defaults_test
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	zscript "github.com/jlcrochet/tree-sitter-zscript/bindings/go"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/defaults"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/engine"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/hierarchy"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/symbols"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/zscriptast"
+)
+
+const source = `class Imp : Actor {
+	int charge;
+	property Charge: charge;
+	flagdef Charged: charge, 0;
+
+	Default {
+		Health 60;
+		Helth 60;
+		Radius 20, 30;
+		DamageFunction (random(1, 8) * 3);
+		Speed "fast";
+		DropItem "Clip", 256;
+		Imp.Charge 5;
+		Inventory.Amount 1;
+		Monster;
+		+NOGRAVITY;
+		+NOGRAVTY;
+		-INVENTORY.AUTOACTIVATE;
+		+Imp.Charged;
+		-Actor.SOLID;
+	}
+}
+`
+
+func parse(t *testing.T) (*defaults.Defaults, *symbols.Table) {
+	t.Helper()
+	tree, err := zscript.Parse(context.Background(), []byte(source))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(tree.Close)
+	if tree.RootNode().HasError() {
+		t.Fatalf("parse error: %s", tree.RootNode().ToSexp())
+	}
+	class := zscriptast.NewFile(tree.Tree, tree.Source).Classes()[0]
+	return defaults.ForClass(class), symbols.Extract(tree)
+}
+
+func TestDefaults(t *testing.T) {
+	d, _ := parse(t)
+	if len(d.Properties) != 9 || len(d.Flags) != 5 {
+		t.Fatalf("got %d properties and %d flags", len(d.Properties), len(d.Flags))
+	}
+	if p := d.Property("health"); p == nil || !reflect.DeepEqual(p.Args(), []string{"60"}) {
+		t.Errorf("Property(health) = %+v", p)
+	}
+	if p := d.Property("DropItem"); p == nil || !reflect.DeepEqual(p.Args(), []string{`"Clip"`, "256"}) {
+		t.Errorf("Property(DropItem) = %+v", p)
+	}
+	if p := d.Property("Inventory.Amount"); p == nil || p.NameRange.StartPoint.Row != 13 {
+		t.Errorf("Property(Inventory.Amount) = %+v", p)
+	}
+	if d.Property("Mass") != nil {
+		t.Error("Property(Mass) is not nil")
+	}
+
+	for _, tt := range []struct {
+		name    string
+		set, ok bool
+	}{
+		{"nogravity", true, true},
+		{"AutoActivate", false, true},
+		{"Inventory.AutoActivate", false, true},
+		{"Charged", true, true},
+		{"SOLID", false, true},
+		{"SHOOTABLE", false, false},
+	} {
+		if set, ok := d.Flag(tt.name); set != tt.set || ok != tt.ok {
+			t.Errorf("Flag(%s) = %v, %v; want %v, %v", tt.name, set, ok, tt.set, tt.ok)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	d, table := parse(t)
+	schema := defaults.NewSchema()
+	schema.Declare(table)
+
+	type problem struct {
+		Line    uint
+		Message string
+	}
+	var got []problem
+	for _, p := range defaults.Validate(d, schema) {
+		got = append(got, problem{p.Range.StartPoint.Row + 1, p.Message})
+	}
+	want := []problem{
+		{8, `unknown property "Helth"; did you mean "Health"?`},
+		{9, "Radius takes 1 argument, got 2"},
+		{11, "Speed expects a number, got a string"},
+		{17, `unknown flag "NOGRAVTY"; did you mean "NOGRAVITY"?`},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Validate() =\n%v\nwant\n%v", got, want)
+	}
+
+	if spec, ok := schema.Property("player.weaponslot"); !ok {
+		t.Error("Player.WeaponSlot is unknown")
+	} else if min, max := spec.Arity(); min != 2 || max != -1 {
+		t.Errorf("Player.WeaponSlot arity = %d, %d", min, max)
+	}
+	if spec, ok := schema.Property("DropItem"); !ok {
+		t.Error("DropItem is unknown")
+	} else if min, max := spec.Arity(); min != 1 || max != 3 {
+		t.Errorf("DropItem arity = %d, %d", min, max)
+	}
+}
+
+const flagSource = `class Imp : Actor {
+	bool bAngry;
+	Default {
+		+NOGRAVITY;
+		-SOLID;
+		+Inventory.AUTOACTIVATE;
+		+NOAUTOFIRE;
+		+ALWAYSRESPAWN;
+		+NOSUCHFLAG;
+		-NOGRAVITY;
+		+NOGRAVITY;
+	}
+	void Anger(bool bLoud) {
+		bool bFound = false;
+		bAngry = true;
+		bLoud = bFound;
+		bNOGRAVITY = false;
+		self.bFriendly = true;
+		bUNDROPPABLE = true;
+		bNoGravty = true;
+		other.bWhatever = true;
+	}
+}
+class Orb : Inventory {
+	Default { +AUTOACTIVATE; +INVENTORY.UNDROPPABLE; }
+	void Drop() { bUNDROPPABLE = false; }
+}
+class Menu2 : Object {
+	void F() { bUNDROPPABLE = true; }
+}
+`
+
+func TestCheckFlags(t *testing.T) {
+	tree, err := zscript.Parse(context.Background(), []byte(flagSource))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tree.Close()
+	table := symbols.Extract(tree)
+	h := hierarchy.Build(table, engine.Table())
+	schema := defaults.NewSchema()
+	schema.Declare(table)
+
+	var got []string
+	for _, c := range zscriptast.NewFile(tree.Tree, tree.Source).Classes() {
+		for _, p := range defaults.CheckFlags(c, h, schema) {
+			got = append(got, fmt.Sprintf("%d:%d: %s", p.Range.StartPoint.Row+1, p.Range.StartPoint.Column+1, p.Message))
+		}
+	}
+	want := []string{
+		`6:4: flag Inventory.AUTOACTIVATE belongs to Inventory, which Imp does not inherit from`,
+		`7:4: flag NOAUTOFIRE belongs to Weapon, which Imp does not inherit from`,
+		`9:4: unknown flag "NOSUCHFLAG"`,
+		`19:3: flag bUNDROPPABLE belongs to Inventory, which Imp does not inherit from`,
+		`20:3: unknown flag field bNoGravty; did you mean "NOGRAVITY"?`,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestContradictions(t *testing.T) {
+	tree, err := zscript.Parse(context.Background(), []byte(flagSource))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tree.Close()
+	class := zscriptast.NewFile(tree.Tree, tree.Source).Classes()[0]
+	var got []string
+	for _, p := range defaults.Contradictions(defaults.ForClass(class)) {
+		got = append(got, fmt.Sprintf("%d: %s", p.Range.StartPoint.Row+1, p.Message))
+	}
+	want := []string{
+		`10: NOGRAVITY is cleared here but set on line 4`,
+		`11: NOGRAVITY is set here but cleared on line 10`,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
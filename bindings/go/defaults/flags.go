@@ -0,0 +1,213 @@
+package defaults
+
+import (
+	"fmt"
+	"strings"
+
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+
+	zscript "github.com/jlcrochet/tree-sitter-zscript/bindings/go"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/hierarchy"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/zscriptast"
+)
+
+// Contradictions reports the flags of d that are both set and cleared.
+// The last statement wins, so the earlier one has no effect. Each
+// statement after the first that changes a flag is reported once.
+func Contradictions(d *Defaults) []Problem {
+	var problems []Problem
+	last := map[string]*Flag{}
+	for _, f := range d.Flags {
+		key := strings.ToLower(bare(f.Name))
+		if prev, ok := last[key]; ok && prev.Set != f.Set && flagMatch(prev.Name, f.Name) {
+			problems = append(problems, Problem{
+				Range:   f.Range,
+				Message: fmt.Sprintf("%s is %s here but %s on line %d", f.Name, state(f.Set), state(prev.Set), prev.Range.StartPoint.Row+1),
+			})
+		}
+		last[key] = f
+	}
+	return problems
+}
+
+func state(set bool) string {
+	if set {
+		return "set"
+	}
+	return "cleared"
+}
+
+// CheckFlags reports the flags that the class c sets or clears, in its
+// Default blocks with +FLAG and -FLAG and in its methods by assigning to
+// a flag field such as bNOGRAVITY, that schema does not know or that
+// belong to a class c does not inherit from. The class's ancestry comes
+// from h; flags of classes whose ancestry is incomplete are assumed to be
+// valid, and flag fields are checked only in classes known to be actors.
+func CheckFlags(c zscriptast.ClassDecl, h *hierarchy.Hierarchy, schema *Schema) []Problem {
+	fc := &flagChecker{class: c.Name(), h: h, schema: schema}
+	for _, f := range ForClass(c).Flags {
+		if !schema.Flag(f.Name) {
+			fc.report(f.NameRange, "unknown flag %q%s", f.Name, suggest(f.Name, schema.FlagNames()))
+			continue
+		}
+		fc.inherited(f.NameRange, f.Name, f.Name)
+	}
+	// Only actors have flag fields.
+	if actor, _ := fc.descends("Actor"); !actor {
+		return fc.problems
+	}
+	for _, m := range c.Methods() {
+		if body := m.Body(); !body.IsZero() {
+			fc.assignments(m, body)
+		}
+	}
+	return fc.problems
+}
+
+type flagChecker struct {
+	class    string
+	h        *hierarchy.Hierarchy
+	schema   *Schema
+	problems []Problem
+}
+
+func (fc *flagChecker) report(r tree_sitter.Range, format string, args ...any) {
+	fc.problems = append(fc.problems, Problem{Range: r, Message: fmt.Sprintf(format, args...)})
+}
+
+// inherited reports the flag name, written as text, unless one of the
+// flags it may refer to belongs to Actor or to an ancestor of the class.
+func (fc *flagChecker) inherited(r tree_sitter.Range, name, text string) {
+	var owners []string
+	for _, canonical := range fc.schema.Flags(name) {
+		owner, _, qualified := strings.Cut(canonical, ".")
+		if !qualified || strings.EqualFold(owner, "Actor") {
+			return
+		}
+		if ok, known := fc.descends(owner); ok || !known {
+			return
+		}
+		owners = append(owners, owner)
+	}
+	if len(owners) > 0 {
+		fc.report(r, "flag %s belongs to %s, which %s does not inherit from", text, fc.spelling(owners[0]), fc.class)
+	}
+}
+
+// spelling returns the name of the class owner as it is declared.
+func (fc *flagChecker) spelling(owner string) string {
+	if c := fc.h.Class(owner); c != nil {
+		return c.Name
+	}
+	return owner
+}
+
+// descends reports whether the class is owner or inherits from it, and
+// whether its ancestry is known up to Object. A class known by name and
+// parent only, as the game actors are, is passed through.
+func (fc *flagChecker) descends(owner string) (ok, known bool) {
+	if strings.EqualFold(fc.class, owner) {
+		return true, true
+	}
+	for _, a := range append([]*hierarchy.Class{fc.h.Class(fc.class)}, fc.h.Ancestors(fc.class)...) {
+		switch {
+		case a != nil && strings.EqualFold(a.Name, owner):
+			return true, true
+		case a == nil || !a.Defined() && a.Parent == nil:
+			return false, a != nil && strings.EqualFold(a.Name, "Object")
+		}
+	}
+	return false, true
+}
+
+// assignments checks the assignments to flag fields in body, the body of
+// method m. Locals and parameters, and fields c or its ancestors declare,
+// are not flag fields.
+func (fc *flagChecker) assignments(m zscriptast.MethodDecl, body zscriptast.Node) {
+	locals := map[string]bool{}
+	for _, p := range m.Parameters() {
+		locals[strings.ToLower(p.Name())] = true
+	}
+	var v zscript.Visitor
+	v.On(zscript.NodeIdentifier, func(n *tree_sitter.Node) zscript.WalkAction {
+		if declares(n) {
+			locals[strings.ToLower(n.Utf8Text(body.Source))] = true
+		}
+		return zscript.WalkContinue
+	})
+	zscript.Walk(body.Raw, &v)
+
+	v = zscript.Visitor{}
+	v.On(zscript.NodeAssignmentExpression, func(n *tree_sitter.Node) zscript.WalkAction {
+		left := n.ChildByFieldName(zscript.FieldLeft)
+		switch {
+		case left == nil:
+		case left.Kind() == zscript.NodeIdentifier:
+		case left.Kind() == zscript.NodeFieldExpression && left.ChildByFieldName(zscript.FieldArgument).Kind() == zscript.NodeSelfExpression:
+			left = left.ChildByFieldName(zscript.FieldField)
+		default:
+			return zscript.WalkContinue
+		}
+		if left == nil {
+			return zscript.WalkContinue
+		}
+		name := left.Utf8Text(body.Source)
+		flag, ok := flagField(name)
+		if !ok || locals[strings.ToLower(name)] || fc.field(name) {
+			return zscript.WalkContinue
+		}
+		if !fc.schema.Flag(flag) {
+			fc.report(left.Range(), "unknown flag field %s%s", name, suggest(flag, fc.schema.FlagNames()))
+			return zscript.WalkContinue
+		}
+		fc.inherited(left.Range(), flag, name)
+		return zscript.WalkContinue
+	})
+	zscript.Walk(body.Raw, &v)
+}
+
+// flagField returns the flag that the field name sets: name without its
+// leading "b", if the letter after it is upper case.
+func flagField(name string) (string, bool) {
+	if len(name) < 2 || name[0] != 'b' || name[1] < 'A' || name[1] > 'Z' {
+		return "", false
+	}
+	return name[1:], true
+}
+
+// field reports whether the class or one of its ancestors declares a
+// field named name.
+func (fc *flagChecker) field(name string) bool {
+	for _, a := range append([]*hierarchy.Class{fc.h.Class(fc.class)}, fc.h.Ancestors(fc.class)...) {
+		if a == nil || !a.Defined() {
+			continue
+		}
+		if a.Decl.Field(name) != nil {
+			return true
+		}
+		for _, e := range a.Extensions {
+			if e.Field(name) != nil {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// declares reports whether the identifier n is the name of a local
+// variable.
+func declares(n *tree_sitter.Node) bool {
+	p := n.Parent()
+	if p == nil {
+		return false
+	}
+	switch p.Kind() {
+	case zscript.NodeDeclaration, zscript.NodeInitDeclarator:
+		value := p.ChildByFieldName(zscript.FieldValue)
+		return value == nil || value.Id() != n.Id()
+	case zscript.NodeForeachStatement:
+		collection := p.ChildByFieldName(zscript.FieldCollection)
+		return collection == nil || collection.Id() != n.Id()
+	}
+	return false
+}
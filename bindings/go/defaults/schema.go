@@ -0,0 +1,345 @@
+package defaults
+
+import (
+	"bufio"
+	_ "embed"
+	"fmt"
+	"iter"
+	"sort"
+	"strings"
+
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+
+	zscript "github.com/jlcrochet/tree-sitter-zscript/bindings/go"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/symbols"
+)
+
+//go:embed properties.txt
+var propertiesFile string
+
+//go:embed flags.txt
+var flagsFile string
+
+// Type is the type of a property argument.
+type Type int
+
+const (
+	TypeExpr Type = iota + 1
+	TypeInt
+	TypeFloat
+	TypeString
+	TypeName
+	TypeColor
+)
+
+var typeNames = [...]string{
+	TypeExpr:   "expr",
+	TypeInt:    "int",
+	TypeFloat:  "float",
+	TypeString: "string",
+	TypeName:   "name",
+	TypeColor:  "color",
+}
+
+func (t Type) String() string {
+	if t > 0 && int(t) < len(typeNames) {
+		return typeNames[t]
+	}
+	return "unknown"
+}
+
+// numeric reports whether values of type t are numbers.
+func (t Type) numeric() bool {
+	return t == TypeInt || t == TypeFloat
+}
+
+// Param is an argument of a property.
+type Param struct {
+	Type     Type
+	Optional bool
+	// Variadic is set for a last parameter that may repeat.
+	Variadic bool
+}
+
+// Spec describes a property.
+type Spec struct {
+	Name   string
+	Params []Param
+}
+
+// Arity returns the least and greatest number of arguments the property
+// takes. max is -1 if there is no limit.
+func (s *Spec) Arity() (min, max int) {
+	for _, p := range s.Params {
+		if p.Variadic {
+			return min + 1, -1
+		}
+		if !p.Optional {
+			min++
+		}
+		max++
+	}
+	return min, max
+}
+
+// param returns the parameter that the i'th argument binds to.
+func (s *Spec) param(i int) (Param, bool) {
+	if i < len(s.Params) {
+		return s.Params[i], true
+	}
+	if n := len(s.Params); n > 0 && s.Params[n-1].Variadic {
+		return s.Params[n-1], true
+	}
+	return Param{}, false
+}
+
+// Schema is a set of known properties and flags.
+type Schema struct {
+	properties map[string]*Spec
+	// flags maps lowercased flag names, with their prefix, to their
+	// canonical spelling; bareFlags maps them without it to every flag of
+	// that name.
+	flags     map[string]string
+	bareFlags map[string][]string
+}
+
+// NewSchema returns a schema of the properties and flags of GZDoom's
+// built-in actor classes.
+func NewSchema() *Schema {
+	s := &Schema{properties: map[string]*Spec{}, flags: map[string]string{}, bareFlags: map[string][]string{}}
+	for line := range lines(propertiesFile) {
+		fields := strings.Fields(line)
+		spec := &Spec{Name: fields[0]}
+		for _, f := range fields[1:] {
+			var p Param
+			f, p.Variadic = strings.CutSuffix(f, "...")
+			f, p.Optional = strings.CutSuffix(f, "?")
+			p.Type = parseType(f)
+			spec.Params = append(spec.Params, p)
+		}
+		s.AddProperty(spec)
+	}
+	for line := range lines(flagsFile) {
+		s.AddFlag(line)
+	}
+	return s
+}
+
+// lines yields the lines of an embedded schema file that are neither
+// blank nor comments.
+func lines(file string) iter.Seq[string] {
+	return func(yield func(string) bool) {
+		sc := bufio.NewScanner(strings.NewReader(file))
+		for sc.Scan() {
+			line := strings.TrimSpace(sc.Text())
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			if !yield(line) {
+				return
+			}
+		}
+	}
+}
+
+func parseType(name string) Type {
+	for t, n := range typeNames {
+		if n == name {
+			return Type(t)
+		}
+	}
+	panic("defaults: unknown type " + name)
+}
+
+// AddProperty adds or replaces a property.
+func (s *Schema) AddProperty(spec *Spec) {
+	s.properties[strings.ToLower(spec.Name)] = spec
+}
+
+// AddFlag adds a flag, written with the prefix of the class that defines
+// it unless it is an Actor flag.
+func (s *Schema) AddFlag(name string) {
+	key := strings.ToLower(name)
+	if _, ok := s.flags[key]; !ok {
+		b := strings.ToLower(bare(name))
+		s.bareFlags[b] = append(s.bareFlags[b], name)
+	}
+	s.flags[key] = name
+}
+
+// Declare adds the properties and flags declared by the classes in the
+// given tables, qualified by their class name.
+func (s *Schema) Declare(tables ...*symbols.Table) {
+	for _, t := range tables {
+		for _, c := range t.Classes {
+			for _, p := range c.Properties {
+				spec := &Spec{Name: c.Name + "." + p.Name}
+				for range p.Fields {
+					spec.Params = append(spec.Params, Param{Type: TypeExpr})
+				}
+				s.AddProperty(spec)
+			}
+			for _, f := range c.FlagDefs {
+				s.AddFlag(c.Name + "." + f.Name)
+			}
+		}
+	}
+}
+
+// Property returns the named property, matched case-insensitively. An
+// "Actor." prefix is optional.
+func (s *Schema) Property(name string) (*Spec, bool) {
+	key := strings.ToLower(normalize(name))
+	spec, ok := s.properties[key]
+	if !ok {
+		spec, ok = s.properties[strings.TrimPrefix(key, "actor.")]
+	}
+	return spec, ok
+}
+
+// Flag reports whether the named flag is known, matching names
+// case-insensitively. The class prefix of a flag may be omitted, and
+// Actor flags may be written with an "Actor." prefix.
+func (s *Schema) Flag(name string) bool {
+	return len(s.Flags(name)) > 0
+}
+
+// Flags returns the canonical names of the flags that name may refer to:
+// the flag of that name, or without a class prefix, every flag with that
+// name in any class.
+func (s *Schema) Flags(name string) []string {
+	key := strings.ToLower(normalize(name))
+	if canonical, ok := s.flags[key]; ok {
+		return []string{canonical}
+	}
+	if prefix, rest, ok := strings.Cut(key, "."); ok {
+		if canonical, known := s.flags[rest]; prefix == "actor" && known {
+			return []string{canonical}
+		}
+		return nil
+	}
+	return s.bareFlags[key]
+}
+
+// Problem is a property or flag that does not match the schema.
+type Problem struct {
+	Range   tree_sitter.Range
+	Message string
+}
+
+// Validate checks d against schema, reporting unknown properties and
+// flags, wrong numbers of arguments, and literals of the wrong type.
+func Validate(d *Defaults, schema *Schema) []Problem {
+	var problems []Problem
+	report := func(r tree_sitter.Range, format string, args ...any) {
+		problems = append(problems, Problem{Range: r, Message: fmt.Sprintf(format, args...)})
+	}
+
+	for _, p := range d.Properties {
+		spec, ok := schema.Property(p.Name)
+		if !ok {
+			report(p.NameRange, "unknown property %q%s", p.Name, suggest(p.Name, schema.PropertyNames()))
+			continue
+		}
+		min, max := spec.Arity()
+		if n := len(p.Values); n < min || (max >= 0 && n > max) {
+			report(p.NameRange, "%s takes %s, got %d", spec.Name, describeArity(min, max), n)
+			continue
+		}
+		for i, v := range p.Values {
+			param, _ := spec.param(i)
+			isString := v.Kind() == zscript.NodeStringLiteral
+			switch {
+			case param.Type.numeric() && isString:
+				report(v.Range(), "%s expects a number, got a string", spec.Name)
+			case !param.Type.numeric() && param.Type != TypeExpr && v.Kind() == zscript.NodeNumberLiteral:
+				report(v.Range(), "%s expects a string, got a number", spec.Name)
+			}
+		}
+	}
+	for _, f := range d.Flags {
+		if !schema.Flag(f.Name) {
+			report(f.NameRange, "unknown flag %q%s", f.Name, suggest(f.Name, schema.FlagNames()))
+		}
+	}
+	return problems
+}
+
+func describeArity(min, max int) string {
+	plural := func(n int) string {
+		if n == 1 {
+			return "1 argument"
+		}
+		return fmt.Sprintf("%d arguments", n)
+	}
+	switch {
+	case max < 0:
+		return "at least " + plural(min)
+	case min == max:
+		return plural(min)
+	}
+	return fmt.Sprintf("%d to %d arguments", min, max)
+}
+
+// PropertyNames returns the names of the properties, sorted.
+func (s *Schema) PropertyNames() []string {
+	names := make([]string, 0, len(s.properties))
+	for _, spec := range s.properties {
+		names = append(names, spec.Name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// FlagNames returns the names of the flags, with their class prefixes,
+// sorted.
+func (s *Schema) FlagNames() []string {
+	names := make([]string, 0, len(s.flags))
+	for _, name := range s.flags {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// suggest returns a "did you mean" clause naming the candidate closest to
+// name, or "" if none is close. Flags are compared without their prefix
+// when name has none.
+func suggest(name string, candidates []string) string {
+	target := strings.ToLower(name)
+	best, bestDist := "", 3
+	for _, c := range candidates {
+		key := strings.ToLower(c)
+		if !strings.Contains(target, ".") {
+			key = strings.ToLower(bare(c))
+		}
+		if d := distance(target, key); d < bestDist {
+			best, bestDist = c, d
+		}
+	}
+	if best == "" {
+		return ""
+	}
+	return fmt.Sprintf("; did you mean %q?", best)
+}
+
+// distance returns the Levenshtein distance between a and b.
+func distance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
@@ -0,0 +1,404 @@
+// Package depgraph builds the dependency graph of a ZScript project, for
+// viewing the architecture of a large mod: which file includes which,
+// which file declares or extends which class, and which class inherits
+// from, replaces or refers to which. It writes the graph in Graphviz DOT,
+// for rendering, and in JSON, for other tools, and finds the files that a
+// change to some files affects, for checking only those.
+//
+// A class refers to another when its body, or that of a class extending
+// it, names the other class as a type, in an expression or in a string or
+// name literal, as in A_SpawnItem("Spark"). Engine classes that project
+// classes inherit from or replace are nodes too, marked external.
+package depgraph
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+
+	zscript "github.com/jlcrochet/tree-sitter-zscript/bindings/go"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/project"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/zscriptast"
+)
+
+// NodeKind classifies the nodes of the graph.
+type NodeKind int
+
+const (
+	// NodeFile is a file of the project.
+	NodeFile NodeKind = iota
+	// NodeClass is a class.
+	NodeClass
+)
+
+func (k NodeKind) String() string {
+	if k == NodeClass {
+		return "class"
+	}
+	return "file"
+}
+
+// Node is a file or class.
+type Node struct {
+	Kind NodeKind
+	// Name is the path of a file or the name of a class, spelled as it is
+	// declared.
+	Name string
+	// Path is the file that declares a class, or "" for an external class.
+	Path string
+	// External is set for classes the project does not declare.
+	External bool
+}
+
+// ID returns the identifier of the node in DOT and JSON output, such as
+// "class:Imp".
+func (n *Node) ID() string {
+	return n.Kind.String() + ":" + n.Name
+}
+
+// EdgeKind classifies the edges of the graph.
+type EdgeKind int
+
+const (
+	// EdgeIncludes goes from a file to a file it includes.
+	EdgeIncludes EdgeKind = iota
+	// EdgeDeclares goes from a file to a class it declares.
+	EdgeDeclares
+	// EdgeInherits goes from a class to its parent.
+	EdgeInherits
+	// EdgeReplaces goes from a class to the class it replaces.
+	EdgeReplaces
+	// EdgeReferences goes from a class to a class it refers to.
+	EdgeReferences
+	// EdgeExtends goes from a file to a class of the project it extends.
+	EdgeExtends
+)
+
+var edgeNames = [...]string{"includes", "declares", "inherits", "replaces", "references", "extends"}
+
+func (k EdgeKind) String() string {
+	return edgeNames[k]
+}
+
+// Edge is a dependency of From on To.
+type Edge struct {
+	From, To *Node
+	Kind     EdgeKind
+}
+
+// Graph is a dependency graph. Nodes are sorted by kind and name, and
+// edges by their ends and kind.
+type Graph struct {
+	Nodes []*Node
+	Edges []*Edge
+}
+
+// Build builds the graph of p.
+func Build(p *project.Project) *Graph {
+	b := &builder{nodes: map[string]*Node{}, edges: map[edgeKey]bool{}, g: &Graph{}}
+	files := make([]zscriptast.File, len(p.Files))
+	for i, f := range p.Files {
+		b.node(NodeFile, f.Path, "")
+		files[i] = zscriptast.NewFile(f.Tree.Tree, f.Tree.Source)
+		for _, c := range files[i].Classes() {
+			if !c.IsExtend() && c.Name() != "" {
+				b.node(NodeClass, c.Name(), f.Path)
+			}
+		}
+	}
+	for i, f := range p.Files {
+		from := b.nodes[key(NodeFile, f.Path)]
+		for _, inc := range f.Includes {
+			if t := p.File(inc); t != nil {
+				b.edge(from, b.nodes[key(NodeFile, t.Path)], EdgeIncludes)
+			}
+		}
+		for _, c := range files[i].Classes() {
+			class := b.nodes[key(NodeClass, c.Name())]
+			if class == nil {
+				continue
+			}
+			if !c.IsExtend() {
+				b.edge(from, class, EdgeDeclares)
+				if parent := c.Parent(); parent != "" {
+					b.edge(class, b.class(parent), EdgeInherits)
+				}
+				if replaced := c.Replaces(); replaced != "" {
+					b.edge(class, b.class(replaced), EdgeReplaces)
+				}
+			} else {
+				b.edge(from, class, EdgeExtends)
+			}
+			b.references(class, c.Node.Raw, f.Tree.Source)
+		}
+	}
+	b.sort()
+	return b.g
+}
+
+type edgeKey struct {
+	from, to *Node
+	kind     EdgeKind
+}
+
+type builder struct {
+	nodes map[string]*Node
+	edges map[edgeKey]bool
+	g     *Graph
+}
+
+func key(kind NodeKind, name string) string {
+	return kind.String() + ":" + strings.ToLower(name)
+}
+
+func (b *builder) node(kind NodeKind, name, path string) *Node {
+	k := key(kind, name)
+	if n := b.nodes[k]; n != nil {
+		return n
+	}
+	n := &Node{Kind: kind, Name: name, Path: path, External: kind == NodeClass && path == ""}
+	b.nodes[k] = n
+	b.g.Nodes = append(b.g.Nodes, n)
+	return n
+}
+
+// class returns the node of the class named name, adding an external one
+// if the project does not declare it.
+func (b *builder) class(name string) *Node {
+	return b.node(NodeClass, name, "")
+}
+
+func (b *builder) edge(from, to *Node, kind EdgeKind) {
+	k := edgeKey{from, to, kind}
+	if from == to || b.edges[k] {
+		return
+	}
+	b.edges[k] = true
+	b.g.Edges = append(b.g.Edges, &Edge{from, to, kind})
+}
+
+// references adds an edge from class to each project class the members
+// of the declaration node name, other than its parent and the class it
+// replaces.
+func (b *builder) references(class *Node, node *tree_sitter.Node, source []byte) {
+	name := func(text string) {
+		to := b.nodes[key(NodeClass, text)]
+		if to != nil && !b.edges[edgeKey{class, to, EdgeInherits}] && !b.edges[edgeKey{class, to, EdgeReplaces}] {
+			b.edge(class, to, EdgeReferences)
+		}
+	}
+	var v zscript.Visitor
+	text := func(n *tree_sitter.Node) zscript.WalkAction {
+		name(n.Utf8Text(source))
+		return zscript.WalkContinue
+	}
+	v.On(zscript.NodeTypeIdentifier, text)
+	v.On(zscript.NodeIdentifier, text)
+	v.On(zscript.NodeStringContent, text)
+	v.On(zscript.NodeNameLiteral, func(n *tree_sitter.Node) zscript.WalkAction {
+		name(strings.Trim(n.Utf8Text(source), "'"))
+		return zscript.WalkSkipChildren
+	})
+	for i := uint(0); i < node.NamedChildCount(); i++ {
+		switch member := node.NamedChild(i); member.Kind() {
+		case zscript.NodeTypeIdentifier, zscript.NodeInheritanceSpecifier, zscript.NodeClassFlags:
+		default:
+			zscript.Walk(member, &v)
+		}
+	}
+}
+
+func (b *builder) sort() {
+	less := func(x, y *Node) bool {
+		if x.Kind != y.Kind {
+			return x.Kind < y.Kind
+		}
+		return strings.ToLower(x.Name) < strings.ToLower(y.Name)
+	}
+	sort.Slice(b.g.Nodes, func(i, j int) bool { return less(b.g.Nodes[i], b.g.Nodes[j]) })
+	sort.Slice(b.g.Edges, func(i, j int) bool {
+		x, y := b.g.Edges[i], b.g.Edges[j]
+		switch {
+		case x.From != y.From:
+			return less(x.From, y.From)
+		case x.To != y.To:
+			return less(x.To, y.To)
+		}
+		return x.Kind < y.Kind
+	})
+}
+
+// Only returns the subgraph of the nodes of the given kind and the edges
+// between them: the include graph of the files, or the class graph.
+func (g *Graph) Only(kind NodeKind) *Graph {
+	sub := &Graph{}
+	for _, n := range g.Nodes {
+		if n.Kind == kind {
+			sub.Nodes = append(sub.Nodes, n)
+		}
+	}
+	for _, e := range g.Edges {
+		if e.From.Kind == kind && e.To.Kind == kind {
+			sub.Edges = append(sub.Edges, e)
+		}
+	}
+	return sub
+}
+
+// Affected returns the paths of the files whose analysis a change to the
+// files at paths may change, in the order of g's nodes: the files
+// themselves and those that include them; the files declaring or extending
+// a class they declare or extend, or a class inheriting from or replacing
+// one of these, however indirectly; and the files declaring or extending
+// a class that refers to any of those classes. Paths that name no file of
+// g, as those of deleted files do, are ignored.
+func (g *Graph) Affected(paths ...string) []string {
+	changed := map[*Node]bool{}
+	byName := map[string]*Node{}
+	for _, n := range g.Nodes {
+		if n.Kind == NodeFile {
+			byName[n.Name] = n
+		}
+	}
+	for _, p := range paths {
+		if n := byName[p]; n != nil {
+			changed[n] = true
+		}
+	}
+
+	// files maps each class to the files declaring or extending it, and
+	// dependents to the classes inheriting from or replacing it.
+	files := map[*Node][]*Node{}
+	dependents := map[*Node][]*Node{}
+	affected := map[*Node]bool{}
+	var queue []*Node
+	for _, e := range g.Edges {
+		switch e.Kind {
+		case EdgeDeclares, EdgeExtends:
+			files[e.To] = append(files[e.To], e.From)
+			if changed[e.From] && !affected[e.To] {
+				affected[e.To] = true
+				queue = append(queue, e.To)
+			}
+		case EdgeInherits, EdgeReplaces:
+			dependents[e.To] = append(dependents[e.To], e.From)
+		}
+	}
+	for len(queue) > 0 {
+		c := queue[0]
+		queue = queue[1:]
+		for _, d := range dependents[c] {
+			if !affected[d] {
+				affected[d] = true
+				queue = append(queue, d)
+			}
+		}
+	}
+
+	result := map[*Node]bool{}
+	for n := range changed {
+		result[n] = true
+	}
+	for c := range affected {
+		for _, f := range files[c] {
+			result[f] = true
+		}
+	}
+	for _, e := range g.Edges {
+		switch {
+		case e.Kind == EdgeIncludes && changed[e.To]:
+			result[e.From] = true
+		case e.Kind == EdgeReferences && affected[e.To]:
+			for _, f := range files[e.From] {
+				result[f] = true
+			}
+		}
+	}
+	var out []string
+	for _, n := range g.Nodes {
+		if result[n] {
+			out = append(out, n.Name)
+		}
+	}
+	return out
+}
+
+// WriteDOT writes g in the Graphviz DOT language. Files are drawn as
+// notes and classes as boxes, dashed if external; inheritance edges have
+// hollow arrowheads and references are dotted.
+func (g *Graph) WriteDOT(w io.Writer) error {
+	var sb strings.Builder
+	sb.WriteString("digraph zscript {\n\trankdir=LR;\n")
+	for _, n := range g.Nodes {
+		attrs := "shape=note"
+		if n.Kind == NodeClass {
+			attrs = "shape=box"
+			if n.External {
+				attrs += ", style=dashed"
+			}
+		}
+		fmt.Fprintf(&sb, "\t%s [label=%s, %s];\n", quote(n.ID()), quote(n.Name), attrs)
+	}
+	for _, e := range g.Edges {
+		attrs := ""
+		switch e.Kind {
+		case EdgeInherits:
+			attrs = ", arrowhead=empty"
+		case EdgeReplaces:
+			attrs = ", style=bold"
+		case EdgeReferences:
+			attrs = ", style=dotted"
+		case EdgeDeclares:
+			attrs = ", arrowhead=none, color=gray"
+		case EdgeExtends:
+			attrs = ", color=gray"
+		}
+		fmt.Fprintf(&sb, "\t%s -> %s [label=%s%s];\n", quote(e.From.ID()), quote(e.To.ID()), quote(e.Kind.String()), attrs)
+	}
+	sb.WriteString("}\n")
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// quote returns s as a DOT quoted string.
+func quote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+type jsonGraph struct {
+	Nodes []jsonNode `json:"nodes"`
+	Edges []jsonEdge `json:"edges"`
+}
+
+type jsonNode struct {
+	ID       string `json:"id"`
+	Kind     string `json:"kind"`
+	Name     string `json:"name"`
+	Path     string `json:"path,omitempty"`
+	External bool   `json:"external,omitempty"`
+}
+
+type jsonEdge struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+	Kind string `json:"kind"`
+}
+
+// WriteJSON writes g as a JSON object with "nodes" and "edges" arrays.
+// Edges name their ends by node ID.
+func (g *Graph) WriteJSON(w io.Writer) error {
+	out := jsonGraph{Nodes: []jsonNode{}, Edges: []jsonEdge{}}
+	for _, n := range g.Nodes {
+		out.Nodes = append(out.Nodes, jsonNode{n.ID(), n.Kind.String(), n.Name, n.Path, n.External})
+	}
+	for _, e := range g.Edges {
+		out.Edges = append(out.Edges, jsonEdge{e.From.ID(), e.To.ID(), e.Kind.String()})
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(out)
+}
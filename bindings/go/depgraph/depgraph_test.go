@@ -0,0 +1,125 @@
+package depgraph_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/depgraph"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/project"
+)
+
+func load(t *testing.T) *project.Project {
+	t.Helper()
+	fsys := fstest.MapFS{
+		"zscript.zs": {Data: []byte(`version "4.10"
+#include "zscript/imp.zs"
+#include "zscript/spark.zs"
+`)},
+		"zscript/imp.zs": {Data: []byte(`#include "zscript/spark.zs"
+class FireImp : DoomImp replaces DoomImp {
+	States { Missile: TROO A 8 A_SpawnItem("Spark"); Stop; }
+}`)},
+		"zscript/spark.zs": {Data: []byte(`class Spark : Actor { void F() { let imp = FireImp(target); } }
+extend class FireImp { Spark last; }`)},
+	}
+	p, err := project.Load(context.Background(), fsys, "zscript.zs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(p.Close)
+	return p
+}
+
+func TestWriteDOT(t *testing.T) {
+	var buf bytes.Buffer
+	if err := depgraph.Build(load(t)).WriteDOT(&buf); err != nil {
+		t.Fatal(err)
+	}
+	want := `digraph zscript {
+	rankdir=LR;
+	"file:zscript.zs" [label="zscript.zs", shape=note];
+	"file:zscript/imp.zs" [label="zscript/imp.zs", shape=note];
+	"file:zscript/spark.zs" [label="zscript/spark.zs", shape=note];
+	"class:Actor" [label="Actor", shape=box, style=dashed];
+	"class:DoomImp" [label="DoomImp", shape=box, style=dashed];
+	"class:FireImp" [label="FireImp", shape=box];
+	"class:Spark" [label="Spark", shape=box];
+	"file:zscript.zs" -> "file:zscript/imp.zs" [label="includes"];
+	"file:zscript.zs" -> "file:zscript/spark.zs" [label="includes"];
+	"file:zscript/imp.zs" -> "file:zscript/spark.zs" [label="includes"];
+	"file:zscript/imp.zs" -> "class:FireImp" [label="declares", arrowhead=none, color=gray];
+	"file:zscript/spark.zs" -> "class:FireImp" [label="extends", color=gray];
+	"file:zscript/spark.zs" -> "class:Spark" [label="declares", arrowhead=none, color=gray];
+	"class:FireImp" -> "class:DoomImp" [label="inherits", arrowhead=empty];
+	"class:FireImp" -> "class:DoomImp" [label="replaces", style=bold];
+	"class:FireImp" -> "class:Spark" [label="references", style=dotted];
+	"class:Spark" -> "class:Actor" [label="inherits", arrowhead=empty];
+	"class:Spark" -> "class:FireImp" [label="references", style=dotted];
+}
+`
+	if got := buf.String(); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestWriteJSON(t *testing.T) {
+	var buf bytes.Buffer
+	if err := depgraph.Build(load(t)).Only(depgraph.NodeFile).WriteJSON(&buf); err != nil {
+		t.Fatal(err)
+	}
+	var out struct {
+		Nodes []struct{ ID, Kind string }
+		Edges []struct{ From, To, Kind string }
+	}
+	if err := json.Unmarshal(buf.Bytes(), &out); err != nil {
+		t.Fatal(err)
+	}
+	if len(out.Nodes) != 3 || len(out.Edges) != 3 {
+		t.Fatalf("file graph:\n%s", buf.String())
+	}
+	if e := out.Edges[2]; e.From != "file:zscript/imp.zs" || e.To != "file:zscript/spark.zs" || e.Kind != "includes" {
+		t.Errorf("edge %+v", e)
+	}
+	for _, n := range out.Nodes {
+		if n.Kind != "file" || !strings.HasPrefix(n.ID, "file:") {
+			t.Errorf("node %+v in the file graph", n)
+		}
+	}
+}
+
+func TestAffected(t *testing.T) {
+	fsys := fstest.MapFS{
+		"zscript.zs": {Data: []byte(`#include "base.zs"
+#include "imp.zs"
+#include "boss.zs"
+#include "other.zs"
+`)},
+		"base.zs":  {Data: []byte(`class Monster : Actor {}`)},
+		"imp.zs":   {Data: []byte(`class Imp : Monster {}`)},
+		"boss.zs":  {Data: []byte(`class Boss : Imp replaces Imp {} extend class Monster { int level; }`)},
+		"other.zs": {Data: []byte(`class Spawner : Actor { void F() { Spawn("Boss", pos); } } class Lone : Actor {}`)},
+	}
+	p, err := project.Load(context.Background(), fsys, "zscript.zs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+	g := depgraph.Build(p)
+	for _, tc := range []struct {
+		changed, want []string
+	}{
+		{[]string{"base.zs"}, []string{"base.zs", "boss.zs", "imp.zs", "other.zs", "zscript.zs"}},
+		{[]string{"boss.zs"}, []string{"base.zs", "boss.zs", "imp.zs", "other.zs", "zscript.zs"}},
+		{[]string{"other.zs"}, []string{"other.zs", "zscript.zs"}},
+		{[]string{"deleted.zs"}, nil},
+	} {
+		if got := g.Affected(tc.changed...); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("Affected(%v) = %v, want %v", tc.changed, got, tc.want)
+		}
+	}
+}
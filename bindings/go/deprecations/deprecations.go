@@ -0,0 +1,204 @@
+// Package deprecations is a table of the functions and fields GZDoom has
+// deprecated, with their replacements and the version that deprecated
+// them.
+package deprecations
+
+import (
+	_ "embed"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/version"
+)
+
+//go:embed deprecations.txt
+var tableFile string
+
+// Kind is the kind of a deprecated declaration.
+type Kind int
+
+const (
+	Function Kind = iota + 1
+	Field
+)
+
+func (k Kind) String() string {
+	switch k {
+	case Function:
+		return "function"
+	case Field:
+		return "field"
+	}
+	return "unknown"
+}
+
+// Entry is a deprecated function or field.
+type Entry struct {
+	Kind Kind
+	// Name is the declaration's name, possibly with a class prefix such
+	// as "LevelLocals.".
+	Name string
+	// Since is the version that deprecated it.
+	Since version.Version
+	// Replacement is what to use instead, or "" if there is nothing to
+	// use instead.
+	Replacement string
+	// RenameArgs is the number of leading arguments the replacement
+	// takes the same way, so that a call passing at most that many can
+	// be rewritten by renaming it; -1 means any call can be. It is 0 if
+	// renaming is not enough.
+	RenameArgs int
+	// Advice is extra guidance on moving off the declaration.
+	Advice string
+}
+
+// Member returns the name without its class prefix.
+func (e *Entry) Member() string {
+	return e.Name[strings.LastIndexByte(e.Name, '.')+1:]
+}
+
+// Message describes the deprecation of the declaration spelled name, such
+// as "A_PlaySound is deprecated since GZDoom 4.3; use A_StartSound
+// instead".
+func (e *Entry) Message(name string) string {
+	msg := fmt.Sprintf("%s is deprecated since GZDoom %s", name, e.Since)
+	switch {
+	case e.Replacement != "" && e.Advice != "":
+		msg += fmt.Sprintf("; use %s instead (%s)", e.Replacement, e.Advice)
+	case e.Replacement != "":
+		msg += fmt.Sprintf("; use %s instead", e.Replacement)
+	case e.Advice != "":
+		msg += "; " + e.Advice
+	}
+	return msg
+}
+
+// Renames reports whether a use passing args arguments can be fixed by
+// renaming it to the replacement. For a field, pass 0.
+func (e *Entry) Renames(args int) bool {
+	return e.Replacement != "" && (e.RenameArgs < 0 || e.Kind == Function && e.RenameArgs > 0 && args <= e.RenameArgs)
+}
+
+// AppliesTo reports whether e applies to code written for version v.
+// GZDoom warns only about deprecations at or before the version a file
+// declares.
+func (e *Entry) AppliesTo(v version.Version) bool {
+	return e.Since.Compare(v) <= 0
+}
+
+type key struct {
+	kind Kind
+	name string
+}
+
+// Table is a set of deprecations.
+type Table struct {
+	entries map[key]*Entry
+}
+
+// New returns an empty table.
+func New() *Table {
+	return &Table{entries: map[key]*Entry{}}
+}
+
+var builtin = sync.OnceValue(func() *Table {
+	t, err := Parse(tableFile)
+	if err != nil {
+		panic(err)
+	}
+	return t
+})
+
+// Default returns the table of GZDoom's own deprecations. It is shared;
+// use Clone before adding to it.
+func Default() *Table {
+	return builtin()
+}
+
+// Parse reads a table in the format of the embedded deprecations.txt:
+// one entry per line of kind, name, version and replacement ("-" for
+// none, with an optional "/n" or "/*" suffix setting RenameArgs),
+// optionally followed by advice. Blank lines and lines starting with "#"
+// are ignored.
+func Parse(text string) (*Table, error) {
+	t := New()
+	for i, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) < 4 {
+			return nil, fmt.Errorf("deprecations: line %d: want kind, name, version and replacement", i+1)
+		}
+		e := &Entry{Name: fields[1], Advice: strings.Join(fields[4:], " ")}
+		switch fields[0] {
+		case "function":
+			e.Kind = Function
+		case "field":
+			e.Kind = Field
+		default:
+			return nil, fmt.Errorf("deprecations: line %d: unknown kind %q", i+1, fields[0])
+		}
+		v, err := version.Parse(fields[2])
+		if err != nil {
+			return nil, fmt.Errorf("deprecations: line %d: %w", i+1, err)
+		}
+		e.Since = v
+		if fields[3] != "-" {
+			replacement, args, renamable := strings.Cut(fields[3], "/")
+			e.Replacement = replacement
+			switch n, err := strconv.Atoi(args); {
+			case !renamable:
+			case args == "*":
+				e.RenameArgs = -1
+			case err == nil && n > 0:
+				e.RenameArgs = n
+			default:
+				return nil, fmt.Errorf("deprecations: line %d: invalid argument count %q", i+1, args)
+			}
+		}
+		t.Add(e)
+	}
+	return t, nil
+}
+
+// Add adds or replaces an entry.
+func (t *Table) Add(e *Entry) {
+	t.entries[key{e.Kind, strings.ToLower(e.Member())}] = e
+}
+
+// Clone returns a copy of t that can be added to independently.
+func (t *Table) Clone() *Table {
+	c := New()
+	for k, e := range t.entries {
+		c.entries[k] = e
+	}
+	return c
+}
+
+// Lookup returns the entry for the function or field spelled name,
+// matched case-insensitively and without any class prefix.
+func (t *Table) Lookup(kind Kind, name string) (*Entry, bool) {
+	e, ok := t.entries[key{kind, strings.ToLower(name[strings.LastIndexByte(name, '.')+1:])}]
+	return e, ok
+}
+
+// Entries returns the entries sorted by kind and name.
+func (t *Table) Entries() []*Entry {
+	entries := make([]*Entry, 0, len(t.entries))
+	for _, e := range t.entries {
+		entries = append(entries, e)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		a, b := entries[i], entries[j]
+		if a.Kind != b.Kind {
+			return a.Kind < b.Kind
+		}
+		return strings.ToLower(a.Name) < strings.ToLower(b.Name)
+	})
+	return entries
+}
@@ -0,0 +1,71 @@
+package deprecations_test
+
+import (
+	"testing"
+
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/deprecations"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/version"
+)
+
+func TestDefault(t *testing.T) {
+	table := deprecations.Default()
+	e, ok := table.Lookup(deprecations.Function, "a_playsound")
+	if !ok {
+		t.Fatal("A_PlaySound not in the default table")
+	}
+	if e.Name != "A_PlaySound" || e.Replacement != "A_StartSound" || e.Since != version.MustParse("4.3") {
+		t.Errorf("A_PlaySound entry = %+v", e)
+	}
+	if _, ok := table.Lookup(deprecations.Field, "A_PlaySound"); ok {
+		t.Error("A_PlaySound found as a field")
+	}
+	if e.AppliesTo(version.MustParse("4.2")) || !e.AppliesTo(version.MustParse("4.3")) {
+		t.Error("AppliesTo does not compare against Since")
+	}
+	if !e.Renames(2) || e.Renames(3) {
+		t.Errorf("A_PlaySound RenameArgs = %d, want 2", e.RenameArgs)
+	}
+	if got, want := e.Message("a_playsound"), "a_playsound is deprecated since GZDoom 4.3; use A_StartSound instead"; got != want {
+		t.Errorf("Message = %q, want %q", got, want)
+	}
+	entries := table.Entries()
+	for i := 1; i < len(entries); i++ {
+		if entries[i-1].Kind == entries[i].Kind && entries[i-1].Name > entries[i].Name {
+			t.Errorf("Entries not sorted: %s before %s", entries[i-1].Name, entries[i].Name)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	table, err := deprecations.Parse(`
+# A comment.
+field LevelLocals.OldTimer 4.1 - use the new timer API
+function Old 3.0 New and check the return value
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, ok := table.Lookup(deprecations.Field, "oldtimer")
+	if !ok || f.Member() != "OldTimer" || f.Replacement != "" {
+		t.Fatalf("OldTimer entry = %+v, %v", f, ok)
+	}
+	if got, want := f.Message("OldTimer"), "OldTimer is deprecated since GZDoom 4.1; use the new timer API"; got != want {
+		t.Errorf("Message = %q, want %q", got, want)
+	}
+	g, _ := table.Lookup(deprecations.Function, "Old")
+	if got, want := g.Message("Old"), "Old is deprecated since GZDoom 3.0; use New instead (and check the return value)"; got != want {
+		t.Errorf("Message = %q, want %q", got, want)
+	}
+
+	clone := table.Clone()
+	clone.Add(&deprecations.Entry{Kind: deprecations.Function, Name: "Extra", Since: version.MustParse("4.0")})
+	if _, ok := table.Lookup(deprecations.Function, "Extra"); ok {
+		t.Error("Add on a clone changed the original")
+	}
+
+	for _, bad := range []string{"function Old", "method Old 4.0 New", "function Old four New", "function Old 4.0 New/x"} {
+		if _, err := deprecations.Parse(bad); err == nil {
+			t.Errorf("Parse(%q) succeeded", bad)
+		}
+	}
+}
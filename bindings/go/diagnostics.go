@@ -0,0 +1,166 @@
+package tree_sitter_zscript
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+)
+
+// Severity is the severity of a diagnostic. The values match the Language
+// Server Protocol's DiagnosticSeverity.
+type Severity int
+
+const (
+	SeverityError Severity = iota + 1
+	SeverityWarning
+	SeverityInformation
+	SeverityHint
+)
+
+func (s Severity) String() string {
+	switch s {
+	case SeverityError:
+		return "error"
+	case SeverityWarning:
+		return "warning"
+	case SeverityInformation:
+		return "info"
+	case SeverityHint:
+		return "hint"
+	}
+	return "unknown"
+}
+
+// Diagnostic describes a syntax error in a parse tree.
+type Diagnostic struct {
+	Range    tree_sitter.Range
+	Severity Severity
+	Message  string
+	// Text is the source text covered by the error. It is empty for
+	// missing tokens.
+	Text string
+	// Expected lists the tokens the parser could have accepted where the
+	// error begins, when that can be determined. Anonymous tokens are
+	// quoted; named tokens such as identifier are not.
+	Expected []string
+}
+
+// maxExpectedInMessage is the longest list of expected tokens that is
+// spelled out in a diagnostic's message.
+const maxExpectedInMessage = 8
+
+// Diagnostics returns a diagnostic for every ERROR and MISSING node in
+// tree, in source order. Errors nested inside another error are not
+// reported separately.
+func Diagnostics(tree *tree_sitter.Tree, source []byte) []Diagnostic {
+	var diagnostics []Diagnostic
+	var visit func(node *tree_sitter.Node)
+	visit = func(node *tree_sitter.Node) {
+		switch {
+		case node.IsError():
+			diagnostics = append(diagnostics, errorDiagnostic(node, source))
+			return
+		case node.IsMissing():
+			diagnostics = append(diagnostics, Diagnostic{
+				Range:    node.Range(),
+				Severity: SeverityError,
+				Message:  fmt.Sprintf("missing %s", describeKind(node.Kind(), node.IsNamed())),
+			})
+			return
+		case !node.HasError():
+			return
+		}
+		count := node.ChildCount()
+		for i := uint(0); i < count; i++ {
+			visit(node.Child(i))
+		}
+	}
+	visit(tree.RootNode())
+	return diagnostics
+}
+
+func errorDiagnostic(node *tree_sitter.Node, source []byte) Diagnostic {
+	text := node.Utf8Text(source)
+	d := Diagnostic{
+		Range:    node.Range(),
+		Severity: SeverityError,
+		Text:     text,
+		Expected: expectedTokens(node),
+	}
+
+	if excerpt := excerpt(text); excerpt != "" {
+		d.Message = fmt.Sprintf("unexpected %q", excerpt)
+	} else {
+		d.Message = "syntax error"
+	}
+	if n := len(d.Expected); n > 0 && n <= maxExpectedInMessage {
+		d.Message += ", expected " + strings.Join(d.Expected, ", ")
+	}
+	return d
+}
+
+// expectedTokens returns the terminals that are valid in the parse state
+// just before the error node.
+func expectedTokens(node *tree_sitter.Node) []string {
+	language := GetLanguage()
+
+	var state uint16
+	if node.ChildCount() > 0 {
+		state = node.Child(0).ParseState()
+	} else if prev := node.PrevSibling(); prev != nil {
+		state = prev.NextParseState()
+	}
+	if state == 0 || state == 0xffff {
+		return nil
+	}
+
+	it := language.LookaheadIterator(state)
+	if it == nil {
+		return nil
+	}
+	defer it.Close()
+
+	// Terminals are numbered before all rules, the first of which is
+	// source_file.
+	firstRule := language.IdForNodeKind(NodeSourceFile, true)
+	seen := map[string]bool{}
+	var expected []string
+	for _, id := range it.Iter() {
+		if id == 0 || id >= firstRule || !language.NodeKindIsVisible(id) {
+			continue
+		}
+		kind := language.NodeKindForId(id)
+		if kind == NodeComment {
+			continue
+		}
+		name := describeKind(kind, language.NodeKindIsNamed(id))
+		if !seen[name] {
+			seen[name] = true
+			expected = append(expected, name)
+		}
+	}
+	sort.Strings(expected)
+	return expected
+}
+
+func describeKind(kind string, named bool) string {
+	if named {
+		return strings.ReplaceAll(kind, "_", " ")
+	}
+	return fmt.Sprintf("%q", kind)
+}
+
+// excerpt returns the first line of text, shortened for use in a message.
+func excerpt(text string) string {
+	const max = 40
+	text = strings.TrimSpace(text)
+	if i := strings.IndexByte(text, '\n'); i >= 0 {
+		text = strings.TrimSpace(text[:i]) + "..."
+	}
+	if len(text) > max {
+		text = text[:max] + "..."
+	}
+	return text
+}
@@ -0,0 +1,49 @@
+package tree_sitter_zscript_test
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+
+	tree_sitter_zscript "github.com/jlcrochet/tree-sitter-zscript/bindings/go"
+)
+
+func diagnose(t *testing.T, source string) []tree_sitter_zscript.Diagnostic {
+	t.Helper()
+	tree, err := tree_sitter_zscript.Parse(context.Background(), []byte(source))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tree.Close()
+	return tree_sitter_zscript.Diagnostics(tree.Tree, tree.Source)
+}
+
+func TestDiagnosticsClean(t *testing.T) {
+	if d := diagnose(t, "class A : Actor { int x; }"); len(d) != 0 {
+		t.Errorf("Diagnostics() = %v", d)
+	}
+}
+
+func TestDiagnosticsMissing(t *testing.T) {
+	d := diagnose(t, "class A {\n\tint x\n}")
+	if len(d) != 1 {
+		t.Fatalf("Diagnostics() = %v", d)
+	}
+	if d[0].Message != `missing ";"` || d[0].Severity != tree_sitter_zscript.SeverityError || d[0].Range.StartPoint.Row != 1 {
+		t.Errorf("Diagnostic = %+v", d[0])
+	}
+}
+
+func TestDiagnosticsError(t *testing.T) {
+	d := diagnose(t, "class A : { }")
+	if len(d) != 1 {
+		t.Fatalf("Diagnostics() = %v", d)
+	}
+	if d[0].Text != ":" || !strings.HasPrefix(d[0].Message, `unexpected ":"`) {
+		t.Errorf("Diagnostic = %+v", d[0])
+	}
+	if !slices.Contains(d[0].Expected, `"{"`) || slices.Contains(d[0].Expected, "class flags") {
+		t.Errorf("Expected = %v", d[0].Expected)
+	}
+}
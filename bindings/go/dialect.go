@@ -0,0 +1,151 @@
+package tree_sitter_zscript
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+)
+
+// Feature is syntax that only later versions of ZScript accept.
+type Feature struct {
+	// Kind is the kind of the nodes of the syntax, and Text, if set, the
+	// text they must have, as for a type keyword.
+	Kind, Text string
+	// What names the syntax in messages, as in "foreach loops".
+	What string
+	// Since is the version that introduced it.
+	Since string
+}
+
+// Features lists the syntax the grammar accepts that older versions of
+// ZScript do not.
+var Features = []Feature{
+	{Kind: NodeForeachStatement, What: "foreach loops", Since: "4.10"},
+	{Kind: NodeMapType, What: "Map types", Since: "4.10"},
+	{Kind: NodeMapiteratorType, What: "MapIterator types", Since: "4.10"},
+	{Kind: NodePrimitiveType, Text: "vector4", What: "vector4", Since: "4.11"},
+}
+
+// Dialect is ZScript as a version of GZDoom accepts it. There is one
+// grammar, for the newest version: a dialect parses with it and reports
+// the Features its version lacks as errors, so that tools targeting an
+// older GZDoom see what it would reject. The zero value is the newest
+// dialect, which accepts everything the grammar does.
+type Dialect struct {
+	name    string
+	version [3]int
+}
+
+// LanguageForVersion returns the dialect of a ZScript version such as
+// "2.8" or "4.10.1". A minor version of "x", as in "4.x", stands for the
+// newest version with that major number.
+func LanguageForVersion(version string) (Dialect, error) {
+	v, err := parseDialectVersion(version)
+	if err != nil {
+		return Dialect{}, err
+	}
+	return Dialect{name: strings.TrimSpace(version), version: v}, nil
+}
+
+func parseDialectVersion(s string) ([3]int, error) {
+	var v [3]int
+	parts := strings.Split(strings.TrimSpace(s), ".")
+	if len(parts) < 2 || len(parts) > 3 {
+		return v, fmt.Errorf("zscript: invalid version %q", s)
+	}
+	for i, p := range parts {
+		if i > 0 && (p == "x" || p == "X") && i == len(parts)-1 {
+			v[i], v[2] = math.MaxInt, math.MaxInt
+			break
+		}
+		n, err := strconv.Atoi(p)
+		if err != nil || n < 0 {
+			return v, fmt.Errorf("zscript: invalid version %q", s)
+		}
+		v[i] = n
+	}
+	return v, nil
+}
+
+// String returns the version of the dialect as it was given, or "newest".
+func (d Dialect) String() string {
+	if d.name == "" {
+		return "newest"
+	}
+	return d.name
+}
+
+// Language returns the language to parse the dialect with.
+func (d Dialect) Language() *tree_sitter.Language {
+	return GetLanguage()
+}
+
+// Allows reports whether the dialect has syntax introduced in version
+// since. It panics if since is not a valid version.
+func (d Dialect) Allows(since string) bool {
+	if d.name == "" {
+		return true
+	}
+	v, err := parseDialectVersion(since)
+	if err != nil {
+		panic(err)
+	}
+	for i := range v {
+		if v[i] != d.version[i] {
+			return v[i] < d.version[i]
+		}
+	}
+	return true
+}
+
+// Check returns an error for each use in tree of a feature the dialect
+// lacks, in source order.
+func (d Dialect) Check(tree *Tree) []Diagnostic {
+	if d.name == "" {
+		return nil
+	}
+	lacking := map[string][]Feature{}
+	for _, f := range Features {
+		if !d.Allows(f.Since) {
+			lacking[f.Kind] = append(lacking[f.Kind], f)
+		}
+	}
+	var diagnostics []Diagnostic
+	var v Visitor
+	for kind, features := range lacking {
+		v.On(kind, func(node *tree_sitter.Node) WalkAction {
+			text := node.Utf8Text(tree.Source)
+			for _, f := range features {
+				if f.Text == "" || strings.EqualFold(text, f.Text) {
+					diagnostics = append(diagnostics, Diagnostic{
+						Range:    node.Range(),
+						Severity: SeverityError,
+						Message:  fmt.Sprintf("%s requires version %s, but the dialect is %s", f.What, f.Since, d),
+						Text:     text,
+					})
+					break
+				}
+			}
+			return WalkContinue
+		})
+	}
+	Walk(tree.RootNode(), &v)
+	return diagnostics
+}
+
+// Diagnostics returns the syntax errors of tree and the errors of Check,
+// in source order.
+func (d Dialect) Diagnostics(tree *Tree) []Diagnostic {
+	diagnostics := Diagnostics(tree.Tree, tree.Source)
+	if extra := d.Check(tree); len(extra) > 0 {
+		diagnostics = append(diagnostics, extra...)
+		sort.SliceStable(diagnostics, func(i, j int) bool {
+			return diagnostics[i].Range.StartByte < diagnostics[j].Range.StartByte
+		})
+	}
+	return diagnostics
+}
@@ -0,0 +1,86 @@
+package tree_sitter_zscript_test
+
+import (
+	"context"
+	"testing"
+
+	tree_sitter_zscript "github.com/jlcrochet/tree-sitter-zscript/bindings/go"
+)
+
+const dialectSource = `class A {
+	Map<int, int> m;
+	vector4 v;
+	void F() { Array<int> a; foreach (x : a) {} }
+}
+`
+
+func TestLanguageForVersion(t *testing.T) {
+	tests := []struct {
+		version string
+		since   string
+		allows  bool
+	}{
+		{"2.8", "4.10", false},
+		{"4.10", "4.10", true},
+		{"4.10.1", "4.10", true},
+		{"4.9.9", "4.10", false},
+		{"4.x", "4.14", true},
+		{"4.x", "5.0", false},
+	}
+	for _, test := range tests {
+		d, err := tree_sitter_zscript.LanguageForVersion(test.version)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := d.Allows(test.since); got != test.allows {
+			t.Errorf("LanguageForVersion(%q).Allows(%q) = %v, want %v", test.version, test.since, got, test.allows)
+		}
+		if d.String() != test.version {
+			t.Errorf("String() = %q, want %q", d, test.version)
+		}
+	}
+	for _, bad := range []string{"", "4", "4.x.1", "x.1", "4.-1", "four.ten"} {
+		if _, err := tree_sitter_zscript.LanguageForVersion(bad); err == nil {
+			t.Errorf("LanguageForVersion(%q) succeeded", bad)
+		}
+	}
+	if !(tree_sitter_zscript.Dialect{}).Allows("99.0") {
+		t.Error("the newest dialect does not allow 99.0")
+	}
+}
+
+func TestDialectDiagnostics(t *testing.T) {
+	check := func(version string, want ...string) {
+		t.Helper()
+		opts := tree_sitter_zscript.ParseOptions{}
+		if version != "" {
+			d, err := tree_sitter_zscript.LanguageForVersion(version)
+			if err != nil {
+				t.Fatal(err)
+			}
+			opts.Dialect = d
+		}
+		tree, err := tree_sitter_zscript.ParseWithOptions(context.Background(), []byte(dialectSource), opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer tree.Close()
+		diags := tree.Dialect.Diagnostics(tree)
+		if len(diags) != len(want) {
+			t.Fatalf("%s: got %d diagnostics, want %d: %v", version, len(diags), len(want), diags)
+		}
+		for i, d := range diags {
+			if d.Message != want[i] || d.Severity != tree_sitter_zscript.SeverityError {
+				t.Errorf("%s: diagnostic %d = %q, want %q", version, i, d.Message, want[i])
+			}
+		}
+	}
+	check("")
+	check("4.x")
+	check("4.10",
+		"vector4 requires version 4.11, but the dialect is 4.10")
+	check("2.8",
+		"Map types requires version 4.10, but the dialect is 2.8",
+		"vector4 requires version 4.11, but the dialect is 2.8",
+		"foreach loops requires version 4.10, but the dialect is 2.8")
+}
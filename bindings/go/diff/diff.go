@@ -0,0 +1,265 @@
+// Package diff compares two versions of ZScript source by declaration
+// rather than by line, reporting the classes, members and state labels
+// that were added, removed or modified.
+//
+// A declaration is modified when its tokens differ; changes to whitespace
+// and comments are ignored. The tokens of a class or struct exclude those
+// of its members, which are compared on their own, so editing a method
+// reports the method but not its class. Default blocks belong to their
+// class.
+//
+// Unified formats an ordinary line-based diff, for tools that rewrite
+// files and show what they changed.
+package diff
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+
+	zscript "github.com/jlcrochet/tree-sitter-zscript/bindings/go"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/project"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/symbols"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/zscriptast"
+)
+
+// Op is the kind of a change.
+type Op int
+
+const (
+	Added Op = iota + 1
+	Removed
+	Modified
+)
+
+func (o Op) String() string {
+	switch o {
+	case Added:
+		return "added"
+	case Removed:
+		return "removed"
+	case Modified:
+		return "modified"
+	}
+	return "unknown"
+}
+
+// Change describes a declaration that differs between two versions.
+type Change struct {
+	Op Op
+	// Name is the declaration's name, qualified by the type that declares
+	// it, such as "Imp.Tick" or "Imp.Spawn" for a state label.
+	Name string
+	Kind symbols.Kind
+	// OldPath and Old locate the declaration in the old version; they are
+	// zero for added declarations. NewPath and New are zero for removed
+	// ones.
+	OldPath, NewPath string
+	Old, New         tree_sitter.Range
+}
+
+func (c Change) String() string {
+	return fmt.Sprintf("%s %s %s", c.Op, c.Kind, c.Name)
+}
+
+// Trees compares the declarations of two parses.
+func Trees(old, new *zscript.Tree) []Change {
+	return compare(collect(old), collect(new))
+}
+
+// Projects compares the declarations of two projects. Declarations are
+// matched by name, so moving one to another file is not a change.
+func Projects(old, new *project.Project) []Change {
+	return compare(collectProject(old), collectProject(new))
+}
+
+// entry is a declaration with the tokens it is compared by.
+type entry struct {
+	name   string
+	kind   symbols.Kind
+	path   string
+	rng    tree_sitter.Range
+	tokens string
+}
+
+// key identifies an entry across versions. Declarations that share a
+// name, such as several extensions of a class, are told apart by their
+// order.
+func (e entry) key(seen map[string]int) string {
+	k := strconv.Itoa(int(e.kind)) + " " + strings.ToLower(e.name)
+	seen[k]++
+	if n := seen[k]; n > 1 {
+		k += "#" + strconv.Itoa(n)
+	}
+	return k
+}
+
+// compare returns the changes in the order of the new declarations,
+// followed by the removed declarations in their old order.
+func compare(old, new []entry) []Change {
+	byKey := map[string]entry{}
+	seen := map[string]int{}
+	var oldKeys []string
+	for _, e := range old {
+		k := e.key(seen)
+		byKey[k] = e
+		oldKeys = append(oldKeys, k)
+	}
+
+	var changes []Change
+	matched := map[string]bool{}
+	seen = map[string]int{}
+	for _, e := range new {
+		k := e.key(seen)
+		o, ok := byKey[k]
+		switch {
+		case !ok:
+			changes = append(changes, Change{Op: Added, Name: e.name, Kind: e.kind, NewPath: e.path, New: e.rng})
+		case o.tokens != e.tokens:
+			changes = append(changes, Change{Op: Modified, Name: e.name, Kind: e.kind, OldPath: o.path, Old: o.rng, NewPath: e.path, New: e.rng})
+		}
+		if ok {
+			matched[k] = true
+		}
+	}
+	for _, k := range oldKeys {
+		if !matched[k] {
+			o := byKey[k]
+			changes = append(changes, Change{Op: Removed, Name: o.name, Kind: o.kind, OldPath: o.path, Old: o.rng})
+		}
+	}
+	return changes
+}
+
+func collectProject(p *project.Project) []entry {
+	var entries []entry
+	for _, f := range p.Files {
+		entries = append(entries, collect(f.Tree)...)
+	}
+	return entries
+}
+
+// collect returns the declarations of tree in source order.
+func collect(tree *zscript.Tree) []entry {
+	c := collector{path: tree.Path}
+	file := zscriptast.NewFile(tree.Tree, tree.Source)
+	for _, class := range file.Classes() {
+		c.class(class)
+	}
+	for _, s := range file.Structs() {
+		c.structDecl(s)
+	}
+	for _, e := range file.Enums() {
+		c.add("", e.Name(), symbols.KindEnum, e.Node, nil)
+	}
+	for _, k := range file.Consts() {
+		c.add("", k.Name(), symbols.KindConst, k.Node, nil)
+	}
+	return c.entries
+}
+
+type collector struct {
+	path    string
+	entries []entry
+}
+
+func (c *collector) add(owner, name string, kind symbols.Kind, n zscriptast.Node, skip map[uintptr]bool) {
+	if owner != "" {
+		name = owner + "." + name
+	}
+	c.entries = append(c.entries, entry{name: name, kind: kind, path: c.path, rng: n.Range(), tokens: tokens(n.Raw, n.Source, skip)})
+}
+
+func (c *collector) class(class zscriptast.ClassDecl) {
+	name := class.Name()
+	skip := map[uintptr]bool{}
+	// The class entry is added first so that entries stay in source
+	// order; its tokens are filled in once its members are known.
+	i := len(c.entries)
+	c.add("", name, symbols.KindClass, class.Node, nil)
+	c.members(name, class.Fields(), class.Methods(), class.Consts(), class.Enums(), skip)
+	for _, p := range class.Properties() {
+		c.add(name, p.Name(), symbols.KindProperty, p.Node, nil)
+		skip[p.Raw.Id()] = true
+	}
+	for _, f := range class.FlagDefs() {
+		c.add(name, f.Name(), symbols.KindFlag, f.Node, nil)
+		skip[f.Raw.Id()] = true
+	}
+	for _, block := range class.States() {
+		for _, label := range block.Labels() {
+			c.add(name, label.Name(), symbols.KindStateLabel, label.Node, nil)
+			skip[label.Raw.Id()] = true
+		}
+	}
+	c.entries[i].tokens = tokens(class.Raw, class.Source, skip)
+}
+
+func (c *collector) structDecl(s zscriptast.StructDecl) {
+	name := s.Name()
+	skip := map[uintptr]bool{}
+	i := len(c.entries)
+	c.add("", name, symbols.KindStruct, s.Node, nil)
+	c.members(name, s.Fields(), s.Methods(), s.Consts(), s.Enums(), skip)
+	c.entries[i].tokens = tokens(s.Raw, s.Source, skip)
+}
+
+func (c *collector) members(owner string, fields []zscriptast.FieldDecl, methods []zscriptast.MethodDecl, consts []zscriptast.ConstDecl, enums []zscriptast.EnumDecl, skip map[uintptr]bool) {
+	for _, k := range consts {
+		c.add(owner, k.Name(), symbols.KindConst, k.Node, nil)
+		skip[k.Raw.Id()] = true
+	}
+	for _, e := range enums {
+		c.add(owner, e.Name(), symbols.KindEnum, e.Node, nil)
+		skip[e.Raw.Id()] = true
+	}
+	for _, f := range fields {
+		// Each declarator is its own field, compared by the shared
+		// modifiers and type and by its own declarator.
+		prefix := tokens(f.ChildOfKind(zscript.NodeMemberModifiers).Raw, f.Source, nil) + "|" + tokens(f.Field(zscript.FieldType).Raw, f.Source, nil)
+		for _, d := range f.Declarators() {
+			name := zscriptast.DeclaratorName(d)
+			c.entries = append(c.entries, entry{
+				name:   owner + "." + name.Text(),
+				kind:   symbols.KindField,
+				path:   c.path,
+				rng:    f.Range(),
+				tokens: prefix + "|" + tokens(d.Raw, d.Source, nil),
+			})
+		}
+		skip[f.Raw.Id()] = true
+	}
+	for _, m := range methods {
+		c.add(owner, m.Name(), symbols.KindMethod, m.Node, nil)
+		skip[m.Raw.Id()] = true
+	}
+}
+
+// tokens returns the leaf tokens of node separated by spaces, leaving out
+// comments and the subtrees in skip.
+func tokens(node *tree_sitter.Node, source []byte, skip map[uintptr]bool) string {
+	if node == nil {
+		return ""
+	}
+	var b strings.Builder
+	var visit func(n *tree_sitter.Node)
+	visit = func(n *tree_sitter.Node) {
+		if skip[n.Id()] || n.Kind() == zscript.NodeComment {
+			return
+		}
+		if n.ChildCount() == 0 {
+			if b.Len() > 0 {
+				b.WriteByte(' ')
+			}
+			b.WriteString(n.Utf8Text(source))
+			return
+		}
+		for i := uint(0); i < n.ChildCount(); i++ {
+			visit(n.Child(i))
+		}
+	}
+	visit(node)
+	return b.String()
+}
@@ -0,0 +1,162 @@
+package diff_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	zscript "github.com/jlcrochet/tree-sitter-zscript/bindings/go"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/diff"
+)
+
+const before = `class Imp : Actor {
+	int health, armor;
+	void Roar() { A_Log("roar"); }
+	void Bite() {}
+	Default { Health 60; }
+	States {
+	Spawn:
+		TROO A 10;
+		Loop;
+	}
+}
+const LIMIT = 1;
+`
+
+// after reformats Imp, changes Roar, a field and the defaults, removes
+// Bite and LIMIT and adds a state label and a struct.
+const after = `// A reformatted imp.
+class Imp : Actor
+{
+	int health, armor = 5;
+
+	void Roar()
+	{
+		A_Log("ROAR");
+	}
+
+	Default
+	{
+		Health 80;
+	}
+
+	States
+	{
+	Spawn:
+		TROO A 10; // unchanged
+		Loop;
+	See:
+		TROO AB 4;
+		Loop;
+	}
+}
+struct Pair { int a; }
+`
+
+func parse(t *testing.T, path, source string) *zscript.Tree {
+	t.Helper()
+	tree, err := zscript.Parse(context.Background(), []byte(source))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(tree.Close)
+	if tree.RootNode().HasError() {
+		t.Fatalf("%s: parse error: %s", path, tree.RootNode().ToSexp())
+	}
+	tree.Path = path
+	return tree
+}
+
+func TestTrees(t *testing.T) {
+	changes := diff.Trees(parse(t, "old.zs", before), parse(t, "new.zs", after))
+	var got []string
+	for _, c := range changes {
+		got = append(got, c.String())
+	}
+	want := []string{
+		"modified class Imp",
+		"modified field Imp.armor",
+		"modified method Imp.Roar",
+		"added state label Imp.See",
+		"added struct Pair",
+		"added field Pair.a",
+		"removed method Imp.Bite",
+		"removed const LIMIT",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("changes =\n%q\nwant\n%q", got, want)
+	}
+
+	roar := changes[2]
+	if roar.OldPath != "old.zs" || roar.NewPath != "new.zs" || roar.Old.StartPoint.Row != 2 || roar.New.StartPoint.Row != 5 {
+		t.Errorf("Roar = %+v", roar)
+	}
+	if bite := changes[6]; bite.NewPath != "" || bite.Old.StartPoint.Row != 3 {
+		t.Errorf("Bite = %+v", bite)
+	}
+}
+
+func TestTreesUnchanged(t *testing.T) {
+	if changes := diff.Trees(parse(t, "a.zs", before), parse(t, "b.zs", before)); len(changes) != 0 {
+		t.Errorf("changes = %v", changes)
+	}
+}
+
+func TestUnified(t *testing.T) {
+	old := "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n13\n14\n15\n16\n17\n18\n19\n20"
+	new := "0\n1\n2\n3\nfour\n5\n6\n7\n8\n9\n10\n11\n12\n13\n14\n15\n16\n17\n18\n19\n20\n21\n"
+	want := `--- old
++++ new
+@@ -1,7 +1,8 @@
++0
+ 1
+ 2
+ 3
+-4
++four
+ 5
+ 6
+ 7
+@@ -17,4 +18,5 @@
+ 17
+ 18
+ 19
+-20
+\ No newline at end of file
++20
++21
+`
+	if got := string(diff.Unified("old", "new", []byte(old), []byte(new))); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+	if got := string(diff.Unified("old", "new", nil, []byte("x\n"))); got != "--- old\n+++ new\n@@ -0,0 +1 @@\n+x\n" {
+		t.Errorf("diff from empty:\n%s", got)
+	}
+	if got := diff.Unified("old", "new", []byte(old), []byte(old)); got != nil {
+		t.Errorf("diff of equal texts:\n%s", got)
+	}
+}
+
+func TestFiles(t *testing.T) {
+	patch := `diff --git a/zscript/imp.zs b/zscript/imp.zs
+--- a/zscript/imp.zs
++++ b/zscript/imp.zs
+@@ -1 +1 @@
+-class Imp {}
++class Imp : Actor {}
+--- a/old.zs	2024-01-01 00:00:00
++++ /dev/null
+@@ -1 +0,0 @@
+-class Old {}
+--- /dev/null
++++ b/new.zs
+@@ -0,0 +1 @@
++class New {}
+`
+	if got, want := diff.Files([]byte(patch)), []string{"zscript/imp.zs", "old.zs", "new.zs"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Files(patch) = %q, want %q", got, want)
+	}
+	if got, want := diff.Files([]byte("a.zs\r\n\nsub/b.zs\na.zs\n")), []string{"a.zs", "sub/b.zs"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Files(list) = %q, want %q", got, want)
+	}
+}
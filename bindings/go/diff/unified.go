@@ -0,0 +1,228 @@
+package diff
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// ContextLines is the number of unchanged lines Unified shows around each
+// change.
+const ContextLines = 3
+
+// Unified returns the line-based unified diff that turns old into new,
+// with the given file names in its header, or nil if they are equal.
+func Unified(oldName, newName string, old, new []byte) []byte {
+	if bytes.Equal(old, new) {
+		return nil
+	}
+	a, b := splitLines(old), splitLines(new)
+	ops := editScript(a, b)
+
+	var out bytes.Buffer
+	fmt.Fprintf(&out, "--- %s\n+++ %s\n", oldName, newName)
+	for start := 0; start < len(ops); {
+		// Find the next change and extend the hunk while changes are
+		// within twice the context of each other.
+		for start < len(ops) && ops[start].kind == ' ' {
+			start++
+		}
+		if start == len(ops) {
+			break
+		}
+		end := start
+		for i := start; i < len(ops); i++ {
+			if ops[i].kind != ' ' {
+				end = i + 1
+			} else if i-end >= 2*ContextLines {
+				break
+			}
+		}
+		first, last := max(start-ContextLines, 0), min(end+ContextLines, len(ops))
+		writeHunk(&out, a, b, ops[first:last])
+		start = last
+	}
+	return out.Bytes()
+}
+
+// op is one line of an edit script: ' ' keeps line a of old as line b of
+// new, '-' deletes line a and '+' inserts line b.
+type op struct {
+	kind byte
+	a, b int
+}
+
+// Files returns the paths of the files that text changes, in the order
+// it names them: the new names of the files of a unified diff, or their
+// old names if they are deleted, without the "a/" and "b/" prefixes git
+// gives them; or, if text is no diff, its lines, as git diff --name-only
+// lists the files.
+func Files(text []byte) []string {
+	lines := strings.Split(strings.ReplaceAll(string(text), "\r\n", "\n"), "\n")
+	isDiff := false
+	for _, line := range lines {
+		if strings.HasPrefix(line, "+++ ") {
+			isDiff = true
+			break
+		}
+	}
+	var files []string
+	seen := map[string]bool{}
+	add := func(name string) {
+		if name != "" && !seen[name] {
+			seen[name] = true
+			files = append(files, name)
+		}
+	}
+	if !isDiff {
+		for _, line := range lines {
+			add(strings.TrimSpace(line))
+		}
+		return files
+	}
+	old := ""
+	for _, line := range lines {
+		switch {
+		case strings.HasPrefix(line, "--- "):
+			old = headerName(line[4:], "a/")
+		case strings.HasPrefix(line, "+++ "):
+			if name := headerName(line[4:], "b/"); name != "" {
+				add(name)
+			} else {
+				add(old)
+			}
+		}
+	}
+	return files
+}
+
+// headerName returns the file name of a --- or +++ line of a diff without
+// its timestamp and prefix, or "" for /dev/null.
+func headerName(name, prefix string) string {
+	if i := strings.IndexByte(name, '\t'); i >= 0 {
+		name = name[:i]
+	}
+	name = strings.TrimSpace(name)
+	if name == "/dev/null" {
+		return ""
+	}
+	return strings.TrimPrefix(name, prefix)
+}
+
+func writeHunk(out *bytes.Buffer, a, b [][]byte, ops []op) {
+	oldStart, newStart, oldCount, newCount := ops[0].a, ops[0].b, 0, 0
+	for _, o := range ops {
+		if o.kind != '+' {
+			oldCount++
+		}
+		if o.kind != '-' {
+			newCount++
+		}
+	}
+	// An empty range is numbered by the line before it.
+	if oldCount > 0 {
+		oldStart++
+	}
+	if newCount > 0 {
+		newStart++
+	}
+	fmt.Fprintf(out, "@@ -%s +%s @@\n", hunkRange(oldStart, oldCount), hunkRange(newStart, newCount))
+	for _, o := range ops {
+		line := b[o.b:]
+		if o.kind == '-' {
+			line = a[o.a:]
+		}
+		out.WriteByte(o.kind)
+		out.Write(line[0])
+		if !bytes.HasSuffix(line[0], []byte("\n")) {
+			out.WriteString("\n\\ No newline at end of file\n")
+		}
+	}
+}
+
+func hunkRange(start, count int) string {
+	if count == 1 {
+		return fmt.Sprint(start)
+	}
+	return fmt.Sprintf("%d,%d", start, count)
+}
+
+// splitLines splits text after each newline.
+func splitLines(text []byte) [][]byte {
+	var lines [][]byte
+	for len(text) > 0 {
+		i := bytes.IndexByte(text, '\n') + 1
+		if i == 0 {
+			i = len(text)
+		}
+		lines = append(lines, text[:i])
+		text = text[i:]
+	}
+	return lines
+}
+
+// editScript returns a shortest edit script from a to b, computed with
+// Myers' algorithm.
+func editScript(a, b [][]byte) []op {
+	n, m := len(a), len(b)
+	offset := n + m + 1
+	v := make([]int, 2*offset+1)
+	// trace[d] holds the entries -d-1 through d+1 of v before round d.
+	var trace [][]int
+	var d int
+search:
+	for d = 0; d <= n+m; d++ {
+		trace = append(trace, append([]int(nil), v[offset-d-1:offset+d+2]...))
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || k != d && v[offset+k-1] < v[offset+k+1] {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && bytes.Equal(a[x], b[y]) {
+				x, y = x+1, y+1
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				break search
+			}
+		}
+	}
+
+	// Walk the trace back from the end, collecting the script in reverse.
+	var ops []op
+	x, y := n, m
+	for ; d > 0; d-- {
+		v := trace[d]
+		k := x - y
+		var prevK int
+		if k == -d || k != d && v[d+k] < v[d+k+2] {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := v[d+1+prevK]
+		prevY := prevX - prevK
+		for x > prevX && y > prevY {
+			x, y = x-1, y-1
+			ops = append(ops, op{' ', x, y})
+		}
+		if x == prevX {
+			y--
+			ops = append(ops, op{'+', x, y})
+		} else {
+			x--
+			ops = append(ops, op{'-', x, y})
+		}
+	}
+	for x > 0 && y > 0 {
+		x, y = x-1, y-1
+		ops = append(ops, op{' ', x, y})
+	}
+	for i, j := 0, len(ops)-1; i < j; i, j = i+1, j-1 {
+		ops[i], ops[j] = ops[j], ops[i]
+	}
+	return ops
+}
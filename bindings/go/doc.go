@@ -0,0 +1,22 @@
+// Package tree_sitter_zscript parses ZScript, the scripting language of
+// GZDoom, with tree-sitter, and provides the walking, query and
+// diagnostic helpers the packages under it build on.
+//
+// # Concurrency
+//
+// The functions of this package may be called from any number of
+// goroutines at once. Parse and the functions built on it lease a parser
+// from a shared pool for the length of one parse; LeaseParser gives
+// direct access to that pool, and SafeParser is a single parser that
+// serializes its callers. Queries likewise take their cursors from a
+// shared pool, through NewQueryCursor.
+//
+// A Tree may be read from several goroutines at once: its nodes, their
+// text and the queries run on them do not change it. Editing a tree, as
+// an IncrementalDocument does, is not safe while another goroutine reads
+// it; give each reader its own copy with Tree.Clone, or hold a lock.
+// Walk and the iterators of CachedQuery use a cursor of their own, and a
+// Visitor may be shared by concurrent walks once its callbacks are
+// registered. IncrementalDocument, tree cursors and query cursors,
+// QueryCursor among them, are not safe for concurrent use.
+package tree_sitter_zscript
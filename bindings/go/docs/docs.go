@@ -0,0 +1,261 @@
+// Package docs extracts documentation comments from ZScript source and
+// renders them as API documentation.
+//
+// A documentation comment is a run of comments that ends on the line just
+// before a declaration, with nothing else on those lines. Both "//" line
+// comments and "/* */" block comments are recognized; the comment markers,
+// a leading "*" on block comment lines, and one space after each marker
+// are removed.
+package docs
+
+import (
+	"sort"
+	"strings"
+
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+
+	zscript "github.com/jlcrochet/tree-sitter-zscript/bindings/go"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/comments"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/project"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/symbols"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/zscriptast"
+)
+
+// Member is a documented declaration.
+type Member struct {
+	Name string
+	Kind symbols.Kind
+	// Signature is the declaration without its body, on one line.
+	Signature  string
+	Doc        string
+	Deprecated bool
+	Path       string
+	// Line is the 1-based line of the declaration.
+	Line uint
+}
+
+// Type is a documented class, struct or enum.
+type Type struct {
+	Member
+	// Parent and Replaces are set for classes.
+	Parent   string
+	Replaces string
+	// Extend is set for an extension of a type defined elsewhere. Package
+	// merges extensions into their type when it is part of the package.
+	Extend  bool
+	Members []*Member
+	// Types are the enums declared inside a class or struct.
+	Types []*Type
+}
+
+// Package is the documentation of a set of files.
+type Package struct {
+	Types  []*Type
+	Consts []*Member
+}
+
+// Extract returns the documentation of a single file.
+func Extract(tree *zscript.Tree) *Package {
+	file := zscriptast.NewFile(tree.Tree, tree.Source)
+	x := extractor{path: tree.Path, comments: comments.Attach(tree.RootNode(), tree.Source)}
+	pkg := &Package{}
+	for _, c := range file.Classes() {
+		pkg.Types = append(pkg.Types, x.class(c))
+	}
+	for _, s := range file.Structs() {
+		pkg.Types = append(pkg.Types, x.structType(s))
+	}
+	for _, e := range file.Enums() {
+		pkg.Types = append(pkg.Types, x.enum(e))
+	}
+	for _, c := range file.Consts() {
+		pkg.Consts = append(pkg.Consts, x.member(c.Node, c.Name(), symbols.KindConst, signature(c.Node)))
+	}
+	return pkg
+}
+
+// FromProject returns the documentation of every file of p. Extensions are
+// merged into the types they extend, and types are sorted by name.
+func FromProject(p *project.Project) *Package {
+	pkg := &Package{}
+	for _, f := range p.Files {
+		x := Extract(f.Tree)
+		pkg.Types = append(pkg.Types, x.Types...)
+		pkg.Consts = append(pkg.Consts, x.Consts...)
+	}
+	pkg.mergeExtensions()
+	sort.SliceStable(pkg.Types, func(i, j int) bool {
+		return strings.ToLower(pkg.Types[i].Name) < strings.ToLower(pkg.Types[j].Name)
+	})
+	sort.SliceStable(pkg.Consts, func(i, j int) bool {
+		return strings.ToLower(pkg.Consts[i].Name) < strings.ToLower(pkg.Consts[j].Name)
+	})
+	return pkg
+}
+
+func (pkg *Package) mergeExtensions() {
+	defs := map[string]*Type{}
+	for _, t := range pkg.Types {
+		if !t.Extend {
+			defs[strings.ToLower(t.Name)] = t
+		}
+	}
+	kept := pkg.Types[:0]
+	for _, t := range pkg.Types {
+		if def := defs[strings.ToLower(t.Name)]; t.Extend && def != nil && def.Kind == t.Kind {
+			def.Members = append(def.Members, t.Members...)
+			def.Types = append(def.Types, t.Types...)
+			continue
+		}
+		kept = append(kept, t)
+	}
+	pkg.Types = kept
+}
+
+// Comment returns the documentation comment of the declaration node, or
+// "". It attaches the comments of the whole tree; Extract attaches them
+// once per file.
+func Comment(node *tree_sitter.Node, source []byte) string {
+	root := node
+	for root.Parent() != nil {
+		root = root.Parent()
+	}
+	return comments.Attach(root, source).Doc(node)
+}
+
+type extractor struct {
+	path     string
+	comments *comments.Map
+}
+
+func (x extractor) member(n zscriptast.Node, name string, kind symbols.Kind, sig string) *Member {
+	return &Member{
+		Name:      name,
+		Kind:      kind,
+		Signature: sig,
+		Doc:       x.comments.Doc(n.Raw),
+		Path:      x.path,
+		Line:      n.Raw.StartPosition().Row + 1,
+	}
+}
+
+func (x extractor) class(c zscriptast.ClassDecl) *Type {
+	t := &Type{
+		Member:   *x.member(c.Node, c.Name(), symbols.KindClass, classSignature(c)),
+		Parent:   c.Parent(),
+		Replaces: c.Replaces(),
+		Extend:   c.IsExtend(),
+	}
+	t.Members = append(t.Members, x.consts(c.Consts())...)
+	t.Members = append(t.Members, x.fields(c.Fields())...)
+	for _, p := range c.Properties() {
+		t.Members = append(t.Members, x.member(p.Node, p.Name(), symbols.KindProperty, signature(p.Node)))
+	}
+	for _, f := range c.FlagDefs() {
+		t.Members = append(t.Members, x.member(f.Node, f.Name(), symbols.KindFlag, signature(f.Node)))
+	}
+	t.Members = append(t.Members, x.methods(c.Methods())...)
+	for _, e := range c.Enums() {
+		t.Types = append(t.Types, x.enum(e))
+	}
+	return t
+}
+
+func (x extractor) structType(s zscriptast.StructDecl) *Type {
+	sig := "struct " + s.Name()
+	if s.IsExtend() {
+		sig = "extend " + sig
+	}
+	t := &Type{
+		Member: *x.member(s.Node, s.Name(), symbols.KindStruct, sig),
+		Extend: s.IsExtend(),
+	}
+	t.Members = append(t.Members, x.consts(s.Consts())...)
+	t.Members = append(t.Members, x.fields(s.Fields())...)
+	t.Members = append(t.Members, x.methods(s.Methods())...)
+	for _, e := range s.Enums() {
+		t.Types = append(t.Types, x.enum(e))
+	}
+	return t
+}
+
+func (x extractor) enum(e zscriptast.EnumDecl) *Type {
+	sig := "enum " + e.Name()
+	if base := e.BaseType(); base != "" {
+		sig += " : " + base
+	}
+	t := &Type{Member: *x.member(e.Node, e.Name(), symbols.KindEnum, sig)}
+	for _, m := range e.Members() {
+		t.Members = append(t.Members, x.member(m.Node, m.Name(), symbols.KindEnumerator, signature(m.Node)))
+	}
+	return t
+}
+
+func (x extractor) consts(decls []zscriptast.ConstDecl) []*Member {
+	var members []*Member
+	for _, c := range decls {
+		members = append(members, x.member(c.Node, c.Name(), symbols.KindConst, signature(c.Node)))
+	}
+	return members
+}
+
+func (x extractor) fields(decls []zscriptast.FieldDecl) []*Member {
+	var members []*Member
+	for _, f := range decls {
+		prefix := f.Type()
+		if mods := f.Modifiers(); len(mods) > 0 {
+			prefix = strings.Join(mods, " ") + " " + prefix
+		}
+		for _, d := range f.Declarators() {
+			m := x.member(f.Node, zscriptast.DeclaratorName(d).Text(), symbols.KindField, prefix+" "+collapse(d.Text()))
+			m.Deprecated = f.HasModifier("deprecated")
+			members = append(members, m)
+		}
+	}
+	return members
+}
+
+func (x extractor) methods(decls []zscriptast.MethodDecl) []*Member {
+	var members []*Member
+	for _, m := range decls {
+		sig := m.Text()
+		if body := m.Body(); !body.IsZero() {
+			sig = string(m.Source[m.Raw.StartByte():body.Raw.StartByte()])
+		}
+		member := x.member(m.Node, m.Name(), symbols.KindMethod, signatureText(sig))
+		member.Deprecated = m.HasModifier("deprecated")
+		members = append(members, member)
+	}
+	return members
+}
+
+func classSignature(c zscriptast.ClassDecl) string {
+	var b strings.Builder
+	if c.IsExtend() {
+		b.WriteString("extend ")
+	} else if c.IsMixin() {
+		b.WriteString("mixin ")
+	}
+	b.WriteString("class " + c.Name())
+	if p := c.Parent(); p != "" {
+		b.WriteString(" : " + p)
+	}
+	if flags := c.ChildOfKind(zscript.NodeClassFlags); !flags.IsZero() {
+		b.WriteString(" " + collapse(flags.Text()))
+	}
+	return b.String()
+}
+
+// signature returns the text of a declaration without its terminator.
+func signature(n zscriptast.Node) string {
+	return signatureText(n.Text())
+}
+
+func signatureText(text string) string {
+	return strings.TrimSuffix(strings.TrimSuffix(collapse(text), ";"), ",")
+}
+
+// collapse replaces each run of whitespace with a single space.
+func collapse(text string) string {
+	return strings.Join(strings.Fields(text), " ")
+}
@@ -0,0 +1,142 @@
+package docs_test
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	zscript "github.com/jlcrochet/tree-sitter-zscript/bindings/go"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/docs"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/project"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/symbols"
+)
+
+const source = `// A friendly imp.
+// It throws fireballs.
+class MyImp : DoomImp replaces DoomImp {
+	/** How angry it is. */
+	int anger; // not documentation
+	int calm;
+
+	// Current ammo.
+
+	property Ammo: anger;
+
+	/*
+	 * Makes it angrier.
+	 * Returns <nothing>.
+	 */
+	deprecated void Provoke(int by = 1) { anger += by; }
+}
+
+/// The limit.
+const MAXANGER = 10;
+`
+
+func extract(t *testing.T) *docs.Package {
+	t.Helper()
+	tree, err := zscript.Parse(context.Background(), []byte(source))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tree.Close()
+	tree.Path = "imp.zs"
+	return docs.Extract(tree)
+}
+
+func TestExtract(t *testing.T) {
+	pkg := extract(t)
+	if len(pkg.Types) != 1 {
+		t.Fatalf("got %d types", len(pkg.Types))
+	}
+	imp := pkg.Types[0]
+	if imp.Doc != "A friendly imp.\nIt throws fireballs." || imp.Signature != "class MyImp : DoomImp replaces DoomImp" || imp.Line != 3 {
+		t.Errorf("class = %+v", imp.Member)
+	}
+
+	want := []struct {
+		name string
+		kind symbols.Kind
+		sig  string
+		doc  string
+	}{
+		{"anger", symbols.KindField, "int anger", "How angry it is."},
+		// The trailing comment on the line above belongs to anger.
+		{"calm", symbols.KindField, "int calm", ""},
+		// A blank line separates the comment from the declaration.
+		{"Ammo", symbols.KindProperty, "property Ammo: anger", ""},
+		{"Provoke", symbols.KindMethod, "deprecated void Provoke(int by = 1)", "Makes it angrier.\nReturns <nothing>."},
+	}
+	if len(imp.Members) != len(want) {
+		t.Fatalf("got %d members", len(imp.Members))
+	}
+	for i, w := range want {
+		m := imp.Members[i]
+		if m.Name != w.name || m.Kind != w.kind || m.Signature != w.sig || m.Doc != w.doc {
+			t.Errorf("member %d = %+v, want %+v", i, *m, w)
+		}
+	}
+	if !imp.Members[3].Deprecated {
+		t.Error("Provoke is not marked deprecated")
+	}
+
+	if len(pkg.Consts) != 1 || pkg.Consts[0].Doc != "The limit." || pkg.Consts[0].Signature != "const MAXANGER = 10" {
+		t.Errorf("consts = %+v", pkg.Consts)
+	}
+}
+
+func TestFromProject(t *testing.T) {
+	fsys := fstest.MapFS{
+		"zscript.zs": {Data: []byte("#include \"b.zs\"\n// Base.\nclass Base {}\n")},
+		"b.zs":       {Data: []byte("extend class base {\n\t// Added later.\n\tvoid Extra() {}\n}\nclass Another {}\n")},
+	}
+	p, err := project.Load(context.Background(), fsys, "zscript.zs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+
+	pkg := docs.FromProject(p)
+	if len(pkg.Types) != 2 || pkg.Types[0].Name != "Another" || pkg.Types[1].Name != "Base" {
+		t.Fatalf("types = %+v", pkg.Types)
+	}
+	base := pkg.Types[1]
+	if len(base.Members) != 1 || base.Members[0].Name != "Extra" || base.Members[0].Doc != "Added later." || base.Members[0].Path != "b.zs" {
+		t.Errorf("Base members = %+v", base.Members)
+	}
+}
+
+func TestRender(t *testing.T) {
+	pkg := extract(t)
+
+	var md bytes.Buffer
+	if err := docs.WriteMarkdown(&md, pkg, docs.Options{Title: "Imps"}); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"# Imps\n",
+		"## MyImp\n\n`class MyImp : DoomImp replaces DoomImp`\n\nA friendly imp.\nIt throws fireballs.\n",
+		"### Methods\n\n#### Provoke\n\n```zscript\ndeprecated void Provoke(int by = 1)\n```\n\n**Deprecated.**\n",
+		"## Constants\n",
+	} {
+		if !strings.Contains(md.String(), want) {
+			t.Errorf("Markdown lacks %q:\n%s", want, md.String())
+		}
+	}
+
+	var html bytes.Buffer
+	if err := docs.WriteHTML(&html, pkg, docs.Options{}); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"<title>API Reference</title>",
+		`<section id="myimp">`,
+		"Returns &lt;nothing&gt;.",
+	} {
+		if !strings.Contains(html.String(), want) {
+			t.Errorf("HTML lacks %q:\n%s", want, html.String())
+		}
+	}
+}
@@ -0,0 +1,166 @@
+package docs
+
+import (
+	htmltemplate "html/template"
+	"io"
+	"strings"
+	"text/template"
+
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/symbols"
+)
+
+// Options controls the generated documents.
+type Options struct {
+	// Title is the heading of the document. It defaults to "API Reference".
+	Title string
+}
+
+// group is a run of members of the same kind, for rendering.
+type group struct {
+	Title   string
+	Members []*Member
+}
+
+var groupTitles = []struct {
+	kind  symbols.Kind
+	title string
+}{
+	{symbols.KindConst, "Constants"},
+	{symbols.KindEnumerator, "Values"},
+	{symbols.KindField, "Fields"},
+	{symbols.KindProperty, "Properties"},
+	{symbols.KindFlag, "Flags"},
+	{symbols.KindMethod, "Methods"},
+}
+
+// groups splits members by kind, in a fixed order of kinds.
+func groups(members []*Member) []group {
+	var result []group
+	for _, g := range groupTitles {
+		var ms []*Member
+		for _, m := range members {
+			if m.Kind == g.kind {
+				ms = append(ms, m)
+			}
+		}
+		if len(ms) > 0 {
+			result = append(result, group{g.title, ms})
+		}
+	}
+	return result
+}
+
+type page struct {
+	Title string
+	*Package
+}
+
+func newPage(pkg *Package, opts Options) page {
+	if opts.Title == "" {
+		opts.Title = "API Reference"
+	}
+	return page{opts.Title, pkg}
+}
+
+var funcs = template.FuncMap{
+	"groups": groups,
+	"anchor": func(name string) string { return strings.ToLower(name) },
+}
+
+var markdown = template.Must(template.New("markdown").Funcs(funcs).Parse(`# {{.Title}}
+{{range .Types}}{{template "type" .}}{{end}}
+{{- if .Consts}}
+## Constants
+{{range .Consts}}{{template "member" .}}{{end}}{{end}}
+{{- define "type"}}
+## {{.Name}}
+
+` + "`{{.Signature}}`" + `
+{{if .Doc}}
+{{.Doc}}
+{{end}}
+Defined in ` + "`{{.Path}}:{{.Line}}`" + `.
+{{range groups .Members}}
+### {{.Title}}
+{{range .Members}}{{template "member" .}}{{end}}{{end}}
+{{- range .Types}}
+### {{.Name}}
+
+` + "`{{.Signature}}`" + `
+{{if .Doc}}
+{{.Doc}}
+{{end}}{{range .Members}}
+- ` + "`{{.Signature}}`" + `{{if .Doc}}: {{.Doc}}{{end}}{{end}}
+{{end}}{{end}}
+{{- define "member"}}
+#### {{.Name}}
+
+` + "```zscript\n{{.Signature}}\n```" + `
+{{if .Deprecated}}
+**Deprecated.**
+{{end}}{{if .Doc}}
+{{.Doc}}
+{{end}}{{end}}`))
+
+var html = htmltemplate.Must(htmltemplate.New("html").Funcs(htmltemplate.FuncMap(funcs)).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<nav><ul>
+{{- range .Types}}
+<li><a href="#{{anchor .Name}}">{{.Name}}</a></li>
+{{- end}}
+</ul></nav>
+{{range .Types}}{{template "type" .}}{{end}}
+{{- if .Consts}}
+<section>
+<h2>Constants</h2>
+{{range .Consts}}{{template "member" .}}{{end}}
+</section>
+{{- end}}
+</body>
+</html>
+{{define "type"}}
+<section id="{{anchor .Name}}">
+<h2>{{.Name}}</h2>
+<pre><code>{{.Signature}}</code></pre>
+{{if .Doc}}<p>{{.Doc}}</p>{{end}}
+<p>Defined in <code>{{.Path}}:{{.Line}}</code>.</p>
+{{- range groups .Members}}
+<h3>{{.Title}}</h3>
+{{range .Members}}{{template "member" .}}{{end}}
+{{- end}}
+{{- range .Types}}
+<h3>{{.Name}}</h3>
+<pre><code>{{.Signature}}</code></pre>
+{{if .Doc}}<p>{{.Doc}}</p>{{end}}
+<ul>
+{{- range .Members}}
+<li><code>{{.Signature}}</code>{{if .Doc}}: {{.Doc}}{{end}}</li>
+{{- end}}
+</ul>
+{{- end}}
+</section>
+{{end}}
+{{define "member"}}
+<div class="member">
+<h4>{{.Name}}</h4>
+<pre><code>{{.Signature}}</code></pre>
+{{if .Deprecated}}<p><strong>Deprecated.</strong></p>{{end}}
+{{if .Doc}}<p>{{.Doc}}</p>{{end}}
+</div>
+{{end}}`))
+
+// WriteMarkdown writes the documentation of pkg as a Markdown document.
+func WriteMarkdown(w io.Writer, pkg *Package, opts Options) error {
+	return markdown.Execute(w, newPage(pkg, opts))
+}
+
+// WriteHTML writes the documentation of pkg as a standalone HTML page.
+func WriteHTML(w io.Writer, pkg *Package, opts Options) error {
+	return html.Execute(w, newPage(pkg, opts))
+}
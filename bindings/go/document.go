@@ -0,0 +1,195 @@
+package tree_sitter_zscript
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/positions"
+)
+
+// PositionEncoding selects the unit in which TextPosition.Character counts
+// the characters of a line.
+type PositionEncoding = positions.Encoding
+
+const (
+	// EncodingUTF16 counts UTF-16 code units, the default of the Language
+	// Server Protocol.
+	EncodingUTF16 = positions.UTF16
+	// EncodingUTF8 counts bytes, as tree-sitter points do.
+	EncodingUTF8 = positions.UTF8
+	// EncodingUTF32 counts code points.
+	EncodingUTF32 = positions.UTF32
+)
+
+// TextPosition is a zero-based line and character, as in the Language
+// Server Protocol.
+type TextPosition = positions.Position
+
+// TextEdit replaces the text between Start and End with Text.
+type TextEdit struct {
+	Start, End TextPosition
+	Text       string
+}
+
+// IncrementalDocument is a source text that is kept parsed as it is
+// edited. Edits update the text and the existing tree immediately; Reparse
+// then parses the new text, reusing the parts of the tree that the edits
+// did not touch.
+//
+// An IncrementalDocument is not safe for concurrent use.
+type IncrementalDocument struct {
+	// Encoding is the unit of the characters in the positions passed to
+	// Apply and EditRange.
+	Encoding PositionEncoding
+
+	tree   *Tree
+	source []byte
+	index  *positions.Index
+	// stale is set when source has been edited since the last parse.
+	stale bool
+}
+
+// NewIncrementalDocument parses source and returns a document for it.
+func NewIncrementalDocument(ctx context.Context, source []byte) (*IncrementalDocument, error) {
+	tree, err := Parse(ctx, source)
+	if err != nil {
+		return nil, err
+	}
+	return &IncrementalDocument{tree: tree, source: source, index: positions.NewIndex(source)}, nil
+}
+
+// Source returns the current text, including edits not yet reparsed. The
+// returned slice is never modified by later edits.
+func (d *IncrementalDocument) Source() []byte {
+	return d.source
+}
+
+// Tree returns the tree of the last parse. The document owns the tree: it
+// stays valid until the next Reparse or Close, and its Source is the text
+// it was parsed from.
+func (d *IncrementalDocument) Tree() *Tree {
+	return d.tree
+}
+
+// Stale reports whether the document has been edited since it was last
+// parsed.
+func (d *IncrementalDocument) Stale() bool {
+	return d.stale
+}
+
+// Close releases the document's tree.
+func (d *IncrementalDocument) Close() {
+	if d.tree != nil {
+		d.tree.Close()
+		d.tree = nil
+	}
+}
+
+// EditBytes replaces source[start:end] with text.
+func (d *IncrementalDocument) EditBytes(start, end uint, text string) error {
+	if start > end || end > uint(len(d.source)) {
+		return fmt.Errorf("zscript: edit range [%d, %d) out of bounds for %d bytes", start, end, len(d.source))
+	}
+	startPoint, oldEndPoint := d.point(start), d.point(end)
+
+	// The new text is built in a fresh array so that slices returned by
+	// Source, and the source of the current tree, stay intact.
+	source := make([]byte, 0, uint(len(d.source))-(end-start)+uint(len(text)))
+	source = append(source, d.source[:start]...)
+	source = append(source, text...)
+	source = append(source, d.source[end:]...)
+
+	newEndPoint := startPoint
+	if i := bytes.LastIndexByte([]byte(text), '\n'); i >= 0 {
+		newEndPoint.Row += uint(bytes.Count([]byte(text), []byte{'\n'}))
+		newEndPoint.Column = uint(len(text) - i - 1)
+	} else {
+		newEndPoint.Column += uint(len(text))
+	}
+
+	d.tree.Edit(&tree_sitter.InputEdit{
+		StartByte:      start,
+		OldEndByte:     end,
+		NewEndByte:     start + uint(len(text)),
+		StartPosition:  startPoint,
+		OldEndPosition: oldEndPoint,
+		NewEndPosition: newEndPoint,
+	})
+	d.source = source
+	d.index = positions.NewIndex(source)
+	d.stale = true
+	return nil
+}
+
+// EditRange replaces the text between start and end, whose characters are
+// counted in d.Encoding, with text. Positions past the end of a line refer
+// to the end of the line; lines past the end of the text refer to the end
+// of the text.
+func (d *IncrementalDocument) EditRange(start, end TextPosition, text string) error {
+	return d.EditBytes(d.Offset(start), d.Offset(end), text)
+}
+
+// Apply applies edits in order and reparses the result. Each edit's
+// positions refer to the text produced by the edits before it, as in an
+// LSP didChange notification.
+func (d *IncrementalDocument) Apply(ctx context.Context, edits ...TextEdit) error {
+	for _, e := range edits {
+		if err := d.EditRange(e.Start, e.End, e.Text); err != nil {
+			return err
+		}
+	}
+	_, err := d.Reparse(ctx)
+	return err
+}
+
+// Replace replaces the whole text and reparses it from scratch.
+func (d *IncrementalDocument) Replace(ctx context.Context, source []byte) error {
+	tree, err := Parse(ctx, source)
+	if err != nil {
+		return err
+	}
+	d.Close()
+	d.tree, d.source, d.index, d.stale = tree, source, positions.NewIndex(source), false
+	return nil
+}
+
+// Reparse parses the edited text, reusing the unchanged parts of the
+// previous tree, and returns the ranges whose syntactic structure changed.
+// It does nothing if the document has not been edited. If parsing fails,
+// the document keeps its edited text and the previous tree, and Reparse
+// can be called again.
+func (d *IncrementalDocument) Reparse(ctx context.Context) ([]tree_sitter.Range, error) {
+	if !d.stale {
+		return nil, nil
+	}
+	tree, err := parse(ctx, d.source, d.tree.Tree)
+	if err != nil {
+		return nil, err
+	}
+	changed := d.tree.ChangedRanges(tree.Tree)
+	tree.Path = d.tree.Path
+	d.tree.Close()
+	d.tree = tree
+	d.stale = false
+	return changed, nil
+}
+
+// Offset returns the byte offset of pos, whose character is counted in
+// d.Encoding.
+func (d *IncrementalDocument) Offset(pos TextPosition) uint {
+	return d.index.Offset(pos, d.Encoding)
+}
+
+// Position returns the position of a byte offset, with the character
+// counted in d.Encoding.
+func (d *IncrementalDocument) Position(offset uint) TextPosition {
+	return d.index.Position(offset, d.Encoding)
+}
+
+// point converts a byte offset to a tree-sitter point.
+func (d *IncrementalDocument) point(offset uint) tree_sitter.Point {
+	return d.index.Point(offset)
+}
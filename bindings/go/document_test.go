@@ -0,0 +1,113 @@
+package tree_sitter_zscript_test
+
+import (
+	"context"
+	"math/rand"
+	"testing"
+
+	tree_sitter_zscript "github.com/jlcrochet/tree-sitter-zscript/bindings/go"
+)
+
+func checkDocument(t *testing.T, doc *tree_sitter_zscript.IncrementalDocument, want string) {
+	t.Helper()
+	if got := string(doc.Source()); got != want {
+		t.Fatalf("Source() = %q, want %q", got, want)
+	}
+	fresh, err := tree_sitter_zscript.Parse(context.Background(), doc.Source())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fresh.Close()
+	if got, want := doc.Tree().RootNode().ToSexp(), fresh.RootNode().ToSexp(); got != want {
+		t.Fatalf("incremental tree differs from a fresh parse:\ngot  %s\nwant %s", got, want)
+	}
+	if got := string(doc.Tree().Source); got != want {
+		t.Fatalf("Tree().Source = %q, want %q", got, want)
+	}
+}
+
+func TestIncrementalDocument(t *testing.T) {
+	ctx := context.Background()
+	doc, err := tree_sitter_zscript.NewIncrementalDocument(ctx, []byte("class A {\n\tString s; // \"héllo😀\"\n\tint x;\n}\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer doc.Close()
+
+	// In UTF-16, the emoji is two code units, so the line ends at character 23, byte 36.
+	if got := doc.Offset(tree_sitter_zscript.TextPosition{Line: 1, Character: 23}); got != 36 {
+		t.Errorf("Offset = %d, want 36", got)
+	}
+	if got := doc.Position(36); got != (tree_sitter_zscript.TextPosition{Line: 1, Character: 23}) {
+		t.Errorf("Position = %+v", got)
+	}
+
+	err = doc.Apply(ctx,
+		tree_sitter_zscript.TextEdit{
+			Start: tree_sitter_zscript.TextPosition{Line: 1, Character: 23},
+			End:   tree_sitter_zscript.TextPosition{Line: 1, Character: 23},
+			Text:  "\n\tdouble d;",
+		},
+		tree_sitter_zscript.TextEdit{
+			Start: tree_sitter_zscript.TextPosition{Line: 3, Character: 5},
+			End:   tree_sitter_zscript.TextPosition{Line: 3, Character: 6},
+			Text:  "y, z",
+		},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkDocument(t, doc, "class A {\n\tString s; // \"héllo😀\"\n\tdouble d;\n\tint y, z;\n}\n")
+
+	if stale := doc.Stale(); stale {
+		t.Error("Stale() after Apply")
+	}
+	if err := doc.EditBytes(0, 5, "struct"); err != nil {
+		t.Fatal(err)
+	}
+	if !doc.Stale() {
+		t.Error("Stale() = false after EditBytes")
+	}
+	changed, err := doc.Reparse(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changed) == 0 {
+		t.Error("Reparse reported no changed ranges")
+	}
+	checkDocument(t, doc, "struct A {\n\tString s; // \"héllo😀\"\n\tdouble d;\n\tint y, z;\n}\n")
+
+	if err := doc.EditBytes(10, 1000, ""); err == nil {
+		t.Error("EditBytes out of bounds succeeded")
+	}
+}
+
+func TestIncrementalDocumentRandomEdits(t *testing.T) {
+	ctx := context.Background()
+	source := "class Foo : Actor {\n\tint x;\n\tvoid Bar() { x = 1; }\n\tStates {\n\tSpawn:\n\t\tTNT1 A 1;\n\t\tLoop;\n\t}\n}\n"
+	doc, err := tree_sitter_zscript.NewIncrementalDocument(ctx, []byte(source))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer doc.Close()
+	doc.Encoding = tree_sitter_zscript.EncodingUTF8
+
+	fragments := []string{"", "x", ";", "{", "}", "\n", " int y;", "(", ")", "// c\n", "\"s\"", "État"}
+	rng := rand.New(rand.NewSource(1))
+	for i := 0; i < 200; i++ {
+		n := uint(len(source))
+		start := uint(rng.Intn(int(n) + 1))
+		end := start + uint(rng.Intn(int(n-start)+1)/4)
+		text := fragments[rng.Intn(len(fragments))]
+		if err := doc.EditBytes(start, end, text); err != nil {
+			t.Fatal(err)
+		}
+		source = source[:start] + text + source[end:]
+		if i%3 == 0 {
+			if _, err := doc.Reparse(ctx); err != nil {
+				t.Fatal(err)
+			}
+			checkDocument(t, doc, source)
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package tree_sitter_zscript
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+)
+
+// DumpFormat selects the output of Dump.
+type DumpFormat int
+
+const (
+	// DumpSexp is an indented S-expression with one named node per line:
+	//
+	//	(class_definition [0:0-2:1]
+	//	  name: (type_identifier [0:6-0:10] "Base"))
+	//
+	// Positions are zero-based rows and byte columns. Leaves show their
+	// text.
+	DumpSexp DumpFormat = iota
+	// DumpJSON is a single JSON object for the root node, with children
+	// nested in "children".
+	DumpJSON
+	// DumpNDJSON writes one JSON object per node in preorder, each with
+	// an "id" and the "parent" id (-1 for the root), for tools that
+	// stream records.
+	DumpNDJSON
+)
+
+// DumpNode is the JSON representation of a node written by Dump.
+type DumpNode struct {
+	ID        int        `json:"id"`
+	Parent    int        `json:"parent"`
+	Type      string     `json:"type"`
+	Field     string     `json:"field,omitempty"`
+	StartByte uint       `json:"start_byte"`
+	EndByte   uint       `json:"end_byte"`
+	Start     DumpPoint  `json:"start"`
+	End       DumpPoint  `json:"end"`
+	Text      string     `json:"text,omitempty"`
+	Error     bool       `json:"error,omitempty"`
+	Missing   bool       `json:"missing,omitempty"`
+	Children  []DumpNode `json:"children,omitempty"`
+}
+
+// DumpPoint is a zero-based row and byte column.
+type DumpPoint struct {
+	Row    uint `json:"row"`
+	Column uint `json:"column"`
+}
+
+// Dump serializes the named nodes of tree in the given format, with
+// their field names, byte offsets and positions. The text of leaf nodes
+// is included. The output ends with a newline.
+func Dump(tree *tree_sitter.Tree, source []byte, format DumpFormat) []byte {
+	var buf bytes.Buffer
+	root := tree.RootNode()
+	switch format {
+	case DumpJSON:
+		id := 0
+		node := dumpNode(root, "", -1, &id, source, true)
+		data, _ := json.Marshal(node)
+		buf.Write(data)
+		buf.WriteByte('\n')
+	case DumpNDJSON:
+		id := 0
+		var visit func(node *tree_sitter.Node, field string, parent int)
+		visit = func(node *tree_sitter.Node, field string, parent int) {
+			record := dumpNode(node, field, parent, &id, source, false)
+			data, _ := json.Marshal(record)
+			buf.Write(data)
+			buf.WriteByte('\n')
+			namedChildren(node, func(child *tree_sitter.Node, field string) {
+				visit(child, field, record.ID)
+			})
+		}
+		visit(root, "", -1)
+	default:
+		dumpSexp(&buf, root, "", 0, source)
+		buf.WriteByte('\n')
+	}
+	return buf.Bytes()
+}
+
+// dumpNode converts node, numbering it and its descendants in preorder
+// from *id. Children are converted only if nested is set.
+func dumpNode(node *tree_sitter.Node, field string, parent int, id *int, source []byte, nested bool) DumpNode {
+	start, end := node.StartPosition(), node.EndPosition()
+	d := DumpNode{
+		ID:        *id,
+		Parent:    parent,
+		Type:      node.Kind(),
+		Field:     field,
+		StartByte: node.StartByte(),
+		EndByte:   node.EndByte(),
+		Start:     DumpPoint{Row: start.Row, Column: start.Column},
+		End:       DumpPoint{Row: end.Row, Column: end.Column},
+		Error:     node.IsError(),
+		Missing:   node.IsMissing(),
+	}
+	*id++
+	if node.NamedChildCount() == 0 {
+		d.Text = node.Utf8Text(source)
+	}
+	if nested {
+		namedChildren(node, func(child *tree_sitter.Node, field string) {
+			d.Children = append(d.Children, dumpNode(child, field, d.ID, id, source, true))
+		})
+	}
+	return d
+}
+
+func dumpSexp(buf *bytes.Buffer, node *tree_sitter.Node, field string, depth int, source []byte) {
+	if depth > 0 {
+		buf.WriteByte('\n')
+	}
+	buf.WriteString(strings.Repeat("  ", depth))
+	if field != "" {
+		buf.WriteString(field)
+		buf.WriteString(": ")
+	}
+	start, end := node.StartPosition(), node.EndPosition()
+	kind := node.Kind()
+	if node.IsMissing() {
+		kind = "MISSING " + kind
+	}
+	fmt.Fprintf(buf, "(%s [%d:%d-%d:%d]", kind, start.Row, start.Column, end.Row, end.Column)
+	if node.NamedChildCount() == 0 && !node.IsMissing() {
+		buf.WriteByte(' ')
+		buf.WriteString(strconv.Quote(node.Utf8Text(source)))
+	}
+	namedChildren(node, func(child *tree_sitter.Node, field string) {
+		dumpSexp(buf, child, field, depth+1, source)
+	})
+	buf.WriteByte(')')
+}
+
+// namedChildren calls fn for each named child of node with its field
+// name, or "".
+func namedChildren(node *tree_sitter.Node, fn func(child *tree_sitter.Node, field string)) {
+	count := node.ChildCount()
+	for i := uint(0); i < count; i++ {
+		child := node.Child(i)
+		if child.IsNamed() {
+			fn(child, node.FieldNameForChild(uint32(i)))
+		}
+	}
+}
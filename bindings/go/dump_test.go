@@ -0,0 +1,74 @@
+package tree_sitter_zscript_test
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+
+	tree_sitter_zscript "github.com/jlcrochet/tree-sitter-zscript/bindings/go"
+)
+
+const dumpSource = "class A { int x; }"
+
+func dump(t *testing.T, format tree_sitter_zscript.DumpFormat) []byte {
+	t.Helper()
+	tree, err := tree_sitter_zscript.Parse(context.Background(), []byte(dumpSource))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tree.Close()
+	return tree_sitter_zscript.Dump(tree.Tree, tree.Source, format)
+}
+
+func TestDumpSexp(t *testing.T) {
+	want := `(source_file [0:0-0:18]
+  (class_definition [0:0-0:18]
+    name: (type_identifier [0:6-0:7] "A")
+    (field_declaration [0:10-0:16]
+      type: (primitive_type [0:10-0:13] "int")
+      declarator: (identifier [0:14-0:15] "x"))))
+`
+	if got := string(dump(t, tree_sitter_zscript.DumpSexp)); got != want {
+		t.Errorf("Dump() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestDumpJSON(t *testing.T) {
+	var root tree_sitter_zscript.DumpNode
+	if err := json.Unmarshal(dump(t, tree_sitter_zscript.DumpJSON), &root); err != nil {
+		t.Fatal(err)
+	}
+	class := root.Children[0]
+	name := class.Children[0]
+	if root.Type != "source_file" || class.Type != "class_definition" || name.Field != "name" || name.Text != "A" {
+		t.Fatalf("Dump() = %+v", root)
+	}
+	if name.ID != 2 || name.Parent != 1 || name.StartByte != 6 || name.End != (tree_sitter_zscript.DumpPoint{Row: 0, Column: 7}) {
+		t.Errorf("name = %+v", name)
+	}
+}
+
+func TestDumpNDJSON(t *testing.T) {
+	var nodes []tree_sitter_zscript.DumpNode
+	scanner := bufio.NewScanner(bytes.NewReader(dump(t, tree_sitter_zscript.DumpNDJSON)))
+	for scanner.Scan() {
+		var n tree_sitter_zscript.DumpNode
+		if err := json.Unmarshal(scanner.Bytes(), &n); err != nil {
+			t.Fatal(err)
+		}
+		nodes = append(nodes, n)
+	}
+	if len(nodes) != 6 {
+		t.Fatalf("got %d records, want 6", len(nodes))
+	}
+	for i, n := range nodes {
+		if n.ID != i || len(n.Children) != 0 {
+			t.Errorf("record %d = %+v", i, n)
+		}
+	}
+	if x := nodes[5]; x.Type != "identifier" || x.Field != "declarator" || x.Parent != 3 || x.Text != "x" {
+		t.Errorf("x = %+v", x)
+	}
+}
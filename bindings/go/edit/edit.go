@@ -0,0 +1,347 @@
+// Package edit computes the text edits of common changes to class
+// declarations, such as adding a method, a Default property or a flag, for
+// tools that generate or update code in existing files.
+//
+// Each change is a few insertions or replacements that leave the rest of
+// the file as it was written. New code is indented to match the code
+// around it and uses the file's line endings.
+package edit
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+
+	zscript "github.com/jlcrochet/tree-sitter-zscript/bindings/go"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/zscriptast"
+)
+
+// Edit replaces a range of a file with new text. An empty range inserts
+// the text.
+type Edit struct {
+	Range   tree_sitter.Range
+	NewText string
+}
+
+// Apply returns src with edits applied. Edits must not overlap; edits at
+// the same offset are applied in the order given.
+func Apply(src []byte, edits []Edit) []byte {
+	sorted := append([]Edit(nil), edits...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Range.StartByte < sorted[j].Range.StartByte })
+	var out []byte
+	last := uint(0)
+	for _, e := range sorted {
+		out = append(out, src[last:e.Range.StartByte]...)
+		out = append(out, e.NewText...)
+		last = e.Range.EndByte
+	}
+	return append(out, src[last:]...)
+}
+
+// InsertMethod returns the edit inserting method, the text of a method
+// definition, into the class named class. It is placed after the class's
+// last method, or at the end of the class if it has none, and indented as
+// the class's members are.
+func InsertMethod(tree *zscript.Tree, class, method string) ([]Edit, error) {
+	c, err := findClass(tree, class)
+	if err != nil {
+		return nil, err
+	}
+	open, close := braces(c.Node)
+	if open == nil || close == nil {
+		return nil, fmt.Errorf("edit: class %s has no body", class)
+	}
+	s := newStyle(tree.Source)
+	indent := s.memberIndent(c.Raw, open)
+
+	var after *tree_sitter.Node
+	if methods := c.Methods(); len(methods) > 0 {
+		after = methods[len(methods)-1].Raw
+	}
+	if after == nil {
+		if members := c.NamedChildren(); len(members) > 0 {
+			if last := members[len(members)-1].Raw; last.StartByte() > open.StartByte() {
+				after = last
+			}
+		}
+	}
+	text := s.block(method, indent)
+	if after == nil {
+		return []Edit{s.insertBefore(close, text)}, nil
+	}
+	return []Edit{s.insert(after.EndByte(), s.newline+s.newline+strings.TrimSuffix(text, s.newline))}, nil
+}
+
+// AddProperty returns the edits that set the Default property name of the
+// class named class to values. A property that is already set is given
+// the new values; otherwise the property is added after the last one in
+// the Default block, which is created at the start of the class if it has
+// none, followed by a blank line.
+func AddProperty(tree *zscript.Tree, class, name string, values ...string) ([]Edit, error) {
+	line := func(name string) string {
+		if len(values) > 0 {
+			name += " " + strings.Join(values, ", ")
+		}
+		return name + ";"
+	}
+	return addDefault(tree, class, line(name), func(d zscriptast.DefaultBlock) ([]Edit, bool) {
+		for _, p := range d.Properties() {
+			if !strings.EqualFold(p.Name(), name) {
+				continue
+			}
+			// Keep the spelling of the name as it was written.
+			return []Edit{{Range: p.Range(), NewText: line(p.Name())}}, true
+		}
+		return nil, false
+	})
+}
+
+// AddFlag returns the edits that set, or with set false clear, the flag
+// named flag in the Default block of the class named class. A flag that is
+// already set or cleared is given the new sign; it returns no edits if the
+// sign is already right. A missing Default block is created as by
+// AddProperty.
+func AddFlag(tree *zscript.Tree, class, flag string, set bool) ([]Edit, error) {
+	sign := "-"
+	if set {
+		sign = "+"
+	}
+	return addDefault(tree, class, sign+flag+";", func(d zscriptast.DefaultBlock) ([]Edit, bool) {
+		for _, f := range d.Flags() {
+			if !strings.EqualFold(f.Name(), flag) {
+				continue
+			}
+			if f.Set() == set {
+				return nil, true
+			}
+			return []Edit{{Range: f.Raw.ChildByFieldName(zscript.FieldSign).Range(), NewText: sign}}, true
+		}
+		return nil, false
+	})
+}
+
+// addDefault changes the Default block of class with update, or adds line
+// to it if update does not find what to change.
+func addDefault(tree *zscript.Tree, class, line string, update func(zscriptast.DefaultBlock) ([]Edit, bool)) ([]Edit, error) {
+	c, err := findClass(tree, class)
+	if err != nil {
+		return nil, err
+	}
+	open, close := braces(c.Node)
+	if open == nil || close == nil {
+		return nil, fmt.Errorf("edit: class %s has no body", class)
+	}
+	s := newStyle(tree.Source)
+	indent := s.memberIndent(c.Raw, open)
+
+	defaults := c.Defaults()
+	for _, d := range defaults {
+		if edits, ok := update(d); ok {
+			return edits, nil
+		}
+	}
+	if len(defaults) > 0 {
+		d := defaults[len(defaults)-1]
+		items := d.ChildrenOfKind(zscript.NodeDefaultProperty)
+		if len(items) > 0 {
+			last := items[len(items)-1].Raw
+			return []Edit{s.insert(last.EndByte(), s.newline+s.indentOf(last.StartByte())+line)}, nil
+		}
+		dOpen, dClose := braces(d.Node)
+		if dOpen == nil || dClose == nil {
+			return nil, fmt.Errorf("edit: Default block of %s has no body", class)
+		}
+		return []Edit{s.insertBefore(dClose, s.block(line, s.memberIndent(d.Raw, dOpen)))}, nil
+	}
+
+	block := s.block("Default\n{\n"+s.unit+line+"\n}", indent)
+	members := c.NamedChildren()
+	for _, m := range members {
+		if m.Raw.StartByte() > open.StartByte() {
+			return []Edit{s.insert(s.lineStart(m.Raw.StartByte()), block+s.newline)}, nil
+		}
+	}
+	return []Edit{s.insertBefore(close, block)}, nil
+}
+
+// WrapInClass returns the edits that wrap the lines from the one where
+// first starts to the one where last ends in a new class named name, with
+// parent as its parent class if it is not empty. The wrapped lines are
+// indented one level further.
+func WrapInClass(tree *zscript.Tree, first, last *tree_sitter.Node, name, parent string) []Edit {
+	s := newStyle(tree.Source)
+	start := s.lineStart(first.StartByte())
+	indent := s.indentOf(first.StartByte())
+	header := indent + "class " + name
+	if parent != "" {
+		header += " : " + parent
+	}
+	header += s.newline + indent + "{" + s.newline
+
+	edits := []Edit{s.insert(start, header)}
+	src := tree.Source
+	for offset := start; offset < last.EndByte(); {
+		if offset < uint(len(src)) && src[offset] != '\n' && src[offset] != '\r' {
+			edits = append(edits, s.insert(offset, s.unit))
+		}
+		next := bytes.IndexByte(src[offset:], '\n')
+		if next < 0 {
+			break
+		}
+		offset += uint(next) + 1
+	}
+	edits = append(edits, s.insert(last.EndByte(), s.newline+indent+"}"))
+	return edits
+}
+
+// findClass returns the definition of the class named name, preferring
+// one that is not an extension.
+func findClass(tree *zscript.Tree, name string) (zscriptast.ClassDecl, error) {
+	var found zscriptast.ClassDecl
+	for _, c := range zscriptast.NewFile(tree.Tree, tree.Source).Classes() {
+		if strings.EqualFold(c.Name(), name) && (found.IsZero() || found.IsExtend() && !c.IsExtend()) {
+			found = c
+		}
+	}
+	if found.IsZero() {
+		return found, fmt.Errorf("edit: no class named %s", name)
+	}
+	return found, nil
+}
+
+// braces returns the braces around the body of n.
+func braces(n zscriptast.Node) (open, close *tree_sitter.Node) {
+	for i := uint(0); i < n.Raw.ChildCount(); i++ {
+		c := n.Raw.Child(i)
+		switch {
+		case c.Kind() == "{" && open == nil:
+			open = c
+		case c.Kind() == "}" && !c.IsMissing():
+			close = c
+		}
+	}
+	return open, close
+}
+
+// style is the layout of a file that new code follows.
+type style struct {
+	src []byte
+	// newline is "\r\n" if the file uses it and "\n" otherwise.
+	newline string
+	// unit is one level of indentation: a tab, or the spaces of the first
+	// line indented with spaces if no line is indented with tabs.
+	unit string
+}
+
+func newStyle(src []byte) *style {
+	s := &style{src: src, newline: "\n", unit: "\t"}
+	if bytes.Contains(src, []byte("\r\n")) {
+		s.newline = "\r\n"
+	}
+	spaces := ""
+	for _, line := range bytes.Split(src, []byte("\n")) {
+		if bytes.HasPrefix(line, []byte("\t")) {
+			return s
+		}
+		if spaces == "" && bytes.HasPrefix(line, []byte(" ")) {
+			trimmed := bytes.TrimLeft(line, " ")
+			if len(bytes.TrimSpace(trimmed)) > 0 {
+				spaces = string(line[:len(line)-len(trimmed)])
+			}
+		}
+	}
+	if spaces != "" {
+		s.unit = spaces
+	}
+	return s
+}
+
+// lineStart returns the offset of the start of the line holding offset.
+func (s *style) lineStart(offset uint) uint {
+	return uint(bytes.LastIndexByte(s.src[:offset], '\n') + 1)
+}
+
+// indentOf returns the leading white space of the line holding offset.
+func (s *style) indentOf(offset uint) string {
+	start := s.lineStart(offset)
+	end := start
+	for end < uint(len(s.src)) && (s.src[end] == ' ' || s.src[end] == '\t') {
+		end++
+	}
+	return string(s.src[start:end])
+}
+
+// memberIndent returns the indentation of the members of n, whose body
+// opens with the brace open: that of its first member on a line of its
+// own, or one level more than n's.
+func (s *style) memberIndent(n, open *tree_sitter.Node) string {
+	for c := open.NextSibling(); c != nil; c = c.NextSibling() {
+		if c.Kind() == "}" {
+			break
+		}
+		if s.lineStart(c.StartByte()) > open.StartByte() && strings.TrimSpace(string(s.src[s.lineStart(c.StartByte()):c.StartByte()])) == "" {
+			return s.indentOf(c.StartByte())
+		}
+	}
+	return s.indentOf(n.StartByte()) + s.unit
+}
+
+// block returns text reindented to indent, with its blank first and last
+// lines and the common indentation of its lines removed and the file's
+// line endings, ending in a newline. Tabs that indent text further are
+// replaced by the file's unit of indentation.
+func (s *style) block(text, indent string) string {
+	lines := strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+	for len(lines) > 0 && strings.TrimSpace(lines[0]) == "" {
+		lines = lines[1:]
+	}
+	for len(lines) > 0 && strings.TrimSpace(lines[len(lines)-1]) == "" {
+		lines = lines[:len(lines)-1]
+	}
+	common, first := "", true
+	for _, l := range lines {
+		if strings.TrimSpace(l) == "" {
+			continue
+		}
+		lead := l[:len(l)-len(strings.TrimLeft(l, " \t"))]
+		if first {
+			common, first = lead, false
+		}
+		for !strings.HasPrefix(lead, common) {
+			common = common[:len(common)-1]
+		}
+	}
+	var b strings.Builder
+	for _, l := range lines {
+		if strings.TrimSpace(l) != "" {
+			b.WriteString(indent)
+			l = strings.TrimPrefix(l, common)
+			tabs := len(l) - len(strings.TrimLeft(l, "\t"))
+			b.WriteString(strings.Repeat(s.unit, tabs) + l[tabs:])
+		}
+		b.WriteString(s.newline)
+	}
+	return b.String()
+}
+
+// insertBefore returns the edit inserting text, whole lines, before the
+// closing brace close. If the brace shares its line with code, it is moved
+// to a line of its own.
+func (s *style) insertBefore(close *tree_sitter.Node, text string) Edit {
+	start := s.lineStart(close.StartByte())
+	if strings.TrimSpace(string(s.src[start:close.StartByte()])) != "" {
+		// The brace ends a line of code, as in "class A {}".
+		return s.insert(close.StartByte(), s.newline+text+s.indentOf(close.StartByte()))
+	}
+	return s.insert(start, text)
+}
+
+// insert returns the edit inserting text at offset.
+func (s *style) insert(offset uint, text string) Edit {
+	row := uint(bytes.Count(s.src[:offset], []byte("\n")))
+	p := tree_sitter.Point{Row: row, Column: offset - s.lineStart(offset)}
+	return Edit{Range: tree_sitter.Range{StartByte: offset, EndByte: offset, StartPoint: p, EndPoint: p}, NewText: text}
+}
@@ -0,0 +1,183 @@
+package edit_test
+
+import (
+	"context"
+	"testing"
+
+	zscript "github.com/jlcrochet/tree-sitter-zscript/bindings/go"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/edit"
+)
+
+func parse(t *testing.T, src string) *zscript.Tree {
+	t.Helper()
+	tree, err := zscript.Parse(context.Background(), []byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(tree.Close)
+	return tree
+}
+
+// apply checks that the edits of change leave a file that parses without
+// errors and equals want.
+func apply(t *testing.T, src, want string, change func(*zscript.Tree) ([]edit.Edit, error)) {
+	t.Helper()
+	tree := parse(t, src)
+	edits, err := change(tree)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := string(edit.Apply(tree.Source, edits))
+	if got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+	if parse(t, got).RootNode().HasError() {
+		t.Errorf("result does not parse:\n%s", got)
+	}
+}
+
+const imp = `class Imp : Actor
+{
+	Default
+	{
+		Health 60;
+		+FLOAT;
+	}
+
+	void Fire()
+	{
+		A_Scream();
+	}
+}
+`
+
+func TestInsertMethod(t *testing.T) {
+	method := `
+		void Burn()
+		{
+			A_Pain();
+		}
+	`
+	apply(t, imp, `class Imp : Actor
+{
+	Default
+	{
+		Health 60;
+		+FLOAT;
+	}
+
+	void Fire()
+	{
+		A_Scream();
+	}
+
+	void Burn()
+	{
+		A_Pain();
+	}
+}
+`, func(tree *zscript.Tree) ([]edit.Edit, error) { return edit.InsertMethod(tree, "imp", method) })
+
+	apply(t, "class A {}\n", "class A {\n\tvoid F() {}\n}\n", func(tree *zscript.Tree) ([]edit.Edit, error) {
+		return edit.InsertMethod(tree, "A", "void F() {}")
+	})
+
+	if _, err := edit.InsertMethod(parse(t, imp), "Baron", "void F() {}"); err == nil {
+		t.Error("InsertMethod found a missing class")
+	}
+}
+
+func TestAddProperty(t *testing.T) {
+	apply(t, imp, `class Imp : Actor
+{
+	Default
+	{
+		Health 80;
+		+FLOAT;
+	}
+
+	void Fire()
+	{
+		A_Scream();
+	}
+}
+`, func(tree *zscript.Tree) ([]edit.Edit, error) { return edit.AddProperty(tree, "Imp", "health", "80") })
+
+	apply(t, imp, `class Imp : Actor
+{
+	Default
+	{
+		Health 60;
+		+FLOAT;
+		Scale 0.5, 0.5;
+	}
+
+	void Fire()
+	{
+		A_Scream();
+	}
+}
+`, func(tree *zscript.Tree) ([]edit.Edit, error) {
+		return edit.AddProperty(tree, "Imp", "Scale", "0.5", "0.5")
+	})
+
+	apply(t, "class A : Actor\n{\n  int x;\n}\n", "class A : Actor\n{\n  Default\n  {\n    Radius 20;\n  }\n\n  int x;\n}\n", func(tree *zscript.Tree) ([]edit.Edit, error) {
+		return edit.AddProperty(tree, "A", "Radius", "20")
+	})
+}
+
+func TestAddFlag(t *testing.T) {
+	flip := func(flag string, set bool) func(*zscript.Tree) ([]edit.Edit, error) {
+		return func(tree *zscript.Tree) ([]edit.Edit, error) { return edit.AddFlag(tree, "Imp", flag, set) }
+	}
+	apply(t, imp, imp, flip("float", true))
+
+	want := `class Imp : Actor
+{
+	Default
+	{
+		Health 60;
+		-FLOAT;
+	}
+
+	void Fire()
+	{
+		A_Scream();
+	}
+}
+`
+	apply(t, imp, want, flip("FLOAT", false))
+
+	want = `class Imp : Actor
+{
+	Default
+	{
+		Health 60;
+		+FLOAT;
+		+NOGRAVITY;
+	}
+
+	void Fire()
+	{
+		A_Scream();
+	}
+}
+`
+	apply(t, imp, want, flip("NOGRAVITY", true))
+
+	apply(t, "class A : Actor\n{\n\tDefault\n\t{\n\t}\n}\n", "class A : Actor\n{\n\tDefault\n\t{\n\t\t+SOLID;\n\t}\n}\n", func(tree *zscript.Tree) ([]edit.Edit, error) {
+		return edit.AddFlag(tree, "A", "SOLID", true)
+	})
+}
+
+func TestWrapInClass(t *testing.T) {
+	src := "version \"4.10\"\nint F()\n{\n\treturn 1;\n}\n\nvoid G() {}\n"
+	want := "version \"4.10\"\nclass Util\n{\n\tint F()\n\t{\n\t\treturn 1;\n\t}\n\n\tvoid G() {}\n}\n"
+	tree := parse(t, src)
+	root := tree.RootNode()
+	first, last := root.NamedChild(1), root.NamedChild(root.NamedChildCount()-1)
+	got := string(edit.Apply(tree.Source, edit.WrapInClass(tree, first, last, "Util", "")))
+	if got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+}
@@ -0,0 +1,87 @@
+package engine
+
+import (
+	_ "embed"
+	"strings"
+	"sync"
+
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/hierarchy"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/symbols"
+)
+
+//go:embed classes.txt
+var classList string
+
+// builtin is a class of gzdoom.pk3: its name as the engine spells it and
+// that of its parent.
+type builtin struct{ name, parent string }
+
+// gameClasses are the classes of classes.txt, by name, with their
+// parents.
+var gameClasses = sync.OnceValue(func() map[string]string {
+	m := map[string]string{}
+	for _, line := range strings.Split(classList, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 2 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		m[fields[0]] = fields[1]
+	}
+	return m
+})
+
+var classes = sync.OnceValue(func() map[string]builtin {
+	m := map[string]builtin{}
+	for _, c := range table().Classes {
+		m[strings.ToLower(c.Name)] = builtin{c.Name, c.Parent}
+	}
+	for name, parent := range gameClasses() {
+		m[strings.ToLower(name)] = builtin{name, parent}
+	}
+	return m
+})
+
+// GameClasses returns the actors of the games the engine plays, which
+// Table does not declare, by name as the engine spells it, with the name
+// of their parents. It is shared; callers must not modify it.
+func GameClasses() map[string]string {
+	return gameClasses()
+}
+
+// Hierarchy returns the hierarchy of the classes of tables and of the
+// engine: those Table declares, after the tables so that a file may
+// define a class of the same name in their place, and the game classes,
+// by parent only, so that a class deriving from DoomImp inherits from
+// Actor.
+func Hierarchy(tables ...*symbols.Table) *hierarchy.Hierarchy {
+	h := hierarchy.Build(append(tables[:len(tables):len(tables)], table())...)
+	h.AddParents(gameClasses())
+	return h
+}
+
+// Class reports whether gzdoom.pk3 defines a class with the name, matched
+// without regard to case, and returns its name as the engine spells it
+// and the name of its parent, "" for Object. Besides the classes Table
+// declares, the engine defines the actors of the games it plays, such as
+// DoomImp, Clip and PowerInvulnerable, which are known by name and parent
+// only.
+func Class(name string) (canonical, parent string, ok bool) {
+	c, ok := classes()[strings.ToLower(name)]
+	return c.name, c.parent, ok
+}
+
+// IsSubclassOf reports whether the engine class named class is ancestor or
+// inherits from it.
+func IsSubclassOf(class, ancestor string) bool {
+	for class != "" {
+		if strings.EqualFold(class, ancestor) {
+			return true
+		}
+		_, parent, ok := Class(class)
+		if !ok {
+			return false
+		}
+		class = parent
+	}
+	return false
+}
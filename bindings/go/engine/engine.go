@@ -0,0 +1,130 @@
+// Package engine declares GZDoom's built-in classes and structs, such as
+// Actor, Inventory and EventHandler, so that tools can resolve the members
+// a project inherits from the engine without reading gzdoom.pk3.
+//
+// The declarations are a summary of the engine's own ZScript sources that
+// internal/genstubs writes to stubs.zs: fields and method signatures,
+// without bodies, Default blocks or States. Members the engine added after
+// version 2.3 carry the version("x") qualifier they have there.
+package engine
+
+//go:generate go run ../internal/genstubs -i $GZDOOM_ZSCRIPT -o stubs.zs -v 4.12
+
+import (
+	"context"
+	_ "embed"
+	"strings"
+	"sync"
+
+	zscript "github.com/jlcrochet/tree-sitter-zscript/bindings/go"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/symbols"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/version"
+)
+
+//go:embed stubs.zs
+var source []byte
+
+// Path is the path the declarations are recorded under, in place of a
+// file of the project.
+const Path = "<gzdoom>"
+
+// Version is the GZDoom version the declarations describe.
+var Version = version.Version{Major: 4, Minor: 12}
+
+// Source returns the ZScript source of the declarations.
+func Source() []byte {
+	return source
+}
+
+var tree = sync.OnceValue(func() *zscript.Tree {
+	tree, err := zscript.Parse(context.Background(), source)
+	if err != nil {
+		panic("engine: " + err.Error())
+	}
+	tree.Path = Path
+	return tree
+})
+
+// Tree returns the parsed source of the declarations, with the path Path,
+// for looking at their syntax. It is parsed once and kept for the life of
+// the program; callers must not close or edit it.
+func Tree() *zscript.Tree {
+	return tree()
+}
+
+var table = sync.OnceValue(func() *symbols.Table {
+	return symbols.Extract(tree())
+})
+
+// Table returns the symbol table of the declarations. It is shared;
+// callers must not modify it.
+func Table() *symbols.Table {
+	return table()
+}
+
+// TableFor returns the declarations a project written for version v can
+// use: those without a version("x") qualifier newer than v.
+func TableFor(v version.Version) *symbols.Table {
+	full := table()
+	t := *full
+	t.Classes, t.Structs = nil, nil
+	for _, c := range full.Classes {
+		if !available(c.Version, v) {
+			continue
+		}
+		class := *c
+		class.Fields = fields(c.Fields, v)
+		class.Methods = methods(c.Methods, v)
+		t.Classes = append(t.Classes, &class)
+	}
+	for _, s := range full.Structs {
+		if !available(s.Version, v) {
+			continue
+		}
+		st := *s
+		st.Fields = fields(s.Fields, v)
+		st.Methods = methods(s.Methods, v)
+		t.Structs = append(t.Structs, &st)
+	}
+	return &t
+}
+
+func fields(all []*symbols.Field, v version.Version) []*symbols.Field {
+	var kept []*symbols.Field
+	for _, f := range all {
+		if available(since(f.Modifiers), v) {
+			kept = append(kept, f)
+		}
+	}
+	return kept
+}
+
+func methods(all []*symbols.Method, v version.Version) []*symbols.Method {
+	var kept []*symbols.Method
+	for _, m := range all {
+		if available(since(m.Modifiers), v) {
+			kept = append(kept, m)
+		}
+	}
+	return kept
+}
+
+// since returns the version in a version("x") modifier, or "".
+func since(modifiers []string) string {
+	for _, m := range modifiers {
+		if inner, ok := strings.CutPrefix(m, "version("); ok {
+			return strings.Trim(strings.TrimSuffix(inner, ")"), `" `)
+		}
+	}
+	return ""
+}
+
+// available reports whether a declaration qualified with version s, which
+// may be "", exists at version v.
+func available(s string, v version.Version) bool {
+	if s == "" {
+		return true
+	}
+	want, err := version.Parse(s)
+	return err != nil || want.Compare(v) <= 0
+}
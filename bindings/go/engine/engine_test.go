@@ -0,0 +1,82 @@
+package engine_test
+
+import (
+	"context"
+	"testing"
+
+	zscript "github.com/jlcrochet/tree-sitter-zscript/bindings/go"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/engine"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/hierarchy"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/symbols"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/version"
+)
+
+func TestSource(t *testing.T) {
+	tree := engine.Tree()
+	if tree.Path != engine.Path || string(tree.Source) != string(engine.Source()) {
+		t.Fatalf("Tree() has the path %q and %d bytes of source", tree.Path, len(tree.Source))
+	}
+	for _, d := range zscript.Diagnostics(tree.Tree, tree.Source) {
+		t.Errorf("stubs.zs:%d:%d: %s", d.Range.StartPoint.Row+1, d.Range.StartPoint.Column+1, d.Message)
+	}
+	if v, ok := version.Of(tree); !ok || v != engine.Version {
+		t.Errorf("stubs.zs declares version %v, want %v", v, engine.Version)
+	}
+}
+
+func TestTable(t *testing.T) {
+	table := engine.Table()
+	if table.Path != engine.Path || table.HasErrors {
+		t.Fatalf("Table() = %s, HasErrors %v", table.Path, table.HasErrors)
+	}
+	h := hierarchy.Build(table)
+	for _, c := range []string{"Weapon", "PlayerPawn", "Ammo", "Health"} {
+		if !h.IsSubclassOf(c, "Actor") {
+			t.Errorf("%s does not inherit from Actor", c)
+		}
+	}
+	if !h.IsSubclassOf("EventHandler", "StaticEventHandler") {
+		t.Error("EventHandler does not inherit from StaticEventHandler")
+	}
+	actor := table.Class("Actor")
+	if actor == nil || actor.Method("BeginPlay") == nil || actor.Field("Health") == nil {
+		t.Fatalf("Actor = %+v", actor)
+	}
+}
+
+func TestTableFor(t *testing.T) {
+	old := engine.TableFor(version.MustParse("2.3"))
+	if old.Class("EventHandler") != nil || old.Struct("WorldEvent") != nil {
+		t.Error("event handlers are declared for 2.3")
+	}
+	if actor := old.Class("Actor"); actor == nil || actor.Method("A_StartSound") != nil || actor.Method("A_PlaySound") == nil {
+		t.Errorf("Actor for 2.3 = %+v", actor)
+	}
+	if engine.Table().Class("Actor").Method("A_StartSound") == nil {
+		t.Error("TableFor modified the shared table")
+	}
+	if h := engine.TableFor(engine.Version).Class("StaticEventHandler"); h == nil || h.Method("WorldHitscanFired") == nil {
+		t.Errorf("StaticEventHandler for %v = %+v", engine.Version, h)
+	}
+}
+
+func TestHierarchy(t *testing.T) {
+	if parent := engine.GameClasses()["DoomImp"]; parent != "Actor" {
+		t.Errorf("parent of DoomImp = %q, want Actor", parent)
+	}
+	tree, err := zscript.Parse(context.Background(), []byte("class Gun : Pistol {}"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tree.Close()
+	h := engine.Hierarchy(symbols.Extract(tree))
+	if !h.IsSubclassOf("Gun", "Weapon") {
+		t.Error("Gun does not inherit from Weapon")
+	}
+	if c := h.Class("Pistol"); c == nil || c.Defined() {
+		t.Errorf("Pistol = %+v, want an undefined ancestor", c)
+	}
+	if h.Class("DoomImp") != nil {
+		t.Error("DoomImp is in a hierarchy no class of which derives from it")
+	}
+}
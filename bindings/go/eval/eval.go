@@ -0,0 +1,606 @@
+// Package eval folds constant expressions: arithmetic and comparisons on
+// literals, and the values of enumerators and const declarations, so that
+// tools can compute the actual values of damage formulas, flag
+// combinations and array sizes.
+//
+// Values follow ZScript's rules: int arithmetic wraps at 32 bits, an
+// operation with a double operand is done in double, and integer division
+// truncates toward zero.
+package eval
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+
+	zscript "github.com/jlcrochet/tree-sitter-zscript/bindings/go"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/engine"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/project"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/resolve"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/symbols"
+)
+
+// ErrNotConstant is wrapped by the errors of expressions that cannot be
+// folded, such as calls or references to variables.
+var ErrNotConstant = errors.New("eval: not a constant expression")
+
+// Kind is the type of a Value.
+type Kind int
+
+// The kinds of value.
+const (
+	Int Kind = iota + 1
+	Float
+	Bool
+	String
+	Name
+)
+
+// String returns the name of the ZScript type of values of kind k.
+func (k Kind) String() string {
+	switch k {
+	case Int:
+		return "int"
+	case Float:
+		return "double"
+	case Bool:
+		return "bool"
+	case String:
+		return "string"
+	case Name:
+		return "name"
+	}
+	return "invalid"
+}
+
+// Value is the value of a constant expression.
+type Value struct {
+	Kind Kind
+	// Int holds an Int.
+	Int int32
+	// Float holds a Float.
+	Float float64
+	// Bool holds a Bool.
+	Bool bool
+	// Text holds a String or a Name.
+	Text string
+}
+
+// IntValue returns an Int holding n.
+func IntValue(n int32) Value { return Value{Kind: Int, Int: n} }
+
+// FloatValue returns a Float holding f.
+func FloatValue(f float64) Value { return Value{Kind: Float, Float: f} }
+
+// BoolValue returns a Bool holding b.
+func BoolValue(b bool) Value { return Value{Kind: Bool, Bool: b} }
+
+// StringValue returns a String holding s.
+func StringValue(s string) Value { return Value{Kind: String, Text: s} }
+
+// Number returns v as a double, and whether it is a number.
+func (v Value) Number() (float64, bool) {
+	switch v.Kind {
+	case Int:
+		return float64(v.Int), true
+	case Float:
+		return v.Float, true
+	}
+	return 0, false
+}
+
+// String formats v as ZScript would concatenate it with "..": numbers in
+// decimal, booleans as 1 or 0, and strings and names as they are.
+func (v Value) String() string {
+	switch v.Kind {
+	case Int:
+		return strconv.Itoa(int(v.Int))
+	case Float:
+		return strconv.FormatFloat(v.Float, 'f', -1, 64)
+	case Bool:
+		if v.Bool {
+			return "1"
+		}
+		return "0"
+	}
+	return v.Text
+}
+
+// Evaluator folds expressions, looking up the constants they name.
+type Evaluator struct {
+	r     *resolve.Resolver
+	trees func(path string) *zscript.Tree
+	// active holds the constants being evaluated, to catch cycles.
+	active map[key]bool
+}
+
+type key struct {
+	path  string
+	start uint
+}
+
+// New returns an evaluator that resolves names with r. trees returns the
+// parsed file at a path declarations record, or nil if it is not
+// available, in which case the constants it declares cannot be folded.
+// The engine's constants are always available.
+func New(r *resolve.Resolver, trees func(path string) *zscript.Tree) *Evaluator {
+	return &Evaluator{r: r, trees: trees, active: map[key]bool{}}
+}
+
+// ForProject returns an evaluator for the files of p and the engine
+// declarations for the version p declares.
+func ForProject(p *project.Project) *Evaluator {
+	return New(resolve.ForProject(p), p.Tree)
+}
+
+// Eval folds node, an expression in tree, resolving names against the
+// declarations of tree alone and those of the engine.
+func Eval(tree *zscript.Tree, node *tree_sitter.Node) (Value, error) {
+	r := resolve.NewWithEngine(engine.Version, symbols.Extract(tree))
+	return New(r, func(path string) *zscript.Tree {
+		if path == tree.Path {
+			return tree
+		}
+		return nil
+	}).Eval(tree, node)
+}
+
+// Eval folds node, an expression in tree.
+func (e *Evaluator) Eval(tree *zscript.Tree, node *tree_sitter.Node) (Value, error) {
+	if node == nil {
+		return Value{}, fmt.Errorf("%w: missing expression", ErrNotConstant)
+	}
+	switch node.Kind() {
+	case zscript.NodeNumberLiteral:
+		return parseNumber(text(tree, node))
+	case zscript.NodeTrue:
+		return BoolValue(true), nil
+	case zscript.NodeFalse:
+		return BoolValue(false), nil
+	case zscript.NodeStringLiteral:
+		return StringValue(unquote(text(tree, node))), nil
+	case zscript.NodeConcatenatedString:
+		var b strings.Builder
+		for i := uint(0); i < node.NamedChildCount(); i++ {
+			if c := node.NamedChild(i); c.Kind() == zscript.NodeStringLiteral {
+				b.WriteString(unquote(text(tree, c)))
+			}
+		}
+		return StringValue(b.String()), nil
+	case zscript.NodeNameLiteral:
+		return Value{Kind: Name, Text: strings.Trim(text(tree, node), "'")}, nil
+	case zscript.NodeParenthesizedExpression:
+		return e.Eval(tree, firstNamed(node))
+	case zscript.NodeUnaryExpression:
+		v, err := e.Eval(tree, node.ChildByFieldName(zscript.FieldArgument))
+		if err != nil {
+			return Value{}, err
+		}
+		return unary(node.ChildByFieldName(zscript.FieldOperator).Kind(), v, node)
+	case zscript.NodeBinaryExpression:
+		return e.binary(tree, node)
+	case zscript.NodeConditionalExpression:
+		cond, err := e.Eval(tree, node.ChildByFieldName(zscript.FieldCondition))
+		if err != nil {
+			return Value{}, err
+		}
+		b, ok := truth(cond)
+		if !ok {
+			return Value{}, typeError(node, "condition is a %s", cond.Kind)
+		}
+		if b {
+			return e.Eval(tree, node.ChildByFieldName(zscript.FieldConsequence))
+		}
+		return e.Eval(tree, node.ChildByFieldName(zscript.FieldAlternative))
+	case zscript.NodeCastExpression:
+		v, err := e.Eval(tree, node.ChildByFieldName(zscript.FieldValue))
+		if err != nil {
+			return Value{}, err
+		}
+		return convert(text(tree, node.ChildByFieldName(zscript.FieldType)), v, node)
+	case zscript.NodeCallExpression:
+		// int(x), double(x) and the like are conversions.
+		fn, args := node.ChildByFieldName(zscript.FieldFunction), node.ChildByFieldName(zscript.FieldArguments)
+		if fn != nil && args != nil && args.NamedChildCount() == 1 && conversions[strings.ToLower(text(tree, fn))] {
+			v, err := e.Eval(tree, args.NamedChild(0))
+			if err != nil {
+				return Value{}, err
+			}
+			return convert(text(tree, fn), v, node)
+		}
+	case zscript.NodeIdentifier:
+		return e.constant(tree, node)
+	case zscript.NodeFieldExpression:
+		return e.constant(tree, node.ChildByFieldName(zscript.FieldField))
+	}
+	return Value{}, notConstant(tree, node)
+}
+
+// constant folds the value of the const or enumerator that the identifier
+// node refers to.
+func (e *Evaluator) constant(tree *zscript.Tree, node *tree_sitter.Node) (Value, error) {
+	if node == nil {
+		return Value{}, fmt.Errorf("%w: missing name", ErrNotConstant)
+	}
+	decl, ok := e.r.Resolve(tree, node)
+	if !ok || decl.Kind != symbols.KindConst && decl.Kind != symbols.KindEnumerator {
+		return Value{}, notConstant(tree, node)
+	}
+	var source *zscript.Tree
+	if decl.Path == engine.Path {
+		source = engine.Tree()
+	} else if e.trees != nil {
+		source = e.trees(decl.Path)
+	}
+	if source == nil {
+		return Value{}, fmt.Errorf("%w: %s is declared in %s, which is not available", ErrNotConstant, decl.Name, decl.Path)
+	}
+	k := key{decl.Path, decl.Range.StartByte}
+	if e.active[k] {
+		return Value{}, fmt.Errorf("%w: %s is defined in terms of itself", ErrNotConstant, decl.Name)
+	}
+	e.active[k] = true
+	defer delete(e.active, k)
+
+	def := source.RootNode().DescendantForByteRange(decl.NameRange.StartByte, decl.NameRange.EndByte)
+	for def != nil && def.Kind() != zscript.NodeConstDefinition && def.Kind() != zscript.NodeEnumerator {
+		def = def.Parent()
+	}
+	if def == nil {
+		return Value{}, notConstant(tree, node)
+	}
+	if value := def.ChildByFieldName(zscript.FieldValue); value != nil || def.Kind() == zscript.NodeConstDefinition {
+		return e.Eval(source, value)
+	}
+	return e.enumerator(source, def)
+}
+
+// enumerator folds the value of an enumerator without an explicit value:
+// one more than the enumerator before it, or 0 for the first.
+func (e *Evaluator) enumerator(tree *zscript.Tree, def *tree_sitter.Node) (Value, error) {
+	prev := def.PrevNamedSibling()
+	for prev != nil && prev.Kind() != zscript.NodeEnumerator {
+		prev = prev.PrevNamedSibling()
+	}
+	if prev == nil {
+		return IntValue(0), nil
+	}
+	var v Value
+	var err error
+	if value := prev.ChildByFieldName(zscript.FieldValue); value != nil {
+		v, err = e.Eval(tree, value)
+	} else {
+		v, err = e.enumerator(tree, prev)
+	}
+	if err != nil {
+		return Value{}, err
+	}
+	if v.Kind != Int {
+		return Value{}, typeError(def, "previous enumerator is a %s", v.Kind)
+	}
+	return IntValue(v.Int + 1), nil
+}
+
+func (e *Evaluator) binary(tree *zscript.Tree, node *tree_sitter.Node) (Value, error) {
+	op := node.ChildByFieldName(zscript.FieldOperator).Kind()
+	left, err := e.Eval(tree, node.ChildByFieldName(zscript.FieldLeft))
+	if err != nil {
+		return Value{}, err
+	}
+	// && and || do not evaluate their right operand when the left one
+	// decides the result.
+	if op == "&&" || op == "||" {
+		l, ok := truth(left)
+		if !ok {
+			return Value{}, typeError(node, "%s operand of %s", left.Kind, op)
+		}
+		if l == (op == "||") {
+			return BoolValue(l), nil
+		}
+	}
+	right, err := e.Eval(tree, node.ChildByFieldName(zscript.FieldRight))
+	if err != nil {
+		return Value{}, err
+	}
+	return binary(op, left, right, node)
+}
+
+var conversions = map[string]bool{
+	"int": true, "uint": true, "int8": true, "uint8": true, "int16": true, "uint16": true,
+	"double": true, "float": true, "bool": true, "name": true, "string": true,
+}
+
+func unary(op string, v Value, node *tree_sitter.Node) (Value, error) {
+	switch {
+	case op == "+" && (v.Kind == Int || v.Kind == Float):
+		return v, nil
+	case op == "-" && v.Kind == Int:
+		return IntValue(-v.Int), nil
+	case op == "-" && v.Kind == Float:
+		return FloatValue(-v.Float), nil
+	case op == "~" && v.Kind == Int:
+		return IntValue(^v.Int), nil
+	case op == "!":
+		if b, ok := truth(v); ok {
+			return BoolValue(!b), nil
+		}
+	}
+	return Value{}, typeError(node, "%s%s", op, v.Kind)
+}
+
+func binary(op string, l, r Value, node *tree_sitter.Node) (Value, error) {
+	switch op {
+	case "..":
+		return StringValue(l.String() + r.String()), nil
+	case "&&", "||":
+		b, ok := truth(r)
+		if !ok {
+			return Value{}, typeError(node, "%s operand of %s", r.Kind, op)
+		}
+		return BoolValue(b), nil
+	case "~==":
+		if l.Kind == String && r.Kind == String || l.Kind == Name && r.Kind == Name {
+			return BoolValue(strings.EqualFold(l.Text, r.Text)), nil
+		}
+	case "==", "!=", "<", "<=", ">", ">=", "<>=":
+		if c, ok := compare(l, r); ok {
+			switch op {
+			case "==":
+				return BoolValue(c == 0), nil
+			case "!=":
+				return BoolValue(c != 0), nil
+			case "<":
+				return BoolValue(c < 0), nil
+			case "<=":
+				return BoolValue(c <= 0), nil
+			case ">":
+				return BoolValue(c > 0), nil
+			case ">=":
+				return BoolValue(c >= 0), nil
+			}
+			return IntValue(int32(c)), nil
+		}
+	case "+", "-", "*", "/", "%", "**":
+		if l.Kind == Int && r.Kind == Int {
+			a, b := l.Int, r.Int
+			switch op {
+			case "+":
+				return IntValue(a + b), nil
+			case "-":
+				return IntValue(a - b), nil
+			case "*":
+				return IntValue(a * b), nil
+			case "/", "%":
+				if b == 0 {
+					return Value{}, fmt.Errorf("eval: %s: integer division by zero", position(node))
+				}
+				if op == "/" {
+					return IntValue(a / b), nil
+				}
+				return IntValue(a % b), nil
+			}
+			return IntValue(int32(math.Pow(float64(a), float64(b)))), nil
+		}
+		a, aok := l.Number()
+		b, bok := r.Number()
+		if aok && bok {
+			switch op {
+			case "+":
+				return FloatValue(a + b), nil
+			case "-":
+				return FloatValue(a - b), nil
+			case "*":
+				return FloatValue(a * b), nil
+			case "/":
+				return FloatValue(a / b), nil
+			case "%":
+				return FloatValue(math.Mod(a, b)), nil
+			}
+			return FloatValue(math.Pow(a, b)), nil
+		}
+	case "&", "|", "^", "<<", ">>", ">>>":
+		if l.Kind == Int && r.Kind == Int {
+			a, b := l.Int, r.Int
+			shift := uint32(b) & 31
+			switch op {
+			case "&":
+				return IntValue(a & b), nil
+			case "|":
+				return IntValue(a | b), nil
+			case "^":
+				return IntValue(a ^ b), nil
+			case "<<":
+				return IntValue(a << shift), nil
+			case ">>":
+				return IntValue(a >> shift), nil
+			}
+			return IntValue(int32(uint32(a) >> shift)), nil
+		}
+	}
+	return Value{}, typeError(node, "%s %s %s", l.Kind, op, r.Kind)
+}
+
+// compare orders two numbers, two strings or two booleans.
+func compare(l, r Value) (int, bool) {
+	if a, ok := l.Number(); ok {
+		b, ok := r.Number()
+		if !ok {
+			return 0, false
+		}
+		if l.Kind == Int && r.Kind == Int {
+			a, b = float64(l.Int), float64(r.Int)
+		}
+		switch {
+		case a < b:
+			return -1, true
+		case a > b:
+			return 1, true
+		}
+		return 0, true
+	}
+	if l.Kind != r.Kind {
+		return 0, false
+	}
+	switch l.Kind {
+	case Bool:
+		if l.Bool == r.Bool {
+			return 0, true
+		}
+		if r.Bool {
+			return -1, true
+		}
+		return 1, true
+	case String, Name:
+		return strings.Compare(l.Text, r.Text), true
+	}
+	return 0, false
+}
+
+// truth returns v as a condition: booleans, and numbers compared with 0.
+func truth(v Value) (bool, bool) {
+	switch v.Kind {
+	case Bool:
+		return v.Bool, true
+	case Int:
+		return v.Int != 0, true
+	case Float:
+		return v.Float != 0, true
+	}
+	return false, false
+}
+
+// convert converts v to the named type.
+func convert(typ string, v Value, node *tree_sitter.Node) (Value, error) {
+	n, isNumber := v.Number()
+	if v.Kind == Bool {
+		n, isNumber = 0, true
+		if v.Bool {
+			n = 1
+		}
+	}
+	switch t := strings.ToLower(strings.TrimSpace(typ)); t {
+	case "int", "uint", "int8", "uint8", "int16", "uint16":
+		if isNumber {
+			i := int64(n)
+			if v.Kind == Int {
+				i = int64(v.Int)
+			}
+			switch t {
+			case "int8":
+				i = int64(int8(i))
+			case "uint8":
+				i = int64(uint8(i))
+			case "int16":
+				i = int64(int16(i))
+			case "uint16":
+				i = int64(uint16(i))
+			}
+			return IntValue(int32(i)), nil
+		}
+	case "double", "float":
+		if isNumber {
+			return FloatValue(n), nil
+		}
+	case "bool":
+		if b, ok := truth(v); ok {
+			return BoolValue(b), nil
+		}
+	case "name":
+		if v.Kind == String || v.Kind == Name {
+			return Value{Kind: Name, Text: v.Text}, nil
+		}
+	case "string":
+		if v.Kind == String || v.Kind == Name {
+			return StringValue(v.Text), nil
+		}
+	}
+	return Value{}, typeError(node, "cannot convert %s to %s", v.Kind, typ)
+}
+
+// parseNumber parses a number literal: decimal, hexadecimal or binary,
+// with optional digit separators, fraction, exponent and suffixes.
+func parseNumber(s string) (Value, error) {
+	lit := strings.ReplaceAll(s, "'", "")
+	hex := strings.HasPrefix(strings.ToLower(strings.TrimLeft(lit, "+-")), "0x")
+	suffixes := "uUlLfF"
+	if hex {
+		suffixes = "uUlL"
+	}
+	digits := strings.TrimRight(lit, suffixes)
+	lower := strings.ToLower(digits)
+	isFloat := strings.Contains(lower, ".") || strings.ContainsAny(lit[len(digits):], "fF")
+	if hex {
+		isFloat = isFloat || strings.Contains(lower, "p")
+	} else {
+		isFloat = isFloat || strings.Contains(lower, "e")
+	}
+	if isFloat {
+		f, err := strconv.ParseFloat(digits, 64)
+		if err != nil {
+			return Value{}, fmt.Errorf("eval: invalid number %q", s)
+		}
+		return FloatValue(f), nil
+	}
+	n, err := strconv.ParseInt(digits, 0, 64)
+	if err != nil {
+		// Literals such as 0xFFFFFFFFFFFFFFFF wrap like the others.
+		u, uerr := strconv.ParseUint(strings.TrimPrefix(digits, "+"), 0, 64)
+		if uerr != nil {
+			return Value{}, fmt.Errorf("eval: invalid number %q", s)
+		}
+		n = int64(u)
+	}
+	return IntValue(int32(n)), nil
+}
+
+// unquote returns the content of a string literal with its escape
+// sequences replaced.
+func unquote(lit string) string {
+	body := strings.TrimSuffix(strings.TrimPrefix(lit, `"`), `"`)
+	if !strings.Contains(body, `\`) {
+		return body
+	}
+	var b strings.Builder
+	for len(body) > 0 {
+		r, _, tail, err := strconv.UnquoteChar(body, '"')
+		if err != nil {
+			// Keep what strconv does not know, such as "\c" colour codes.
+			b.WriteByte(body[0])
+			body = body[1:]
+			continue
+		}
+		b.WriteRune(r)
+		body = tail
+	}
+	return b.String()
+}
+
+func text(tree *zscript.Tree, node *tree_sitter.Node) string {
+	return node.Utf8Text(tree.Source)
+}
+
+func firstNamed(node *tree_sitter.Node) *tree_sitter.Node {
+	for i := uint(0); i < node.NamedChildCount(); i++ {
+		if c := node.NamedChild(i); c.Kind() != zscript.NodeComment {
+			return c
+		}
+	}
+	return nil
+}
+
+func position(node *tree_sitter.Node) string {
+	p := node.StartPosition()
+	return fmt.Sprintf("%d:%d", p.Row+1, p.Column+1)
+}
+
+func notConstant(tree *zscript.Tree, node *tree_sitter.Node) error {
+	return fmt.Errorf("%w: %s: %s", ErrNotConstant, position(node), text(tree, node))
+}
+
+func typeError(node *tree_sitter.Node, format string, args ...any) error {
+	return fmt.Errorf("eval: %s: invalid operation: %s", position(node), fmt.Sprintf(format, args...))
+}
@@ -0,0 +1,165 @@
+package eval_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+
+	zscript "github.com/jlcrochet/tree-sitter-zscript/bindings/go"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/eval"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/project"
+)
+
+// value parses source, a class whose const X holds the expression to fold,
+// and folds it.
+func value(t *testing.T, source string) (eval.Value, error) {
+	t.Helper()
+	tree, err := zscript.Parse(context.Background(), []byte(source))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(tree.Close)
+	return eval.Eval(tree, constValue(tree, "X"))
+}
+
+// constValue returns the value of the const named name in tree.
+func constValue(tree *zscript.Tree, name string) *tree_sitter.Node {
+	var found *tree_sitter.Node
+	var v zscript.Visitor
+	v.On(zscript.NodeConstDefinition, func(node *tree_sitter.Node) zscript.WalkAction {
+		if node.ChildByFieldName(zscript.FieldName).Utf8Text(tree.Source) == name {
+			found = node.ChildByFieldName(zscript.FieldValue)
+			return zscript.WalkStop
+		}
+		return zscript.WalkContinue
+	})
+	zscript.Walk(tree.RootNode(), &v)
+	return found
+}
+
+func TestEval(t *testing.T) {
+	tests := []struct {
+		expr string
+		want eval.Value
+	}{
+		{"1 + 2 * 3", eval.IntValue(7)},
+		{"(1 + 2) * 3", eval.IntValue(9)},
+		{"7 / 2", eval.IntValue(3)},
+		{"-7 / 2", eval.IntValue(-3)},
+		{"-7 % 3", eval.IntValue(-1)},
+		{"7 / 2.", eval.FloatValue(3.5)},
+		{"1'000 + 0x10 + 0b11", eval.IntValue(1019)},
+		{"0xFFFFFFFF", eval.IntValue(-1)},
+		{"2147483647 + 1", eval.IntValue(-2147483648)},
+		{"1 << 4 | 1 << 1", eval.IntValue(18)},
+		{"-16 >> 2", eval.IntValue(-4)},
+		{"-16 >>> 28", eval.IntValue(15)},
+		{"~0 ^ 5", eval.IntValue(-6)},
+		{"2 ** 10", eval.IntValue(1024)},
+		{"1.5e1f", eval.FloatValue(15)},
+		{"3 > 2 && 2 > 1", eval.BoolValue(true)},
+		{"1 <>= 2", eval.IntValue(-1)},
+		{"!false ? 10 : 20", eval.IntValue(10)},
+		{`"abc" ~== "ABC"`, eval.BoolValue(true)},
+		{`"hp: " .. 10`, eval.StringValue("hp: 10")},
+		{`"a\tb" "c"`, eval.StringValue("a\tbc")},
+		{"int(3.9)", eval.IntValue(3)},
+		{"Y * 2", eval.IntValue(20)},
+		{"B_Two | B_Four", eval.IntValue(6)},
+		{"EBits.B_Next", eval.IntValue(5)},
+		{"DEFAULT_HEALTH / 10", eval.IntValue(100)},
+	}
+	for _, tt := range tests {
+		source := "class Imp : Actor {\n\tenum EBits { B_None, B_One, B_Two = B_One << 1, B_Four = 4, B_Next }\n\tconst Y = 10;\n\tconst X = " + tt.expr + ";\n}\n"
+		got, err := value(t, source)
+		if err != nil {
+			t.Errorf("%s: %v", tt.expr, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%s = %+v, want %+v", tt.expr, got, tt.want)
+		}
+	}
+}
+
+func TestEvalErrors(t *testing.T) {
+	tests := []struct {
+		expr string
+		// constant is set if the error must wrap ErrNotConstant.
+		constant bool
+		want     string
+	}{
+		{"Random(1, 2)", true, "Random(1, 2)"},
+		{"health", true, "health"},
+		{"X + 1", true, "defined in terms of itself"},
+		{"1 / 0", false, "division by zero"},
+		{`"a" - 1`, false, "string - int"},
+	}
+	for _, tt := range tests {
+		_, err := value(t, "class Imp : Actor {\n\tconst X = "+tt.expr+";\n}\n")
+		if err == nil || errors.Is(err, eval.ErrNotConstant) != tt.constant || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: err = %v", tt.expr, err)
+		}
+	}
+}
+
+func TestForProject(t *testing.T) {
+	fsys := fstest.MapFS{
+		"zscript.zs": {Data: []byte("version \"4.12\"\n#include \"b.zs\"\nconst SIZE = HALF * 2;\n")},
+		"b.zs":       {Data: []byte("const HALF = 8;\n")},
+	}
+	p, err := project.Load(context.Background(), fsys, "zscript.zs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+	tree := p.File("zscript.zs").Tree
+	got, err := eval.ForProject(p).Eval(tree, constValue(tree, "SIZE"))
+	if err != nil || got != eval.IntValue(16) {
+		t.Errorf("SIZE = %+v, %v", got, err)
+	}
+}
+
+func TestUses(t *testing.T) {
+	source := `const GLOBAL = 3;
+class Imp : Actor {
+	const TICS = 4;
+	enum Mode { MODE_IDLE, MODE_ANGRY = TICS * 2, MODE_LAST }
+	int mode;
+	void Think() {
+		int local = 1;
+		mode = MODE_ANGRY + local;
+		A_SetTics(Imp.TICS + GLOBAL);
+	}
+	States {
+	Spawn:
+		TROO A 1 A_SetTics(MODE_LAST);
+		Loop;
+	}
+}
+`
+	tree, err := zscript.Parse(context.Background(), []byte(source))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tree.Close()
+	var got []string
+	for _, u := range eval.Uses(tree, tree.RootNode()) {
+		got = append(got, fmt.Sprintf("%d:%d %s = %s", u.Range.StartPoint.Row+1, u.Range.StartPoint.Column+1, u.Name, u.Value))
+	}
+	want := []string{
+		"4:38 TICS = 4",
+		"8:10 MODE_ANGRY = 8",
+		"9:13 Imp.TICS = 4",
+		"9:24 GLOBAL = 3",
+		"13:22 MODE_LAST = 9",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
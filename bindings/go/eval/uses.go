@@ -0,0 +1,74 @@
+package eval
+
+import (
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+
+	zscript "github.com/jlcrochet/tree-sitter-zscript/bindings/go"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/engine"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/resolve"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/symbols"
+)
+
+// Use is a name that refers to a const or an enumerator, with the value
+// it folds to, for showing the values of symbolic constants next to them.
+type Use struct {
+	// Range spans the name, or the whole of a qualified name such as
+	// Weapon.ammo_slot.
+	Range tree_sitter.Range
+	Name  string
+	Value Value
+}
+
+// Uses returns the names in node, which may be any node of tree, that
+// refer to consts and enumerators, in source order. Names whose values
+// cannot be folded are left out, as are the names declared by const
+// definitions and enumerators themselves.
+func (e *Evaluator) Uses(tree *zscript.Tree, node *tree_sitter.Node) []Use {
+	var uses []Use
+	add := func(n, name *tree_sitter.Node) bool {
+		v, err := e.constant(tree, name)
+		if err != nil {
+			return false
+		}
+		uses = append(uses, Use{Range: n.Range(), Name: text(tree, n), Value: v})
+		return true
+	}
+	var v zscript.Visitor
+	v.On(zscript.NodeIdentifier, func(n *tree_sitter.Node) zscript.WalkAction {
+		if !declares(n) {
+			add(n, n)
+		}
+		return zscript.WalkContinue
+	})
+	v.On(zscript.NodeFieldExpression, func(n *tree_sitter.Node) zscript.WalkAction {
+		if field := n.ChildByFieldName(zscript.FieldField); field != nil && add(n, field) {
+			return zscript.WalkSkipChildren
+		}
+		return zscript.WalkContinue
+	})
+	zscript.Walk(node, &v)
+	return uses
+}
+
+// Uses returns the uses in node, a node of tree, of the constants declared
+// by tree alone and by the engine.
+func Uses(tree *zscript.Tree, node *tree_sitter.Node) []Use {
+	r := resolve.NewWithEngine(engine.Version, symbols.Extract(tree))
+	return New(r, func(path string) *zscript.Tree {
+		if path == tree.Path {
+			return tree
+		}
+		return nil
+	}).Uses(tree, node)
+}
+
+// declares reports whether the identifier n is the name of a const
+// definition or an enumerator.
+func declares(n *tree_sitter.Node) bool {
+	parent := n.Parent()
+	if parent == nil || parent.Kind() != zscript.NodeConstDefinition && parent.Kind() != zscript.NodeEnumerator {
+		return false
+	}
+	name := parent.ChildByFieldName(zscript.FieldName)
+	return name != nil && name.Id() == n.Id()
+}
@@ -0,0 +1,242 @@
+// Package events finds the event handlers of a ZScript project, the
+// classes that extend EventHandler or StaticEventHandler, and checks that
+// each is registered. GZDoom only runs a handler named by the
+// AddEventHandlers or EventHandlers key of MAPINFO's GameInfo block, or by
+// the EventHandlers key of a map definition; one that is defined but never
+// added is silently ignored.
+//
+// Handlers registered some other way, such as by another archive's
+// MAPINFO, can be listed in a manifest: a text file naming one class per
+// line, in which blank lines and lines starting with # are ignored.
+package events
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+
+	zscript "github.com/jlcrochet/tree-sitter-zscript/bindings/go"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/engine"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/lint"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/mapinfo"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/project"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/symbols"
+)
+
+// RuleUnregistered is the rule name of the findings of Check.
+const RuleUnregistered = "unregistered-event-handler"
+
+// Options configures Find and Check.
+type Options struct {
+	// Registered names handlers registered outside the MAPINFO files
+	// given, as read from a manifest by ParseManifest.
+	Registered []string
+}
+
+// Handler is an event handler class of the project.
+type Handler struct {
+	Name      string
+	Path      string
+	NameRange tree_sitter.Range
+	// Static is set for handlers that extend StaticEventHandler but not
+	// EventHandler, which persist across levels and must be registered
+	// in GameInfo.
+	Static   bool
+	Abstract bool
+	// Callbacks are the names of the event callbacks the class overrides,
+	// such as WorldThingSpawned, in source order.
+	Callbacks []string
+	// Registrations are where the handler is registered.
+	Registrations []Registration
+	// Subclasses are the names of the handlers that extend this one.
+	Subclasses []string
+}
+
+// Registration is a MAPINFO entry or manifest line naming a handler.
+type Registration struct {
+	// Path is the MAPINFO file, or "" for a manifest.
+	Path  string
+	Range tree_sitter.Range
+	// Key is the MAPINFO key, such as AddEventHandlers, or "" for a
+	// manifest.
+	Key string
+	// Block is the header of the MAPINFO block holding the key, such as
+	// "GameInfo" or "map MAP01".
+	Block string
+}
+
+// String returns the location of r, as in:
+//
+//	mapinfo.txt:3:21: GameInfo AddEventHandlers
+//
+// or "manifest".
+func (r Registration) String() string {
+	if r.Key == "" {
+		return "manifest"
+	}
+	return fmt.Sprintf("%s:%d:%d: %s %s", r.Path, r.Range.StartPoint.Row+1, r.Range.StartPoint.Column+1, r.Block, r.Key)
+}
+
+// ParseManifest returns the class names listed in a manifest.
+func ParseManifest(source []byte) []string {
+	var names []string
+	s := bufio.NewScanner(bytes.NewReader(source))
+	for s.Scan() {
+		if line := strings.TrimSpace(s.Text()); line != "" && !strings.HasPrefix(line, "#") {
+			names = append(names, line)
+		}
+	}
+	return names
+}
+
+// registrationKeys are the lowercased keys of the blocks that register
+// handlers.
+var registrationKeys = map[string][]string{
+	"gameinfo":      {"addeventhandlers", "eventhandlers"},
+	"map":           {"eventhandlers"},
+	"defaultmap":    {"eventhandlers"},
+	"adddefaultmap": {"eventhandlers"},
+}
+
+// Find returns the event handlers of p, sorted by name, with the
+// registrations of Options.Registered and of files.
+func Find(p *project.Project, opts Options, files ...*mapinfo.File) []*Handler {
+	trees := make([]*zscript.Tree, len(p.Files))
+	for i, f := range p.Files {
+		trees[i] = f.Tree
+	}
+	tables := symbols.ExtractAll(0, trees...)
+	h := engine.Hierarchy(tables...)
+	callbacks := engine.Table().Class("StaticEventHandler")
+
+	byName := map[string]*Handler{}
+	var handlers []*Handler
+	for i, t := range tables {
+		for _, c := range t.Classes {
+			if c.Extend || !h.IsSubclassOf(c.Name, "StaticEventHandler") {
+				continue
+			}
+			handler := &Handler{
+				Name:      c.Name,
+				Path:      trees[i].Path,
+				NameRange: c.NameRange,
+				Static:    !h.IsSubclassOf(c.Name, "EventHandler"),
+				Abstract:  hasFlag(c.Flags, "abstract"),
+			}
+			for _, m := range c.Methods {
+				if callbacks != nil && callbacks.Method(m.Name) != nil {
+					handler.Callbacks = append(handler.Callbacks, m.Name)
+				}
+			}
+			byName[strings.ToLower(c.Name)] = handler
+			handlers = append(handlers, handler)
+		}
+	}
+	for _, handler := range handlers {
+		if class := h.Class(handler.Name); class != nil && class.Parent != nil {
+			if parent := byName[strings.ToLower(class.Parent.Name)]; parent != nil {
+				parent.Subclasses = append(parent.Subclasses, handler.Name)
+			}
+		}
+	}
+
+	for _, name := range opts.Registered {
+		if handler := byName[strings.ToLower(name)]; handler != nil {
+			handler.Registrations = append(handler.Registrations, Registration{})
+		}
+	}
+	for _, f := range files {
+		for _, b := range f.Blocks {
+			keys := registrationKeys[strings.ToLower(b.Name.Text)]
+			block := b.Name.Text
+			for _, arg := range b.Args {
+				block += " " + arg.Text
+			}
+			for _, key := range keys {
+				if e := b.Entry(key); e != nil {
+					for _, v := range e.Values {
+						if handler := byName[strings.ToLower(v.Text)]; handler != nil {
+							handler.Registrations = append(handler.Registrations, Registration{f.Path, v.Range, e.Key.Text, block})
+						}
+					}
+				}
+			}
+		}
+	}
+
+	sort.Slice(handlers, func(i, j int) bool {
+		return strings.ToLower(handlers[i].Name) < strings.ToLower(handlers[j].Name)
+	})
+	return handlers
+}
+
+func hasFlag(flags []string, name string) bool {
+	for _, f := range flags {
+		if strings.EqualFold(f, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// Check reports the handlers of p that neither files nor
+// Options.Registered register. Abstract handlers, and handlers that
+// others extend, are taken to be base classes and are not reported.
+// Findings are sorted by path and position.
+func Check(p *project.Project, opts Options, files ...*mapinfo.File) []lint.Finding {
+	var findings []lint.Finding
+	for _, handler := range Find(p, opts, files...) {
+		if len(handler.Registrations) > 0 || handler.Abstract || len(handler.Subclasses) > 0 {
+			continue
+		}
+		key := "the AddEventHandlers key of GameInfo in MAPINFO"
+		if !handler.Static {
+			key += ", or to the EventHandlers key of a map"
+		}
+		findings = append(findings, lint.Finding{
+			Rule:     RuleUnregistered,
+			Severity: zscript.SeverityWarning,
+			Path:     handler.Path,
+			Range:    handler.NameRange,
+			Message:  fmt.Sprintf("event handler %q is never registered; add it to %s", handler.Name, key),
+		})
+	}
+	sort.SliceStable(findings, func(i, j int) bool {
+		if findings[i].Path != findings[j].Path {
+			return findings[i].Path < findings[j].Path
+		}
+		return findings[i].Range.StartByte < findings[j].Range.StartByte
+	})
+	return findings
+}
+
+// Write writes a listing of handlers to w: for each, its base class and
+// location, where it is registered, and the callbacks it overrides.
+func Write(w io.Writer, handlers []*Handler) error {
+	bw := bufio.NewWriter(w)
+	for _, handler := range handlers {
+		base := "EventHandler"
+		if handler.Static {
+			base = "StaticEventHandler"
+		}
+		if handler.Abstract {
+			base += ", abstract"
+		}
+		fmt.Fprintf(bw, "%s (%s) %s:%d\n", handler.Name, base, handler.Path, handler.NameRange.StartPoint.Row+1)
+		if len(handler.Registrations) == 0 {
+			fmt.Fprintf(bw, "\tnot registered\n")
+		}
+		for _, r := range handler.Registrations {
+			fmt.Fprintf(bw, "\tregistered in %s\n", r)
+		}
+		if len(handler.Callbacks) > 0 {
+			fmt.Fprintf(bw, "\tcallbacks %s\n", strings.Join(handler.Callbacks, ", "))
+		}
+	}
+	return bw.Flush()
+}
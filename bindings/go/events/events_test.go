@@ -0,0 +1,104 @@
+package events_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/events"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/mapinfo"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/project"
+)
+
+const source = `class ScoreHandler : EventHandler {
+	override void WorldThingDied(WorldEvent e) {}
+	override void WorldLoaded(WorldEvent e) {}
+	void Helper() {}
+}
+class MapHandler : EventHandler {}
+class Forgotten : StaticEventHandler {
+	override void OnRegister() {}
+}
+class Base : EventHandler abstract {}
+class Shared : EventHandler {}
+class Derived : Shared { override void WorldTick() {} }
+class FromManifest : StaticEventHandler {}
+class Imp : Actor {}
+`
+
+const zmapinfo = `GameInfo
+{
+	AddEventHandlers = "ScoreHandler", "Derived"
+}
+
+map MAP01 "Hangar"
+{
+	EventHandlers = "MapHandler"
+}
+`
+
+func load(t *testing.T) (*project.Project, []*mapinfo.File) {
+	t.Helper()
+	fsys := fstest.MapFS{
+		"zscript.zs": {Data: []byte(source)},
+		"ZMAPINFO":   {Data: []byte(zmapinfo)},
+	}
+	p, err := project.Load(context.Background(), fsys, "zscript.zs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(p.Close)
+	files, err := mapinfo.Load(fsys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p, files
+}
+
+func TestCheck(t *testing.T) {
+	p, files := load(t)
+	manifest := events.ParseManifest([]byte("# registered by the base mod\n\nFromManifest\n"))
+	var got []string
+	for _, f := range events.Check(p, events.Options{Registered: manifest}, files...) {
+		got = append(got, f.String())
+	}
+	want := []string{
+		`zscript.zs:7:7: warning: event handler "Forgotten" is never registered; add it to the AddEventHandlers key of GameInfo in MAPINFO (unregistered-event-handler)`,
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+	if got := events.Check(p, events.Options{}); len(got) != 5 {
+		t.Errorf("without MAPINFO: got %d findings, want 5: %v", len(got), got)
+	}
+}
+
+func TestWrite(t *testing.T) {
+	p, files := load(t)
+	var b strings.Builder
+	if err := events.Write(&b, events.Find(p, events.Options{Registered: []string{"FromManifest"}}, files...)); err != nil {
+		t.Fatal(err)
+	}
+	want := `Base (EventHandler, abstract) zscript.zs:10
+	not registered
+Derived (EventHandler) zscript.zs:12
+	registered in ZMAPINFO:3:37: GameInfo AddEventHandlers
+	callbacks WorldTick
+Forgotten (StaticEventHandler) zscript.zs:7
+	not registered
+	callbacks OnRegister
+FromManifest (StaticEventHandler) zscript.zs:13
+	registered in manifest
+MapHandler (EventHandler) zscript.zs:6
+	registered in ZMAPINFO:8:18: map MAP01 Hangar EventHandlers
+ScoreHandler (EventHandler) zscript.zs:1
+	registered in ZMAPINFO:3:21: GameInfo AddEventHandlers
+	callbacks WorldThingDied, WorldLoaded
+Shared (EventHandler) zscript.zs:11
+	not registered
+`
+	if b.String() != want {
+		t.Errorf("got:\n%s\nwant:\n%s", b.String(), want)
+	}
+}
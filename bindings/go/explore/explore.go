@@ -0,0 +1,339 @@
+// Package explore is an interactive explorer of syntax trees, read from
+// and written to a terminal a command at a time, like a REPL. It shows
+// the tree as numbered rows, one per node, with its field and range, and
+// lets the user expand and collapse nodes, focus on a subtree, print the
+// source of a node, and jump from a position in the source to the node
+// at it.
+//
+// The commands are:
+//
+//	N         expand or collapse row N
+//	cd N      focus on the node of row N
+//	..        focus on the parent of the focused node
+//	/         focus on the root
+//	g L:C     select the node at line L, column C, expanding the rows to it
+//	src [N]   print the source of row N, or of the selected node
+//	all [D]   expand the focused node to depth D, 3 by default
+//	none      collapse everything under the focused node
+//	anon      show or hide anonymous nodes, such as punctuation
+//	ls        print the rows again
+//	help      print the commands
+//	q         quit
+//
+// Rows are written like Dump writes nodes, with zero-based rows and byte
+// columns; lines and columns given to g are one-based, as in diagnostics.
+package explore
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+
+	zscript "github.com/jlcrochet/tree-sitter-zscript/bindings/go"
+)
+
+// Explorer is the state of an exploration of one tree.
+type Explorer struct {
+	tree     *zscript.Tree
+	focus    *tree_sitter.Node
+	expanded map[uintptr]bool
+	selected uintptr
+	// anonymous is set if anonymous nodes are shown.
+	anonymous bool
+	// rows are the nodes of the rows last printed.
+	rows []*tree_sitter.Node
+}
+
+// New returns an explorer of tree, focused on its root with the root's
+// children shown.
+func New(tree *zscript.Tree) *Explorer {
+	root := tree.RootNode()
+	return &Explorer{tree: tree, focus: root, expanded: map[uintptr]bool{root.Id(): true}}
+}
+
+// Run prints the rows and then reads commands from r, writing their
+// output and a prompt to w, until r ends or the user quits.
+func (e *Explorer) Run(r io.Reader, w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	e.list(bw)
+	s := bufio.NewScanner(r)
+	for {
+		fmt.Fprint(bw, "> ")
+		if err := bw.Flush(); err != nil {
+			return err
+		}
+		if !s.Scan() {
+			fmt.Fprintln(bw)
+			break
+		}
+		if !e.Exec(s.Text(), bw) {
+			break
+		}
+	}
+	if err := s.Err(); err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+const help = `N         expand or collapse row N
+cd N      focus on the node of row N
+..        focus on the parent of the focused node
+/         focus on the root
+g L:C     select the node at line L, column C
+src [N]   print the source of row N, or of the selected node
+all [D]   expand the focused node to depth D, 3 by default
+none      collapse everything under the focused node
+anon      show or hide anonymous nodes
+ls        print the rows again
+help      print the commands
+q         quit
+`
+
+// Exec runs the command line, writing its output to w. It returns false
+// if the command is to quit.
+func (e *Explorer) Exec(line string, w io.Writer) bool {
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		e.list(w)
+		return true
+	}
+	arg := ""
+	if len(fields) > 1 {
+		arg = fields[1]
+	}
+	switch cmd := fields[0]; cmd {
+	case "q", "quit", "exit":
+		return false
+	case "help", "?":
+		fmt.Fprint(w, help)
+		return true
+	case "ls":
+	case "cd":
+		n := e.row(arg, w)
+		if n == nil {
+			return true
+		}
+		e.focus = n
+		e.expanded[n.Id()] = true
+	case "..":
+		if parent := e.focus.Parent(); parent != nil {
+			e.focus = parent
+			e.expanded[parent.Id()] = true
+		}
+	case "/":
+		e.focus = e.tree.RootNode()
+	case "g":
+		if !e.jump(arg, w) {
+			return true
+		}
+	case "src":
+		e.source(arg, w)
+		return true
+	case "all":
+		depth := 3
+		if arg != "" {
+			d, err := strconv.Atoi(arg)
+			if err != nil || d < 0 {
+				fmt.Fprintf(w, "invalid depth %q\n", arg)
+				return true
+			}
+			depth = d
+		}
+		e.expand(e.focus, depth)
+	case "none":
+		e.collapse(e.focus)
+		e.expanded[e.focus.Id()] = true
+	case "anon":
+		e.anonymous = !e.anonymous
+	default:
+		n := e.row(cmd, w)
+		if n == nil {
+			return true
+		}
+		e.expanded[n.Id()] = !e.expanded[n.Id()]
+	}
+	e.list(w)
+	return true
+}
+
+// row returns the node of the row numbered s, or writes why there is none.
+func (e *Explorer) row(s string, w io.Writer) *tree_sitter.Node {
+	if len(e.rows) == 0 {
+		e.list(io.Discard)
+	}
+	i, err := strconv.Atoi(s)
+	switch {
+	case s == "":
+		fmt.Fprintln(w, "missing row number")
+	case err != nil:
+		fmt.Fprintf(w, "unknown command %q; type help for the commands\n", s)
+	case i < 0 || i >= len(e.rows):
+		fmt.Fprintf(w, "no row %d\n", i)
+	default:
+		return e.rows[i]
+	}
+	return nil
+}
+
+// jump selects the deepest named node at the position s, written L:C, and
+// expands the rows leading to it, focusing on the root if the node is not
+// under the focused node.
+func (e *Explorer) jump(s string, w io.Writer) bool {
+	line, col, ok := strings.Cut(s, ":")
+	l, err1 := strconv.Atoi(line)
+	c, err2 := strconv.Atoi(col)
+	if !ok || err1 != nil || err2 != nil || l < 1 || c < 1 {
+		fmt.Fprintf(w, "invalid position %q; want line:column\n", s)
+		return false
+	}
+	p := tree_sitter.Point{Row: uint(l - 1), Column: uint(c - 1)}
+	n := e.tree.RootNode().NamedDescendantForPointRange(p, p)
+	if n == nil {
+		fmt.Fprintf(w, "no node at %s\n", s)
+		return false
+	}
+	if n.StartByte() < e.focus.StartByte() || n.EndByte() > e.focus.EndByte() {
+		e.focus = e.tree.RootNode()
+	}
+	for a := n.Parent(); a != nil; a = a.Parent() {
+		e.expanded[a.Id()] = true
+		if a.Id() == e.focus.Id() {
+			break
+		}
+	}
+	e.selected = n.Id()
+	return true
+}
+
+func (e *Explorer) source(arg string, w io.Writer) {
+	var n *tree_sitter.Node
+	if arg != "" {
+		if n = e.row(arg, w); n == nil {
+			return
+		}
+	} else {
+		for _, r := range e.rows {
+			if r.Id() == e.selected {
+				n = r
+			}
+		}
+		if n == nil {
+			n = e.focus
+		}
+	}
+	text := n.Utf8Text(e.tree.Source)
+	fmt.Fprint(w, text)
+	if !strings.HasSuffix(text, "\n") {
+		fmt.Fprintln(w)
+	}
+}
+
+func (e *Explorer) expand(n *tree_sitter.Node, depth int) {
+	if depth == 0 {
+		return
+	}
+	e.expanded[n.Id()] = true
+	for _, c := range e.children(n) {
+		e.expand(c, depth-1)
+	}
+}
+
+func (e *Explorer) collapse(n *tree_sitter.Node) {
+	delete(e.expanded, n.Id())
+	for _, c := range e.children(n) {
+		e.collapse(c)
+	}
+}
+
+// child is a child node with its field name.
+type child struct {
+	*tree_sitter.Node
+	field string
+}
+
+func (e *Explorer) children(n *tree_sitter.Node) []*tree_sitter.Node {
+	var nodes []*tree_sitter.Node
+	for _, c := range e.fields(n) {
+		nodes = append(nodes, c.Node)
+	}
+	return nodes
+}
+
+func (e *Explorer) fields(n *tree_sitter.Node) []child {
+	var children []child
+	for i := uint(0); i < n.ChildCount(); i++ {
+		if c := n.Child(i); e.anonymous || c.IsNamed() {
+			children = append(children, child{c, n.FieldNameForChild(uint32(i))})
+		}
+	}
+	return children
+}
+
+// list numbers and prints the rows under the focused node, as in:
+//
+//	0 - class_definition [0:0-2:1]
+//	1     name: type_identifier [0:6-0:10] "Base"
+func (e *Explorer) list(w io.Writer) {
+	e.rows = e.rows[:0]
+	field := ""
+	if parent := e.focus.Parent(); parent != nil {
+		for _, c := range e.fields(parent) {
+			if c.Id() == e.focus.Id() {
+				field = c.field
+			}
+		}
+	}
+	e.print(w, e.focus, field, 0)
+}
+
+func (e *Explorer) print(w io.Writer, n *tree_sitter.Node, field string, depth int) {
+	children := e.fields(n)
+	marker := " "
+	switch {
+	case len(children) > 0 && e.expanded[n.Id()]:
+		marker = "-"
+	case len(children) > 0:
+		marker = "+"
+	}
+	selected := " "
+	if n.Id() == e.selected {
+		selected = "*"
+	}
+	label := n.Kind()
+	switch {
+	case n.IsMissing():
+		label = "MISSING " + label
+	case !n.IsNamed():
+		label = strconv.Quote(label)
+	}
+	if field != "" {
+		label = field + ": " + label
+	}
+	start, end := n.StartPosition(), n.EndPosition()
+	fmt.Fprintf(w, "%3d%s%s %s%s [%d:%d-%d:%d]", len(e.rows), selected, marker, strings.Repeat("  ", depth), label, start.Row, start.Column, end.Row, end.Column)
+	if len(children) == 0 && n.IsNamed() {
+		fmt.Fprintf(w, " %s", quote(n.Utf8Text(e.tree.Source)))
+	}
+	fmt.Fprintln(w)
+	e.rows = append(e.rows, n)
+	if marker == "-" {
+		for _, c := range children {
+			e.print(w, c.Node, c.field, depth+1)
+		}
+	}
+}
+
+// maxText is the number of bytes of a leaf's text shown in its row.
+const maxText = 40
+
+func quote(text string) string {
+	if len(text) > maxText {
+		return strconv.Quote(text[:maxText]) + "..."
+	}
+	return strconv.Quote(text)
+}
@@ -0,0 +1,123 @@
+package explore_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	zscript "github.com/jlcrochet/tree-sitter-zscript/bindings/go"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/explore"
+)
+
+const source = `class Imp : Actor {
+	void Bar(int x) { if (x > 1) { A_Log("a"); } }
+}
+`
+
+func explorer(t *testing.T) *explore.Explorer {
+	t.Helper()
+	tree, err := zscript.Parse(context.Background(), []byte(source))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(tree.Close)
+	return explore.New(tree)
+}
+
+// run executes the commands and returns the output of the last.
+func run(t *testing.T, e *explore.Explorer, commands ...string) string {
+	t.Helper()
+	var b strings.Builder
+	for _, c := range commands {
+		b.Reset()
+		if !e.Exec(c, &b) {
+			t.Fatalf("%q quit", c)
+		}
+	}
+	return b.String()
+}
+
+func TestExpand(t *testing.T) {
+	e := explorer(t)
+	want := `  0 - source_file [0:0-3:0]
+  1 -   class_definition [0:0-2:1]
+  2       name: type_identifier [0:6-0:9] "Imp"
+  3 +     inheritance_specifier [0:10-0:17]
+  4 +     method_definition [1:1-1:47]
+`
+	if got := run(t, e, "ls", "1"); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+	if got := run(t, e, "1"); !strings.HasSuffix(got, "+   class_definition [0:0-2:1]\n") {
+		t.Errorf("not collapsed:\n%s", got)
+	}
+}
+
+func TestFocus(t *testing.T) {
+	e := explorer(t)
+	want := `  0 - inheritance_specifier [0:10-0:17]
+  1     parent: type_identifier [0:12-0:17] "Actor"
+`
+	if got := run(t, e, "1", "cd 3"); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+	if got := run(t, e, ".."); !strings.HasPrefix(got, "  0 - class_definition") {
+		t.Errorf("parent:\n%s", got)
+	}
+	if got := run(t, e, "/"); !strings.HasPrefix(got, "  0 - source_file") {
+		t.Errorf("root:\n%s", got)
+	}
+}
+
+func TestJump(t *testing.T) {
+	e := explorer(t)
+	got := run(t, e, "g 2:24")
+	if !strings.Contains(got, "*  "+strings.Repeat("  ", 6)+"left: identifier [1:23-1:24] \"x\"\n") {
+		t.Errorf("x not selected:\n%s", got)
+	}
+	if got := run(t, e, "src"); got != "x\n" {
+		t.Errorf("src = %q", got)
+	}
+	if got := run(t, e, "src 4"); !strings.HasPrefix(got, "void Bar") {
+		t.Errorf("src 4 = %q", got)
+	}
+}
+
+func TestAll(t *testing.T) {
+	e := explorer(t)
+	if got := run(t, e, "all 2"); !strings.Contains(got, "method_definition") || strings.Contains(got, "body:") {
+		t.Errorf("all 2:\n%s", got)
+	}
+	if got := run(t, e, "none"); strings.Count(got, "\n") != 2 {
+		t.Errorf("none:\n%s", got)
+	}
+	if got := run(t, e, "anon", "1"); !strings.Contains(got, `"class" [0:0-0:5]`) {
+		t.Errorf("anon:\n%s", got)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	e := explorer(t)
+	for command, want := range map[string]string{
+		"9":      "no row 9\n",
+		"cd":     "missing row number\n",
+		"frob":   "unknown command \"frob\"; type help for the commands\n",
+		"g 2":    "invalid position \"2\"; want line:column\n",
+		"all -1": "invalid depth \"-1\"\n",
+	} {
+		if got := run(t, e, command); got != want {
+			t.Errorf("%s: got %q, want %q", command, got, want)
+		}
+	}
+}
+
+func TestRun(t *testing.T) {
+	var b strings.Builder
+	if err := explorer(t).Run(strings.NewReader("help\nq\n1\n"), &b); err != nil {
+		t.Fatal(err)
+	}
+	got := b.String()
+	if !strings.Contains(got, "expand or collapse row N") || strings.Count(got, "> ") != 2 || strings.Contains(got, "name:") {
+		t.Errorf("got\n%s", got)
+	}
+}
@@ -0,0 +1,337 @@
+// Package flow builds control-flow graphs of function bodies, to find the
+// code that can never run and the functions that can end without
+// returning a value.
+package flow
+
+import (
+	"strings"
+
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+
+	zscript "github.com/jlcrochet/tree-sitter-zscript/bindings/go"
+)
+
+// Block is a basic block: statements and conditions that run in order,
+// with no jumps between them.
+type Block struct {
+	// Nodes are the statements of the block, and the conditions of the
+	// branches and loops that end it, in order.
+	Nodes []*tree_sitter.Node
+	// Succs are the blocks that control may continue to.
+	Succs []*Block
+}
+
+// Graph is the control-flow graph of a function body.
+type Graph struct {
+	// Entry is where the function starts.
+	Entry *Block
+	// End is where control arrives when it runs off the end of the body,
+	// rather than returning.
+	End *Block
+	// Exit follows End and every return.
+	Exit   *Block
+	Blocks []*Block
+
+	// stmts are the statements of the body in source order, with the
+	// blocks they start in.
+	stmts []stmt
+}
+
+type stmt struct {
+	node  *tree_sitter.Node
+	block *Block
+}
+
+// New builds the graph of body, the compound statement of a function, in
+// source. A loop whose condition is the literal true or 1, or is missing
+// from a for statement, is left only by break or return.
+func New(body *tree_sitter.Node, source []byte) *Graph {
+	g := &Graph{}
+	b := &builder{g: g, source: source}
+	g.Entry = b.block()
+	g.End = b.block()
+	g.Exit = b.block()
+	end := b.stmt(body, g.Entry)
+	edge(end, g.End)
+	edge(g.End, g.Exit)
+	return g
+}
+
+// Reachable returns the blocks that control can reach from Entry.
+func (g *Graph) Reachable() map[*Block]bool {
+	seen := map[*Block]bool{g.Entry: true}
+	stack := []*Block{g.Entry}
+	for len(stack) > 0 {
+		b := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		for _, s := range b.Succs {
+			if !seen[s] {
+				seen[s] = true
+				stack = append(stack, s)
+			}
+		}
+	}
+	return seen
+}
+
+// FallsOffEnd reports whether control can run off the end of the body
+// without a return statement.
+func (g *Graph) FallsOffEnd() bool {
+	return g.Reachable()[g.End]
+}
+
+// Unreachable returns the first statement of each run of statements that
+// can never run, in source order. Statements nested in one that is
+// returned, and those that follow it in the same block, are left out.
+func (g *Graph) Unreachable() []*tree_sitter.Node {
+	reachable := g.Reachable()
+	dead := map[uintptr]bool{}
+	var result []*tree_sitter.Node
+	for _, s := range g.stmts {
+		if reachable[s.block] {
+			continue
+		}
+		dead[s.node.Id()] = true
+		covered := false
+		if prev := prevStatement(s.node); prev != nil && dead[prev.Id()] {
+			covered = true
+		}
+		for p := s.node.Parent(); p != nil && !covered; p = p.Parent() {
+			covered = dead[p.Id()]
+		}
+		if !covered {
+			result = append(result, s.node)
+		}
+	}
+	return result
+}
+
+// prevStatement returns the named sibling before n that is not a comment.
+func prevStatement(n *tree_sitter.Node) *tree_sitter.Node {
+	for p := n.PrevNamedSibling(); p != nil; p = p.PrevNamedSibling() {
+		if p.Kind() != zscript.NodeComment {
+			return p
+		}
+	}
+	return nil
+}
+
+type builder struct {
+	g      *Graph
+	source []byte
+	// breaks and continues are the targets of break and continue in the
+	// enclosing loops and switches, innermost last. A switch has no
+	// continue target of its own.
+	breaks, continues []*Block
+}
+
+func (b *builder) block() *Block {
+	blk := &Block{}
+	b.g.Blocks = append(b.g.Blocks, blk)
+	return blk
+}
+
+func (blk *Block) add(n *tree_sitter.Node) {
+	if n != nil {
+		blk.Nodes = append(blk.Nodes, n)
+	}
+}
+
+func edge(from, to *Block) {
+	from.Succs = append(from.Succs, to)
+}
+
+// stmt adds the statement n, which starts in cur, and returns the block
+// that control continues in after it. After a jump, that is a new block
+// that nothing leads to.
+func (b *builder) stmt(n *tree_sitter.Node, cur *Block) *Block {
+	if n == nil || n.Kind() == zscript.NodeComment {
+		return cur
+	}
+	if n.Kind() != zscript.NodeCompoundStatement || n.Parent() == nil || isStatementList(n.Parent()) {
+		b.g.stmts = append(b.g.stmts, stmt{n, cur})
+	}
+	switch n.Kind() {
+	case zscript.NodeCompoundStatement:
+		for _, c := range children(n) {
+			cur = b.stmt(c, cur)
+		}
+		return cur
+	case zscript.NodeLabeledStatement:
+		for _, c := range children(n) {
+			if isStatement(c) {
+				cur = b.stmt(c, cur)
+			}
+		}
+		return cur
+	case zscript.NodeIfStatement:
+		cur.add(n.ChildByFieldName(zscript.FieldCondition))
+		then := b.block()
+		edge(cur, then)
+		after := b.block()
+		edge(b.stmt(n.ChildByFieldName(zscript.FieldConsequence), then), after)
+		if alt := n.ChildByFieldName(zscript.FieldAlternative); alt != nil {
+			els := b.block()
+			edge(cur, els)
+			for _, c := range children(alt) {
+				els = b.stmt(c, els)
+			}
+			edge(els, after)
+		} else {
+			edge(cur, after)
+		}
+		return after
+	case zscript.NodeWhileStatement, zscript.NodeForeachStatement:
+		head := b.block()
+		edge(cur, head)
+		if n.Kind() == zscript.NodeForeachStatement {
+			head.add(n.ChildByFieldName(zscript.FieldCollection))
+		} else {
+			head.add(n.ChildByFieldName(zscript.FieldCondition))
+		}
+		return b.loop(n, head, head, head)
+	case zscript.NodeForStatement:
+		cur.add(n.ChildByFieldName(zscript.FieldInitializer))
+		head := b.block()
+		edge(cur, head)
+		head.add(n.ChildByFieldName(zscript.FieldCondition))
+		update := b.block()
+		update.add(n.ChildByFieldName(zscript.FieldUpdate))
+		edge(update, head)
+		return b.loop(n, head, head, update)
+	case zscript.NodeDoStatement:
+		body := b.block()
+		edge(cur, body)
+		cond := b.block()
+		cond.add(n.ChildByFieldName(zscript.FieldCondition))
+		return b.loop(n, cond, body, cond)
+	case zscript.NodeSwitchStatement:
+		cur.add(n.ChildByFieldName(zscript.FieldCondition))
+		after := b.block()
+		b.breaks = append(b.breaks, after)
+		defer func() { b.breaks = b.breaks[:len(b.breaks)-1] }()
+		hasDefault := false
+		var prev *Block
+		body := n.ChildByFieldName(zscript.FieldBody)
+		for _, c := range children(body) {
+			if c.Kind() != zscript.NodeCaseStatement {
+				// Statements before the first case never run.
+				if prev == nil {
+					prev = b.block()
+				}
+				prev = b.stmt(c, prev)
+				continue
+			}
+			if c.ChildByFieldName(zscript.FieldValue) == nil {
+				hasDefault = true
+			}
+			blk := b.block()
+			edge(cur, blk)
+			if prev != nil {
+				edge(prev, blk)
+			}
+			value := c.ChildByFieldName(zscript.FieldValue)
+			for _, s := range children(c) {
+				if value == nil || s.Id() != value.Id() {
+					blk = b.stmt(s, blk)
+				}
+			}
+			prev = blk
+		}
+		if prev != nil {
+			edge(prev, after)
+		}
+		if !hasDefault {
+			edge(cur, after)
+		}
+		return after
+	case zscript.NodeReturnStatement:
+		cur.add(n)
+		edge(cur, b.g.Exit)
+		return b.block()
+	case zscript.NodeBreakStatement, zscript.NodeContinueStatement:
+		cur.add(n)
+		targets := b.breaks
+		if n.Kind() == zscript.NodeContinueStatement {
+			targets = b.continues
+		}
+		if len(targets) > 0 {
+			edge(cur, targets[len(targets)-1])
+		}
+		return b.block()
+	}
+	cur.add(n)
+	return cur
+}
+
+// loop adds the body of the loop n. head is the block that tests the
+// condition, entry the block the body starts in, and next the target of
+// continue.
+func (b *builder) loop(n *tree_sitter.Node, head, entry, next *Block) *Block {
+	after := b.block()
+	if !b.forever(n) {
+		edge(head, after)
+	}
+	body := entry
+	if entry == head {
+		body = b.block()
+		edge(head, body)
+	}
+	b.breaks = append(b.breaks, after)
+	b.continues = append(b.continues, next)
+	end := b.stmt(n.ChildByFieldName(zscript.FieldBody), body)
+	b.breaks = b.breaks[:len(b.breaks)-1]
+	b.continues = b.continues[:len(b.continues)-1]
+	if n.Kind() == zscript.NodeDoStatement {
+		edge(end, head)
+		edge(head, entry)
+	} else {
+		edge(end, next)
+	}
+	return after
+}
+
+// forever reports whether the loop n has a condition of true or 1, or
+// none.
+func (b *builder) forever(n *tree_sitter.Node) bool {
+	if n.Kind() == zscript.NodeForeachStatement {
+		return false
+	}
+	cond := n.ChildByFieldName(zscript.FieldCondition)
+	if cond == nil {
+		return n.Kind() == zscript.NodeForStatement
+	}
+	for cond.Kind() == zscript.NodeParenthesizedExpression && cond.NamedChildCount() == 1 {
+		cond = cond.NamedChild(0)
+	}
+	return cond.Kind() == zscript.NodeTrue || cond.Kind() == zscript.NodeNumberLiteral && cond.Utf8Text(b.source) == "1"
+}
+
+// children returns the named children of n that are not comments.
+func children(n *tree_sitter.Node) []*tree_sitter.Node {
+	if n == nil {
+		return nil
+	}
+	var result []*tree_sitter.Node
+	for i := uint(0); i < n.NamedChildCount(); i++ {
+		if c := n.NamedChild(i); c.Kind() != zscript.NodeComment {
+			result = append(result, c)
+		}
+	}
+	return result
+}
+
+// isStatementList reports whether the children of n are statements run
+// in sequence.
+func isStatementList(n *tree_sitter.Node) bool {
+	switch n.Kind() {
+	case zscript.NodeCompoundStatement, zscript.NodeCaseStatement, zscript.NodeLabeledStatement:
+		return true
+	}
+	return false
+}
+
+// isStatement reports whether n is a statement or declaration.
+func isStatement(n *tree_sitter.Node) bool {
+	return n.Kind() == zscript.NodeDeclaration || strings.HasSuffix(n.Kind(), "_statement")
+}
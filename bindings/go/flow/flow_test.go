@@ -0,0 +1,93 @@
+package flow_test
+
+import (
+	"context"
+	"testing"
+
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+
+	zscript "github.com/jlcrochet/tree-sitter-zscript/bindings/go"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/flow"
+)
+
+// body parses src, the body of a function, and returns its graph.
+func body(t *testing.T, src string) (*flow.Graph, *zscript.Tree) {
+	t.Helper()
+	tree, err := zscript.Parse(context.Background(), []byte("class Foo { int F() "+src+" }"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(tree.Close)
+	var b *tree_sitter.Node
+	var v zscript.Visitor
+	v.On(zscript.NodeMethodDefinition, func(node *tree_sitter.Node) zscript.WalkAction {
+		b = node.ChildByFieldName(zscript.FieldBody)
+		return zscript.WalkStop
+	})
+	zscript.Walk(tree.RootNode(), &v)
+	if b == nil {
+		t.Fatalf("no body in %q", src)
+	}
+	return flow.New(b, tree.Source), tree
+}
+
+func TestFallsOffEnd(t *testing.T) {
+	tests := []struct {
+		body string
+		want bool
+	}{
+		{"{}", true},
+		{"{ return 1; }", false},
+		{"{ if (x) return 1; }", true},
+		{"{ if (x) return 1; else return 2; }", false},
+		{"{ if (x) { return 1; } else if (y) { return 2; } else { return 3; } }", false},
+		{"{ while (x) { return 1; } }", true},
+		{"{ while (true) { if (x) return 1; } }", false},
+		{"{ while (1) { if (x) break; } }", true},
+		{"{ for (;;) { } }", false},
+		{"{ for (int i = 0; i < 3; i++) { return i; } }", true},
+		{"{ do { return 1; } while (x); }", false},
+		{"{ do { if (x) continue; return 1; } while (x); }", true},
+		{"{ switch (x) { case 1: return 1; default: return 2; } }", false},
+		{"{ switch (x) { case 1: return 1; case 2: return 2; } }", true},
+		{"{ switch (x) { case 1: break; default: return 2; } }", true},
+		{"{ switch (x) { case 1: default: return 2; } }", false},
+		{"{ foreach (a : list) { return 1; } }", true},
+	}
+	for _, tt := range tests {
+		g, _ := body(t, tt.body)
+		if got := g.FallsOffEnd(); got != tt.want {
+			t.Errorf("FallsOffEnd(%s) = %v, want %v", tt.body, got, tt.want)
+		}
+	}
+}
+
+func TestUnreachable(t *testing.T) {
+	tests := []struct {
+		body string
+		want []string
+	}{
+		{"{ return 1; }", nil},
+		{"{ return 1; x = 2; y = 3; }", []string{"x = 2;"}},
+		{"{ if (x) return 1; else return 2; { y = 3; } }", []string{"{ y = 3; }"}},
+		{"{ while (x) { break; y++; } return 0; }", []string{"y++;"}},
+		{"{ for (;;) {} return 0; }", []string{"return 0;"}},
+		{"{ switch (x) { case 1: return 1; y = 1; case 2: break; } return 0; }", []string{"y = 1;"}},
+	}
+	for _, tt := range tests {
+		g, tree := body(t, tt.body)
+		var got []string
+		for _, n := range g.Unreachable() {
+			got = append(got, n.Utf8Text(tree.Source))
+		}
+		if len(got) != len(tt.want) {
+			t.Errorf("Unreachable(%s) = %q, want %q", tt.body, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("Unreachable(%s) = %q, want %q", tt.body, got, tt.want)
+			}
+		}
+	}
+}
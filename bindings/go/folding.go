@@ -0,0 +1,84 @@
+package tree_sitter_zscript
+
+import tree_sitter "github.com/tree-sitter/go-tree-sitter"
+
+// FoldKind classifies a folding range.
+type FoldKind int
+
+const (
+	FoldRegion FoldKind = iota
+	FoldComment
+)
+
+func (k FoldKind) String() string {
+	if k == FoldComment {
+		return "comment"
+	}
+	return "region"
+}
+
+// FoldingRange is a span of lines an editor can collapse. Both lines are
+// zero-based and inclusive.
+type FoldingRange struct {
+	StartLine uint
+	EndLine   uint
+	Kind      FoldKind
+}
+
+// Folding returns the foldable regions of tree in source order: class,
+// struct, enum, method, states, default and compound blocks, state labels
+// and multi-line comments. A block delimited by braces folds up to the
+// line before its closing brace, so the brace stays visible. At most one
+// range starts on any line; the outermost wins.
+func Folding(tree *tree_sitter.Tree) []FoldingRange {
+	var f Folder
+	var v Visitor
+	v.Enter = func(node *tree_sitter.Node) WalkAction {
+		f.Add(node.Kind(), node.Range())
+		return WalkContinue
+	}
+	Walk(tree.RootNode(), &v)
+	return f.Ranges()
+}
+
+// Folder computes the folding ranges of nodes given to it in depth-first
+// order, as Folding does for a tree, so that they can be found from a
+// summary of a tree without parsing it again. The zero Folder is ready to
+// use.
+type Folder struct {
+	ranges []FoldingRange
+	seen   map[uint]bool
+}
+
+// Add adds the range of a node of the given kind spanning r, if it folds.
+func (f *Folder) Add(kind string, r tree_sitter.Range) {
+	switch kind {
+	case NodeClassDefinition, NodeStructDefinition, NodeEnumDefinition, NodeMethodDefinition,
+		NodeStatesBlock, NodeDefaultBlock, NodeCompoundStatement, NodeInitializerList:
+		f.add(r, FoldRegion, true)
+	case NodeStateLabel:
+		f.add(r, FoldRegion, false)
+	case NodeComment:
+		f.add(r, FoldComment, false)
+	}
+}
+
+func (f *Folder) add(r tree_sitter.Range, kind FoldKind, keepLast bool) {
+	start, end := r.StartPoint.Row, r.EndPoint.Row
+	if keepLast {
+		end--
+	}
+	if end <= start || f.seen[start] {
+		return
+	}
+	if f.seen == nil {
+		f.seen = map[uint]bool{}
+	}
+	f.seen[start] = true
+	f.ranges = append(f.ranges, FoldingRange{StartLine: start, EndLine: end, Kind: kind})
+}
+
+// Ranges returns the ranges added so far, in the order they were added.
+func (f *Folder) Ranges() []FoldingRange {
+	return f.ranges
+}
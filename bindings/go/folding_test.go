@@ -0,0 +1,54 @@
+package tree_sitter_zscript_test
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	tree_sitter_zscript "github.com/jlcrochet/tree-sitter-zscript/bindings/go"
+)
+
+func TestFolding(t *testing.T) {
+	source := `/*
+ * Header.
+ */
+class A : Actor
+{
+	enum E {
+		E_One,
+	}
+	void F()
+	{
+		if (true) {
+			return;
+		}
+	}
+	// One line.
+	States {
+	Spawn:
+		TNT1 A 1;
+		Loop;
+	}
+}
+`
+	tree, err := tree_sitter_zscript.Parse(context.Background(), []byte(source))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tree.Close()
+
+	got := tree_sitter_zscript.Folding(tree.Tree)
+	want := []tree_sitter_zscript.FoldingRange{
+		{StartLine: 0, EndLine: 2, Kind: tree_sitter_zscript.FoldComment},
+		{StartLine: 3, EndLine: 19},
+		{StartLine: 5, EndLine: 6},
+		{StartLine: 8, EndLine: 12},
+		{StartLine: 9, EndLine: 12},
+		{StartLine: 10, EndLine: 11},
+		{StartLine: 15, EndLine: 18},
+		{StartLine: 16, EndLine: 18},
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("Folding() =\n%v\nwant\n%v", got, want)
+	}
+}
@@ -0,0 +1,646 @@
+// Package format pretty-prints ZScript source from its parse tree.
+//
+// The formatter works on the stream of tokens and comments in the tree.
+// Line breaks and indentation are decided from the structure around each
+// token: declarations and statements get their own lines, block contents
+// are indented, and blank lines from the original are kept (collapsed to
+// one). Line breaks inside a statement are kept where the original had
+// them and indented as continuation lines. Comments stay attached to the
+// same tokens they followed.
+package format
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+
+	zscript "github.com/jlcrochet/tree-sitter-zscript/bindings/go"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/positions"
+)
+
+// BraceStyle controls where the opening brace of a block goes.
+type BraceStyle int
+
+const (
+	// BraceSameLine puts opening braces at the end of the line that
+	// introduces the block.
+	BraceSameLine BraceStyle = iota
+	// BraceNextLine puts opening braces on a line of their own.
+	BraceNextLine
+)
+
+// Options configures the formatter.
+type Options struct {
+	// UseSpaces indents with IndentWidth spaces instead of tabs.
+	UseSpaces   bool
+	IndentWidth int
+	BraceStyle  BraceStyle
+	// AlignStates aligns the duration and action columns of the state
+	// lines in each States block.
+	AlignStates bool
+}
+
+// DefaultOptions returns the options used when none are given: tab
+// indentation, braces on the same line, and aligned States blocks.
+func DefaultOptions() Options {
+	return Options{IndentWidth: 4, AlignStates: true}
+}
+
+// ErrSyntax is returned when asked to format source that does not parse.
+var ErrSyntax = errors.New("format: source has syntax errors")
+
+// Source formats ZScript source.
+func Source(source []byte, opts Options) ([]byte, error) {
+	return SourceContext(context.Background(), source, opts)
+}
+
+// SourceContext is like Source but stops parsing and returns ctx.Err() if
+// ctx is cancelled or its deadline passes.
+func SourceContext(ctx context.Context, source []byte, opts Options) ([]byte, error) {
+	tree, err := zscript.Parse(ctx, source)
+	if err != nil {
+		return nil, err
+	}
+	defer tree.Close()
+	return Tree(tree, opts)
+}
+
+// Tree formats a parsed file. Trees with syntax errors are rejected, since
+// the formatter cannot know what the broken regions were meant to be.
+func Tree(tree *zscript.Tree, opts Options) ([]byte, error) {
+	out, _, err := TreeWithMap(tree, opts)
+	return out, err
+}
+
+// TreeWithMap is like Tree but also returns a map between the positions
+// of the original source and those of the output.
+func TreeWithMap(tree *zscript.Tree, opts Options) ([]byte, *SourceMap, error) {
+	root := tree.RootNode()
+	if root.HasError() {
+		if d := zscript.Diagnostics(tree.Tree, tree.Source); len(d) > 0 {
+			p := d[0].Range.StartPoint
+			return nil, nil, fmt.Errorf("%w: %d:%d: %s", ErrSyntax, p.Row+1, p.Column+1, d[0].Message)
+		}
+		return nil, nil, ErrSyntax
+	}
+	if opts.IndentWidth <= 0 {
+		opts.IndentWidth = 4
+	}
+
+	c := &collector{source: tree.Source}
+	c.visit(root, 0, nil)
+	if opts.AlignStates {
+		c.alignStates()
+	}
+
+	p := &printer{opts: opts, tokens: c.tokens}
+	out := p.print()
+	return out, &SourceMap{Segments: p.segments, original: positions.NewIndex(tree.Source), formatted: positions.NewIndex(out)}, nil
+}
+
+// token is a leaf of the tree, or a node printed verbatim such as a string
+// literal, together with the context the printer needs.
+type token struct {
+	text     string
+	kind     string
+	parent   string
+	field    string
+	comment  bool
+	startRow uint
+	endRow   uint
+	// span is the range of the token in the original source.
+	span Span
+
+	// indent is the indentation level of a line starting with this token.
+	indent int
+	// open and close mark the braces of an indented block.
+	open, close bool
+	// empty marks an open brace immediately followed by its close brace.
+	empty bool
+	// nextLineBrace marks block braces that the BraceNextLine style moves
+	// to their own line.
+	nextLineBrace bool
+	// endsLine marks the last token of a declaration or statement.
+	endsLine bool
+	// hang marks the first token of an unbraced statement body.
+	hang bool
+
+	// Alignment within States blocks.
+	stateLine uintptr
+	statesID  uintptr
+	role      stateRole
+	alignTo   int
+}
+
+type stateRole int
+
+const (
+	roleNone stateRole = iota
+	roleSprite
+	roleDuration
+	roleRest
+)
+
+// containers are the nodes whose children each start on a new line.
+var containers = map[string]bool{
+	zscript.NodeSourceFile:        true,
+	zscript.NodeClassDefinition:   true,
+	zscript.NodeStructDefinition:  true,
+	zscript.NodeEnumDefinition:    true,
+	zscript.NodeCompoundStatement: true,
+	zscript.NodeDefaultBlock:      true,
+	zscript.NodeStatesBlock:       true,
+}
+
+// verbatim nodes are printed as a single token.
+var verbatim = map[string]bool{
+	zscript.NodeStringLiteral:     true,
+	zscript.NodeNameLiteral:       true,
+	zscript.NodeStateSpriteFrames: true,
+	zscript.NodeComment:           true,
+	zscript.NodeNumberLiteral:     true,
+}
+
+// bodyFields are the fields holding the statement controlled by an if,
+// else, or loop.
+var bodyFields = map[string]bool{
+	zscript.FieldConsequence: true,
+	zscript.FieldBody:        true,
+}
+
+type collector struct {
+	source []byte
+	tokens []*token
+	states uintptr
+}
+
+// lineContext locates the parts of the state line being collected.
+type lineContext struct {
+	id               uintptr
+	durStart, durEnd uint
+}
+
+// visit collects the tokens under n. indent is the level of n's own lines,
+// and stateLine describes the enclosing state line, if any.
+func (c *collector) visit(n *tree_sitter.Node, indent int, stateLine *lineContext) {
+	kind := n.Kind()
+	container := containers[kind]
+	inside := kind == zscript.NodeSourceFile
+	afterColon := false
+	if kind == zscript.NodeStatesBlock {
+		c.states = n.Id()
+	}
+	if kind == zscript.NodeStateLine {
+		stateLine = &lineContext{id: n.Id()}
+		cursor := n.Walk()
+		for _, d := range n.ChildrenByFieldName(zscript.FieldDuration, cursor) {
+			if stateLine.durStart == 0 || d.StartByte() < stateLine.durStart {
+				stateLine.durStart = d.StartByte()
+			}
+			stateLine.durEnd = max(stateLine.durEnd, d.EndByte())
+		}
+		cursor.Close()
+	}
+
+	count := n.ChildCount()
+	for i := uint(0); i < count; i++ {
+		child := n.Child(i)
+		childKind := child.Kind()
+		field := n.FieldNameForChild(uint32(i))
+		childIndent := indent
+		hang := false
+
+		switch {
+		case container && inside && childKind != "}":
+			childIndent = indent + 1
+			if kind == zscript.NodeSourceFile {
+				childIndent = indent
+			}
+		case kind == zscript.NodeStateBody:
+			childIndent = indent + 1
+		case kind == zscript.NodeCaseStatement && afterColon:
+			childIndent = indent + 1
+		case bodyFields[field] && isStatementBody(kind) && childKind != zscript.NodeCompoundStatement,
+			kind == zscript.NodeElseClause && child.IsNamed() && !child.IsExtra() &&
+				childKind != zscript.NodeCompoundStatement && childKind != zscript.NodeIfStatement:
+			childIndent = indent + 1
+			hang = true
+		}
+
+		first := len(c.tokens)
+		if child.ChildCount() == 0 || verbatim[childKind] {
+			c.add(child, n, field, childIndent, stateLine)
+		} else {
+			c.visit(child, childIndent, stateLine)
+		}
+		if len(c.tokens) == first {
+			continue
+		}
+		if hang {
+			c.tokens[first].hang = true
+		}
+
+		last := c.tokens[len(c.tokens)-1]
+		switch {
+		case container && childKind == "{" && !inside:
+			inside = true
+			last.open = true
+			last.nextLineBrace = braceMovable(n)
+			if i+1 < count && n.Child(i+1).Kind() == "}" {
+				last.empty = true
+			}
+		case container && childKind == "}":
+			last.close = true
+			last.nextLineBrace = braceMovable(n)
+		case container && inside, kind == zscript.NodeStateBody, kind == zscript.NodeCaseStatement && afterColon:
+			if !child.IsExtra() {
+				last.endsLine = true
+			}
+		}
+		if kind == zscript.NodeCaseStatement && childKind == ":" {
+			afterColon = true
+			last.endsLine = true
+		}
+		if kind == zscript.NodeStateLabel && childKind == ":" {
+			last.endsLine = true
+		}
+	}
+}
+
+func (c *collector) add(n, parent *tree_sitter.Node, field string, indent int, stateLine *lineContext) {
+	text := n.Utf8Text(c.source)
+	if text == "" {
+		return
+	}
+	kind := n.Kind()
+	if kind == zscript.NodeStateSpriteFrames {
+		text = strings.Join(strings.Fields(text), " ")
+	}
+	t := &token{
+		text:     text,
+		kind:     kind,
+		parent:   parent.Kind(),
+		field:    field,
+		comment:  n.IsExtra(),
+		startRow: n.StartPosition().Row,
+		endRow:   n.EndPosition().Row,
+		span:     Span{n.StartByte(), n.EndByte()},
+		indent:   indent,
+	}
+	if stateLine != nil && !t.comment {
+		t.stateLine = stateLine.id
+		t.statesID = c.states
+		switch {
+		case kind == zscript.NodeStateSpriteFrames:
+			t.role = roleSprite
+		case n.StartByte() >= stateLine.durStart && n.EndByte() <= stateLine.durEnd:
+			t.role = roleDuration
+		default:
+			t.role = roleRest
+		}
+	}
+	c.tokens = append(c.tokens, t)
+}
+
+func isStatementBody(kind string) bool {
+	switch kind {
+	case zscript.NodeIfStatement, zscript.NodeWhileStatement, zscript.NodeDoStatement,
+		zscript.NodeForStatement, zscript.NodeForeachStatement:
+		return true
+	}
+	return false
+}
+
+// braceMovable reports whether the braces of n follow the brace style.
+// Anonymous functions in state lines always keep their braces inline.
+func braceMovable(n *tree_sitter.Node) bool {
+	switch n.Kind() {
+	case zscript.NodeSourceFile:
+		return false
+	case zscript.NodeCompoundStatement:
+		if p := n.Parent(); p != nil && p.Kind() == zscript.NodeStateAction {
+			return false
+		}
+	}
+	return true
+}
+
+// alignStates computes the alignment columns of each States block.
+func (c *collector) alignStates() {
+	type widths struct{ sprite, duration int }
+	blocks := map[uintptr]*widths{}
+	lineWidths := func(tokens []*token) (sprite, duration int) {
+		var prev *token
+		for _, t := range tokens {
+			switch t.role {
+			case roleSprite:
+				sprite = len(t.text)
+			case roleDuration:
+				if prev != nil && prev.role == roleDuration && space(prev, t) {
+					duration++
+				}
+				duration += len(t.text)
+			}
+			prev = t
+		}
+		return sprite, duration
+	}
+
+	lines := c.stateLines()
+	for _, line := range lines {
+		sprite, duration := lineWidths(line)
+		w := blocks[line[0].statesID]
+		if w == nil {
+			w = &widths{}
+			blocks[line[0].statesID] = w
+		}
+		w.sprite = max(w.sprite, sprite)
+		w.duration = max(w.duration, duration)
+	}
+	for _, line := range lines {
+		w := blocks[line[0].statesID]
+		sawDuration, sawRest := false, false
+		for _, t := range line {
+			switch {
+			case t.role == roleDuration && !sawDuration:
+				sawDuration = true
+				t.alignTo = w.sprite + 1
+			case t.role == roleRest && sawDuration && !sawRest:
+				sawRest = true
+				if t.kind != ";" {
+					t.alignTo = w.sprite + 1 + w.duration + 1
+				}
+			}
+		}
+	}
+}
+
+// stateLines groups the tokens of each state line.
+func (c *collector) stateLines() [][]*token {
+	var lines [][]*token
+	for i := 0; i < len(c.tokens); {
+		t := c.tokens[i]
+		if t.stateLine == 0 {
+			i++
+			continue
+		}
+		j := i
+		for j < len(c.tokens) && (c.tokens[j].stateLine == t.stateLine || c.tokens[j].comment) {
+			j++
+		}
+		for j > i && c.tokens[j-1].comment {
+			j--
+		}
+		lines = append(lines, c.tokens[i:j])
+		i = j
+	}
+	return lines
+}
+
+// spacedColons are the parents of ":" tokens that get a space on both
+// sides.
+var spacedColons = map[string]bool{
+	zscript.NodeInheritanceSpecifier:  true,
+	zscript.NodeEnumBaseType:          true,
+	zscript.NodeConditionalExpression: true,
+	zscript.NodeForeachStatement:      true,
+}
+
+// tightParens are the parents whose "(" follows the preceding token
+// without a space, as in calls and declarations.
+var tightParens = map[string]bool{
+	zscript.NodeArgumentList:       true,
+	zscript.NodeParameterList:      true,
+	zscript.NodeStatesOptions:      true,
+	zscript.NodeRandomExpression:   true,
+	zscript.NodeGetclassExpression: true,
+	zscript.NodeStateExpression:    true,
+	zscript.NodeAlignofExpression:  true,
+	zscript.NodeMemberModifier:     true,
+	zscript.NodeClassFlag:          true,
+	zscript.NodeStructFlag:         true,
+	zscript.NodeStateModifier:      true,
+}
+
+var typeArguments = map[string]bool{
+	zscript.NodeArrayType:       true,
+	zscript.NodeMapType:         true,
+	zscript.NodeMapiteratorType: true,
+	zscript.NodeClassType:       true,
+	zscript.NodeReadonlyType:    true,
+}
+
+// space reports whether a space separates tokens a and b on the same line.
+func space(a, b *token) bool {
+	if a.comment || b.comment {
+		return true
+	}
+	switch b.kind {
+	case ")", "]", ",", ";", ".", "::":
+		return false
+	case ":":
+		return spacedColons[b.parent]
+	case "(":
+		if tightParens[b.parent] {
+			return false
+		}
+	case "[":
+		switch b.parent {
+		case zscript.NodeSubscriptExpression, zscript.NodeArrayDeclarator,
+			zscript.NodeRandomExpression, zscript.NodeStaticConstArray:
+			return false
+		}
+	case "<", ">":
+		if typeArguments[b.parent] {
+			return false
+		}
+	case "}":
+		if b.parent == zscript.NodeInitializerList {
+			return false
+		}
+	}
+	switch a.kind {
+	case "(", "[", ".", "::":
+		return false
+	case "<":
+		if typeArguments[a.parent] {
+			return false
+		}
+	case "{":
+		if a.parent == zscript.NodeInitializerList {
+			return false
+		}
+	case "-":
+		if a.parent == zscript.NodeStateLine {
+			return false
+		}
+	case ")":
+		if a.parent == zscript.NodeCastExpression {
+			return false
+		}
+	}
+	if a.field == zscript.FieldOperator {
+		switch a.parent {
+		case zscript.NodeUnaryExpression, zscript.NodeUpdateExpression, zscript.NodePointerExpression:
+			return false
+		}
+	}
+	if b.field == zscript.FieldOperator && b.parent == zscript.NodeUpdateExpression {
+		return false
+	}
+	if a.field == zscript.FieldSign && a.parent == zscript.NodeFlagStatement {
+		return false
+	}
+	if a.parent == zscript.NodeStateGotoTarget && a.kind == "+" || b.parent == zscript.NodeStateGotoTarget && b.kind == "+" {
+		return false
+	}
+	return true
+}
+
+type printer struct {
+	opts   Options
+	tokens []*token
+	buf    bytes.Buffer
+	// lineStart is the buffer offset just after the current line's
+	// indentation.
+	lineStart int
+	// baseIndent is the indentation of the line that began the current
+	// declaration or statement, which continuation lines are relative to.
+	baseIndent int
+	// pending is set when a line break was deferred past a trailing
+	// comment.
+	pending bool
+	// segments record where each token was written.
+	segments []Segment
+}
+
+func (p *printer) print() []byte {
+	var prev *token
+	for _, t := range p.tokens {
+		switch p.breakBefore(prev, t) {
+		case noBreak:
+			if prev != nil {
+				p.pad(prev, t)
+			}
+		case lineBreak:
+			p.newline(prev, t, t.indent)
+			p.baseIndent = t.indent
+		case continuation:
+			indent := max(t.indent, p.baseIndent+1)
+			if isClosing(t) {
+				indent = max(t.indent, p.baseIndent)
+			}
+			p.newline(prev, t, indent)
+		}
+		start := uint(p.buf.Len())
+		p.buf.WriteString(t.text)
+		p.segments = append(p.segments, Segment{Original: t.span, Formatted: Span{start, uint(p.buf.Len())}})
+		prev = t
+	}
+	if p.buf.Len() > 0 {
+		p.buf.WriteByte('\n')
+	}
+	return p.buf.Bytes()
+}
+
+type breakKind int
+
+const (
+	noBreak breakKind = iota
+	lineBreak
+	continuation
+)
+
+func (p *printer) breakBefore(prev, t *token) breakKind {
+	if prev == nil {
+		return noBreak
+	}
+	sourceBreak := t.startRow > prev.endRow
+	required := p.pending || prev.open && !prev.empty || prev.endsLine
+	p.pending = false
+
+	switch {
+	case prev.comment && strings.HasPrefix(prev.text, "//"):
+		return lineBreak
+	case t.comment:
+		if sourceBreak {
+			return lineBreak
+		}
+		// A trailing comment stays on its line, and the break that was due
+		// after the previous token moves after the comment.
+		p.pending = required
+		return noBreak
+	case required, t.close && !prev.empty:
+		return lineBreak
+	case t.open && t.nextLineBrace && !t.empty:
+		if p.opts.BraceStyle == BraceNextLine {
+			return lineBreak
+		}
+		// A brace on a line of its own moves up to the line before.
+		return noBreak
+	case t.hang:
+		if sourceBreak {
+			return lineBreak
+		}
+		return noBreak
+	case t.kind == "else" || t.parent == zscript.NodeDoStatement && t.kind == "while":
+		if prev.kind != "}" || p.opts.BraceStyle == BraceNextLine {
+			return lineBreak
+		}
+		return noBreak
+	case sourceBreak && prev.comment:
+		return lineBreak
+	case sourceBreak && !noBreakBefore(t):
+		return continuation
+	}
+	return noBreak
+}
+
+// noBreakBefore reports whether t must stay on the line of the token
+// before it.
+func noBreakBefore(t *token) bool {
+	switch t.kind {
+	case ";", ",":
+		return true
+	}
+	return false
+}
+
+func isClosing(t *token) bool {
+	switch t.kind {
+	case ")", "]", "}":
+		return true
+	}
+	return false
+}
+
+func (p *printer) newline(prev, t *token, indent int) {
+	p.buf.WriteByte('\n')
+	if t.startRow > prev.endRow+1 && !prev.open && !t.close {
+		p.buf.WriteByte('\n')
+	}
+	if p.opts.UseSpaces {
+		p.buf.WriteString(strings.Repeat(" ", indent*p.opts.IndentWidth))
+	} else {
+		p.buf.WriteString(strings.Repeat("\t", indent))
+	}
+	p.lineStart = p.buf.Len()
+}
+
+// pad writes the space between two tokens on the same line, aligning
+// state line columns where requested.
+func (p *printer) pad(prev, t *token) {
+	if t.alignTo > 0 {
+		if n := t.alignTo - (p.buf.Len() - p.lineStart); n > 0 {
+			p.buf.WriteString(strings.Repeat(" ", n))
+			return
+		}
+	}
+	if space(prev, t) {
+		p.buf.WriteByte(' ')
+	}
+}
@@ -0,0 +1,356 @@
+package format_test
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+
+	zscript "github.com/jlcrochet/tree-sitter-zscript/bindings/go"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/format"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/internal/corpus"
+)
+
+const input = `class MyImp:DoomImp replaces DoomImp{
+int x,y[ 3 ]; // trailing
+Default{
+Health 100;+NOGRAVITY;
+}
+
+override void Tick(){Super.Tick();if(x>1)x++;else{x=(int)(y)+-1;}}
+States{
+Spawn:
+TROO AB 10 A_Look;
+TROO    C -1 Bright;
+Loop;
+See:
+TROO AABBCCDD random(1,3) A_Chase;
+Goto Spawn+2;
+}
+}
+`
+
+const sameLine = `class MyImp : DoomImp replaces DoomImp {
+	int x, y[3]; // trailing
+	Default {
+		Health 100;
+		+NOGRAVITY;
+	}
+
+	override void Tick() {
+		Super.Tick();
+		if (x > 1) x++;
+		else {
+			x = (int)(y) + -1;
+		}
+	}
+	States {
+		Spawn:
+			TROO AB       10           A_Look;
+			TROO C        -1           Bright;
+			Loop;
+		See:
+			TROO AABBCCDD random(1, 3) A_Chase;
+			Goto Spawn+2;
+	}
+}
+`
+
+const nextLine = `class MyImp : DoomImp replaces DoomImp
+{
+  int x, y[3]; // trailing
+  Default
+  {
+    Health 100;
+    +NOGRAVITY;
+  }
+
+  override void Tick()
+  {
+    Super.Tick();
+    if (x > 1) x++;
+    else
+    {
+      x = (int)(y) + -1;
+    }
+  }
+  States
+  {
+    Spawn:
+      TROO AB 10 A_Look;
+      TROO C -1 Bright;
+      Loop;
+    See:
+      TROO AABBCCDD random(1, 3) A_Chase;
+      Goto Spawn+2;
+  }
+}
+`
+
+func TestSource(t *testing.T) {
+	got, err := format.Source([]byte(input), format.DefaultOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != sameLine {
+		t.Errorf("Source() =\n%s\nwant\n%s", got, sameLine)
+	}
+
+	opts := format.Options{UseSpaces: true, IndentWidth: 2, BraceStyle: format.BraceNextLine}
+	got, err = format.Source([]byte(input), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != nextLine {
+		t.Errorf("Source() =\n%s\nwant\n%s", got, nextLine)
+	}
+
+	// Braces on lines of their own move up.
+	got, err = format.Source([]byte(nextLine), format.DefaultOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != sameLine {
+		t.Errorf("Source() =\n%s\nwant\n%s", got, sameLine)
+	}
+}
+
+func TestTreeWithMap(t *testing.T) {
+	tree, err := zscript.Parse(context.Background(), []byte(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tree.Close()
+	got, m, err := format.TreeWithMap(tree, format.DefaultOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != sameLine {
+		t.Fatalf("TreeWithMap() =\n%s\nwant\n%s", got, sameLine)
+	}
+	for _, text := range []string{"MyImp", "// trailing", "Super.Tick", "(int)(y)", "A_Chase", "Spawn+2"} {
+		from := uint(strings.Index(input, text))
+		to := uint(strings.Index(sameLine, text))
+		if got := m.ToFormatted(from); got != to {
+			t.Errorf("ToFormatted(%d) for %q = %d, want %d", from, text, got, to)
+		}
+		if got := m.ToOriginal(to); got != from {
+			t.Errorf("ToOriginal(%d) for %q = %d, want %d", to, text, got, from)
+		}
+		r := m.FormattedRange(tree_sitter.Range{StartByte: from, EndByte: from + uint(len(text))})
+		if s := sameLine[r.StartByte:r.EndByte]; s != text {
+			t.Errorf("FormattedRange for %q spans %q", text, s)
+		}
+		lines := strings.Split(sameLine[:r.StartByte], "\n")
+		if r.StartPoint.Row != uint(len(lines)-1) || r.StartPoint.Column != uint(len(lines[len(lines)-1])) {
+			t.Errorf("FormattedRange for %q starts at %v", text, r.StartPoint)
+		}
+	}
+
+	// Whitespace maps to the next token, or the previous one for the end
+	// of a range.
+	gap := uint(strings.Index(input, ":DoomImp"))
+	if got, want := m.ToFormatted(gap), uint(strings.Index(sameLine, ":")); got != want {
+		t.Errorf("ToFormatted(%d) = %d, want %d", gap, got, want)
+	}
+	space := uint(strings.Index(input, " 3 ]"))
+	r := m.FormattedRange(tree_sitter.Range{StartByte: space, EndByte: space + 3})
+	if s := sameLine[r.StartByte:r.EndByte]; s != "3" {
+		t.Errorf("FormattedRange of a gap spans %q", s)
+	}
+}
+
+func TestSyntaxError(t *testing.T) {
+	if _, err := format.Source([]byte("class A {"), format.DefaultOptions()); err == nil {
+		t.Error("Source() accepted a syntax error")
+	}
+}
+
+// structure lists the nodes of the tree of source in document order, one
+// line per node with its depth, field name, kind and, for leaves, text
+// with runs of whitespace collapsed. Comments are left out, since the
+// formatter may move them between lines, and signed numbers are listed as
+// literals. Two sources with the
+// same structure differ only in layout.
+func structure(t *testing.T, source []byte) []string {
+	t.Helper()
+	tree, err := zscript.Parse(context.Background(), source)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tree.Close()
+
+	var lines []string
+	cursor := tree.Walk()
+	defer cursor.Close()
+	depth := 0
+	for {
+		node := cursor.Node()
+		if n, ok := signedNumber(node, source); ok {
+			lines = append(lines, fmt.Sprintf("%*s%s:%s %q", 2*depth, "", cursor.FieldName(), zscript.NodeNumberLiteral, n))
+		} else if node.Kind() != zscript.NodeComment {
+			line := fmt.Sprintf("%*s%s:%s", 2*depth, "", cursor.FieldName(), node.Kind())
+			if node.ChildCount() == 0 {
+				// Some tokens, such as the sprite and frames of a state,
+				// contain spaces that the formatter normalizes.
+				line += " " + strconv.Quote(strings.Join(strings.Fields(node.Utf8Text(source)), " "))
+			}
+			if node.IsMissing() {
+				line += " (missing)"
+			}
+			lines = append(lines, line)
+			if cursor.GotoFirstChild() {
+				depth++
+				continue
+			}
+		}
+		for !cursor.GotoNextSibling() {
+			if !cursor.GotoParent() {
+				return lines
+			}
+			depth--
+		}
+	}
+}
+
+// signedNumber reports whether node is a sign applied to a number, such
+// as "- 1", and returns the number as one literal. The lexer reads "-1" as
+// a single literal, so removing the space changes the tree but not the
+// meaning.
+func signedNumber(node *tree_sitter.Node, source []byte) (string, bool) {
+	if node.Kind() != zscript.NodeUnaryExpression || node.ChildCount() != 2 {
+		return "", false
+	}
+	op, arg := node.Child(0), node.Child(1)
+	switch op.Kind() {
+	case "+", "-":
+	default:
+		return "", false
+	}
+	n := arg.Utf8Text(source)
+	if arg.Kind() != zscript.NodeNumberLiteral || strings.HasPrefix(n, "+") || strings.HasPrefix(n, "-") {
+		return "", false
+	}
+	return op.Kind() + n, true
+}
+
+// comments returns the text of the comments of source, in order.
+func comments(t *testing.T, source []byte) []string {
+	t.Helper()
+	tree, err := zscript.Parse(context.Background(), source)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tree.Close()
+	var result []string
+	var v zscript.Visitor
+	v.On(zscript.NodeComment, func(node *tree_sitter.Node) zscript.WalkAction {
+		result = append(result, strings.TrimSpace(node.Utf8Text(source)))
+		return zscript.WalkSkipChildren
+	})
+	zscript.Walk(tree.RootNode(), &v)
+	return result
+}
+
+// roundTrip formats source with opts, checks that the result parses into
+// the same structure with the same comments and that formatting it again
+// changes nothing, and returns the formatted text. Sources the formatter
+// rejects are skipped.
+func roundTrip(t *testing.T, source []byte, opts format.Options) []byte {
+	t.Helper()
+	once, err := format.Source(source, opts)
+	if err != nil {
+		t.Skip(err)
+	}
+	twice, err := format.Source(once, opts)
+	if err != nil {
+		t.Fatalf("%v in\n%s", err, once)
+	}
+	if string(once) != string(twice) {
+		t.Errorf("not idempotent:\n%s\nthen\n%s", once, twice)
+	}
+	before, after := structure(t, source), structure(t, once)
+	for i := range max(len(before), len(after)) {
+		if i >= len(before) || i >= len(after) || before[i] != after[i] {
+			t.Errorf("tree changed at node %d:\n%s\n%s\nformatted:\n%s", i, at(before, i), at(after, i), once)
+			break
+		}
+	}
+	if before, after := comments(t, source), comments(t, once); !slices.Equal(before, after) {
+		t.Errorf("comments changed:\n%q\n%q\nformatted:\n%s", before, after, once)
+	}
+	return once
+}
+
+// at returns the lines of nodes around i, for error messages.
+func at(nodes []string, i int) string {
+	return strings.Join(nodes[max(0, i-3):min(len(nodes), i+2)], "\n")
+}
+
+// TestCorpus checks that formatting each corpus example preserves its tree
+// and is idempotent.
+func TestCorpus(t *testing.T) {
+	examples, err := corpus.Load("../../../test/corpus")
+	if err != nil || len(examples) == 0 {
+		t.Fatalf("no corpus examples: %v", err)
+	}
+	for _, e := range examples {
+		t.Run(e.File+"/"+e.Name, func(t *testing.T) {
+			for _, opts := range []format.Options{format.DefaultOptions(), {BraceStyle: format.BraceNextLine}} {
+				roundTrip(t, []byte(e.Input), opts)
+			}
+		})
+	}
+}
+
+// TestCorpusFiles formats the examples of each corpus file joined into one
+// source, so that the layout between declarations is exercised too.
+func TestCorpusFiles(t *testing.T) {
+	examples, err := corpus.Load("../../../test/corpus")
+	if err != nil || len(examples) == 0 {
+		t.Fatalf("no corpus examples: %v", err)
+	}
+	files := map[string][]string{}
+	var names []string
+	for _, e := range examples {
+		if e.HasAttribute("error") || e.HasAttribute("skip") {
+			continue
+		}
+		if files[e.File] == nil {
+			names = append(names, e.File)
+		}
+		files[e.File] = append(files[e.File], e.Input)
+	}
+	for _, name := range names {
+		t.Run(name, func(t *testing.T) {
+			source := []byte(strings.Join(files[name], "\n\n"))
+			if _, err := format.Source(source, format.DefaultOptions()); err != nil {
+				t.Fatalf("joined examples do not format: %v", err)
+			}
+			roundTrip(t, source, format.DefaultOptions())
+		})
+	}
+}
+
+// FuzzFormat checks the round trip of TestCorpus on arbitrary sources that
+// parse without errors.
+func FuzzFormat(f *testing.F) {
+	examples, err := corpus.Load("../../../test/corpus")
+	if err != nil {
+		f.Fatal(err)
+	}
+	for _, e := range examples {
+		f.Add(e.Input, false)
+	}
+	f.Fuzz(func(t *testing.T, source string, nextLine bool) {
+		opts := format.DefaultOptions()
+		if nextLine {
+			opts.BraceStyle = format.BraceNextLine
+		}
+		roundTrip(t, []byte(source), opts)
+	})
+}
@@ -0,0 +1,112 @@
+package format
+
+import (
+	"sort"
+
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/positions"
+)
+
+// Span is the range of bytes [Start, End).
+type Span struct {
+	Start, End uint
+}
+
+// Segment pairs the span of a token in the original source with the span
+// it was written to in the output.
+type Segment struct {
+	Original, Formatted Span
+}
+
+// SourceMap maps positions between the source given to TreeWithMap and
+// its output, so that ranges computed on one can be shown on the other.
+//
+// Every token, including comments, has a segment. A position inside a
+// token maps to the same position in its copy; one in the whitespace
+// between tokens maps to the start of the next token, or for the end of
+// a range, to the end of the previous token.
+type SourceMap struct {
+	// Segments are in the order of both the original and the output.
+	Segments []Segment
+
+	original, formatted *positions.Index
+}
+
+// ToFormatted returns the output offset of the original source offset.
+func (m *SourceMap) ToFormatted(offset uint) uint {
+	return m.mapOffset(offset, false, true)
+}
+
+// ToOriginal returns the original source offset of the output offset.
+func (m *SourceMap) ToOriginal(offset uint) uint {
+	return m.mapOffset(offset, false, false)
+}
+
+// FormattedRange returns the range of the output that corresponds to r,
+// a range of the original source.
+func (m *SourceMap) FormattedRange(r tree_sitter.Range) tree_sitter.Range {
+	return m.mapRange(r, true)
+}
+
+// OriginalRange returns the range of the original source that
+// corresponds to r, a range of the output.
+func (m *SourceMap) OriginalRange(r tree_sitter.Range) tree_sitter.Range {
+	return m.mapRange(r, false)
+}
+
+func (m *SourceMap) mapRange(r tree_sitter.Range, toFormatted bool) tree_sitter.Range {
+	start := m.mapOffset(r.StartByte, false, toFormatted)
+	end := max(start, m.mapOffset(r.EndByte, true, toFormatted))
+	text := m.original
+	if toFormatted {
+		text = m.formatted
+	}
+	return tree_sitter.Range{
+		StartByte:  start,
+		EndByte:    end,
+		StartPoint: text.Point(start),
+		EndPoint:   text.Point(end),
+	}
+}
+
+// mapOffset maps offset in the original source, or in the output if
+// toFormatted is false, to the other. isEnd selects how an offset between
+// tokens is mapped.
+func (m *SourceMap) mapOffset(offset uint, isEnd, toFormatted bool) uint {
+	from := func(s Segment) Span {
+		if toFormatted {
+			return s.Original
+		}
+		return s.Formatted
+	}
+	to := func(s Segment) Span {
+		if toFormatted {
+			return s.Formatted
+		}
+		return s.Original
+	}
+	segs := m.Segments
+	// prev is the last segment that contains offset or ends before it.
+	// The end of a range lies in the segment it closes, and a start in
+	// the one it opens.
+	i := sort.Search(len(segs), func(i int) bool {
+		if isEnd {
+			return from(segs[i]).Start >= offset
+		}
+		return from(segs[i]).Start > offset
+	})
+	if i > 0 {
+		f, t := from(segs[i-1]), to(segs[i-1])
+		if offset < f.End || isEnd && offset == f.End {
+			return min(t.Start+(offset-f.Start), t.End)
+		}
+		if isEnd || i == len(segs) {
+			return t.End
+		}
+	}
+	if i < len(segs) {
+		return to(segs[i]).Start
+	}
+	return 0
+}
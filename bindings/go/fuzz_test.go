@@ -0,0 +1,133 @@
+package tree_sitter_zscript_test
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"testing"
+	"time"
+
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+
+	tree_sitter_zscript "github.com/jlcrochet/tree-sitter-zscript/bindings/go"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/internal/corpus"
+)
+
+// fuzzTimeout bounds a single parse; taking longer is reported as a hang.
+const fuzzTimeout = 10 * time.Second
+
+func addCorpusSeeds(f *testing.F, add func(input string)) {
+	examples, err := corpus.Load("../../test/corpus")
+	if err != nil {
+		f.Fatal(err)
+	}
+	for _, e := range examples {
+		add(e.Input)
+	}
+}
+
+func fuzzParse(t *testing.T, source []byte) *tree_sitter_zscript.Tree {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), fuzzTimeout)
+	defer cancel()
+	tree, err := tree_sitter_zscript.Parse(ctx, source)
+	if errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("parse did not finish within %v", fuzzTimeout)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tree
+}
+
+// FuzzParse checks that any input parses in bounded time into a tree whose
+// nodes have consistent ranges.
+func FuzzParse(f *testing.F) {
+	addCorpusSeeds(f, func(input string) { f.Add([]byte(input)) })
+	f.Fuzz(func(t *testing.T, source []byte) {
+		tree := fuzzParse(t, source)
+		defer tree.Close()
+		checkRanges(t, tree.RootNode(), source)
+		tree_sitter_zscript.Diagnostics(tree.Tree, source)
+	})
+}
+
+// FuzzReparse checks that reparsing an edited tree gives the same result
+// as parsing the edited text from scratch, when both are free of errors.
+func FuzzReparse(f *testing.F) {
+	addCorpusSeeds(f, func(input string) {
+		f.Add([]byte(input), uint(len(input)/3), uint(len(input)/2), "x")
+	})
+	f.Fuzz(func(t *testing.T, source []byte, start, end uint, text string) {
+		start, end = min(start, uint(len(source))), min(end, uint(len(source)))
+		if start > end {
+			start, end = end, start
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), fuzzTimeout)
+		defer cancel()
+		doc, err := tree_sitter_zscript.NewIncrementalDocument(ctx, source)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer doc.Close()
+		if err := doc.EditBytes(start, end, text); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := doc.Reparse(ctx); err != nil {
+			t.Fatal(err)
+		}
+		edited := doc.Tree()
+		checkRanges(t, edited.RootNode(), edited.Source)
+
+		fresh := fuzzParse(t, edited.Source)
+		defer fresh.Close()
+		if edited.RootNode().HasError() || fresh.RootNode().HasError() {
+			return
+		}
+		if got, want := edited.RootNode().ToSexp(), fresh.RootNode().ToSexp(); got != want {
+			t.Errorf("reparsed tree differs from a fresh parse:\n%s\nwant:\n%s", got, want)
+		}
+	})
+}
+
+// checkRanges checks that every node lies within its parent and the
+// source, that siblings do not overlap, and that the points of each node
+// agree with its byte offsets.
+func checkRanges(t *testing.T, root *tree_sitter.Node, source []byte) {
+	t.Helper()
+	lines := []uint{0}
+	for i, b := range source {
+		if b == '\n' {
+			lines = append(lines, uint(i+1))
+		}
+	}
+	point := func(offset uint) tree_sitter.Point {
+		row := uint(sort.Search(len(lines), func(i int) bool { return lines[i] > offset }) - 1)
+		return tree_sitter.Point{Row: row, Column: offset - lines[row]}
+	}
+
+	var check func(node *tree_sitter.Node)
+	check = func(node *tree_sitter.Node) {
+		start, end := node.StartByte(), node.EndByte()
+		if start > end || end > uint(len(source)) {
+			t.Fatalf("%s spans [%d, %d) in %d bytes", node.Kind(), start, end, len(source))
+		}
+		if got, want := node.StartPosition(), point(start); got != want {
+			t.Fatalf("%s starts at byte %d but point %v, want %v", node.Kind(), start, got, want)
+		}
+		if got, want := node.EndPosition(), point(end); got != want {
+			t.Fatalf("%s ends at byte %d but point %v, want %v", node.Kind(), end, got, want)
+		}
+		prev := start
+		for i := uint(0); i < node.ChildCount(); i++ {
+			child := node.Child(i)
+			if child.StartByte() < prev || child.EndByte() > end {
+				t.Fatalf("%s [%d, %d) is not within its parent %s [%d, %d) after its previous sibling ending at %d",
+					child.Kind(), child.StartByte(), child.EndByte(), node.Kind(), start, end, prev)
+			}
+			prev = child.EndByte()
+			check(child)
+		}
+	}
+	check(root)
+}
@@ -0,0 +1,269 @@
+// Package hierarchy builds the class inheritance graph of a ZScript project
+// from the symbol tables of its files.
+package hierarchy
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/symbols"
+)
+
+// Class is a node in the inheritance graph.
+type Class struct {
+	Name string
+	// Decl is the class definition, or nil for classes that are referenced
+	// but not defined in the project, such as engine classes.
+	Decl *symbols.Class
+	// Path is the file that defines the class.
+	Path string
+	// Extensions are the "extend class" blocks for this class.
+	Extensions []*symbols.Class
+
+	Parent     *Class
+	Replaces   *Class
+	Children   []*Class
+	ReplacedBy []*Class
+}
+
+// Defined reports whether the class is defined in the project.
+func (c *Class) Defined() bool {
+	return c.Decl != nil
+}
+
+// Hierarchy is the inheritance graph of a set of files. Class names are
+// matched case-insensitively, as in ZScript.
+type Hierarchy struct {
+	classes map[string]*Class
+	decls   []decl
+	// parents are the parents of classes known by name only, by
+	// lowercased name.
+	parents map[string]string
+	dirty   bool
+}
+
+type decl struct {
+	class *symbols.Class
+	path  string
+}
+
+// rootClass is the implicit parent of classes declared without one.
+const rootClass = "Object"
+
+// New returns an empty hierarchy.
+func New() *Hierarchy {
+	return &Hierarchy{classes: map[string]*Class{}}
+}
+
+// Build returns the hierarchy of the given files.
+func Build(tables ...*symbols.Table) *Hierarchy {
+	h := New()
+	for _, t := range tables {
+		h.Add(t)
+	}
+	return h
+}
+
+// Add adds the classes defined in table.
+func (h *Hierarchy) Add(table *symbols.Table) {
+	for _, c := range table.Classes {
+		h.decls = append(h.decls, decl{c, table.Path})
+	}
+	h.dirty = true
+}
+
+// AddParents records the parents of classes that are not defined by any
+// table but known by name, such as the game actors of the engine, so
+// that the ancestry of the classes deriving from them resolves. The
+// classes stay undefined and enter the graph only as ancestors of the
+// classes added; a definition of a class of the same name takes
+// precedence.
+func (h *Hierarchy) AddParents(parents map[string]string) {
+	if h.parents == nil {
+		h.parents = map[string]string{}
+	}
+	for name, parent := range parents {
+		h.parents[strings.ToLower(name)] = parent
+	}
+	h.dirty = true
+}
+
+func (h *Hierarchy) node(name string) *Class {
+	key := strings.ToLower(name)
+	c := h.classes[key]
+	if c == nil {
+		c = &Class{Name: name}
+		h.classes[key] = c
+	}
+	return c
+}
+
+// link rebuilds the graph edges from the added declarations.
+func (h *Hierarchy) link() {
+	if !h.dirty {
+		return
+	}
+	h.dirty = false
+	h.classes = map[string]*Class{}
+
+	// Definitions first, so that nodes take the spelling of the
+	// definition rather than of the first reference.
+	for _, d := range h.decls {
+		if d.class.Extend || d.class.Mixin {
+			continue
+		}
+		c := h.node(d.class.Name)
+		if c.Decl == nil {
+			c.Name = d.class.Name
+			c.Decl = d.class
+			c.Path = d.path
+		}
+	}
+	for _, d := range h.decls {
+		switch {
+		case d.class.Extend:
+			c := h.node(d.class.Name)
+			c.Extensions = append(c.Extensions, d.class)
+		case d.class.Mixin:
+			// Mixins are not part of the inheritance graph.
+		default:
+			c := h.node(d.class.Name)
+			if c.Decl != d.class {
+				continue
+			}
+			switch parent := d.class.Parent; {
+			case parent != "":
+				c.Parent = h.node(parent)
+			case !strings.EqualFold(c.Name, rootClass):
+				c.Parent = h.node(rootClass)
+			}
+			if c.Parent != nil {
+				c.Parent.Children = append(c.Parent.Children, c)
+			}
+			if d.class.Replaces != "" {
+				c.Replaces = h.node(d.class.Replaces)
+				c.Replaces.ReplacedBy = append(c.Replaces.ReplacedBy, c)
+			}
+		}
+	}
+	if len(h.parents) > 0 {
+		work := make([]*Class, 0, len(h.classes))
+		for _, c := range h.classes {
+			work = append(work, c)
+		}
+		for len(work) > 0 {
+			c := work[len(work)-1]
+			work = work[:len(work)-1]
+			parent, ok := h.parents[strings.ToLower(c.Name)]
+			if c.Decl != nil || c.Parent != nil || !ok {
+				continue
+			}
+			c.Parent = h.node(parent)
+			c.Parent.Children = append(c.Parent.Children, c)
+			work = append(work, c.Parent)
+		}
+	}
+	for _, c := range h.classes {
+		sortByName(c.Children)
+		sortByName(c.ReplacedBy)
+	}
+}
+
+// Class returns the named class, or nil if it is neither defined nor
+// referenced.
+func (h *Hierarchy) Class(name string) *Class {
+	h.link()
+	return h.classes[strings.ToLower(name)]
+}
+
+// Classes returns every class in the graph, sorted by name.
+func (h *Hierarchy) Classes() []*Class {
+	h.link()
+	classes := make([]*Class, 0, len(h.classes))
+	for _, c := range h.classes {
+		classes = append(classes, c)
+	}
+	sortByName(classes)
+	return classes
+}
+
+// Subclasses returns every direct and indirect subclass of the named
+// class, in depth-first order.
+func (h *Hierarchy) Subclasses(name string) []*Class {
+	c := h.Class(name)
+	if c == nil {
+		return nil
+	}
+	var result []*Class
+	seen := map[*Class]bool{c: true}
+	var visit func(*Class)
+	visit = func(c *Class) {
+		for _, child := range c.Children {
+			if seen[child] {
+				continue
+			}
+			seen[child] = true
+			result = append(result, child)
+			visit(child)
+		}
+	}
+	visit(c)
+	return result
+}
+
+// Ancestors returns the parents of the named class, nearest first. A cycle
+// in the graph ends the list.
+func (h *Hierarchy) Ancestors(name string) []*Class {
+	c := h.Class(name)
+	if c == nil {
+		return nil
+	}
+	var result []*Class
+	seen := map[*Class]bool{c: true}
+	for p := c.Parent; p != nil && !seen[p]; p = p.Parent {
+		seen[p] = true
+		result = append(result, p)
+	}
+	return result
+}
+
+// IsSubclassOf reports whether class inherits, directly or indirectly, from
+// ancestor.
+func (h *Hierarchy) IsSubclassOf(class, ancestor string) bool {
+	for _, a := range h.Ancestors(class) {
+		if strings.EqualFold(a.Name, ancestor) {
+			return true
+		}
+	}
+	return false
+}
+
+// Replacements returns the classes that replace the named class.
+func (h *Hierarchy) Replacements(name string) []*Class {
+	if c := h.Class(name); c != nil {
+		return c.ReplacedBy
+	}
+	return nil
+}
+
+// Cycles returns the classes that are their own ancestor.
+func (h *Hierarchy) Cycles() []*Class {
+	var result []*Class
+	for _, c := range h.Classes() {
+		seen := map[*Class]bool{}
+		for p := c.Parent; p != nil && !seen[p]; p = p.Parent {
+			if p == c {
+				result = append(result, c)
+				break
+			}
+			seen[p] = true
+		}
+	}
+	return result
+}
+
+func sortByName(classes []*Class) {
+	sort.Slice(classes, func(i, j int) bool {
+		return strings.ToLower(classes[i].Name) < strings.ToLower(classes[j].Name)
+	})
+}
@@ -0,0 +1,98 @@
+package hierarchy_test
+
+import (
+	"context"
+	"testing"
+
+	zscript "github.com/jlcrochet/tree-sitter-zscript/bindings/go"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/hierarchy"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/symbols"
+)
+
+func extract(t *testing.T, path, source string) *symbols.Table {
+	t.Helper()
+	tree, err := zscript.Parse(context.Background(), []byte(source))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tree.Close()
+	tree.Path = path
+	return symbols.Extract(tree)
+}
+
+func names(classes []*hierarchy.Class) []string {
+	var result []string
+	for _, c := range classes {
+		result = append(result, c.Name)
+	}
+	return result
+}
+
+func TestHierarchy(t *testing.T) {
+	h := hierarchy.Build(
+		extract(t, "weapons.zs", `
+			class MyPlasmaRifle : PlasmaRifle replaces PlasmaRifle {}
+			class MyBFG : Weapon replaces BFG9000 {}
+		`),
+		extract(t, "base.zs", `
+			class PlasmaRifle : Weapon {}
+			extend class Weapon { int extra; }
+			class Helper {}
+		`),
+	)
+
+	if got := names(h.Subclasses("weapon")); len(got) != 3 || got[0] != "MyBFG" || got[1] != "PlasmaRifle" || got[2] != "MyPlasmaRifle" {
+		t.Errorf("Subclasses(\"weapon\") = %v", got)
+	}
+	if got := names(h.Ancestors("MyPlasmaRifle")); len(got) != 2 || got[0] != "PlasmaRifle" || got[1] != "Weapon" {
+		t.Errorf("Ancestors(\"MyPlasmaRifle\") = %v", got)
+	}
+	if !h.IsSubclassOf("MyPlasmaRifle", "WEAPON") || h.IsSubclassOf("Weapon", "PlasmaRifle") {
+		t.Error("IsSubclassOf gave the wrong answer")
+	}
+	if got := names(h.Replacements("PlasmaRifle")); len(got) != 1 || got[0] != "MyPlasmaRifle" {
+		t.Errorf("Replacements(\"PlasmaRifle\") = %v", got)
+	}
+
+	weapon := h.Class("Weapon")
+	if weapon.Defined() || len(weapon.Extensions) != 1 {
+		t.Errorf("Weapon: Defined() = %v, %d extensions", weapon.Defined(), len(weapon.Extensions))
+	}
+	if helper := h.Class("Helper"); helper.Parent == nil || helper.Parent.Name != "Object" || helper.Path != "base.zs" {
+		t.Errorf("Helper: Parent = %v, Path = %q", helper.Parent, helper.Path)
+	}
+	if bfg := h.Class("BFG9000"); bfg == nil || bfg.Defined() {
+		t.Errorf("BFG9000 = %+v", bfg)
+	}
+}
+
+func TestCycles(t *testing.T) {
+	h := hierarchy.Build(extract(t, "a.zs", `class A : B {} class B : A {}`))
+	if got := names(h.Cycles()); len(got) != 2 {
+		t.Errorf("Cycles() = %v", got)
+	}
+	if got := h.Ancestors("A"); len(got) != 1 {
+		t.Errorf("Ancestors(\"A\") = %v", names(got))
+	}
+}
+
+func TestAddParents(t *testing.T) {
+	h := hierarchy.Build(extract(t, "imp.zs", `
+		class MyImp : DoomImp {}
+		class Zombie : ZombieMan {}
+		class Actor {}
+	`))
+	h.AddParents(map[string]string{"doomimp": "Actor", "ZombieMan": "Actor", "Zombie": "Object", "Clip": "Ammo"})
+	if got := names(h.Ancestors("MyImp")); len(got) != 3 || got[0] != "DoomImp" || got[1] != "Actor" || got[2] != "Object" {
+		t.Errorf("Ancestors(\"MyImp\") = %v", got)
+	}
+	if imp := h.Class("DoomImp"); imp.Defined() {
+		t.Error("DoomImp is defined")
+	}
+	if got := names(h.Ancestors("Zombie")); len(got) != 3 || got[0] != "ZombieMan" {
+		t.Errorf("Ancestors(\"Zombie\") = %v, want the parent of the definition", got)
+	}
+	if h.Class("Clip") != nil {
+		t.Error("Clip entered the graph without being referenced")
+	}
+}
@@ -0,0 +1,262 @@
+// Package highlight renders ZScript source as syntax-highlighted HTML,
+// using the grammar's highlights query, so that documentation and wiki
+// generators can highlight code without running the tree-sitter CLI.
+package highlight
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"html"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+
+	zscript "github.com/jlcrochet/tree-sitter-zscript/bindings/go"
+)
+
+// Theme maps highlight captures to CSS. The output uses inline styles, so
+// it needs no stylesheet.
+type Theme struct {
+	// Base styles the whole block, such as its colors and font.
+	Base string
+	// LineNumbers styles the line numbers.
+	LineNumbers string
+	// Captures maps capture names, such as "keyword.return", to CSS
+	// declarations. A capture without an entry uses the entry of its
+	// longest dotted prefix, so "keyword" also styles "keyword.return";
+	// a capture with no entry at all is left unstyled.
+	Captures map[string]string
+}
+
+// Style returns the CSS for capture, or "".
+func (t Theme) Style(capture string) string {
+	for {
+		if style, ok := t.Captures[capture]; ok {
+			return style
+		}
+		i := strings.LastIndexByte(capture, '.')
+		if i < 0 {
+			return ""
+		}
+		capture = capture[:i]
+	}
+}
+
+const (
+	baseStyle       = "font-family:monospace;tab-size:4;padding:0.5em;overflow-x:auto;"
+	lineNumberStyle = "user-select:none;padding-right:1em;"
+)
+
+// Light is a theme for light backgrounds.
+var Light = Theme{
+	Base:        baseStyle + "background:#ffffff;color:#24292f",
+	LineNumbers: lineNumberStyle + "color:#8c959f",
+	Captures: map[string]string{
+		"attribute":        "color:#953800",
+		"boolean":          "color:#0550ae",
+		"comment":          "color:#6e7781;font-style:italic",
+		"constant":         "color:#0550ae",
+		"function":         "color:#8250df",
+		"keyword":          "color:#cf222e",
+		"label":            "color:#953800;font-weight:bold",
+		"number":           "color:#0550ae",
+		"operator":         "color:#cf222e",
+		"property":         "color:#0550ae",
+		"punctuation":      "color:#57606a",
+		"string":           "color:#0a3069",
+		"string.escape":    "color:#116329",
+		"string.special":   "color:#116329",
+		"type":             "color:#953800",
+		"type.builtin":     "color:#cf222e",
+		"variable.builtin": "color:#0550ae",
+	},
+}
+
+// Dark is a theme for dark backgrounds.
+var Dark = Theme{
+	Base:        baseStyle + "background:#0d1117;color:#c9d1d9",
+	LineNumbers: lineNumberStyle + "color:#6e7681",
+	Captures: map[string]string{
+		"attribute":        "color:#ffa657",
+		"boolean":          "color:#79c0ff",
+		"comment":          "color:#8b949e;font-style:italic",
+		"constant":         "color:#79c0ff",
+		"function":         "color:#d2a8ff",
+		"keyword":          "color:#ff7b72",
+		"label":            "color:#ffa657;font-weight:bold",
+		"number":           "color:#79c0ff",
+		"operator":         "color:#ff7b72",
+		"property":         "color:#79c0ff",
+		"punctuation":      "color:#8b949e",
+		"string":           "color:#a5d6ff",
+		"string.escape":    "color:#7ee787",
+		"string.special":   "color:#7ee787",
+		"type":             "color:#ffa657",
+		"type.builtin":     "color:#ff7b72",
+		"variable.builtin": "color:#79c0ff",
+	},
+}
+
+// Span is a run of source bytes with one highlight.
+type Span struct {
+	StartByte, EndByte uint
+	// Capture is the capture name, such as "keyword", or "" for text
+	// the query does not capture.
+	Capture string
+}
+
+var highlightsQuery = sync.OnceValue(func() *zscript.CachedQuery {
+	return zscript.MustQuery(string(zscript.HighlightsQuery()))
+})
+
+// Spans divides source, the text of tree, into highlighted runs that
+// cover it without gaps. Where captures overlap, the innermost node wins;
+// for the same node, the capture of the pattern with the most nodes wins,
+// and then the later pattern. Captures whose names start with "_" are
+// ignored.
+func Spans(tree *tree_sitter.Tree, source []byte) []Span {
+	q := highlightsQuery()
+	specificity := patternSpecificity(q)
+
+	type capture struct {
+		start, end uint
+		name       int
+		pattern    uint
+	}
+	var captures []capture
+	names := q.CaptureNames()
+	root := tree.RootNode()
+	for c := range q.Captures(root, source) {
+		if strings.HasPrefix(c.Name, "_") {
+			continue
+		}
+		index, _ := q.CaptureIndexForName(c.Name)
+		captures = append(captures, capture{c.Node.StartByte(), c.Node.EndByte(), int(index), c.PatternIndex})
+	}
+	sort.SliceStable(captures, func(i, j int) bool {
+		a, b := captures[i], captures[j]
+		if la, lb := a.end-a.start, b.end-b.start; la != lb {
+			return la > lb
+		}
+		if sa, sb := specificity[a.pattern], specificity[b.pattern]; sa != sb {
+			return sa < sb
+		}
+		return a.pattern < b.pattern
+	})
+
+	// paint holds, for each byte, one more than the index of its capture
+	// name, or zero.
+	paint := make([]int, len(source))
+	for _, c := range captures {
+		for i := c.start; i < c.end && i < uint(len(paint)); i++ {
+			paint[i] = c.name + 1
+		}
+	}
+
+	var spans []Span
+	for start := 0; start < len(paint); {
+		end := start + 1
+		for end < len(paint) && paint[end] == paint[start] {
+			end++
+		}
+		s := Span{StartByte: uint(start), EndByte: uint(end)}
+		if paint[start] > 0 {
+			s.Capture = names[paint[start]-1]
+		}
+		spans = append(spans, s)
+		start = end
+	}
+	return spans
+}
+
+// patternSpecificity returns the number of nodes in each pattern of q,
+// counted as the opening parentheses outside strings and predicates.
+func patternSpecificity(q *zscript.CachedQuery) []int {
+	result := make([]int, q.PatternCount())
+	for i := range result {
+		text := q.Pattern[q.StartByteForPattern(uint(i)):q.EndByteForPattern(uint(i))]
+		inString := false
+		for j := 0; j < len(text); j++ {
+			switch c := text[j]; {
+			case inString && c == '\\':
+				j++
+			case c == '"':
+				inString = !inString
+			case !inString && c == ';':
+				for j < len(text) && text[j] != '\n' {
+					j++
+				}
+			case !inString && c == '(' && j+1 < len(text) && text[j+1] != '#':
+				result[i]++
+			}
+		}
+	}
+	return result
+}
+
+// HTML parses source and renders it with theme as a <pre> element with
+// line numbers.
+func HTML(source []byte, theme Theme) ([]byte, error) {
+	tree, err := zscript.Parse(context.Background(), source)
+	if err != nil {
+		return nil, err
+	}
+	defer tree.Close()
+	var buf bytes.Buffer
+	if err := WriteHTML(&buf, tree, theme); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// WriteHTML renders tree with theme to w, like HTML.
+func WriteHTML(w io.Writer, tree *zscript.Tree, theme Theme) error {
+	source := tree.Source
+	lines := bytes.Count(source, []byte("\n")) + 1
+	if len(source) > 0 && source[len(source)-1] == '\n' {
+		lines--
+	}
+	width := len(strconv.Itoa(max(lines, 1)))
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "<pre style=\"%s\"><code>", html.EscapeString(theme.Base))
+	line := 0
+	startLine := func() {
+		line++
+		fmt.Fprintf(&b, "<span style=\"%s\">%*d</span>", html.EscapeString(theme.LineNumbers), width, line)
+	}
+	if len(source) > 0 {
+		startLine()
+	}
+	for _, s := range Spans(tree.Tree, source) {
+		style := theme.Style(s.Capture)
+		// Spans are split at newlines so that each line number starts
+		// outside any styled element.
+		text := source[s.StartByte:s.EndByte]
+		for len(text) > 0 {
+			piece, rest, newline := bytes.Cut(text, []byte("\n"))
+			if len(piece) > 0 {
+				if style != "" {
+					fmt.Fprintf(&b, "<span style=\"%s\">%s</span>", html.EscapeString(style), html.EscapeString(string(piece)))
+				} else {
+					b.WriteString(html.EscapeString(string(piece)))
+				}
+			}
+			if newline {
+				b.WriteByte('\n')
+				if len(rest) > 0 || s.EndByte < uint(len(source)) {
+					startLine()
+				}
+			}
+			text = rest
+		}
+	}
+	b.WriteString("</code></pre>\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
@@ -0,0 +1,88 @@
+package highlight_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	zscript "github.com/jlcrochet/tree-sitter-zscript/bindings/go"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/highlight"
+)
+
+const source = `class Imp : Actor {
+	// <Roar>
+	override void Tick() { Super.Tick(); A_StartSound("imp\n"); }
+}
+`
+
+func TestSpans(t *testing.T) {
+	tree, err := zscript.Parse(context.Background(), []byte(source))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tree.Close()
+
+	got := map[string]string{}
+	end := uint(0)
+	for _, s := range highlight.Spans(tree.Tree, tree.Source) {
+		if s.StartByte != end {
+			t.Fatalf("span %+v does not start where the last one ended, at %d", s, end)
+		}
+		end = s.EndByte
+		text := strings.TrimSpace(string(tree.Source[s.StartByte:s.EndByte]))
+		if _, seen := got[text]; !seen && text != "" {
+			got[text] = s.Capture
+		}
+	}
+	if end != uint(len(source)) {
+		t.Errorf("spans end at %d, want %d", end, len(source))
+	}
+	for text, want := range map[string]string{
+		"class":        "keyword",
+		"Imp":          "type.definition",
+		"Actor":        "type",
+		"override":     "keyword.modifier",
+		"Tick":         "function.method",
+		"Super":        "variable.builtin",
+		"A_StartSound": "function.call",
+		`\n`:           "string.escape",
+		"// <Roar>":    "comment",
+	} {
+		if got[text] != want {
+			t.Errorf("%q highlighted as %q, want %q", text, got[text], want)
+		}
+	}
+}
+
+func TestHTML(t *testing.T) {
+	out, err := highlight.HTML([]byte(source), highlight.Light)
+	if err != nil {
+		t.Fatal(err)
+	}
+	html := string(out)
+	if !strings.HasPrefix(html, `<pre style="`) || !strings.HasSuffix(html, "</code></pre>\n") {
+		t.Errorf("HTML is not a pre element:\n%s", html)
+	}
+	ln := `<span style="` + highlight.Light.LineNumbers + `">`
+	if n := strings.Count(html, ln); n != 4 {
+		t.Errorf("got %d line numbers, want 4:\n%s", n, html)
+	}
+	for _, want := range []string{
+		ln + "1</span>",
+		`<span style="color:#6e7781;font-style:italic">// &lt;Roar&gt;</span>`,
+		`<span style="color:#cf222e">class</span>`,
+	} {
+		if !strings.Contains(html, want) {
+			t.Errorf("HTML lacks %q:\n%s", want, html)
+		}
+	}
+}
+
+func TestThemeStyle(t *testing.T) {
+	if got, want := highlight.Dark.Style("keyword.return"), highlight.Dark.Captures["keyword"]; got != want {
+		t.Errorf(`Style("keyword.return") = %q, want %q`, got, want)
+	}
+	if got := highlight.Dark.Style("unknown"); got != "" {
+		t.Errorf(`Style("unknown") = %q, want ""`, got)
+	}
+}
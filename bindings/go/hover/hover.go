@@ -0,0 +1,195 @@
+// Package hover describes the declaration an identifier refers to, as an
+// editor shows it when the pointer rests on the identifier: its signature,
+// the class or struct that declares it, its documentation comment and any
+// deprecation, rendered as Markdown.
+package hover
+
+import (
+	"fmt"
+	"strings"
+
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+
+	zscript "github.com/jlcrochet/tree-sitter-zscript/bindings/go"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/deprecations"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/docs"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/engine"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/project"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/resolve"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/symbols"
+)
+
+// Info describes an identifier.
+type Info struct {
+	// Declaration is what the identifier refers to.
+	Declaration resolve.Declaration
+	// Range spans the identifier.
+	Range tree_sitter.Range
+	// Signature is the declaration on one line, without any body, such
+	// as "virtual void Imp.Fire(int count)".
+	Signature string
+	// Doc is the documentation comment of the declaration, or "".
+	Doc string
+	// Deprecation explains why the declaration is deprecated, or is "" if
+	// it is not.
+	Deprecation string
+}
+
+// Markdown renders i as Markdown: the signature in a code block, followed
+// by the declaring type, the deprecation and the documentation comment.
+func (i Info) Markdown() string {
+	parts := []string{"```zscript\n" + i.Signature + "\n```"}
+	if owner := i.Declaration.Owner; owner != "" {
+		where := fmt.Sprintf("Declared in `%s`", owner)
+		if i.Declaration.Path == engine.Path {
+			where += ", built into GZDoom"
+		}
+		parts = append(parts, where+".")
+	}
+	if i.Deprecation != "" {
+		parts = append(parts, "**Deprecated:** "+i.Deprecation+".")
+	}
+	if i.Doc != "" {
+		parts = append(parts, i.Doc)
+	}
+	return strings.Join(parts, "\n\n")
+}
+
+// Hover describes the identifier at pos in the file of p at path. ok is
+// false if there is no identifier there or its declaration is not known.
+// Declarations are resolved against p and the engine's built-in
+// declarations for the version p declares.
+func Hover(p *project.Project, path string, pos tree_sitter.Point) (Info, bool) {
+	f := p.File(path)
+	if f == nil {
+		return Info{}, false
+	}
+	node := identifierAt(f.Tree.RootNode(), pos)
+	if node == nil {
+		return Info{}, false
+	}
+	return Describe(resolve.ForProject(p), p.Tree, f.Tree, node)
+}
+
+// Describe describes node, an identifier in tree, resolving it with r.
+// trees returns the parsed file at a path declarations record, or nil if
+// it is not available, in which case some signatures are shortened and
+// documentation comments are left out.
+func Describe(r *resolve.Resolver, trees func(path string) *zscript.Tree, tree *zscript.Tree, node *tree_sitter.Node) (Info, bool) {
+	decl, ok := r.Resolve(tree, node)
+	if !ok {
+		return Info{}, false
+	}
+	info := Info{Declaration: decl, Range: node.Range()}
+
+	var source *zscript.Tree
+	if decl.Path == engine.Path {
+		source = engine.Tree()
+	} else if trees != nil {
+		source = trees(decl.Path)
+	}
+	var declNode *tree_sitter.Node
+	if source != nil {
+		declNode = declaration(source, decl.Range)
+		info.Doc = docs.Comment(declNode, source.Source)
+	}
+	info.Signature = signature(decl, declNode, source)
+	info.Deprecation = deprecation(decl)
+	return info, true
+}
+
+// signature formats decl. The declarations that the resolver describes
+// only by name are taken from the source of decl, node, when there is
+// one.
+func signature(decl resolve.Declaration, node *tree_sitter.Node, source *zscript.Tree) string {
+	name := decl.Name
+	if decl.Owner != "" {
+		name = decl.Owner + "." + name
+	}
+	mods := qualifiers(decl.Modifiers)
+	switch decl.Kind {
+	case symbols.KindMethod:
+		return mods + resolve.SignatureOf(decl).Label
+	case symbols.KindField:
+		return mods + decl.Type + " " + name
+	case symbols.KindLocal, symbols.KindParameter:
+		return strings.TrimSpace(decl.Type + " " + decl.Name)
+	case symbols.KindStateLabel:
+		return name + ":"
+	}
+	if node == nil {
+		return decl.Kind.String() + " " + name
+	}
+	text := node.Utf8Text(source.Source)
+	if i := strings.IndexAny(text, "{;"); i >= 0 {
+		text = text[:i]
+	}
+	return strings.TrimSuffix(strings.Join(strings.Fields(text), " "), ",")
+}
+
+// qualifiers returns the modifiers worth showing, each followed by a
+// space.
+func qualifiers(modifiers []string) string {
+	var b strings.Builder
+	for _, m := range modifiers {
+		if !strings.HasPrefix(m, "version(") {
+			b.WriteString(m + " ")
+		}
+	}
+	return b.String()
+}
+
+// deprecation explains the deprecation of decl, from the table of GZDoom's
+// deprecations for the engine's declarations.
+func deprecation(decl resolve.Declaration) string {
+	kind := deprecations.Function
+	if decl.Kind == symbols.KindField {
+		kind = deprecations.Field
+	} else if decl.Kind != symbols.KindMethod {
+		return ""
+	}
+	if decl.Path == engine.Path {
+		if e, ok := deprecations.Default().Lookup(kind, decl.Name); ok {
+			return e.Message(decl.Name)
+		}
+	}
+	for _, m := range decl.Modifiers {
+		if m == "deprecated" {
+			return decl.Name + " is deprecated"
+		}
+	}
+	return ""
+}
+
+// declaration returns the outermost node of tree that spans r.
+func declaration(tree *zscript.Tree, r tree_sitter.Range) *tree_sitter.Node {
+	n := tree.RootNode().DescendantForByteRange(r.StartByte, r.EndByte)
+	for n != nil {
+		p := n.Parent()
+		if p == nil || p.StartByte() != n.StartByte() || p.EndByte() != n.EndByte() || p.Kind() == zscript.NodeSourceFile {
+			break
+		}
+		n = p
+	}
+	return n
+}
+
+// identifierAt returns the identifier at pos, or the one ending there when
+// pos is just past a word.
+func identifierAt(root *tree_sitter.Node, pos tree_sitter.Point) *tree_sitter.Node {
+	points := []tree_sitter.Point{pos}
+	if pos.Column > 0 {
+		points = append(points, tree_sitter.Point{Row: pos.Row, Column: pos.Column - 1})
+	}
+	for _, p := range points {
+		node := root.NamedDescendantForPointRange(p, p)
+		if node == nil {
+			continue
+		}
+		switch node.Kind() {
+		case zscript.NodeIdentifier, zscript.NodeTypeIdentifier, zscript.NodeFieldIdentifier:
+			return node
+		}
+	}
+	return nil
+}
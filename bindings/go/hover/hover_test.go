@@ -0,0 +1,108 @@
+package hover_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/hover"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/project"
+)
+
+const base = `version "4.12"
+#include "imp.zs"
+
+// Base is the root of the project's monsters.
+class Base : Actor {
+	const MAXV = 3;
+	/* How hard it hits. */
+	int power;
+	// Fire attacks count times.
+	virtual void Fire(int count, double spread = 1.5) {}
+	deprecated void Old() {}
+}
+`
+
+const imp = `class Imp : Base {
+	override void Fire(int count, double spread) {
+		int shots = count * MAXV;
+		Super.Fire(shots);
+		power = shots;
+		A_PlaySound("imp/fire");
+		Old();
+		let b = Base(self);
+	}
+}
+`
+
+func TestHover(t *testing.T) {
+	fsys := fstest.MapFS{
+		"zscript.zs": {Data: []byte(base)},
+		"imp.zs":     {Data: []byte(imp)},
+	}
+	p, err := project.Load(context.Background(), fsys, "zscript.zs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+
+	tests := []struct {
+		// The identifier hovered is the first after, on line.
+		line      uint
+		after     string
+		signature string
+		doc       string
+		// deprecation must start with it.
+		deprecation string
+	}{
+		{0, "Imp : ", "class Base : Actor", "Base is the root of the project's monsters.", ""},
+		{2, "int ", "int shots", "", ""},
+		{2, "* ", "const MAXV = 3", "", ""},
+		{3, "Super.", "virtual void Base.Fire(int count, double spread = 1.5)", "Fire attacks count times.", ""},
+		{4, "\t\t", "int Base.power", "How hard it hits.", ""},
+		{5, "\t\t", "deprecated native void Actor.A_PlaySound(sound whattoplay = \"weapons/pistol\", int slot = CHAN_BODY, double volume = 1.0, bool looping = false, double attenuation = ATTN_NORM, bool local = false, double pitch = 0.0)", "", "A_PlaySound is deprecated since GZDoom 4.3; use A_StartSound instead"},
+		{6, "\t\t", "deprecated void Base.Old()", "", "Old is deprecated"},
+	}
+	for _, tt := range tests {
+		line := strings.Split(imp, "\n")[tt.line]
+		pos := tree_sitter.Point{Row: tt.line, Column: uint(strings.Index(line, tt.after) + len(tt.after))}
+		info, ok := hover.Hover(p, "imp.zs", pos)
+		if !ok {
+			t.Errorf("%d:%d: no hover", pos.Row, pos.Column)
+			continue
+		}
+		if info.Signature != tt.signature || info.Doc != tt.doc || !strings.HasPrefix(info.Deprecation, tt.deprecation) || (tt.deprecation == "") != (info.Deprecation == "") {
+			t.Errorf("%d:%d: got %q, %q, %q", pos.Row, pos.Column, info.Signature, info.Doc, info.Deprecation)
+		}
+	}
+
+	if _, ok := hover.Hover(p, "imp.zs", tree_sitter.Point{Row: 1, Column: 0}); ok {
+		t.Error("hover on whitespace")
+	}
+	if _, ok := hover.Hover(p, "missing.zs", tree_sitter.Point{}); ok {
+		t.Error("hover in a missing file")
+	}
+}
+
+func TestMarkdown(t *testing.T) {
+	fsys := fstest.MapFS{
+		"zscript.zs": {Data: []byte(base)},
+		"imp.zs":     {Data: []byte(imp)},
+	}
+	p, err := project.Load(context.Background(), fsys, "zscript.zs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+	info, ok := hover.Hover(p, "imp.zs", tree_sitter.Point{Row: 4, Column: 3})
+	if !ok {
+		t.Fatal("no hover")
+	}
+	want := "```zscript\nint Base.power\n```\n\nDeclared in `Base`.\n\nHow hard it hits."
+	if got := info.Markdown(); got != want {
+		t.Errorf("Markdown() = %q, want %q", got, want)
+	}
+}
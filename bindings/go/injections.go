@@ -0,0 +1,89 @@
+package tree_sitter_zscript
+
+import (
+	"sort"
+	"sync"
+
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+)
+
+// Injection is a region of a ZScript file written in another language,
+// as marked by queries/injections.scm. The languages are:
+//
+//	comment   comments
+//	acs       ACS script names passed to CallACS and ACS_Named*
+//	console   console commands passed to ConsoleCommand
+//	printf    format strings passed to Format, Printf and AppendFormat
+//	language  LANGUAGE lump references, strings starting with "$"
+type Injection struct {
+	Language string
+	// Node is the node the query captured.
+	Node tree_sitter.Node
+	// Range is the injected text. For a string literal it excludes the
+	// quotes.
+	Range tree_sitter.Range
+}
+
+var injectionsQuery = sync.OnceValue(func() *CachedQuery {
+	return MustQuery(string(InjectionsQuery()))
+})
+
+// Injections returns the injected regions of tree, whose text is source,
+// in source order. The pieces of a concatenated string are returned
+// separately. A region matched by several patterns is returned once, for
+// the first pattern in the query file.
+func Injections(tree *tree_sitter.Tree, source []byte) []Injection {
+	q := injectionsQuery()
+	type found struct {
+		injection Injection
+		pattern   uint
+	}
+	byNode := map[uintptr]found{}
+
+	add := func(node tree_sitter.Node, language string, pattern uint) {
+		r := node.Range()
+		if node.Kind() == "string_literal" && r.EndByte-r.StartByte >= 2 {
+			r.StartByte++
+			r.StartPoint.Column++
+			r.EndByte--
+			r.EndPoint.Column--
+		}
+		if old, ok := byNode[node.Id()]; ok && old.pattern <= pattern {
+			return
+		}
+		byNode[node.Id()] = found{Injection{Language: language, Node: node, Range: r}, pattern}
+	}
+
+	root := tree.RootNode()
+	for m := range q.Matches(root, source) {
+		language := ""
+		for _, p := range q.PropertySettings(m.PatternIndex) {
+			if p.Key == "injection.language" && p.Value != nil {
+				language = *p.Value
+			}
+		}
+		if node, ok := m.Capture("injection.language"); ok {
+			language = node.Utf8Text(source)
+		}
+		node, ok := m.Capture("injection.content")
+		if !ok || language == "" {
+			continue
+		}
+		if node.Kind() == "concatenated_string" {
+			cursor := node.Walk()
+			for _, piece := range node.NamedChildren(cursor) {
+				add(piece, language, m.PatternIndex)
+			}
+			cursor.Close()
+			continue
+		}
+		add(node, language, m.PatternIndex)
+	}
+
+	result := make([]Injection, 0, len(byNode))
+	for _, f := range byNode {
+		result = append(result, f.injection)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Range.StartByte < result[j].Range.StartByte })
+	return result
+}
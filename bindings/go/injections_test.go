@@ -0,0 +1,50 @@
+package tree_sitter_zscript_test
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	tree_sitter_zscript "github.com/jlcrochet/tree-sitter-zscript/bindings/go"
+)
+
+func TestInjections(t *testing.T) {
+	source := `// Header.
+class A : Actor {
+	void F() {
+		CallACS("OpenDoor", 1);
+		ConsoleCommand("give all");
+		Console.Printf("%s has " "%d", name, n);
+		String s = String.Format("%d", StringTable.Localize("$HELLO"));
+	}
+	States {
+	Spawn:
+		TNT1 A 0 ACS_NamedExecuteAlways("Spawned");
+		Stop;
+	}
+}
+`
+	tree, err := tree_sitter_zscript.Parse(context.Background(), []byte(source))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tree.Close()
+
+	var got []string
+	for _, inj := range tree_sitter_zscript.Injections(tree.Tree, tree.Source) {
+		got = append(got, inj.Language+" "+source[inj.Range.StartByte:inj.Range.EndByte])
+	}
+	want := []string{
+		"comment // Header.",
+		"acs OpenDoor",
+		"console give all",
+		"printf %s has ",
+		"printf %d",
+		"printf %d",
+		"language $HELLO",
+		"acs Spawned",
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("Injections =\n%q\nwant\n%q", got, want)
+	}
+}
@@ -0,0 +1,208 @@
+// Package inlay computes inlay hints, the labels an editor shows within
+// the text without their being part of it: the names of the parameters
+// that positional arguments are passed to, the types inferred for let
+// declarations, and the values that the constants and enumerators used
+// fold to.
+package inlay
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+
+	zscript "github.com/jlcrochet/tree-sitter-zscript/bindings/go"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/engine"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/eval"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/project"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/resolve"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/symbols"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/types"
+)
+
+// Kind classifies a Hint.
+type Kind int
+
+const (
+	// Parameter labels an argument with the parameter it is passed to,
+	// as in "flags:", and goes before the argument.
+	Parameter Kind = iota
+	// Type labels the variable of a let declaration with its inferred
+	// type, as in ": Inventory", and goes after the variable's name.
+	Type
+	// Value labels a constant or an enumerator with its value, as in
+	// "= 8", and goes after the name.
+	Value
+)
+
+func (k Kind) String() string {
+	switch k {
+	case Type:
+		return "type"
+	case Value:
+		return "value"
+	}
+	return "parameter"
+}
+
+// Hint is a label to show at a position in the text.
+type Hint struct {
+	Kind Kind
+	// Offset is the byte offset the label goes at, and Point its line
+	// and byte column.
+	Offset uint
+	Point  tree_sitter.Point
+	Label  string
+}
+
+// Provider computes the hints of files.
+type Provider struct {
+	r     *resolve.Resolver
+	types *types.Checker
+	eval  *eval.Evaluator
+}
+
+// New returns a provider that resolves names with r. trees returns the
+// parsed file at a path declarations record, or nil if it is not
+// available, in which case the types and values of its constants are not
+// known.
+func New(r *resolve.Resolver, trees func(path string) *zscript.Tree) *Provider {
+	return &Provider{r: r, types: types.New(r, trees), eval: eval.New(r, trees)}
+}
+
+// ForProject returns a provider for the files of p and the engine
+// declarations for the version p declares.
+func ForProject(p *project.Project) *Provider {
+	return New(resolve.ForProject(p), p.Tree)
+}
+
+// Hints returns the hints of tree between the byte offsets start and end,
+// resolving names against the declarations of tree alone and those of the
+// engine.
+func Hints(tree *zscript.Tree, start, end uint) []Hint {
+	r := resolve.NewWithEngine(engine.Version, symbols.Extract(tree))
+	return New(r, func(path string) *zscript.Tree {
+		if path == tree.Path {
+			return tree
+		}
+		return nil
+	}).Hints(tree, start, end)
+}
+
+// Hints returns the hints of tree whose offsets lie between the byte
+// offsets start and end, inclusive, ordered by offset.
+func (p *Provider) Hints(tree *zscript.Tree, start, end uint) []Hint {
+	var hints []Hint
+	add := func(kind Kind, offset uint, point tree_sitter.Point, label string) {
+		if offset >= start && offset <= end {
+			hints = append(hints, Hint{Kind: kind, Offset: offset, Point: point, Label: label})
+		}
+	}
+	var v zscript.Visitor
+	v.Enter = func(n *tree_sitter.Node) zscript.WalkAction {
+		if n.EndByte() < start || n.StartByte() > end {
+			return zscript.WalkSkipChildren
+		}
+		return zscript.WalkContinue
+	}
+	v.On(zscript.NodeCallExpression, func(n *tree_sitter.Node) zscript.WalkAction {
+		p.parameters(tree, n, add)
+		return zscript.WalkContinue
+	})
+	v.On(zscript.NodeStateActionCall, func(n *tree_sitter.Node) zscript.WalkAction {
+		p.parameters(tree, n, add)
+		return zscript.WalkContinue
+	})
+	v.On(zscript.NodeDeclaration, func(n *tree_sitter.Node) zscript.WalkAction {
+		typ := n.ChildByFieldName(zscript.FieldType)
+		if typ == nil || !strings.EqualFold(text(tree, typ), "let") {
+			return zscript.WalkContinue
+		}
+		c := n.Walk()
+		defer c.Close()
+		for _, d := range n.ChildrenByFieldName(zscript.FieldDeclarator, c) {
+			name := d.ChildByFieldName(zscript.FieldDeclarator)
+			t := p.types.TypeOf(tree, d.ChildByFieldName(zscript.FieldValue))
+			if name != nil && t.Kind != types.Unknown {
+				add(Type, name.EndByte(), name.EndPosition(), ": "+t.String())
+			}
+		}
+		return zscript.WalkContinue
+	})
+	zscript.Walk(tree.RootNode(), &v)
+
+	for _, u := range p.eval.Uses(tree, tree.RootNode()) {
+		add(Value, u.Range.EndByte, u.Range.EndPoint, "= "+literal(u.Value))
+	}
+	sort.SliceStable(hints, func(i, j int) bool { return hints[i].Offset < hints[j].Offset })
+	return hints
+}
+
+// parameters adds the names of the parameters that the positional
+// arguments of call are passed to. Arguments that already read as the
+// parameter's name, such as a variable of that name, are not labelled,
+// nor are those passed to the variadic parameter.
+func (p *Provider) parameters(tree *zscript.Tree, call *tree_sitter.Node, add func(Kind, uint, tree_sitter.Point, string)) {
+	fn := call.ChildByFieldName(zscript.FieldFunction)
+	args := call.ChildByFieldName(zscript.FieldArguments)
+	if fn == nil || args == nil || args.NamedChildCount() == 0 {
+		return
+	}
+	if fn.Kind() == zscript.NodeFieldExpression {
+		fn = fn.ChildByFieldName(zscript.FieldField)
+	}
+	if fn == nil {
+		return
+	}
+	var method *symbols.Method
+	for _, decl := range p.r.ResolveAll(tree, fn) {
+		if decl.Method != nil {
+			method = decl.Method
+			break
+		}
+	}
+	if method == nil {
+		return
+	}
+	for i := uint(0); i < args.NamedChildCount() && i < uint(len(method.Params)); i++ {
+		arg, param := args.NamedChild(i), method.Params[i]
+		if arg.Kind() == zscript.NodeNamedArgument || arg.Kind() == zscript.NodeComment {
+			// Arguments after a named one are named too.
+			break
+		}
+		if param.Variadic || param.Name == "" || readsAs(text(tree, arg), param.Name) {
+			continue
+		}
+		add(Parameter, arg.StartByte(), arg.StartPosition(), param.Name+":")
+	}
+}
+
+// readsAs reports whether the argument expression arg ends with the name
+// of the parameter, as "mo.target" does for a parameter named target.
+func readsAs(arg, param string) bool {
+	if len(arg) < len(param) || !strings.EqualFold(arg[len(arg)-len(param):], param) {
+		return false
+	}
+	rest := arg[:len(arg)-len(param)]
+	return rest == "" || strings.HasSuffix(rest, ".")
+}
+
+// literal writes v as ZScript would write it as a literal.
+func literal(v eval.Value) string {
+	switch v.Kind {
+	case eval.String:
+		return strconv.Quote(v.Text)
+	case eval.Name:
+		return "'" + v.Text + "'"
+	case eval.Float:
+		if s := v.String(); !strings.ContainsAny(s, ".eE") {
+			return s + ".0"
+		}
+	}
+	return v.String()
+}
+
+func text(tree *zscript.Tree, node *tree_sitter.Node) string {
+	return node.Utf8Text(tree.Source)
+}
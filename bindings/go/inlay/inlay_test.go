@@ -0,0 +1,83 @@
+package inlay_test
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	zscript "github.com/jlcrochet/tree-sitter-zscript/bindings/go"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/inlay"
+)
+
+const source = `class Imp : Actor {
+	const TICS = 4;
+	enum Mode { MODE_IDLE, MODE_ANGRY = TICS * 2 }
+	void Fire(int count, double spread = 1) {}
+	void Think(Actor target) {
+		let inv = target.FindInventory("Clip");
+		let scale = 2.5;
+		Fire(MODE_ANGRY);
+		Fire(3, spread: 0.5);
+		Console.Printf("%d", count);
+		A_SetTics(TICS);
+		target.A_Face(target);
+	}
+	States {
+	Spawn:
+		TROO A 1 A_Jump(128, "See");
+		Loop;
+	}
+}
+`
+
+func parse(t *testing.T) *zscript.Tree {
+	t.Helper()
+	tree, err := zscript.Parse(context.Background(), []byte(source))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(tree.Close)
+	return tree
+}
+
+func format(hints []inlay.Hint) string {
+	var b strings.Builder
+	for _, h := range hints {
+		fmt.Fprintf(&b, "%d:%d %s %s\n", h.Point.Row+1, h.Point.Column+1, h.Kind, h.Label)
+	}
+	return b.String()
+}
+
+func TestHints(t *testing.T) {
+	tree := parse(t)
+	// A_SetTics(TICS) reads as the name of its parameter.
+	want := `3:42 value = 4
+6:10 type : Inventory
+6:34 parameter itemtype:
+7:12 type : double
+8:8 parameter count:
+8:18 value = 8
+9:8 parameter count:
+10:18 parameter fmt:
+11:17 value = 4
+12:17 parameter faceto:
+16:19 parameter chance:
+16:24 parameter label:
+`
+	if got := format(inlay.Hints(tree, 0, uint(len(source)))); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestRange(t *testing.T) {
+	tree := parse(t)
+	start := uint(strings.Index(source, "Fire(MODE"))
+	end := uint(strings.Index(source, "Fire(3"))
+	want := `8:8 parameter count:
+8:18 value = 8
+`
+	if got := format(inlay.Hints(tree, start, end)); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+}
@@ -0,0 +1,105 @@
+// Package corpus reads tree-sitter test corpus files, as found in
+// test/corpus.
+package corpus
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Example is a single test case of a corpus file.
+type Example struct {
+	// File is the base name of the corpus file.
+	File string
+	Name string
+	// Attributes are the words following ":" on the lines after the name,
+	// such as "skip" or "error".
+	Attributes []string
+	Input      string
+	// Expected is the expected S-expression as written in the file.
+	Expected string
+}
+
+// HasAttribute reports whether the example is marked with the attribute.
+func (e *Example) HasAttribute(name string) bool {
+	for _, a := range e.Attributes {
+		if a == name {
+			return true
+		}
+	}
+	return false
+}
+
+// header matches an example header: a line of "=" signs, the name and
+// optional attribute lines, and another line of "=" signs. The delimiters
+// may carry a common suffix.
+var header = regexp.MustCompile(`(?m)^(={3,})([^=\n]*)\n((?:.*\n)+?)={3,}[^=\n]*\n`)
+
+// divider matches the line of "-" signs between input and expected output.
+var divider = regexp.MustCompile(`(?m)^-{3,}[^-\n]*\n`)
+
+// Parse splits the text of a corpus file into examples.
+func Parse(file, text string) []Example {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	matches := header.FindAllStringSubmatchIndex(text, -1)
+	var examples []Example
+	for i, m := range matches {
+		end := len(text)
+		if i+1 < len(matches) {
+			end = matches[i+1][0]
+		}
+		lines := strings.Split(strings.TrimRight(text[m[6]:m[7]], "\n"), "\n")
+		e := Example{File: file, Name: strings.TrimSpace(lines[0])}
+		for _, line := range lines[1:] {
+			if attr, ok := strings.CutPrefix(strings.TrimSpace(line), ":"); ok {
+				e.Attributes = append(e.Attributes, attr)
+			}
+		}
+
+		body := text[m[1]:end]
+		// The last divider separates input from output, so that inputs may
+		// contain dashed lines of their own.
+		dividers := divider.FindAllStringIndex(body, -1)
+		if len(dividers) == 0 {
+			e.Input = body
+		} else {
+			d := dividers[len(dividers)-1]
+			e.Input = body[:d[0]]
+			e.Expected = strings.TrimSpace(body[d[1]:])
+		}
+		e.Input = strings.TrimSuffix(e.Input, "\n")
+		examples = append(examples, e)
+	}
+	return examples
+}
+
+// Load reads every *.txt corpus file in dir.
+func Load(dir string) ([]Example, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.txt"))
+	if err != nil {
+		return nil, err
+	}
+	var examples []Example
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		examples = append(examples, Parse(filepath.Base(file), string(data))...)
+	}
+	return examples, nil
+}
+
+var (
+	space       = regexp.MustCompile(`\s+`)
+	spaceBefore = regexp.MustCompile(` \)`)
+)
+
+// NormalizeSexp collapses the whitespace of an S-expression so that trees
+// printed on one line compare equal to trees laid out over several.
+func NormalizeSexp(sexp string) string {
+	sexp = space.ReplaceAllString(strings.TrimSpace(sexp), " ")
+	return spaceBefore.ReplaceAllString(sexp, ")")
+}
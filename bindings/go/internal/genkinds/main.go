@@ -0,0 +1,115 @@
+// Command genkinds generates the node kind and field name constants in
+// kinds.go, and the per-kind Visitor methods in visit_kinds.go, from the
+// grammar's node-types.json.
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"go/format"
+	"log"
+	"os"
+	"sort"
+	"strings"
+)
+
+type nodeType struct {
+	Type   string                     `json:"type"`
+	Named  bool                       `json:"named"`
+	Fields map[string]json.RawMessage `json:"fields"`
+}
+
+func main() {
+	input := flag.String("i", "../../src/node-types.json", "path to node-types.json")
+	output := flag.String("o", "kinds.go", "output file for constants")
+	visitor := flag.String("v", "visit_kinds.go", "output file for Visitor methods")
+	pkg := flag.String("p", "tree_sitter_zscript", "package name")
+	flag.Parse()
+
+	data, err := os.ReadFile(*input)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	var types []nodeType
+	if err := json.Unmarshal(data, &types); err != nil {
+		log.Fatalf("%s: %v", *input, err)
+	}
+
+	kinds := map[string]bool{}
+	fields := map[string]bool{}
+	for _, t := range types {
+		if t.Named {
+			kinds[t.Type] = true
+		}
+		for name := range t.Fields {
+			fields[name] = true
+		}
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "// Code generated by genkinds from src/node-types.json. DO NOT EDIT.\n\n")
+	fmt.Fprintf(&buf, "package %s\n\n", *pkg)
+
+	fmt.Fprintf(&buf, "// Named node kinds, as returned by Node.Kind.\n")
+	fmt.Fprintf(&buf, "const (\n")
+	for _, kind := range sorted(kinds) {
+		fmt.Fprintf(&buf, "\tNode%s = %q\n", camel(kind), kind)
+	}
+	fmt.Fprintf(&buf, ")\n\n")
+
+	fmt.Fprintf(&buf, "// Field names, as accepted by Node.ChildByFieldName.\n")
+	fmt.Fprintf(&buf, "const (\n")
+	for _, name := range sorted(fields) {
+		fmt.Fprintf(&buf, "\tField%s = %q\n", camel(name), name)
+	}
+	fmt.Fprintf(&buf, ")\n")
+
+	write(*output, buf.Bytes())
+
+	buf.Reset()
+	fmt.Fprintf(&buf, "// Code generated by genkinds from src/node-types.json. DO NOT EDIT.\n\n")
+	fmt.Fprintf(&buf, "package %s\n", *pkg)
+	for _, kind := range sorted(kinds) {
+		name := camel(kind)
+		fmt.Fprintf(&buf, "\n// On%s registers fn to be called for %s nodes.\n", name, kind)
+		fmt.Fprintf(&buf, "func (v *Visitor) On%s(fn VisitFunc) *Visitor {\n", name)
+		fmt.Fprintf(&buf, "\treturn v.On(Node%s, fn)\n", name)
+		fmt.Fprintf(&buf, "}\n")
+	}
+	write(*visitor, buf.Bytes())
+}
+
+func write(path string, src []byte) {
+	src, err := format.Source(src)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := os.WriteFile(path, src, 0o644); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func sorted(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for k := range set {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// camel converts a snake_case name to CamelCase.
+func camel(name string) string {
+	var b strings.Builder
+	for _, part := range strings.Split(name, "_") {
+		if part == "" {
+			continue
+		}
+		b.WriteString(strings.ToUpper(part[:1]))
+		b.WriteString(part[1:])
+	}
+	return b.String()
+}
@@ -0,0 +1,226 @@
+// Command genstubs generates engine/stubs.zs, the summary of GZDoom's
+// built-in declarations that package engine embeds, from the ZScript
+// sources of the engine, such as the zscript directory of an unpacked
+// gzdoom.pk3.
+//
+// The summary keeps the classes, structs, enums and constants of the
+// sources with their fields, method signatures, properties and flagdefs,
+// and drops method bodies, Default blocks and States. Modifiers come
+// out in a fixed order, lowercased. Declarations the grammar cannot parse,
+// such as methods with several return types, may be missing; files that
+// contain them are reported.
+package main
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	zscript "github.com/jlcrochet/tree-sitter-zscript/bindings/go"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/symbols"
+)
+
+func main() {
+	input := flag.String("i", "", "directory of the engine's ZScript sources")
+	output := flag.String("o", "engine/stubs.zs", "output file")
+	engine := flag.String("v", "", "engine version the sources are from, such as 4.12")
+	flag.Parse()
+	if *input == "" || *engine == "" {
+		log.Fatal("genstubs: -i and -v are required")
+	}
+
+	var paths []string
+	err := filepath.WalkDir(*input, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if ext := strings.ToLower(filepath.Ext(path)); !d.IsDir() && (ext == ".zs" || ext == ".zsc") {
+			paths = append(paths, path)
+		}
+		return nil
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+	sort.Strings(paths)
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "// Code generated by genstubs from the ZScript sources of GZDoom %s. DO NOT EDIT.\n", *engine)
+	fmt.Fprintf(&buf, "\nversion \"%s\"\n", *engine)
+	for _, path := range paths {
+		source, err := os.ReadFile(path)
+		if err != nil {
+			log.Fatal(err)
+		}
+		tree, err := zscript.Parse(context.Background(), source)
+		if err != nil {
+			log.Fatalf("%s: %v", path, err)
+		}
+		tree.Path = path
+		table := symbols.Extract(tree)
+		tree.Close()
+		if table.HasErrors {
+			log.Printf("%s: syntax errors; some declarations may be missing", path)
+		}
+		writeTable(&buf, table)
+	}
+	if err := os.WriteFile(*output, buf.Bytes(), 0o644); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func writeTable(w *bytes.Buffer, t *symbols.Table) {
+	for _, c := range t.Classes {
+		fmt.Fprintf(w, "\n")
+		if c.Extend {
+			fmt.Fprintf(w, "extend ")
+		}
+		fmt.Fprintf(w, "class %s", c.Name)
+		if c.Parent != "" {
+			fmt.Fprintf(w, " : %s", c.Parent)
+		}
+		for _, flag := range c.Flags {
+			if !strings.EqualFold(flag, "version") {
+				fmt.Fprintf(w, " %s", strings.ToLower(flag))
+			}
+		}
+		if c.Version != "" {
+			fmt.Fprintf(w, " version(%q)", c.Version)
+		}
+		fmt.Fprintf(w, "\n{\n")
+		writeEnums(w, c.Enums)
+		writeConsts(w, c.Consts)
+		writeFields(w, c.Fields)
+		for _, p := range c.Properties {
+			fmt.Fprintf(w, "\tproperty %s: %s;\n", p.Name, strings.Join(p.Fields, ", "))
+		}
+		for _, f := range c.FlagDefs {
+			fmt.Fprintf(w, "\tflagdef %s: %s, %s;\n", f.Name, f.Field, f.Bit)
+		}
+		writeMethods(w, c.Methods)
+		fmt.Fprintf(w, "}\n")
+	}
+	for _, s := range t.Structs {
+		fmt.Fprintf(w, "\n")
+		if s.Extend {
+			fmt.Fprintf(w, "extend ")
+		}
+		fmt.Fprintf(w, "struct %s", s.Name)
+		if s.Version != "" {
+			fmt.Fprintf(w, " version(%q)", s.Version)
+		}
+		fmt.Fprintf(w, "\n{\n")
+		writeEnums(w, s.Enums)
+		writeConsts(w, s.Consts)
+		writeFields(w, s.Fields)
+		writeMethods(w, s.Methods)
+		fmt.Fprintf(w, "}\n")
+	}
+	if len(t.Enums) > 0 || len(t.Consts) > 0 {
+		fmt.Fprintf(w, "\n")
+	}
+	for _, e := range t.Enums {
+		writeEnum(w, "", e)
+	}
+	for _, c := range t.Consts {
+		fmt.Fprintf(w, "const %s = %s;\n", c.Name, c.Value)
+	}
+}
+
+func writeEnums(w *bytes.Buffer, enums []*symbols.Enum) {
+	for _, e := range enums {
+		writeEnum(w, "\t", e)
+	}
+}
+
+func writeEnum(w *bytes.Buffer, indent string, e *symbols.Enum) {
+	fmt.Fprintf(w, "%senum %s", indent, e.Name)
+	if e.BaseType != "" {
+		fmt.Fprintf(w, " : %s", e.BaseType)
+	}
+	fmt.Fprintf(w, " { ")
+	for i, m := range e.Members {
+		if i > 0 {
+			fmt.Fprintf(w, ", ")
+		}
+		fmt.Fprintf(w, "%s", m.Name)
+		if m.Value != "" {
+			fmt.Fprintf(w, " = %s", m.Value)
+		}
+	}
+	fmt.Fprintf(w, " }\n")
+}
+
+func writeConsts(w *bytes.Buffer, consts []*symbols.Const) {
+	for _, c := range consts {
+		fmt.Fprintf(w, "\tconst %s = %s;\n", c.Name, c.Value)
+	}
+}
+
+func writeFields(w *bytes.Buffer, fields []*symbols.Field) {
+	for _, f := range fields {
+		fmt.Fprintf(w, "\t%s%s %s;\n", modifiers(f.Modifiers), f.Type, f.Name)
+	}
+}
+
+func writeMethods(w *bytes.Buffer, methods []*symbols.Method) {
+	for _, m := range methods {
+		mods := m.Modifiers
+		if m.HasBody {
+			// Without its body, a method must be declared native.
+			mods = append([]string{"native"}, mods...)
+		}
+		fmt.Fprintf(w, "\t%s", modifiers(mods))
+		if m.ReturnType != "" {
+			fmt.Fprintf(w, "%s ", m.ReturnType)
+		}
+		fmt.Fprintf(w, "%s(", m.Name)
+		for i, p := range m.Params {
+			if i > 0 {
+				fmt.Fprintf(w, ", ")
+			}
+			if p.Variadic {
+				fmt.Fprintf(w, "...")
+				continue
+			}
+			fmt.Fprintf(w, "%s%s %s", modifiers(p.Modifiers), p.Type, p.Name)
+			if p.Default != "" {
+				fmt.Fprintf(w, " = %s", p.Default)
+			}
+		}
+		fmt.Fprintf(w, ")")
+		if m.Const {
+			fmt.Fprintf(w, " const")
+		}
+		fmt.Fprintf(w, ";\n")
+	}
+}
+
+// modifierOrder ranks the modifiers that must come first: the grammar
+// reads "native readonly T" as a readonly<T> type missing its brackets.
+var modifierOrder = map[string]int{"readonly": -1}
+
+// modifiers returns the modifiers, deduplicated and each followed by a
+// space, with readonly first.
+func modifiers(list []string) string {
+	seen := map[string]bool{}
+	var out []string
+	for _, m := range list {
+		if !seen[m] {
+			seen[m] = true
+			out = append(out, m)
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool { return modifierOrder[out[i]] < modifierOrder[out[j]] })
+	if len(out) == 0 {
+		return ""
+	}
+	return strings.Join(out, " ") + " "
+}
@@ -0,0 +1,192 @@
+// Package jsonrpc implements JSON-RPC 2.0 over a stream, framed with
+// Content-Length headers as in the Language Server Protocol.
+package jsonrpc
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Error codes defined by JSON-RPC 2.0.
+const (
+	CodeParseError     = -32700
+	CodeInvalidRequest = -32600
+	CodeMethodNotFound = -32601
+	CodeInvalidParams  = -32602
+	CodeInternalError  = -32603
+)
+
+// Error is a JSON-RPC error object. Handlers can return one to control
+// the code sent to the client.
+type Error struct {
+	Code    int             `json:"code"`
+	Message string          `json:"message"`
+	Data    json.RawMessage `json:"data,omitempty"`
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("jsonrpc: %s (code %d)", e.Message, e.Code)
+}
+
+// Errorf returns an Error with the given code and formatted message.
+func Errorf(code int, format string, args ...any) *Error {
+	return &Error{Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+// Message is a request, notification or response. Requests have an ID and
+// a Method, notifications only a Method, and responses only an ID.
+type Message struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method,omitempty"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *Error          `json:"error,omitempty"`
+}
+
+// IsRequest reports whether m is a request that expects a response.
+func (m *Message) IsRequest() bool {
+	return m.Method != "" && m.ID != nil
+}
+
+// Conn reads and writes framed messages. Writes are safe for concurrent
+// use; reads are not.
+type Conn struct {
+	r  *bufio.Reader
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// NewConn returns a connection that reads from r and writes to w.
+func NewConn(r io.Reader, w io.Writer) *Conn {
+	return &Conn{r: bufio.NewReader(r), w: w}
+}
+
+// Read returns the next message. It returns io.EOF when the stream ends
+// between messages.
+func (c *Conn) Read() (*Message, error) {
+	header, err := textproto.NewReader(c.r).ReadMIMEHeader()
+	if err != nil {
+		if errors.Is(err, io.EOF) && len(header) == 0 {
+			return nil, io.EOF
+		}
+		return nil, fmt.Errorf("jsonrpc: reading header: %w", err)
+	}
+	length, err := strconv.Atoi(strings.TrimSpace(header.Get("Content-Length")))
+	if err != nil || length < 0 {
+		return nil, fmt.Errorf("jsonrpc: invalid Content-Length %q", header.Get("Content-Length"))
+	}
+	body := make([]byte, length)
+	if _, err := io.ReadFull(c.r, body); err != nil {
+		return nil, fmt.Errorf("jsonrpc: reading body: %w", err)
+	}
+	var m Message
+	if err := json.Unmarshal(body, &m); err != nil {
+		return nil, &Error{Code: CodeParseError, Message: err.Error()}
+	}
+	return &m, nil
+}
+
+// Write sends m.
+func (c *Conn) Write(m *Message) error {
+	m.JSONRPC = "2.0"
+	body, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, err := fmt.Fprintf(c.w, "Content-Length: %d\r\n\r\n", len(body)); err != nil {
+		return err
+	}
+	_, err = c.w.Write(body)
+	return err
+}
+
+// Notify sends a notification.
+func (c *Conn) Notify(method string, params any) error {
+	raw, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+	return c.Write(&Message{Method: method, Params: raw})
+}
+
+// Reply sends the response to the request with the given ID. A non-nil err
+// is sent as an error response; errors other than *Error are reported as
+// internal errors.
+func (c *Conn) Reply(id json.RawMessage, result any, err error) error {
+	m := &Message{ID: id}
+	if err != nil {
+		var rpcErr *Error
+		if !errors.As(err, &rpcErr) {
+			rpcErr = &Error{Code: CodeInternalError, Message: err.Error()}
+		}
+		m.Error = rpcErr
+	} else {
+		raw, err := json.Marshal(result)
+		if err != nil {
+			return err
+		}
+		m.Result = raw
+	}
+	return c.Write(m)
+}
+
+// Handler handles a request or notification. The result of a notification
+// is discarded.
+type Handler func(ctx context.Context, method string, params json.RawMessage) (any, error)
+
+// ErrStop can be returned by a handler to make Serve return after the
+// current message has been answered.
+var ErrStop = errors.New("jsonrpc: stop")
+
+// Serve reads messages from c and dispatches them to handler one at a
+// time, in the order they arrive. It returns nil when the stream ends or
+// a handler returns ErrStop.
+func Serve(ctx context.Context, c *Conn, handler Handler) error {
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		m, err := c.Read()
+		if err != nil {
+			var rpcErr *Error
+			switch {
+			case errors.Is(err, io.EOF):
+				return nil
+			case errors.As(err, &rpcErr):
+				if err := c.Reply(json.RawMessage("null"), nil, rpcErr); err != nil {
+					return err
+				}
+				continue
+			}
+			return err
+		}
+		if m.Method == "" {
+			// Responses to requests we never send.
+			continue
+		}
+		result, err := handler(ctx, m.Method, m.Params)
+		stop := errors.Is(err, ErrStop)
+		if stop {
+			err = nil
+		}
+		if m.IsRequest() {
+			if err := c.Reply(m.ID, result, err); err != nil {
+				return err
+			}
+		}
+		if stop {
+			return nil
+		}
+	}
+}
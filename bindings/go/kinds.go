@@ -0,0 +1,176 @@
+// Code generated by genkinds from src/node-types.json. DO NOT EDIT.
+
+package tree_sitter_zscript
+
+// Named node kinds, as returned by Node.Kind.
+const (
+	NodeAlignofExpression       = "alignof_expression"
+	NodeArgumentList            = "argument_list"
+	NodeArrayDeclarator         = "array_declarator"
+	NodeArrayPattern            = "array_pattern"
+	NodeArrayType               = "array_type"
+	NodeAssignmentExpression    = "assignment_expression"
+	NodeBinaryExpression        = "binary_expression"
+	NodeBreakStatement          = "break_statement"
+	NodeCallExpression          = "call_expression"
+	NodeCaseStatement           = "case_statement"
+	NodeCastExpression          = "cast_expression"
+	NodeClassDefinition         = "class_definition"
+	NodeClassFlag               = "class_flag"
+	NodeClassFlags              = "class_flags"
+	NodeClassModifier           = "class_modifier"
+	NodeClassType               = "class_type"
+	NodeComment                 = "comment"
+	NodeCompoundStatement       = "compound_statement"
+	NodeConcatenatedString      = "concatenated_string"
+	NodeConditionalExpression   = "conditional_expression"
+	NodeConstDefinition         = "const_definition"
+	NodeConstQualifier          = "const_qualifier"
+	NodeContinueStatement       = "continue_statement"
+	NodeDeclaration             = "declaration"
+	NodeDeclarator              = "declarator"
+	NodeDefaultBlock            = "default_block"
+	NodeDefaultProperty         = "default_property"
+	NodeDoStatement             = "do_statement"
+	NodeElseClause              = "else_clause"
+	NodeEnumBaseType            = "enum_base_type"
+	NodeEnumDefinition          = "enum_definition"
+	NodeEnumerator              = "enumerator"
+	NodeEnumeratorList          = "enumerator_list"
+	NodeEscapeSequence          = "escape_sequence"
+	NodeExpression              = "expression"
+	NodeExpressionStatement     = "expression_statement"
+	NodeFalse                   = "false"
+	NodeFieldDeclaration        = "field_declaration"
+	NodeFieldExpression         = "field_expression"
+	NodeFieldIdentifier         = "field_identifier"
+	NodeFlagDefinition          = "flag_definition"
+	NodeFlagName                = "flag_name"
+	NodeFlagStatement           = "flag_statement"
+	NodeForStatement            = "for_statement"
+	NodeForeachStatement        = "foreach_statement"
+	NodeFunctionDeclarator      = "function_declarator"
+	NodeFunctionDefinition      = "function_definition"
+	NodeGetclassExpression      = "getclass_expression"
+	NodeIdentifier              = "identifier"
+	NodeIfStatement             = "if_statement"
+	NodeIncludeDirective        = "include_directive"
+	NodeInheritanceSpecifier    = "inheritance_specifier"
+	NodeInitDeclarator          = "init_declarator"
+	NodeInitializerList         = "initializer_list"
+	NodeInvokerExpression       = "invoker_expression"
+	NodeLabeledStatement        = "labeled_statement"
+	NodeMapType                 = "map_type"
+	NodeMapiteratorType         = "mapiterator_type"
+	NodeMemberModifier          = "member_modifier"
+	NodeMemberModifiers         = "member_modifiers"
+	NodeMethodDefinition        = "method_definition"
+	NodeMixinStatement          = "mixin_statement"
+	NodeNameLiteral             = "name_literal"
+	NodeNamedArgument           = "named_argument"
+	NodeNull                    = "null"
+	NodeNumberLiteral           = "number_literal"
+	NodeParameterDeclaration    = "parameter_declaration"
+	NodeParameterList           = "parameter_list"
+	NodeParameterModifier       = "parameter_modifier"
+	NodeParameterModifiers      = "parameter_modifiers"
+	NodeParenthesizedDeclarator = "parenthesized_declarator"
+	NodeParenthesizedExpression = "parenthesized_expression"
+	NodePointerDeclarator       = "pointer_declarator"
+	NodePointerExpression       = "pointer_expression"
+	NodePrimitiveType           = "primitive_type"
+	NodePropertyAssignment      = "property_assignment"
+	NodePropertyDefinition      = "property_definition"
+	NodePropertyIdentifier      = "property_identifier"
+	NodeRandomExpression        = "random_expression"
+	NodeReadonlyType            = "readonly_type"
+	NodeReturnStatement         = "return_statement"
+	NodeSelfExpression          = "self_expression"
+	NodeSizedTypeSpecifier      = "sized_type_specifier"
+	NodeSizeofExpression        = "sizeof_expression"
+	NodeSourceFile              = "source_file"
+	NodeStateAction             = "state_action"
+	NodeStateActionCall         = "state_action_call"
+	NodeStateBody               = "state_body"
+	NodeStateExpression         = "state_expression"
+	NodeStateFlow               = "state_flow"
+	NodeStateGotoTarget         = "state_goto_target"
+	NodeStateLabel              = "state_label"
+	NodeStateLabelName          = "state_label_name"
+	NodeStateLine               = "state_line"
+	NodeStateModifier           = "state_modifier"
+	NodeStateModifiers          = "state_modifiers"
+	NodeStateSpriteFrames       = "state_sprite_frames"
+	NodeStatement               = "statement"
+	NodeStatementIdentifier     = "statement_identifier"
+	NodeStatesBlock             = "states_block"
+	NodeStatesOptions           = "states_options"
+	NodeStaticConstArray        = "static_const_array"
+	NodeStorageClassSpecifier   = "storage_class_specifier"
+	NodeStringContent           = "string_content"
+	NodeStringLiteral           = "string_literal"
+	NodeStructDefinition        = "struct_definition"
+	NodeStructFlag              = "struct_flag"
+	NodeStructFlags             = "struct_flags"
+	NodeSubscriptExpression     = "subscript_expression"
+	NodeSuperExpression         = "super_expression"
+	NodeSwitchStatement         = "switch_statement"
+	NodeTrue                    = "true"
+	NodeTypeIdentifier          = "type_identifier"
+	NodeTypeMemberExpression    = "type_member_expression"
+	NodeTypeQualifier           = "type_qualifier"
+	NodeTypeSpecifier           = "type_specifier"
+	NodeUnaryExpression         = "unary_expression"
+	NodeUpdateExpression        = "update_expression"
+	NodeVectorLiteral           = "vector_literal"
+	NodeVersionDirective        = "version_directive"
+	NodeWhileStatement          = "while_statement"
+)
+
+// Field names, as accepted by Node.ChildByFieldName.
+const (
+	FieldAlternative  = "alternative"
+	FieldArgument     = "argument"
+	FieldArguments    = "arguments"
+	FieldBit          = "bit"
+	FieldBody         = "body"
+	FieldClass        = "class"
+	FieldCollection   = "collection"
+	FieldCondition    = "condition"
+	FieldConsequence  = "consequence"
+	FieldDeclarator   = "declarator"
+	FieldDefault      = "default"
+	FieldDuration     = "duration"
+	FieldElement      = "element"
+	FieldField        = "field"
+	FieldFlag         = "flag"
+	FieldFunction     = "function"
+	FieldId           = "id"
+	FieldIndex        = "index"
+	FieldInitializer  = "initializer"
+	FieldKey          = "key"
+	FieldLabel        = "label"
+	FieldLeft         = "left"
+	FieldMember       = "member"
+	FieldName         = "name"
+	FieldOperator     = "operator"
+	FieldParameters   = "parameters"
+	FieldParent       = "parent"
+	FieldPath         = "path"
+	FieldProperty     = "property"
+	FieldRight        = "right"
+	FieldSign         = "sign"
+	FieldSize         = "size"
+	FieldSpriteFrames = "sprite_frames"
+	FieldState        = "state"
+	FieldTarget       = "target"
+	FieldType         = "type"
+	FieldUpdate       = "update"
+	FieldValue        = "value"
+	FieldVariable     = "variable"
+	FieldVersion      = "version"
+	FieldW            = "w"
+	FieldX            = "x"
+	FieldY            = "y"
+	FieldZ            = "z"
+)
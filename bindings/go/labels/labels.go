@@ -0,0 +1,352 @@
+// Package labels resolves the state labels that code names to their
+// declarations and reports those that do not exist, which GZDoom only
+// notices when the jump is taken.
+//
+// A label is named by a Goto target, by ResolveState("Label"), or by a
+// string or name literal passed for a statelabel parameter, such as those
+// of A_Jump, A_JumpIfInventory and SetStateLabel.
+package labels
+
+import (
+	"fmt"
+	"strings"
+
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+
+	zscript "github.com/jlcrochet/tree-sitter-zscript/bindings/go"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/engine"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/hierarchy"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/lint"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/project"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/resolve"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/symbols"
+)
+
+// RuleUnresolvedLabel is the rule name of the findings of Check.
+const RuleUnresolvedLabel = "unresolved-state-label"
+
+// EngineLabels are the state labels of the engine's classes, which the
+// built-in declarations of package engine leave out, by class name. A
+// class that inherits from an engine class the map does not list may
+// have labels the package cannot see, so its references are never
+// reported.
+var EngineLabels = map[string][]string{
+	"Object":          nil,
+	"Thinker":         nil,
+	"Actor":           {"Spawn", "Null", "GenericFreezeDeath", "GenericCrush"},
+	"Inventory":       {"HoldAndDestroy", "Held"},
+	"StateProvider":   nil,
+	"CustomInventory": nil,
+	"Weapon":          {"LightDone"},
+}
+
+// Reference is a state label named in code.
+type Reference struct {
+	// Label is the label as written, without spaces, such as
+	// "Death.Fire".
+	Label string
+	// Class qualifies the label, as "Super" does in "Super::Spawn", or is
+	// "".
+	Class string
+	// Owner is the class whose states the label is looked up in: the
+	// class that contains the reference, or the receiver of a call such
+	// as mo.SetStateLabel("Pain"). It is "" if the receiver's class is not
+	// known.
+	Owner string
+	Path  string
+	// Range spans the label name of a Goto target, or the whole literal.
+	Range tree_sitter.Range
+	// Goto is set for a Goto target, which GZDoom resolves when it
+	// compiles the class. Other references are resolved when they run,
+	// against the class of the actor, which may inherit from Owner.
+	Goto bool
+}
+
+// String returns the reference as written, such as "Super::Spawn".
+func (ref Reference) String() string {
+	if ref.Class != "" {
+		return ref.Class + "::" + ref.Label
+	}
+	return ref.Label
+}
+
+// References returns the state labels named in tree, in source order,
+// resolving calls with r to find their statelabel parameters.
+func References(r *resolve.Resolver, tree *zscript.Tree) []Reference {
+	var refs []Reference
+	var v zscript.Visitor
+	v.On(zscript.NodeStateGotoTarget, func(node *tree_sitter.Node) zscript.WalkAction {
+		name := childOfKind(node, zscript.NodeStateLabelName)
+		if name == nil {
+			return zscript.WalkContinue
+		}
+		ref := Reference{
+			Label: strings.Join(strings.Fields(name.Utf8Text(tree.Source)), ""),
+			Owner: enclosingClass(node, tree.Source),
+			Path:  tree.Path,
+			Range: name.Range(),
+			Goto:  true,
+		}
+		if class := node.ChildByFieldName(zscript.FieldClass); class != nil {
+			ref.Class = class.Utf8Text(tree.Source)
+		}
+		refs = append(refs, ref)
+		return zscript.WalkSkipChildren
+	})
+	literal := func(node *tree_sitter.Node) zscript.WalkAction {
+		if ref, ok := Argument(r, tree, node); ok {
+			refs = append(refs, ref)
+		}
+		return zscript.WalkSkipChildren
+	}
+	v.On(zscript.NodeStringLiteral, literal)
+	v.On(zscript.NodeNameLiteral, literal)
+	zscript.Walk(tree.RootNode(), &v)
+	return refs
+}
+
+// Argument returns the reference that node, a string or name literal in
+// tree, makes as the argument of ResolveState or of a statelabel
+// parameter. ok is false if node is anything else, or is empty.
+func Argument(r *resolve.Resolver, tree *zscript.Tree, node *tree_sitter.Node) (ref Reference, ok bool) {
+	if k := node.Kind(); k != zscript.NodeStringLiteral && k != zscript.NodeNameLiteral {
+		return Reference{}, false
+	}
+	parent := node.Parent()
+	if parent == nil {
+		return Reference{}, false
+	}
+	owner := enclosingClass(node, tree.Source)
+	switch parent.Kind() {
+	case zscript.NodeStateExpression:
+	case zscript.NodeArgumentList, zscript.NodeNamedArgument:
+		if owner, ok = labelParameter(r, tree, node); !ok {
+			return Reference{}, false
+		}
+	default:
+		return Reference{}, false
+	}
+	text := strings.Trim(node.Utf8Text(tree.Source), `"'`)
+	if text == "" {
+		return Reference{}, false
+	}
+	ref = Reference{Label: text, Owner: owner, Path: tree.Path, Range: node.Range()}
+	if class, label, found := strings.Cut(text, "::"); found {
+		ref.Class, ref.Label = class, label
+	}
+	ref.Label = strings.Join(strings.Fields(ref.Label), "")
+	return ref, true
+}
+
+// labelParameter reports whether arg is passed for a statelabel parameter
+// of the method a call resolves to, and returns the class whose states
+// the call looks in.
+func labelParameter(r *resolve.Resolver, tree *zscript.Tree, arg *tree_sitter.Node) (owner string, ok bool) {
+	named := ""
+	if p := arg.Parent(); p.Kind() == zscript.NodeNamedArgument {
+		if p.ChildByFieldName(zscript.FieldValue) == nil || p.ChildByFieldName(zscript.FieldValue).Id() != arg.Id() {
+			return "", false
+		}
+		named = p.ChildByFieldName(zscript.FieldName).Utf8Text(tree.Source)
+		arg = p
+	}
+	args := arg.Parent()
+	call := args.Parent()
+	if call == nil || (call.Kind() != zscript.NodeCallExpression && call.Kind() != zscript.NodeStateActionCall) {
+		return "", false
+	}
+	fn := call.ChildByFieldName(zscript.FieldFunction)
+	if fn == nil {
+		return "", false
+	}
+	owner = enclosingClass(call, tree.Source)
+	if fn.Kind() == zscript.NodeFieldExpression {
+		owner = ""
+		if typ, _, known := r.TypeOf(tree, fn.ChildByFieldName(zscript.FieldArgument)); known {
+			owner = typ
+		}
+		fn = fn.ChildByFieldName(zscript.FieldField)
+	}
+	var method *symbols.Method
+	for _, decl := range r.ResolveAll(tree, fn) {
+		if decl.Method != nil {
+			method = decl.Method
+			break
+		}
+	}
+	if method == nil {
+		return "", false
+	}
+
+	var param *symbols.Param
+	if named != "" {
+		for i, p := range method.Params {
+			if strings.EqualFold(p.Name, named) {
+				param = &method.Params[i]
+			}
+		}
+	} else {
+		index := 0
+		for i := uint(0); i < args.NamedChildCount(); i++ {
+			c := args.NamedChild(i)
+			if c.Id() == arg.Id() {
+				break
+			}
+			if c.Kind() != zscript.NodeComment {
+				index++
+			}
+		}
+		params := method.Params
+		if n := len(params); n > 1 && params[n-1].Variadic && index >= n-1 {
+			// The arguments of "..." repeat the parameter before it, as
+			// the labels of A_Jump do.
+			index = n - 2
+		}
+		if index < len(params) {
+			param = &params[index]
+		}
+	}
+	return owner, param != nil && strings.EqualFold(param.Type, "statelabel")
+}
+
+// Lookup returns the declaration of the label ref names. Like GZDoom, it
+// falls back from a dotted label such as "Death.Fire" to its longest
+// prefix that is defined, such as "Death". The labels of engine classes
+// come from EngineLabels and have the path engine.Path and no range.
+//
+// found is false if no label matches. complete is false if ref.Owner is
+// not known, or it or a class it inherits from is an engine class that
+// EngineLabels does not list, so that a label that is not found may yet
+// exist.
+func Lookup(r *resolve.Resolver, ref Reference) (decl resolve.Declaration, found, complete bool) {
+	class, super := ref.Owner, false
+	if strings.EqualFold(ref.Class, "super") {
+		super = true
+	} else if ref.Class != "" {
+		class = ref.Class
+	}
+	h := r.Hierarchy()
+	c := h.Class(class)
+	if c == nil {
+		return resolve.Declaration{}, false, false
+	}
+	lineage := append([]*hierarchy.Class{c}, h.Ancestors(class)...)
+	if super {
+		lineage = lineage[1:]
+	}
+	complete = true
+	for _, a := range lineage {
+		if _, listed := EngineLabels[a.Name]; !a.Defined() || a.Path == engine.Path && !listed {
+			complete = false
+		}
+	}
+
+	declared := r.StateLabels(class, super)
+	for name := ref.Label; name != ""; {
+		for _, d := range declared {
+			if strings.EqualFold(d.Name, name) {
+				return d, true, complete
+			}
+		}
+		for _, a := range lineage {
+			for _, l := range EngineLabels[a.Name] {
+				if strings.EqualFold(l, name) {
+					decl := resolve.Declaration{Path: engine.Path, Owner: a.Name}
+					decl.Name, decl.Kind = l, symbols.KindStateLabel
+					return decl, true, complete
+				}
+			}
+		}
+		i := strings.LastIndexByte(name, '.')
+		if i < 0 {
+			break
+		}
+		name = name[:i]
+	}
+	return resolve.Declaration{}, false, complete
+}
+
+// Check reports the state labels named in the files of p that do not
+// exist, in file order. References are resolved against p and the
+// engine's built-in declarations for the version p declares. A reference
+// that is resolved when it runs is not reported if a class that inherits
+// from its Owner may define the label.
+func Check(p *project.Project) []lint.Finding {
+	r := resolve.ForProject(p)
+	var findings []lint.Finding
+	for _, f := range p.Files {
+		for _, ref := range References(r, f.Tree) {
+			if _, found, complete := Lookup(r, ref); found || !complete || !ref.Goto && definedBelow(r, ref) {
+				continue
+			}
+			class := ref.Owner
+			switch {
+			case strings.EqualFold(ref.Class, "super"):
+				if c := r.Hierarchy().Class(class); c != nil && c.Parent != nil {
+					class = c.Parent.Name
+				}
+			case ref.Class != "":
+				class = ref.Class
+			}
+			findings = append(findings, lint.Finding{
+				Rule:     RuleUnresolvedLabel,
+				Severity: zscript.SeverityError,
+				Path:     ref.Path,
+				Range:    ref.Range,
+				Message:  fmt.Sprintf("state label %q is not defined in %s or the classes it inherits from", ref.String(), class),
+			})
+		}
+	}
+	return findings
+}
+
+// definedBelow reports whether a class that inherits from ref.Owner
+// defines the label ref names, or may define it.
+func definedBelow(r *resolve.Resolver, ref Reference) bool {
+	if ref.Class != "" {
+		return false
+	}
+	c := r.Hierarchy().Class(ref.Owner)
+	if c == nil {
+		return true
+	}
+	seen := map[*hierarchy.Class]bool{c: true}
+	stack := append([]*hierarchy.Class{}, c.Children...)
+	for len(stack) > 0 {
+		d := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if seen[d] {
+			continue
+		}
+		seen[d] = true
+		sub := ref
+		sub.Owner = d.Name
+		if _, found, complete := Lookup(r, sub); found || !complete {
+			return true
+		}
+		stack = append(stack, d.Children...)
+	}
+	return false
+}
+
+// enclosingClass returns the name of the class that contains node, or "".
+func enclosingClass(node *tree_sitter.Node, source []byte) string {
+	for n := node.Parent(); n != nil; n = n.Parent() {
+		if n.Kind() == zscript.NodeClassDefinition {
+			if name := n.ChildByFieldName(zscript.FieldName); name != nil {
+				return name.Utf8Text(source)
+			}
+			return ""
+		}
+	}
+	return ""
+}
+
+func childOfKind(node *tree_sitter.Node, kind string) *tree_sitter.Node {
+	for i := uint(0); i < node.NamedChildCount(); i++ {
+		if c := node.NamedChild(i); c.Kind() == kind {
+			return c
+		}
+	}
+	return nil
+}
@@ -0,0 +1,148 @@
+package labels_test
+
+import (
+	"context"
+	"testing"
+	"testing/fstest"
+
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/engine"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/labels"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/project"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/resolve"
+)
+
+const source = `version "4.12"
+
+class Base : Actor {
+	States {
+	See:
+		TNT1 A 1;
+		Loop;
+	Death:
+		TNT1 A 1;
+		Stop;
+	}
+}
+
+class Imp : Base {
+	void Hurt(Actor mo, Base b) {
+		mo.SetStateLabel("Pain");
+		b.SetStateLabel(st: 'Fly');
+		let s = ResolveState("Missile");
+	}
+	States {
+	Spawn:
+		TNT1 A 0 A_Jump(128, "See", "Death.Fire", "Melee");
+		TNT1 A 0 A_JumpIfInventory("Clip", 1, "Super::See");
+		TNT1 A 0 A_JumpIf(true, "Null");
+		Goto Super::Spawn;
+	Missile:
+		Goto Base::Missile;
+	}
+}
+
+class Demon : Imp {
+	States {
+	Melee:
+		TNT1 A 1;
+		Stop;
+	}
+}
+
+class Mystery : DoomImp {
+	States {
+	Spawn:
+		Goto Pain;
+	}
+}
+`
+
+func load(t *testing.T) *project.Project {
+	t.Helper()
+	p, err := project.Load(context.Background(), fstest.MapFS{"zscript.zs": {Data: []byte(source)}}, "zscript.zs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(p.Close)
+	return p
+}
+
+func TestReferences(t *testing.T) {
+	p := load(t)
+	refs := labels.References(resolve.ForProject(p), p.File("zscript.zs").Tree)
+	want := []labels.Reference{
+		{Label: "Fly", Owner: "Base"},
+		{Label: "Missile", Owner: "Imp"},
+		{Label: "See", Owner: "Imp"},
+		{Label: "Death.Fire", Owner: "Imp"},
+		{Label: "Melee", Owner: "Imp"},
+		{Label: "See", Class: "Super", Owner: "Imp"},
+		{Label: "Null", Owner: "Imp"},
+		{Label: "Spawn", Class: "Super", Owner: "Imp"},
+		{Label: "Missile", Class: "Base", Owner: "Imp"},
+		{Label: "Pain", Owner: "Mystery"},
+	}
+	// mo.SetStateLabel("Pain") is first, with an Owner of Actor.
+	if len(refs) != len(want)+1 {
+		t.Fatalf("References() = %+v", refs)
+	}
+	if refs[0].Label != "Pain" || refs[0].Owner != "Actor" {
+		t.Errorf("refs[0] = %+v", refs[0])
+	}
+	for i, w := range want {
+		got := refs[i+1]
+		if got.Label != w.Label || got.Class != w.Class || got.Owner != w.Owner {
+			t.Errorf("refs[%d] = %+v, want %+v", i+1, got, w)
+		}
+	}
+}
+
+func TestLookup(t *testing.T) {
+	p := load(t)
+	r := resolve.ForProject(p)
+	tests := []struct {
+		ref             labels.Reference
+		owner           string
+		found, complete bool
+	}{
+		{labels.Reference{Label: "See", Owner: "Imp"}, "Base", true, true},
+		{labels.Reference{Label: "death.fire", Owner: "Imp"}, "Base", true, true},
+		{labels.Reference{Label: "Spawn", Owner: "Imp"}, "Imp", true, true},
+		{labels.Reference{Label: "Spawn", Class: "Super", Owner: "Imp"}, "Actor", true, true},
+		{labels.Reference{Label: "Missile", Class: "Base", Owner: "Imp"}, "", false, true},
+		{labels.Reference{Label: "Pain", Owner: "Mystery"}, "", false, false},
+		{labels.Reference{Label: "Pain", Owner: ""}, "", false, false},
+	}
+	for _, tt := range tests {
+		decl, found, complete := labels.Lookup(r, tt.ref)
+		if found != tt.found || complete != tt.complete || decl.Owner != tt.owner {
+			t.Errorf("Lookup(%+v) = %s in %q, %v, %v", tt.ref, decl.Name, decl.Owner, found, complete)
+		}
+	}
+	if decl, _, _ := labels.Lookup(r, labels.Reference{Label: "Null", Owner: "Imp"}); decl.Path != engine.Path {
+		t.Errorf("Null is declared in %q", decl.Path)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	p := load(t)
+	var got []string
+	for _, f := range labels.Check(p) {
+		if f.Rule != labels.RuleUnresolvedLabel {
+			t.Errorf("rule = %q", f.Rule)
+		}
+		got = append(got, f.Message)
+	}
+	want := []string{
+		`state label "Fly" is not defined in Base or the classes it inherits from`,
+		`state label "Base::Missile" is not defined in Base or the classes it inherits from`,
+	}
+	if len(got) != len(want) {
+		t.Fatalf("Check() = %q", got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("finding %d = %q, want %q", i, got[i], want[i])
+		}
+	}
+}
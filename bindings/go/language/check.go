@@ -0,0 +1,174 @@
+package language
+
+import (
+	"fmt"
+	"strings"
+
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+
+	zscript "github.com/jlcrochet/tree-sitter-zscript/bindings/go"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/lint"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/project"
+)
+
+// The rule names of the findings.
+const (
+	// RuleMissingKey reports a key that no lump defines, or that no lump
+	// defines for one of the locales.
+	RuleMissingKey = "missing-key"
+	// RuleUnusedKey reports a defined key that nothing uses.
+	RuleUnusedKey = "unused-key"
+)
+
+// Options configures CheckWithOptions.
+type Options struct {
+	// Known are keys defined outside the lumps checked, such as the
+	// engine's own.
+	Known []string
+	// Used are keys used outside ZScript, such as by MENUDEF or MAPINFO,
+	// without the "$".
+	Used []string
+	// Locales are the locales each key must have text for. If nil, they
+	// are all the locales the lumps name.
+	Locales []string
+}
+
+// Reference is a localization key used in ZScript.
+type Reference struct {
+	// Key is the key, without the "$".
+	Key   string
+	Path  string
+	Range tree_sitter.Range
+}
+
+// References returns the keys used in tree, in source order: the strings
+// of the form "$KEY", which the engine localizes wherever it expects text,
+// and the keys passed to StringTable.Localize with a false second
+// argument, which need no "$".
+func References(tree *zscript.Tree) []Reference {
+	var refs []Reference
+	var v zscript.Visitor
+	v.On(zscript.NodeStringLiteral, func(node *tree_sitter.Node) zscript.WalkAction {
+		text := strings.Trim(node.Utf8Text(tree.Source), `"`)
+		key, ok := strings.CutPrefix(text, "$")
+		if !ok && isUnprefixedKey(node, tree.Source) {
+			key, ok = text, true
+		}
+		if ok && isKey(key) {
+			refs = append(refs, Reference{Key: key, Path: tree.Path, Range: node.Range()})
+		}
+		return zscript.WalkSkipChildren
+	})
+	zscript.Walk(tree.RootNode(), &v)
+	return refs
+}
+
+// isUnprefixedKey reports whether node is the first argument of a call
+// StringTable.Localize(node, false).
+func isUnprefixedKey(node *tree_sitter.Node, source []byte) bool {
+	args := node.Parent()
+	if args == nil || args.Kind() != zscript.NodeArgumentList || args.NamedChildCount() != 2 ||
+		args.NamedChild(0).Id() != node.Id() || args.NamedChild(1).Kind() != zscript.NodeFalse {
+		return false
+	}
+	call := args.Parent()
+	fn := call.ChildByFieldName(zscript.FieldFunction)
+	if call.Kind() != zscript.NodeCallExpression || fn == nil || fn.Kind() != zscript.NodeFieldExpression {
+		return false
+	}
+	receiver, method := fn.ChildByFieldName(zscript.FieldArgument), fn.ChildByFieldName(zscript.FieldField)
+	return receiver != nil && method != nil &&
+		strings.EqualFold(receiver.Utf8Text(source), "StringTable") &&
+		strings.EqualFold(method.Utf8Text(source), "Localize")
+}
+
+func isKey(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if !(r == '_' || r == '.' || r >= '0' && r <= '9' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z') {
+			return false
+		}
+	}
+	return true
+}
+
+// Check reports the keys p uses that files do not define, and the entries
+// of files whose keys nothing uses. A key that is defined for some locales
+// but not others is reported once per missing locale, at its first use.
+// ZScript findings come first, in file order, then those of the lumps.
+func Check(p *project.Project, files ...*File) []lint.Finding {
+	return CheckWithOptions(p, Options{}, files...)
+}
+
+// CheckWithOptions is like Check but takes options.
+func CheckWithOptions(p *project.Project, opts Options, files ...*File) []lint.Finding {
+	known := map[string]bool{}
+	for _, key := range opts.Known {
+		known[strings.ToLower(key)] = true
+	}
+	// defined maps the lowercased keys of files to their lowercased
+	// locales.
+	defined := map[string]map[string]bool{}
+	locales := opts.Locales
+	seenLocale := map[string]bool{}
+	for _, f := range files {
+		for _, e := range f.Entries {
+			key := strings.ToLower(e.Key)
+			if defined[key] == nil {
+				defined[key] = map[string]bool{}
+			}
+			for _, l := range e.Locales {
+				defined[key][l] = true
+				if opts.Locales == nil && !seenLocale[l] {
+					seenLocale[l] = true
+					locales = append(locales, l)
+				}
+			}
+		}
+	}
+	used := map[string]bool{}
+	for _, key := range opts.Used {
+		used[strings.ToLower(key)] = true
+	}
+
+	// checked holds the lowercased keys whose locales have been checked.
+	checked := map[string]bool{}
+	var findings []lint.Finding
+	report := func(rule, path string, r tree_sitter.Range, format string, args ...any) {
+		findings = append(findings, lint.Finding{
+			Rule:     rule,
+			Severity: zscript.SeverityWarning,
+			Path:     path,
+			Range:    r,
+			Message:  fmt.Sprintf(format, args...),
+		})
+	}
+	for _, f := range p.Files {
+		for _, r := range References(f.Tree) {
+			key := strings.ToLower(r.Key)
+			used[key] = true
+			switch {
+			case known[key]:
+			case defined[key] == nil:
+				report(RuleMissingKey, r.Path, r.Range, "key %q is not defined in LANGUAGE", r.Key)
+			case !checked[key]:
+				checked[key] = true
+				for _, l := range locales {
+					if !defined[key][strings.ToLower(l)] {
+						report(RuleMissingKey, r.Path, r.Range, "key %q has no text for locale %q", r.Key, l)
+					}
+				}
+			}
+		}
+	}
+	for _, f := range files {
+		for _, e := range f.Entries {
+			if !used[strings.ToLower(e.Key)] {
+				report(RuleUnusedKey, f.Path, e.Range, "key %q is never used", e.Key)
+			}
+		}
+	}
+	return findings
+}
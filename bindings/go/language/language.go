@@ -0,0 +1,295 @@
+// Package language reads LANGUAGE lumps and checks them against the
+// localization keys a ZScript project uses.
+//
+// A LANGUAGE lump is a list of sections, each a header naming locales and
+// the entries for them:
+//
+//	[enu default]
+//	MYMOD_PICKUP = "Picked up the ";
+//		"thing.";
+//	$ifgame(doom) MYMOD_TAG = "Imp";
+//
+// An entry is a key, "=", one or more strings that are joined together,
+// and a semicolon. A "$ifgame" condition may precede it.
+package language
+
+import (
+	"fmt"
+	"io/fs"
+	"path"
+	"strings"
+
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+)
+
+// Entry is the text of a key in a section.
+type Entry struct {
+	Key   string
+	Value string
+	// Locales are the locales of the section, in lower case, without
+	// "default".
+	Locales []string
+	// Default reports whether the section is also the default, the text
+	// the engine falls back to.
+	Default bool
+	// Game is the game of a "$ifgame" condition, in lower case, or "".
+	Game string
+	// Range locates the key.
+	Range tree_sitter.Range
+}
+
+// File is a parsed lump.
+type File struct {
+	Path    string
+	Entries []*Entry
+}
+
+// Lookup returns the first entry for key in locale, ignoring case, or nil. An
+// empty locale matches the default sections.
+func (f *File) Lookup(key, locale string) *Entry {
+	for _, e := range f.Entries {
+		if strings.EqualFold(e.Key, key) && e.has(locale) {
+			return e
+		}
+	}
+	return nil
+}
+
+// Locales returns the locales of the sections of f, in order of first
+// appearance.
+func (f *File) Locales() []string {
+	var locales []string
+	seen := map[string]bool{}
+	for _, e := range f.Entries {
+		for _, l := range e.Locales {
+			if !seen[l] {
+				seen[l] = true
+				locales = append(locales, l)
+			}
+		}
+	}
+	return locales
+}
+
+func (e *Entry) has(locale string) bool {
+	if locale == "" {
+		return e.Default
+	}
+	for _, l := range e.Locales {
+		if strings.EqualFold(l, locale) {
+			return true
+		}
+	}
+	return false
+}
+
+// Error is a syntax error in a lump.
+type Error struct {
+	Path    string
+	Point   tree_sitter.Point
+	Message string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("language: %s:%d:%d: %s", e.Path, e.Point.Row+1, e.Point.Column+1, e.Message)
+}
+
+// Parse parses the lump source read from path.
+func Parse(path string, source []byte) (*File, error) {
+	tokens, err := scan(path, source)
+	if err != nil {
+		return nil, err
+	}
+	f := &File{Path: path}
+	errorAt := func(t token, format string, args ...any) error {
+		return &Error{Path: path, Point: t.Range.StartPoint, Message: fmt.Sprintf(format, args...)}
+	}
+	var locales []string
+	var isDefault, inSection bool
+	for len(tokens) > 0 {
+		t := tokens[0]
+		if t.is("[") {
+			end := 1
+			for end < len(tokens) && !tokens[end].is("]") {
+				if tokens[end].quoted || tokens[end].punct {
+					return nil, errorAt(tokens[end], "unexpected %q in section header", tokens[end].text)
+				}
+				end++
+			}
+			if end == len(tokens) {
+				return nil, errorAt(t, "unclosed [")
+			}
+			locales, isDefault, inSection = nil, false, true
+			for _, l := range tokens[1:end] {
+				switch name := strings.ToLower(l.text); name {
+				case "default":
+					isDefault = true
+				case "*":
+				default:
+					locales = append(locales, name)
+				}
+			}
+			tokens = tokens[end+1:]
+			continue
+		}
+
+		e := &Entry{Locales: locales, Default: isDefault}
+		if strings.HasPrefix(strings.ToLower(t.text), "$ifgame") && !t.quoted {
+			// The condition may be split, as in "$ifgame (doom)".
+			cond := t.text
+			tokens = tokens[1:]
+			for !strings.HasSuffix(cond, ")") && len(tokens) > 0 && !tokens[0].punct && !tokens[0].quoted {
+				cond += tokens[0].text
+				tokens = tokens[1:]
+			}
+			open := strings.IndexByte(cond, '(')
+			if open < 0 || !strings.HasSuffix(cond, ")") {
+				return nil, errorAt(t, "malformed condition %q", cond)
+			}
+			e.Game = strings.ToLower(strings.TrimSpace(cond[open+1 : len(cond)-1]))
+			if len(tokens) == 0 {
+				return nil, errorAt(t, "condition without an entry")
+			}
+			t = tokens[0]
+		}
+		if t.quoted || t.punct {
+			return nil, errorAt(t, "unexpected %q", t.text)
+		}
+		if !inSection {
+			return nil, errorAt(t, "entry outside a section")
+		}
+		if len(tokens) < 2 || !tokens[1].is("=") {
+			return nil, errorAt(t, "expected = after %q", t.text)
+		}
+		e.Key, e.Range = t.text, t.Range
+		tokens = tokens[2:]
+		var value strings.Builder
+		for len(tokens) > 0 && tokens[0].quoted {
+			value.WriteString(tokens[0].text)
+			tokens = tokens[1:]
+		}
+		if len(tokens) == 0 || !tokens[0].is(";") {
+			return nil, errorAt(t, "expected strings and ; after %q", e.Key)
+		}
+		tokens = tokens[1:]
+		e.Value = value.String()
+		f.Entries = append(f.Entries, e)
+	}
+	return f, nil
+}
+
+// token is a token of a lump.
+type token struct {
+	text   string
+	quoted bool
+	// punct reports whether the token is one of [ ] = ;
+	punct bool
+	tree_sitter.Range
+}
+
+func (t token) is(punct string) bool {
+	return t.punct && t.text == punct
+}
+
+// scan splits source into words, strings and the punctuation [ ] = and ;,
+// skipping comments. Strings keep the escape sequences of the engine, such
+// as "\n", as written.
+func scan(path string, source []byte) ([]token, error) {
+	var tokens []token
+	var point tree_sitter.Point
+	i := 0
+	advance := func() {
+		if source[i] == '\n' {
+			point.Row++
+			point.Column = 0
+		} else {
+			point.Column++
+		}
+		i++
+	}
+	for i < len(source) {
+		c := source[i]
+		start, startPoint := i, point
+		switch {
+		case c == ' ' || c == '\t' || c == '\r' || c == '\n':
+			advance()
+			continue
+		case c == '/' && i+1 < len(source) && source[i+1] == '/':
+			for i < len(source) && source[i] != '\n' {
+				advance()
+			}
+			continue
+		case c == '/' && i+1 < len(source) && source[i+1] == '*':
+			advance()
+			advance()
+			for i < len(source) && !(source[i] == '*' && i+1 < len(source) && source[i+1] == '/') {
+				advance()
+			}
+			if i >= len(source) {
+				return nil, &Error{Path: path, Point: startPoint, Message: "unterminated comment"}
+			}
+			advance()
+			advance()
+			continue
+		}
+
+		t := token{}
+		switch {
+		case c == '"':
+			t.quoted = true
+			advance()
+			for i < len(source) && source[i] != '"' {
+				if source[i] == '\\' && i+1 < len(source) {
+					advance()
+				}
+				advance()
+			}
+			if i >= len(source) {
+				return nil, &Error{Path: path, Point: startPoint, Message: "unterminated string"}
+			}
+			t.text = string(source[start+1 : i])
+			advance()
+		case strings.IndexByte("[]=;", c) >= 0:
+			t.punct = true
+			advance()
+			t.text = string(c)
+		default:
+			for i < len(source) && !strings.ContainsRune(" \t\r\n\"[]=;", rune(source[i])) &&
+				!(source[i] == '/' && i+1 < len(source) && (source[i+1] == '/' || source[i+1] == '*')) {
+				advance()
+			}
+			t.text = string(source[start:i])
+		}
+		t.Range = tree_sitter.Range{StartByte: uint(start), EndByte: uint(i), StartPoint: startPoint, EndPoint: point}
+		tokens = append(tokens, t)
+	}
+	return tokens, nil
+}
+
+// Load reads every LANGUAGE lump at the root of fsys. A lump may have an
+// extension, as in "language.enu"; lumps in the CSV format, with the
+// extension ".csv", are not read.
+func Load(fsys fs.FS) ([]*File, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+	var files []*File
+	for _, e := range entries {
+		name := strings.ToLower(e.Name())
+		ext := path.Ext(name)
+		if e.IsDir() || strings.TrimSuffix(name, ext) != "language" || ext == ".csv" {
+			continue
+		}
+		source, err := fs.ReadFile(fsys, e.Name())
+		if err != nil {
+			return nil, err
+		}
+		f, err := Parse(e.Name(), source)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, f)
+	}
+	return files, nil
+}
@@ -0,0 +1,134 @@
+package language_test
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/language"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/project"
+)
+
+const enu = `// English.
+[enu default]
+MYMOD_PICKUP = "Picked up the "
+	"thing.";
+$ifgame(doom) MYMOD_TAG = "Imp";
+MYMOD_MENU = "Options";
+MYMOD_UNUSED = "Nothing";
+`
+
+const fra = `[fra]
+MYMOD_PICKUP = "Vous avez pris la chose."; /* pickup */
+MYMOD_MENU = "Options";
+`
+
+const source = `class Thing : Inventory {
+	Default {
+		Tag "$MYMOD_TAG";
+		Inventory.PickupMessage "$MYMOD_PICKUP";
+	}
+	void Greet() {
+		A_Print(StringTable.Localize("MYMOD_PICKUP", false));
+		A_Print(StringTable.Localize("$MYMOD_MISSING"));
+		A_Print("$1 is not a key?");
+		A_Print(StringTable.Localize("plain text"));
+	}
+}
+`
+
+func TestParse(t *testing.T) {
+	f, err := language.Parse("language.enu", []byte(enu))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, e := range f.Entries {
+		got = append(got, strings.Join([]string{strings.Join(e.Locales, ","), e.Game, e.Key, e.Value}, "|"))
+	}
+	want := []string{
+		"enu||MYMOD_PICKUP|Picked up the thing.",
+		"enu|doom|MYMOD_TAG|Imp",
+		"enu||MYMOD_MENU|Options",
+		"enu||MYMOD_UNUSED|Nothing",
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("got:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+	if e := f.Lookup("mymod_tag", ""); e == nil || !e.Default || e.Range.StartPoint.Row != 4 || e.Range.StartPoint.Column != 14 {
+		t.Errorf("Lookup = %+v", e)
+	}
+	if e := f.Lookup("MYMOD_TAG", "fra"); e != nil {
+		t.Errorf("Lookup for fra = %+v", e)
+	}
+	if got := f.Locales(); !slices.Equal(got, []string{"enu"}) {
+		t.Errorf("Locales = %v", got)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct{ source, want string }{
+		{`KEY = "x";`, `language: language:1:1: entry outside a section`},
+		{"[enu\nKEY = \"x\";", `language: language:2:5: unexpected "=" in section header`},
+		{"[enu]\nKEY \"x\";", `language: language:2:1: expected = after "KEY"`},
+		{"[enu]\nKEY = \"x\"", `language: language:2:1: expected strings and ; after "KEY"`},
+		{"[enu]\n$ifgame(doom", `language: language:2:1: malformed condition "$ifgame(doom"`},
+		{"[enu]\nKEY = \"x;", `language: language:2:7: unterminated string`},
+	}
+	for _, test := range tests {
+		_, err := language.Parse("language", []byte(test.source))
+		if err == nil || err.Error() != test.want {
+			t.Errorf("Parse(%q) = %v, want %s", test.source, err, test.want)
+		}
+	}
+}
+
+func TestCheck(t *testing.T) {
+	fsys := fstest.MapFS{
+		"zscript.zs":   {Data: []byte(source)},
+		"LANGUAGE.enu": {Data: []byte(enu)},
+		"language.fra": {Data: []byte(fra)},
+		"language.csv": {Data: []byte("default,enu\n")},
+	}
+	p, err := project.Load(context.Background(), fsys, "zscript.zs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+	files, err := language.Load(fsys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 2 {
+		t.Fatalf("Load read %d files", len(files))
+	}
+
+	var got []string
+	for _, f := range language.CheckWithOptions(p, language.Options{Used: []string{"MYMOD_MENU"}}, files...) {
+		got = append(got, f.String())
+	}
+	want := []string{
+		`zscript.zs:3:7: warning: key "MYMOD_TAG" has no text for locale "fra" (missing-key)`,
+		`zscript.zs:8:32: warning: key "MYMOD_MISSING" is not defined in LANGUAGE (missing-key)`,
+		`LANGUAGE.enu:7:1: warning: key "MYMOD_UNUSED" is never used (unused-key)`,
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("got:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	got = nil
+	opts := language.Options{Known: []string{"mymod_missing"}, Locales: []string{"enu"}}
+	for _, f := range language.CheckWithOptions(p, opts, files...) {
+		got = append(got, f.Path+" "+f.Message)
+	}
+	want = []string{
+		`LANGUAGE.enu key "MYMOD_MENU" is never used`,
+		`LANGUAGE.enu key "MYMOD_UNUSED" is never used`,
+		`language.fra key "MYMOD_MENU" is never used`,
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("with options:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
@@ -0,0 +1,293 @@
+package lint
+
+import (
+	"strings"
+
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+
+	zscript "github.com/jlcrochet/tree-sitter-zscript/bindings/go"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/engine"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/eval"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/resolve"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/zscriptast"
+)
+
+type arrayBounds struct{}
+
+func (arrayBounds) Name() string { return "array-bounds" }
+func (arrayBounds) Doc() string {
+	return "array is indexed out of bounds, has no elements, or is resized while being iterated over"
+}
+func (arrayBounds) Severity() zscript.Severity { return zscript.SeverityError }
+
+func (arrayBounds) Check(pass *Pass) {
+	a := &arrayChecker{pass: pass, eval: eval.New(resolve.NewWithEngine(engine.Version, pass.Tables...), func(path string) *zscript.Tree {
+		if path == pass.Tree.Path {
+			return pass.Tree
+		}
+		return nil
+	})}
+	var v zscript.Visitor
+	v.On(zscript.NodeArrayDeclarator, func(node *tree_sitter.Node) zscript.WalkAction {
+		if size := node.ChildByFieldName(zscript.FieldSize); size != nil {
+			if n, ok := a.constant(size); ok && n <= 0 {
+				pass.ReportNode(size, "array %q has size %d", a.name(node), n)
+			}
+		}
+		return zscript.WalkContinue
+	})
+	v.On(zscript.NodeSubscriptExpression, func(node *tree_sitter.Node) zscript.WalkAction {
+		a.subscript(node)
+		return zscript.WalkContinue
+	})
+	v.On(zscript.NodeForeachStatement, func(node *tree_sitter.Node) zscript.WalkAction {
+		collection := node.ChildByFieldName(zscript.FieldCollection)
+		if body := node.ChildByFieldName(zscript.FieldBody); collection != nil && body != nil {
+			for _, call := range a.resizes(body, pass.Text(collection)) {
+				pass.ReportNode(&call, "%s is resized while foreach iterates over it", pass.Text(collection))
+			}
+		}
+		return zscript.WalkContinue
+	})
+	v.On(zscript.NodeForStatement, func(node *tree_sitter.Node) zscript.WalkAction {
+		a.cachedSize(node)
+		return zscript.WalkContinue
+	})
+	zscript.Walk(pass.Tree.RootNode(), &v)
+}
+
+// resizingMethods are the methods of dynamic arrays and maps that change
+// the number of elements.
+var resizingMethods = map[string]bool{
+	"push": true, "pop": true, "delete": true, "insert": true, "clear": true,
+	"resize": true, "reserve": true, "append": true, "move": true, "copy": true,
+	"remove": true,
+}
+
+type arrayChecker struct {
+	pass *Pass
+	eval *eval.Evaluator
+}
+
+// array is what a name indexed by a subscript was declared as: a fixed
+// array of the sizes of its dimensions, of which those that are not
+// constant are -1, or a dynamic array.
+type array struct {
+	sizes   []int64
+	dynamic bool
+}
+
+// constant returns the value of node if it folds to an int.
+func (a *arrayChecker) constant(node *tree_sitter.Node) (int64, bool) {
+	v, err := a.eval.Eval(a.pass.Tree, node)
+	if err != nil || v.Kind != eval.Int {
+		return 0, false
+	}
+	return int64(v.Int), true
+}
+
+// name returns the name declared by a declarator.
+func (a *arrayChecker) name(declarator *tree_sitter.Node) string {
+	name := zscriptast.DeclaratorName(zscriptast.Wrap(declarator, a.pass.Tree.Source))
+	if name.Raw == nil {
+		return ""
+	}
+	return a.pass.Text(name.Raw)
+}
+
+// subscript checks the constant indexes of node, the outermost of a chain
+// of subscripts, against the array it indexes.
+func (a *arrayChecker) subscript(node *tree_sitter.Node) {
+	if parent := node.Parent(); parent != nil && parent.Kind() == zscript.NodeSubscriptExpression {
+		if arg := parent.ChildByFieldName(zscript.FieldArgument); arg != nil && arg.Id() == node.Id() {
+			return
+		}
+	}
+	var indexes []*tree_sitter.Node
+	base := node
+	for base != nil && base.Kind() == zscript.NodeSubscriptExpression {
+		indexes = append([]*tree_sitter.Node{base.ChildByFieldName(zscript.FieldIndex)}, indexes...)
+		base = base.ChildByFieldName(zscript.FieldArgument)
+	}
+	if base == nil {
+		return
+	}
+	arr, ok := a.lookup(base)
+	if !ok {
+		return
+	}
+	name := a.pass.Text(base)
+	for i, index := range indexes {
+		if index == nil || (arr.dynamic && i > 0) {
+			continue
+		}
+		n, ok := a.constant(index)
+		switch {
+		case !ok:
+		case n < 0:
+			a.pass.ReportNode(index, "index %d of %s is negative", n, name)
+		case !arr.dynamic && i < len(arr.sizes) && arr.sizes[i] >= 0 && n >= arr.sizes[i]:
+			a.pass.ReportNode(index, "index %d is out of bounds for %s, which has %d elements", n, name, arr.sizes[i])
+		}
+	}
+}
+
+// lookup finds the declaration of base, an identifier or a field of self,
+// among the locals, parameters and fields of the enclosing scopes. It
+// reports false if base is not an array declared in the file's enclosing
+// class or struct.
+func (a *arrayChecker) lookup(base *tree_sitter.Node) (array, bool) {
+	local := base.Kind() == zscript.NodeIdentifier
+	if base.Kind() == zscript.NodeFieldExpression {
+		arg := base.ChildByFieldName(zscript.FieldArgument)
+		if arg == nil || arg.Kind() != zscript.NodeSelfExpression {
+			return array{}, false
+		}
+		base = base.ChildByFieldName(zscript.FieldField)
+	} else if !local {
+		return array{}, false
+	}
+	if base == nil {
+		return array{}, false
+	}
+	name := a.pass.Text(base)
+	for scope := base.Parent(); scope != nil; scope = scope.Parent() {
+		switch scope.Kind() {
+		case zscript.NodeCompoundStatement:
+			if !local {
+				continue
+			}
+			var found *array
+			for _, stmt := range namedChildrenOfKind(scope, zscript.NodeDeclaration) {
+				if stmt.StartByte() >= base.StartByte() {
+					break
+				}
+				if arr, ok := a.declares(&stmt, name); ok {
+					found = arr
+				}
+			}
+			if found != nil {
+				return *found, found.sizes != nil || found.dynamic
+			}
+		case zscript.NodeForStatement:
+			if init := scope.ChildByFieldName(zscript.FieldInitializer); local && init != nil && init.Kind() == zscript.NodeDeclaration {
+				if arr, ok := a.declares(init, name); ok {
+					return *arr, arr.sizes != nil || arr.dynamic
+				}
+			}
+		case zscript.NodeForeachStatement:
+			if v := scope.ChildByFieldName(zscript.FieldVariable); local && v != nil && strings.EqualFold(a.pass.Text(v), name) {
+				return array{}, false
+			}
+		case zscript.NodeMethodDefinition, zscript.NodeFunctionDefinition:
+			params := scope.ChildByFieldName(zscript.FieldParameters)
+			if local && params != nil {
+				for _, p := range namedChildrenOfKind(params, zscript.NodeParameterDeclaration) {
+					if strings.EqualFold(a.name(&p), name) {
+						return array{}, false
+					}
+				}
+			}
+		case zscript.NodeClassDefinition, zscript.NodeStructDefinition:
+			for _, f := range namedChildrenOfKind(scope, zscript.NodeFieldDeclaration) {
+				if arr, ok := a.declares(&f, name); ok {
+					return *arr, arr.sizes != nil || arr.dynamic
+				}
+			}
+			return array{}, false
+		}
+	}
+	return array{}, false
+}
+
+// declares returns what decl, a declaration or field declaration, declares
+// name as, if it declares it.
+func (a *arrayChecker) declares(decl *tree_sitter.Node, name string) (*array, bool) {
+	typ := decl.ChildByFieldName(zscript.FieldType)
+	for _, d := range namedChildrenByField(decl, zscript.FieldDeclarator) {
+		if !strings.EqualFold(a.name(&d), name) {
+			continue
+		}
+		arr := &array{}
+		n := &d
+		if n.Kind() == zscript.NodeInitDeclarator {
+			n = n.ChildByFieldName(zscript.FieldDeclarator)
+		}
+		for n != nil && n.Kind() == zscript.NodeArrayDeclarator {
+			size := int64(-1)
+			if s := n.ChildByFieldName(zscript.FieldSize); s != nil {
+				if v, ok := a.constant(s); ok {
+					size = v
+				}
+			}
+			arr.sizes = append([]int64{size}, arr.sizes...)
+			n = n.ChildByFieldName(zscript.FieldDeclarator)
+		}
+		if arr.sizes == nil && typ != nil && typ.Kind() == zscript.NodeArrayType {
+			arr.dynamic = true
+		}
+		return arr, true
+	}
+	return nil, false
+}
+
+// resizes returns the calls in node of methods that resize the array or
+// map spelled collection.
+func (a *arrayChecker) resizes(node *tree_sitter.Node, collection string) []tree_sitter.Node {
+	var calls []tree_sitter.Node
+	var v zscript.Visitor
+	v.On(zscript.NodeCallExpression, func(call *tree_sitter.Node) zscript.WalkAction {
+		fn := call.ChildByFieldName(zscript.FieldFunction)
+		if fn == nil || fn.Kind() != zscript.NodeFieldExpression {
+			return zscript.WalkContinue
+		}
+		arg, field := fn.ChildByFieldName(zscript.FieldArgument), fn.ChildByFieldName(zscript.FieldField)
+		if arg != nil && field != nil && resizingMethods[strings.ToLower(a.pass.Text(field))] && sameExpression(a.pass.Text(arg), collection) {
+			calls = append(calls, *call)
+		}
+		return zscript.WalkContinue
+	})
+	zscript.Walk(node, &v)
+	return calls
+}
+
+// cachedSize reports the resizing of an array in the body of node, a for
+// loop, whose condition compares against the array's size saved in a
+// variable by the loop's initializer: once the array shrinks, the loop
+// runs past its end.
+func (a *arrayChecker) cachedSize(node *tree_sitter.Node) {
+	init := node.ChildByFieldName(zscript.FieldInitializer)
+	cond := node.ChildByFieldName(zscript.FieldCondition)
+	body := node.ChildByFieldName(zscript.FieldBody)
+	if init == nil || cond == nil || body == nil || init.Kind() != zscript.NodeDeclaration {
+		return
+	}
+	for _, d := range namedChildrenOfKind(init, zscript.NodeInitDeclarator) {
+		value := d.ChildByFieldName(zscript.FieldValue)
+		if value == nil || value.Kind() != zscript.NodeCallExpression {
+			continue
+		}
+		fn := value.ChildByFieldName(zscript.FieldFunction)
+		if fn == nil || fn.Kind() != zscript.NodeFieldExpression {
+			continue
+		}
+		arg, field := fn.ChildByFieldName(zscript.FieldArgument), fn.ChildByFieldName(zscript.FieldField)
+		if arg == nil || field == nil || !strings.EqualFold(a.pass.Text(field), "Size") {
+			continue
+		}
+		name := a.name(&d)
+		if !referencedAfter(a.pass, cond, name, cond.StartByte()) {
+			continue
+		}
+		for _, call := range a.resizes(body, a.pass.Text(arg)) {
+			a.pass.ReportNode(&call, "%s is resized in a loop bounded by its earlier size in %q", a.pass.Text(arg), name)
+		}
+	}
+}
+
+// sameExpression reports whether two expressions are spelled the same,
+// ignoring case and spaces.
+func sameExpression(a, b string) bool {
+	strip := func(s string) string { return strings.Join(strings.Fields(s), "") }
+	return strings.EqualFold(strip(a), strip(b))
+}
@@ -0,0 +1,181 @@
+package lint
+
+import (
+	"strings"
+
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+
+	zscript "github.com/jlcrochet/tree-sitter-zscript/bindings/go"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/defaults"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/engine"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/zscriptast"
+)
+
+// classParam is a parameter, or a property value, that takes the name of
+// a class: the index of the argument and the class it must inherit from.
+type classParam struct {
+	index    int
+	ancestor string
+}
+
+// classCalls are the functions that take the name of a class, by lower
+// case name.
+var classCalls = map[string]classParam{
+	"spawn":               {0, "Actor"},
+	"a_spawnitem":         {0, "Actor"},
+	"a_spawnitemex":       {0, "Actor"},
+	"a_spawnprojectile":   {0, "Actor"},
+	"a_custommissile":     {0, "Actor"},
+	"a_fireprojectile":    {0, "Actor"},
+	"a_firecustommissile": {0, "Actor"},
+	"a_spawndebris":       {0, "Actor"},
+	"a_throwgrenade":      {0, "Actor"},
+	"a_dropitem":          {0, "Actor"},
+	"spawnmissileangle":   {0, "Actor"},
+	"spawnmissile":        {1, "Actor"},
+	"a_giveinventory":     {0, "Inventory"},
+	"a_takeinventory":     {0, "Inventory"},
+	"a_givetotarget":      {0, "Inventory"},
+	"a_takefromtarget":    {0, "Inventory"},
+	"a_setinventory":      {0, "Inventory"},
+	"a_radiusgive":        {0, "Inventory"},
+	"a_jumpifinventory":   {0, "Inventory"},
+	"giveinventory":       {0, "Inventory"},
+	"takeinventory":       {0, "Inventory"},
+	"setinventory":        {0, "Inventory"},
+	"findinventory":       {0, "Inventory"},
+	"countinv":            {0, "Inventory"},
+	"checkinventory":      {0, "Inventory"},
+	"a_selectweapon":      {0, "Weapon"},
+}
+
+// classProperties are the Default block properties that take the name of
+// a class, by lower case name.
+var classProperties = map[string]classParam{
+	"dropitem":                     {0, "Actor"},
+	"powerup.type":                 {0, "Powerup"},
+	"weapon.ammotype":              {0, "Ammo"},
+	"weapon.ammotype1":             {0, "Ammo"},
+	"weapon.ammotype2":             {0, "Ammo"},
+	"weapon.sisterweapon":          {0, "Weapon"},
+	"player.startitem":             {0, "Inventory"},
+	"player.morphweapon":           {0, "Weapon"},
+	"player.spawnclass":            {0, "PlayerPawn"},
+	"morphprojectile.playerclass":  {0, "PlayerPawn"},
+	"morphprojectile.monsterclass": {0, "Actor"},
+}
+
+type unknownClasses struct{}
+
+func (unknownClasses) Name() string { return "unknown-class" }
+func (unknownClasses) Doc() string {
+	return "class name given as a string is not a known class of the right kind"
+}
+func (unknownClasses) Severity() zscript.Severity { return zscript.SeverityWarning }
+
+func (unknownClasses) Check(pass *Pass) {
+	var v zscript.Visitor
+	v.On(zscript.NodeCallExpression, func(call *tree_sitter.Node) zscript.WalkAction {
+		fn := call.ChildByFieldName(zscript.FieldFunction)
+		if fn != nil && fn.Kind() == zscript.NodeFieldExpression {
+			fn = fn.ChildByFieldName(zscript.FieldField)
+		}
+		args := call.ChildByFieldName(zscript.FieldArguments)
+		if fn == nil || args == nil {
+			return zscript.WalkContinue
+		}
+		param, ok := classCalls[strings.ToLower(pass.Text(fn))]
+		if !ok {
+			return zscript.WalkContinue
+		}
+		positional := 0
+		for i := uint(0); i < args.NamedChildCount(); i++ {
+			arg := args.NamedChild(i)
+			if arg.Kind() == zscript.NodeComment {
+				continue
+			}
+			if arg.Kind() == zscript.NodeNamedArgument {
+				break
+			}
+			if positional == param.index {
+				checkClassName(pass, arg, param.ancestor, false)
+				break
+			}
+			positional++
+		}
+		return zscript.WalkContinue
+	})
+	zscript.Walk(pass.Tree.RootNode(), &v)
+
+	for _, c := range zscriptast.NewFile(pass.Tree.Tree, pass.Tree.Source).Classes() {
+		for _, p := range defaults.ForClass(c).Properties {
+			param, ok := classProperties[strings.ToLower(p.Name)]
+			if !ok || param.index >= len(p.Values) {
+				continue
+			}
+			checkClassName(pass, p.Values[param.index].Raw, param.ancestor, strings.EqualFold(p.Name, "Powerup.Type"))
+		}
+	}
+}
+
+// checkClassName reports node, a class name given as a string or name
+// literal, if it names no class the project or the engine defines, or a
+// class that does not inherit from ancestor. A powerup name may leave out
+// the "Power" prefix, as GZDoom tries the name with it too.
+func checkClassName(pass *Pass, node *tree_sitter.Node, ancestor string, powerup bool) {
+	if node.Kind() != zscript.NodeStringLiteral && node.Kind() != zscript.NodeNameLiteral {
+		return
+	}
+	l := newLiteral(pass, node)
+	if l == nil {
+		return
+	}
+	name := string(l.text)
+	if name == "" || strings.EqualFold(name, "None") {
+		return
+	}
+	is, known := inherits(pass, name, ancestor)
+	if !known && powerup {
+		if is, known = inherits(pass, "Power"+name, ancestor); known {
+			name = "Power" + name
+		}
+	}
+	switch {
+	case !known:
+		pass.ReportNode(node, "unknown class %q", name)
+	case !is:
+		pass.ReportNode(node, "class %s is not a subclass of %s", name, ancestor)
+	}
+}
+
+// inherits reports whether the class named name is ancestor or inherits
+// from it, looking its ancestry up in the hierarchy of the pass and then
+// among the classes of the engine. known is false if there is no class of
+// the name; is is set if the ancestry cannot be followed to its end.
+func inherits(pass *Pass, name, ancestor string) (is, known bool) {
+	seen := map[string]bool{}
+	for class := name; class != ""; {
+		if strings.EqualFold(class, ancestor) {
+			return true, true
+		}
+		key := strings.ToLower(class)
+		if seen[key] {
+			return true, true
+		}
+		seen[key] = true
+		if c := pass.Hierarchy.Class(class); c != nil && c.Defined() {
+			if c.Parent == nil {
+				return false, true
+			}
+			class = c.Parent.Name
+			continue
+		}
+		_, parent, ok := engine.Class(class)
+		if !ok {
+			// A parent that is not known may be anything.
+			return class != name, class != name
+		}
+		class = parent
+	}
+	return false, true
+}
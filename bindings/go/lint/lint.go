@@ -0,0 +1,393 @@
+// Package lint runs static checks over parsed ZScript files and reports
+// their findings.
+//
+// A check is a Rule. The Linter runs a set of rules over every file of a
+// project, giving each rule a Pass with the file's parse tree, the symbol
+// tables of all files and the class hierarchy built from them and the
+// engine's built-in classes. Rules may attach a Fix to a finding; FixAll
+// applies them. Besides the built-in rules, a project may write its own as
+// tree-sitter queries; see QueryRule.
+package lint
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+
+	zscript "github.com/jlcrochet/tree-sitter-zscript/bindings/go"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/engine"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/hierarchy"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/project"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/symbols"
+)
+
+// Rule is a single check.
+type Rule interface {
+	// Name identifies the rule in findings, such as "unused-local".
+	Name() string
+	// Doc is a one-line description of what the rule reports.
+	Doc() string
+	// Severity is the severity of the rule's findings.
+	Severity() zscript.Severity
+	// Check inspects pass.Tree and reports findings through the pass.
+	Check(pass *Pass)
+}
+
+// Pass is the input of a rule for one file.
+type Pass struct {
+	Tree  *zscript.Tree
+	Table *symbols.Table
+	// Tables are the symbol tables of every file being linted, including
+	// this one.
+	Tables []*symbols.Table
+	// Hierarchy holds the classes of Tables and those of package engine.
+	Hierarchy *hierarchy.Hierarchy
+
+	rule     Rule
+	findings *[]Finding
+}
+
+// Report records a finding at r.
+func (p *Pass) Report(r tree_sitter.Range, format string, args ...any) {
+	*p.findings = append(*p.findings, Finding{
+		Rule:     p.rule.Name(),
+		Severity: p.rule.Severity(),
+		Path:     p.Tree.Path,
+		Range:    r,
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
+
+// ReportFix records a finding at r that fix resolves.
+func (p *Pass) ReportFix(r tree_sitter.Range, fix *Fix, format string, args ...any) {
+	p.Report(r, format, args...)
+	(*p.findings)[len(*p.findings)-1].Fix = fix
+}
+
+// Note attaches a note at r in the file at path to the finding reported
+// last, such as one showing where a declaration the finding names is.
+func (p *Pass) Note(path string, r tree_sitter.Range, format string, args ...any) {
+	f := &(*p.findings)[len(*p.findings)-1]
+	f.Notes = append(f.Notes, Note{Path: path, Range: r, Message: fmt.Sprintf(format, args...)})
+}
+
+// ReportNode records a finding spanning node.
+func (p *Pass) ReportNode(node *tree_sitter.Node, format string, args ...any) {
+	p.Report(node.Range(), format, args...)
+}
+
+// Text returns the source text of node.
+func (p *Pass) Text(node *tree_sitter.Node) string {
+	return node.Utf8Text(p.Tree.Source)
+}
+
+// Finding is a problem reported by a rule.
+type Finding struct {
+	Rule     string
+	Severity zscript.Severity
+	Path     string
+	Range    tree_sitter.Range
+	Message  string
+	// Fix, if set, is a change to the file that resolves the finding.
+	Fix *Fix
+	// Notes point to other code that explains the finding.
+	Notes []Note
+}
+
+// Note is a remark on a finding about code elsewhere.
+type Note struct {
+	Path    string
+	Range   tree_sitter.Range
+	Message string
+}
+
+// Fix is a set of edits to one file that resolves a finding.
+type Fix struct {
+	// Message describes the change, such as "add override".
+	Message string
+	Edits   []Edit
+}
+
+// Edit replaces a range of a file with new text. An empty range inserts
+// the text.
+type Edit struct {
+	Range   tree_sitter.Range
+	NewText string
+}
+
+// Replace returns an edit replacing node with text.
+func Replace(node *tree_sitter.Node, text string) Edit {
+	return Edit{Range: node.Range(), NewText: text}
+}
+
+// Insert returns an edit inserting text at the start of node, or at its
+// end if after is set.
+func Insert(node *tree_sitter.Node, text string, after bool) Edit {
+	r := node.Range()
+	if after {
+		r.StartByte, r.StartPoint = r.EndByte, r.EndPoint
+	} else {
+		r.EndByte, r.EndPoint = r.StartByte, r.StartPoint
+	}
+	return Edit{Range: r, NewText: text}
+}
+
+func (f Finding) String() string {
+	p := f.Range.StartPoint
+	return fmt.Sprintf("%s:%d:%d: %s: %s (%s)", f.Path, p.Row+1, p.Column+1, f.Severity, f.Message, f.Rule)
+}
+
+// jsonFinding is the machine-readable form of a Finding. Lines and columns
+// are 1-based; columns count bytes.
+type jsonFinding struct {
+	Rule      string     `json:"rule"`
+	Severity  string     `json:"severity"`
+	Path      string     `json:"path"`
+	Line      uint       `json:"line"`
+	Column    uint       `json:"column"`
+	EndLine   uint       `json:"endLine"`
+	EndColumn uint       `json:"endColumn"`
+	Message   string     `json:"message"`
+	Fix       *jsonFix   `json:"fix,omitempty"`
+	Notes     []jsonNote `json:"notes,omitempty"`
+}
+
+type jsonNote struct {
+	Path    string `json:"path"`
+	Line    uint   `json:"line"`
+	Column  uint   `json:"column"`
+	Message string `json:"message"`
+}
+
+type jsonFix struct {
+	Message string     `json:"message"`
+	Edits   []jsonEdit `json:"edits"`
+}
+
+type jsonEdit struct {
+	Line      uint   `json:"line"`
+	Column    uint   `json:"column"`
+	EndLine   uint   `json:"endLine"`
+	EndColumn uint   `json:"endColumn"`
+	NewText   string `json:"newText"`
+}
+
+// MarshalJSON encodes the finding with 1-based line and column numbers.
+func (f Finding) MarshalJSON() ([]byte, error) {
+	start, end := f.Range.StartPoint, f.Range.EndPoint
+	j := jsonFinding{
+		Rule:      f.Rule,
+		Severity:  f.Severity.String(),
+		Path:      f.Path,
+		Line:      start.Row + 1,
+		Column:    start.Column + 1,
+		EndLine:   end.Row + 1,
+		EndColumn: end.Column + 1,
+		Message:   f.Message,
+	}
+	if f.Fix != nil {
+		j.Fix = &jsonFix{Message: f.Fix.Message, Edits: []jsonEdit{}}
+		for _, e := range f.Fix.Edits {
+			start, end := e.Range.StartPoint, e.Range.EndPoint
+			j.Fix.Edits = append(j.Fix.Edits, jsonEdit{
+				Line:      start.Row + 1,
+				Column:    start.Column + 1,
+				EndLine:   end.Row + 1,
+				EndColumn: end.Column + 1,
+				NewText:   e.NewText,
+			})
+		}
+	}
+	for _, n := range f.Notes {
+		p := n.Range.StartPoint
+		j.Notes = append(j.Notes, jsonNote{Path: n.Path, Line: p.Row + 1, Column: p.Column + 1, Message: n.Message})
+	}
+	return json.Marshal(j)
+}
+
+// WriteJSON writes findings to w as a JSON array.
+func WriteJSON(w io.Writer, findings []Finding) error {
+	if findings == nil {
+		findings = []Finding{}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(findings)
+}
+
+// Linter runs a set of rules.
+type Linter struct {
+	Rules []Rule
+}
+
+// New returns a linter that runs the given rules, or every built-in rule
+// if none are given.
+func New(rules ...Rule) *Linter {
+	if len(rules) == 0 {
+		rules = DefaultRules()
+	}
+	return &Linter{Rules: rules}
+}
+
+// Project lints every file of p.
+func (l *Linter) Project(p *project.Project) []Finding {
+	trees := make([]*zscript.Tree, len(p.Files))
+	for i, f := range p.Files {
+		trees[i] = f.Tree
+	}
+	return l.Trees(trees...)
+}
+
+// Trees lints the given files as a single program, so that classes in one
+// file can see the members they inherit from another. Findings are sorted
+// by path and position.
+func (l *Linter) Trees(trees ...*zscript.Tree) []Finding {
+	return l.lint(trees, nil)
+}
+
+// Only lints the files of trees whose paths are among paths, as Trees
+// does, with the other files read for the classes they declare but not
+// linted themselves. It is how a change to a few files of a large project
+// is checked quickly.
+func (l *Linter) Only(paths []string, trees ...*zscript.Tree) []Finding {
+	only := map[string]bool{}
+	for _, p := range paths {
+		only[p] = true
+	}
+	return l.lint(trees, only)
+}
+
+// Tables lints trees as Only does, or as Trees does if paths is nil, with
+// tables, the symbol tables of trees in the same order, and h, the
+// hierarchy engine.Hierarchy builds of them, rather than building them
+// again. A client linting the same files over and over keeps them until
+// the files change.
+func (l *Linter) Tables(tables []*symbols.Table, h *hierarchy.Hierarchy, paths []string, trees ...*zscript.Tree) []Finding {
+	var only map[string]bool
+	if paths != nil {
+		only = map[string]bool{}
+		for _, p := range paths {
+			only[p] = true
+		}
+	}
+	return l.run(trees, tables, h, only)
+}
+
+// lint lints the trees whose paths are in only, or every tree if only is
+// nil.
+func (l *Linter) lint(trees []*zscript.Tree, only map[string]bool) []Finding {
+	tables := symbols.ExtractAll(0, trees...)
+	return l.run(trees, tables, engine.Hierarchy(tables...), only)
+}
+
+func (l *Linter) run(trees []*zscript.Tree, tables []*symbols.Table, h *hierarchy.Hierarchy, only map[string]bool) []Finding {
+	var findings []Finding
+	for i, tree := range trees {
+		if only != nil && !only[tree.Path] {
+			continue
+		}
+		for _, rule := range l.Rules {
+			rule.Check(&Pass{
+				Tree:      tree,
+				Table:     tables[i],
+				Tables:    tables,
+				Hierarchy: h,
+				rule:      rule,
+				findings:  &findings,
+			})
+		}
+	}
+	sort.SliceStable(findings, func(i, j int) bool {
+		a, b := findings[i], findings[j]
+		if a.Path != b.Path {
+			return a.Path < b.Path
+		}
+		return a.Range.StartByte < b.Range.StartByte
+	})
+	return findings
+}
+
+// NewFindings returns the findings that are not among base, the findings
+// of an earlier version of the same files. Findings are matched by path,
+// rule and message, not by position, which edits elsewhere in a file
+// move; when a file has more findings of a kind than it had, the last of
+// them are taken for the new ones.
+func NewFindings(base, findings []Finding) []Finding {
+	type key struct{ path, rule, message string }
+	old := map[key]int{}
+	for _, f := range base {
+		old[key{f.Path, f.Rule, f.Message}]++
+	}
+	var added []Finding
+	for _, f := range findings {
+		k := key{f.Path, f.Rule, f.Message}
+		if old[k] > 0 {
+			old[k]--
+			continue
+		}
+		added = append(added, f)
+	}
+	return added
+}
+
+// FixAll applies the fixes of findings, which must all be in source, and
+// returns the new source and the findings whose fixes were not applied
+// because they overlap a fix applied before them. Fixes are considered in
+// the order of their first edit and are applied whole or not at all; two
+// edits starting at the same place overlap, and a fix whose own edits
+// overlap is skipped too. Linting the result again yields fixes for the
+// skipped findings.
+func FixAll(source []byte, findings []Finding) ([]byte, []Finding) {
+	var fixable []Finding
+	for _, f := range findings {
+		if f.Fix != nil && len(f.Fix.Edits) > 0 {
+			fixable = append(fixable, f)
+		}
+	}
+	first := func(f Finding) uint {
+		start := f.Fix.Edits[0].Range.StartByte
+		for _, e := range f.Fix.Edits[1:] {
+			start = min(start, e.Range.StartByte)
+		}
+		return start
+	}
+	sort.SliceStable(fixable, func(i, j int) bool { return first(fixable[i]) < first(fixable[j]) })
+
+	var applied []Edit
+	var skipped []Finding
+	overlaps := func(e Edit, edits []Edit) bool {
+		for _, a := range edits {
+			if e.Range.StartByte < a.Range.EndByte && a.Range.StartByte < e.Range.EndByte ||
+				e.Range.StartByte == a.Range.StartByte {
+				return true
+			}
+		}
+		return false
+	}
+	for _, f := range fixable {
+		ok := true
+		for i, e := range f.Fix.Edits {
+			if overlaps(e, applied) || overlaps(e, f.Fix.Edits[:i]) || e.Range.EndByte > uint(len(source)) || e.Range.StartByte > e.Range.EndByte {
+				ok = false
+			}
+		}
+		if !ok {
+			skipped = append(skipped, f)
+			continue
+		}
+		applied = append(applied, f.Fix.Edits...)
+	}
+
+	sort.SliceStable(applied, func(i, j int) bool { return applied[i].Range.StartByte < applied[j].Range.StartByte })
+	var out []byte
+	last := uint(0)
+	for _, e := range applied {
+		out = append(out, source[last:e.Range.StartByte]...)
+		out = append(out, e.NewText...)
+		last = e.Range.EndByte
+	}
+	return append(out, source[last:]...), skipped
+}
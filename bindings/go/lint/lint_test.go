@@ -0,0 +1,776 @@
+package lint_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+
+	zscript "github.com/jlcrochet/tree-sitter-zscript/bindings/go"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/deprecations"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/engine"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/lint"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/symbols"
+)
+
+func parse(t *testing.T, path, source string) *zscript.Tree {
+	t.Helper()
+	tree, err := zscript.Parse(context.Background(), []byte(source))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(tree.Close)
+	tree.Path = path
+	return tree
+}
+
+func messages(findings []lint.Finding) []string {
+	var result []string
+	for _, f := range findings {
+		result = append(result, f.String())
+	}
+	return result
+}
+
+func check(t *testing.T, rule lint.Rule, want []string, trees ...*zscript.Tree) {
+	t.Helper()
+	got := messages(lint.New(rule).Trees(trees...))
+	if len(got) != len(want) {
+		t.Fatalf("got %d findings, want %d:\n%q", len(got), len(want), got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("finding %d:\ngot  %s\nwant %s", i, got[i], want[i])
+		}
+	}
+}
+
+func TestUnusedLocals(t *testing.T) {
+	check(t, lint.UnusedLocals, []string{
+		`a.zs:3:7: warning: local variable "unused" is declared but never used (unused-local)`,
+		`a.zs:7:12: warning: local variable "j" is declared but never used (unused-local)`,
+	}, parse(t, "a.zs", `class Foo {
+	void Bar() {
+		int unused = 1;
+		int used, other;
+		USED = 2;
+		other++;
+		for (int j = 0; ; ) {}
+		for (int i = 0; i < 3; i++) {}
+	}
+}`))
+}
+
+func TestMissingOverride(t *testing.T) {
+	base := parse(t, "base.zs", `class Base {
+	virtual void Tick() {}
+	virtual int Health() { return 0; }
+	void Plain() {}
+}`)
+	check(t, lint.MissingOverride, []string{
+		`derived.zs:2:7: error: method "tick" overrides Base.Tick but is not marked override (missing-override)`,
+		`derived.zs:6:6: error: method "Health" overrides Base.Health but is not marked override (missing-override)`,
+		`derived.zs:10:7: error: method "PostBeginPlay" overrides Actor.PostBeginPlay but is not marked override (missing-override)`,
+		`derived.zs:13:7: error: method "PostBeginPlay" overrides Actor.PostBeginPlay but is not marked override (missing-override)`,
+	}, base, parse(t, "derived.zs", `class Derived : Base {
+	void tick() {}
+	void Plain() {}
+}
+class Grandchild : Derived {
+	int Health() { return 1; }
+	override void Tick() {}
+}
+class Monster : Actor {
+	void PostBeginPlay() {}
+}
+class Zombie : ZombieMan {
+	void PostBeginPlay() {}
+}`))
+}
+
+func TestDeprecatedCalls(t *testing.T) {
+	check(t, lint.DeprecatedCalls, []string{
+		`a.zs:2:40: warning: Foo.Old is deprecated (deprecated-call)`,
+		`a.zs:5:12: warning: A_PlaySound is deprecated since GZDoom 4.3; use A_StartSound instead (deprecated-call)`,
+		`a.zs:6:12: warning: a_changeflag is deprecated since GZDoom 2.3; assign the flag directly instead (deprecated-call)`,
+	}, parse(t, "a.zs", `class Foo : Actor {
+	deprecated void Old() {} void New() { Old(); }
+	States {
+	Spawn:
+		TNT1 A 1 A_PlaySound("x");
+		TNT1 A 1 a_changeflag("x", 1);
+		Stop;
+	}
+}`))
+}
+
+func TestDeprecatedCallsVersion(t *testing.T) {
+	// A_PlaySound was deprecated after 4.0; A_CustomMissile before it.
+	check(t, lint.DeprecatedCalls, []string{
+		`a.zs:5:3: warning: A_CustomMissile is deprecated since GZDoom 2.3; use A_SpawnProjectile instead (deprecated-call)`,
+	}, parse(t, "a.zs", `version "4.0"
+class Foo : Actor {
+	void F() {
+		A_PlaySound("x");
+		A_CustomMissile("Ball");
+	}
+}`))
+}
+
+func TestDeprecatedCallsWith(t *testing.T) {
+	table, err := deprecations.Parse(`
+function Old.Spawn  4.0 NewSpawn
+field    Thing.speed 4.0 velocity
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	check(t, lint.DeprecatedCallsWith(table), []string{
+		`a.zs:3:6: warning: Spawn is deprecated since GZDoom 4.0; use NewSpawn instead (deprecated-call)`,
+		`a.zs:4:17: warning: speed is deprecated since GZDoom 4.0; use velocity instead (deprecated-call)`,
+	}, parse(t, "a.zs", `class Foo : Actor {
+	void F(Thing th) {
+		th.Spawn();
+		double s = th.speed;
+		A_PlaySound("x");
+	}
+}`))
+}
+
+func TestStateFallthrough(t *testing.T) {
+	check(t, lint.StateFallthrough, []string{
+		`a.zs:6:2: warning: state sequence "See" falls through into "Melee" (state-fallthrough)`,
+		`a.zs:14:2: warning: state sequence "Death.Fire" runs off the end of the States block (state-fallthrough)`,
+	}, parse(t, "a.zs", `class Foo : Actor {
+	States {
+	Spawn:
+		POSS AB 10 A_Look;
+		Loop;
+	See:
+		POSS A 4;
+	Melee:
+	Missile:
+		POSS E 10;
+		Goto See;
+	Death:
+		POSS L -1;
+	Death.Fire:
+		POSS H 5;
+	}
+}`))
+}
+
+func TestShadowedFields(t *testing.T) {
+	check(t, lint.ShadowedFields, []string{
+		`b.zs:1:28: warning: field "Count" shadows Base.count (shadowed-field)`,
+	}, parse(t, "a.zs", `class Base { int count; }
+extend class Base { int extra; }`), parse(t, "b.zs", `class Derived : Base { int Count; int other; }`))
+}
+
+func TestOverrideMismatch(t *testing.T) {
+	check(t, lint.OverrideMismatch, []string{
+		`a.zs:2:15: error: method "Damage" does not match Base.Damage: it has 1 parameter, want 2 (override-mismatch)`,
+		`a.zs:3:16: error: method "Fire" does not match Base.Fire: parameter 1 is double, want int (override-mismatch)`,
+		`a.zs:4:16: error: method "Plain" overrides Base.Plain, which is not virtual (override-mismatch)`,
+		`a.zs:5:16: error: method "Nothing" is marked override but Foo inherits no method of that name (override-mismatch)`,
+		`a.zs:6:15: error: method "Tick" does not match Actor.Tick: it returns int, want void (override-mismatch)`,
+		`a.zs:7:16: error: method "Find" does not match Base.Find: parameter 1 is int, want out int (override-mismatch)`,
+	}, parse(t, "a.zs", `class Foo : Base {
+	override int Damage(int amount) { return amount; }
+	override void Fire(double angle) {}
+	override void Plain() {}
+	override void Nothing() {}
+	override int Tick() { return 0; }
+	override bool Find(int x) { return true; }
+	override void PostBeginPlay() { Super.PostBeginPlay(); }
+	override void Aim(Actor target, int Flags) {}
+}
+class Other : Unknown {
+	override void Anything() {}
+}`), parse(t, "b.zs", `class Base : Actor {
+	virtual int Damage(int amount, Name kind) { return amount; }
+	virtual void Fire(int angle) {}
+	void Plain() {}
+	virtual bool Find(out int x) { return true; }
+	virtual void Aim(actor Target, int flags) {}
+}`))
+}
+
+func TestMissingReturn(t *testing.T) {
+	check(t, lint.MissingReturn, []string{
+		`a.zs:2:6: error: function "Half" does not return a value on every path (missing-return)`,
+		`a.zs:19:6: error: function "Find" does not return a value on every path (missing-return)`,
+	}, parse(t, "a.zs", `class Foo {
+	int Half(int x) {
+		if (x > 0) return x / 2;
+	}
+	int Sign(int x) {
+		if (x > 0) return 1;
+		else if (x < 0) return -1;
+		else return 0;
+	}
+	int Wait() {
+		while (true) {
+			if (Ready()) return 1;
+		}
+	}
+	void Nothing() {}
+	int Pick(int x) {
+		switch (x) { case 1: return 1; default: return 0; }
+	}
+	int Find(int x) {
+		for (int i = 0; i < x; i++) { if (i == 3) return i; }
+	}
+}`))
+}
+
+func TestUnreachableCode(t *testing.T) {
+	check(t, lint.UnreachableCode, []string{
+		`a.zs:4:3: warning: unreachable code (unreachable-code)`,
+		`a.zs:10:4: warning: unreachable code (unreachable-code)`,
+		`a.zs:17:3: warning: state can never be reached (unreachable-code)`,
+		`a.zs:19:22: warning: unreachable code (unreachable-code)`,
+	}, parse(t, "a.zs", `class Foo : Actor {
+	int Bar() {
+		return 1;
+		Console.Printf("never");
+		int x = 2;
+	}
+	void Baz() {
+		for (int i = 0; i < 3; i++) {
+			continue;
+			i++;
+		}
+	}
+	States {
+	Spawn:
+		TNT1 A 1;
+		Stop;
+		TNT1 B 1;
+	See:
+		TNT1 A 0 { return; A_Look(); }
+		Goto See+1;
+		TNT1 B 1;
+	}
+}`))
+}
+
+func TestWriteJSON(t *testing.T) {
+	findings := lint.New().Trees(parse(t, "a.zs", `class Foo { void Bar() { int x; } }`))
+	var buf bytes.Buffer
+	if err := lint.WriteJSON(&buf, findings); err != nil {
+		t.Fatal(err)
+	}
+	var decoded []map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if len(decoded) != 1 {
+		t.Fatalf("got %d findings, want 1: %s", len(decoded), buf.Bytes())
+	}
+	want := map[string]any{
+		"rule": "unused-local", "severity": "warning", "path": "a.zs",
+		"line": 1.0, "column": 30.0, "endLine": 1.0, "endColumn": 31.0,
+		"message": `local variable "x" is declared but never used`,
+	}
+	for k, v := range want {
+		if decoded[0][k] != v {
+			t.Errorf("%s = %v, want %v", k, decoded[0][k], v)
+		}
+	}
+
+	buf.Reset()
+	if err := lint.WriteJSON(&buf, nil); err != nil {
+		t.Fatal(err)
+	}
+	if got := buf.String(); got != "[]\n" {
+		t.Errorf("empty findings = %q, want %q", got, "[]\n")
+	}
+}
+
+func TestWriteSARIF(t *testing.T) {
+	tree := parse(t, "dir/a b.zs", "class Foo : Actor {\n\tvoid Tick() { int x; }\n\tint y\n}")
+	findings := append(lint.SyntaxErrors(tree), lint.New(lint.UnusedLocals, lint.MissingOverride).Trees(tree)...)
+	var buf bytes.Buffer
+	if err := lint.WriteSARIF(&buf, []lint.Rule{lint.UnusedLocals, lint.MissingOverride}, findings); err != nil {
+		t.Fatal(err)
+	}
+	var log struct {
+		Version string
+		Runs    []struct {
+			Tool struct {
+				Driver struct {
+					Rules []struct {
+						ID                   string
+						DefaultConfiguration *struct{ Level string }
+					}
+				}
+			}
+			Results []struct {
+				RuleID    string
+				RuleIndex int
+				Level     string
+				Message   struct{ Text string }
+				Locations []struct {
+					PhysicalLocation struct {
+						ArtifactLocation struct{ URI string }
+						Region           struct{ StartLine, StartColumn, EndLine, EndColumn int }
+					}
+				}
+				Fixes []struct {
+					ArtifactChanges []struct {
+						Replacements []struct {
+							InsertedContent struct{ Text string }
+						}
+					}
+				}
+			}
+		}
+	}
+	if err := json.Unmarshal(buf.Bytes(), &log); err != nil {
+		t.Fatal(err)
+	}
+	if log.Version != "2.1.0" || len(log.Runs) != 1 {
+		t.Fatalf("log = %s", buf.Bytes())
+	}
+	run := log.Runs[0]
+	var ids []string
+	for _, r := range run.Tool.Driver.Rules {
+		ids = append(ids, r.ID)
+	}
+	if want := "unused-local missing-override syntax-error"; strings.Join(ids, " ") != want {
+		t.Errorf("rules = %v, want %s", ids, want)
+	}
+	if c := run.Tool.Driver.Rules[1].DefaultConfiguration; c == nil || c.Level != "error" {
+		t.Errorf("missing-override configuration = %+v", c)
+	}
+	var got []string
+	for _, r := range run.Results {
+		loc := r.Locations[0].PhysicalLocation
+		got = append(got, fmt.Sprintf("%s %d %s %s %d:%d-%d:%d", r.RuleID, r.RuleIndex, r.Level, loc.ArtifactLocation.URI,
+			loc.Region.StartLine, loc.Region.StartColumn, loc.Region.EndLine, loc.Region.EndColumn))
+	}
+	want := []string{
+		"syntax-error 2 error dir/a%20b.zs 3:7-3:7",
+		"missing-override 1 error dir/a%20b.zs 2:7-2:11",
+		"unused-local 0 warning dir/a%20b.zs 2:20-2:21",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("results =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+	if fixes := run.Results[1].Fixes; len(fixes) != 1 || fixes[0].ArtifactChanges[0].Replacements[0].InsertedContent.Text != "override " {
+		t.Errorf("fixes = %+v", fixes)
+	}
+}
+
+func TestFixAll(t *testing.T) {
+	const source = `class Base : Actor {
+	virtual void Charge() {}
+	virtual void Burst() {}
+}
+class Foo : Base {
+	void Charge() { A_PlaySound("a"); A_PlaySound("b", 1, 0.5); }
+	virtual void Burst() { A_CustomMissile("Ball", 32); }
+	States {
+	Spawn:
+		TNT1 A 1;
+	See:
+		TNT1 B 1;
+	}
+}
+`
+	tree := parse(t, "a.zs", source)
+	findings := lint.New().Trees(tree)
+	fixed, skipped := lint.FixAll(tree.Source, findings)
+	if len(skipped) != 0 {
+		t.Errorf("skipped %v", skipped)
+	}
+	want := `class Base : Actor {
+	virtual void Charge() {}
+	virtual void Burst() {}
+}
+class Foo : Base {
+	override void Charge() { A_StartSound("a"); A_PlaySound("b", 1, 0.5); }
+	override void Burst() { A_SpawnProjectile("Ball", 32); }
+	States {
+	Spawn:
+		TNT1 A 1;
+		Goto See;
+	See:
+		TNT1 B 1;
+		Stop;
+	}
+}
+`
+	if string(fixed) != want {
+		t.Errorf("FixAll =\n%s\nwant\n%s", fixed, want)
+	}
+
+	// A fix overlapping one applied before it is skipped whole.
+	insert := lint.Finding{Fix: &lint.Fix{Edits: []lint.Edit{{NewText: "a"}}}}
+	replace := lint.Finding{Message: "second", Fix: &lint.Fix{Edits: []lint.Edit{
+		{Range: tree_sitter.Range{StartByte: 2, EndByte: 3}, NewText: "x"},
+		{Range: tree_sitter.Range{StartByte: 0, EndByte: 1}, NewText: "b"},
+	}}}
+	fixed, skipped = lint.FixAll([]byte("0123"), []lint.Finding{insert, replace, {Message: "no fix"}})
+	if string(fixed) != "a0123" || len(skipped) != 1 || skipped[0].Message != "second" {
+		t.Errorf("FixAll = %q, skipped %v", fixed, skipped)
+	}
+
+	// So is one whose own edits start at the same place.
+	self := lint.Finding{Message: "self", Fix: &lint.Fix{Edits: []lint.Edit{
+		{Range: tree_sitter.Range{StartByte: 1, EndByte: 3}, NewText: "x"},
+		{Range: tree_sitter.Range{StartByte: 1, EndByte: 1}, NewText: "y"},
+	}}}
+	fixed, skipped = lint.FixAll([]byte("0123"), []lint.Finding{self})
+	if string(fixed) != "0123" || len(skipped) != 1 || skipped[0].Message != "self" {
+		t.Errorf("FixAll = %q, skipped %v", fixed, skipped)
+	}
+}
+
+func TestInvalidFlags(t *testing.T) {
+	check(t, lint.InvalidFlags, []string{
+		`a.zs:1:31: error: unknown flag "SHOOTABEL"; did you mean "SHOOTABLE"? (invalid-flag)`,
+		`b.zs:1:59: error: flag bNOAUTOFIRE belongs to Weapon, which Base does not inherit from (invalid-flag)`,
+	}, parse(t, "a.zs", `class Imp : Base { Default { +SHOOTABEL; +Base.HOT; } }`),
+		parse(t, "b.zs", `class Base : Actor { flagdef Hot: x, 0; int x; void A() { bNOAUTOFIRE = true; } }
+class Ball : Base {}`))
+}
+
+func TestContradictoryFlags(t *testing.T) {
+	check(t, lint.ContradictoryFlags, []string{
+		`a.zs:1:51: warning: SOLID is cleared here but set on line 1 (contradictory-flag)`,
+	}, parse(t, "a.zs", `class Imp : Actor { Default { +SOLID; +SHOOTABLE; -SOLID; } }`))
+}
+
+func TestInvalidEscapes(t *testing.T) {
+	check(t, lint.InvalidEscapes, []string{
+		`a.zs:3:24: warning: unknown escape sequence \q (invalid-escape)`,
+		`a.zs:4:19: warning: color name after \c is missing its closing "]" (invalid-escape)`,
+		`a.zs:5:19: warning: invalid color code '?' after \c (invalid-escape)`,
+		`a.zs:6:21: warning: color escape \c is not followed by a color (invalid-escape)`,
+		`a.zs:7:14: warning: unknown escape sequence \8 (invalid-escape)`,
+	}, parse(t, "a.zs", `class A {
+	void F() {
+		Console.Printf("\cGok\q\c[Gold]", "\c-\c[Red]\x41\101\n");
+		Console.Printf("\c[Gold");
+		Console.Printf("\c?");
+		Console.Printf("ok\c");
+		name n = 'a\8';
+		Console.Printf("\c" "[Gold]");
+	}
+}`))
+}
+
+func TestFormatMismatch(t *testing.T) {
+	check(t, lint.FormatMismatch, []string{
+		`a.zs:3:31: error: too few arguments for the format string: %s has no argument (format-mismatch)`,
+		`a.zs:4:32: error: too many arguments for the format string: it uses 1 but is given 2 (format-mismatch)`,
+		`a.zs:5:28: error: %*d expects a number but is given a string (format-mismatch)`,
+		`a.zs:6:19: error: invalid format specifier %y (format-mismatch)`,
+		`a.zs:7:34: error: %s expects a string but is given a number (format-mismatch)`,
+		`a.zs:8:24: error: format string ends in an incomplete specifier %-5 (format-mismatch)`,
+	}, parse(t, "a.zs", `class A {
+	void F(String s) {
+		s = String.Format("%d%% and %s", 1);
+		Console.Printf("%5.2f", 1.0, 2);
+		s.AppendFormat("%*d", 3, "x");
+		Console.Printf("%y");
+		Console.PrintfEx(0, "%s" "%i", 1, 2);
+		ThrowAbortException("%-5");
+		Console.Printf("%s, %x, %c", s, 1, 65);
+		Foo.Format("%d");
+	}
+}`))
+}
+
+func TestInvalidTranslations(t *testing.T) {
+	check(t, lint.InvalidTranslations, []string{
+		`a.zs:1:61: error: invalid translation: expected ':' at the end of the translation (invalid-translation)`,
+		`a.zs:2:53: error: invalid translation: palette index 256 is not a whole number from 0 to 255 (invalid-translation)`,
+	}, parse(t, "a.zs", `class Imp : Actor { Default { Translation "112:127=[255,0,0]", "Ice"; } }
+class Ball : Actor { void F() { A_SetTranslation("0:256=1:2"); A_SetTranslation('Ice'); } }`))
+}
+
+func TestArrayBounds(t *testing.T) {
+	check(t, lint.ArrayBounds, []string{
+		`a.zs:4:11: error: array "none" has size 0 (array-bounds)`,
+		`a.zs:12:7: error: index 2 is out of bounds for loc, which has 2 elements (array-bounds)`,
+		`a.zs:12:29: error: index 3 is out of bounds for arr, which has 3 elements (array-bounds)`,
+		`a.zs:13:11: error: index 4 is out of bounds for grid, which has 4 elements (array-bounds)`,
+		`a.zs:13:26: error: index 2 is out of bounds for self.grid, which has 2 elements (array-bounds)`,
+		`a.zs:14:8: error: index -1 of list is negative (array-bounds)`,
+		`a.zs:15:50: error: list is resized in a loop bounded by its earlier size in "n" (array-bounds)`,
+		`a.zs:17:24: error: list is resized while foreach iterates over it (array-bounds)`,
+	}, parse(t, "a.zs", `class Imp : Actor {
+	const N = 3;
+	int arr[N];
+	int none[0];
+	int grid[2][4];
+	Array<int> list;
+	void F(int arr) {
+		arr[7] = 0;
+	}
+	void G() {
+		int loc[2];
+		loc[2] = arr[N - 1] + arr[N];
+		grid[1][4] = self.grid[2][0];
+		list[-1] = list[100];
+		for (int i = 0, n = list.Size(); i < n; i++) { list.Delete(i); }
+		for (int i = list.Size() - 1; i >= 0; i--) { list.Delete(i); }
+		foreach (x : list) { list.Push(x); }
+	}
+}`))
+}
+
+func TestNullDereference(t *testing.T) {
+	check(t, lint.NullDereference, []string{
+		`a.zs:4:7: warning: target may be null here; check it before accessing health (null-dereference)`,
+		`a.zs:5:3: warning: tracer may be null here; check it before accessing A_Die (null-dereference)`,
+		`a.zs:16:3: warning: mo may be null here, holding the result of Spawn; check it before accessing A_Die (null-dereference)`,
+		`a.zs:19:3: warning: inv may be null here, holding the result of FindInventory; check it before accessing amount (null-dereference)`,
+		`a.zs:35:42: warning: master may be null here; check it before accessing A_Die (null-dereference)`,
+		`a.zs:40:13: warning: owner may be null here; check it before accessing A_Die (null-dereference)`,
+		`a.zs:43:42: warning: target may be null here; check it before accessing health (null-dereference)`,
+	}, parse(t, "a.zs", `class Imp : Actor {
+	void Unchecked() {
+		A_FaceTarget();
+		if (target.health > 0) {}
+		tracer.A_Die();
+	}
+	void Checked(Actor master) {
+		if (!target || target.health <= 0) return;
+		target.A_Die();
+		int h = tracer ? tracer.health : 0;
+		if (self.tracer != null) tracer.A_Die();
+		master.A_Die();
+	}
+	void Results() {
+		let mo = Spawn("Imp", pos);
+		mo.A_Die();
+		let inv = FindInventory("Clip");
+		if (inv) inv.amount++;
+		inv.amount = 0;
+		let pawn = PlayerPawn(mo);
+		if (pawn == null) {
+			return;
+		}
+		pawn.A_Die();
+		let it = ThinkerIterator.Create("Imp");
+		Actor a;
+		while (a = Actor(it.Next())) a.A_Die();
+		let b = Spawn("Imp", pos);
+		b = self;
+		b.A_Die();
+	}
+	static void Static() { }
+	States {
+	Spawn:
+		TNT1 A 0 { if (target) target.A_Die(); master.A_Die(); }
+		Stop;
+	}
+}
+class Pickup : Inventory {
+	void F() { owner.A_Die(); }
+}
+class Thing { Actor target; void F() { target.A_Die(); } }
+class Fiend : DoomImp { int F() { return target.health; } }`))
+}
+
+func TestQueryRules(t *testing.T) {
+	rules, err := lint.LoadQueryRules(fstest.MapFS{
+		"old-sound.scm": {Data: []byte(`; message: use A_StartSound instead of {fn}
+; severity: error
+(call_expression
+  function: (identifier) @fn
+  (#match? @fn "(?i)^A_PlaySound$")) @report
+`)},
+		"no-goto.scm": {Data: []byte(`; Goto jumps between states.
+; message: avoid Goto
+(state_goto_target) @goto
+`)},
+		"notes.txt": {Data: []byte("not a rule")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rules) != 2 || rules[0].Name() != "no-goto" || rules[0].Doc() != "avoid Goto" || rules[1].Severity() != zscript.SeverityError {
+		t.Fatalf("got rules %v", rules)
+	}
+	check(t, rules[1], []string{
+		`a.zs:3:3: error: use A_StartSound instead of a_playsound (old-sound)`,
+	}, parse(t, "a.zs", `class Foo : Actor {
+	void F() {
+		a_playsound("x");
+		A_StartSound("x");
+	}
+}`))
+
+	for _, tc := range []struct{ source, err string }{
+		{"(identifier) @x", "lint: bad: no message"},
+		{"; message: m\n; severity: loud\n(identifier) @x", `lint: bad:2: invalid severity "loud"`},
+		{"; message: m\n; colour: red\n(identifier) @x", `lint: bad:2: unknown key "colour"`},
+		{"; message: m\n(identifier)", "lint: bad: the query captures nothing to report"},
+		{"; message: {y}\n(identifier) @x", "lint: bad: the message uses {y}, which the query does not capture"},
+	} {
+		if _, err := lint.ParseQueryRule("bad", []byte(tc.source)); err == nil || err.Error() != tc.err {
+			t.Errorf("ParseQueryRule(%q): got error %v, want %s", tc.source, err, tc.err)
+		}
+	}
+	if _, err := lint.ParseQueryRule("bad", []byte("; message: m\n(no_such_node) @x")); err == nil || !strings.HasPrefix(err.Error(), "lint: bad: ") {
+		t.Errorf("got error %v for an invalid query", err)
+	}
+}
+
+func TestUnknownClasses(t *testing.T) {
+	check(t, lint.UnknownClasses, []string{
+		`a.zs:5:19: warning: class DoomImp is not a subclass of Inventory (unknown-class)`,
+		`a.zs:6:24: warning: unknown class "Clipp" (unknown-class)`,
+		`a.zs:10:16: warning: unknown class "Nothing" (unknown-class)`,
+		`a.zs:13:20: warning: class MyPuff is not a subclass of Inventory (unknown-class)`,
+		`a.zs:14:12: warning: unknown class "Ghost" (unknown-class)`,
+	}, parse(t, "a.zs", `class MyImp : DoomImp {
+	void F() {
+		Spawn("DoomImp", pos);
+		Spawn('MyPuff', pos);
+		A_GiveInventory("DoomImp");
+		target.GiveInventory("Clipp", 1);
+		FindInventory("clip");
+	}
+	Default {
+		Powerup.Type "Nothing";
+		DropItem "None";
+		Player.StartItem "Shotgun";
+		Player.StartItem "MyPuff";
+		DropItem "Ghost";
+	}
+}
+class MyPowerup : PowerupGiver {
+	Default {
+		Powerup.Type "Invulnerable";
+		Powerup.Type "MyPower";
+	}
+}
+class MyPower : Powerup {}
+class MyPuff : Blood {}
+class Sub : Missing { void F() { A_GiveInventory("Sub"); } }`))
+}
+
+func TestOnlyAndNewFindings(t *testing.T) {
+	base := parse(t, "base.zs", "class Base { virtual void Tick() {} void F() { int a; } }")
+	derived := `class Derived : Base {
+	void Tick() {}
+	void F() { int b; }
+}`
+	l := lint.New(lint.MissingOverride, lint.UnusedLocals)
+	old := l.Only([]string{"derived.zs"}, base, parse(t, "derived.zs", derived))
+	if got := messages(old); len(got) != 2 || !strings.HasPrefix(got[0], "derived.zs:2:7:") {
+		t.Fatalf("Only linted %q", got)
+	}
+
+	// Tables lints the same with the symbols built beforehand.
+	trees := []*zscript.Tree{base, parse(t, "derived.zs", derived)}
+	tables := symbols.ExtractAll(0, trees...)
+	if got := messages(l.Tables(tables, engine.Hierarchy(tables...), []string{"derived.zs"}, trees...)); strings.Join(got, "\n") != strings.Join(messages(old), "\n") {
+		t.Errorf("Tables linted %q, want %q", got, messages(old))
+	}
+
+	// Moving the findings down a line and adding one reports only the
+	// added one.
+	findings := l.Only([]string{"derived.zs"}, base, parse(t, "derived.zs", "// Edited.\n"+strings.Replace(derived, "int b;", "int b, c;", 1)))
+	want := []string{`derived.zs:4:20: warning: local variable "c" is declared but never used (unused-local)`}
+	if got := messages(lint.NewFindings(old, findings)); strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("NewFindings = %q, want %q", got, want)
+	}
+}
+
+func TestRenderer(t *testing.T) {
+	base := parse(t, "base.zs", "class Base { virtual void Tick() {} }")
+	derived := parse(t, "derived.zs", "class Derived : Base {\n\tvoid Tick() {}\n\tvoid F() { int a = 1 +\n\t\t2 +\n\t\t3; }\n}")
+	findings := lint.New(lint.MissingOverride, lint.UnusedLocals).Trees(base, derived)
+	findings = append(findings,
+		lint.Finding{Rule: "sum", Severity: zscript.SeverityHint, Path: "derived.zs", Message: "long sum", Range: tree_sitter.Range{
+			StartPoint: tree_sitter.Point{Row: 2, Column: 20},
+			EndPoint:   tree_sitter.Point{Row: 4, Column: 3},
+		}},
+		lint.Finding{Rule: "other", Severity: zscript.SeverityInformation, Path: "gone.zs", Message: "no source"})
+	sources := map[string][]byte{"base.zs": base.Source, "derived.zs": derived.Source}
+
+	var buf bytes.Buffer
+	r := &lint.Renderer{Source: func(path string) []byte { return sources[path] }, TabWidth: 2}
+	if err := r.Render(&buf, findings); err != nil {
+		t.Fatal(err)
+	}
+	want := `error[missing-override]: method "Tick" overrides Base.Tick but is not marked override
+ --> derived.zs:2:7
+  |
+2 |   void Tick() {}
+  |        ^^^^
+note: Base.Tick is declared here
+ --> base.zs:1:27
+  |
+1 | class Base { virtual void Tick() {} }
+  |                           ----
+help: add override
+  |
+2 |   override void Tick() {}
+  |   +++++++++
+
+warning[unused-local]: local variable "a" is declared but never used
+ --> derived.zs:3:17
+  |
+3 |   void F() { int a = 1 +
+  |                  ^
+
+hint[sum]: long sum
+ --> derived.zs:3:21
+  |
+3 |   void F() { int a = 1 +
+  |                      ^^^
+...
+5 |     3; }
+  |     ^
+
+info[other]: no source
+ --> gone.zs:1:1
+`
+	if got := buf.String(); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+
+	buf.Reset()
+	r.Color = true
+	if err := r.Render(&buf, findings[:1]); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "\x1b[1;31merror[missing-override]\x1b[0m") {
+		t.Errorf("no colored header in %q", buf.String())
+	}
+}
+
+func TestGameClasses(t *testing.T) {
+	// DoomImp and Pistol are known to the engine by name and parent
+	// only, so these rules follow them to Actor and Weapon.
+	tree := parse(t, "a.zs", `class Imp : DoomImp {
+	int Health;
+	override int Tick() { return 0; }
+	void A() { bNOAUTOFIRE = true; }
+}
+class Gun : Pistol {
+	void A() { bNOAUTOFIRE = true; }
+}`)
+	check(t, lint.OverrideMismatch, []string{
+		`a.zs:3:15: error: method "Tick" does not match Actor.Tick: it returns int, want void (override-mismatch)`,
+	}, tree)
+	check(t, lint.ShadowedFields, []string{
+		`a.zs:2:6: warning: field "Health" shadows Actor.Health (shadowed-field)`,
+	}, tree)
+	check(t, lint.InvalidFlags, []string{
+		`a.zs:4:13: error: flag bNOAUTOFIRE belongs to Weapon, which Imp does not inherit from (invalid-flag)`,
+	}, tree)
+}
@@ -0,0 +1,451 @@
+package lint
+
+import (
+	"strings"
+
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+
+	zscript "github.com/jlcrochet/tree-sitter-zscript/bindings/go"
+)
+
+type nullDereference struct{}
+
+func (nullDereference) Name() string { return "null-dereference" }
+func (nullDereference) Doc() string {
+	return "member of a pointer that may be null is accessed without checking it"
+}
+func (nullDereference) Severity() zscript.Severity { return zscript.SeverityWarning }
+
+// nullableCalls are the functions whose results are null when they fail:
+// spawning blocked by the map, missing inventory, or the end of an
+// iteration.
+var nullableCalls = map[string]bool{
+	"spawn": true, "findinventory": true, "getpointer": true, "next": true,
+	"spawnmissile": true, "spawnmissilez": true, "spawnmissilexyz": true,
+	"spawnmissileangle": true, "spawnmissileanglez": true, "spawnsubmissile": true,
+	"spawnplayermissile": true,
+}
+
+// nullableFields are the pointer fields that may be null when a method of
+// a subclass of the class they are declared in starts.
+var nullableFields = map[string][]string{
+	"Actor":     {"target", "tracer", "master"},
+	"Inventory": {"owner"},
+}
+
+func (nullDereference) Check(pass *Pass) {
+	c := &nullChecker{pass: pass}
+	var v zscript.Visitor
+	v.On(zscript.NodeMethodDefinition, func(node *tree_sitter.Node) zscript.WalkAction {
+		if body := node.ChildByFieldName(zscript.FieldBody); body != nil {
+			c.function(node, body)
+		}
+		return zscript.WalkSkipChildren
+	})
+	v.On(zscript.NodeStateAction, func(node *tree_sitter.Node) zscript.WalkAction {
+		for _, body := range namedChildrenOfKind(node, zscript.NodeCompoundStatement) {
+			c.function(node, &body)
+		}
+		return zscript.WalkSkipChildren
+	})
+	zscript.Walk(pass.Tree.RootNode(), &v)
+}
+
+// nullState maps the lowercased names of the locals, parameters and
+// fields of self that may be null to what they may hold: the call or cast
+// that set them, or "" for a field not yet checked.
+type nullState map[string]string
+
+func (s nullState) copy() nullState {
+	c := make(nullState, len(s))
+	for k, v := range s {
+		c[k] = v
+	}
+	return c
+}
+
+// merge returns the state after two paths join: a name may be null if it
+// may be null on either of them.
+func merge(a, b nullState) nullState {
+	m := a.copy()
+	for k, v := range b {
+		if _, ok := m[k]; !ok {
+			m[k] = v
+		}
+	}
+	return m
+}
+
+// exit is how a statement leaves the code that follows it.
+type exit int
+
+const (
+	exitNone exit = iota
+	// exitBreak leaves the enclosing loop or switch.
+	exitBreak
+	// exitReturn leaves the function.
+	exitReturn
+)
+
+type nullChecker struct {
+	pass *Pass
+}
+
+// function checks body, the body of the method or anonymous state function
+// node.
+func (c *nullChecker) function(node, body *tree_sitter.Node) {
+	st := nullState{}
+	if !isStatic(c.pass, node) {
+		class := enclosingClass(c.pass, node)
+		for base, fields := range nullableFields {
+			if class != "" && (strings.EqualFold(class, base) || c.pass.Hierarchy.IsSubclassOf(class, base)) {
+				for _, f := range fields {
+					st[f] = ""
+				}
+			}
+		}
+	}
+	if params := node.ChildByFieldName(zscript.FieldParameters); params != nil {
+		for _, p := range namedChildrenOfKind(params, zscript.NodeParameterDeclaration) {
+			if d := p.ChildByFieldName(zscript.FieldDeclarator); d != nil {
+				delete(st, strings.ToLower(c.pass.Text(d)))
+			}
+		}
+	}
+	c.stmt(body, st)
+}
+
+// isStatic reports whether node is a static method.
+func isStatic(pass *Pass, node *tree_sitter.Node) bool {
+	for _, mods := range namedChildrenOfKind(node, zscript.NodeMemberModifiers) {
+		for _, m := range namedChildrenOfKind(&mods, zscript.NodeMemberModifier) {
+			if strings.EqualFold(pass.Text(&m), "static") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// enclosingClass returns the name of the class node is declared in, or "".
+func enclosingClass(pass *Pass, node *tree_sitter.Node) string {
+	for p := node.Parent(); p != nil; p = p.Parent() {
+		if p.Kind() == zscript.NodeClassDefinition {
+			if name := p.ChildByFieldName(zscript.FieldName); name != nil {
+				return pass.Text(name)
+			}
+		}
+	}
+	return ""
+}
+
+// stmt interprets the statement n in the state st, reporting the
+// dereferences of names that may be null, and returns the state after it
+// and how it exits.
+func (c *nullChecker) stmt(n *tree_sitter.Node, st nullState) (nullState, exit) {
+	if n == nil {
+		return st, exitNone
+	}
+	switch n.Kind() {
+	case zscript.NodeComment:
+		return st, exitNone
+	case zscript.NodeCompoundStatement, zscript.NodeLabeledStatement, zscript.NodeElseClause:
+		for i := uint(0); i < n.NamedChildCount(); i++ {
+			child := n.NamedChild(i)
+			if n.Kind() == zscript.NodeLabeledStatement && !isStatementNode(child) {
+				continue
+			}
+			var how exit
+			if st, how = c.stmt(child, st); how != exitNone {
+				return st, how
+			}
+		}
+		return st, exitNone
+	case zscript.NodeIfStatement:
+		cond := n.ChildByFieldName(zscript.FieldCondition)
+		c.expr(cond, st)
+		then, thenExit := c.stmt(n.ChildByFieldName(zscript.FieldConsequence), c.refine(cond, true, st.copy()))
+		els, elseExit := c.refine(cond, false, st.copy()), exitNone
+		if alt := n.ChildByFieldName(zscript.FieldAlternative); alt != nil {
+			els, elseExit = c.stmt(alt, els)
+		}
+		switch {
+		case thenExit != exitNone && elseExit != exitNone:
+			return st, max(thenExit, elseExit)
+		case thenExit != exitNone:
+			return els, exitNone
+		case elseExit != exitNone:
+			return then, exitNone
+		}
+		return merge(then, els), exitNone
+	case zscript.NodeWhileStatement, zscript.NodeForStatement:
+		if init := n.ChildByFieldName(zscript.FieldInitializer); init != nil {
+			st, _ = c.stmt(init, st)
+		}
+		cond := n.ChildByFieldName(zscript.FieldCondition)
+		c.expr(cond, st)
+		body, how := c.stmt(n.ChildByFieldName(zscript.FieldBody), c.refine(cond, true, st.copy()))
+		c.expr(n.ChildByFieldName(zscript.FieldUpdate), body)
+		after := c.refine(cond, false, st)
+		if how == exitReturn {
+			return after, exitNone
+		}
+		return merge(after, body), exitNone
+	case zscript.NodeDoStatement:
+		body, how := c.stmt(n.ChildByFieldName(zscript.FieldBody), st.copy())
+		if how == exitReturn {
+			return st, exitReturn
+		}
+		cond := n.ChildByFieldName(zscript.FieldCondition)
+		c.expr(cond, body)
+		return c.refine(cond, false, body), exitNone
+	case zscript.NodeForeachStatement:
+		c.expr(n.ChildByFieldName(zscript.FieldCollection), st)
+		inner := st.copy()
+		if v := n.ChildByFieldName(zscript.FieldVariable); v != nil {
+			delete(inner, strings.ToLower(c.pass.Text(v)))
+		}
+		body, how := c.stmt(n.ChildByFieldName(zscript.FieldBody), inner)
+		if how == exitReturn {
+			return st, exitNone
+		}
+		return merge(st, body), exitNone
+	case zscript.NodeSwitchStatement:
+		c.expr(n.ChildByFieldName(zscript.FieldCondition), st)
+		result, cur := st.copy(), st.copy()
+		body := n.ChildByFieldName(zscript.FieldBody)
+		for i := uint(0); body != nil && i < body.NamedChildCount(); i++ {
+			child := body.NamedChild(i)
+			if child.Kind() != zscript.NodeCaseStatement {
+				continue
+			}
+			// Control reaches a case from the switch or by falling
+			// through the case before it.
+			cur = merge(cur, st)
+			value := child.ChildByFieldName(zscript.FieldValue)
+			for j := uint(0); j < child.NamedChildCount(); j++ {
+				s := child.NamedChild(j)
+				if value != nil && s.Id() == value.Id() {
+					continue
+				}
+				var how exit
+				if cur, how = c.stmt(s, cur); how != exitNone {
+					if how == exitBreak {
+						result = merge(result, cur)
+					}
+					cur = st.copy()
+					break
+				}
+			}
+		}
+		return merge(result, cur), exitNone
+	case zscript.NodeReturnStatement:
+		for i := uint(0); i < n.NamedChildCount(); i++ {
+			c.expr(n.NamedChild(i), st)
+		}
+		return st, exitReturn
+	case zscript.NodeBreakStatement, zscript.NodeContinueStatement:
+		return st, exitBreak
+	case zscript.NodeDeclaration:
+		for _, d := range namedChildrenByField(n, zscript.FieldDeclarator) {
+			name := d.ChildByFieldName(zscript.FieldDeclarator)
+			if d.Kind() != zscript.NodeInitDeclarator || name == nil {
+				delete(st, strings.ToLower(c.pass.Text(&d)))
+				continue
+			}
+			value := d.ChildByFieldName(zscript.FieldValue)
+			c.expr(value, st)
+			c.assign(st, strings.ToLower(c.pass.Text(name)), value)
+		}
+		return st, exitNone
+	case zscript.NodeExpressionStatement:
+		c.expr(n.NamedChild(0), st)
+		if call := n.NamedChild(0); call != nil && call.Kind() == zscript.NodeCallExpression {
+			if fn := call.ChildByFieldName(zscript.FieldFunction); fn != nil && strings.EqualFold(c.pass.Text(fn), "ThrowAbortException") {
+				return st, exitReturn
+			}
+		}
+		return st, exitNone
+	}
+	c.expr(n, st)
+	return st, exitNone
+}
+
+// isStatementNode reports whether n is a statement or declaration.
+func isStatementNode(n *tree_sitter.Node) bool {
+	return n.Kind() == zscript.NodeDeclaration || n.Kind() == zscript.NodeCompoundStatement || strings.HasSuffix(n.Kind(), "_statement")
+}
+
+// expr interprets the expression n in the state st, which it updates with
+// the assignments in n.
+func (c *nullChecker) expr(n *tree_sitter.Node, st nullState) {
+	if n == nil {
+		return
+	}
+	switch n.Kind() {
+	case zscript.NodeBinaryExpression:
+		left, right := n.ChildByFieldName(zscript.FieldLeft), n.ChildByFieldName(zscript.FieldRight)
+		c.expr(left, st)
+		switch operator(n) {
+		case "&&":
+			c.expr(right, c.refine(left, true, st.copy()))
+		case "||":
+			c.expr(right, c.refine(left, false, st.copy()))
+		default:
+			c.expr(right, st)
+		}
+		return
+	case zscript.NodeConditionalExpression:
+		cond := n.ChildByFieldName(zscript.FieldCondition)
+		c.expr(cond, st)
+		c.expr(n.ChildByFieldName(zscript.FieldConsequence), c.refine(cond, true, st.copy()))
+		c.expr(n.ChildByFieldName(zscript.FieldAlternative), c.refine(cond, false, st.copy()))
+		return
+	case zscript.NodeAssignmentExpression:
+		left, right := n.ChildByFieldName(zscript.FieldLeft), n.ChildByFieldName(zscript.FieldRight)
+		c.expr(right, st)
+		if key := c.key(left); key != "" && operator(n) == "=" {
+			c.assign(st, key, right)
+		} else {
+			c.expr(left, st)
+		}
+		return
+	case zscript.NodeFieldExpression:
+		arg := n.ChildByFieldName(zscript.FieldArgument)
+		c.expr(arg, st)
+		key := c.key(arg)
+		if holds, ok := st[key]; key != "" && ok {
+			field := ""
+			if f := n.ChildByFieldName(zscript.FieldField); f != nil {
+				field = c.pass.Text(f)
+			}
+			if holds == "" {
+				c.pass.ReportNode(arg, "%s may be null here; check it before accessing %s", c.pass.Text(arg), field)
+			} else {
+				c.pass.ReportNode(arg, "%s may be null here, holding %s; check it before accessing %s", c.pass.Text(arg), holds, field)
+			}
+			// Report each path to a name once.
+			delete(st, key)
+		}
+		return
+	}
+	for i := uint(0); i < n.NamedChildCount(); i++ {
+		c.expr(n.NamedChild(i), st)
+	}
+}
+
+// assign records in st that the name key was assigned value.
+func (c *nullChecker) assign(st nullState, key string, value *tree_sitter.Node) {
+	if holds, ok := c.nullable(value, st); ok {
+		st[key] = holds
+	} else {
+		delete(st, key)
+	}
+}
+
+// nullable reports whether n may be null, and what it then holds.
+func (c *nullChecker) nullable(n *tree_sitter.Node, st nullState) (string, bool) {
+	n = unparen(n)
+	if n == nil {
+		return "", false
+	}
+	switch n.Kind() {
+	case zscript.NodeNull:
+		return "null", true
+	case zscript.NodeCallExpression:
+		fn := n.ChildByFieldName(zscript.FieldFunction)
+		if fn == nil {
+			return "", false
+		}
+		name := fn
+		if fn.Kind() == zscript.NodeFieldExpression {
+			name = fn.ChildByFieldName(zscript.FieldField)
+		}
+		if name == nil {
+			return "", false
+		}
+		text := c.pass.Text(name)
+		if nullableCalls[strings.ToLower(text)] {
+			return "the result of " + text, true
+		}
+		if fn.Kind() == zscript.NodeIdentifier && c.pass.Hierarchy.Class(text) != nil {
+			return "the result of a cast to " + text, true
+		}
+	}
+	if key := c.key(n); key != "" {
+		holds, ok := st[key]
+		return holds, ok
+	}
+	return "", false
+}
+
+// refine updates st with what holds when cond is truth.
+func (c *nullChecker) refine(cond *tree_sitter.Node, truth bool, st nullState) nullState {
+	cond = unparen(cond)
+	if cond == nil {
+		return st
+	}
+	switch cond.Kind() {
+	case zscript.NodeUnaryExpression:
+		if operator(cond) == "!" {
+			return c.refine(cond.ChildByFieldName(zscript.FieldArgument), !truth, st)
+		}
+	case zscript.NodeBinaryExpression:
+		left, right := cond.ChildByFieldName(zscript.FieldLeft), cond.ChildByFieldName(zscript.FieldRight)
+		switch op := operator(cond); {
+		case op == "&&" && truth, op == "||" && !truth:
+			return c.refine(right, truth, c.refine(left, truth, st))
+		case op == "==" || op == "!=":
+			if l, r := unparen(left), unparen(right); l != nil && r != nil && (op == "!=") == truth {
+				if r.Kind() == zscript.NodeNull {
+					delete(st, c.key(l))
+				} else if l.Kind() == zscript.NodeNull {
+					delete(st, c.key(r))
+				}
+			}
+		}
+	case zscript.NodeAssignmentExpression:
+		return c.refine(cond.ChildByFieldName(zscript.FieldLeft), truth, st)
+	}
+	if key := c.key(cond); key != "" && truth {
+		delete(st, key)
+	}
+	return st
+}
+
+// key returns the lowercased name of n if it is a local, a parameter or a
+// field of self, or "".
+func (c *nullChecker) key(n *tree_sitter.Node) string {
+	n = unparen(n)
+	if n == nil {
+		return ""
+	}
+	switch n.Kind() {
+	case zscript.NodeIdentifier:
+		return strings.ToLower(c.pass.Text(n))
+	case zscript.NodeFieldExpression:
+		if arg := n.ChildByFieldName(zscript.FieldArgument); arg != nil && arg.Kind() == zscript.NodeSelfExpression {
+			if f := n.ChildByFieldName(zscript.FieldField); f != nil {
+				return strings.ToLower(c.pass.Text(f))
+			}
+		}
+	}
+	return ""
+}
+
+// unparen returns n without the parentheses around it.
+func unparen(n *tree_sitter.Node) *tree_sitter.Node {
+	for n != nil && n.Kind() == zscript.NodeParenthesizedExpression && n.NamedChildCount() == 1 {
+		n = n.NamedChild(0)
+	}
+	return n
+}
+
+// operator returns the operator of a unary, binary or assignment
+// expression.
+func operator(n *tree_sitter.Node) string {
+	if op := n.ChildByFieldName(zscript.FieldOperator); op != nil {
+		return op.Kind()
+	}
+	return ""
+}
@@ -0,0 +1,249 @@
+package lint
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+
+	zscript "github.com/jlcrochet/tree-sitter-zscript/bindings/go"
+)
+
+// Renderer writes findings for people reading them in a terminal, in the
+// manner of rustc: each finding is followed by the lines of source it
+// spans, with carets under the range it reports, and by its notes, with
+// their own excerpts, and its fix, shown as the line it changes would
+// read with it applied.
+//
+//	error[missing-override]: method "Tick" overrides Base.Tick but is not marked override
+//	 --> derived.zs:2:7
+//	  |
+//	2 |     void Tick() {}
+//	  |          ^^^^
+//	note: Base.Tick is declared here
+//	 --> base.zs:1:27
+//	  |
+//	1 | class Base { virtual void Tick() {} }
+//	  |                           ----
+//	help: add override
+//	  |
+//	2 |     override void Tick() {}
+//	  |     +++++++++
+type Renderer struct {
+	// Source returns the text of the file at path, or nil if it is not
+	// at hand, in which case the excerpts of the file are left out. A nil
+	// Source leaves out every excerpt.
+	Source func(path string) []byte
+	// Color, if set, colors the output with ANSI escape sequences.
+	Color bool
+	// TabWidth is the number of columns tabs are expanded to, 4 if zero.
+	TabWidth int
+}
+
+// ANSI escape sequences of the styles of rendered findings.
+const (
+	styleReset  = "\x1b[0m"
+	styleBold   = "\x1b[1m"
+	styleRed    = "\x1b[1;31m"
+	styleYellow = "\x1b[1;33m"
+	styleGreen  = "\x1b[1;32m"
+	styleBlue   = "\x1b[1;34m"
+	styleCyan   = "\x1b[1;36m"
+	styleGutter = "\x1b[1;34m"
+)
+
+// Render writes findings to w, separated by blank lines.
+func (r *Renderer) Render(w io.Writer, findings []Finding) error {
+	bw := bufio.NewWriter(w)
+	for i, f := range findings {
+		if i > 0 {
+			bw.WriteString("\n")
+		}
+		r.finding(bw, f)
+	}
+	return bw.Flush()
+}
+
+// style returns text in style s, if r colors its output.
+func (r *Renderer) style(s, text string) string {
+	if !r.Color {
+		return text
+	}
+	return s + text + styleReset
+}
+
+func (r *Renderer) finding(w *bufio.Writer, f Finding) {
+	color := severityStyle(f.Severity)
+	header := f.Severity.String()
+	if f.Rule != "" {
+		header += "[" + f.Rule + "]"
+	}
+	fmt.Fprintf(w, "%s%s\n", r.style(color, header), r.style(styleBold, ": "+f.Message))
+
+	// The gutter fits the largest line number shown.
+	rows := []uint{f.Range.EndPoint.Row}
+	for _, n := range f.Notes {
+		rows = append(rows, n.Range.EndPoint.Row)
+	}
+	width := 1
+	for _, row := range rows {
+		width = max(width, len(strconv.Itoa(int(row)+1)))
+	}
+
+	r.excerpt(w, width, f.Path, f.Range, '^', color)
+	for _, n := range f.Notes {
+		fmt.Fprintf(w, "%s%s\n", r.style(styleGreen, "note"), r.style(styleBold, ": "+n.Message))
+		r.excerpt(w, width, n.Path, n.Range, '-', styleBlue)
+	}
+	if f.Fix != nil {
+		fmt.Fprintf(w, "%s%s\n", r.style(styleCyan, "help"), r.style(styleBold, ": "+f.Fix.Message))
+		r.fix(w, width, f)
+	}
+}
+
+func severityStyle(s zscript.Severity) string {
+	switch s {
+	case zscript.SeverityError:
+		return styleRed
+	case zscript.SeverityWarning:
+		return styleYellow
+	}
+	return styleCyan
+}
+
+// gutter writes the gutter of a line of an excerpt: the line number, or
+// blanks for the lines under it. The text of the line follows after a
+// space.
+func (r *Renderer) gutter(w *bufio.Writer, width int, number string) {
+	w.WriteString(r.style(styleGutter, fmt.Sprintf("%*s |", width, number)))
+}
+
+// excerpt writes the location of rng in the file at path and, if its
+// source is at hand, the lines rng spans with marker under the range. A
+// range of many lines shows its first and last line.
+func (r *Renderer) excerpt(w *bufio.Writer, width int, path string, rng tree_sitter.Range, marker byte, style string) {
+	p := rng.StartPoint
+	fmt.Fprintf(w, "%s%s %s:%d:%d\n", strings.Repeat(" ", width), r.style(styleGutter, "-->"), path, p.Row+1, p.Column+1)
+	var source []byte
+	if r.Source != nil {
+		source = r.Source(path)
+	}
+	lines := splitSource(source)
+	if int(rng.StartPoint.Row) >= len(lines) {
+		return
+	}
+	end := rng.EndPoint
+	if int(end.Row) >= len(lines) {
+		end = tree_sitter.Point{Row: uint(len(lines) - 1), Column: uint(len(lines[len(lines)-1]))}
+	}
+
+	r.gutter(w, width, "")
+	w.WriteString("\n")
+	mark := func(row, from, to uint) {
+		line := lines[row]
+		from, to = min(from, uint(len(line))), min(to, uint(len(line)))
+		r.gutter(w, width, strconv.Itoa(int(row)+1))
+		w.WriteString(" " + r.expand(line) + "\n")
+		start, stop := r.columns(line, from), r.columns(line, to)
+		r.gutter(w, width, "")
+		w.WriteString(strings.Repeat(" ", start+1) + r.style(style, strings.Repeat(string(marker), max(stop-start, 1))) + "\n")
+	}
+	if end.Row == p.Row {
+		mark(p.Row, p.Column, end.Column)
+		return
+	}
+	mark(p.Row, p.Column, uint(len(lines[p.Row])))
+	if end.Row > p.Row+1 {
+		w.WriteString(r.style(styleGutter, "...") + "\n")
+	}
+	last := lines[end.Row]
+	indent := uint(len(last) - len(bytes.TrimLeft(last, " \t")))
+	mark(end.Row, min(indent, end.Column), end.Column)
+}
+
+// fix writes the line of f's file that its fix changes as the fix leaves
+// it, marking the text added with + and the text replaced with ~. It
+// writes nothing if the fix changes several lines or another file's.
+func (r *Renderer) fix(w *bufio.Writer, width int, f Finding) {
+	if r.Source == nil || len(f.Fix.Edits) == 0 {
+		return
+	}
+	lines := splitSource(r.Source(f.Path))
+	row := f.Fix.Edits[0].Range.StartPoint.Row
+	edits := append([]Edit(nil), f.Fix.Edits...)
+	for _, e := range edits {
+		if e.Range.StartPoint.Row != row || e.Range.EndPoint.Row != row || strings.Contains(e.NewText, "\n") {
+			return
+		}
+	}
+	if int(row) >= len(lines) {
+		return
+	}
+	sort.SliceStable(edits, func(i, j int) bool { return edits[i].Range.StartPoint.Column < edits[j].Range.StartPoint.Column })
+
+	line := lines[row]
+	var fixed, marks strings.Builder
+	at := uint(0)
+	for _, e := range edits {
+		from, to := e.Range.StartPoint.Column, e.Range.EndPoint.Column
+		if from < at || to > uint(len(line)) {
+			return
+		}
+		kept := string(line[at:from])
+		fixed.WriteString(kept + e.NewText)
+		marks.WriteString(strings.Repeat(" ", r.columns([]byte(kept), uint(len(kept)))))
+		sign := "~"
+		if from == to {
+			sign = "+"
+		}
+		marks.WriteString(r.style(styleGreen, strings.Repeat(sign, r.columns([]byte(e.NewText), uint(len(e.NewText))))))
+		at = to
+	}
+	fixed.Write(line[at:])
+
+	r.gutter(w, width, "")
+	w.WriteString("\n")
+	r.gutter(w, width, strconv.Itoa(int(row)+1))
+	w.WriteString(" " + r.expand([]byte(fixed.String())) + "\n")
+	r.gutter(w, width, "")
+	if m := strings.TrimRight(marks.String(), " "); m != "" {
+		w.WriteString(" " + m)
+	}
+	w.WriteString("\n")
+}
+
+func (r *Renderer) tabWidth() int {
+	if r.TabWidth <= 0 {
+		return 4
+	}
+	return r.TabWidth
+}
+
+// expand returns line with its tabs expanded to spaces.
+func (r *Renderer) expand(line []byte) string {
+	return strings.ReplaceAll(string(line), "\t", strings.Repeat(" ", r.tabWidth()))
+}
+
+// columns returns the number of columns the first n bytes of line take
+// up once expanded, counting a character as one column.
+func (r *Renderer) columns(line []byte, n uint) int {
+	return utf8.RuneCountInString(r.expand(line[:n]))
+}
+
+// splitSource splits source into lines without their line endings.
+func splitSource(source []byte) [][]byte {
+	if source == nil {
+		return nil
+	}
+	lines := bytes.Split(source, []byte("\n"))
+	for i, line := range lines {
+		lines[i] = bytes.TrimSuffix(line, []byte("\r"))
+	}
+	return lines
+}
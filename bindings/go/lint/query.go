@@ -0,0 +1,157 @@
+package lint
+
+import (
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"strings"
+
+	zscript "github.com/jlcrochet/tree-sitter-zscript/bindings/go"
+)
+
+// QueryRule is a rule written as a tree-sitter query rather than in Go,
+// so that a project can enforce its own conventions. The rule reports
+// every match of the query.
+//
+// The query is read from a file whose leading comment lines give the
+// rule's metadata as "key: value" pairs:
+//
+//	; message: use A_StartSound instead of {fn}
+//	; severity: warning
+//	; doc: calls to the old sound functions
+//	(call_expression
+//	  function: (identifier) @fn
+//	  (#match? @fn "(?i)^A_PlaySound$")) @report
+//
+// The message is required; "{name}" in it is replaced with the text of
+// the node captured as @name. The severity is "error", "warning", "info"
+// or "hint", "warning" by default, and the doc defaults to the message.
+// A finding spans the node captured as @report, or the first captured
+// node of the match if there is none.
+type QueryRule struct {
+	name     string
+	doc      string
+	message  string
+	severity zscript.Severity
+	query    *zscript.CachedQuery
+}
+
+// metadata matches a "; key: value" comment line of a query rule.
+var metadata = regexp.MustCompile(`^;+\s*([A-Za-z-]+)\s*:\s*(.*?)\s*$`)
+
+// placeholder matches a "{name}" in the message of a query rule.
+var placeholder = regexp.MustCompile(`\{([A-Za-z_][A-Za-z0-9_.-]*)\}`)
+
+// ParseQueryRule returns the rule named name that the query file source
+// describes.
+func ParseQueryRule(name string, source []byte) (*QueryRule, error) {
+	r := &QueryRule{name: name, severity: zscript.SeverityWarning}
+	for i, line := range strings.Split(string(source), "\n") {
+		line = strings.TrimSpace(line)
+		if !strings.HasPrefix(line, ";") {
+			if line == "" {
+				continue
+			}
+			break
+		}
+		m := metadata.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		switch key, value := strings.ToLower(m[1]), m[2]; key {
+		case "message":
+			r.message = value
+		case "doc":
+			r.doc = value
+		case "severity":
+			severity, ok := severities[strings.ToLower(value)]
+			if !ok {
+				return nil, fmt.Errorf("lint: %s:%d: invalid severity %q", name, i+1, value)
+			}
+			r.severity = severity
+		default:
+			return nil, fmt.Errorf("lint: %s:%d: unknown key %q", name, i+1, m[1])
+		}
+	}
+	if r.message == "" {
+		return nil, fmt.Errorf("lint: %s: no message", name)
+	}
+	if r.doc == "" {
+		r.doc = r.message
+	}
+	q, err := zscript.Query(string(source))
+	if err != nil {
+		return nil, fmt.Errorf("lint: %s: %w", name, err)
+	}
+	if len(q.CaptureNames()) == 0 {
+		return nil, fmt.Errorf("lint: %s: the query captures nothing to report", name)
+	}
+	for _, m := range placeholder.FindAllStringSubmatch(r.message, -1) {
+		if _, ok := q.CaptureIndexForName(m[1]); !ok {
+			return nil, fmt.Errorf("lint: %s: the message uses {%s}, which the query does not capture", name, m[1])
+		}
+	}
+	r.query = q
+	return r, nil
+}
+
+// severities maps the severity names of query rules to severities.
+var severities = map[string]zscript.Severity{
+	"error":       zscript.SeverityError,
+	"warning":     zscript.SeverityWarning,
+	"info":        zscript.SeverityInformation,
+	"information": zscript.SeverityInformation,
+	"hint":        zscript.SeverityHint,
+}
+
+// LoadQueryRules returns the rules of the query files of fsys, those whose
+// names end in ".scm", in the order of their names. Each rule is named
+// after its file without the extension.
+func LoadQueryRules(fsys fs.FS) ([]Rule, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, fmt.Errorf("lint: %w", err)
+	}
+	var rules []Rule
+	for _, entry := range entries {
+		file := entry.Name()
+		if entry.IsDir() || path.Ext(file) != ".scm" {
+			continue
+		}
+		source, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			return nil, fmt.Errorf("lint: %w", err)
+		}
+		r, err := ParseQueryRule(strings.TrimSuffix(file, ".scm"), source)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, r)
+	}
+	return rules, nil
+}
+
+func (r *QueryRule) Name() string               { return r.name }
+func (r *QueryRule) Doc() string                { return r.doc }
+func (r *QueryRule) Severity() zscript.Severity { return r.severity }
+
+func (r *QueryRule) Check(pass *Pass) {
+	source := pass.Tree.Source
+	for m := range r.query.Matches(pass.Tree.RootNode(), source) {
+		if len(m.Captures) == 0 {
+			continue
+		}
+		node, ok := m.Capture("report")
+		if !ok {
+			node = m.Captures[0].Node
+		}
+		message := placeholder.ReplaceAllStringFunc(r.message, func(s string) string {
+			if n, ok := m.Capture(s[1 : len(s)-1]); ok {
+				return n.Utf8Text(source)
+			}
+			return ""
+		})
+		pass.ReportNode(&node, "%s", message)
+	}
+}
@@ -0,0 +1,686 @@
+package lint
+
+import (
+	"fmt"
+	"strings"
+
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+
+	zscript "github.com/jlcrochet/tree-sitter-zscript/bindings/go"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/defaults"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/deprecations"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/engine"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/flow"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/hierarchy"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/symbols"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/translation"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/version"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/zscriptast"
+)
+
+// The built-in rules.
+var (
+	// UnusedLocals reports local variables that are never referenced
+	// after their declaration.
+	UnusedLocals Rule = unusedLocals{}
+	// MissingOverride reports methods that redefine an inherited virtual
+	// method without the override modifier.
+	MissingOverride Rule = missingOverride{}
+	// DeprecatedCalls reports uses of the engine functions and fields in
+	// the deprecations table, and calls to project methods declared
+	// deprecated.
+	DeprecatedCalls Rule = deprecatedCalls{}
+	// StateFallthrough reports state sequences that do not end in Stop,
+	// Loop, Wait, Fail or Goto.
+	StateFallthrough Rule = stateFallthrough{}
+	// ShadowedFields reports fields that hide a field of the same name in
+	// an ancestor class.
+	ShadowedFields Rule = shadowedFields{}
+	// OverrideMismatch reports override methods that override nothing,
+	// override a method that is not virtual, or differ from the method
+	// they override in their parameters or return type.
+	OverrideMismatch Rule = overrideMismatch{}
+	// MissingReturn reports functions with a return type that can run off
+	// the end of their body without returning a value.
+	MissingReturn Rule = missingReturn{}
+	// UnreachableCode reports statements that follow a return, break or
+	// continue, and states that follow Stop, Loop, Wait, Fail or Goto.
+	UnreachableCode Rule = unreachableCode{}
+	// InvalidFlags reports flags set or cleared in Default blocks, and
+	// flag fields assigned in methods, that GZDoom does not know or that
+	// belong to a class the actor does not inherit from.
+	InvalidFlags Rule = invalidFlags{}
+	// ContradictoryFlags reports flags that a class's Default blocks both
+	// set and clear.
+	ContradictoryFlags Rule = contradictoryFlags{}
+	// InvalidEscapes reports escape sequences in string and name literals
+	// that GZDoom does not know, and \c color escapes not followed by a
+	// color code or a bracketed color name.
+	InvalidEscapes Rule = invalidEscapes{}
+	// FormatMismatch reports invalid specifiers in the format strings of
+	// String.Format, Console.Printf and their kin, and arguments that are
+	// missing, left over or of the wrong kind of literal.
+	FormatMismatch Rule = formatMismatch{}
+	// InvalidTranslations reports malformed translation ranges given to
+	// the Translation property or to A_SetTranslation.
+	InvalidTranslations Rule = invalidTranslations{}
+	// ArrayBounds reports constant indexes outside the bounds of fixed
+	// arrays, negative indexes of dynamic arrays, arrays of size zero,
+	// and dynamic arrays and maps resized while a loop iterates over them.
+	ArrayBounds Rule = arrayBounds{}
+	// NullDereference reports member accesses through target, tracer,
+	// master and owner, and through the results of Spawn, FindInventory,
+	// class casts and their kin, on paths where they are not checked for
+	// null first.
+	NullDereference Rule = nullDereference{}
+	// UnknownClasses reports class names given as strings, to Spawn,
+	// A_GiveInventory and their kin or to properties such as DropItem and
+	// Powerup.Type, that name no class of the project or the engine, or a
+	// class of the wrong kind, such as a monster given as inventory.
+	UnknownClasses Rule = unknownClasses{}
+)
+
+// DefaultRules returns every built-in rule.
+func DefaultRules() []Rule {
+	return []Rule{UnusedLocals, MissingOverride, DeprecatedCalls, StateFallthrough, ShadowedFields, OverrideMismatch, MissingReturn, UnreachableCode, InvalidFlags, ContradictoryFlags, InvalidEscapes, FormatMismatch, InvalidTranslations, ArrayBounds, NullDereference, UnknownClasses}
+}
+
+type unusedLocals struct{}
+
+func (unusedLocals) Name() string               { return "unused-local" }
+func (unusedLocals) Doc() string                { return "local variable is declared but never used" }
+func (unusedLocals) Severity() zscript.Severity { return zscript.SeverityWarning }
+
+func (unusedLocals) Check(pass *Pass) {
+	var v zscript.Visitor
+	v.On(zscript.NodeDeclaration, func(node *tree_sitter.Node) zscript.WalkAction {
+		// The scope of a local is the rest of the block or statement that
+		// contains its declaration.
+		scope := node.Parent()
+		if scope == nil {
+			return zscript.WalkContinue
+		}
+		for _, d := range namedChildrenByField(node, zscript.FieldDeclarator) {
+			name := zscriptast.DeclaratorName(zscriptast.Wrap(&d, pass.Tree.Source)).Raw
+			if name == nil {
+				continue
+			}
+			if !referencedAfter(pass, scope, pass.Text(name), name.EndByte()) {
+				pass.ReportNode(name, "local variable %q is declared but never used", pass.Text(name))
+			}
+		}
+		return zscript.WalkContinue
+	})
+	zscript.Walk(pass.Tree.RootNode(), &v)
+}
+
+// referencedAfter reports whether an identifier spelled name occurs in
+// scope after the byte offset start.
+func referencedAfter(pass *Pass, scope *tree_sitter.Node, name string, start uint) bool {
+	found := false
+	var v zscript.Visitor
+	v.Enter = func(node *tree_sitter.Node) zscript.WalkAction {
+		if node.EndByte() <= start {
+			return zscript.WalkSkipChildren
+		}
+		if node.Kind() == zscript.NodeIdentifier && node.StartByte() >= start && strings.EqualFold(pass.Text(node), name) {
+			found = true
+			return zscript.WalkStop
+		}
+		return zscript.WalkContinue
+	}
+	zscript.Walk(scope, &v)
+	return found
+}
+
+type missingOverride struct{}
+
+func (missingOverride) Name() string { return "missing-override" }
+func (missingOverride) Doc() string {
+	return "method redefines an inherited virtual method without override"
+}
+func (missingOverride) Severity() zscript.Severity { return zscript.SeverityError }
+
+func (missingOverride) Check(pass *Pass) {
+	for _, class := range pass.Table.Classes {
+		if class.Mixin {
+			continue
+		}
+		ancestors := pass.Hierarchy.Ancestors(class.Name)
+		for _, m := range class.Methods {
+			if m.HasModifier("override") || m.HasModifier("static") {
+				continue
+			}
+			for _, a := range ancestors {
+				inherited := method(a, m.Name)
+				if inherited != nil && (inherited.HasModifier("virtual") || inherited.HasModifier("override")) {
+					pass.ReportFix(m.NameRange, overrideFix(pass, m), "method %q overrides %s.%s but is not marked override", m.Name, a.Name, inherited.Name)
+					if a.Defined() && a.Path != engine.Path && a.Decl.Method(m.Name) == inherited {
+						pass.Note(a.Path, inherited.NameRange, "%s.%s is declared here", a.Name, inherited.Name)
+					}
+					break
+				}
+			}
+		}
+	}
+}
+
+type overrideMismatch struct{}
+
+func (overrideMismatch) Name() string { return "override-mismatch" }
+func (overrideMismatch) Doc() string {
+	return "override method does not match a virtual method it inherits"
+}
+func (overrideMismatch) Severity() zscript.Severity { return zscript.SeverityError }
+
+func (overrideMismatch) Check(pass *Pass) {
+	for _, class := range pass.Table.Classes {
+		if class.Mixin {
+			continue
+		}
+		ancestors := pass.Hierarchy.Ancestors(class.Name)
+		for _, m := range class.Methods {
+			if !m.HasModifier("override") {
+				continue
+			}
+			var owner *hierarchy.Class
+			var inherited *symbols.Method
+			complete := true
+			for _, a := range ancestors {
+				if !a.Defined() {
+					complete = false
+				}
+				if inherited = method(a, m.Name); inherited != nil {
+					owner = a
+					break
+				}
+			}
+			switch {
+			case inherited == nil:
+				// A class of unknown ancestry may inherit the method from a
+				// class that is not loaded.
+				if complete && len(ancestors) > 0 {
+					pass.Report(m.NameRange, "method %q is marked override but %s inherits no method of that name", m.Name, class.Name)
+				}
+			case !inherited.HasModifier("virtual") && !inherited.HasModifier("override"):
+				pass.Report(m.NameRange, "method %q overrides %s.%s, which is not virtual", m.Name, owner.Name, inherited.Name)
+			default:
+				if diff := signatureDiff(m, inherited); diff != "" {
+					pass.Report(m.NameRange, "method %q does not match %s.%s: %s", m.Name, owner.Name, inherited.Name, diff)
+				}
+			}
+		}
+	}
+}
+
+// signatureDiff describes the first difference between the parameters
+// and return type of m and those of the method it overrides, or returns
+// "" if they match.
+func signatureDiff(m, inherited *symbols.Method) string {
+	if len(m.Params) != len(inherited.Params) {
+		noun := "parameters"
+		if len(m.Params) == 1 {
+			noun = "parameter"
+		}
+		return fmt.Sprintf("it has %d %s, want %d", len(m.Params), noun, len(inherited.Params))
+	}
+	for i, p := range m.Params {
+		want := inherited.Params[i]
+		switch {
+		case !sameType(p.Type, want.Type):
+			return fmt.Sprintf("parameter %d is %s, want %s", i+1, p.Type, want.Type)
+		case p.Variadic != want.Variadic:
+			return fmt.Sprintf("parameter %d is %s, want %s", i+1, paramText(p), paramText(want))
+		case hasModifier(p.Modifiers, "out") != hasModifier(want.Modifiers, "out"):
+			return fmt.Sprintf("parameter %d is %s, want %s", i+1, paramText(p), paramText(want))
+		}
+	}
+	if !sameType(m.ReturnType, inherited.ReturnType) {
+		return fmt.Sprintf("it returns %s, want %s", m.ReturnType, inherited.ReturnType)
+	}
+	return ""
+}
+
+// sameType reports whether a and b spell the same type, ignoring case and
+// spaces.
+func sameType(a, b string) bool {
+	return strings.EqualFold(strings.Join(strings.Fields(a), ""), strings.Join(strings.Fields(b), ""))
+}
+
+func paramText(p symbols.Param) string {
+	if p.Variadic {
+		return "..."
+	}
+	if hasModifier(p.Modifiers, "out") {
+		return "out " + p.Type
+	}
+	return p.Type
+}
+
+func hasModifier(modifiers []string, name string) bool {
+	for _, m := range modifiers {
+		if strings.EqualFold(m, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// DeprecatedCallsWith returns a rule like DeprecatedCalls that consults
+// table instead of the built-in deprecations.
+func DeprecatedCallsWith(table *deprecations.Table) Rule {
+	return deprecatedCalls{table}
+}
+
+// overrideFix returns a fix marking m override, replacing virtual if m
+// is marked that.
+func overrideFix(pass *Pass, m *symbols.Method) *Fix {
+	node := pass.Tree.RootNode().NamedDescendantForByteRange(m.Range.StartByte, m.Range.EndByte)
+	if node == nil || node.Kind() != zscript.NodeMethodDefinition {
+		return nil
+	}
+	fix := &Fix{Message: "add override"}
+	for _, mods := range namedChildrenOfKind(node, zscript.NodeMemberModifiers) {
+		for _, mod := range namedChildrenOfKind(&mods, zscript.NodeMemberModifier) {
+			if strings.EqualFold(pass.Text(&mod), "virtual") {
+				fix.Message = "replace virtual with override"
+				fix.Edits = []Edit{Replace(&mod, "override")}
+				return fix
+			}
+		}
+	}
+	fix.Edits = []Edit{Insert(node, "override ", false)}
+	return fix
+}
+
+type deprecatedCalls struct {
+	table *deprecations.Table
+}
+
+func (deprecatedCalls) Name() string               { return "deprecated-call" }
+func (deprecatedCalls) Doc() string                { return "use of a deprecated function or field" }
+func (deprecatedCalls) Severity() zscript.Severity { return zscript.SeverityWarning }
+
+func (r deprecatedCalls) Check(pass *Pass) {
+	declared := map[string]string{}
+	for _, t := range pass.Tables {
+		for _, c := range t.Classes {
+			for _, m := range c.Methods {
+				if m.HasModifier("deprecated") {
+					declared[strings.ToLower(m.Name)] = c.Name + "." + m.Name
+				}
+			}
+		}
+	}
+
+	// Engine deprecations apply only from the version that introduced
+	// them; a file without a version directive is taken to target the
+	// latest one.
+	target, versioned := version.Of(pass.Tree)
+	table := r.table
+	if table == nil {
+		table = deprecations.Default()
+	}
+	check := func(name *tree_sitter.Node, kind deprecations.Kind, args *tree_sitter.Node) {
+		if name == nil {
+			return
+		}
+		text := pass.Text(name)
+		if qualified, ok := declared[strings.ToLower(text)]; ok && kind == deprecations.Function {
+			pass.ReportNode(name, "%s is deprecated", qualified)
+		} else if e, ok := table.Lookup(kind, text); ok && (!versioned || e.AppliesTo(target)) {
+			var fix *Fix
+			n := 0
+			if args != nil {
+				n = int(args.NamedChildCount())
+			}
+			if e.Renames(n) {
+				fix = &Fix{Message: "use " + e.Replacement, Edits: []Edit{Replace(name, e.Replacement)}}
+			}
+			pass.ReportFix(name.Range(), fix, "%s", e.Message(text))
+		}
+	}
+	var v zscript.Visitor
+	v.On(zscript.NodeCallExpression, func(node *tree_sitter.Node) zscript.WalkAction {
+		fn := node.ChildByFieldName(zscript.FieldFunction)
+		if fn != nil && fn.Kind() == zscript.NodeFieldExpression {
+			fn = fn.ChildByFieldName(zscript.FieldField)
+		}
+		check(fn, deprecations.Function, node.ChildByFieldName(zscript.FieldArguments))
+		return zscript.WalkContinue
+	})
+	v.On(zscript.NodeStateActionCall, func(node *tree_sitter.Node) zscript.WalkAction {
+		check(node.ChildByFieldName(zscript.FieldFunction), deprecations.Function, node.ChildByFieldName(zscript.FieldArguments))
+		return zscript.WalkContinue
+	})
+	v.On(zscript.NodeFieldExpression, func(node *tree_sitter.Node) zscript.WalkAction {
+		// The callee of a method call is checked as a function above.
+		if parent := node.Parent(); parent == nil || !isCallee(parent, node) {
+			check(node.ChildByFieldName(zscript.FieldField), deprecations.Field, nil)
+		}
+		return zscript.WalkContinue
+	})
+	zscript.Walk(pass.Tree.RootNode(), &v)
+}
+
+// isCallee reports whether node is the function called by call.
+func isCallee(call, node *tree_sitter.Node) bool {
+	if call.Kind() != zscript.NodeCallExpression {
+		return false
+	}
+	fn := call.ChildByFieldName(zscript.FieldFunction)
+	return fn != nil && fn.Id() == node.Id()
+}
+
+type stateFallthrough struct{}
+
+func (stateFallthrough) Name() string { return "state-fallthrough" }
+func (stateFallthrough) Doc() string {
+	return "state sequence does not end in Stop, Loop, Wait, Fail or Goto"
+}
+func (stateFallthrough) Severity() zscript.Severity { return zscript.SeverityWarning }
+
+func (stateFallthrough) Check(pass *Pass) {
+	var v zscript.Visitor
+	v.On(zscript.NodeStatesBlock, func(node *tree_sitter.Node) zscript.WalkAction {
+		labels := namedChildrenOfKind(node, zscript.NodeStateLabel)
+		for i := range labels {
+			label := &labels[i]
+			last := fallsThrough(pass, label)
+			if last == nil {
+				continue
+			}
+			name := label.ChildByFieldName(zscript.FieldName)
+			if i == len(labels)-1 {
+				fix := &Fix{Message: "add Stop", Edits: []Edit{insertLine(pass, last, "Stop;")}}
+				pass.ReportFix(name.Range(), fix, "state sequence %q runs off the end of the States block", pass.Text(name))
+			} else {
+				// Falling through may be intended; the fix keeps the
+				// behavior and makes it explicit.
+				next := labels[i+1].ChildByFieldName(zscript.FieldName)
+				fix := &Fix{Message: "add Goto " + pass.Text(next), Edits: []Edit{insertLine(pass, last, "Goto "+pass.Text(next)+";")}}
+				pass.ReportFix(name.Range(), fix, "state sequence %q falls through into %q", pass.Text(name), pass.Text(next))
+			}
+		}
+		return zscript.WalkSkipChildren
+	})
+	zscript.Walk(pass.Tree.RootNode(), &v)
+}
+
+// insertLine returns an edit adding text on a line of its own after
+// node, indented like the line node starts on.
+func insertLine(pass *Pass, node *tree_sitter.Node, text string) Edit {
+	src := pass.Tree.Source
+	start := node.StartByte()
+	for start > 0 && src[start-1] != '\n' {
+		start--
+	}
+	indent := start
+	for indent < node.StartByte() && (src[indent] == ' ' || src[indent] == '\t') {
+		indent++
+	}
+	return Insert(node, "\n"+string(src[start:indent])+text, true)
+}
+
+// fallsThrough returns the last state under label if the states continue
+// past it, and nil otherwise. Labels without states of their own are
+// aliases for the next label and do not count.
+func fallsThrough(pass *Pass, label *tree_sitter.Node) *tree_sitter.Node {
+	body := label.ChildByFieldName(zscript.FieldBody)
+	if body == nil {
+		return nil
+	}
+	var last *tree_sitter.Node
+	for i := uint(0); i < body.NamedChildCount(); i++ {
+		if child := body.NamedChild(i); !child.IsExtra() {
+			last = child
+		}
+	}
+	switch {
+	case last == nil, last.Kind() == zscript.NodeStateFlow:
+		return nil
+	case last.Kind() == zscript.NodeStateLine:
+		// A state with a duration of -1 lasts forever.
+		if d := last.ChildByFieldName(zscript.FieldDuration); d != nil && strings.TrimSpace(pass.Text(d)) == "-1" {
+			return nil
+		}
+	}
+	return last
+}
+
+type shadowedFields struct{}
+
+func (shadowedFields) Name() string               { return "shadowed-field" }
+func (shadowedFields) Doc() string                { return "field hides a field inherited from an ancestor class" }
+func (shadowedFields) Severity() zscript.Severity { return zscript.SeverityWarning }
+
+func (shadowedFields) Check(pass *Pass) {
+	for _, class := range pass.Table.Classes {
+		if class.Mixin {
+			continue
+		}
+		ancestors := pass.Hierarchy.Ancestors(class.Name)
+		for _, f := range class.Fields {
+			for _, a := range ancestors {
+				if inherited := field(a, f.Name); inherited != nil {
+					pass.Report(f.NameRange, "field %q shadows %s.%s", f.Name, a.Name, inherited.Name)
+					break
+				}
+			}
+		}
+	}
+}
+
+type missingReturn struct{}
+
+func (missingReturn) Name() string               { return "missing-return" }
+func (missingReturn) Doc() string                { return "function does not return a value on every path" }
+func (missingReturn) Severity() zscript.Severity { return zscript.SeverityError }
+
+func (missingReturn) Check(pass *Pass) {
+	check := func(node, typ, name *tree_sitter.Node) zscript.WalkAction {
+		body := node.ChildByFieldName(zscript.FieldBody)
+		if body == nil || typ == nil || name == nil || strings.EqualFold(pass.Text(typ), "void") {
+			return zscript.WalkSkipChildren
+		}
+		if flow.New(body, pass.Tree.Source).FallsOffEnd() {
+			pass.ReportNode(name, "function %q does not return a value on every path", pass.Text(name))
+		}
+		return zscript.WalkSkipChildren
+	}
+	var v zscript.Visitor
+	v.On(zscript.NodeMethodDefinition, func(node *tree_sitter.Node) zscript.WalkAction {
+		return check(node, node.ChildByFieldName(zscript.FieldType), node.ChildByFieldName(zscript.FieldName))
+	})
+	v.On(zscript.NodeFunctionDefinition, func(node *tree_sitter.Node) zscript.WalkAction {
+		var name *tree_sitter.Node
+		if d := node.ChildByFieldName(zscript.FieldDeclarator); d != nil {
+			name = d.ChildByFieldName(zscript.FieldDeclarator)
+		}
+		return check(node, node.ChildByFieldName(zscript.FieldType), name)
+	})
+	zscript.Walk(pass.Tree.RootNode(), &v)
+}
+
+type unreachableCode struct{}
+
+func (unreachableCode) Name() string               { return "unreachable-code" }
+func (unreachableCode) Doc() string                { return "code can never run" }
+func (unreachableCode) Severity() zscript.Severity { return zscript.SeverityWarning }
+
+func (unreachableCode) Check(pass *Pass) {
+	// A Goto with an offset can reach states past the end of the
+	// sequence it names.
+	offset := map[string]bool{}
+	var v zscript.Visitor
+	v.On(zscript.NodeStateGotoTarget, func(node *tree_sitter.Node) zscript.WalkAction {
+		name := namedChildrenOfKind(node, zscript.NodeStateLabelName)
+		if len(name) > 0 && len(namedChildrenOfKind(node, zscript.NodeNumberLiteral)) > 0 {
+			offset[strings.ToLower(strings.Join(strings.Fields(pass.Text(&name[0])), ""))] = true
+		}
+		return zscript.WalkSkipChildren
+	})
+	zscript.Walk(pass.Tree.RootNode(), &v)
+
+	v = zscript.Visitor{}
+	v.On(zscript.NodeCompoundStatement, func(node *tree_sitter.Node) zscript.WalkAction {
+		switch node.Parent().Kind() {
+		case zscript.NodeMethodDefinition, zscript.NodeFunctionDefinition, zscript.NodeStateAction:
+			for _, n := range flow.New(node, pass.Tree.Source).Unreachable() {
+				pass.ReportNode(n, "unreachable code")
+			}
+		}
+		return zscript.WalkContinue
+	})
+	v.On(zscript.NodeStateLabel, func(node *tree_sitter.Node) zscript.WalkAction {
+		body := node.ChildByFieldName(zscript.FieldBody)
+		name := node.ChildByFieldName(zscript.FieldName)
+		if body == nil || name == nil || offset[strings.ToLower(strings.Join(strings.Fields(pass.Text(name)), ""))] {
+			return zscript.WalkContinue
+		}
+		ended := false
+		for i := uint(0); i < body.NamedChildCount(); i++ {
+			child := body.NamedChild(i)
+			if child.IsExtra() {
+				continue
+			}
+			if ended {
+				pass.ReportNode(child, "state can never be reached")
+				break
+			}
+			ended = child.Kind() == zscript.NodeStateFlow
+		}
+		return zscript.WalkContinue
+	})
+	zscript.Walk(pass.Tree.RootNode(), &v)
+}
+
+// declarations returns the definition of c followed by its extensions.
+func declarations(c *hierarchy.Class) []*symbols.Class {
+	var decls []*symbols.Class
+	if c.Decl != nil {
+		decls = append(decls, c.Decl)
+	}
+	return append(decls, c.Extensions...)
+}
+
+func method(c *hierarchy.Class, name string) *symbols.Method {
+	for _, d := range declarations(c) {
+		if m := d.Method(name); m != nil {
+			return m
+		}
+	}
+	return nil
+}
+
+func field(c *hierarchy.Class, name string) *symbols.Field {
+	for _, d := range declarations(c) {
+		if f := d.Field(name); f != nil {
+			return f
+		}
+	}
+	return nil
+}
+
+func namedChildrenByField(node *tree_sitter.Node, field string) []tree_sitter.Node {
+	cursor := node.Walk()
+	defer cursor.Close()
+	var result []tree_sitter.Node
+	for _, child := range node.ChildrenByFieldName(field, cursor) {
+		if child.IsNamed() {
+			result = append(result, child)
+		}
+	}
+	return result
+}
+
+func namedChildrenOfKind(node *tree_sitter.Node, kind string) []tree_sitter.Node {
+	var result []tree_sitter.Node
+	for i := uint(0); i < node.NamedChildCount(); i++ {
+		if child := node.NamedChild(i); child.Kind() == kind {
+			result = append(result, *child)
+		}
+	}
+	return result
+}
+
+type invalidFlags struct{}
+
+func (invalidFlags) Name() string { return "invalid-flag" }
+func (invalidFlags) Doc() string {
+	return "flag is unknown or belongs to a class the actor does not inherit from"
+}
+func (invalidFlags) Severity() zscript.Severity { return zscript.SeverityError }
+
+func (invalidFlags) Check(pass *Pass) {
+	schema := defaults.NewSchema()
+	schema.Declare(pass.Tables...)
+	for _, c := range zscriptast.NewFile(pass.Tree.Tree, pass.Tree.Source).Classes() {
+		for _, p := range defaults.CheckFlags(c, pass.Hierarchy, schema) {
+			pass.Report(p.Range, "%s", p.Message)
+		}
+	}
+}
+
+type contradictoryFlags struct{}
+
+func (contradictoryFlags) Name() string               { return "contradictory-flag" }
+func (contradictoryFlags) Doc() string                { return "flag is both set and cleared in a Default block" }
+func (contradictoryFlags) Severity() zscript.Severity { return zscript.SeverityWarning }
+
+func (contradictoryFlags) Check(pass *Pass) {
+	for _, c := range zscriptast.NewFile(pass.Tree.Tree, pass.Tree.Source).Classes() {
+		for _, p := range defaults.Contradictions(defaults.ForClass(c)) {
+			pass.Report(p.Range, "%s", p.Message)
+		}
+	}
+}
+
+type invalidTranslations struct{}
+
+func (invalidTranslations) Name() string               { return "invalid-translation" }
+func (invalidTranslations) Doc() string                { return "translation range is malformed" }
+func (invalidTranslations) Severity() zscript.Severity { return zscript.SeverityError }
+
+func (invalidTranslations) Check(pass *Pass) {
+	var v zscript.Visitor
+	v.On(zscript.NodeCallExpression, func(call *tree_sitter.Node) zscript.WalkAction {
+		fn := call.ChildByFieldName(zscript.FieldFunction)
+		if fn == nil || fn.Kind() != zscript.NodeIdentifier || !strings.EqualFold(pass.Text(fn), "A_SetTranslation") {
+			return zscript.WalkContinue
+		}
+		if args := call.ChildByFieldName(zscript.FieldArguments); args != nil {
+			for _, arg := range namedChildrenOfKind(args, zscript.NodeStringLiteral) {
+				checkTranslation(pass, &arg)
+			}
+		}
+		return zscript.WalkContinue
+	})
+	zscript.Walk(pass.Tree.RootNode(), &v)
+
+	for _, c := range zscriptast.NewFile(pass.Tree.Tree, pass.Tree.Source).Classes() {
+		for _, p := range defaults.ForClass(c).Properties {
+			if !strings.EqualFold(p.Name, "Translation") {
+				continue
+			}
+			for _, value := range p.Values {
+				if value.Kind() == zscript.NodeStringLiteral {
+					checkTranslation(pass, value.Raw)
+				}
+			}
+		}
+	}
+}
+
+// checkTranslation reports the error in the translation range that the
+// string literal node holds, if it holds one.
+func checkTranslation(pass *Pass, node *tree_sitter.Node) {
+	l := newLiteral(pass, node)
+	if l == nil || !translation.IsRange(string(l.text)) {
+		return
+	}
+	if _, err := translation.Parse(string(l.text)); err != nil {
+		e := err.(*translation.Error)
+		pass.Report(l.span(e.Offset, e.Offset+1), "invalid translation: %s", e.Message)
+	}
+}
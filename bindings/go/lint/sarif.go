@@ -0,0 +1,204 @@
+package lint
+
+import (
+	"encoding/json"
+	"io"
+	"net/url"
+	"path/filepath"
+	"strings"
+
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+
+	zscript "github.com/jlcrochet/tree-sitter-zscript/bindings/go"
+)
+
+// RuleSyntax is the rule name of the findings of SyntaxErrors.
+const RuleSyntax = "syntax-error"
+
+// SyntaxErrors returns the syntax errors of tree, including the syntax
+// its Dialect lacks, as findings, so that they can be reported alongside
+// those of the rules.
+func SyntaxErrors(tree *zscript.Tree) []Finding {
+	var findings []Finding
+	for _, d := range tree.Dialect.Diagnostics(tree) {
+		findings = append(findings, Finding{
+			Rule:     RuleSyntax,
+			Severity: d.Severity,
+			Path:     tree.Path,
+			Range:    d.Range,
+			Message:  d.Message,
+		})
+	}
+	return findings
+}
+
+// The subset of SARIF 2.1.0 that WriteSARIF produces.
+type (
+	sarifLog struct {
+		Schema  string     `json:"$schema"`
+		Version string     `json:"version"`
+		Runs    []sarifRun `json:"runs"`
+	}
+	sarifRun struct {
+		Tool       sarifTool     `json:"tool"`
+		ColumnKind string        `json:"columnKind"`
+		Results    []sarifResult `json:"results"`
+	}
+	sarifTool struct {
+		Driver sarifDriver `json:"driver"`
+	}
+	sarifDriver struct {
+		Name           string      `json:"name"`
+		InformationURI string      `json:"informationUri"`
+		Rules          []sarifRule `json:"rules"`
+	}
+	sarifRule struct {
+		ID                   string         `json:"id"`
+		ShortDescription     *sarifMessage  `json:"shortDescription,omitempty"`
+		DefaultConfiguration *sarifRuleConf `json:"defaultConfiguration,omitempty"`
+	}
+	sarifRuleConf struct {
+		Level string `json:"level"`
+	}
+	sarifMessage struct {
+		Text string `json:"text"`
+	}
+	sarifResult struct {
+		RuleID    string          `json:"ruleId"`
+		RuleIndex int             `json:"ruleIndex"`
+		Level     string          `json:"level"`
+		Message   sarifMessage    `json:"message"`
+		Locations []sarifLocation `json:"locations"`
+		Related   []sarifLocation `json:"relatedLocations,omitempty"`
+		Fixes     []sarifFix      `json:"fixes,omitempty"`
+	}
+	sarifLocation struct {
+		PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+		Message          *sarifMessage         `json:"message,omitempty"`
+	}
+	sarifPhysicalLocation struct {
+		ArtifactLocation sarifArtifact `json:"artifactLocation"`
+		Region           sarifRegion   `json:"region"`
+	}
+	sarifArtifact struct {
+		URI string `json:"uri"`
+	}
+	sarifRegion struct {
+		StartLine   uint `json:"startLine"`
+		StartColumn uint `json:"startColumn"`
+		EndLine     uint `json:"endLine"`
+		EndColumn   uint `json:"endColumn"`
+	}
+	sarifFix struct {
+		Description     sarifMessage          `json:"description"`
+		ArtifactChanges []sarifArtifactChange `json:"artifactChanges"`
+	}
+	sarifArtifactChange struct {
+		ArtifactLocation sarifArtifact      `json:"artifactLocation"`
+		Replacements     []sarifReplacement `json:"replacements"`
+	}
+	sarifReplacement struct {
+		DeletedRegion   sarifRegion  `json:"deletedRegion"`
+		InsertedContent sarifMessage `json:"insertedContent"`
+	}
+)
+
+// WriteSARIF writes findings to w as a SARIF 2.1.0 log, the format read by
+// GitHub code scanning and other CI dashboards. rules describe the rules
+// in the log's tool section; rules of findings that are not among them,
+// such as RuleSyntax, are listed by name alone. Paths are written as
+// URIs, relative unless the path is absolute, and columns count bytes,
+// which match the log's Unicode code points for ASCII source.
+func WriteSARIF(w io.Writer, rules []Rule, findings []Finding) error {
+	driver := sarifDriver{
+		Name:           "zscript",
+		InformationURI: "https://github.com/jlcrochet/tree-sitter-zscript",
+		Rules:          []sarifRule{},
+	}
+	index := map[string]int{}
+	for _, r := range rules {
+		if _, ok := index[r.Name()]; ok {
+			continue
+		}
+		index[r.Name()] = len(driver.Rules)
+		driver.Rules = append(driver.Rules, sarifRule{
+			ID:                   r.Name(),
+			ShortDescription:     &sarifMessage{r.Doc()},
+			DefaultConfiguration: &sarifRuleConf{sarifLevel(r.Severity())},
+		})
+	}
+
+	results := []sarifResult{}
+	for _, f := range findings {
+		i, ok := index[f.Rule]
+		if !ok {
+			i = len(driver.Rules)
+			index[f.Rule] = i
+			driver.Rules = append(driver.Rules, sarifRule{ID: f.Rule})
+		}
+		uri := artifactURI(f.Path)
+		result := sarifResult{
+			RuleID:    f.Rule,
+			RuleIndex: i,
+			Level:     sarifLevel(f.Severity),
+			Message:   sarifMessage{f.Message},
+			Locations: []sarifLocation{{PhysicalLocation: sarifPhysicalLocation{sarifArtifact{uri}, region(f.Range)}}},
+		}
+		for _, n := range f.Notes {
+			result.Related = append(result.Related, sarifLocation{
+				PhysicalLocation: sarifPhysicalLocation{sarifArtifact{artifactURI(n.Path)}, region(n.Range)},
+				Message:          &sarifMessage{n.Message},
+			})
+		}
+		if f.Fix != nil {
+			change := sarifArtifactChange{ArtifactLocation: sarifArtifact{uri}}
+			for _, e := range f.Fix.Edits {
+				change.Replacements = append(change.Replacements, sarifReplacement{region(e.Range), sarifMessage{e.NewText}})
+			}
+			result.Fixes = []sarifFix{{sarifMessage{f.Fix.Message}, []sarifArtifactChange{change}}}
+		}
+		results = append(results, result)
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(sarifLog{
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Version: "2.1.0",
+		Runs:    []sarifRun{{Tool: sarifTool{driver}, ColumnKind: "unicodeCodePoints", Results: results}},
+	})
+}
+
+// artifactURI returns the URI of a path: a file URI if it is absolute
+// and a relative reference otherwise.
+func artifactURI(path string) string {
+	slashed := filepath.ToSlash(path)
+	if !filepath.IsAbs(path) {
+		return (&url.URL{Path: slashed}).String()
+	}
+	if !strings.HasPrefix(slashed, "/") {
+		slashed = "/" + slashed
+	}
+	return (&url.URL{Scheme: "file", Path: slashed}).String()
+}
+
+// region converts r to a SARIF region, with 1-based lines and columns.
+func region(r tree_sitter.Range) sarifRegion {
+	return sarifRegion{
+		StartLine:   r.StartPoint.Row + 1,
+		StartColumn: r.StartPoint.Column + 1,
+		EndLine:     r.EndPoint.Row + 1,
+		EndColumn:   r.EndPoint.Column + 1,
+	}
+}
+
+// sarifLevel returns the SARIF level of a severity.
+func sarifLevel(s zscript.Severity) string {
+	switch s {
+	case zscript.SeverityError:
+		return "error"
+	case zscript.SeverityWarning:
+		return "warning"
+	}
+	return "note"
+}
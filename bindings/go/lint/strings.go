@@ -0,0 +1,313 @@
+package lint
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+
+	zscript "github.com/jlcrochet/tree-sitter-zscript/bindings/go"
+)
+
+// literal is the text between the quotes of one or more adjacent string
+// literals, or of a name literal, with the source position of each byte.
+type literal struct {
+	text   []byte
+	starts []tree_sitter.Point
+	bytes  []uint
+	node   *tree_sitter.Node
+}
+
+// newLiteral returns the contents of node, a string literal, concatenated
+// string or name literal. It returns nil if node has syntax errors.
+func newLiteral(pass *Pass, node *tree_sitter.Node) *literal {
+	if node.HasError() {
+		return nil
+	}
+	l := &literal{node: node}
+	pieces := []tree_sitter.Node{*node}
+	if node.Kind() == zscript.NodeConcatenatedString {
+		pieces = namedChildrenOfKind(node, zscript.NodeStringLiteral)
+	}
+	for _, p := range pieces {
+		start, end := p.StartByte()+1, p.EndByte()-1
+		if end < start || p.StartPosition().Row != p.EndPosition().Row {
+			return nil
+		}
+		point := p.StartPosition()
+		point.Column++
+		for i := start; i < end; i++ {
+			l.text = append(l.text, pass.Tree.Source[i])
+			l.starts = append(l.starts, point)
+			l.bytes = append(l.bytes, i)
+			point.Column++
+		}
+	}
+	return l
+}
+
+// span returns the source range of the bytes i through j-1 of the text.
+// At the end of the text it returns the range of the closing quote.
+func (l *literal) span(i, j int) tree_sitter.Range {
+	if j <= i {
+		j = i + 1
+	}
+	if i == len(l.text) && i > 0 {
+		r := l.span(i-1, i)
+		r.StartPoint, r.StartByte = r.EndPoint, r.EndByte
+		r.EndPoint.Column++
+		r.EndByte++
+		return r
+	}
+	if j > len(l.text) {
+		return l.node.Range()
+	}
+	end, endByte := l.starts[j-1], l.bytes[j-1]+1
+	end.Column++
+	return tree_sitter.Range{StartPoint: l.starts[i], EndPoint: end, StartByte: l.bytes[i], EndByte: endByte}
+}
+
+type invalidEscapes struct{}
+
+func (invalidEscapes) Name() string { return "invalid-escape" }
+func (invalidEscapes) Doc() string {
+	return "string contains an unknown escape sequence or a malformed color escape"
+}
+func (invalidEscapes) Severity() zscript.Severity { return zscript.SeverityWarning }
+
+func (invalidEscapes) Check(pass *Pass) {
+	var v zscript.Visitor
+	check := func(node *tree_sitter.Node) zscript.WalkAction {
+		if l := newLiteral(pass, node); l != nil {
+			checkEscapes(pass, l, node.Kind() != zscript.NodeNameLiteral)
+		}
+		return zscript.WalkSkipChildren
+	}
+	v.On(zscript.NodeStringLiteral, check)
+	v.On(zscript.NodeConcatenatedString, check)
+	v.On(zscript.NodeNameLiteral, check)
+	zscript.Walk(pass.Tree.RootNode(), &v)
+}
+
+// checkEscapes reports the escape sequences of l that GZDoom does not
+// know, and, if colors is set, the \c escapes not followed by a color.
+func checkEscapes(pass *Pass, l *literal, colors bool) {
+	text := l.text
+	for i := 0; i < len(text); i++ {
+		if text[i] != '\\' || i+1 == len(text) {
+			continue
+		}
+		c := text[i+1]
+		switch {
+		case strings.IndexByte(`abfnrtv\"'?xuU`, c) >= 0, '0' <= c && c <= '7':
+		case c == 'c' && colors:
+			checkColor(pass, l, i)
+		case c == 'c':
+			pass.Report(l.span(i, i+2), `color escape \c in a name literal`)
+		default:
+			r, size := utf8.DecodeRune(text[i+1:])
+			pass.Report(l.span(i, i+1+size), `unknown escape sequence \%c`, r)
+		}
+		i++
+	}
+}
+
+// checkColor reports the \c escape at byte i of l unless a color code or
+// a bracketed color name follows it.
+func checkColor(pass *Pass, l *literal, i int) {
+	text, j := l.text, i+2
+	switch {
+	case j == len(text):
+		pass.Report(l.span(i, j), `color escape \c is not followed by a color`)
+	case text[j] == '[':
+		end := strings.IndexByte(string(text[j:]), ']')
+		switch {
+		case end < 0:
+			pass.Report(l.span(i, len(text)), `color name after \c is missing its closing "]"`)
+		case end == 1:
+			pass.Report(l.span(i, j+2), `color escape \c has an empty color name`)
+		}
+	case 'a' <= text[j] && text[j] <= 'z', 'A' <= text[j] && text[j] <= 'Z', strings.IndexByte("+-*!", text[j]) >= 0:
+	default:
+		r, size := utf8.DecodeRune(text[j:])
+		pass.Report(l.span(i, j+size), `invalid color code %q after \c`, r)
+	}
+}
+
+type formatMismatch struct{}
+
+func (formatMismatch) Name() string { return "format-mismatch" }
+func (formatMismatch) Doc() string {
+	return "format string has invalid specifiers or does not match its arguments"
+}
+func (formatMismatch) Severity() zscript.Severity { return zscript.SeverityError }
+
+func (formatMismatch) Check(pass *Pass) {
+	var v zscript.Visitor
+	v.On(zscript.NodeCallExpression, func(call *tree_sitter.Node) zscript.WalkAction {
+		index, ok := formatIndex(pass, call)
+		if !ok {
+			return zscript.WalkContinue
+		}
+		var args []tree_sitter.Node
+		if list := call.ChildByFieldName(zscript.FieldArguments); list != nil {
+			for i := uint(0); i < list.NamedChildCount(); i++ {
+				switch arg := list.NamedChild(i); arg.Kind() {
+				case zscript.NodeComment:
+				case zscript.NodeNamedArgument:
+					return zscript.WalkContinue
+				default:
+					args = append(args, *arg)
+				}
+			}
+		}
+		if len(args) <= index {
+			return zscript.WalkContinue
+		}
+		switch args[index].Kind() {
+		case zscript.NodeStringLiteral, zscript.NodeConcatenatedString:
+			if l := newLiteral(pass, &args[index]); l != nil {
+				checkFormat(pass, l, args[index+1:])
+			}
+		}
+		return zscript.WalkContinue
+	})
+	zscript.Walk(pass.Tree.RootNode(), &v)
+}
+
+// formatIndex returns the position of the format string among the
+// arguments of call, if call is to String.Format, String.AppendFormat,
+// Console.Printf, Console.PrintfEx or ThrowAbortException.
+func formatIndex(pass *Pass, call *tree_sitter.Node) (int, bool) {
+	fn := call.ChildByFieldName(zscript.FieldFunction)
+	if fn == nil {
+		return 0, false
+	}
+	if fn.Kind() == zscript.NodeIdentifier {
+		return 0, strings.EqualFold(pass.Text(fn), "ThrowAbortException")
+	}
+	if fn.Kind() != zscript.NodeFieldExpression {
+		return 0, false
+	}
+	receiver, field := fn.ChildByFieldName(zscript.FieldArgument), fn.ChildByFieldName(zscript.FieldField)
+	if receiver == nil || field == nil {
+		return 0, false
+	}
+	is := func(name string) bool {
+		return receiver.Kind() == zscript.NodeIdentifier && strings.EqualFold(pass.Text(receiver), name)
+	}
+	switch strings.ToLower(pass.Text(field)) {
+	case "appendformat":
+		return 0, true
+	case "format":
+		return 0, is("String")
+	case "printf":
+		return 0, is("Console")
+	case "printfex":
+		return 1, is("Console")
+	}
+	return 0, false
+}
+
+// checkFormat reports the invalid specifiers of the format string l, the
+// arguments that do not suit their specifiers, and a difference between
+// the number of arguments the format uses and len(args).
+func checkFormat(pass *Pass, l *literal, args []tree_sitter.Node) {
+	text, used := l.text, 0
+	// take consumes the next argument for the specifier at bytes i to j,
+	// which wants a value of kind "number" or "string".
+	take := func(i, j int, want string) bool {
+		if used == len(args) {
+			pass.Report(l.span(i, j), "too few arguments for the format string: %s has no argument", text[i:j])
+			return false
+		}
+		arg := &args[used]
+		used++
+		got := ""
+		switch arg.Kind() {
+		case zscript.NodeStringLiteral, zscript.NodeConcatenatedString:
+			got = "string"
+		case zscript.NodeNumberLiteral:
+			got = "number"
+		}
+		if got != "" && got != want {
+			pass.ReportNode(arg, "%s expects a %s but is given a %s", text[i:j], want, got)
+		}
+		return true
+	}
+	for i := 0; i < len(text); i++ {
+		switch text[i] {
+		case '\\':
+			i++
+			continue
+		case '%':
+		default:
+			continue
+		}
+		j := i + 1
+		if j < len(text) && text[j] == '%' {
+			i++
+			continue
+		}
+		for j < len(text) && strings.IndexByte("-+ #0", text[j]) >= 0 {
+			j++
+		}
+		if j < len(text) && text[j] == '*' {
+			j++
+			if !take(i, j, "number") {
+				return
+			}
+		}
+		j = skipDigits(text, j)
+		if j < len(text) && text[j] == '.' {
+			j++
+			if j < len(text) && text[j] == '*' {
+				j++
+				if !take(i, j, "number") {
+					return
+				}
+			}
+			j = skipDigits(text, j)
+		}
+		for j < len(text) && strings.IndexByte("hlLqjzt", text[j]) >= 0 {
+			j++
+		}
+		if j == len(text) {
+			pass.Report(l.span(i, j), "format string ends in an incomplete specifier %s", text[i:j])
+			return
+		}
+		verb := text[j]
+		j++
+		want := ""
+		switch {
+		case strings.IndexByte("diuxXocBfFeEgGaA", verb) >= 0:
+			want = "number"
+		case verb == 's':
+			want = "string"
+		case verb == 'p':
+		case verb == 'n':
+			pass.Report(l.span(i, j), "format specifier %%n is not supported")
+			i = j - 1
+			continue
+		default:
+			r, size := utf8.DecodeRune(text[j-1:])
+			pass.Report(l.span(i, j-1+size), "invalid format specifier %%%c", r)
+			i = j - 2 + size
+			continue
+		}
+		if !take(i, j, want) {
+			return
+		}
+		i = j - 1
+	}
+	if used < len(args) {
+		pass.ReportNode(&args[used], "too many arguments for the format string: it uses %d but is given %d", used, len(args))
+	}
+}
+
+func skipDigits(text []byte, i int) int {
+	for i < len(text) && '0' <= text[i] && text[i] <= '9' {
+		i++
+	}
+	return i
+}
@@ -0,0 +1,50 @@
+package lsp
+
+import (
+	"context"
+
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/codeaction"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/project"
+)
+
+// codeActions returns the actions for rng in d, of the kinds in only or of
+// every kind. They are computed over a project of every known document;
+// indexed documents are parsed for the duration of the request. Renames
+// are left to the client, which asks for the new name first.
+func (s *Server) codeActions(ctx context.Context, d *document, rng Range, only []string) []CodeAction {
+	_, byPath := s.resolver()
+	trees, done := s.trees(ctx, byPath)
+	defer done()
+	var files []*project.File
+	for _, doc := range s.documents() {
+		if tree := trees(doc.table.Path); tree != nil && byPath[doc.table.Path] == doc {
+			files = append(files, &project.File{Path: doc.table.Path, Tree: tree})
+		}
+	}
+	req := &codeaction.Request{
+		Project: project.New(files...),
+		Path:    d.table.Path,
+		Start:   d.offset(rng.Start, s.utf8),
+		End:     d.offset(rng.End, s.utf8),
+	}
+	for _, k := range only {
+		req.Only = append(req.Only, codeaction.Kind(k))
+	}
+
+	providers := codeaction.DefaultProviders()
+	if s.linter != nil {
+		// The fixes offered are those of the configured rules.
+		providers[0] = codeaction.Fixes(s.linter)
+	}
+	result := []CodeAction{}
+	for _, a := range codeaction.Actions(req, providers...) {
+		edit := &WorkspaceEdit{Changes: map[string][]TextEdit{}}
+		for _, e := range a.Edits {
+			if doc := byPath[e.Path]; doc != nil {
+				edit.Changes[doc.uri] = append(edit.Changes[doc.uri], TextEdit{Range: doc.lspRange(e.Range, s.utf8), NewText: e.NewText})
+			}
+		}
+		result = append(result, CodeAction{Title: a.Title, Kind: string(a.Kind), IsPreferred: a.Preferred, Edit: edit})
+	}
+	return result
+}
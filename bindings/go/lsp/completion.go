@@ -0,0 +1,39 @@
+package lsp
+
+import (
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/complete"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/defaults"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/symbols"
+)
+
+// completionKinds maps the kinds of declarations to LSP completion item
+// kinds. Keywords, of kind 0, are 14.
+var completionKinds = map[symbols.Kind]int{
+	0:                      14,
+	symbols.KindClass:      7,
+	symbols.KindStruct:     22,
+	symbols.KindEnum:       13,
+	symbols.KindEnumerator: 20,
+	symbols.KindConst:      21,
+	symbols.KindField:      5,
+	symbols.KindMethod:     2,
+	symbols.KindProperty:   10,
+	symbols.KindFlag:       20,
+	symbols.KindStateLabel: 18,
+}
+
+// completion returns the completions at pos in d. Default blocks are
+// completed with the engine's properties and flags and those the known
+// documents declare.
+func (s *Server) completion(d *document, pos Position) []CompletionItem {
+	r, _ := s.resolver()
+	schema := defaults.NewSchema()
+	for _, doc := range s.documents() {
+		schema.Declare(doc.table)
+	}
+	items := []CompletionItem{}
+	for _, item := range complete.Complete(r, schema, d.tree, d.offset(pos, s.utf8)).Items {
+		items = append(items, CompletionItem{Label: item.Label, Kind: completionKinds[item.Kind], Detail: item.Detail})
+	}
+	return items
+}
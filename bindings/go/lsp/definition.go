@@ -0,0 +1,145 @@
+package lsp
+
+import (
+	"context"
+	"errors"
+
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+
+	zscript "github.com/jlcrochet/tree-sitter-zscript/bindings/go"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/engine"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/navigate"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/resolve"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/symbols"
+)
+
+// resolver returns a resolver over every known document and the engine's
+// built-in declarations, and the documents by the path their symbol tables
+// record.
+func (s *Server) resolver() (*resolve.Resolver, map[string]*document) {
+	docs := s.documents()
+	tables := make([]*symbols.Table, len(docs))
+	byPath := map[string]*document{}
+	for i, d := range docs {
+		tables[i] = d.table
+		if _, ok := byPath[d.table.Path]; !ok {
+			byPath[d.table.Path] = d
+		}
+	}
+	return resolve.NewWithEngine(engine.Version, tables...), byPath
+}
+
+// definition returns the declarations of the identifier at pos in d, or
+// of the class or state label a string literal there names.
+func (s *Server) definition(d *document, pos Position) []Location {
+	locations := []Location{}
+	offset := d.offset(pos, s.utf8)
+	node := identifierAt(d, offset)
+	if node == nil {
+		node = literalAt(d, offset)
+	}
+	if node == nil {
+		return locations
+	}
+	r, byPath := s.resolver()
+	for _, decl := range navigate.Declarations(r, d.tree, node) {
+		if doc := byPath[decl.Path]; doc != nil {
+			locations = append(locations, Location{URI: doc.uri, Range: doc.lspRange(decl.NameRange, s.utf8)})
+		}
+	}
+	return locations
+}
+
+// typeDefinition returns the declarations of the type of the identifier
+// at pos in d.
+func (s *Server) typeDefinition(d *document, pos Position) []Location {
+	locations := []Location{}
+	node := identifierAt(d, d.offset(pos, s.utf8))
+	if node == nil {
+		return locations
+	}
+	r, byPath := s.resolver()
+	for _, decl := range navigate.TypeDeclarations(r, d.tree, node) {
+		if doc := byPath[decl.Path]; doc != nil {
+			locations = append(locations, Location{URI: doc.uri, Range: doc.lspRange(decl.NameRange, s.utf8)})
+		}
+	}
+	return locations
+}
+
+// identifierAt returns the identifier at offset, or the one ending there
+// when the cursor is just past a word.
+func identifierAt(d *document, offset uint) *tree_sitter.Node {
+	root := d.tree.RootNode()
+	for _, o := range []uint{offset, offset - 1} {
+		if o > offset {
+			break
+		}
+		node := root.NamedDescendantForByteRange(o, o)
+		if node == nil {
+			continue
+		}
+		switch node.Kind() {
+		case zscript.NodeIdentifier, zscript.NodeTypeIdentifier, zscript.NodeFieldIdentifier:
+			return node
+		}
+	}
+	return nil
+}
+
+// literalAt returns the string or name literal that contains offset.
+func literalAt(d *document, offset uint) *tree_sitter.Node {
+	node := d.tree.RootNode().NamedDescendantForByteRange(offset, offset)
+	if node != nil && node.Kind() == zscript.NodeStringContent {
+		node = node.Parent()
+	}
+	if node != nil && (node.Kind() == zscript.NodeStringLiteral || node.Kind() == zscript.NodeNameLiteral) {
+		return node
+	}
+	return nil
+}
+
+// references returns the uses of the declaration of the identifier at pos
+// in d across every known document. Indexed documents are parsed for the
+// duration of the search.
+func (s *Server) references(ctx context.Context, d *document, pos Position, includeDeclaration bool) ([]Location, error) {
+	locations := []Location{}
+	node := identifierAt(d, d.offset(pos, s.utf8))
+	if node == nil {
+		return locations, nil
+	}
+	r, byPath := s.resolver()
+	decl, ok := r.Resolve(d.tree, node)
+	if !ok {
+		return locations, nil
+	}
+
+	var trees []*zscript.Tree
+	for _, doc := range s.documents() {
+		if doc.tree != nil {
+			trees = append(trees, doc.tree)
+			continue
+		}
+		parseCtx, cancel := s.parseContext(ctx)
+		tree, err := zscript.Parse(parseCtx, doc.text)
+		cancel()
+		if errors.Is(err, context.DeadlineExceeded) && ctx.Err() == nil {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		defer tree.Close()
+		tree.Path = doc.table.Path
+		trees = append(trees, tree)
+	}
+	for _, ref := range r.References(decl, trees...) {
+		if !includeDeclaration && ref.Path == decl.Path && ref.Range.StartByte == decl.NameRange.StartByte {
+			continue
+		}
+		if doc := byPath[ref.Path]; doc != nil {
+			locations = append(locations, Location{URI: doc.uri, Range: doc.lspRange(ref.Range, s.utf8)})
+		}
+	}
+	return locations, nil
+}
@@ -0,0 +1,133 @@
+package lsp
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"path/filepath"
+
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+
+	zscript "github.com/jlcrochet/tree-sitter-zscript/bindings/go"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/cache"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/positions"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/symbols"
+)
+
+// document is a parsed file, either open in the editor or indexed from the
+// workspace. Open documents are kept parsed incrementally as they change;
+// indexed files keep their symbol table and folding ranges but not their
+// tree.
+type document struct {
+	uri     string
+	version int
+	text    []byte
+	index   *positions.Index
+	tree    *zscript.Tree
+	table   *symbols.Table
+	// folds are the folding ranges of an indexed file.
+	folds []zscript.FoldingRange
+	// inc owns tree for open documents.
+	inc *zscript.IncrementalDocument
+}
+
+// indexDocument parses a workspace file and keeps its symbol table and
+// folding ranges. They are taken from c, if it is not nil and has an
+// entry for text.
+func indexDocument(ctx context.Context, uri string, text []byte, c *cache.Cache) (*document, error) {
+	if c != nil {
+		e, err := c.Load(ctx, uriPath(uri), text)
+		if err != nil {
+			return nil, err
+		}
+		return &document{uri: uri, text: text, index: positions.NewIndex(text), table: e.Table, folds: e.Syntax.Folding()}, nil
+	}
+	tree, err := zscript.Parse(ctx, text)
+	if err != nil {
+		return nil, err
+	}
+	defer tree.Close()
+	tree.Path = uriPath(uri)
+	return &document{
+		uri:   uri,
+		text:  text,
+		index: positions.NewIndex(text),
+		table: symbols.Extract(tree),
+		folds: zscript.Folding(tree.Tree),
+	}, nil
+}
+
+// openDocument parses a document opened in the editor.
+func openDocument(ctx context.Context, uri string, version int, text []byte, encoding zscript.PositionEncoding) (*document, error) {
+	inc, err := zscript.NewIncrementalDocument(ctx, text)
+	if errors.Is(err, context.DeadlineExceeded) {
+		// Keep the text with an empty tree, so that later changes apply
+		// to it and parse it again.
+		if inc, err = zscript.NewIncrementalDocument(context.Background(), nil); err == nil {
+			err = inc.EditBytes(0, 0, string(text))
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	inc.Encoding = encoding
+	inc.Tree().Path = uriPath(uri)
+	d := &document{uri: uri, version: version, inc: inc}
+	d.refresh()
+	return d, nil
+}
+
+// refresh updates the text, tree and symbols of an open document after
+// its incremental document has been reparsed.
+func (d *document) refresh() {
+	d.tree = d.inc.Tree()
+	d.text = d.tree.Source
+	d.index = positions.NewIndex(d.text)
+	d.table = symbols.Extract(d.tree)
+}
+
+func (d *document) close() {
+	if d.inc != nil {
+		d.inc.Close()
+		d.inc = nil
+		d.tree = nil
+	}
+}
+
+// offset converts p to a byte offset, clamping it to the document.
+func (d *document) offset(p Position, utf8Encoding bool) uint {
+	return d.index.Offset(positions.Position{Line: p.Line, Character: p.Character}, positionEncoding(utf8Encoding))
+}
+
+// position converts a tree-sitter point, whose column counts bytes, to an
+// LSP position.
+func (d *document) position(pt tree_sitter.Point, utf8Encoding bool) Position {
+	p := d.index.PointPosition(pt, positionEncoding(utf8Encoding))
+	return Position{Line: p.Line, Character: p.Character}
+}
+
+func positionEncoding(utf8Encoding bool) positions.Encoding {
+	if utf8Encoding {
+		return positions.UTF8
+	}
+	return positions.UTF16
+}
+
+func (d *document) lspRange(r tree_sitter.Range, utf8Encoding bool) Range {
+	return Range{Start: d.position(r.StartPoint, utf8Encoding), End: d.position(r.EndPoint, utf8Encoding)}
+}
+
+// uriPath returns the file system path of a file URI, or the URI itself
+// if it is not one.
+func uriPath(uri string) string {
+	u, err := url.Parse(uri)
+	if err != nil || u.Scheme != "file" {
+		return uri
+	}
+	return filepath.FromSlash(u.Path)
+}
+
+// pathURI returns the file URI of an absolute path.
+func pathURI(path string) string {
+	return (&url.URL{Scheme: "file", Path: filepath.ToSlash(path)}).String()
+}
@@ -0,0 +1,16 @@
+package lsp
+
+import zscript "github.com/jlcrochet/tree-sitter-zscript/bindings/go"
+
+// foldingRanges returns the foldable regions of d.
+func foldingRanges(d *document) []FoldingRange {
+	folds := d.folds
+	if d.tree != nil {
+		folds = zscript.Folding(d.tree.Tree)
+	}
+	result := []FoldingRange{}
+	for _, r := range folds {
+		result = append(result, FoldingRange{StartLine: r.StartLine, EndLine: r.EndLine, Kind: r.Kind.String()})
+	}
+	return result
+}
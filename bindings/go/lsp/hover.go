@@ -0,0 +1,63 @@
+package lsp
+
+import (
+	"context"
+
+	zscript "github.com/jlcrochet/tree-sitter-zscript/bindings/go"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/hover"
+)
+
+// hover describes the identifier at pos in d, or returns nil. An indexed
+// document that declares it is parsed for the duration of the request, for
+// its documentation comment.
+func (s *Server) hover(ctx context.Context, d *document, pos Position) *Hover {
+	node := identifierAt(d, d.offset(pos, s.utf8))
+	if node == nil {
+		return nil
+	}
+	r, byPath := s.resolver()
+	trees, done := s.trees(ctx, byPath)
+	defer done()
+	info, ok := hover.Describe(r, trees, d.tree, node)
+	if !ok {
+		return nil
+	}
+	rng := d.lspRange(info.Range, s.utf8)
+	return &Hover{Contents: MarkupContent{Kind: "markdown", Value: info.Markdown()}, Range: &rng}
+}
+
+// trees returns a function that returns the tree of the document at a
+// path in byPath, parsing an indexed document the first time it is asked
+// for, and a function that closes the trees parsed.
+func (s *Server) trees(ctx context.Context, byPath map[string]*document) (func(path string) *zscript.Tree, func()) {
+	parsed := map[string]*zscript.Tree{}
+	trees := func(path string) *zscript.Tree {
+		doc := byPath[path]
+		switch {
+		case doc == nil:
+			return nil
+		case doc.tree != nil:
+			return doc.tree
+		}
+		if tree, ok := parsed[path]; ok {
+			return tree
+		}
+		parseCtx, cancel := s.parseContext(ctx)
+		defer cancel()
+		tree, err := zscript.Parse(parseCtx, doc.text)
+		if err != nil {
+			parsed[path] = nil
+			return nil
+		}
+		tree.Path = path
+		parsed[path] = tree
+		return tree
+	}
+	return trees, func() {
+		for _, tree := range parsed {
+			if tree != nil {
+				tree.Close()
+			}
+		}
+	}
+}
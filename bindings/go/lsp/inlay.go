@@ -0,0 +1,30 @@
+package lsp
+
+import (
+	"context"
+
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/inlay"
+)
+
+// inlayHints returns the hints of d in rng. Indexed documents that declare
+// the constants and types involved are parsed for the duration of the
+// request.
+func (s *Server) inlayHints(ctx context.Context, d *document, rng Range) []InlayHint {
+	r, byPath := s.resolver()
+	trees, done := s.trees(ctx, byPath)
+	defer done()
+	result := []InlayHint{}
+	for _, h := range inlay.New(r, trees).Hints(d.tree, d.offset(rng.Start, s.utf8), d.offset(rng.End, s.utf8)) {
+		hint := InlayHint{Position: d.position(h.Point, s.utf8), Label: h.Label}
+		switch h.Kind {
+		case inlay.Parameter:
+			hint.Kind, hint.PaddingRight = InlayHintParameter, true
+		case inlay.Type:
+			hint.Kind = InlayHintType
+		case inlay.Value:
+			hint.PaddingLeft = true
+		}
+		result = append(result, hint)
+	}
+	return result
+}
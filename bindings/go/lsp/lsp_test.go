@@ -0,0 +1,503 @@
+package lsp_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"testing"
+
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/cache"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/internal/jsonrpc"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/lsp"
+)
+
+type client struct {
+	t             testing.TB
+	conn          *jsonrpc.Conn
+	nextID        int
+	messages      chan *jsonrpc.Message
+	notifications []*jsonrpc.Message
+	done          chan error
+}
+
+func start(t *testing.T) *client {
+	t.Helper()
+	return startServer(t, lsp.NewServer())
+}
+
+// startServer runs s on a pipe and returns a client connected to it.
+func startServer(t testing.TB, s *lsp.Server) *client {
+	t.Helper()
+	clientR, serverW := io.Pipe()
+	serverR, clientW := io.Pipe()
+	c := &client{
+		t:        t,
+		conn:     jsonrpc.NewConn(clientR, clientW),
+		messages: make(chan *jsonrpc.Message, 16),
+		done:     make(chan error, 1),
+	}
+	go func() {
+		c.done <- s.Serve(context.Background(), serverR, serverW)
+		serverW.Close()
+	}()
+	// Read continuously so that the server never blocks writing
+	// notifications.
+	go func() {
+		defer close(c.messages)
+		for {
+			m, err := c.conn.Read()
+			if err != nil {
+				return
+			}
+			c.messages <- m
+		}
+	}()
+	t.Cleanup(func() { clientW.Close() })
+	return c
+}
+
+// call sends a request and returns its result, collecting the
+// notifications that arrive before the response.
+func (c *client) call(method string, params, result any) {
+	c.t.Helper()
+	c.nextID++
+	id := json.RawMessage(strconv.Itoa(c.nextID))
+	raw, _ := json.Marshal(params)
+	if err := c.conn.Write(&jsonrpc.Message{ID: id, Method: method, Params: raw}); err != nil {
+		c.t.Fatal(err)
+	}
+	for {
+		m, ok := <-c.messages
+		if !ok {
+			c.t.Fatalf("%s: connection closed", method)
+		}
+		if m.Method != "" {
+			c.notifications = append(c.notifications, m)
+			continue
+		}
+		if string(m.ID) != string(id) {
+			c.t.Fatalf("%s: response has id %s, want %s", method, m.ID, id)
+		}
+		if m.Error != nil {
+			c.t.Fatalf("%s: %v", method, m.Error)
+		}
+		if result != nil {
+			if err := json.Unmarshal(m.Result, result); err != nil {
+				c.t.Fatalf("%s: %v", method, err)
+			}
+		}
+		return
+	}
+}
+
+func (c *client) notify(method string, params any) {
+	c.t.Helper()
+	if err := c.conn.Notify(method, params); err != nil {
+		c.t.Fatal(err)
+	}
+}
+
+const base = `class Base : Actor {
+	int health;
+	virtual void Tick() {}
+	States {
+	Spawn:
+		TNT1 A 1;
+		Loop;
+	}
+}
+`
+
+const main = `class Imp : Base {
+	override void Tick() {
+		int count = health;
+		String s = "héllo😀"; count++;
+	}
+	States {
+	Spawn:
+		TNT1 A 1;
+		Goto Super::Spawn;
+	}
+}
+`
+
+func TestServer(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "base.zs"), []byte(base), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	baseURI := "file://" + filepath.ToSlash(filepath.Join(dir, "base.zs"))
+	mainURI := "file://" + filepath.ToSlash(filepath.Join(dir, "main.zs"))
+
+	c := start(t)
+	var init lsp.InitializeResult
+	c.call("initialize", map[string]any{"rootUri": "file://" + filepath.ToSlash(dir)}, &init)
+	if init.Capabilities.PositionEncoding != "utf-16" || !init.Capabilities.DefinitionProvider {
+		t.Errorf("capabilities = %+v", init.Capabilities)
+	}
+	c.notify("initialized", map[string]any{})
+
+	t.Run("diagnostics", func(t *testing.T) {
+		c.notify("textDocument/didOpen", lsp.DidOpenTextDocumentParams{TextDocument: lsp.TextDocumentItem{
+			URI: mainURI, LanguageID: "zscript", Version: 1, Text: "class Imp { int x }",
+		}})
+		c.notify("textDocument/didChange", lsp.DidChangeTextDocumentParams{
+			TextDocument:   lsp.VersionedTextDocumentIdentifier{URI: mainURI, Version: 2},
+			ContentChanges: []lsp.TextDocumentContentChangeEvent{{Text: main}},
+		})
+		c.notifications = nil
+		var symbols []lsp.DocumentSymbol
+		c.call("textDocument/documentSymbol", lsp.DocumentSymbolParams{TextDocument: lsp.TextDocumentIdentifier{URI: mainURI}}, &symbols)
+
+		var published []lsp.PublishDiagnosticsParams
+		for _, m := range c.notifications {
+			var p lsp.PublishDiagnosticsParams
+			json.Unmarshal(m.Params, &p)
+			published = append(published, p)
+		}
+		if len(published) != 2 {
+			t.Fatalf("got %d publishDiagnostics, want 2", len(published))
+		}
+		if d := published[0].Diagnostics; len(d) != 1 || d[0].Message != `missing ";"` || d[0].Range.Start != (lsp.Position{Line: 0, Character: 17}) {
+			t.Errorf("version 1 diagnostics = %+v", d)
+		}
+		if d := published[1]; d.Version != 2 || len(d.Diagnostics) != 0 {
+			t.Errorf("version 2 diagnostics = %+v", d)
+		}
+	})
+
+	t.Run("documentSymbol", func(t *testing.T) {
+		var symbols []lsp.DocumentSymbol
+		c.call("textDocument/documentSymbol", lsp.DocumentSymbolParams{TextDocument: lsp.TextDocumentIdentifier{URI: mainURI}}, &symbols)
+		if len(symbols) != 1 || symbols[0].Name != "Imp" || symbols[0].Kind != lsp.SymbolClass || symbols[0].Detail != "Base" {
+			t.Fatalf("symbols = %+v", symbols)
+		}
+		children := symbols[0].Children
+		if len(children) != 2 || children[0].Name != "Tick" || children[0].Kind != lsp.SymbolMethod ||
+			children[1].Name != "Spawn" || children[1].Kind != lsp.SymbolKey {
+			t.Errorf("children = %+v", children)
+		}
+		if r := children[0].SelectionRange; r != (lsp.Range{Start: lsp.Position{Line: 1, Character: 15}, End: lsp.Position{Line: 1, Character: 19}}) {
+			t.Errorf("Tick selection range = %+v", r)
+		}
+	})
+
+	t.Run("foldingRange", func(t *testing.T) {
+		var ranges []lsp.FoldingRange
+		c.call("textDocument/foldingRange", lsp.FoldingRangeParams{TextDocument: lsp.TextDocumentIdentifier{URI: mainURI}}, &ranges)
+		want := []lsp.FoldingRange{
+			{StartLine: 0, EndLine: 9, Kind: lsp.FoldRegion},
+			{StartLine: 1, EndLine: 3, Kind: lsp.FoldRegion},
+			{StartLine: 5, EndLine: 8, Kind: lsp.FoldRegion},
+			{StartLine: 6, EndLine: 8, Kind: lsp.FoldRegion},
+		}
+		if len(ranges) != len(want) {
+			t.Fatalf("ranges = %+v", ranges)
+		}
+		for i := range want {
+			if ranges[i] != want[i] {
+				t.Errorf("range %d = %+v, want %+v", i, ranges[i], want[i])
+			}
+		}
+	})
+
+	t.Run("definition", func(t *testing.T) {
+		tests := []struct {
+			name string
+			pos  lsp.Position
+			want lsp.Location
+		}{
+			{"parent class", lsp.Position{Line: 0, Character: 14}, lsp.Location{URI: baseURI, Range: lsp.Range{Start: lsp.Position{Line: 0, Character: 6}, End: lsp.Position{Line: 0, Character: 10}}}},
+			{"inherited field", lsp.Position{Line: 2, Character: 16}, lsp.Location{URI: baseURI, Range: lsp.Range{Start: lsp.Position{Line: 1, Character: 5}, End: lsp.Position{Line: 1, Character: 11}}}},
+			// The string holds a character outside the BMP, which counts
+			// as two UTF-16 code units.
+			{"local after non-ASCII text", lsp.Position{Line: 3, Character: 24}, lsp.Location{URI: mainURI, Range: lsp.Range{Start: lsp.Position{Line: 2, Character: 6}, End: lsp.Position{Line: 2, Character: 11}}}},
+			{"super state label", lsp.Position{Line: 8, Character: 15}, lsp.Location{URI: baseURI, Range: lsp.Range{Start: lsp.Position{Line: 4, Character: 1}, End: lsp.Position{Line: 4, Character: 6}}}},
+		}
+		for _, tt := range tests {
+			var got []lsp.Location
+			c.call("textDocument/definition", lsp.TextDocumentPositionParams{TextDocument: lsp.TextDocumentIdentifier{URI: mainURI}, Position: tt.pos}, &got)
+			if len(got) != 1 || got[0] != tt.want {
+				t.Errorf("%s: got %+v, want %+v", tt.name, got, tt.want)
+			}
+		}
+	})
+
+	t.Run("typeDefinition", func(t *testing.T) {
+		var got []lsp.Location
+		c.call("textDocument/typeDefinition", lsp.TextDocumentPositionParams{TextDocument: lsp.TextDocumentIdentifier{URI: mainURI}, Position: lsp.Position{Line: 0, Character: 14}}, &got)
+		want := lsp.Location{URI: baseURI, Range: lsp.Range{Start: lsp.Position{Line: 0, Character: 6}, End: lsp.Position{Line: 0, Character: 10}}}
+		if len(got) != 1 || got[0] != want {
+			t.Errorf("type definition = %+v, want %+v", got, want)
+		}
+		c.call("textDocument/typeDefinition", lsp.TextDocumentPositionParams{TextDocument: lsp.TextDocumentIdentifier{URI: mainURI}, Position: lsp.Position{Line: 2, Character: 16}}, &got)
+		if len(got) != 0 {
+			t.Errorf("type definition of an int = %+v", got)
+		}
+	})
+
+	t.Run("references", func(t *testing.T) {
+		var got []lsp.Location
+		params := lsp.ReferenceParams{
+			TextDocumentPositionParams: lsp.TextDocumentPositionParams{TextDocument: lsp.TextDocumentIdentifier{URI: mainURI}, Position: lsp.Position{Line: 2, Character: 16}},
+			Context:                    lsp.ReferenceContext{IncludeDeclaration: true},
+		}
+		c.call("textDocument/references", params, &got)
+		want := []lsp.Location{
+			{URI: mainURI, Range: lsp.Range{Start: lsp.Position{Line: 2, Character: 14}, End: lsp.Position{Line: 2, Character: 20}}},
+			{URI: baseURI, Range: lsp.Range{Start: lsp.Position{Line: 1, Character: 5}, End: lsp.Position{Line: 1, Character: 11}}},
+		}
+		if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+			t.Errorf("references = %+v, want %+v", got, want)
+		}
+
+		params.Context.IncludeDeclaration = false
+		c.call("textDocument/references", params, &got)
+		if len(got) != 1 || got[0] != want[0] {
+			t.Errorf("references without declaration = %+v", got)
+		}
+	})
+
+	t.Run("hover", func(t *testing.T) {
+		var got lsp.Hover
+		c.call("textDocument/hover", lsp.TextDocumentPositionParams{TextDocument: lsp.TextDocumentIdentifier{URI: mainURI}, Position: lsp.Position{Line: 2, Character: 16}}, &got)
+		want := "```zscript\nint Base.health\n```\n\nDeclared in `Base`."
+		if got.Contents.Kind != "markdown" || got.Contents.Value != want || got.Range == nil || got.Range.Start != (lsp.Position{Line: 2, Character: 14}) {
+			t.Errorf("hover = %+v", got)
+		}
+	})
+
+	t.Run("inlayHint", func(t *testing.T) {
+		uri := "file://" + filepath.ToSlash(filepath.Join(dir, "hints.zs"))
+		c.notify("textDocument/didOpen", lsp.DidOpenTextDocumentParams{TextDocument: lsp.TextDocumentItem{
+			URI: uri, LanguageID: "zscript", Version: 1, Text: "class Hints : Base {\n\tconst N = 2;\n\tvoid F() { let c = health; A_SetTics(N); }\n}\n",
+		}})
+		defer c.notify("textDocument/didClose", lsp.DidCloseTextDocumentParams{TextDocument: lsp.TextDocumentIdentifier{URI: uri}})
+		var got []lsp.InlayHint
+		params := lsp.InlayHintParams{TextDocument: lsp.TextDocumentIdentifier{URI: uri}, Range: lsp.Range{End: lsp.Position{Line: 4}}}
+		c.call("textDocument/inlayHint", params, &got)
+		want := []lsp.InlayHint{
+			{Position: lsp.Position{Line: 2, Character: 17}, Label: ": int", Kind: lsp.InlayHintType},
+			{Position: lsp.Position{Line: 2, Character: 38}, Label: "tics:", Kind: lsp.InlayHintParameter, PaddingRight: true},
+			{Position: lsp.Position{Line: 2, Character: 39}, Label: "= 2", PaddingLeft: true},
+		}
+		if len(got) != len(want) {
+			t.Fatalf("inlay hints = %+v, want %+v", got, want)
+		}
+		for i := range want {
+			if got[i] != want[i] {
+				t.Errorf("hint %d = %+v, want %+v", i, got[i], want[i])
+			}
+		}
+	})
+
+	t.Run("codeAction", func(t *testing.T) {
+		uri := "file://" + filepath.ToSlash(filepath.Join(dir, "actions.zs"))
+		c.notify("textDocument/didOpen", lsp.DidOpenTextDocumentParams{TextDocument: lsp.TextDocumentItem{
+			URI: uri, LanguageID: "zscript", Version: 1, Text: "class Act : Base {\n\tvoid Tick() {}\n}\n",
+		}})
+		defer c.notify("textDocument/didClose", lsp.DidCloseTextDocumentParams{TextDocument: lsp.TextDocumentIdentifier{URI: uri}})
+		var got []lsp.CodeAction
+		at := lsp.Position{Line: 1, Character: 7}
+		c.call("textDocument/codeAction", lsp.CodeActionParams{TextDocument: lsp.TextDocumentIdentifier{URI: uri}, Range: lsp.Range{Start: at, End: at}}, &got)
+		if len(got) != 1 || got[0].Title != "Add override" || got[0].Kind != "quickfix" || !got[0].IsPreferred || got[0].Edit == nil {
+			t.Fatalf("code actions = %+v", got)
+		}
+		want := []lsp.TextEdit{{Range: lsp.Range{Start: lsp.Position{Line: 1, Character: 1}, End: lsp.Position{Line: 1, Character: 1}}, NewText: "override "}}
+		if edits := got[0].Edit.Changes[uri]; len(edits) != 1 || edits[0] != want[0] {
+			t.Errorf("edits = %+v, want %+v", got[0].Edit.Changes, want)
+		}
+	})
+
+	t.Run("semanticTokens", func(t *testing.T) {
+		var got lsp.SemanticTokens
+		c.call("textDocument/semanticTokens/full", lsp.SemanticTokensParams{TextDocument: lsp.TextDocumentIdentifier{URI: mainURI}}, &got)
+		// Imp, Base, Tick, count, health, s, count, the Spawn label
+		// and the goto target Super::Spawn.
+		want := []uint32{
+			0, 6, 3, 0, 1,
+			0, 6, 4, 0, 0,
+			1, 15, 4, 5, 1 | 16,
+			1, 6, 5, 7, 1,
+			0, 8, 6, 4, 0,
+			1, 9, 1, 7, 1,
+			0, 15, 5, 7, 0,
+			3, 1, 5, 9, 1,
+			2, 7, 5, 0, 0,
+			0, 7, 5, 9, 0,
+		}
+		if len(got.Data) != len(want) {
+			t.Fatalf("data = %v, want %v", got.Data, want)
+		}
+		for i := range want {
+			if got.Data[i] != want[i] {
+				t.Fatalf("data = %v, want %v", got.Data, want)
+			}
+		}
+	})
+
+	t.Run("incremental change", func(t *testing.T) {
+		insert := lsp.Range{Start: lsp.Position{Line: 1, Character: 0}, End: lsp.Position{Line: 1, Character: 0}}
+		rename := lsp.Range{Start: lsp.Position{Line: 0, Character: 6}, End: lsp.Position{Line: 0, Character: 9}}
+		c.notify("textDocument/didChange", lsp.DidChangeTextDocumentParams{
+			TextDocument: lsp.VersionedTextDocumentIdentifier{URI: mainURI, Version: 3},
+			ContentChanges: []lsp.TextDocumentContentChangeEvent{
+				{Range: &insert, Text: "\tint extra;\n"},
+				{Range: &rename, Text: "Demon"},
+			},
+		})
+		var symbols []lsp.DocumentSymbol
+		c.call("textDocument/documentSymbol", lsp.DocumentSymbolParams{TextDocument: lsp.TextDocumentIdentifier{URI: mainURI}}, &symbols)
+		if len(symbols) != 1 || symbols[0].Name != "Demon" {
+			t.Fatalf("symbols = %+v", symbols)
+		}
+		if children := symbols[0].Children; len(children) != 3 || children[0].Name != "extra" || children[0].Range.Start.Line != 1 {
+			t.Errorf("children = %+v", children)
+		}
+	})
+
+	t.Run("signatureHelp", func(t *testing.T) {
+		// Line 3 is "\t\tint count = health;" after the change above.
+		insert := lsp.Range{Start: lsp.Position{Line: 3, Character: 2}, End: lsp.Position{Line: 3, Character: 2}}
+		c.notify("textDocument/didChange", lsp.DidChangeTextDocumentParams{
+			TextDocument:   lsp.VersionedTextDocumentIdentifier{URI: mainURI, Version: 4},
+			ContentChanges: []lsp.TextDocumentContentChangeEvent{{Range: &insert, Text: `GiveInventory("Clip", `}},
+		})
+		var got lsp.SignatureHelp
+		c.call("textDocument/signatureHelp", lsp.TextDocumentPositionParams{TextDocument: lsp.TextDocumentIdentifier{URI: mainURI}, Position: lsp.Position{Line: 3, Character: 24}}, &got)
+		want := "bool Actor.GiveInventory(class<Inventory> type, int amount, bool givecheat = false)"
+		if len(got.Signatures) != 1 || got.Signatures[0].Label != want || len(got.Signatures[0].Parameters) != 3 || got.ActiveParameter != 1 {
+			t.Errorf("signature help = %+v", got)
+		}
+	})
+
+	t.Run("completion", func(t *testing.T) {
+		var got []lsp.CompletionItem
+		c.call("textDocument/completion", lsp.TextDocumentPositionParams{TextDocument: lsp.TextDocumentIdentifier{URI: mainURI}, Position: lsp.Position{Line: 1, Character: 1}}, &got)
+		if len(got) != 0 {
+			t.Errorf("completion in class body = %+v", got)
+		}
+		insert := lsp.Range{Start: lsp.Position{Line: 2, Character: 0}, End: lsp.Position{Line: 2, Character: 0}}
+		c.notify("textDocument/didChange", lsp.DidChangeTextDocumentParams{
+			TextDocument:   lsp.VersionedTextDocumentIdentifier{URI: mainURI, Version: 5},
+			ContentChanges: []lsp.TextDocumentContentChangeEvent{{Range: &insert, Text: "\tvoid F() { Super.Ti }\n"}},
+		})
+		c.call("textDocument/completion", lsp.TextDocumentPositionParams{TextDocument: lsp.TextDocumentIdentifier{URI: mainURI}, Position: lsp.Position{Line: 2, Character: 20}}, &got)
+		want := lsp.CompletionItem{Label: "Tick", Kind: 2, Detail: "void Base.Tick()"}
+		if len(got) == 0 || got[0] != want {
+			t.Errorf("completion = %+v, want %+v first", got, want)
+		}
+	})
+
+	c.call("shutdown", nil, nil)
+	c.notify("exit", nil)
+	if err := <-c.done; err != nil {
+		t.Errorf("Serve = %v", err)
+	}
+}
+
+func TestIndexedOutline(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "base.zs"), []byte(base), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	baseURI := "file://" + filepath.ToSlash(filepath.Join(dir, "base.zs"))
+	c, err := cache.Open(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []lsp.FoldingRange{
+		{StartLine: 0, EndLine: 7, Kind: lsp.FoldRegion},
+		{StartLine: 3, EndLine: 6, Kind: lsp.FoldRegion},
+		{StartLine: 4, EndLine: 6, Kind: lsp.FoldRegion},
+	}
+	// Without a cache, then filling the cache, then restoring from it.
+	for i, cached := range []bool{false, true, true} {
+		s := lsp.NewServer()
+		if cached {
+			s.Cache = c
+		}
+		cl := startServer(t, s)
+		cl.call("initialize", map[string]any{"rootUri": "file://" + filepath.ToSlash(dir)}, nil)
+		var ranges []lsp.FoldingRange
+		cl.call("textDocument/foldingRange", lsp.FoldingRangeParams{TextDocument: lsp.TextDocumentIdentifier{URI: baseURI}}, &ranges)
+		if !reflect.DeepEqual(ranges, want) {
+			t.Errorf("session %d: folding ranges of an indexed file = %+v, want %+v", i, ranges, want)
+		}
+		var symbols []lsp.DocumentSymbol
+		cl.call("textDocument/documentSymbol", lsp.DocumentSymbolParams{TextDocument: lsp.TextDocumentIdentifier{URI: baseURI}}, &symbols)
+		if len(symbols) != 1 || symbols[0].Name != "Base" {
+			t.Errorf("session %d: symbols of an indexed file = %+v", i, symbols)
+		}
+		cl.call("shutdown", nil, nil)
+		cl.notify("exit", nil)
+		if err := <-cl.done; err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkInitialize measures the start of a session on a large
+// workspace, which indexes the symbol table and folding ranges of every
+// file: Parse parses them all, as a server without a cache does, and
+// Cached restores them from a warm cache, the ranges from the syntax of
+// each entry.
+func BenchmarkInitialize(b *testing.B) {
+	dir := b.TempDir()
+	paths, err := filepath.Glob("../testdata/bench/*.zs")
+	if err != nil || len(paths) == 0 {
+		b.Fatalf("no fixtures: %v", err)
+	}
+	// Copies that differ by a comment stand in for the hundreds of files
+	// of a big mod without sharing cache entries.
+	size := 0
+	for i := 0; i < 50; i++ {
+		for _, path := range paths {
+			source, err := os.ReadFile(path)
+			if err != nil {
+				b.Fatal(err)
+			}
+			source = append([]byte("// copy "+strconv.Itoa(i)+"\n"), source...)
+			name := filepath.Join(dir, strconv.Itoa(i)+"_"+filepath.Base(path))
+			if err := os.WriteFile(name, source, 0o644); err != nil {
+				b.Fatal(err)
+			}
+			size += len(source)
+		}
+	}
+	c, err := cache.Open(b.TempDir())
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	initialize := func(b *testing.B, s *lsp.Server) {
+		cl := startServer(b, s)
+		cl.call("initialize", map[string]any{"rootUri": "file://" + filepath.ToSlash(dir)}, nil)
+		cl.call("shutdown", nil, nil)
+		cl.notify("exit", nil)
+		if err := <-cl.done; err != nil {
+			b.Fatal(err)
+		}
+	}
+	b.Run("Parse", func(b *testing.B) {
+		b.SetBytes(int64(size))
+		for i := 0; i < b.N; i++ {
+			initialize(b, lsp.NewServer())
+		}
+	})
+	warm := lsp.NewServer()
+	warm.Cache = c
+	initialize(b, warm)
+	b.Run("Cached", func(b *testing.B) {
+		b.SetBytes(int64(size))
+		for i := 0; i < b.N; i++ {
+			s := lsp.NewServer()
+			s.Cache = c
+			initialize(b, s)
+		}
+	})
+}
@@ -0,0 +1,9 @@
+package lsp
+
+import "github.com/jlcrochet/tree-sitter-zscript/bindings/go/semantic"
+
+// semanticTokens classifies the identifiers of d against the index.
+func (s *Server) semanticTokens(d *document) SemanticTokens {
+	r, _ := s.resolver()
+	return SemanticTokens{Data: semantic.Encode(semantic.Tokens(r, d.tree), d.text, s.encoding())}
+}
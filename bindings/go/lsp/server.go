@@ -0,0 +1,494 @@
+// Package lsp implements a Language Server Protocol server for ZScript.
+//
+// The server keeps open documents parsed incrementally, publishes syntax errors as
+// diagnostics, together with the findings of the lint rules that the
+// .zscriptrc or zscript.toml file of the first workspace folder enables
+// (see package config), and answers document symbol, folding range, definition, type
+// definition, references, hover, semantic token, signature help, completion,
+// inlay hint and code action requests. Declarations are looked up in an index
+// of the open documents and of every ZScript file under the workspace folders,
+// and in the engine's built-in declarations.
+package lsp
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	zscript "github.com/jlcrochet/tree-sitter-zscript/bindings/go"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/cache"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/codeaction"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/config"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/internal/jsonrpc"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/lint"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/semantic"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/watch"
+)
+
+// Server is a language server. Use Serve to run it over a stream.
+type Server struct {
+	// Cache, if set before Serve is called, keeps the symbol tables of
+	// indexed workspace files between sessions.
+	Cache *cache.Cache
+	// ParseTimeout bounds the time spent parsing one file, so that a
+	// pathological file cannot hang the server. Zero means no limit. An
+	// open document whose parse times out keeps its previous tree until a
+	// later change parses in time; an indexed file is skipped.
+	ParseTimeout time.Duration
+
+	mu sync.Mutex
+	// docs are the documents open in the editor, by URI.
+	docs map[string]*document
+	// indexed are the workspace files, by URI. Open documents take
+	// precedence over them.
+	indexed map[string]*document
+	// utf8 is set when the client accepted UTF-8 positions; otherwise
+	// positions count UTF-16 code units.
+	utf8     bool
+	shutdown bool
+	conn     *jsonrpc.Conn
+	// config is the configuration of the first workspace folder, and
+	// linter the linter it describes, or nil.
+	config *config.Config
+	linter *lint.Linter
+}
+
+// DefaultParseTimeout is the ParseTimeout of a server returned by
+// NewServer.
+const DefaultParseTimeout = 5 * time.Second
+
+// NewServer returns a server with no open documents.
+func NewServer() *Server {
+	return &Server{docs: map[string]*document{}, indexed: map[string]*document{}, ParseTimeout: DefaultParseTimeout}
+}
+
+// parseContext returns the context for parsing one file.
+func (s *Server) parseContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if s.ParseTimeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, s.ParseTimeout)
+}
+
+// Serve runs the server, reading requests from r and writing responses to
+// w, until the client sends "exit" or r is exhausted.
+func (s *Server) Serve(ctx context.Context, r io.Reader, w io.Writer) error {
+	s.conn = jsonrpc.NewConn(r, w)
+	defer s.close()
+	return jsonrpc.Serve(ctx, s.conn, s.handle)
+}
+
+func (s *Server) close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, d := range s.docs {
+		d.close()
+	}
+	s.docs = map[string]*document{}
+}
+
+func (s *Server) handle(ctx context.Context, method string, params json.RawMessage) (any, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch method {
+	case "initialize":
+		var p InitializeParams
+		if err := unmarshal(params, &p); err != nil {
+			return nil, err
+		}
+		return s.initialize(ctx, &p), nil
+	case "initialized":
+		return nil, nil
+	case "shutdown":
+		s.shutdown = true
+		return nil, nil
+	case "exit":
+		return nil, jsonrpc.ErrStop
+	case "textDocument/didOpen":
+		var p DidOpenTextDocumentParams
+		if err := unmarshal(params, &p); err != nil {
+			return nil, err
+		}
+		parseCtx, cancel := s.parseContext(ctx)
+		defer cancel()
+		d, err := openDocument(parseCtx, p.TextDocument.URI, p.TextDocument.Version, []byte(p.TextDocument.Text), s.encoding())
+		if err != nil {
+			return nil, err
+		}
+		if old := s.docs[d.uri]; old != nil {
+			old.close()
+		}
+		s.docs[d.uri] = d
+		return nil, s.publishDiagnostics(d)
+	case "textDocument/didChange":
+		var p DidChangeTextDocumentParams
+		if err := unmarshal(params, &p); err != nil {
+			return nil, err
+		}
+		d, err := s.document(p.TextDocument.URI)
+		if err != nil {
+			return nil, err
+		}
+		if err := s.change(ctx, d, p.ContentChanges); err != nil {
+			return nil, err
+		}
+		d.version = p.TextDocument.Version
+		return nil, s.publishDiagnostics(d)
+	case "textDocument/didClose":
+		var p DidCloseTextDocumentParams
+		if err := unmarshal(params, &p); err != nil {
+			return nil, err
+		}
+		if d := s.docs[p.TextDocument.URI]; d != nil {
+			d.close()
+			delete(s.docs, p.TextDocument.URI)
+		}
+		return nil, s.conn.Notify("textDocument/publishDiagnostics", PublishDiagnosticsParams{
+			URI:         p.TextDocument.URI,
+			Diagnostics: []Diagnostic{},
+		})
+	case "textDocument/documentSymbol":
+		var p DocumentSymbolParams
+		if err := unmarshal(params, &p); err != nil {
+			return nil, err
+		}
+		d, err := s.outlineDocument(p.TextDocument.URI)
+		if err != nil {
+			return nil, err
+		}
+		return s.documentSymbols(d), nil
+	case "textDocument/foldingRange":
+		var p FoldingRangeParams
+		if err := unmarshal(params, &p); err != nil {
+			return nil, err
+		}
+		d, err := s.outlineDocument(p.TextDocument.URI)
+		if err != nil {
+			return nil, err
+		}
+		return foldingRanges(d), nil
+	case "textDocument/definition":
+		var p TextDocumentPositionParams
+		if err := unmarshal(params, &p); err != nil {
+			return nil, err
+		}
+		d, err := s.document(p.TextDocument.URI)
+		if err != nil {
+			return nil, err
+		}
+		return s.definition(d, p.Position), nil
+	case "textDocument/typeDefinition":
+		var p TextDocumentPositionParams
+		if err := unmarshal(params, &p); err != nil {
+			return nil, err
+		}
+		d, err := s.document(p.TextDocument.URI)
+		if err != nil {
+			return nil, err
+		}
+		return s.typeDefinition(d, p.Position), nil
+	case "textDocument/references":
+		var p ReferenceParams
+		if err := unmarshal(params, &p); err != nil {
+			return nil, err
+		}
+		d, err := s.document(p.TextDocument.URI)
+		if err != nil {
+			return nil, err
+		}
+		return s.references(ctx, d, p.Position, p.Context.IncludeDeclaration)
+	case "textDocument/semanticTokens/full":
+		var p SemanticTokensParams
+		if err := unmarshal(params, &p); err != nil {
+			return nil, err
+		}
+		d, err := s.document(p.TextDocument.URI)
+		if err != nil {
+			return nil, err
+		}
+		return s.semanticTokens(d), nil
+	case "textDocument/signatureHelp":
+		var p TextDocumentPositionParams
+		if err := unmarshal(params, &p); err != nil {
+			return nil, err
+		}
+		d, err := s.document(p.TextDocument.URI)
+		if err != nil {
+			return nil, err
+		}
+		if help := s.signatureHelp(d, p.Position); help != nil {
+			return help, nil
+		}
+		return nil, nil
+	case "textDocument/completion":
+		var p TextDocumentPositionParams
+		if err := unmarshal(params, &p); err != nil {
+			return nil, err
+		}
+		d, err := s.document(p.TextDocument.URI)
+		if err != nil {
+			return nil, err
+		}
+		return s.completion(d, p.Position), nil
+	case "textDocument/hover":
+		var p TextDocumentPositionParams
+		if err := unmarshal(params, &p); err != nil {
+			return nil, err
+		}
+		d, err := s.document(p.TextDocument.URI)
+		if err != nil {
+			return nil, err
+		}
+		if h := s.hover(ctx, d, p.Position); h != nil {
+			return h, nil
+		}
+		return nil, nil
+	case "textDocument/inlayHint":
+		var p InlayHintParams
+		if err := unmarshal(params, &p); err != nil {
+			return nil, err
+		}
+		d, err := s.document(p.TextDocument.URI)
+		if err != nil {
+			return nil, err
+		}
+		return s.inlayHints(ctx, d, p.Range), nil
+	case "textDocument/codeAction":
+		var p CodeActionParams
+		if err := unmarshal(params, &p); err != nil {
+			return nil, err
+		}
+		d, err := s.document(p.TextDocument.URI)
+		if err != nil {
+			return nil, err
+		}
+		return s.codeActions(ctx, d, p.Range, p.Context.Only), nil
+	}
+	if strings.HasPrefix(method, "$/") {
+		return nil, nil
+	}
+	return nil, jsonrpc.Errorf(jsonrpc.CodeMethodNotFound, "method not found: %s", method)
+}
+
+func unmarshal(params json.RawMessage, v any) error {
+	if err := json.Unmarshal(params, v); err != nil {
+		return jsonrpc.Errorf(jsonrpc.CodeInvalidParams, "invalid params: %v", err)
+	}
+	return nil
+}
+
+func (s *Server) document(uri string) (*document, error) {
+	if d := s.docs[uri]; d != nil {
+		return d, nil
+	}
+	return nil, jsonrpc.Errorf(jsonrpc.CodeInvalidParams, "document not open: %s", uri)
+}
+
+// outlineDocument is like document but also returns a workspace file
+// that is indexed without being open, for the requests its symbol table
+// and folding ranges answer.
+func (s *Server) outlineDocument(uri string) (*document, error) {
+	if d := s.indexed[uri]; d != nil && s.docs[uri] == nil {
+		return d, nil
+	}
+	return s.document(uri)
+}
+
+func (s *Server) initialize(ctx context.Context, p *InitializeParams) *InitializeResult {
+	for _, enc := range p.Capabilities.General.PositionEncodings {
+		if enc == "utf-8" {
+			s.utf8 = true
+		}
+	}
+	encoding := "utf-16"
+	if s.utf8 {
+		encoding = "utf-8"
+	}
+
+	roots := []string{}
+	for _, f := range p.WorkspaceFolders {
+		roots = append(roots, uriPath(f.URI))
+	}
+	if len(roots) == 0 && p.RootURI != "" {
+		roots = append(roots, uriPath(p.RootURI))
+	}
+	for _, root := range roots {
+		s.index(ctx, root)
+	}
+	s.configure(roots)
+
+	return &InitializeResult{
+		Capabilities: ServerCapabilities{
+			PositionEncoding:       encoding,
+			TextDocumentSync:       TextDocumentSyncOptions{OpenClose: true, Change: SyncIncremental},
+			DocumentSymbolProvider: true,
+			FoldingRangeProvider:   true,
+			DefinitionProvider:     true,
+			TypeDefinitionProvider: true,
+			ReferencesProvider:     true,
+			HoverProvider:          true,
+			InlayHintProvider:      true,
+			CodeActionProvider:     &CodeActionOptions{CodeActionKinds: []string{string(codeaction.QuickFix), string(codeaction.RefactorExtract), string(codeaction.Source)}},
+			SemanticTokensProvider: &SemanticTokensOptions{
+				Legend: SemanticTokensLegend{TokenTypes: semantic.TokenTypes, TokenModifiers: semantic.TokenModifiers},
+				Full:   true,
+			},
+			SignatureHelpProvider: &SignatureHelpOptions{TriggerCharacters: []string{"(", ","}},
+			CompletionProvider:    &CompletionOptions{TriggerCharacters: []string{".", ":", "+"}},
+		},
+		ServerInfo: ServerInfo{Name: "zscript-langserver"},
+	}
+}
+
+// configure reads the configuration of the first of roots, or keeps the
+// default one, telling the client if it is invalid.
+func (s *Server) configure(roots []string) {
+	dir := "."
+	if len(roots) > 0 {
+		dir = roots[0]
+	}
+	s.config, s.linter = config.Default(dir), nil
+	c, err := config.Find(dir)
+	var linter *lint.Linter
+	if err == nil {
+		linter, err = c.Linter()
+	}
+	if err != nil {
+		s.conn.Notify("window/showMessage", ShowMessageParams{Type: MessageError, Message: err.Error()})
+		return
+	}
+	s.config, s.linter = c, linter
+}
+
+// index adds every ZScript file under root to the symbol index. Files
+// that cannot be read or parsed are skipped.
+func (s *Server) index(ctx context.Context, root string) {
+	filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil || ctx.Err() != nil {
+			return nil
+		}
+		if entry.IsDir() {
+			if path != root && strings.HasPrefix(entry.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !watch.IsSource(path) {
+			return nil
+		}
+		text, err := os.ReadFile(path)
+		if err != nil {
+			return nil
+		}
+		abs, err := filepath.Abs(path)
+		if err != nil {
+			return nil
+		}
+		uri := pathURI(abs)
+		parseCtx, cancel := s.parseContext(ctx)
+		defer cancel()
+		if d, err := indexDocument(parseCtx, uri, text, s.Cache); err == nil {
+			s.indexed[uri] = d
+		}
+		return nil
+	})
+}
+
+func (s *Server) encoding() zscript.PositionEncoding {
+	if s.utf8 {
+		return zscript.EncodingUTF8
+	}
+	return zscript.EncodingUTF16
+}
+
+// change applies the changes of a didChange notification to d. A change
+// without a range replaces the whole text.
+func (s *Server) change(ctx context.Context, d *document, changes []TextDocumentContentChangeEvent) error {
+	for _, c := range changes {
+		if c.Range == nil {
+			// Replacing the text through an edit keeps it even if the
+			// reparse below times out, so that later changes apply to it.
+			d.inc.EditBytes(0, uint(len(d.inc.Source())), c.Text)
+			continue
+		}
+		start := zscript.TextPosition{Line: c.Range.Start.Line, Character: c.Range.Start.Character}
+		end := zscript.TextPosition{Line: c.Range.End.Line, Character: c.Range.End.Character}
+		if err := d.inc.EditRange(start, end, c.Text); err != nil {
+			return jsonrpc.Errorf(jsonrpc.CodeInvalidParams, "%v", err)
+		}
+	}
+	parseCtx, cancel := s.parseContext(ctx)
+	defer cancel()
+	if _, err := d.inc.Reparse(parseCtx); err != nil {
+		return err
+	}
+	d.refresh()
+	return nil
+}
+
+// dialect returns the dialect of the version the configuration targets.
+func (s *Server) dialect() zscript.Dialect {
+	if s.config == nil {
+		return zscript.Dialect{}
+	}
+	return s.config.Dialect()
+}
+
+// publishDiagnostics sends the syntax errors of d, and the findings of the
+// configured linter, to the client.
+func (s *Server) publishDiagnostics(d *document) error {
+	diagnostics := []Diagnostic{}
+	d.tree.Dialect = s.dialect()
+	for _, diag := range d.tree.Dialect.Diagnostics(d.tree) {
+		diagnostics = append(diagnostics, Diagnostic{
+			Range:    d.lspRange(diag.Range, s.utf8),
+			Severity: int(diag.Severity),
+			Source:   "zscript",
+			Message:  diag.Message,
+		})
+	}
+	if s.linter != nil {
+		for _, f := range s.linter.Trees(d.tree) {
+			diagnostics = append(diagnostics, Diagnostic{
+				Range:    d.lspRange(f.Range, s.utf8),
+				Severity: int(f.Severity),
+				Source:   "zscript-lint",
+				Code:     f.Rule,
+				Message:  f.Message,
+			})
+		}
+	}
+	return s.conn.Notify("textDocument/publishDiagnostics", PublishDiagnosticsParams{
+		URI:         d.uri,
+		Version:     d.version,
+		Diagnostics: diagnostics,
+	})
+}
+
+// documents returns every open and indexed document, open ones first,
+// each group sorted by URI.
+func (s *Server) documents() []*document {
+	var open, indexed []*document
+	for _, d := range s.docs {
+		open = append(open, d)
+	}
+	for uri, d := range s.indexed {
+		if s.docs[uri] == nil {
+			indexed = append(indexed, d)
+		}
+	}
+	byURI := func(docs []*document) {
+		sort.Slice(docs, func(i, j int) bool { return docs[i].uri < docs[j].uri })
+	}
+	byURI(open)
+	byURI(indexed)
+	return append(open, indexed...)
+}
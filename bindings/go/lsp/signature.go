@@ -0,0 +1,20 @@
+package lsp
+
+// signatureHelp returns the signatures of the call around pos in d, or nil
+// if pos is not inside the arguments of a call to a known method.
+func (s *Server) signatureHelp(d *document, pos Position) *SignatureHelp {
+	r, _ := s.resolver()
+	help, ok := r.SignatureHelp(d.tree, d.offset(pos, s.utf8))
+	if !ok {
+		return nil
+	}
+	result := &SignatureHelp{Signatures: []SignatureInformation{}, ActiveParameter: help.ActiveParameter}
+	for _, sig := range help.Signatures {
+		info := SignatureInformation{Label: sig.Label, Parameters: []ParameterInformation{}}
+		for _, p := range sig.Params {
+			info.Parameters = append(info.Parameters, ParameterInformation{Label: p})
+		}
+		result.Signatures = append(result.Signatures, info)
+	}
+	return result
+}
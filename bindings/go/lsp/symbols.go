@@ -0,0 +1,29 @@
+package lsp
+
+import (
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/symbols"
+)
+
+// documentSymbols returns the outline of d.
+func (s *Server) documentSymbols(d *document) []DocumentSymbol {
+	var convert func(items []symbols.OutlineItem) []DocumentSymbol
+	convert = func(items []symbols.OutlineItem) []DocumentSymbol {
+		var result []DocumentSymbol
+		for _, item := range items {
+			result = append(result, DocumentSymbol{
+				Name:           item.Name,
+				Detail:         item.Detail,
+				Kind:           item.Kind.SymbolKind(),
+				Range:          d.lspRange(item.Range, s.utf8),
+				SelectionRange: d.lspRange(item.SelectionRange, s.utf8),
+				Children:       convert(item.Children),
+			})
+		}
+		return result
+	}
+	result := convert(d.table.Outline())
+	if result == nil {
+		result = []DocumentSymbol{}
+	}
+	return result
+}
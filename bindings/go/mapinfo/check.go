@@ -0,0 +1,217 @@
+package mapinfo
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	zscript "github.com/jlcrochet/tree-sitter-zscript/bindings/go"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/hierarchy"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/lint"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/project"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/symbols"
+)
+
+// The rule names of the findings.
+const (
+	// RuleUndefinedClass reports a class that neither the project nor the
+	// engine defines.
+	RuleUndefinedClass = "undefined-class"
+	// RuleWrongBaseClass reports a class that does not descend from the
+	// class its key requires, such as a player class that is not a
+	// PlayerPawn.
+	RuleWrongBaseClass = "wrong-base-class"
+)
+
+// Options configures CheckWithOptions.
+type Options struct {
+	// Classes are classes defined outside the project, such as by another
+	// archive, that the lumps may name. The classes returned by
+	// EngineClasses are always known.
+	Classes []string
+}
+
+// engineParents maps the lowercased engine classes that MAPINFO commonly
+// names, and their ancestors, to their parents.
+var engineParents = map[string]string{
+	"thinker":            "Object",
+	"actor":              "Thinker",
+	"inventory":          "Actor",
+	"backpackitem":       "Inventory",
+	"backpack":           "BackpackItem",
+	"bagofholding":       "BackpackItem",
+	"ammosatchel":        "BackpackItem",
+	"playerpawn":         "Actor",
+	"doomplayer":         "PlayerPawn",
+	"hereticplayer":      "PlayerPawn",
+	"strifeplayer":       "PlayerPawn",
+	"fighterplayer":      "PlayerPawn",
+	"clericplayer":       "PlayerPawn",
+	"mageplayer":         "PlayerPawn",
+	"chexplayer":         "DoomPlayer",
+	"staticeventhandler": "Object",
+	"eventhandler":       "StaticEventHandler",
+	"statusbarcore":      "Object",
+	"basestatusbar":      "StatusBarCore",
+	"doomstatusbar":      "BaseStatusBar",
+	"hereticstatusbar":   "BaseStatusBar",
+	"hexenstatusbar":     "BaseStatusBar",
+	"strifestatusbar":    "BaseStatusBar",
+	"menu":               "Object",
+	"genericmenu":        "Menu",
+	"messageboxmenu":     "Menu",
+	"menudelegatebase":   "Object",
+	"object":             "",
+}
+
+// EngineClasses returns the engine classes Check knows, in lower case.
+func EngineClasses() []string {
+	names := make([]string, 0, len(engineParents))
+	for name := range engineParents {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// gameInfoKeys maps the lowercased GameInfo keys that name classes to the
+// class they must descend from.
+var gameInfoKeys = map[string]string{
+	"playerclasses":     "PlayerPawn",
+	"addeventhandlers":  "StaticEventHandler",
+	"eventhandlers":     "StaticEventHandler",
+	"statusbarclass":    "BaseStatusBar",
+	"backpacktype":      "Inventory",
+	"messageboxclass":   "MessageBoxMenu",
+	"menudelegateclass": "MenuDelegateBase",
+}
+
+// mapKeys is like gameInfoKeys for map definitions.
+var mapKeys = map[string]string{
+	"eventhandlers": "StaticEventHandler",
+}
+
+// Check reports the classes named by files that p does not define, or
+// that do not descend from the class their key requires. It checks the
+// DoomEdNums and SpawnNums blocks, the class keys of GameInfo, and the
+// event handlers of map definitions. Findings are in file order.
+func Check(p *project.Project, files ...*File) []lint.Finding {
+	return CheckWithOptions(p, Options{}, files...)
+}
+
+// CheckWithOptions is like Check but takes options.
+func CheckWithOptions(p *project.Project, opts Options, files ...*File) []lint.Finding {
+	trees := make([]*zscript.Tree, len(p.Files))
+	for i, f := range p.Files {
+		trees[i] = f.Tree
+	}
+	c := &checker{
+		hierarchy: hierarchy.Build(symbols.ExtractAll(0, trees...)...),
+		external:  map[string]bool{},
+	}
+	for _, name := range opts.Classes {
+		c.external[strings.ToLower(name)] = true
+	}
+	for _, f := range files {
+		c.file = f
+		for _, b := range f.Blocks {
+			switch {
+			case b.Name.Is("DoomEdNums"), b.Name.Is("SpawnNums"):
+				for _, e := range b.Entries {
+					if len(e.Values) > 0 && !e.Values[0].Is("none") && !strings.HasPrefix(e.Values[0].Text, "$") {
+						c.check(e.Values[0], "Actor", b.Name.Text)
+					}
+				}
+			case b.Name.Is("GameInfo"):
+				c.entries(b, gameInfoKeys)
+			case b.Name.Is("map"), b.Name.Is("DefaultMap"), b.Name.Is("AddDefaultMap"):
+				c.entries(b, mapKeys)
+			}
+		}
+	}
+	return c.findings
+}
+
+type checker struct {
+	hierarchy *hierarchy.Hierarchy
+	// external holds the lowercased names of Options.Classes.
+	external map[string]bool
+	file     *File
+	findings []lint.Finding
+}
+
+// entries checks the values of the entries of b whose keys are in keys.
+func (c *checker) entries(b *Block, keys map[string]string) {
+	for _, e := range b.Entries {
+		base, ok := keys[strings.ToLower(e.Key.Text)]
+		if !ok {
+			continue
+		}
+		for _, v := range e.Values {
+			if v.Text != "" {
+				c.check(v, base, e.Key.Text)
+			}
+		}
+	}
+}
+
+// check reports class unless it is known and descends from base.
+func (c *checker) check(class Token, base, key string) {
+	name := class.Text
+	if !c.defined(name) {
+		c.report(RuleUndefinedClass, class, "%s names class %q, which is not defined", key, name)
+		return
+	}
+	if ok, known := c.descends(name, base); known && !ok {
+		c.report(RuleWrongBaseClass, class, "%s names class %q, which does not inherit from %s", key, name, base)
+	}
+}
+
+func (c *checker) defined(name string) bool {
+	lower := strings.ToLower(name)
+	if _, ok := engineParents[lower]; ok || c.external[lower] {
+		return true
+	}
+	class := c.hierarchy.Class(name)
+	return class != nil && class.Defined()
+}
+
+// descends reports whether class is base or inherits from it, and whether
+// that is known: the ancestry of a class defined outside the project and
+// the engine is not.
+func (c *checker) descends(class, base string) (ok, known bool) {
+	seen := map[string]bool{}
+	for name := class; name != ""; {
+		lower := strings.ToLower(name)
+		if strings.EqualFold(name, base) {
+			return true, true
+		}
+		if seen[lower] {
+			return false, true
+		}
+		seen[lower] = true
+		if hc := c.hierarchy.Class(name); hc != nil && hc.Defined() {
+			name = "Object"
+			if hc.Parent != nil {
+				name = hc.Parent.Name
+			}
+			continue
+		}
+		parent, ok := engineParents[lower]
+		if !ok {
+			return false, false
+		}
+		name = parent
+	}
+	return false, true
+}
+
+func (c *checker) report(rule string, t Token, format string, args ...any) {
+	c.findings = append(c.findings, lint.Finding{
+		Rule:     rule,
+		Severity: zscript.SeverityError,
+		Path:     c.file.Path,
+		Range:    t.Range,
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
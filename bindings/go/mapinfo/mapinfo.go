@@ -0,0 +1,402 @@
+// Package mapinfo reads MAPINFO and ZMAPINFO lumps and checks the classes
+// they name against a ZScript project, and the editor numbers and spawn
+// IDs they assign against each other and the numbers the games use.
+//
+// The parser knows the shape of the format, not its keys: a lump is a list
+// of blocks, each a header such as "map MAP01 "Hangar"" or "DoomEdNums"
+// and an optional body in braces. A body holds entries of a key and,
+// after "=", comma-separated values, and may nest further blocks. An entry
+// ends at the end of its line unless the line ends with a comma. A header
+// without a body, as in the old MAPINFO format, ends at the end of its
+// line too.
+package mapinfo
+
+import (
+	"fmt"
+	"io/fs"
+	"path"
+	"strings"
+
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+)
+
+// File is a parsed lump.
+type File struct {
+	Path   string
+	Blocks []*Block
+}
+
+// Block is a top-level definition or a nested block.
+type Block struct {
+	// Name is the first word of the header, such as "map" or "gameinfo".
+	Name Token
+	// Args are the rest of the header, such as the lump and title of a
+	// map.
+	Args    []Token
+	Entries []*Entry
+	Blocks  []*Block
+	// HasBody reports whether the block has braces.
+	HasBody bool
+}
+
+// Entry is a key, such as "next" or "3001", and its values.
+type Entry struct {
+	Key    Token
+	Values []Token
+}
+
+// Token is a word, number or string of a lump.
+type Token struct {
+	// Text is the text of the token, without the quotes of a string.
+	Text   string
+	Quoted bool
+	Range  tree_sitter.Range
+}
+
+// Is reports whether the unquoted token is the word s, ignoring case.
+func (t Token) Is(s string) bool {
+	return !t.Quoted && strings.EqualFold(t.Text, s)
+}
+
+// Find returns the top-level blocks named name, ignoring case.
+func (f *File) Find(name string) []*Block {
+	var result []*Block
+	for _, b := range f.Blocks {
+		if b.Name.Is(name) {
+			result = append(result, b)
+		}
+	}
+	return result
+}
+
+// Entry returns the first entry of b whose key is key, ignoring case, or
+// nil.
+func (b *Block) Entry(key string) *Entry {
+	for _, e := range b.Entries {
+		if e.Key.Is(key) {
+			return e
+		}
+	}
+	return nil
+}
+
+// Error is a syntax error in a lump.
+type Error struct {
+	Path    string
+	Point   tree_sitter.Point
+	Message string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("mapinfo: %s:%d:%d: %s", e.Path, e.Point.Row+1, e.Point.Column+1, e.Message)
+}
+
+// Parse parses the lump source read from path.
+func Parse(path string, source []byte) (*File, error) {
+	p := &parser{lexer: lexer{path: path, src: source}}
+	if err := p.next(); err != nil {
+		return nil, err
+	}
+	f := &File{Path: path}
+	for p.tok.kind != tokEOF {
+		if p.tok.kind != tokWord {
+			return nil, p.errorf("unexpected %q", p.tok.Text)
+		}
+		b, err := p.block()
+		if err != nil {
+			return nil, err
+		}
+		f.Blocks = append(f.Blocks, b)
+	}
+	return f, nil
+}
+
+// lumpNames are the names of the lumps Load reads, most preferred first;
+// GZDoom ignores MAPINFO when ZMAPINFO is present.
+var lumpNames = []string{"zmapinfo", "mapinfo"}
+
+// Load reads the MAPINFO lump at the root of fsys, preferring ZMAPINFO
+// to MAPINFO, and the files it includes. The lump may have an extension,
+// as in "zmapinfo.txt". It returns no files if there is no lump.
+func Load(fsys fs.FS) ([]*File, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+	for _, want := range lumpNames {
+		for _, e := range entries {
+			name := strings.ToLower(e.Name())
+			if e.IsDir() || strings.TrimSuffix(name, path.Ext(name)) != want {
+				continue
+			}
+			return LoadFile(fsys, e.Name())
+		}
+	}
+	return nil, nil
+}
+
+// LoadFile reads the lump name from fsys and, in order, the files it
+// includes. Included paths are relative to the root of fsys.
+func LoadFile(fsys fs.FS, name string) ([]*File, error) {
+	var files []*File
+	seen := map[string]bool{}
+	var load func(name string) error
+	load = func(name string) error {
+		key := strings.ToLower(path.Clean(name))
+		if seen[key] {
+			return nil
+		}
+		seen[key] = true
+		source, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return err
+		}
+		f, err := Parse(name, source)
+		if err != nil {
+			return err
+		}
+		files = append(files, f)
+		for _, b := range f.Find("include") {
+			if len(b.Args) == 1 {
+				if err := load(strings.TrimPrefix(b.Args[0].Text, "/")); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+	if err := load(name); err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokWord
+	tokString
+	tokPunct // one of { } = ,
+)
+
+type token struct {
+	Token
+	kind tokenKind
+	// newline reports whether a line break precedes the token.
+	newline bool
+}
+
+type lexer struct {
+	path   string
+	src    []byte
+	offset int
+	point  tree_sitter.Point
+}
+
+func (l *lexer) advance() {
+	if l.src[l.offset] == '\n' {
+		l.point.Row++
+		l.point.Column = 0
+	} else {
+		l.point.Column++
+	}
+	l.offset++
+}
+
+func (l *lexer) errorAt(p tree_sitter.Point, format string, args ...any) error {
+	return &Error{Path: l.path, Point: p, Message: fmt.Sprintf(format, args...)}
+}
+
+// scan returns the next token.
+func (l *lexer) scan() (token, error) {
+	var t token
+	// Skip whitespace and comments.
+skip:
+	for l.offset < len(l.src) {
+		c := l.src[l.offset]
+		rest := l.src[l.offset:]
+		switch {
+		case c == '\n':
+			t.newline = true
+			l.advance()
+		case c == ' ' || c == '\t' || c == '\r' || c == '\f' || c == '\v':
+			l.advance()
+		case c == ';' || len(rest) > 1 && c == '/' && rest[1] == '/':
+			// Old MAPINFO comments start with a semicolon.
+			for l.offset < len(l.src) && l.src[l.offset] != '\n' {
+				l.advance()
+			}
+		case len(rest) > 1 && c == '/' && rest[1] == '*':
+			start := l.point
+			l.advance()
+			l.advance()
+			for l.offset < len(l.src) && !(l.src[l.offset] == '*' && l.offset+1 < len(l.src) && l.src[l.offset+1] == '/') {
+				if l.src[l.offset] == '\n' {
+					t.newline = true
+				}
+				l.advance()
+			}
+			if l.offset >= len(l.src) {
+				return t, l.errorAt(start, "unterminated comment")
+			}
+			l.advance()
+			l.advance()
+		default:
+			break skip
+		}
+	}
+	t.Range.StartByte, t.Range.StartPoint = uint(l.offset), l.point
+	if l.offset >= len(l.src) {
+		t.Range.EndByte, t.Range.EndPoint = t.Range.StartByte, t.Range.StartPoint
+		return t, nil
+	}
+	switch c := l.src[l.offset]; {
+	case c == '"':
+		t.kind, t.Quoted = tokString, true
+		l.advance()
+		var text strings.Builder
+		for {
+			if l.offset >= len(l.src) {
+				return t, l.errorAt(t.Range.StartPoint, "unterminated string")
+			}
+			c := l.src[l.offset]
+			if c == '"' {
+				l.advance()
+				break
+			}
+			if c == '\\' && l.offset+1 < len(l.src) {
+				l.advance()
+				c = l.src[l.offset]
+			}
+			text.WriteByte(c)
+			l.advance()
+		}
+		t.Text = text.String()
+	case c == '{' || c == '}' || c == '=' || c == ',':
+		t.kind, t.Text = tokPunct, string(c)
+		l.advance()
+	default:
+		t.kind = tokWord
+		start := l.offset
+		for l.offset < len(l.src) && !isDelimiter(l.src[l.offset]) {
+			l.advance()
+		}
+		t.Text = string(l.src[start:l.offset])
+	}
+	t.Range.EndByte, t.Range.EndPoint = uint(l.offset), l.point
+	return t, nil
+}
+
+func isDelimiter(c byte) bool {
+	switch c {
+	case ' ', '\t', '\r', '\n', '\f', '\v', '"', '{', '}', '=', ',', ';':
+		return true
+	}
+	return false
+}
+
+type parser struct {
+	lexer
+	tok token
+}
+
+func (p *parser) next() (err error) {
+	p.tok, err = p.scan()
+	return err
+}
+
+func (p *parser) errorf(format string, args ...any) error {
+	return p.errorAt(p.tok.Range.StartPoint, format, args...)
+}
+
+func (p *parser) is(punct string) bool {
+	return p.tok.kind == tokPunct && p.tok.Text == punct
+}
+
+// header reads the words and strings of a line, stopping before a brace.
+func (p *parser) header() ([]Token, error) {
+	var tokens []Token
+	for {
+		tokens = append(tokens, p.tok.Token)
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		if p.tok.newline || p.tok.kind == tokEOF || p.tok.kind == tokPunct {
+			return tokens, nil
+		}
+	}
+}
+
+// block reads a block whose first word is the current token.
+func (p *parser) block() (*Block, error) {
+	header, err := p.header()
+	if err != nil {
+		return nil, err
+	}
+	b := &Block{Name: header[0], Args: header[1:]}
+	if !p.is("{") {
+		return b, nil
+	}
+	return b, p.body(b)
+}
+
+// body reads the members of b up to and including its closing brace; the
+// current token is the opening one.
+func (p *parser) body(b *Block) error {
+	b.HasBody = true
+	start := p.tok.Range.StartPoint
+	if err := p.next(); err != nil {
+		return err
+	}
+	for !p.is("}") {
+		switch {
+		case p.tok.kind == tokEOF:
+			return p.errorAt(start, "unclosed {")
+		case p.tok.kind == tokPunct:
+			return p.errorf("unexpected %q", p.tok.Text)
+		}
+		if err := p.member(b); err != nil {
+			return err
+		}
+	}
+	return p.next()
+}
+
+// member reads an entry or a nested block into b.
+func (p *parser) member(b *Block) error {
+	header, err := p.header()
+	if err != nil {
+		return err
+	}
+	if p.is("{") {
+		nested := &Block{Name: header[0], Args: header[1:]}
+		b.Blocks = append(b.Blocks, nested)
+		return p.body(nested)
+	}
+	// Values follow the key, or the "=" after it. Words after the key
+	// without an "=", as in "sky1 SKY1 0", are values too.
+	e := &Entry{Key: header[0], Values: header[1:]}
+	b.Entries = append(b.Entries, e)
+	if !p.is("=") {
+		return nil
+	}
+	if err := p.next(); err != nil {
+		return err
+	}
+	for {
+		if p.tok.kind != tokWord && p.tok.kind != tokString {
+			return p.errorf("missing value for %q", e.Key.Text)
+		}
+		e.Values = append(e.Values, p.tok.Token)
+		if err := p.next(); err != nil {
+			return err
+		}
+		if !p.is(",") {
+			return nil
+		}
+		if err := p.next(); err != nil {
+			return err
+		}
+	}
+}
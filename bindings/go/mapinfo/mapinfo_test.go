@@ -0,0 +1,211 @@
+package mapinfo_test
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/mapinfo"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/project"
+)
+
+const zmapinfo = `// Game settings.
+GameInfo
+{
+	PlayerClasses = "MarinePlayer", "GhostPlayer"
+	AddEventHandlers = "ScoreHandler",
+		"MissingHandler", "Imp"
+	StatusBarClass = "DoomStatusBar"
+}
+
+DoomEdNums
+{
+	3001 = Imp
+	3002 = Demon, 1, 2 /* args */
+	9300 = "$PolyAnchor"
+	20000 = none
+}
+
+map MAP01 "Hangar"
+{
+	next = "MAP02"
+	EventHandlers = "MapHandler"
+	sky1 = "SKY1", 0
+}
+
+include "mapinfo/episodes.txt"
+`
+
+const episodes = `clearepisodes
+episode MAP01
+{
+	name = "Knee-Deep in ZScript"
+	key = "k"
+}
+`
+
+const source = `class MarinePlayer : DoomPlayer {}
+class GhostPlayer : Actor {}
+class ScoreHandler : EventHandler {}
+class MapHandler : StaticEventHandler {}
+class Imp : Actor {}
+`
+
+func TestParse(t *testing.T) {
+	files, err := mapinfo.LoadFile(fstest.MapFS{
+		"zmapinfo.txt":         {Data: []byte(zmapinfo)},
+		"mapinfo/episodes.txt": {Data: []byte(episodes)},
+	}, "zmapinfo.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, f := range files {
+		for _, b := range f.Blocks {
+			line := f.Path + ": " + b.Name.Text
+			for _, a := range b.Args {
+				line += " " + a.Text
+			}
+			got = append(got, line)
+			for _, e := range b.Entries {
+				var values []string
+				for _, v := range e.Values {
+					values = append(values, v.Text)
+				}
+				got = append(got, fmt.Sprintf("  %s = %s", e.Key.Text, strings.Join(values, ", ")))
+			}
+		}
+	}
+	want := []string{
+		"zmapinfo.txt: GameInfo",
+		"  PlayerClasses = MarinePlayer, GhostPlayer",
+		"  AddEventHandlers = ScoreHandler, MissingHandler, Imp",
+		"  StatusBarClass = DoomStatusBar",
+		"zmapinfo.txt: DoomEdNums",
+		"  3001 = Imp",
+		"  3002 = Demon, 1, 2",
+		"  9300 = $PolyAnchor",
+		"  20000 = none",
+		"zmapinfo.txt: map MAP01 Hangar",
+		"  next = MAP02",
+		"  EventHandlers = MapHandler",
+		"  sky1 = SKY1, 0",
+		"zmapinfo.txt: include mapinfo/episodes.txt",
+		"mapinfo/episodes.txt: clearepisodes",
+		"mapinfo/episodes.txt: episode MAP01",
+		"  name = Knee-Deep in ZScript",
+		"  key = k",
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("got:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct{ source, want string }{
+		{"map MAP01 {\n\tnext = \"MAP02\n}", `mapinfo: zmapinfo:2:9: unterminated string`},
+		{"GameInfo {\n\tnext =\n}", `mapinfo: zmapinfo:3:1: missing value for "next"`},
+		{"GameInfo {\n", `mapinfo: zmapinfo:1:10: unclosed {`},
+		{"}", `mapinfo: zmapinfo:1:1: unexpected "}"`},
+		{"/* comment", `mapinfo: zmapinfo:1:1: unterminated comment`},
+	}
+	for _, test := range tests {
+		_, err := mapinfo.Parse("zmapinfo", []byte(test.source))
+		if err == nil || err.Error() != test.want {
+			t.Errorf("Parse(%q) = %v, want %s", test.source, err, test.want)
+		}
+	}
+}
+
+func TestCheck(t *testing.T) {
+	fsys := fstest.MapFS{
+		"zscript.zs":           {Data: []byte(source)},
+		"ZMAPINFO":             {Data: []byte(zmapinfo)},
+		"MAPINFO":              {Data: []byte("map MAP01 \"Ignored\"\n")},
+		"mapinfo/episodes.txt": {Data: []byte(episodes)},
+	}
+	p, err := project.Load(context.Background(), fsys, "zscript.zs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+	files, err := mapinfo.Load(fsys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 2 || files[0].Path != "ZMAPINFO" {
+		t.Fatalf("Load read %d files", len(files))
+	}
+
+	var got []string
+	for _, f := range mapinfo.CheckWithOptions(p, mapinfo.Options{Classes: []string{"Demon"}}, files...) {
+		got = append(got, f.String())
+	}
+	want := []string{
+		`ZMAPINFO:4:34: error: PlayerClasses names class "GhostPlayer", which does not inherit from PlayerPawn (wrong-base-class)`,
+		`ZMAPINFO:6:3: error: AddEventHandlers names class "MissingHandler", which is not defined (undefined-class)`,
+		`ZMAPINFO:6:21: error: AddEventHandlers names class "Imp", which does not inherit from StaticEventHandler (wrong-base-class)`,
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("got:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	if got := mapinfo.Check(p, files...); len(got) != 4 || got[3].Rule != mapinfo.RuleUndefinedClass {
+		t.Errorf("without Demon defined, Check found %v", got)
+	}
+}
+
+func TestCheckNumbers(t *testing.T) {
+	fsys := fstest.MapFS{
+		"zscript.zs": {Data: []byte(`class MyImp : Actor replaces DoomImp {}
+class Ball : Actor {}
+class Spark : Actor {}
+class Bolt : Actor {}
+`)},
+		"mapinfo.txt": {Data: []byte(`DoomEdNums
+{
+	3001 = MyImp
+	3002 = Ball
+	20000 = Spark
+	20000 = Bolt
+	20001 = Bolt
+	20001 = bolt
+	9300 = "$PolyAnchor"
+}
+SpawnNums
+{
+	12 = Spark
+	300 = Bolt
+}
+`)},
+	}
+	p, err := project.Load(context.Background(), fsys, "zscript.zs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+	files, err := mapinfo.Load(fsys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	numbers := mapinfo.Numbers(files...)
+	if len(numbers) != 8 || numbers[6].Kind != mapinfo.SpawnID || numbers[6].Value != 12 {
+		t.Fatalf("Numbers() = %v", numbers)
+	}
+
+	var got []string
+	for _, f := range mapinfo.CheckNumbers(p, numbers) {
+		got = append(got, f.String())
+	}
+	want := []string{
+		`mapinfo.txt:4:2: warning: editor number 3002 of Ball is in the range 1-4999 used by the Doom, Heretic, Hexen and Strife things (reserved-number)`,
+		`mapinfo.txt:6:2: error: editor number 20000 of Bolt is already assigned to Spark at mapinfo.txt:5:2 (duplicate-number)`,
+		`mapinfo.txt:13:2: warning: spawn ID 12 of Spark is in the range 1-255 used by the engine's spawn IDs (reserved-number)`,
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("got:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
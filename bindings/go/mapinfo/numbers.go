@@ -0,0 +1,153 @@
+package mapinfo
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+
+	zscript "github.com/jlcrochet/tree-sitter-zscript/bindings/go"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/hierarchy"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/lint"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/project"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/symbols"
+)
+
+// The rule names of the findings of CheckNumbers.
+const (
+	// RuleDuplicateNumber reports an editor number or spawn ID assigned
+	// to two classes; the later assignment wins, so maps that place the
+	// first class get the second.
+	RuleDuplicateNumber = "duplicate-number"
+	// RuleReservedNumber reports a number in a range the games or the
+	// engine use, given to a class that does not replace an engine class.
+	RuleReservedNumber = "reserved-number"
+)
+
+// NumberKind is the kind of a number a class is given.
+type NumberKind int
+
+const (
+	// EditorNumber is a DoomEdNums number, which maps place things by.
+	EditorNumber NumberKind = iota
+	// SpawnID is a SpawnNums number, which scripts spawn things by.
+	SpawnID
+)
+
+func (k NumberKind) String() string {
+	if k == SpawnID {
+		return "spawn ID"
+	}
+	return "editor number"
+}
+
+// Number is an editor number or spawn ID assigned to a class.
+type Number struct {
+	Kind  NumberKind
+	Value int
+	Class string
+	Path  string
+	// Range is the range of the number.
+	Range tree_sitter.Range
+}
+
+// Reserved is a range of numbers used by the games or the engine.
+type Reserved struct {
+	Kind     NumberKind
+	Min, Max int
+	// Owner names the users of the range.
+	Owner string
+}
+
+// ReservedRanges are the ranges CheckNumbers reports. Maps made for a
+// game place its things, and GZDoom's own things, by these numbers, so a
+// mod's class given one of them appears in every such map.
+var ReservedRanges = []Reserved{
+	{EditorNumber, 1, 4999, "the Doom, Heretic, Hexen and Strife things"},
+	{EditorNumber, 5000, 5999, "the ZDoom additions"},
+	{EditorNumber, 8000, 8999, "the Hexen things"},
+	{EditorNumber, 9000, 9999, "the GZDoom map spots, sectors and specials"},
+	{EditorNumber, 10000, 10999, "the Hexen and Strife things"},
+	{EditorNumber, 14001, 14999, "the GZDoom sound things"},
+	{EditorNumber, 32000, 32767, "the GZDoom editor and internal things"},
+	{SpawnID, 1, 255, "the engine's spawn IDs"},
+}
+
+// Numbers returns the editor numbers and spawn IDs that the DoomEdNums and
+// SpawnNums blocks of files assign, in file order. Entries that remove a
+// number with "none" or assign one to a special such as "$PolyAnchor" are
+// left out.
+func Numbers(files ...*File) []Number {
+	var numbers []Number
+	for _, f := range files {
+		for _, b := range f.Blocks {
+			kind := EditorNumber
+			switch {
+			case b.Name.Is("DoomEdNums"):
+			case b.Name.Is("SpawnNums"):
+				kind = SpawnID
+			default:
+				continue
+			}
+			for _, e := range b.Entries {
+				n, err := strconv.Atoi(e.Key.Text)
+				if err != nil || len(e.Values) == 0 || e.Values[0].Is("none") || strings.HasPrefix(e.Values[0].Text, "$") {
+					continue
+				}
+				numbers = append(numbers, Number{kind, n, e.Values[0].Text, f.Path, e.Key.Range})
+			}
+		}
+	}
+	return numbers
+}
+
+// CheckNumbers reports the numbers assigned to two different classes and
+// those in ReservedRanges, unless the class replaces another, as a class
+// meant to take the place of a game's thing does. Findings are in the
+// order of numbers.
+func CheckNumbers(p *project.Project, numbers []Number) []lint.Finding {
+	trees := make([]*zscript.Tree, len(p.Files))
+	for i, f := range p.Files {
+		trees[i] = f.Tree
+	}
+	h := hierarchy.Build(symbols.ExtractAll(0, trees...)...)
+
+	type key struct {
+		kind  NumberKind
+		value int
+	}
+	first := map[key]Number{}
+	var findings []lint.Finding
+	report := func(n Number, rule string, severity zscript.Severity, format string, args ...any) {
+		findings = append(findings, lint.Finding{
+			Rule:     rule,
+			Severity: severity,
+			Path:     n.Path,
+			Range:    n.Range,
+			Message:  fmt.Sprintf(format, args...),
+		})
+	}
+	for _, n := range numbers {
+		k := key{n.Kind, n.Value}
+		if prev, ok := first[k]; !ok {
+			first[k] = n
+		} else if !strings.EqualFold(prev.Class, n.Class) {
+			p := prev.Range.StartPoint
+			report(n, RuleDuplicateNumber, zscript.SeverityError, "%s %d of %s is already assigned to %s at %s:%d:%d",
+				n.Kind, n.Value, n.Class, prev.Class, prev.Path, p.Row+1, p.Column+1)
+			continue
+		}
+		if c := h.Class(n.Class); c != nil && c.Replaces != nil {
+			continue
+		}
+		for _, r := range ReservedRanges {
+			if r.Kind == n.Kind && r.Min <= n.Value && n.Value <= r.Max {
+				report(n, RuleReservedNumber, zscript.SeverityWarning, "%s %d of %s is in the range %d-%d used by %s",
+					n.Kind, n.Value, n.Class, r.Min, r.Max, r.Owner)
+				break
+			}
+		}
+	}
+	return findings
+}
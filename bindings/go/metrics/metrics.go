@@ -0,0 +1,362 @@
+// Package metrics measures ZScript source: the cyclomatic complexity,
+// nesting depth and length of each method, the member counts of each class
+// and the line counts of each file.
+//
+// A Report collects the measurements of a set of files and can be written
+// as JSON or as a text table. Limits turns a report into a quality gate.
+package metrics
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+
+	zscript "github.com/jlcrochet/tree-sitter-zscript/bindings/go"
+)
+
+// Lines counts the lines of a span of source. A line with code and a
+// comment counts as code.
+type Lines struct {
+	Total   int `json:"total"`
+	Code    int `json:"code"`
+	Comment int `json:"comment"`
+	Blank   int `json:"blank"`
+}
+
+// Function holds the measurements of a method with a body.
+type Function struct {
+	// Class is the class or struct that declares the method.
+	Class string `json:"class"`
+	Name  string `json:"name"`
+	Path  string `json:"path"`
+	// Line is the 1-based line the method starts on.
+	Line  int   `json:"line"`
+	Lines Lines `json:"lines"`
+	// Complexity is the cyclomatic complexity: one plus the number of
+	// if, loop, case, ?: and short-circuit && and || decisions.
+	Complexity int `json:"complexity"`
+	// Nesting is the deepest nesting of if, loop and switch statements;
+	// else if chains do not nest.
+	Nesting    int `json:"nesting"`
+	Parameters int `json:"parameters"`
+}
+
+// String returns the qualified name, such as "Imp.Tick".
+func (f *Function) String() string {
+	return f.Class + "." + f.Name
+}
+
+// Class holds the member counts of a class or struct. Extensions are
+// counted separately.
+type Class struct {
+	Name    string `json:"name"`
+	Path    string `json:"path"`
+	Line    int    `json:"line"`
+	Struct  bool   `json:"struct,omitempty"`
+	Methods int    `json:"methods"`
+	Fields  int    `json:"fields"`
+	// Complexity is the sum of the complexities of the methods.
+	Complexity int   `json:"complexity"`
+	Lines      Lines `json:"lines"`
+}
+
+// File holds the line counts of a file.
+type File struct {
+	Path  string `json:"path"`
+	Lines Lines  `json:"lines"`
+}
+
+// Report holds the measurements of a set of files, in the order they were
+// added.
+type Report struct {
+	Files     []*File     `json:"files"`
+	Classes   []*Class    `json:"classes"`
+	Functions []*Function `json:"functions"`
+}
+
+// Compute measures the given files.
+func Compute(trees ...*zscript.Tree) *Report {
+	r := &Report{}
+	for _, tree := range trees {
+		r.Add(tree)
+	}
+	return r
+}
+
+// Add measures tree and adds it to the report.
+func (r *Report) Add(tree *zscript.Tree) {
+	m := newMeasurer(tree)
+	r.Files = append(r.Files, &File{Path: tree.Path, Lines: m.count(0, m.rows)})
+
+	var v zscript.Visitor
+	var class *Class
+	visitType := func(node *tree_sitter.Node) zscript.WalkAction {
+		name := node.ChildByFieldName(zscript.FieldName)
+		if name == nil {
+			return zscript.WalkSkipChildren
+		}
+		class = &Class{
+			Name:   name.Utf8Text(tree.Source),
+			Path:   tree.Path,
+			Line:   int(node.StartPosition().Row) + 1,
+			Struct: node.Kind() == zscript.NodeStructDefinition,
+			Lines:  m.span(node),
+		}
+		r.Classes = append(r.Classes, class)
+		return zscript.WalkContinue
+	}
+	v.On(zscript.NodeClassDefinition, visitType)
+	v.On(zscript.NodeStructDefinition, visitType)
+	v.On(zscript.NodeFieldDeclaration, func(node *tree_sitter.Node) zscript.WalkAction {
+		if class != nil {
+			class.Fields += len(childrenByField(node, zscript.FieldDeclarator))
+		}
+		return zscript.WalkSkipChildren
+	})
+	v.On(zscript.NodeMethodDefinition, func(node *tree_sitter.Node) zscript.WalkAction {
+		if class == nil {
+			return zscript.WalkSkipChildren
+		}
+		class.Methods++
+		body := node.ChildByFieldName(zscript.FieldBody)
+		name := node.ChildByFieldName(zscript.FieldName)
+		if body == nil || name == nil {
+			return zscript.WalkSkipChildren
+		}
+		f := &Function{
+			Class:      class.Name,
+			Name:       name.Utf8Text(tree.Source),
+			Path:       tree.Path,
+			Line:       int(node.StartPosition().Row) + 1,
+			Lines:      m.span(node),
+			Complexity: 1 + decisions(body, tree.Source),
+			Nesting:    nesting(body),
+		}
+		if params := node.ChildByFieldName(zscript.FieldParameters); params != nil {
+			f.Parameters = int(params.NamedChildCount())
+		}
+		class.Complexity += f.Complexity
+		r.Functions = append(r.Functions, f)
+		return zscript.WalkSkipChildren
+	})
+	v.Leave = func(node *tree_sitter.Node) {
+		if k := node.Kind(); k == zscript.NodeClassDefinition || k == zscript.NodeStructDefinition {
+			class = nil
+		}
+	}
+	zscript.Walk(tree.RootNode(), &v)
+}
+
+// decisions counts the decision points under node.
+func decisions(node *tree_sitter.Node, source []byte) int {
+	n := 0
+	var v zscript.Visitor
+	v.Enter = func(node *tree_sitter.Node) zscript.WalkAction {
+		switch node.Kind() {
+		case zscript.NodeIfStatement, zscript.NodeWhileStatement, zscript.NodeDoStatement,
+			zscript.NodeForStatement, zscript.NodeForeachStatement, zscript.NodeConditionalExpression:
+			n++
+		case zscript.NodeCaseStatement:
+			if node.ChildByFieldName(zscript.FieldValue) != nil {
+				n++
+			}
+		case zscript.NodeBinaryExpression:
+			if op := node.ChildByFieldName(zscript.FieldOperator); op != nil {
+				if text := op.Utf8Text(source); text == "&&" || text == "||" {
+					n++
+				}
+			}
+		}
+		return zscript.WalkContinue
+	}
+	zscript.Walk(node, &v)
+	return n
+}
+
+// nesting returns the nesting depth of control statements under node.
+func nesting(node *tree_sitter.Node) int {
+	depth, deepest := 0, 0
+	nests := func(n *tree_sitter.Node) bool {
+		switch n.Kind() {
+		case zscript.NodeIfStatement:
+			parent := n.Parent()
+			return parent == nil || parent.Kind() != zscript.NodeElseClause
+		case zscript.NodeWhileStatement, zscript.NodeDoStatement, zscript.NodeForStatement,
+			zscript.NodeForeachStatement, zscript.NodeSwitchStatement:
+			return true
+		}
+		return false
+	}
+	var v zscript.Visitor
+	v.Enter = func(n *tree_sitter.Node) zscript.WalkAction {
+		if nests(n) {
+			depth++
+			deepest = max(deepest, depth)
+		}
+		return zscript.WalkContinue
+	}
+	v.Leave = func(n *tree_sitter.Node) {
+		if nests(n) {
+			depth--
+		}
+	}
+	zscript.Walk(node, &v)
+	return deepest
+}
+
+// measurer classifies the lines of a file.
+type measurer struct {
+	rows    int
+	code    []bool
+	comment []bool
+}
+
+func newMeasurer(tree *zscript.Tree) *measurer {
+	rows := strings.Count(string(tree.Source), "\n")
+	if len(tree.Source) > 0 && tree.Source[len(tree.Source)-1] != '\n' {
+		rows++
+	}
+	m := &measurer{rows: rows, code: make([]bool, rows+1), comment: make([]bool, rows+1)}
+	var v zscript.Visitor
+	v.Enter = func(node *tree_sitter.Node) zscript.WalkAction {
+		var lines []bool
+		switch {
+		case node.Kind() == zscript.NodeComment:
+			lines = m.comment
+		case node.ChildCount() == 0 && node.EndByte() > node.StartByte():
+			lines = m.code
+		default:
+			return zscript.WalkContinue
+		}
+		for row := node.StartPosition().Row; row <= node.EndPosition().Row && int(row) < len(lines); row++ {
+			lines[row] = true
+		}
+		return zscript.WalkSkipChildren
+	}
+	zscript.Walk(tree.RootNode(), &v)
+	return m
+}
+
+// count classifies the rows from start up to but not including end.
+func (m *measurer) count(start, end int) Lines {
+	var l Lines
+	for row := start; row < end; row++ {
+		l.Total++
+		switch {
+		case m.code[row]:
+			l.Code++
+		case m.comment[row]:
+			l.Comment++
+		default:
+			l.Blank++
+		}
+	}
+	return l
+}
+
+func (m *measurer) span(node *tree_sitter.Node) Lines {
+	return m.count(int(node.StartPosition().Row), int(node.EndPosition().Row)+1)
+}
+
+func childrenByField(node *tree_sitter.Node, field string) []tree_sitter.Node {
+	cursor := node.Walk()
+	defer cursor.Close()
+	return node.ChildrenByFieldName(field, cursor)
+}
+
+// Limits are the thresholds of a quality gate. A zero limit is not
+// checked.
+type Limits struct {
+	Complexity int
+	Nesting    int
+	// Lines limits the code lines of a method.
+	Lines int
+	// Methods limits the methods of a class.
+	Methods int
+}
+
+// Violation is a measurement above a limit.
+type Violation struct {
+	Path string
+	Line int
+	// Name is the qualified method name or the class name.
+	Name string
+	// Metric is "complexity", "nesting", "lines" or "methods".
+	Metric string
+	Value  int
+	Limit  int
+}
+
+func (v Violation) String() string {
+	return fmt.Sprintf("%s:%d: %s has %s %d, over the limit of %d", v.Path, v.Line, v.Name, v.Metric, v.Value, v.Limit)
+}
+
+// Check returns the measurements of r above limits, in report order.
+func (r *Report) Check(limits Limits) []Violation {
+	var result []Violation
+	check := func(path string, line int, name, metric string, value, limit int) {
+		if limit > 0 && value > limit {
+			result = append(result, Violation{Path: path, Line: line, Name: name, Metric: metric, Value: value, Limit: limit})
+		}
+	}
+	for _, c := range r.Classes {
+		check(c.Path, c.Line, c.Name, "methods", c.Methods, limits.Methods)
+	}
+	for _, f := range r.Functions {
+		check(f.Path, f.Line, f.String(), "complexity", f.Complexity, limits.Complexity)
+		check(f.Path, f.Line, f.String(), "nesting", f.Nesting, limits.Nesting)
+		check(f.Path, f.Line, f.String(), "lines", f.Lines.Code, limits.Lines)
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		a, b := result[i], result[j]
+		if a.Path != b.Path {
+			return a.Path < b.Path
+		}
+		return a.Line < b.Line
+	})
+	return result
+}
+
+// WriteJSON writes r to w as indented JSON.
+func (r *Report) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+// WriteText writes r to w as tables of methods, sorted by decreasing
+// complexity, and of classes, followed by the line totals.
+func (r *Report) WriteText(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+
+	functions := append([]*Function(nil), r.Functions...)
+	sort.SliceStable(functions, func(i, j int) bool { return functions[i].Complexity > functions[j].Complexity })
+	fmt.Fprintln(tw, "complexity\tnesting\tlines\tparams\tmethod")
+	for _, f := range functions {
+		fmt.Fprintf(tw, "%d\t%d\t%d\t%d\t%s %s:%d\n", f.Complexity, f.Nesting, f.Lines.Code, f.Parameters, f, f.Path, f.Line)
+	}
+	fmt.Fprintln(tw)
+	fmt.Fprintln(tw, "methods\tfields\tcomplexity\tlines\tclass")
+	for _, c := range r.Classes {
+		fmt.Fprintf(tw, "%d\t%d\t%d\t%d\t%s %s:%d\n", c.Methods, c.Fields, c.Complexity, c.Lines.Code, c.Name, c.Path, c.Line)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	var total Lines
+	for _, f := range r.Files {
+		total.Total += f.Lines.Total
+		total.Code += f.Lines.Code
+		total.Comment += f.Lines.Comment
+		total.Blank += f.Lines.Blank
+	}
+	_, err := fmt.Fprintf(w, "\n%d files, %d lines: %d code, %d comment, %d blank\n",
+		len(r.Files), total.Total, total.Code, total.Comment, total.Blank)
+	return err
+}
@@ -0,0 +1,192 @@
+package metrics_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	zscript "github.com/jlcrochet/tree-sitter-zscript/bindings/go"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/metrics"
+)
+
+const source = `// Monsters.
+class Imp : Actor {
+	int a, b;
+	bool c;
+
+	int Pick(int x, int y) {
+		switch (x) {
+		case 1:
+		case 2:
+			return 1;
+		default:
+			break;
+		}
+		// Nested.
+		if (x && y || !x) {
+			while (y) {
+				y--;
+			}
+		} else if (y) {
+			return y ? 1 : 2;
+		} else {
+			return 0;
+		}
+		return 0;
+	}
+
+	void Native();
+	void Tick() {}
+}
+
+struct Point {
+	double x, y;
+}
+`
+
+func compute(t *testing.T) *metrics.Report {
+	t.Helper()
+	tree, err := zscript.Parse(context.Background(), []byte(source))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tree.Close()
+	tree.Path = "a.zs"
+	return metrics.Compute(tree)
+}
+
+func TestCompute(t *testing.T) {
+	r := compute(t)
+
+	if got, want := r.Files[0].Lines, (metrics.Lines{Total: 33, Code: 28, Comment: 2, Blank: 3}); got != want {
+		t.Errorf("file lines = %+v, want %+v", got, want)
+	}
+	if len(r.Functions) != 2 {
+		t.Fatalf("got %d functions, want 2", len(r.Functions))
+	}
+	pick := r.Functions[0]
+	if pick.String() != "Imp.Pick" || pick.Line != 6 || pick.Parameters != 2 {
+		t.Errorf("Pick = %+v", pick)
+	}
+	// Two cases, the if, the else if, the while, the ?: and two short
+	// circuits.
+	if pick.Complexity != 9 {
+		t.Errorf("Pick complexity = %d, want 9", pick.Complexity)
+	}
+	if pick.Nesting != 2 {
+		t.Errorf("Pick nesting = %d, want 2", pick.Nesting)
+	}
+	if got, want := pick.Lines, (metrics.Lines{Total: 20, Code: 19, Comment: 1}); got != want {
+		t.Errorf("Pick lines = %+v, want %+v", got, want)
+	}
+	if tick := r.Functions[1]; tick.Complexity != 1 || tick.Nesting != 0 {
+		t.Errorf("Tick = %+v", tick)
+	}
+
+	if len(r.Classes) != 2 {
+		t.Fatalf("got %d classes, want 2", len(r.Classes))
+	}
+	imp, point := r.Classes[0], r.Classes[1]
+	if imp.Methods != 3 || imp.Fields != 3 || imp.Complexity != 10 {
+		t.Errorf("Imp = %+v", imp)
+	}
+	if !point.Struct || point.Fields != 2 || point.Methods != 0 {
+		t.Errorf("Point = %+v", point)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	var got []string
+	for _, v := range compute(t).Check(metrics.Limits{Complexity: 5, Nesting: 2, Methods: 2}) {
+		got = append(got, v.String())
+	}
+	want := []string{
+		"a.zs:2: Imp has methods 3, over the limit of 2",
+		"a.zs:6: Imp.Pick has complexity 9, over the limit of 5",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Check =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestWrite(t *testing.T) {
+	r := compute(t)
+	var buf bytes.Buffer
+	if err := r.WriteJSON(&buf); err != nil {
+		t.Fatal(err)
+	}
+	var decoded metrics.Report
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if len(decoded.Functions) != 2 || decoded.Functions[0].Complexity != 9 {
+		t.Errorf("decoded functions = %+v", decoded.Functions)
+	}
+
+	buf.Reset()
+	if err := r.WriteText(&buf); err != nil {
+		t.Fatal(err)
+	}
+	text := buf.String()
+	for _, want := range []string{"Imp.Pick a.zs:6", "Point a.zs:31", "1 files, 33 lines: 28 code, 2 comment, 3 blank"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("text report lacks %q:\n%s", want, text)
+		}
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	var trees []*zscript.Tree
+	for _, f := range []struct{ path, text string }{
+		{"a.zs", source},
+		{"b.zs", `class BaseMonster : Actor {
+	States {
+	Spawn:
+		TROO AB 10;
+		Loop;
+	}
+}
+class Zombie : BaseMonster {}
+class Shells : ammo {}
+class Handler : EventHandler {}
+class Plain {}
+extend class Imp { void More() { a = 1; } }
+class Broken : Actor { int }
+`},
+	} {
+		tree, err := zscript.Parse(context.Background(), []byte(f.text))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer tree.Close()
+		tree.Path = f.path
+		trees = append(trees, tree)
+	}
+	s := metrics.Summarize(trees...)
+	if len(s.Files) != 2 || s.Files[0].Path != "a.zs" || s.Files[1].Errors == 0 || s.Errors != s.Files[1].Errors {
+		t.Errorf("got files %+v, errors %d", s.Files, s.Errors)
+	}
+	if s.Lines.Total != 33+13 {
+		t.Errorf("got %d lines", s.Lines.Total)
+	}
+	want := map[string]int{"Actor": 4, "Ammo": 1, "EventHandler": 1, "Object": 1}
+	if s.Classes != 7 || s.Structs != 1 || s.Actors != 5 || !reflect.DeepEqual(s.Ancestry, want) {
+		t.Errorf("got %d classes, %d structs, %d actors, ancestry %v", s.Classes, s.Structs, s.Actors, s.Ancestry)
+	}
+	if s.States != 1 || s.Functions != 3 {
+		t.Errorf("got %d states, %d functions", s.States, s.Functions)
+	}
+
+	var buf bytes.Buffer
+	if err := s.WriteText(&buf); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"classes:     7, 5 actors", "  Actor         4", "b.zs"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("text summary lacks %q:\n%s", want, buf.String())
+		}
+	}
+}
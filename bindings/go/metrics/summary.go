@@ -0,0 +1,166 @@
+package metrics
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+
+	zscript "github.com/jlcrochet/tree-sitter-zscript/bindings/go"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/engine"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/symbols"
+)
+
+// Summary is an overview of a set of files taken as one project, for
+// following the growth of a mod from release to release.
+type Summary struct {
+	Files []*FileSummary `json:"files"`
+	// Lines are the line counts of all the files.
+	Lines Lines `json:"lines"`
+	// Classes counts the classes defined, not counting extensions, and
+	// Structs the structs.
+	Classes int `json:"classes"`
+	Structs int `json:"structs"`
+	// Ancestry counts the classes by the nearest ancestor that the files
+	// do not define, such as Actor, Inventory or EventHandler; classes
+	// without a parent count under Object.
+	Ancestry map[string]int `json:"ancestry"`
+	// Actors counts the classes that descend from Actor.
+	Actors int `json:"actors"`
+	// States counts the state lines of the States blocks.
+	States int `json:"states"`
+	// Functions counts the methods with a body, and FunctionLines their
+	// average number of code lines.
+	Functions     int     `json:"functions"`
+	FunctionLines float64 `json:"functionLines"`
+	// Errors counts the syntax errors.
+	Errors int `json:"errors"`
+}
+
+// FileSummary is the part of a Summary about one file.
+type FileSummary struct {
+	Path   string `json:"path"`
+	Lines  Lines  `json:"lines"`
+	Errors int    `json:"errors"`
+	// ParseTime is the time the file took to parse, as measured by the
+	// caller, who parsed it; Summarize leaves it zero.
+	ParseTime time.Duration `json:"parseTime"`
+}
+
+// Summarize returns the summary of the given files.
+func Summarize(trees ...*zscript.Tree) *Summary {
+	s := &Summary{Ancestry: map[string]int{}}
+	r := Compute(trees...)
+	for i, tree := range trees {
+		f := &FileSummary{Path: tree.Path, Lines: r.Files[i].Lines, Errors: len(zscript.Diagnostics(tree.Tree, tree.Source))}
+		s.Files = append(s.Files, f)
+		s.Lines.Total += f.Lines.Total
+		s.Lines.Code += f.Lines.Code
+		s.Lines.Comment += f.Lines.Comment
+		s.Lines.Blank += f.Lines.Blank
+		s.Errors += f.Errors
+
+		var v zscript.Visitor
+		v.On(zscript.NodeStateLine, func(*tree_sitter.Node) zscript.WalkAction {
+			s.States++
+			return zscript.WalkSkipChildren
+		})
+		zscript.Walk(tree.RootNode(), &v)
+	}
+
+	code := 0
+	for _, f := range r.Functions {
+		code += f.Lines.Code
+	}
+	s.Functions = len(r.Functions)
+	if s.Functions > 0 {
+		s.FunctionLines = float64(code) / float64(s.Functions)
+	}
+
+	tables := symbols.ExtractAll(0, trees...)
+	h := engine.Hierarchy(tables...)
+	for _, t := range tables {
+		s.Structs += len(t.Structs)
+		for _, c := range t.Classes {
+			if c.Extend {
+				continue
+			}
+			s.Classes++
+			ancestor := "Object"
+			for p := h.Class(c.Name).Parent; p != nil; p = p.Parent {
+				if !p.Defined() || p.Path == engine.Path {
+					ancestor = p.Name
+					break
+				}
+			}
+			if name, _, ok := engine.Class(ancestor); ok {
+				ancestor = name
+			}
+			s.Ancestry[ancestor]++
+			if h.IsSubclassOf(c.Name, "Actor") || engine.IsSubclassOf(ancestor, "Actor") {
+				s.Actors++
+			}
+		}
+	}
+	return s
+}
+
+// WriteJSON writes s to w as indented JSON. Parse times are in
+// nanoseconds.
+func (s *Summary) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(s)
+}
+
+// WriteText writes s to w: the totals, the classes by ancestry from the
+// most common, and a table of the files.
+func (s *Summary) WriteText(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	var parse time.Duration
+	for _, f := range s.Files {
+		parse += f.ParseTime
+	}
+	fmt.Fprintf(tw, "files:\t%d\n", len(s.Files))
+	fmt.Fprintf(tw, "lines:\t%d: %d code, %d comment, %d blank\n", s.Lines.Total, s.Lines.Code, s.Lines.Comment, s.Lines.Blank)
+	fmt.Fprintf(tw, "errors:\t%d\n", s.Errors)
+	fmt.Fprintf(tw, "parse time:\t%v\n", parse.Round(time.Microsecond))
+	fmt.Fprintf(tw, "classes:\t%d, %d actors\n", s.Classes, s.Actors)
+	fmt.Fprintf(tw, "structs:\t%d\n", s.Structs)
+	fmt.Fprintf(tw, "states:\t%d\n", s.States)
+	fmt.Fprintf(tw, "functions:\t%d, %.1f lines of code on average\n", s.Functions, s.FunctionLines)
+
+	ancestors := make([]string, 0, len(s.Ancestry))
+	for a := range s.Ancestry {
+		ancestors = append(ancestors, a)
+	}
+	sort.Slice(ancestors, func(i, j int) bool {
+		a, b := ancestors[i], ancestors[j]
+		if s.Ancestry[a] != s.Ancestry[b] {
+			return s.Ancestry[a] > s.Ancestry[b]
+		}
+		return strings.ToLower(a) < strings.ToLower(b)
+	})
+	if len(ancestors) > 0 {
+		fmt.Fprintln(tw, "\nclasses by ancestry:")
+		for _, a := range ancestors {
+			fmt.Fprintf(tw, "  %s\t%d\n", a, s.Ancestry[a])
+		}
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	fmt.Fprintln(w)
+	tw = tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "lines\terrors\tparse time\tfile")
+	for _, f := range s.Files {
+		fmt.Fprintf(tw, "%d\t%d\t%v\t%s\n", f.Lines.Total, f.Errors, f.ParseTime.Round(time.Microsecond), f.Path)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,369 @@
+// Package minify shrinks ZScript source for release builds: it strips
+// comments, collapses whitespace to what separates the tokens, and
+// renames local variables to short names, while keeping what the code
+// does.
+//
+// Only locals are renamed. Parameters keep their names, since calls in
+// other files may pass arguments to them by name, as do fields, methods
+// and classes, which other files and lumps refer to. #include directives
+// and the version directive keep lines of their own.
+package minify
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+
+	zscript "github.com/jlcrochet/tree-sitter-zscript/bindings/go"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/engine"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/symbols"
+)
+
+// Options configures Minify.
+type Options struct {
+	// KeepNames leaves local variables their names.
+	KeepNames bool
+	// Reserved are names the renamed locals must not take, such as those
+	// of fields inherited from classes in other files. The identifiers of
+	// the file being minified, and the members of the engine's classes,
+	// are always reserved.
+	Reserved []string
+}
+
+// Source parses source and minifies it.
+func Source(ctx context.Context, source []byte, opts Options) ([]byte, error) {
+	tree, err := zscript.Parse(ctx, source)
+	if err != nil {
+		return nil, err
+	}
+	defer tree.Close()
+	return Minify(tree, opts)
+}
+
+// Minify returns the minified source of tree. It fails if the tree has
+// syntax errors, since the meaning of the code around them is not known.
+func Minify(tree *zscript.Tree, opts Options) ([]byte, error) {
+	root := tree.RootNode()
+	if root.HasError() {
+		return nil, fmt.Errorf("minify: %s has syntax errors", name(tree))
+	}
+	m := &minifier{source: tree.Source, renames: map[uint]string{}}
+	if !opts.KeepNames {
+		r := &renamer{source: tree.Source, renames: m.renames, taken: map[string]bool{}}
+		for _, name := range opts.Reserved {
+			r.taken[strings.ToLower(name)] = true
+		}
+		r.reserve(root)
+		r.reserveEngine()
+		r.functions(root)
+	}
+	m.node(root)
+	if m.out.Len() > 0 && m.last != '\n' {
+		m.out.WriteByte('\n')
+	}
+
+	// The output is parsed again, so that a mistake in the spacing rules
+	// is an error rather than a broken release.
+	check, err := zscript.Parse(context.Background(), m.out.Bytes())
+	if err != nil {
+		return nil, err
+	}
+	defer check.Close()
+	if check.RootNode().HasError() {
+		return nil, fmt.Errorf("minify: %s: the minified source does not parse", name(tree))
+	}
+	return m.out.Bytes(), nil
+}
+
+func name(tree *zscript.Tree) string {
+	if tree.Path == "" {
+		return "source"
+	}
+	return tree.Path
+}
+
+type minifier struct {
+	source []byte
+	// renames maps the start bytes of renamed identifiers to their new
+	// names.
+	renames map[uint]string
+	out     bytes.Buffer
+	// last is the last byte written, and number is set if it ended a
+	// number.
+	last   byte
+	number bool
+}
+
+// atomic are the kinds of node written as they are.
+var atomic = map[string]bool{
+	zscript.NodeStringLiteral: true,
+	zscript.NodeNameLiteral:   true,
+	zscript.NodeNumberLiteral: true,
+}
+
+func (m *minifier) node(n *tree_sitter.Node) {
+	switch {
+	case n.IsExtra() && n.Kind() == zscript.NodeComment:
+		return
+	case n.Kind() == zscript.NodeIncludeDirective, n.Kind() == zscript.NodeVersionDirective:
+		if m.out.Len() > 0 && m.last != '\n' {
+			m.write("\n", false)
+		}
+		m.children(n)
+		m.write("\n", false)
+		return
+	case atomic[n.Kind()]:
+		m.token(n.Utf8Text(m.source), n.Kind() == zscript.NodeNumberLiteral)
+		return
+	case n.ChildCount() == 0:
+		text, ok := m.renames[n.StartByte()]
+		if !ok {
+			// Leaves such as the sprite and frames of a state keep one
+			// space between their words.
+			text = strings.Join(strings.Fields(n.Utf8Text(m.source)), " ")
+		}
+		m.token(text, false)
+		return
+	}
+	m.children(n)
+}
+
+func (m *minifier) children(n *tree_sitter.Node) {
+	for i := uint(0); i < n.ChildCount(); i++ {
+		m.node(n.Child(i))
+	}
+}
+
+// token writes text, after a space if it would otherwise run into the
+// token before it.
+func (m *minifier) token(text string, number bool) {
+	if text == "" {
+		return
+	}
+	if m.needsSpace(text[0]) {
+		m.out.WriteByte(' ')
+	}
+	m.write(text, number)
+}
+
+func (m *minifier) write(text string, number bool) {
+	m.out.WriteString(text)
+	m.last, m.number = text[len(text)-1], number
+}
+
+// operatorChars are the characters of operators, any two of which might
+// join into another operator or a comment.
+const operatorChars = "+-*/%&|^<>=!~?:.#@"
+
+func (m *minifier) needsSpace(next byte) bool {
+	prev := m.last
+	switch {
+	case m.out.Len() == 0 || prev == '\n':
+		return false
+	case isWord(prev) && isWord(next):
+		return true
+	case m.number && next == '.', prev == '.' && isDigit(next):
+		return true
+	}
+	return strings.IndexByte(operatorChars, prev) >= 0 && strings.IndexByte(operatorChars, next) >= 0
+}
+
+func isWord(c byte) bool {
+	return c == '_' || isDigit(c) || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= 0x80
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// keywords holds the lowercased keywords of the grammar, which new names
+// must not be.
+var keywords = sync.OnceValue(func() map[string]bool {
+	language := zscript.GetLanguage()
+	words := map[string]bool{}
+	for id := uint16(0); uint32(id) < language.NodeKindCount(); id++ {
+		if kind := language.NodeKindForId(id); !language.NodeKindIsNamed(id) && kind != "" && isWord(kind[0]) {
+			words[strings.ToLower(kind)] = true
+		}
+	}
+	return words
+})
+
+// renamer chooses the new names of locals.
+type renamer struct {
+	source  []byte
+	renames map[uint]string
+	// taken holds the lowercased names new names must not be.
+	taken map[string]bool
+	next  int
+	// scopes map the lowercased names of the locals and parameters in
+	// scope to their new names, or "" for those kept, innermost last.
+	scopes []map[string]string
+}
+
+// reserve adds every name in n to taken.
+func (r *renamer) reserve(n *tree_sitter.Node) {
+	var v zscript.Visitor
+	v.Enter = func(node *tree_sitter.Node) zscript.WalkAction {
+		if node.ChildCount() == 0 && node.IsNamed() {
+			r.taken[strings.ToLower(node.Utf8Text(r.source))] = true
+		}
+		return zscript.WalkContinue
+	}
+	zscript.Walk(n, &v)
+}
+
+// reserveEngine adds to taken the names of the fields, constants and
+// enumerators of the engine's classes and structs, which a local in a
+// subclass would hide.
+func (r *renamer) reserveEngine() {
+	t := engine.Table()
+	add := func(fields []*symbols.Field, consts []*symbols.Const, enums []*symbols.Enum) {
+		for _, f := range fields {
+			r.taken[strings.ToLower(f.Name)] = true
+		}
+		for _, c := range consts {
+			r.taken[strings.ToLower(c.Name)] = true
+		}
+		for _, e := range enums {
+			for _, m := range e.Members {
+				r.taken[strings.ToLower(m.Name)] = true
+			}
+		}
+	}
+	add(nil, t.Consts, t.Enums)
+	for _, c := range t.Classes {
+		add(c.Fields, c.Consts, c.Enums)
+	}
+	for _, s := range t.Structs {
+		add(s.Fields, s.Consts, s.Enums)
+	}
+}
+
+// fresh returns the next short name that is not taken.
+func (r *renamer) fresh() string {
+	const letters = "abcdefghijklmnopqrstuvwxyz"
+	for {
+		n := r.next
+		r.next++
+		name := string(letters[n%len(letters)])
+		for n /= len(letters); n > 0; n /= len(letters) {
+			name += string(letters[(n-1)%len(letters)])
+		}
+		if !r.taken[name] && !keywords()[name] {
+			return name
+		}
+	}
+}
+
+// functions renames the locals of every method and anonymous state
+// function in n.
+func (r *renamer) functions(n *tree_sitter.Node) {
+	var v zscript.Visitor
+	v.On(zscript.NodeMethodDefinition, func(node *tree_sitter.Node) zscript.WalkAction {
+		if body := node.ChildByFieldName(zscript.FieldBody); body != nil {
+			params := map[string]string{}
+			if list := node.ChildByFieldName(zscript.FieldParameters); list != nil {
+				for i := uint(0); i < list.NamedChildCount(); i++ {
+					if d := list.NamedChild(i).ChildByFieldName(zscript.FieldDeclarator); d != nil {
+						params[strings.ToLower(d.Utf8Text(r.source))] = ""
+					}
+				}
+			}
+			r.scopes = []map[string]string{params}
+			r.walk(body)
+		}
+		return zscript.WalkSkipChildren
+	})
+	v.On(zscript.NodeStateAction, func(node *tree_sitter.Node) zscript.WalkAction {
+		r.scopes = nil
+		r.walk(node)
+		return zscript.WalkSkipChildren
+	})
+	zscript.Walk(n, &v)
+}
+
+func (r *renamer) walk(n *tree_sitter.Node) {
+	switch n.Kind() {
+	case zscript.NodeCompoundStatement, zscript.NodeForStatement:
+		r.scopes = append(r.scopes, map[string]string{})
+		r.named(n)
+		r.scopes = r.scopes[:len(r.scopes)-1]
+	case zscript.NodeForeachStatement:
+		r.walkField(n, zscript.FieldCollection)
+		r.scopes = append(r.scopes, map[string]string{})
+		for i := uint(0); i < n.NamedChildCount(); i++ {
+			if n.FieldNameForNamedChild(uint32(i)) == zscript.FieldVariable {
+				r.declare(n.NamedChild(i))
+			}
+		}
+		r.walkField(n, zscript.FieldBody)
+		r.scopes = r.scopes[:len(r.scopes)-1]
+	case zscript.NodeDeclaration:
+		for i := uint(0); i < n.NamedChildCount(); i++ {
+			if n.FieldNameForNamedChild(uint32(i)) == zscript.FieldDeclarator {
+				r.declarator(n.NamedChild(i))
+			}
+		}
+	case zscript.NodeNamedArgument:
+		r.walkField(n, zscript.FieldValue)
+	case zscript.NodeFieldExpression:
+		r.walkField(n, zscript.FieldArgument)
+	case zscript.NodeIdentifier:
+		key := strings.ToLower(n.Utf8Text(r.source))
+		for i := len(r.scopes) - 1; i >= 0; i-- {
+			if to, ok := r.scopes[i][key]; ok {
+				if to != "" {
+					r.renames[n.StartByte()] = to
+				}
+				return
+			}
+		}
+	default:
+		r.named(n)
+	}
+}
+
+func (r *renamer) named(n *tree_sitter.Node) {
+	for i := uint(0); i < n.NamedChildCount(); i++ {
+		r.walk(n.NamedChild(i))
+	}
+}
+
+func (r *renamer) walkField(n *tree_sitter.Node, field string) {
+	if c := n.ChildByFieldName(field); c != nil {
+		r.walk(c)
+	}
+}
+
+// declarator declares the local of d, after walking its initial value and
+// array sizes, which cannot refer to it.
+func (r *renamer) declarator(d *tree_sitter.Node) {
+	switch d.Kind() {
+	case zscript.NodeIdentifier:
+		r.declare(d)
+	case zscript.NodeInitDeclarator, zscript.NodeArrayDeclarator:
+		r.walkField(d, zscript.FieldValue)
+		r.walkField(d, zscript.FieldSize)
+		if inner := d.ChildByFieldName(zscript.FieldDeclarator); inner != nil {
+			r.declarator(inner)
+		}
+	default:
+		r.named(d)
+	}
+}
+
+// declare gives the local named by the identifier n a new name in the
+// innermost scope.
+func (r *renamer) declare(n *tree_sitter.Node) {
+	if n.Kind() != zscript.NodeIdentifier || len(r.scopes) == 0 {
+		return
+	}
+	to := r.fresh()
+	r.scopes[len(r.scopes)-1][strings.ToLower(n.Utf8Text(r.source))] = to
+	r.renames[n.StartByte()] = to
+}
@@ -0,0 +1,164 @@
+package minify_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	zscript "github.com/jlcrochet/tree-sitter-zscript/bindings/go"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/internal/corpus"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/minify"
+)
+
+func TestMinify(t *testing.T) {
+	tests := []struct {
+		name, source, want string
+		opts               minify.Options
+	}{{
+		name: "comments and whitespace",
+		source: `// header
+class Foo : Actor /* base */ {
+	int count;
+	States {
+	Spawn:
+		TNT1   AB   4;
+		Loop;
+	}
+}
+`,
+		want: "class Foo:Actor{int count;States{Spawn:TNT1 AB 4;Loop;}}\n",
+	}, {
+		name: "directives",
+		source: `version "4.10"
+#include "a.zs"
+#include "b.zs"
+class Foo {}
+`,
+		want: "version\"4.10\"\n#include\"a.zs\"\n#include\"b.zs\"\nclass Foo{}\n",
+	}, {
+		name: "operators",
+		source: `class Foo {
+	void Bar(int x) {
+		x = x - -1;
+		x = x + +1;
+		x = 1. .. "a";
+		Array<Array<int> > nested;
+	}
+}
+`,
+		// "a" is taken by the string.
+		want: "class Foo{void Bar(int x){x=x- -1;x=x+ +1;x=1. ..\"a\";Array<Array<int> >b;}}\n",
+	}, {
+		name: "locals",
+		source: `class Foo : Actor {
+	int total;
+	void Bar(int amount) {
+		int total = amount;
+		for (int i = 0; i < total; i++) {
+			let mo = Spawn("Imp", pos);
+			mo.A_SetHealth(i);
+		}
+		Array<int> values;
+		foreach (value : values) { TOTAL += value; }
+		self.total = total;
+		A_StartSound("x", volume: amount);
+	}
+	States {
+	Spawn:
+		TNT1 A 0 { int tics = 5; A_SetTics(tics); }
+		Stop;
+	}
+}
+`,
+		want: "class Foo:Actor{int total;void Bar(int amount){int a=amount;for(int b=0;b<a;b++){let c=Spawn(\"Imp\",pos);c.A_SetHealth(b);}Array<int>d;foreach(e:d){a+=e;}self.total=a;A_StartSound(\"x\",volume:amount);}States{Spawn:TNT1 A 0{int f=5;A_SetTics(f);}Stop;}}\n",
+	}, {
+		name: "reserved names",
+		source: `class Foo {
+	int a;
+	void Bar() { int first = 1; int second = first; }
+}
+`,
+		opts: minify.Options{Reserved: []string{"B"}},
+		want: "class Foo{int a;void Bar(){int c=1;int d=c;}}\n",
+	}, {
+		name: "shadowing",
+		source: `class Foo {
+	void Bar() {
+		int x = 1;
+		{ int x = x + 1; }
+		x = 2;
+	}
+}
+`,
+		want: "class Foo{void Bar(){int a=1;{int b=a+1;}a=2;}}\n",
+	}, {
+		name:   "keep names",
+		source: "class Foo { void Bar() { int first = 1; } }\n",
+		opts:   minify.Options{KeepNames: true},
+		want:   "class Foo{void Bar(){int first=1;}}\n",
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := minify.Source(context.Background(), []byte(tt.source), tt.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("got\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMinifySyntaxErrors(t *testing.T) {
+	tree, err := zscript.Parse(context.Background(), []byte("class Foo { void Bar( }"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tree.Close()
+	tree.Path = "bad.zs"
+	if _, err := minify.Minify(tree, minify.Options{}); err == nil || !strings.Contains(err.Error(), "bad.zs") {
+		t.Errorf("got %v, want an error naming bad.zs", err)
+	}
+}
+
+// TestCorpus checks that minifying each corpus example without renaming
+// keeps its tree.
+func TestCorpus(t *testing.T) {
+	examples, err := corpus.Load("../../../test/corpus")
+	if err != nil || len(examples) == 0 {
+		t.Fatalf("no corpus examples: %v", err)
+	}
+	for _, e := range examples {
+		t.Run(e.File+"/"+e.Name, func(t *testing.T) {
+			before, err := zscript.Parse(context.Background(), []byte(e.Input))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer before.Close()
+			if before.RootNode().HasError() {
+				t.Skip("syntax errors")
+			}
+			out, err := minify.Minify(before, minify.Options{KeepNames: true})
+			if err != nil {
+				t.Fatal(err)
+			}
+			after, err := zscript.Parse(context.Background(), out)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer after.Close()
+			if want, got := withoutComments(before.RootNode().ToSexp()), after.RootNode().ToSexp(); got != want {
+				t.Errorf("tree changed:\n%s\nminified:\n%s\n%s", want, out, got)
+			}
+			if _, err := minify.Minify(before, minify.Options{}); err != nil {
+				t.Errorf("renaming: %v", err)
+			}
+		})
+	}
+}
+
+// withoutComments removes the comment nodes from an S-expression.
+func withoutComments(sexp string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(sexp, " (comment)", ""), "(comment) ", "")
+}
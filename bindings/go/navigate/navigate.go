@@ -0,0 +1,251 @@
+// Package navigate finds where the code at a position of a project is
+// declared, for an editor's go-to-definition and go-to-type-definition
+// commands.
+//
+// Beyond the identifiers package resolve binds, it follows Super to the
+// parent class, class names written as strings on the right of "is",
+// state labels written as strings, as in A_Jump(128, "See"), and #include
+// paths to the files they include.
+package navigate
+
+import (
+	"strings"
+
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+
+	zscript "github.com/jlcrochet/tree-sitter-zscript/bindings/go"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/engine"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/labels"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/project"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/resolve"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/symbols"
+)
+
+// Definition returns the names of the declarations of what is at pos in
+// the file of p at path, or the start of the file an #include there
+// includes. Built-in declarations have the path engine.Path.
+func Definition(p *project.Project, path string, pos tree_sitter.Point) []resolve.Location {
+	f := p.File(path)
+	if f == nil {
+		return nil
+	}
+	node := nodeAt(f.Tree.RootNode(), pos)
+	if node == nil {
+		return nil
+	}
+	if inc := ancestor(node, zscript.NodeIncludeDirective); inc != nil {
+		if target := inc.ChildByFieldName(zscript.FieldPath); target != nil {
+			name, ok := p.ResolveInclude(f.Path, strings.Trim(target.Utf8Text(f.Tree.Source), `"`))
+			if file := p.File(name); ok && file != nil {
+				return []resolve.Location{{Path: file.Path}}
+			}
+		}
+		return nil
+	}
+	return locations(Declarations(resolve.ForProject(p), f.Tree, node))
+}
+
+// TypeDefinition returns the names of the declarations of the type of what
+// is at pos in the file of p at path: the class, struct or enum a
+// variable, field, parameter or enumerator is declared with, or that a
+// method returns. Element types are looked through, so that the type of a
+// variable of type array<Imp> or class<Imp> is Imp. A type name is its own
+// type.
+func TypeDefinition(p *project.Project, path string, pos tree_sitter.Point) []resolve.Location {
+	f := p.File(path)
+	if f == nil {
+		return nil
+	}
+	node := nodeAt(f.Tree.RootNode(), pos)
+	if node == nil {
+		return nil
+	}
+	return locations(TypeDeclarations(resolve.ForProject(p), f.Tree, node))
+}
+
+// Declarations returns the declarations of node, in tree, resolving it
+// with r. node may be an identifier, Super, a string naming a class on
+// the right of "is", or a string or name literal naming a state label.
+func Declarations(r *resolve.Resolver, tree *zscript.Tree, node *tree_sitter.Node) []resolve.Declaration {
+	switch {
+	case isSuper(tree, node):
+		if parent := parentClass(node); parent != nil {
+			return r.ResolveAll(tree, parent)
+		}
+		return nil
+	case node.Kind() == zscript.NodeStringLiteral || node.Kind() == zscript.NodeNameLiteral:
+		if !isClassTest(node) {
+			return label(r, tree, node)
+		}
+		var decls []resolve.Declaration
+		for _, decl := range r.LookupType("", strings.Trim(node.Utf8Text(tree.Source), `"`)) {
+			if decl.Kind == symbols.KindClass {
+				decls = append(decls, decl)
+			}
+		}
+		return decls
+	}
+	return r.ResolveAll(tree, node)
+}
+
+// label returns the declaration of the state label that node, a string or
+// name literal, names, if it is one. The engine's labels have no source.
+func label(r *resolve.Resolver, tree *zscript.Tree, node *tree_sitter.Node) []resolve.Declaration {
+	ref, ok := labels.Argument(r, tree, node)
+	if !ok {
+		return nil
+	}
+	if decl, found, _ := labels.Lookup(r, ref); found && decl.Path != engine.Path {
+		return []resolve.Declaration{decl}
+	}
+	return nil
+}
+
+// TypeDeclarations is like Declarations but returns the declarations of
+// the types of what node refers to.
+func TypeDeclarations(r *resolve.Resolver, tree *zscript.Tree, node *tree_sitter.Node) []resolve.Declaration {
+	if node.Kind() == zscript.NodeSelfExpression {
+		node = enclosingName(node)
+		if node == nil {
+			return nil
+		}
+	}
+	var types []resolve.Declaration
+	for _, decl := range Declarations(r, tree, node) {
+		switch decl.Kind {
+		case symbols.KindClass, symbols.KindStruct, symbols.KindEnum:
+			types = append(types, decl)
+		default:
+			if name := elementType(decl.Type); name != "" {
+				scope := decl.Owner
+				if scope == "" {
+					if n := enclosingName(node); n != nil {
+						scope = n.Utf8Text(tree.Source)
+					}
+				}
+				types = append(types, r.LookupType(scope, name)...)
+			}
+		}
+	}
+	return types
+}
+
+// elementType returns the class, struct or enum named by a declared type,
+// looking through readonly<T>, class<T> and array<T>; for map<K, V>, it is
+// V. It returns "" for a type without a name, such as let.
+func elementType(typ string) string {
+	typ = strings.Join(strings.Fields(typ), "")
+	for {
+		open := strings.IndexByte(typ, '<')
+		if open < 0 || !strings.HasSuffix(typ, ">") {
+			break
+		}
+		typ = typ[open+1 : len(typ)-1]
+		if i := strings.LastIndexByte(typ, ','); i >= 0 && !strings.Contains(typ[i:], ">") {
+			typ = typ[i+1:]
+		}
+	}
+	typ, _, _ = strings.Cut(typ, "[")
+	switch strings.ToLower(typ) {
+	case "", "let", "var", "void":
+		return ""
+	}
+	return typ
+}
+
+func locations(decls []resolve.Declaration) []resolve.Location {
+	var locs []resolve.Location
+	seen := map[resolve.Location]bool{}
+	for _, decl := range decls {
+		loc := resolve.Location{Path: decl.Path, Range: decl.NameRange}
+		if !seen[loc] {
+			seen[loc] = true
+			locs = append(locs, loc)
+		}
+	}
+	return locs
+}
+
+// isSuper reports whether node is Super, as a receiver or as the class of
+// a goto target.
+func isSuper(tree *zscript.Tree, node *tree_sitter.Node) bool {
+	if node.Kind() == zscript.NodeSuperExpression {
+		return true
+	}
+	p := node.Parent()
+	return p != nil && p.Kind() == zscript.NodeStateGotoTarget && strings.EqualFold(node.Utf8Text(tree.Source), "super") &&
+		p.ChildByFieldName(zscript.FieldClass) != nil && p.ChildByFieldName(zscript.FieldClass).Id() == node.Id()
+}
+
+// isClassTest reports whether the string node is the right operand of an
+// "is" expression.
+func isClassTest(node *tree_sitter.Node) bool {
+	p := node.Parent()
+	if p == nil || p.Kind() != zscript.NodeBinaryExpression {
+		return false
+	}
+	right := p.ChildByFieldName(zscript.FieldRight)
+	op := p.ChildByFieldName(zscript.FieldOperator)
+	return right != nil && right.Id() == node.Id() && op != nil && op.Kind() == "is"
+}
+
+// parentClass returns the parent name in the inheritance specifier of the
+// class that contains node, or nil.
+func parentClass(node *tree_sitter.Node) *tree_sitter.Node {
+	class := ancestor(node, zscript.NodeClassDefinition)
+	if class == nil {
+		return nil
+	}
+	for i := uint(0); i < class.NamedChildCount(); i++ {
+		if c := class.NamedChild(i); c.Kind() == zscript.NodeInheritanceSpecifier {
+			return c.ChildByFieldName(zscript.FieldParent)
+		}
+	}
+	return nil
+}
+
+// enclosingName returns the name of the class or struct that contains
+// node, or nil.
+func enclosingName(node *tree_sitter.Node) *tree_sitter.Node {
+	for n := node.Parent(); n != nil; n = n.Parent() {
+		switch n.Kind() {
+		case zscript.NodeClassDefinition, zscript.NodeStructDefinition:
+			return n.ChildByFieldName(zscript.FieldName)
+		}
+	}
+	return nil
+}
+
+func ancestor(node *tree_sitter.Node, kind string) *tree_sitter.Node {
+	for n := node; n != nil; n = n.Parent() {
+		if n.Kind() == kind {
+			return n
+		}
+	}
+	return nil
+}
+
+// nodeAt returns the node at pos that navigation starts from: an
+// identifier, Super or self, or a string or name literal. When pos is
+// just past one, that one is returned.
+func nodeAt(root *tree_sitter.Node, pos tree_sitter.Point) *tree_sitter.Node {
+	points := []tree_sitter.Point{pos}
+	if pos.Column > 0 {
+		points = append(points, tree_sitter.Point{Row: pos.Row, Column: pos.Column - 1})
+	}
+	for _, p := range points {
+		node := root.NamedDescendantForPointRange(p, p)
+		if node != nil && node.Kind() == zscript.NodeStringContent {
+			node = node.Parent()
+		}
+		if node == nil {
+			continue
+		}
+		switch node.Kind() {
+		case zscript.NodeIdentifier, zscript.NodeTypeIdentifier, zscript.NodeFieldIdentifier,
+			zscript.NodeSuperExpression, zscript.NodeSelfExpression, zscript.NodeStringLiteral, zscript.NodeNameLiteral:
+			return node
+		}
+	}
+	return nil
+}
@@ -0,0 +1,140 @@
+package navigate_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/engine"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/navigate"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/project"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/resolve"
+)
+
+const root = `version "4.12"
+#include "actors/imp.zs"
+
+class Base : Actor {
+	enum EMode { M_One }
+	EMode mode;
+	Array<Base> friends;
+	Base Leader() { return self; }
+	States {
+	Spawn:
+		TNT1 A 1;
+		Loop;
+	}
+}
+`
+
+const imp = `class Imp : Base {
+	void F(Actor mo) {
+		if (mo is "Base") {}
+		let b = Base(mo);
+		Super.Tick();
+		mode = M_One;
+		b = friends[0].Leader();
+	}
+	States {
+	See:
+		TNT1 A 1;
+		Goto Base::Spawn;
+	Pain:
+		TNT1 A 0 A_Jump(128, "See");
+		Goto Super::Spawn;
+	}
+}
+`
+
+func load(t *testing.T) *project.Project {
+	t.Helper()
+	fsys := fstest.MapFS{
+		"zscript.zs":    {Data: []byte(root)},
+		"actors/imp.zs": {Data: []byte(imp)},
+	}
+	p, err := project.Load(context.Background(), fsys, "zscript.zs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(p.Close)
+	return p
+}
+
+// at returns the position just after the first occurrence of after in
+// source, on the given line.
+func at(source string, line uint, after string) tree_sitter.Point {
+	text := strings.Split(source, "\n")[line]
+	return tree_sitter.Point{Row: line, Column: uint(strings.Index(text, after) + len(after))}
+}
+
+// name returns the text a location spans in the project.
+func name(p *project.Project, loc resolve.Location) string {
+	if loc.Path == engine.Path {
+		return string(engine.Source()[loc.Range.StartByte:loc.Range.EndByte])
+	}
+	f := p.File(loc.Path)
+	return string(f.Tree.Source[loc.Range.StartByte:loc.Range.EndByte])
+}
+
+func TestDefinition(t *testing.T) {
+	p := load(t)
+	tests := []struct {
+		file  string
+		pos   tree_sitter.Point
+		path  string
+		want  string
+		start uint
+	}{
+		{"actors/imp.zs", at(imp, 0, "Imp : "), "zscript.zs", "Base", 3},
+		{"actors/imp.zs", at(imp, 2, `is "`), "zscript.zs", "Base", 3},
+		{"actors/imp.zs", at(imp, 3, "let b = "), "zscript.zs", "Base", 3},
+		{"actors/imp.zs", at(imp, 4, "\t\t"), "zscript.zs", "Base", 3},
+		{"actors/imp.zs", at(imp, 4, "Super."), engine.Path, "Tick", 0},
+		{"actors/imp.zs", at(imp, 11, "Goto "), "zscript.zs", "Base", 3},
+		{"actors/imp.zs", at(imp, 11, "Base::"), "zscript.zs", "Spawn", 9},
+		{"actors/imp.zs", at(imp, 13, `"S`), "actors/imp.zs", "See", 9},
+		{"actors/imp.zs", at(imp, 14, "Goto "), "zscript.zs", "Base", 3},
+		{"actors/imp.zs", at(imp, 14, "Super::"), "zscript.zs", "Spawn", 9},
+		{"zscript.zs", at(root, 1, `"act`), "actors/imp.zs", "", 0},
+	}
+	for _, tt := range tests {
+		locs := navigate.Definition(p, tt.file, tt.pos)
+		if len(locs) != 1 {
+			t.Errorf("%s:%d:%d: got %+v", tt.file, tt.pos.Row, tt.pos.Column, locs)
+			continue
+		}
+		loc := locs[0]
+		if loc.Path != tt.path || name(p, loc) != tt.want || (loc.Path != engine.Path && loc.Range.StartPoint.Row != tt.start) {
+			t.Errorf("%s:%d:%d: got %s %q at line %d, want %s %q at line %d", tt.file, tt.pos.Row, tt.pos.Column, loc.Path, name(p, loc), loc.Range.StartPoint.Row, tt.path, tt.want, tt.start)
+		}
+	}
+
+	if locs := navigate.Definition(p, "actors/imp.zs", at(imp, 1, "\t")); len(locs) != 0 {
+		t.Errorf("definition of a keyword = %+v", locs)
+	}
+}
+
+func TestTypeDefinition(t *testing.T) {
+	p := load(t)
+	tests := []struct {
+		pos  tree_sitter.Point
+		path string
+		want string
+	}{
+		{at(imp, 2, "if ("), engine.Path, "Actor"},
+		{at(imp, 5, "\t\t"), "zscript.zs", "EMode"},
+		{at(imp, 5, "= "), "zscript.zs", "EMode"},
+		{at(imp, 6, "b = "), "zscript.zs", "Base"},
+		{at(imp, 6, "].Lead"), "zscript.zs", "Base"},
+		{at(imp, 0, "Imp : "), "zscript.zs", "Base"},
+	}
+	for _, tt := range tests {
+		locs := navigate.TypeDefinition(p, "actors/imp.zs", tt.pos)
+		if len(locs) != 1 || locs[0].Path != tt.path || name(p, locs[0]) != tt.want {
+			t.Errorf("%d:%d: got %+v, want %s %q", tt.pos.Row, tt.pos.Column, locs, tt.path, tt.want)
+		}
+	}
+}
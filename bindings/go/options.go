@@ -0,0 +1,141 @@
+package tree_sitter_zscript
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/positions"
+)
+
+// SourceEncoding is the text encoding of the source given to
+// ParseWithOptions.
+type SourceEncoding int
+
+const (
+	// SourceUTF8 is UTF-8, the encoding Parse assumes.
+	SourceUTF8 SourceEncoding = iota
+	// SourceUTF16 is UTF-16 in the byte order given by a leading byte
+	// order mark, or little-endian without one, as Windows editors save
+	// it.
+	SourceUTF16
+	// SourceUTF16LE is little-endian UTF-16.
+	SourceUTF16LE
+	// SourceUTF16BE is big-endian UTF-16.
+	SourceUTF16BE
+)
+
+// ErrOddLength is returned for UTF-16 source of an odd number of bytes.
+var ErrOddLength = errors.New("zscript: UTF-16 source has an odd number of bytes")
+
+// ParseOptions configures ParseWithOptions. The zero value parses as
+// Parse does.
+type ParseOptions struct {
+	// Encoding is the encoding of the source. A UTF-16 source is converted
+	// to UTF-8, without its byte order mark, before parsing; the tree's
+	// Source and positions refer to the converted text.
+	Encoding SourceEncoding
+	// IncludedRanges limits parsing to these ranges of the (UTF-8)
+	// source, as when ZScript is embedded in another lump or in
+	// documentation; the text between them is ignored. They must be in
+	// order and must not overlap. Only the byte offsets need be set: the
+	// points are computed from the source.
+	IncludedRanges []tree_sitter.Range
+	// SkipExtras sets the tree's SkipExtras field, so that Tree.Visit does
+	// not visit comments.
+	SkipExtras bool
+	// Dialect sets the tree's Dialect field.
+	Dialect Dialect
+}
+
+// ParseWithOptions is like Parse but decodes, limits and marks the tree
+// as opts asks.
+func ParseWithOptions(ctx context.Context, source []byte, opts ParseOptions) (*Tree, error) {
+	if opts.Encoding != SourceUTF8 {
+		var err error
+		if source, err = decodeUTF16(source, opts.Encoding); err != nil {
+			return nil, err
+		}
+	}
+	if len(opts.IncludedRanges) == 0 {
+		tree, err := Parse(ctx, source)
+		if err != nil {
+			return nil, err
+		}
+		tree.SkipExtras, tree.Dialect = opts.SkipExtras, opts.Dialect
+		return tree, nil
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	ranges := make([]tree_sitter.Range, len(opts.IncludedRanges))
+	index := positions.NewIndex(source)
+	for i, r := range opts.IncludedRanges {
+		if r.EndByte > uint(len(source)) {
+			return nil, fmt.Errorf("zscript: included range %d ends at byte %d, after the source", i, r.EndByte)
+		}
+		ranges[i] = tree_sitter.Range{
+			StartByte:  r.StartByte,
+			EndByte:    r.EndByte,
+			StartPoint: index.Point(r.StartByte),
+			EndPoint:   index.Point(r.EndByte),
+		}
+	}
+	p := parserPool.Get().(*pooledParser)
+	if p.err != nil {
+		return nil, p.err
+	}
+	defer parserPool.Put(p)
+	if err := p.parser.SetIncludedRanges(ranges); err != nil {
+		return nil, fmt.Errorf("zscript: %w", err)
+	}
+	defer p.parser.SetIncludedRanges(nil)
+	tree, err := parseWith(ctx, p.parser, readSource(source), nil)
+	if err != nil {
+		return nil, err
+	}
+	return &Tree{Tree: tree, Source: source, SkipExtras: opts.SkipExtras, Dialect: opts.Dialect}, nil
+}
+
+// decodeUTF16 converts source from UTF-16 in the given encoding to UTF-8.
+func decodeUTF16(source []byte, encoding SourceEncoding) ([]byte, error) {
+	if len(source)%2 != 0 {
+		return nil, ErrOddLength
+	}
+	var order binary.ByteOrder = binary.LittleEndian
+	switch {
+	case encoding == SourceUTF16BE:
+		order = binary.BigEndian
+	case encoding == SourceUTF16 && len(source) >= 2 && source[0] == 0xFE && source[1] == 0xFF:
+		order = binary.BigEndian
+	}
+	units := make([]uint16, len(source)/2)
+	for i := range units {
+		units[i] = order.Uint16(source[2*i:])
+	}
+	if len(units) > 0 && units[0] == 0xFEFF {
+		units = units[1:]
+	}
+	out := make([]byte, 0, len(units))
+	for _, r := range utf16.Decode(units) {
+		out = utf8.AppendRune(out, r)
+	}
+	return out, nil
+}
+
+// Visit walks the tree from its root with v, as Walk does, leaving out
+// comments if the tree's SkipExtras field or v.SkipExtras is set.
+func (t *Tree) Visit(v *Visitor) bool {
+	if t.SkipExtras && !v.SkipExtras {
+		skipping := *v
+		skipping.SkipExtras = true
+		v = &skipping
+	}
+	return Walk(t.RootNode(), v)
+}
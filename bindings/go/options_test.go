@@ -0,0 +1,165 @@
+package tree_sitter_zscript_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"unicode/utf16"
+
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+
+	tree_sitter_zscript "github.com/jlcrochet/tree-sitter-zscript/bindings/go"
+)
+
+func encodeUTF16(s string, bigEndian, bom bool) []byte {
+	units := utf16.Encode([]rune(s))
+	if bom {
+		units = append([]uint16{0xFEFF}, units...)
+	}
+	out := make([]byte, 0, 2*len(units))
+	for _, u := range units {
+		if bigEndian {
+			out = append(out, byte(u>>8), byte(u))
+		} else {
+			out = append(out, byte(u), byte(u>>8))
+		}
+	}
+	return out
+}
+
+func TestParseWithOptionsUTF16(t *testing.T) {
+	const src = "class A : Actor { Default { Tag \"Ämber\"; } }\n"
+	tests := []struct {
+		name     string
+		encoding tree_sitter_zscript.SourceEncoding
+		source   []byte
+	}{
+		{"le", tree_sitter_zscript.SourceUTF16LE, encodeUTF16(src, false, false)},
+		{"be", tree_sitter_zscript.SourceUTF16BE, encodeUTF16(src, true, false)},
+		{"bom le", tree_sitter_zscript.SourceUTF16, encodeUTF16(src, false, true)},
+		{"bom be", tree_sitter_zscript.SourceUTF16, encodeUTF16(src, true, true)},
+		{"no bom", tree_sitter_zscript.SourceUTF16, encodeUTF16(src, false, false)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tree, err := tree_sitter_zscript.ParseWithOptions(context.Background(), tt.source, tree_sitter_zscript.ParseOptions{Encoding: tt.encoding})
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer tree.Close()
+			if string(tree.Source) != src {
+				t.Errorf("Source = %q, want %q", tree.Source, src)
+			}
+			if tree.RootNode().HasError() {
+				t.Errorf("tree has errors: %s", tree.RootNode().ToSexp())
+			}
+		})
+	}
+
+	_, err := tree_sitter_zscript.ParseWithOptions(context.Background(), []byte{'a', 0, 'b'}, tree_sitter_zscript.ParseOptions{Encoding: tree_sitter_zscript.SourceUTF16LE})
+	if !errors.Is(err, tree_sitter_zscript.ErrOddLength) {
+		t.Errorf("odd length: err = %v, want ErrOddLength", err)
+	}
+}
+
+func TestParseWithOptionsIncludedRanges(t *testing.T) {
+	source := []byte("Some documentation.\n```zscript\nclass A : Actor {}\n```\nMore text {\n```zscript\nclass B : A {}\n```\n")
+	var ranges []tree_sitter.Range
+	text := string(source)
+	for offset := 0; ; {
+		start := strings.Index(text[offset:], "```zscript\n")
+		if start < 0 {
+			break
+		}
+		start += offset + len("```zscript\n")
+		end := start + strings.Index(text[start:], "```")
+		ranges = append(ranges, tree_sitter.Range{StartByte: uint(start), EndByte: uint(end)})
+		offset = end + 3
+	}
+
+	tree, err := tree_sitter_zscript.ParseWithOptions(context.Background(), source, tree_sitter_zscript.ParseOptions{IncludedRanges: ranges})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tree.Close()
+	root := tree.RootNode()
+	if root.HasError() {
+		t.Fatalf("tree has errors: %s", root.ToSexp())
+	}
+	var classes []string
+	for i := uint(0); i < root.NamedChildCount(); i++ {
+		n := root.NamedChild(i)
+		name := n.ChildByFieldName(tree_sitter_zscript.FieldName)
+		classes = append(classes, name.Utf8Text(source))
+		if i == 1 && n.StartPosition().Row != 6 {
+			t.Errorf("B starts on row %d, want 6", n.StartPosition().Row)
+		}
+	}
+	if strings.Join(classes, " ") != "A B" {
+		t.Errorf("classes = %v, want [A B]", classes)
+	}
+
+	// The pooled parser must not keep the ranges.
+	plain, err := tree_sitter_zscript.Parse(context.Background(), []byte("class C {}"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer plain.Close()
+	if plain.RootNode().NamedChildCount() != 1 {
+		t.Errorf("later parse: %s", plain.RootNode().ToSexp())
+	}
+
+	_, err = tree_sitter_zscript.ParseWithOptions(context.Background(), source, tree_sitter_zscript.ParseOptions{
+		IncludedRanges: []tree_sitter.Range{{StartByte: 0, EndByte: uint(len(source)) + 1}},
+	})
+	if err == nil {
+		t.Error("range past the source: no error")
+	}
+}
+
+func TestParseWithOptionsSkipExtras(t *testing.T) {
+	source := []byte("// leading\nclass A { /* inner */ int x; }\n")
+	count := func(tree *tree_sitter_zscript.Tree, v tree_sitter_zscript.Visitor) int {
+		n := 0
+		v.On(tree_sitter_zscript.NodeComment, func(*tree_sitter.Node) tree_sitter_zscript.WalkAction {
+			n++
+			return tree_sitter_zscript.WalkContinue
+		})
+		tree.Visit(&v)
+		return n
+	}
+
+	tree, err := tree_sitter_zscript.ParseWithOptions(context.Background(), source, tree_sitter_zscript.ParseOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tree.Close()
+	if got := count(tree, tree_sitter_zscript.Visitor{}); got != 2 {
+		t.Errorf("default: visited %d comments, want 2", got)
+	}
+	if got := count(tree, tree_sitter_zscript.Visitor{SkipExtras: true}); got != 0 {
+		t.Errorf("Visitor.SkipExtras: visited %d comments, want 0", got)
+	}
+
+	skipping, err := tree_sitter_zscript.ParseWithOptions(context.Background(), source, tree_sitter_zscript.ParseOptions{SkipExtras: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer skipping.Close()
+	if got := count(skipping, tree_sitter_zscript.Visitor{}); got != 0 {
+		t.Errorf("ParseOptions.SkipExtras: visited %d comments, want 0", got)
+	}
+	// Walking a comment directly still visits it.
+	comment := skipping.RootNode().NamedChild(0)
+	visited := false
+	v := tree_sitter_zscript.Visitor{SkipExtras: true}
+	v.On(tree_sitter_zscript.NodeComment, func(*tree_sitter.Node) tree_sitter_zscript.WalkAction {
+		visited = true
+		return tree_sitter_zscript.WalkContinue
+	})
+	tree_sitter_zscript.Walk(comment, &v)
+	if !visited {
+		t.Error("Walk from a comment did not visit it")
+	}
+}
@@ -0,0 +1,191 @@
+package tree_sitter_zscript
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"runtime"
+	"sync"
+
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+)
+
+// Tree is a parse tree together with the source it was parsed from. Call
+// Close when done with it.
+type Tree struct {
+	*tree_sitter.Tree
+	// Source is the parsed text. It must not be modified while the tree is
+	// in use.
+	Source []byte
+	// Path is the file the source was read from, if any.
+	Path string
+	// SkipExtras makes Visit leave out comments. ParseWithOptions sets it.
+	SkipExtras bool
+	// Dialect is the version of ZScript the source is written for; its
+	// Diagnostics method reports the syntax that version lacks.
+	// ParseWithOptions sets it.
+	Dialect Dialect
+}
+
+// ErrParseFailed is returned when the parser gives up without producing a
+// tree for a reason other than cancellation.
+var ErrParseFailed = errors.New("zscript: parse failed")
+
+var language = sync.OnceValue(func() *tree_sitter.Language {
+	return tree_sitter.NewLanguage(Language())
+})
+
+// GetLanguage returns the shared tree-sitter Language for this grammar.
+func GetLanguage() *tree_sitter.Language {
+	return language()
+}
+
+// pooledParser owns a parser for the lifetime of its pool entry. The pool
+// may drop entries at any time, so the parser is closed by a finalizer.
+type pooledParser struct {
+	parser *tree_sitter.Parser
+	err    error
+}
+
+var parserPool = sync.Pool{
+	New: func() any {
+		parser, err := newParser()
+		p := &pooledParser{parser: parser, err: err}
+		if parser != nil {
+			runtime.SetFinalizer(p, func(p *pooledParser) { p.parser.Close() })
+		}
+		return p
+	},
+}
+
+// Parse parses source with a parser from an internal pool. Parsing stops
+// early and returns ctx.Err() if ctx is cancelled or its deadline passes,
+// so a timeout set with context.WithTimeout bounds the time spent on a
+// pathological input.
+func Parse(ctx context.Context, source []byte) (*Tree, error) {
+	return parse(ctx, source, nil)
+}
+
+// parse parses source, reusing the unchanged parts of old if it is not
+// nil. old must already have been edited to match source.
+func parse(ctx context.Context, source []byte, old *tree_sitter.Tree) (*Tree, error) {
+	tree, err := parseInput(ctx, readSource(source), old)
+	if err != nil {
+		return nil, err
+	}
+	return &Tree{Tree: tree, Source: source}, nil
+}
+
+// readSource returns a read function for parseInput and parseWith that
+// returns source.
+func readSource(source []byte) func(int, tree_sitter.Point) []byte {
+	return func(offset int, _ tree_sitter.Point) []byte {
+		if offset >= len(source) {
+			return nil
+		}
+		return source[offset:]
+	}
+}
+
+// parseInput parses the text returned by read with a pooled parser.
+func parseInput(ctx context.Context, read func(int, tree_sitter.Point) []byte, old *tree_sitter.Tree) (*tree_sitter.Tree, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	p := parserPool.Get().(*pooledParser)
+	if p.err != nil {
+		return nil, p.err
+	}
+	defer parserPool.Put(p)
+	return parseWith(ctx, p.parser, read, old)
+}
+
+// parseWith parses the text returned by read with parser, stopping early
+// if ctx ends.
+func parseWith(ctx context.Context, parser *tree_sitter.Parser, read func(int, tree_sitter.Point) []byte, old *tree_sitter.Tree) (*tree_sitter.Tree, error) {
+	options := tree_sitter.ParseOptions{
+		ProgressCallback: func(tree_sitter.ParseState) bool {
+			return ctx.Err() != nil
+		},
+	}
+	tree := parser.ParseWithOptions(read, old, &options)
+	if tree == nil {
+		// A cancelled parse leaves state behind that would otherwise be
+		// resumed by the next caller.
+		parser.Reset()
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		return nil, ErrParseFailed
+	}
+	return tree, nil
+}
+
+// ChunkSize is the number of bytes ParseReaderAt reads at a time.
+const ChunkSize = 64 << 10
+
+// ParseReaderAt parses the first size bytes of r, reading them in chunks
+// of ChunkSize, so that a large lump stored in an archive or file can be
+// parsed without first being read into a single buffer. The returned tree
+// does not hold the text; use NodeText to read the text of its nodes.
+//
+// The tree-sitter binding keeps the chunks it has read until parsing
+// finishes, so the text is still held in memory during the parse, though
+// not in one contiguous allocation.
+func ParseReaderAt(ctx context.Context, r io.ReaderAt, size int64) (*tree_sitter.Tree, error) {
+	buf := make([]byte, ChunkSize)
+	var readErr error
+	tree, err := parseInput(ctx, func(offset int, _ tree_sitter.Point) []byte {
+		if readErr != nil || int64(offset) >= size {
+			return nil
+		}
+		n, err := r.ReadAt(buf[:min(int64(len(buf)), size-int64(offset))], int64(offset))
+		if err != nil && !(err == io.EOF && n > 0) {
+			readErr = err
+			if n == 0 {
+				return nil
+			}
+		}
+		return buf[:n]
+	}, nil)
+	if err != nil {
+		return nil, err
+	}
+	if readErr != nil {
+		tree.Close()
+		return nil, readErr
+	}
+	return tree, nil
+}
+
+// NodeText reads the text spanned by node from r, the input a tree was
+// parsed from by ParseReaderAt.
+func NodeText(r io.ReaderAt, node *tree_sitter.Node) (string, error) {
+	buf := make([]byte, node.EndByte()-node.StartByte())
+	if _, err := r.ReadAt(buf, int64(node.StartByte())); err != nil && err != io.EOF {
+		return "", err
+	}
+	return string(buf), nil
+}
+
+// ParseFile reads and parses the file at path.
+func ParseFile(path string) (*Tree, error) {
+	return ParseFileContext(context.Background(), path)
+}
+
+// ParseFileContext is like ParseFile but stops early and returns ctx.Err()
+// if ctx is cancelled or its deadline passes while parsing.
+func ParseFileContext(ctx context.Context, path string) (*Tree, error) {
+	source, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	tree, err := Parse(ctx, source)
+	if err != nil {
+		return nil, err
+	}
+	tree.Path = path
+	return tree, nil
+}
@@ -0,0 +1,145 @@
+package tree_sitter_zscript_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"testing/iotest"
+	"time"
+
+	tree_sitter_zscript "github.com/jlcrochet/tree-sitter-zscript/bindings/go"
+)
+
+func TestParse(t *testing.T) {
+	tree, err := tree_sitter_zscript.Parse(context.Background(), []byte("class A : Actor {}"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tree.Close()
+
+	root := tree.RootNode()
+	if root.HasError() {
+		t.Errorf("Unexpected error in %s", root.ToSexp())
+	}
+	if got := root.NamedChild(0).Kind(); got != tree_sitter_zscript.NodeClassDefinition {
+		t.Errorf("Kind() = %q", got)
+	}
+}
+
+func TestParseConcurrently(t *testing.T) {
+	var wg sync.WaitGroup
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			tree, err := tree_sitter_zscript.Parse(context.Background(), []byte("struct S { int x; }"))
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			tree.Close()
+		}()
+	}
+	wg.Wait()
+}
+
+func TestParseCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := tree_sitter_zscript.Parse(ctx, []byte("class A {}")); !errors.Is(err, context.Canceled) {
+		t.Errorf("err = %v, want context.Canceled", err)
+	}
+}
+
+func TestParseTimeout(t *testing.T) {
+	source := []byte(strings.Repeat("class A { void F() { x = 1 + 2 * (3 - y); } }\n", 100000))
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	if _, err := tree_sitter_zscript.Parse(ctx, source); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("err = %v, want context.DeadlineExceeded", err)
+	}
+
+	// The pooled parser must not resume the abandoned parse.
+	tree, err := tree_sitter_zscript.Parse(context.Background(), []byte("class B {}"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tree.Close()
+	if got := tree.RootNode().ToSexp(); strings.Contains(got, "ERROR") || tree.RootNode().EndByte() != 10 {
+		t.Errorf("parse after timeout = %s", got)
+	}
+}
+
+func TestParseFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "zscript.zs")
+	if err := os.WriteFile(path, []byte(`version "4.10"`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tree, err := tree_sitter_zscript.ParseFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tree.Close()
+
+	if tree.Path != path || string(tree.Source) != `version "4.10"` {
+		t.Errorf("Path = %q, Source = %q", tree.Path, tree.Source)
+	}
+}
+
+// countingReader records the largest read made from it.
+type countingReader struct {
+	r       *bytes.Reader
+	maxRead int
+}
+
+func (c *countingReader) ReadAt(p []byte, off int64) (int, error) {
+	c.maxRead = max(c.maxRead, len(p))
+	return c.r.ReadAt(p, off)
+}
+
+func TestParseReaderAt(t *testing.T) {
+	source := []byte(strings.Repeat("class A : Actor { int x; void F() { x = 1; } }\n", 4000))
+	r := &countingReader{r: bytes.NewReader(source)}
+	tree, err := tree_sitter_zscript.ParseReaderAt(context.Background(), r, int64(len(source)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tree.Close()
+
+	want, err := tree_sitter_zscript.Parse(context.Background(), source)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer want.Close()
+	if got := tree.RootNode().ToSexp(); got != want.RootNode().ToSexp() {
+		t.Error("ParseReaderAt() tree differs from Parse()")
+	}
+	if r.maxRead > tree_sitter_zscript.ChunkSize {
+		t.Errorf("read %d bytes at once, want at most %d", r.maxRead, tree_sitter_zscript.ChunkSize)
+	}
+
+	last := tree.RootNode().NamedChild(tree.RootNode().NamedChildCount() - 1)
+	if text, err := tree_sitter_zscript.NodeText(r, last.ChildByFieldName("name")); err != nil || text != "A" {
+		t.Errorf("NodeText() = %q, %v", text, err)
+	}
+}
+
+func TestParseReaderAtError(t *testing.T) {
+	r := iotest.ErrReader(errors.New("boom"))
+	if _, err := tree_sitter_zscript.ParseReaderAt(context.Background(), readerAt{r}, 100); err == nil || err.Error() != "boom" {
+		t.Errorf("err = %v, want boom", err)
+	}
+}
+
+type readerAt struct{ r io.Reader }
+
+func (r readerAt) ReadAt(p []byte, _ int64) (int, error) {
+	return r.r.Read(p)
+}
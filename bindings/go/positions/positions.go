@@ -0,0 +1,262 @@
+// Package positions converts between the ways of locating a character in
+// a source text: byte offsets, which tree-sitter and the slices of the
+// text use, offsets counted in code points or in UTF-16 code units, as
+// editors and the Language Server Protocol count, and zero-based lines
+// with a column in any of those units.
+//
+// An Index is built by searching the text for newlines, and counting the
+// code points of each line if the text is not all ASCII. Conversions then
+// take a binary search over the lines and, unless the text is ASCII, a
+// scan of the line up to the column; on ASCII text every unit is a byte.
+package positions
+
+import (
+	"bytes"
+	"encoding/binary"
+	"sort"
+	"unicode/utf8"
+
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+)
+
+// Encoding selects the unit in which columns and offsets count
+// characters.
+type Encoding int
+
+const (
+	// UTF16 counts UTF-16 code units, the default of the Language Server
+	// Protocol.
+	UTF16 Encoding = iota
+	// UTF8 counts bytes, as tree-sitter points do.
+	UTF8
+	// UTF32 counts code points.
+	UTF32
+)
+
+func (e Encoding) String() string {
+	switch e {
+	case UTF8:
+		return "utf-8"
+	case UTF32:
+		return "utf-32"
+	}
+	return "utf-16"
+}
+
+// Position is a zero-based line and character, as in the Language Server
+// Protocol. The unit of Character depends on the encoding it is used with.
+type Position struct {
+	Line      uint
+	Character uint
+}
+
+// Index locates the lines of a text. It is safe for concurrent use.
+type Index struct {
+	source []byte
+	// lines holds the byte offset at which each line starts.
+	lines []uint
+	// runes and units hold the number of code points and of UTF-16 code
+	// units before each line, or are nil if the text is ASCII.
+	runes, units []uint
+}
+
+// NewIndex returns the index of source, which must not be modified while
+// the index is in use. Lines end at "\n"; a "\r" before it is part of the
+// line.
+func NewIndex(source []byte) *Index {
+	x := &Index{source: source, lines: make([]uint, 1, len(source)/32+1)}
+	for i := 0; ; {
+		n := bytes.IndexByte(source[i:], '\n')
+		if n < 0 {
+			break
+		}
+		i += n + 1
+		x.lines = append(x.lines, uint(i))
+	}
+	ascii := isASCII(source)
+	if ascii {
+		return x
+	}
+	x.runes = make([]uint, len(x.lines))
+	x.units = make([]uint, len(x.lines))
+	for i := 1; i < len(x.lines); i++ {
+		line := source[x.lines[i-1]:x.lines[i]]
+		x.runes[i] = x.runes[i-1] + Count(line, UTF32)
+		x.units[i] = x.units[i-1] + Count(line, UTF16)
+	}
+	return x
+}
+
+// isASCII reports whether text is all ASCII, testing eight bytes at a
+// time.
+func isASCII(text []byte) bool {
+	for ; len(text) >= 8; text = text[8:] {
+		if binary.LittleEndian.Uint64(text)&0x8080808080808080 != 0 {
+			return false
+		}
+	}
+	for _, b := range text {
+		if b >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
+
+// Source returns the text of x.
+func (x *Index) Source() []byte {
+	return x.source
+}
+
+// LineCount returns the number of lines, one more than the number of
+// newlines.
+func (x *Index) LineCount() int {
+	return len(x.lines)
+}
+
+// LineStart returns the byte offset at which line starts, or the length
+// of the text for lines past its end.
+func (x *Index) LineStart(line uint) uint {
+	if line >= uint(len(x.lines)) {
+		return uint(len(x.source))
+	}
+	return x.lines[line]
+}
+
+// Line returns the text of line without its newline, or nil for lines
+// past the end of the text.
+func (x *Index) Line(line uint) []byte {
+	if line >= uint(len(x.lines)) {
+		return nil
+	}
+	end := uint(len(x.source))
+	if line+1 < uint(len(x.lines)) {
+		end = x.lines[line+1] - 1
+	}
+	return x.source[x.lines[line]:end]
+}
+
+// line returns the line holding the byte offset.
+func (x *Index) line(offset uint) uint {
+	return uint(sort.Search(len(x.lines), func(i int) bool { return x.lines[i] > offset }) - 1)
+}
+
+// Point returns the line and byte column of a byte offset, clamped to the
+// text.
+func (x *Index) Point(offset uint) tree_sitter.Point {
+	offset = min(offset, uint(len(x.source)))
+	line := x.line(offset)
+	return tree_sitter.Point{Row: line, Column: offset - x.lines[line]}
+}
+
+// PointOffset returns the byte offset of a point. Columns past the end of
+// a line refer to the end of the line, and lines past the end of the text
+// to the end of the text.
+func (x *Index) PointOffset(p tree_sitter.Point) uint {
+	return x.Offset(Position{Line: p.Row, Character: p.Column}, UTF8)
+}
+
+// Position returns the position of a byte offset, clamped to the text,
+// with its character counted in enc.
+func (x *Index) Position(offset uint, enc Encoding) Position {
+	p := x.Point(offset)
+	return x.PointPosition(p, enc)
+}
+
+// PointPosition converts a point, whose column counts bytes, to a
+// position whose character is counted in enc.
+func (x *Index) PointPosition(p tree_sitter.Point, enc Encoding) Position {
+	if enc == UTF8 || x.runes == nil {
+		return Position{Line: p.Row, Character: p.Column}
+	}
+	line := x.Line(p.Row)
+	return Position{Line: p.Row, Character: Count(line[:min(p.Column, uint(len(line)))], enc)}
+}
+
+// Offset returns the byte offset of pos, whose character is counted in
+// enc. Characters past the end of a line refer to the end of the line,
+// and lines past the end of the text to the end of the text.
+func (x *Index) Offset(pos Position, enc Encoding) uint {
+	if pos.Line >= uint(len(x.lines)) {
+		return uint(len(x.source))
+	}
+	if x.runes == nil {
+		enc = UTF8
+	}
+	return x.lines[pos.Line] + Column(x.Line(pos.Line), pos.Character, enc)
+}
+
+// Units returns the offset, counted in enc from the start of the text, of
+// a byte offset, clamped to the text.
+func (x *Index) Units(offset uint, enc Encoding) uint {
+	offset = min(offset, uint(len(x.source)))
+	if enc == UTF8 || x.runes == nil {
+		return offset
+	}
+	line := x.line(offset)
+	return x.before(enc)[line] + Count(x.source[x.lines[line]:offset], enc)
+}
+
+// ByteOffset returns the byte offset of an offset counted in enc from the
+// start of the text. Offsets past the end refer to the end, and those
+// inside a code point to its end.
+func (x *Index) ByteOffset(units uint, enc Encoding) uint {
+	if enc == UTF8 || x.runes == nil {
+		return min(units, uint(len(x.source)))
+	}
+	before := x.before(enc)
+	line := sort.Search(len(before), func(i int) bool { return before[i] > units }) - 1
+	start := x.lines[line]
+	end := uint(len(x.source))
+	if line+1 < len(x.lines) {
+		end = x.lines[line+1]
+	}
+	return start + Column(x.source[start:end], units-before[line], enc)
+}
+
+// before returns the number of units of enc before each line.
+func (x *Index) before(enc Encoding) []uint {
+	if enc == UTF32 {
+		return x.runes
+	}
+	return x.units
+}
+
+// Count returns the length of text counted in enc.
+func Count(text []byte, enc Encoding) uint {
+	switch enc {
+	case UTF8:
+		return uint(len(text))
+	case UTF32:
+		return uint(utf8.RuneCount(text))
+	}
+	var n uint
+	for _, r := range string(text) {
+		n++
+		if r >= 0x10000 {
+			n++
+		}
+	}
+	return n
+}
+
+// Column returns the byte offset within line of the character, counted in
+// enc, clamped to the line. A character inside a code point, such as the
+// second UTF-16 unit of a surrogate pair, refers to the end of the code
+// point.
+func Column(line []byte, character uint, enc Encoding) uint {
+	if enc == UTF8 {
+		return min(character, uint(len(line)))
+	}
+	var units uint
+	for i, r := range string(line) {
+		if units >= character {
+			return uint(i)
+		}
+		units++
+		if enc == UTF16 && r >= 0x10000 {
+			units++
+		}
+	}
+	return uint(len(line))
+}
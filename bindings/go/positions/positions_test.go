@@ -0,0 +1,136 @@
+package positions_test
+
+import (
+	"testing"
+
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/positions"
+)
+
+// The second line holds "é", two bytes and one UTF-16 unit, and "😀",
+// four bytes and two UTF-16 units.
+const text = "class A {\n\t\"é😀\" x;\r\n}"
+
+func TestLines(t *testing.T) {
+	x := positions.NewIndex([]byte(text))
+	if n := x.LineCount(); n != 3 {
+		t.Fatalf("LineCount = %d, want 3", n)
+	}
+	for line, want := range []string{"class A {", "\t\"é😀\" x;\r", "}", ""} {
+		if got := string(x.Line(uint(line))); got != want {
+			t.Errorf("Line(%d) = %q, want %q", line, got, want)
+		}
+	}
+	if got := x.LineStart(2); got != 24 {
+		t.Errorf("LineStart(2) = %d, want 24", got)
+	}
+	if got := x.LineStart(9); got != uint(len(text)) {
+		t.Errorf("LineStart(9) = %d, want %d", got, len(text))
+	}
+}
+
+func TestPositions(t *testing.T) {
+	x := positions.NewIndex([]byte(text))
+	tests := []struct {
+		offset          uint
+		point           tree_sitter.Point
+		utf16, utf32    uint
+		absolute16, a32 uint
+	}{
+		{0, tree_sitter.Point{Row: 0, Column: 0}, 0, 0, 0, 0},
+		{10, tree_sitter.Point{Row: 1, Column: 0}, 0, 0, 10, 10},
+		{12, tree_sitter.Point{Row: 1, Column: 2}, 2, 2, 12, 12},
+		// After é.
+		{14, tree_sitter.Point{Row: 1, Column: 4}, 3, 3, 13, 13},
+		// After 😀.
+		{18, tree_sitter.Point{Row: 1, Column: 8}, 5, 4, 15, 14},
+		{24, tree_sitter.Point{Row: 2, Column: 0}, 0, 0, 21, 20},
+		{99, tree_sitter.Point{Row: 2, Column: 1}, 1, 1, 22, 21},
+	}
+	for _, tt := range tests {
+		if got := x.Point(tt.offset); got != tt.point {
+			t.Errorf("Point(%d) = %v, want %v", tt.offset, got, tt.point)
+		}
+		for _, c := range []struct {
+			enc       positions.Encoding
+			character uint
+			absolute  uint
+		}{
+			{positions.UTF8, tt.point.Column, min(tt.offset, uint(len(text)))},
+			{positions.UTF16, tt.utf16, tt.absolute16},
+			{positions.UTF32, tt.utf32, tt.a32},
+		} {
+			want := positions.Position{Line: tt.point.Row, Character: c.character}
+			if got := x.Position(tt.offset, c.enc); got != want {
+				t.Errorf("Position(%d, %s) = %v, want %v", tt.offset, c.enc, got, want)
+			}
+			if got := x.Offset(want, c.enc); got != min(tt.offset, uint(len(text))) {
+				t.Errorf("Offset(%v, %s) = %d, want %d", want, c.enc, got, tt.offset)
+			}
+			if got := x.Units(tt.offset, c.enc); got != c.absolute {
+				t.Errorf("Units(%d, %s) = %d, want %d", tt.offset, c.enc, got, c.absolute)
+			}
+			if got := x.ByteOffset(c.absolute, c.enc); got != min(tt.offset, uint(len(text))) {
+				t.Errorf("ByteOffset(%d, %s) = %d, want %d", c.absolute, c.enc, got, tt.offset)
+			}
+		}
+	}
+}
+
+func TestClamping(t *testing.T) {
+	x := positions.NewIndex([]byte(text))
+	// Past the end of the second line, before its "\n".
+	if got := x.Offset(positions.Position{Line: 1, Character: 50}, positions.UTF16); got != 23 {
+		t.Errorf("past the line = %d, want 23", got)
+	}
+	if got := x.Offset(positions.Position{Line: 5}, positions.UTF16); got != uint(len(text)) {
+		t.Errorf("past the text = %d, want %d", got, len(text))
+	}
+	// Inside the surrogate pair of 😀.
+	if got := x.Offset(positions.Position{Line: 1, Character: 4}, positions.UTF16); got != 18 {
+		t.Errorf("inside a surrogate pair = %d, want 18", got)
+	}
+	if got := x.PointOffset(tree_sitter.Point{Row: 0, Column: 40}); got != 9 {
+		t.Errorf("PointOffset past the line = %d, want 9", got)
+	}
+}
+
+func TestASCII(t *testing.T) {
+	x := positions.NewIndex([]byte("a\nbc\n"))
+	if got := x.Position(4, positions.UTF16); got != (positions.Position{Line: 1, Character: 2}) {
+		t.Errorf("Position = %v", got)
+	}
+	if got := x.Offset(positions.Position{Line: 1, Character: 9}, positions.UTF32); got != 4 {
+		t.Errorf("Offset = %d, want 4", got)
+	}
+	if got := x.ByteOffset(3, positions.UTF16); got != 3 {
+		t.Errorf("ByteOffset = %d, want 3", got)
+	}
+}
+
+func TestCount(t *testing.T) {
+	for enc, want := range map[positions.Encoding]uint{positions.UTF8: 7, positions.UTF16: 4, positions.UTF32: 3} {
+		if got := positions.Count([]byte("aé😀"), enc); got != want {
+			t.Errorf("Count(%s) = %d, want %d", enc, got, want)
+		}
+	}
+	if got := positions.Column([]byte("aé😀b"), 4, positions.UTF16); got != 7 {
+		t.Errorf("Column = %d, want 7", got)
+	}
+}
+
+func BenchmarkNewIndex(b *testing.B) {
+	for name, line := range map[string]string{"ascii": "\tint x = 1; // e\n", "utf-8": "\tint x = 1; // é\n"} {
+		b.Run(name, func(b *testing.B) {
+			text := make([]byte, 0, 1<<20)
+			for len(text) < 1<<20 {
+				text = append(text, line...)
+			}
+			b.SetBytes(int64(len(text)))
+			for range b.N {
+				positions.NewIndex(text)
+			}
+		})
+	}
+}
@@ -0,0 +1,163 @@
+package project
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+
+	zscript "github.com/jlcrochet/tree-sitter-zscript/bindings/go"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/hierarchy"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/zscriptast"
+)
+
+// CheckIncludes reports the #include directives the project can do
+// without: those of a file already included through a path spelled
+// differently, which GZDoom skips, and those of a file that declares
+// nothing the rest of the project refers to, counting the files it
+// includes in turn; an include is reported once, as a duplicate if it is
+// one. Names are referred to by identifiers and by string and name
+// literals; used adds the names that other lumps refer to, such as the
+// event handlers MAPINFO registers. Files that extend a class or struct,
+// declare a class that replaces another, or declare an actor, which maps
+// and consoles may spawn by name, take effect unreferenced and are always
+// used; h tells the actors, the classes deriving from Actor, apart, and
+// may be nil to take none for one. Diagnostics are sorted by path and
+// position.
+func (p *Project) CheckIncludes(h *hierarchy.Hierarchy, used ...string) []Diagnostic {
+	c := &includeChecker{p: p, h: h, refs: map[string][]*File{}, external: map[string]bool{}, state: map[*File]loadState{}, used: map[*File]bool{}}
+	for _, name := range used {
+		c.external[strings.ToLower(name)] = true
+	}
+	for _, f := range p.Files {
+		c.collect(f)
+	}
+
+	diags := append([]Diagnostic(nil), p.duplicates...)
+	type at struct {
+		path  string
+		start uint
+	}
+	duplicate := map[at]bool{}
+	for _, d := range p.duplicates {
+		duplicate[at{d.Path, d.Range.StartByte}] = true
+	}
+	for _, f := range p.Files {
+		for _, inc := range zscriptast.NewFile(f.Tree.Tree, f.Tree.Source).Includes() {
+			if duplicate[at{f.Path, inc.Range().StartByte}] {
+				continue
+			}
+			target, ok := p.ResolveInclude(f.Path, inc.Path())
+			if t := p.File(target); ok && t != nil && !c.isUsed(t) {
+				diags = append(diags, Diagnostic{
+					Path:    f.Path,
+					Range:   inc.Range(),
+					Message: fmt.Sprintf("included file %q declares nothing the project uses", inc.Path()),
+				})
+			}
+		}
+	}
+	sort.SliceStable(diags, func(i, j int) bool {
+		if diags[i].Path != diags[j].Path {
+			return diags[i].Path < diags[j].Path
+		}
+		return diags[i].Range.StartByte < diags[j].Range.StartByte
+	})
+	return diags
+}
+
+type includeChecker struct {
+	p *Project
+	h *hierarchy.Hierarchy
+	// refs maps each lowercased name referred to to the files that refer
+	// to it, and external holds the names other lumps refer to.
+	refs     map[string][]*File
+	external map[string]bool
+	state    map[*File]loadState
+	used     map[*File]bool
+}
+
+// collect records the names f refers to.
+func (c *includeChecker) collect(f *File) {
+	seen := map[string]bool{}
+	add := func(name string) {
+		if key := strings.ToLower(name); name != "" && !seen[key] {
+			seen[key] = true
+			c.refs[key] = append(c.refs[key], f)
+		}
+	}
+	var v zscript.Visitor
+	identifier := func(n *tree_sitter.Node) zscript.WalkAction {
+		add(n.Utf8Text(f.Tree.Source))
+		return zscript.WalkContinue
+	}
+	v.On(zscript.NodeIdentifier, identifier)
+	v.On(zscript.NodeTypeIdentifier, identifier)
+	v.On(zscript.NodeFieldIdentifier, identifier)
+	v.On(zscript.NodeStringContent, identifier)
+	v.On(zscript.NodeNameLiteral, func(n *tree_sitter.Node) zscript.WalkAction {
+		add(strings.Trim(n.Utf8Text(f.Tree.Source), "'"))
+		return zscript.WalkSkipChildren
+	})
+	zscript.Walk(f.Tree.RootNode(), &v)
+}
+
+// isUsed reports whether f, or a file it includes, takes effect or
+// declares a name that another file or a lump refers to.
+func (c *includeChecker) isUsed(f *File) bool {
+	switch c.state[f] {
+	case visiting:
+		return false
+	case visited:
+		return c.used[f]
+	}
+	c.state[f] = visiting
+	used := c.declaresUsed(f)
+	for _, inc := range f.Includes {
+		if t := c.p.File(inc); t != nil && c.isUsed(t) {
+			used = true
+		}
+	}
+	c.state[f] = visited
+	c.used[f] = used
+	return used
+}
+
+func (c *includeChecker) declaresUsed(f *File) bool {
+	file := zscriptast.NewFile(f.Tree.Tree, f.Tree.Source)
+	var names []string
+	for _, d := range file.Classes() {
+		if d.IsExtend() || d.Replaces() != "" || c.h != nil && c.h.IsSubclassOf(d.Name(), "Actor") {
+			return true
+		}
+		names = append(names, d.Name())
+	}
+	for _, d := range file.Structs() {
+		if d.IsExtend() {
+			return true
+		}
+		names = append(names, d.Name())
+	}
+	for _, d := range file.Enums() {
+		names = append(names, d.Name())
+		for _, m := range d.Members() {
+			names = append(names, m.Name())
+		}
+	}
+	for _, d := range file.Consts() {
+		names = append(names, d.Name())
+	}
+	for _, name := range names {
+		key := strings.ToLower(name)
+		if c.external[key] {
+			return true
+		}
+		for _, other := range c.refs[key] {
+			if other != f {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,463 @@
+// Package project loads a multi-file ZScript project by following #include
+// directives from one or more root lumps, and finds the includes a project
+// can do without.
+package project
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+
+	zscript "github.com/jlcrochet/tree-sitter-zscript/bindings/go"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/zscriptast"
+)
+
+// File is a parsed file of a project.
+type File struct {
+	// Path is the slash-separated path of the file within the project's
+	// file system, spelled as it is stored there.
+	Path string
+	Tree *zscript.Tree
+	// Includes are the resolved paths of the files this file includes, in
+	// the order they appear.
+	Includes []string
+}
+
+// Diagnostic is a problem found while following includes.
+type Diagnostic struct {
+	Path    string
+	Range   tree_sitter.Range
+	Message string
+}
+
+func (d Diagnostic) String() string {
+	return fmt.Sprintf("%s:%d:%d: %s", d.Path, d.Range.StartPoint.Row+1, d.Range.StartPoint.Column+1, d.Message)
+}
+
+// Project is a set of parsed files. Once loaded, it may be read from
+// several goroutines at once.
+type Project struct {
+	FS fs.FS
+	// Files are in dependency order: every file comes after the files it
+	// includes, except where includes form a cycle.
+	Files       []*File
+	Diagnostics []Diagnostic
+
+	byPath map[string]*File
+	// duplicates are the includes of files already included through
+	// another path, in the order the loader meets them.
+	duplicates []Diagnostic
+}
+
+// New returns a project of files already parsed, kept in the order given,
+// for tools such as editors that hold the trees of files themselves. It
+// has no file system, and Close closes the trees of files.
+func New(files ...*File) *Project {
+	p := &Project{Files: files, byPath: map[string]*File{}}
+	for _, f := range files {
+		key := strings.ToLower(cleanPath(f.Path))
+		if p.byPath[key] == nil {
+			p.byPath[key] = f
+		}
+	}
+	return p
+}
+
+// File returns the file with the given path, matched case-insensitively,
+// or nil.
+func (p *Project) File(name string) *File {
+	return p.byPath[strings.ToLower(cleanPath(name))]
+}
+
+// Tree returns the parse tree of the file with the given path, or nil. It
+// suits the packages that take a function from the path a declaration
+// records to its tree, such as types and eval.
+func (p *Project) Tree(name string) *zscript.Tree {
+	if f := p.File(name); f != nil {
+		return f.Tree
+	}
+	return nil
+}
+
+// Close releases the parse trees of every file.
+func (p *Project) Close() {
+	for _, f := range p.Files {
+		f.Tree.Close()
+	}
+}
+
+// IndexOptions controls how a project is loaded.
+type IndexOptions struct {
+	// Workers is the number of files parsed at once. Zero or less means
+	// runtime.GOMAXPROCS(0).
+	Workers int
+	// FileTimeout, if positive, bounds the time spent parsing each file.
+	// An included file that takes longer is left out of the project and
+	// reported as a diagnostic; a root that takes longer is an error
+	// wrapping context.DeadlineExceeded.
+	FileTimeout time.Duration
+}
+
+// Load parses the given root files from fsys and every file they include.
+// A root that cannot be read is an error; an include that cannot be read
+// is reported as a diagnostic.
+func Load(ctx context.Context, fsys fs.FS, roots ...string) (*Project, error) {
+	return LoadWithOptions(ctx, fsys, IndexOptions{}, roots...)
+}
+
+// LoadWithOptions is like Load but parses files in parallel as opts
+// permits. fsys must be safe for concurrent use. The result does not
+// depend on the number of workers.
+func LoadWithOptions(ctx context.Context, fsys fs.FS, opts IndexOptions, roots ...string) (*Project, error) {
+	var names []string
+	for _, root := range roots {
+		name, ok := resolve(fsys, cleanPath(root))
+		if !ok {
+			return nil, fmt.Errorf("project: %s: %w", root, fs.ErrNotExist)
+		}
+		names = append(names, name)
+	}
+
+	workers := opts.Workers
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	l := &loader{
+		parsed:   parseAll(ctx, fsys, names, workers, opts.FileTimeout),
+		project:  &Project{FS: fsys, byPath: map[string]*File{}},
+		state:    map[string]loadState{},
+		included: map[string]includedAt{},
+		timeout:  opts.FileTimeout,
+	}
+	for _, name := range names {
+		if l.state[strings.ToLower(name)] != unvisited {
+			continue
+		}
+		if err := l.load(name); err != nil {
+			for _, f := range l.parsed {
+				if f.tree != nil {
+					f.tree.Close()
+				}
+			}
+			return nil, err
+		}
+	}
+	return l.project, nil
+}
+
+// LoadDir loads the project rooted at dir, starting from the ZSCRIPT lumps
+// returned by FindRoots.
+func LoadDir(ctx context.Context, dir string) (*Project, error) {
+	fsys := os.DirFS(dir)
+	roots, err := FindRoots(fsys)
+	if err != nil {
+		return nil, err
+	}
+	if len(roots) == 0 {
+		return nil, fmt.Errorf("project: no zscript lump in %s", dir)
+	}
+	return Load(ctx, fsys, roots...)
+}
+
+// FindRoots returns the files at the top of fsys that GZDoom loads as
+// ZScript roots, those IsRoot accepts, sorted by name.
+func FindRoots(fsys fs.FS) ([]string, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+	var roots []string
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+		if IsRoot(e.Name()) {
+			roots = append(roots, e.Name())
+		}
+	}
+	return roots, nil
+}
+
+// IsRoot reports whether a file named name at the top of a mod is a root
+// GZDoom loads as ZScript: one named "zscript" with any extension, in any
+// case, or a lump of a WAD named so, which package archive numbers as in
+// "ZSCRIPT#1" when the WAD has several. The configuration file
+// zscript.toml of package config is not taken for one.
+func IsRoot(name string) bool {
+	base := strings.ToLower(name)
+	if i := strings.LastIndexByte(base, '#'); i >= 0 {
+		if _, err := strconv.Atoi(base[i+1:]); err == nil {
+			base = base[:i]
+		}
+	}
+	return base == "zscript" || strings.HasPrefix(base, "zscript.") && base != "zscript.toml"
+}
+
+type loadState int
+
+const (
+	unvisited loadState = iota
+	visiting
+	visited
+)
+
+// parsedFile is a file read and parsed by parseAll.
+type parsedFile struct {
+	name     string
+	tree     *zscript.Tree
+	includes []parsedInclude
+	err      error
+	// timedOut is set when parsing took longer than the file timeout.
+	timedOut bool
+}
+
+// parsedInclude is an #include directive with its resolved target, or ""
+// if the target does not exist.
+type parsedInclude struct {
+	path   string
+	target string
+	rng    tree_sitter.Range
+}
+
+// parseAll parses the roots and every file they include, transitively,
+// using the given number of workers. Files are keyed by lowercased path.
+func parseAll(ctx context.Context, fsys fs.FS, roots []string, workers int, timeout time.Duration) map[string]*parsedFile {
+	jobs := make(chan string)
+	results := make(chan *parsedFile)
+	for i := 0; i < workers; i++ {
+		go func() {
+			for name := range jobs {
+				results <- parseFile(ctx, fsys, name, timeout)
+			}
+		}()
+	}
+	defer close(jobs)
+
+	parsed := map[string]*parsedFile{}
+	seen := map[string]bool{}
+	var queue []string
+	enqueue := func(name string) {
+		if key := strings.ToLower(name); !seen[key] {
+			seen[key] = true
+			queue = append(queue, name)
+		}
+	}
+	for _, root := range roots {
+		enqueue(root)
+	}
+	for pending := 0; len(queue) > 0 || pending > 0; {
+		// Sending is disabled while the queue is empty by leaving send
+		// nil.
+		var send chan string
+		var next string
+		if len(queue) > 0 {
+			send, next = jobs, queue[0]
+		}
+		select {
+		case send <- next:
+			queue = queue[1:]
+			pending++
+		case f := <-results:
+			pending--
+			parsed[strings.ToLower(f.name)] = f
+			for _, inc := range f.includes {
+				if inc.target != "" {
+					enqueue(inc.target)
+				}
+			}
+		}
+	}
+	return parsed
+}
+
+func parseFile(ctx context.Context, fsys fs.FS, name string, timeout time.Duration) *parsedFile {
+	f := &parsedFile{name: name}
+	source, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		f.err = err
+		return f
+	}
+	parseCtx := ctx
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		parseCtx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	tree, err := zscript.Parse(parseCtx, source)
+	if err != nil {
+		f.timedOut = errors.Is(err, context.DeadlineExceeded) && ctx.Err() == nil
+		if f.timedOut {
+			err = fmt.Errorf("parsing took longer than %v: %w", timeout, err)
+		}
+		f.err = fmt.Errorf("project: %s: %w", name, err)
+		return f
+	}
+	tree.Path = name
+	f.tree = tree
+	for _, inc := range zscriptast.NewFile(tree.Tree, source).Includes() {
+		target, _ := resolveInclude(fsys, name, inc.Path())
+		f.includes = append(f.includes, parsedInclude{path: inc.Path(), target: target, rng: inc.Range()})
+	}
+	return f
+}
+
+// loader orders the parsed files and reports include problems, visiting
+// includes depth first in the order they appear.
+type loader struct {
+	parsed  map[string]*parsedFile
+	project *Project
+	state   map[string]loadState
+	stack   []string
+	// included holds the first include of each file included so far.
+	included map[string]includedAt
+	timeout  time.Duration
+}
+
+// includedAt is an #include directive of the file named from.
+type includedAt struct {
+	from string
+	inc  parsedInclude
+}
+
+func (l *loader) load(name string) error {
+	key := strings.ToLower(name)
+	l.state[key] = visiting
+	l.stack = append(l.stack, name)
+
+	parsed := l.parsed[key]
+	if parsed.err != nil {
+		return parsed.err
+	}
+	file := &File{Path: name, Tree: parsed.tree}
+
+	for _, inc := range parsed.includes {
+		if inc.target == "" {
+			l.diagnose(name, inc.rng, fmt.Sprintf("included file %q not found", inc.path))
+			continue
+		}
+		target := inc.target
+		if t := l.parsed[strings.ToLower(target)]; t != nil && t.timedOut {
+			l.diagnose(name, inc.rng, fmt.Sprintf("included file %q skipped: parsing took longer than %v", inc.path, l.timeout))
+			continue
+		}
+		file.Includes = append(file.Includes, target)
+		l.duplicate(name, inc)
+
+		switch l.state[strings.ToLower(target)] {
+		case visiting:
+			l.diagnose(name, inc.rng, "include cycle: "+l.cycle(target))
+		case unvisited:
+			if err := l.load(target); err != nil {
+				return err
+			}
+		}
+	}
+
+	l.stack = l.stack[:len(l.stack)-1]
+	l.state[key] = visited
+	l.project.Files = append(l.project.Files, file)
+	l.project.byPath[key] = file
+	return nil
+}
+
+// duplicate records inc, an include in the file name, for CheckIncludes
+// if the file it includes was already included through a path spelled
+// differently.
+func (l *loader) duplicate(name string, inc parsedInclude) {
+	key := strings.ToLower(inc.target)
+	first, ok := l.included[key]
+	if !ok {
+		l.included[key] = includedAt{name, inc}
+		return
+	}
+	if includeSpelling(first.inc.path) != includeSpelling(inc.path) {
+		p := first.inc.rng.StartPoint
+		l.project.duplicates = append(l.project.duplicates, Diagnostic{
+			Path:    name,
+			Range:   inc.rng,
+			Message: fmt.Sprintf("file %q is already included as %q at %s:%d:%d", inc.path, first.inc.path, first.from, p.Row+1, p.Column+1),
+		})
+	}
+}
+
+func includeSpelling(include string) string {
+	return strings.ToLower(strings.ReplaceAll(include, `\`, "/"))
+}
+
+func (l *loader) diagnose(name string, r tree_sitter.Range, message string) {
+	l.project.Diagnostics = append(l.project.Diagnostics, Diagnostic{Path: name, Range: r, Message: message})
+}
+
+// cycle describes the include chain from target back to itself.
+func (l *loader) cycle(target string) string {
+	for i, name := range l.stack {
+		if strings.EqualFold(name, target) {
+			return strings.Join(append(l.stack[i:len(l.stack):len(l.stack)], target), " -> ")
+		}
+	}
+	return target
+}
+
+// ResolveInclude returns the path of the file of p that an #include of
+// include written in the file from refers to, resolved as Load resolves
+// it. ok is false if no such file exists in p's file system.
+func (p *Project) ResolveInclude(from, include string) (name string, ok bool) {
+	return resolveInclude(p.FS, from, include)
+}
+
+// resolveInclude resolves an include path written in the file from. Paths
+// starting with "./" or "../" are relative to the including file; all
+// others are relative to the root of the file system.
+func resolveInclude(fsys fs.FS, from, include string) (string, bool) {
+	include = strings.ReplaceAll(include, `\`, "/")
+	if strings.HasPrefix(include, "./") || strings.HasPrefix(include, "../") {
+		include = path.Join(path.Dir(from), include)
+	}
+	name := path.Clean(include)
+	if strings.HasPrefix(name, "../") || name == ".." {
+		return "", false
+	}
+	return resolve(fsys, strings.TrimPrefix(name, "/"))
+}
+
+// resolve finds name in fsys, falling back to a case-insensitive match of
+// each path element since lump names are case-insensitive.
+func resolve(fsys fs.FS, name string) (string, bool) {
+	if info, err := fs.Stat(fsys, name); err == nil && !info.IsDir() {
+		return name, true
+	}
+
+	dir := "."
+	elems := strings.Split(name, "/")
+	for i, elem := range elems {
+		entries, err := fs.ReadDir(fsys, dir)
+		if err != nil {
+			return "", false
+		}
+		found := false
+		for _, e := range entries {
+			if strings.EqualFold(e.Name(), elem) && e.IsDir() == (i < len(elems)-1) {
+				dir = path.Join(dir, e.Name())
+				found = true
+				break
+			}
+		}
+		if !found {
+			return "", false
+		}
+	}
+	return dir, true
+}
+
+func cleanPath(name string) string {
+	return strings.TrimPrefix(path.Clean("/"+strings.ReplaceAll(name, `\`, "/")), "/")
+}
@@ -0,0 +1,217 @@
+package project_test
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	zscript "github.com/jlcrochet/tree-sitter-zscript/bindings/go"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/engine"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/project"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/symbols"
+)
+
+func paths(p *project.Project) []string {
+	var result []string
+	for _, f := range p.Files {
+		result = append(result, f.Path)
+	}
+	return result
+}
+
+func TestLoad(t *testing.T) {
+	fsys := fstest.MapFS{
+		"ZSCRIPT.txt": {Data: []byte(`version "4.10"
+#include "zscript/actors.zs"
+#include "zscript/Weapons/rifle.zs"
+#include "zscript/missing.zs"
+`)},
+		"zscript/actors.zs":        {Data: []byte(`#include "./common.zs"` + "\nclass MyImp : DoomImp {}")},
+		"zscript/common.zs":        {Data: []byte(`const X = 1;`)},
+		"zscript/weapons/Rifle.zs": {Data: []byte(`#include "zscript/common.zs"` + "\nclass Rifle : Weapon {}")},
+		"zscript.toml":             {Data: []byte(`version = "4.10"`)},
+	}
+
+	roots, err := project.FindRoots(fsys)
+	if err != nil || len(roots) != 1 || roots[0] != "ZSCRIPT.txt" {
+		t.Fatalf("FindRoots() = %v, %v", roots, err)
+	}
+
+	p, err := project.Load(context.Background(), fsys, roots...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+
+	want := "zscript/common.zs zscript/actors.zs zscript/weapons/Rifle.zs ZSCRIPT.txt"
+	if got := strings.Join(paths(p), " "); got != want {
+		t.Errorf("Files = %s, want %s", got, want)
+	}
+	if len(p.Diagnostics) != 1 || !strings.Contains(p.Diagnostics[0].Message, "missing.zs") {
+		t.Errorf("Diagnostics = %v", p.Diagnostics)
+	}
+	if d := p.Diagnostics[0]; d.Path != "ZSCRIPT.txt" || d.Range.StartPoint.Row != 3 {
+		t.Errorf("Diagnostic at %s", d)
+	}
+	if f := p.File("zscript/WEAPONS/rifle.zs"); f == nil || len(f.Includes) != 1 {
+		t.Errorf("File() = %v", f)
+	}
+}
+
+func TestNew(t *testing.T) {
+	var files []*project.File
+	for _, path := range []string{"/src/Imp.zs", "/src/base.zs"} {
+		tree, err := zscript.Parse(context.Background(), []byte("class A {}"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		tree.Path = path
+		files = append(files, &project.File{Path: path, Tree: tree})
+	}
+	p := project.New(files...)
+	defer p.Close()
+	if got := strings.Join(paths(p), " "); got != "/src/Imp.zs /src/base.zs" {
+		t.Errorf("Files = %s", got)
+	}
+	if f := p.File("/src/imp.zs"); f != files[0] {
+		t.Errorf("File() = %v", f)
+	}
+}
+
+func TestLoadCycle(t *testing.T) {
+	fsys := fstest.MapFS{
+		"zscript": {Data: []byte(`#include "a.zs"`)},
+		"a.zs":    {Data: []byte(`#include "b.zs"`)},
+		"b.zs":    {Data: []byte(`#include "a.zs"`)},
+	}
+	p, err := project.Load(context.Background(), fsys, "zscript")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+
+	if len(p.Files) != 3 {
+		t.Errorf("Files = %v", paths(p))
+	}
+	if len(p.Diagnostics) != 1 || !strings.Contains(p.Diagnostics[0].Message, "a.zs -> b.zs -> a.zs") {
+		t.Errorf("Diagnostics = %v", p.Diagnostics)
+	}
+}
+
+func TestLoadMissingRoot(t *testing.T) {
+	if _, err := project.Load(context.Background(), fstest.MapFS{}, "zscript"); err == nil {
+		t.Error("Load() succeeded without a root")
+	}
+}
+
+func TestLoadWithOptions(t *testing.T) {
+	fsys := fstest.MapFS{}
+	var root strings.Builder
+	for i := 0; i < 40; i++ {
+		name := "a" + strconv.Itoa(i) + ".zs"
+		var data string
+		if i+1 < 40 {
+			data = `#include "a` + strconv.Itoa(i+1) + `.zs"` + "\n"
+		}
+		data += `#include "shared.zs"` + "\nclass C" + strconv.Itoa(i) + " {}"
+		fsys[name] = &fstest.MapFile{Data: []byte(data)}
+		root.WriteString(`#include "` + name + `"` + "\n")
+	}
+	fsys["shared.zs"] = &fstest.MapFile{Data: []byte(`const X = 1;`)}
+	fsys["zscript"] = &fstest.MapFile{Data: []byte(root.String())}
+
+	var want string
+	for _, workers := range []int{1, 2, 8, 0} {
+		p, err := project.LoadWithOptions(context.Background(), fsys, project.IndexOptions{Workers: workers}, "zscript")
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := strings.Join(paths(p), " ")
+		p.Close()
+		if len(p.Files) != 42 {
+			t.Fatalf("Workers %d: %d files", workers, len(p.Files))
+		}
+		if want == "" {
+			want = got
+		} else if got != want {
+			t.Errorf("Workers %d: Files = %s, want %s", workers, got, want)
+		}
+	}
+	if !strings.HasPrefix(want, "shared.zs a39.zs a38.zs") {
+		t.Errorf("Files = %s", want)
+	}
+}
+
+func TestLoadFileTimeout(t *testing.T) {
+	huge := strings.Repeat("class A { void F() { x = 1 + 2 * (3 - y); } }\n", 200000)
+	fsys := fstest.MapFS{
+		"zscript":  &fstest.MapFile{Data: []byte("#include \"huge.zs\"\n#include \"small.zs\"\n")},
+		"huge.zs":  &fstest.MapFile{Data: []byte(huge)},
+		"small.zs": &fstest.MapFile{Data: []byte("class B {}")},
+	}
+	p, err := project.LoadWithOptions(context.Background(), fsys, project.IndexOptions{FileTimeout: 20 * time.Millisecond}, "zscript")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+	if got := strings.Join(paths(p), " "); got != "small.zs zscript" {
+		t.Errorf("Files = %s, want small.zs zscript", got)
+	}
+	if len(p.Diagnostics) != 1 || !strings.Contains(p.Diagnostics[0].Message, `"huge.zs" skipped`) {
+		t.Errorf("Diagnostics = %v", p.Diagnostics)
+	}
+
+	_, err = project.LoadWithOptions(context.Background(), fsys, project.IndexOptions{FileTimeout: 20 * time.Millisecond}, "huge.zs")
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("loading a slow root: err = %v, want context.DeadlineExceeded", err)
+	}
+}
+
+func TestCheckIncludes(t *testing.T) {
+	fsys := fstest.MapFS{
+		"zscript.zs": {Data: []byte(`version "4.10"
+#include "zscript/actors.zs"
+#include "zscript/util.zs"
+#include "./Zscript/Util.zs"
+#include "zscript/extend.zs"
+#include "zscript/all.zs"
+#include "zscript/handler.zs"
+#include "zscript/lonely.zs"
+#include "./zscript/lonely.zs"
+`)},
+		"zscript/actors.zs":  {Data: []byte("class MyImp : DoomImp { void F() { Util.Log(); A_SpawnItem(\"Spark\"); } }")},
+		"zscript/util.zs":    {Data: []byte("class Util { static void Log() {} }")},
+		"zscript/extend.zs":  {Data: []byte("extend class Actor { int counter; }")},
+		"zscript/all.zs":     {Data: []byte(`#include "zscript/spark.zs"` + "\n" + `#include "zscript/unused.zs"`)},
+		"zscript/spark.zs":   {Data: []byte("class Spark : Actor {}")},
+		"zscript/unused.zs":  {Data: []byte("class Unused { void F() { Unused u; } }\nenum EUnused { UN_A }")},
+		"zscript/handler.zs": {Data: []byte("class MyHandler : EventHandler {}")},
+		"zscript/lonely.zs":  {Data: []byte("class Lonely : Thinker {}")},
+	}
+	p, err := project.Load(context.Background(), fsys, "zscript.zs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+	var got []string
+	var trees []*zscript.Tree
+	for _, f := range p.Files {
+		trees = append(trees, f.Tree)
+	}
+	for _, d := range p.CheckIncludes(engine.Hierarchy(symbols.ExtractAll(0, trees...)...), "myhandler") {
+		got = append(got, d.String())
+	}
+	want := []string{
+		`zscript.zs:4:1: file "./Zscript/Util.zs" is already included as "zscript/util.zs" at zscript.zs:3:1`,
+		`zscript.zs:8:1: included file "zscript/lonely.zs" declares nothing the project uses`,
+		`zscript.zs:9:1: file "./zscript/lonely.zs" is already included as "zscript/lonely.zs" at zscript.zs:8:1`,
+		`zscript/all.zs:2:1: included file "zscript/unused.zs" declares nothing the project uses`,
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("CheckIncludes() =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
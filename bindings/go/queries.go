@@ -0,0 +1,46 @@
+package tree_sitter_zscript
+
+import (
+	"io/fs"
+
+	zscript "github.com/jlcrochet/tree-sitter-zscript"
+)
+
+// Queries returns the grammar's query files, rooted at the queries
+// directory (e.g. "highlights.scm").
+func Queries() fs.FS {
+	queries, err := fs.Sub(zscript.Queries, "queries")
+	if err != nil {
+		panic(err)
+	}
+	return queries
+}
+
+// HighlightsQuery returns the source of queries/highlights.scm.
+func HighlightsQuery() []byte {
+	return mustReadQuery("highlights.scm")
+}
+
+// LocalsQuery returns the source of queries/locals.scm.
+func LocalsQuery() []byte {
+	return mustReadQuery("locals.scm")
+}
+
+// InjectionsQuery returns the source of queries/injections.scm.
+func InjectionsQuery() []byte {
+	return mustReadQuery("injections.scm")
+}
+
+// TagsQuery returns the source of queries/tags.scm, which captures
+// definitions and references for code navigation.
+func TagsQuery() []byte {
+	return mustReadQuery("tags.scm")
+}
+
+func mustReadQuery(name string) []byte {
+	data, err := fs.ReadFile(zscript.Queries, "queries/"+name)
+	if err != nil {
+		panic(err)
+	}
+	return data
+}
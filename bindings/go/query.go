@@ -0,0 +1,244 @@
+package tree_sitter_zscript
+
+import (
+	"container/list"
+	"iter"
+	"math"
+	"runtime"
+	"sync"
+
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+)
+
+// CachedQuery is a compiled query shared through the cache used by Query.
+// It is safe for concurrent use; each iteration uses its own cursor.
+type CachedQuery struct {
+	*tree_sitter.Query
+	// Pattern is the source the query was compiled from.
+	Pattern string
+}
+
+// Capture is a node captured by a query.
+type Capture struct {
+	// Name is the capture name without the leading "@".
+	Name string
+	Node tree_sitter.Node
+	// PatternIndex is the index of the pattern that matched.
+	PatternIndex uint
+}
+
+// Match is a match of one pattern of a query.
+type Match struct {
+	PatternIndex uint
+	Captures     []Capture
+}
+
+// Capture returns the first node captured under name.
+func (m *Match) Capture(name string) (tree_sitter.Node, bool) {
+	for _, c := range m.Captures {
+		if c.Name == name {
+			return c.Node, true
+		}
+	}
+	return tree_sitter.Node{}, false
+}
+
+// DefaultQueryCacheSize is the number of compiled queries Query keeps
+// until SetQueryCacheSize is called.
+const DefaultQueryCacheSize = 64
+
+// queryLRU is a least-recently-used cache of compiled queries. Evicted
+// queries are not closed, since callers may still hold them; a finalizer
+// closes each query once it is unreachable.
+type queryLRU struct {
+	sync.Mutex
+	size    int
+	order   *list.List // of *CachedQuery, most recently used first
+	entries map[string]*list.Element
+}
+
+var queryCache = queryLRU{
+	size:    DefaultQueryCacheSize,
+	order:   list.New(),
+	entries: map[string]*list.Element{},
+}
+
+// Query returns the compiled query for pattern, compiling it on first use.
+// Compiled queries are kept in a least-recently-used cache shared by the
+// whole process.
+func Query(pattern string) (*CachedQuery, error) {
+	c := &queryCache
+	c.Lock()
+	if e, ok := c.entries[pattern]; ok {
+		c.order.MoveToFront(e)
+		c.Unlock()
+		return e.Value.(*CachedQuery), nil
+	}
+	c.Unlock()
+
+	// Compile without holding the lock; if another goroutine compiles
+	// the same pattern meanwhile, its query wins and this one is dropped.
+	q, qerr := tree_sitter.NewQuery(GetLanguage(), pattern)
+	if qerr != nil {
+		return nil, qerr
+	}
+	query := &CachedQuery{Query: q, Pattern: pattern}
+	runtime.SetFinalizer(query, func(q *CachedQuery) { q.Query.Close() })
+
+	c.Lock()
+	defer c.Unlock()
+	if e, ok := c.entries[pattern]; ok {
+		c.order.MoveToFront(e)
+		return e.Value.(*CachedQuery), nil
+	}
+	c.entries[pattern] = c.order.PushFront(query)
+	c.evict()
+	return query, nil
+}
+
+// MustQuery is like Query but panics if the pattern does not compile. It
+// is meant for patterns written into the program.
+func MustQuery(pattern string) *CachedQuery {
+	q, err := Query(pattern)
+	if err != nil {
+		panic(err)
+	}
+	return q
+}
+
+// SetQueryCacheSize sets the number of compiled queries Query keeps. A
+// size of zero or less disables caching.
+func SetQueryCacheSize(size int) {
+	c := &queryCache
+	c.Lock()
+	defer c.Unlock()
+	c.size = size
+	c.evict()
+}
+
+func (c *queryLRU) evict() {
+	for c.order.Len() > max(c.size, 0) {
+		e := c.order.Back()
+		c.order.Remove(e)
+		delete(c.entries, e.Value.(*CachedQuery).Pattern)
+	}
+}
+
+// cursorPool holds idle query cursors. A cursor the pool drops is closed
+// by a finalizer.
+var cursorPool = sync.Pool{
+	New: func() any {
+		c := tree_sitter.NewQueryCursor()
+		runtime.SetFinalizer(c, (*tree_sitter.QueryCursor).Close)
+		return c
+	},
+}
+
+// QueryCursor runs queries, streaming their matches and captures as it
+// finds them instead of collecting them first. Cursors come from a pool
+// shared by the whole process, so that running many queries, from any
+// number of goroutines, does not allocate a new cursor each time; Close
+// returns a cursor to the pool. A cursor runs one query at a time: each
+// iteration of Matches or Captures restarts it, so it must not be
+// interleaved with another on the same cursor or used from two goroutines
+// at once.
+type QueryCursor struct {
+	cursor *tree_sitter.QueryCursor
+}
+
+// NewQueryCursor returns a cursor from the pool, matching in the whole of
+// the nodes it is given.
+func NewQueryCursor() *QueryCursor {
+	return &QueryCursor{cursor: cursorPool.Get().(*tree_sitter.QueryCursor)}
+}
+
+// SetByteRange limits the cursor to the matches that overlap the bytes
+// from start to end, and returns it.
+func (c *QueryCursor) SetByteRange(start, end uint) *QueryCursor {
+	c.cursor.SetByteRange(start, end)
+	return c
+}
+
+// Close resets the cursor and returns it to the pool. The cursor must not
+// be used once closed.
+func (c *QueryCursor) Close() {
+	if c.cursor == nil {
+		return
+	}
+	c.cursor.SetByteRange(0, math.MaxUint32)
+	cursorPool.Put(c.cursor)
+	c.cursor = nil
+}
+
+// Matches yields the matches of q in node, whose text is source. Text
+// predicates such as #eq? and #match? are applied.
+func (c *QueryCursor) Matches(q *CachedQuery, node *tree_sitter.Node, source []byte) iter.Seq[*Match] {
+	return func(yield func(*Match) bool) {
+		names := q.CaptureNames()
+		matches := c.cursor.Matches(q.Query, node, source)
+		for m := matches.Next(); m != nil; m = matches.Next() {
+			match := &Match{PatternIndex: m.PatternIndex}
+			for _, capture := range m.Captures {
+				match.Captures = append(match.Captures, Capture{
+					Name:         names[capture.Index],
+					Node:         capture.Node,
+					PatternIndex: m.PatternIndex,
+				})
+			}
+			if !yield(match) {
+				return
+			}
+		}
+	}
+}
+
+// Captures yields the captures of q in node in source order, whose text is
+// source.
+func (c *QueryCursor) Captures(q *CachedQuery, node *tree_sitter.Node, source []byte) iter.Seq[Capture] {
+	return func(yield func(Capture) bool) {
+		names := q.CaptureNames()
+		captures := c.cursor.Captures(q.Query, node, source)
+		for m, i := captures.Next(); m != nil; m, i = captures.Next() {
+			capture := m.Captures[i]
+			if !yield(Capture{Name: names[capture.Index], Node: capture.Node, PatternIndex: m.PatternIndex}) {
+				return
+			}
+		}
+	}
+}
+
+// Matches yields the matches of q in node, whose text is source, using a
+// cursor of the pool. Text predicates such as #eq? and #match? are
+// applied.
+func (q *CachedQuery) Matches(node *tree_sitter.Node, source []byte) iter.Seq[*Match] {
+	return func(yield func(*Match) bool) {
+		c := NewQueryCursor()
+		defer c.Close()
+		c.Matches(q, node, source)(yield)
+	}
+}
+
+// Captures yields the captures of q in node in source order, whose text is
+// source, using a cursor of the pool.
+func (q *CachedQuery) Captures(node *tree_sitter.Node, source []byte) iter.Seq[Capture] {
+	return func(yield func(Capture) bool) {
+		c := NewQueryCursor()
+		defer c.Close()
+		c.Captures(q, node, source)(yield)
+	}
+}
+
+// TreeMatches yields the matches of q in the whole of tree.
+func (q *CachedQuery) TreeMatches(tree *Tree) iter.Seq[*Match] {
+	return q.Matches(tree.RootNode(), tree.Source)
+}
+
+// TreeCaptures yields the captures of q in the whole of tree in source
+// order, as in
+//
+//	for c := range q.TreeCaptures(tree) {
+//		...
+//	}
+func (q *CachedQuery) TreeCaptures(tree *Tree) iter.Seq[Capture] {
+	return q.Captures(tree.RootNode(), tree.Source)
+}
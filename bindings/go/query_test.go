@@ -0,0 +1,121 @@
+package tree_sitter_zscript_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	tree_sitter_zscript "github.com/jlcrochet/tree-sitter-zscript/bindings/go"
+)
+
+func TestQueryCache(t *testing.T) {
+	const pattern = `(class_definition name: (type_identifier) @name)`
+	q1, err := tree_sitter_zscript.Query(pattern)
+	if err != nil {
+		t.Fatal(err)
+	}
+	q2, err := tree_sitter_zscript.Query(pattern)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if q1 != q2 {
+		t.Error("Query did not return the cached query")
+	}
+
+	if _, err := tree_sitter_zscript.Query(`(no_such_node) @x`); err == nil {
+		t.Error("Query accepted an invalid pattern")
+	}
+
+	tree_sitter_zscript.SetQueryCacheSize(1)
+	defer tree_sitter_zscript.SetQueryCacheSize(tree_sitter_zscript.DefaultQueryCacheSize)
+	if _, err := tree_sitter_zscript.Query(`(struct_definition) @s`); err != nil {
+		t.Fatal(err)
+	}
+	q3, err := tree_sitter_zscript.Query(pattern)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if q3 == q1 {
+		t.Error("Query returned an evicted query")
+	}
+}
+
+func TestQueryIterators(t *testing.T) {
+	source := []byte(`class A : Actor {} class B : Inventory {} class C : Actor {}`)
+	tree, err := tree_sitter_zscript.Parse(context.Background(), source)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tree.Close()
+
+	q := tree_sitter_zscript.MustQuery(`(class_definition
+		name: (type_identifier) @name
+		(inheritance_specifier parent: (type_identifier) @parent (#eq? @parent "Actor")))`)
+	var names []string
+	for m := range q.Matches(tree.RootNode(), source) {
+		name, ok := m.Capture("name")
+		if !ok {
+			t.Fatal("match without @name")
+		}
+		names = append(names, name.Utf8Text(source))
+	}
+	if len(names) != 2 || names[0] != "A" || names[1] != "C" {
+		t.Errorf("matched classes = %q, want [A C]", names)
+	}
+
+	var captured []string
+	for c := range q.Captures(tree.RootNode(), source) {
+		captured = append(captured, c.Name+"="+c.Node.Utf8Text(source))
+		if len(captured) == 3 {
+			break
+		}
+	}
+	if want := []string{"name=A", "parent=Actor", "name=C"}; len(captured) != 3 || captured[0] != want[0] || captured[1] != want[1] || captured[2] != want[2] {
+		t.Errorf("captures = %q, want %q", captured, want)
+	}
+}
+
+func TestQueryCursor(t *testing.T) {
+	source := []byte(`class A : Actor {} class B : Inventory {} class C : Actor {}`)
+	tree, err := tree_sitter_zscript.Parse(context.Background(), source)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tree.Close()
+	q := tree_sitter_zscript.MustQuery(`(class_definition name: (type_identifier) @name)`)
+
+	c := tree_sitter_zscript.NewQueryCursor().SetByteRange(19, 40)
+	var names []string
+	for capture := range c.Captures(q, tree.RootNode(), source) {
+		names = append(names, capture.Node.Utf8Text(source))
+	}
+	c.Close()
+	if len(names) != 1 || names[0] != "B" {
+		t.Errorf("captures in range = %q, want [B]", names)
+	}
+
+	// Cursors return to the pool reset, and the pool serves goroutines
+	// running queries at once.
+	var wg sync.WaitGroup
+	counts := make([]int, 8)
+	for i := range counts {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 20 {
+				for range q.TreeCaptures(tree) {
+					counts[i]++
+				}
+				for range q.TreeMatches(tree) {
+					counts[i]++
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	for i, n := range counts {
+		if n != 20*6 {
+			t.Errorf("goroutine %d found %d captures and matches, want %d", i, n, 20*6)
+		}
+	}
+}
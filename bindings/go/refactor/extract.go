@@ -0,0 +1,319 @@
+package refactor
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+
+	zscript "github.com/jlcrochet/tree-sitter-zscript/bindings/go"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/edit"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/project"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/resolve"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/symbols"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/types"
+)
+
+// ExtractMethod returns the edits that move the statements of the file of
+// p at path between the byte offsets start and end into a new method of
+// the same class, named name, and replace them with a call to it. The new
+// method goes after the class's last method and keeps the static, action
+// and scope qualifiers of the method the statements come from.
+//
+// The selection must span whole statements of one block of a method,
+// apart from white space around them. The locals and parameters of the
+// method that the statements use are passed to the new method, as out
+// parameters if the statements assign them. It is an error for the
+// statements to return, to break or continue a loop around them, or to
+// declare a local that is used after them.
+func ExtractMethod(p *project.Project, path string, start, end uint, name string) ([]Edit, error) {
+	f := p.File(path)
+	if f == nil {
+		return nil, fmt.Errorf("refactor: no file %s", path)
+	}
+	if !identifier.MatchString(name) || reserved[strings.ToLower(name)] {
+		return nil, fmt.Errorf("refactor: %q is not a valid name", name)
+	}
+	tree := f.Tree
+	stmts := statements(tree, start, end)
+	if len(stmts) == 0 {
+		return nil, fmt.Errorf("refactor: the selection is not a sequence of statements")
+	}
+	first, last := stmts[0], stmts[len(stmts)-1]
+	start, end = first.StartByte(), last.EndByte()
+
+	method := first.Parent()
+	for method != nil && method.Kind() != zscript.NodeMethodDefinition {
+		method = method.Parent()
+	}
+	class := method
+	for class != nil && class.Kind() != zscript.NodeClassDefinition {
+		class = class.Parent()
+	}
+	if method == nil || class == nil {
+		return nil, fmt.Errorf("refactor: the selection is not in a method of a class")
+	}
+	className := class.ChildByFieldName(zscript.FieldName).Utf8Text(tree.Source)
+	tables := make([]*symbols.Table, len(p.Files))
+	for i, f := range p.Files {
+		tables[i] = symbols.Extract(f.Tree)
+	}
+	owner := resolve.Declaration{Symbol: symbols.Symbol{Name: name, Kind: symbols.KindMethod}, Owner: className}
+	if err := checkConflict(tables, owner, name); err != nil {
+		return nil, err
+	}
+	for _, s := range stmts {
+		if err := checkJumps(tree, s); err != nil {
+			return nil, err
+		}
+	}
+
+	r := resolve.ForProject(p)
+	checker := types.ForProject(p)
+	type variable struct {
+		decl resolve.Declaration
+		typ  string
+		out  bool
+	}
+	vars := map[uint]*variable{}
+	var err error
+	var v zscript.Visitor
+	v.On(zscript.NodeIdentifier, func(n *tree_sitter.Node) zscript.WalkAction {
+		decl, ok := r.Resolve(tree, n)
+		if !ok || decl.Kind != symbols.KindLocal && decl.Kind != symbols.KindParameter || decl.Path != tree.Path {
+			return zscript.WalkContinue
+		}
+		inside := decl.NameRange.StartByte >= start && decl.NameRange.EndByte <= end
+		switch {
+		case inside && n.StartByte() >= end && err == nil:
+			err = fmt.Errorf("refactor: %s is declared in the selection and used after it", decl.Name)
+		case !inside && n.StartByte() >= start && n.EndByte() <= end:
+			u := vars[decl.NameRange.StartByte]
+			if u == nil {
+				typ := decl.Type
+				if typ == "" || strings.EqualFold(typ, "let") {
+					typ = checker.TypeOf(tree, n).String()
+				}
+				if typ == "" {
+					err = fmt.Errorf("refactor: the type of %s is not known", decl.Name)
+					return zscript.WalkStop
+				}
+				u = &variable{decl: decl, typ: typ}
+				vars[decl.NameRange.StartByte] = u
+			}
+			u.out = u.out || assigned(n)
+		}
+		return zscript.WalkContinue
+	})
+	zscript.Walk(method.ChildByFieldName(zscript.FieldBody), &v)
+	if err != nil {
+		return nil, err
+	}
+
+	var params []*variable
+	for _, u := range vars {
+		params = append(params, u)
+	}
+	sort.Slice(params, func(i, j int) bool { return params[i].decl.NameRange.StartByte < params[j].decl.NameRange.StartByte })
+	var decls, args []string
+	for _, u := range params {
+		decl := u.typ + " " + u.decl.Name
+		if u.out {
+			decl = "out " + decl
+		}
+		decls = append(decls, decl)
+		args = append(args, u.decl.Name)
+	}
+
+	var sig strings.Builder
+	if mods := method.NamedChild(0); mods != nil && mods.Kind() == zscript.NodeMemberModifiers {
+		for i := uint(0); i < mods.NamedChildCount(); i++ {
+			switch mod := mods.NamedChild(i).Utf8Text(tree.Source); strings.ToLower(mod) {
+			case "static", "action", "clearscope", "play", "ui":
+				sig.WriteString(mod + " ")
+			}
+		}
+	}
+	fmt.Fprintf(&sig, "void %s(%s)", name, strings.Join(decls, ", "))
+	body := method.ChildByFieldName(zscript.FieldBody)
+	for i := uint(0); i < method.ChildCount(); i++ {
+		if method.Child(i).Kind() == zscript.NodeConstQualifier {
+			sig.WriteString(" const")
+		}
+	}
+	// The new method's braces are placed as the old method's are.
+	open := " {\n"
+	if params := method.ChildByFieldName(zscript.FieldParameters); params != nil && strings.Contains(string(tree.Source[params.EndByte():body.StartByte()]), "\n") {
+		open = "\n{\n"
+	}
+	text := sig.String() + open + indentBlock(tree.Source, start, end, indentUnit(tree.Source, method, body)) + "}\n"
+
+	inserted, err := edit.InsertMethod(tree, className, text)
+	if err != nil {
+		return nil, err
+	}
+	call := first.Range()
+	call.EndByte, call.EndPoint = last.EndByte(), last.EndPosition()
+	edits := []Edit{{Path: tree.Path, Range: call, NewText: fmt.Sprintf("%s(%s);", name, strings.Join(args, ", "))}}
+	for _, e := range inserted {
+		edits = append(edits, Edit{Path: tree.Path, Range: e.Range, NewText: e.NewText})
+	}
+	sort.Slice(edits, func(i, j int) bool { return edits[i].Range.StartByte < edits[j].Range.StartByte })
+	return edits, nil
+}
+
+// statements returns the statements of the innermost block of tree whose
+// statements the byte range from start to end spans, white space around
+// them aside, or nil if it cuts a statement.
+func statements(tree *zscript.Tree, start, end uint) []*tree_sitter.Node {
+	src := tree.Source
+	end = min(end, uint(len(src)))
+	for start < end && strings.ContainsRune(" \t\r\n", rune(src[start])) {
+		start++
+	}
+	for end > start && strings.ContainsRune(" \t\r\n", rune(src[end-1])) {
+		end--
+	}
+	if start == end {
+		return nil
+	}
+	block := tree.RootNode().DescendantForByteRange(start, end)
+	for block != nil && block.Kind() != zscript.NodeCompoundStatement {
+		block = block.Parent()
+	}
+	for ; block != nil; block = block.Parent() {
+		if block.Kind() != zscript.NodeCompoundStatement || block.StartByte() == start {
+			continue
+		}
+		var stmts []*tree_sitter.Node
+		cut := false
+		for i := uint(0); i < block.NamedChildCount(); i++ {
+			c := block.NamedChild(i)
+			switch {
+			case c.EndByte() <= start || c.StartByte() >= end:
+			case c.StartByte() >= start && c.EndByte() <= end:
+				stmts = append(stmts, c)
+			default:
+				cut = true
+			}
+		}
+		if !cut && len(stmts) > 0 && stmts[0].StartByte() == start && stmts[len(stmts)-1].EndByte() == end {
+			return stmts
+		}
+		if cut {
+			return nil
+		}
+	}
+	return nil
+}
+
+// checkJumps reports an error if stmt returns, or breaks or continues a
+// loop or switch that is not inside it.
+func checkJumps(tree *zscript.Tree, stmt *tree_sitter.Node) error {
+	var err error
+	var walk func(n *tree_sitter.Node, loop, swtch bool)
+	walk = func(n *tree_sitter.Node, loop, swtch bool) {
+		switch n.Kind() {
+		case zscript.NodeReturnStatement:
+			err = fmt.Errorf("refactor: the selection returns at line %d", n.StartPosition().Row+1)
+		case zscript.NodeBreakStatement:
+			if !loop && !swtch {
+				err = fmt.Errorf("refactor: the selection breaks out of it at line %d", n.StartPosition().Row+1)
+			}
+		case zscript.NodeContinueStatement:
+			if !loop {
+				err = fmt.Errorf("refactor: the selection continues a loop around it at line %d", n.StartPosition().Row+1)
+			}
+		case zscript.NodeForStatement, zscript.NodeForeachStatement, zscript.NodeWhileStatement, zscript.NodeDoStatement:
+			loop = true
+		case zscript.NodeSwitchStatement:
+			swtch = true
+		}
+		for i := uint(0); i < n.NamedChildCount() && err == nil; i++ {
+			walk(n.NamedChild(i), loop, swtch)
+		}
+	}
+	walk(stmt, false, false)
+	return err
+}
+
+// assigned reports whether the identifier n is assigned to or
+// incremented.
+func assigned(n *tree_sitter.Node) bool {
+	parent := n.Parent()
+	if parent == nil {
+		return false
+	}
+	switch parent.Kind() {
+	case zscript.NodeAssignmentExpression:
+		left := parent.ChildByFieldName(zscript.FieldLeft)
+		return left != nil && left.Id() == n.Id()
+	case zscript.NodeUpdateExpression:
+		return true
+	}
+	return false
+}
+
+// indentUnit returns the indentation of the statements of body relative
+// to method, or a tab if it has none on lines of their own.
+func indentUnit(src []byte, method, body *tree_sitter.Node) string {
+	outer := lineIndent(src, method.StartByte())
+	for i := uint(0); i < body.NamedChildCount(); i++ {
+		c := body.NamedChild(i)
+		inner := lineIndent(src, c.StartByte())
+		if c.StartPosition().Row != body.StartPosition().Row && len(inner) > len(outer) && strings.HasPrefix(inner, outer) {
+			return inner[len(outer):]
+		}
+	}
+	return "\t"
+}
+
+// lineStart returns the offset of the start of the line holding offset.
+func lineStart(src []byte, offset uint) uint {
+	return uint(bytes.LastIndexByte(src[:offset], '\n') + 1)
+}
+
+// lineIndent returns the leading white space of the line holding offset.
+func lineIndent(src []byte, offset uint) string {
+	line := lineStart(src, offset)
+	end := line
+	for end < uint(len(src)) && (src[end] == ' ' || src[end] == '\t') {
+		end++
+	}
+	return string(src[line:end])
+}
+
+// indentBlock returns the lines of src from start to end with their
+// common indentation replaced by indent, each ending in a newline.
+func indentBlock(src []byte, start, end uint, indent string) string {
+	if line := lineStart(src, start); strings.TrimSpace(string(src[line:start])) == "" {
+		// Keep the indentation of the first line, so that every line is
+		// dedented alike.
+		start = line
+	}
+	text := strings.ReplaceAll(string(src[start:end]), "\r\n", "\n")
+	lines := strings.Split(text, "\n")
+	common, first := "", true
+	for _, l := range lines {
+		if strings.TrimSpace(l) == "" {
+			continue
+		}
+		lead := l[:len(l)-len(strings.TrimLeft(l, " \t"))]
+		if first {
+			common, first = lead, false
+		}
+		for !strings.HasPrefix(lead, common) {
+			common = common[:len(common)-1]
+		}
+	}
+	var b strings.Builder
+	for _, l := range lines {
+		if strings.TrimSpace(l) != "" {
+			b.WriteString(indent + strings.TrimPrefix(l, common))
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
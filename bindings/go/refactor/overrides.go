@@ -0,0 +1,166 @@
+package refactor
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+
+	zscript "github.com/jlcrochet/tree-sitter-zscript/bindings/go"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/edit"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/project"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/resolve"
+)
+
+// overrideModifiers are the modifiers of a virtual method that its
+// overrides repeat.
+var overrideModifiers = map[string]bool{"protected": true, "action": true, "clearscope": true, "play": true, "ui": true}
+
+// Overridable returns the virtual methods that the class named class
+// inherits, from the classes of p and the engine's, and neither overrides
+// nor could, sorted by name. Methods that are final or private, or that
+// take variable arguments, are left out.
+func Overridable(p *project.Project, class string) []resolve.Declaration {
+	r := resolve.ForProject(p)
+	own := map[string]bool{}
+	for _, decl := range r.Members(class, false) {
+		if strings.EqualFold(decl.Owner, class) {
+			own[strings.ToLower(decl.Name)] = true
+		}
+	}
+	var decls []resolve.Declaration
+	for _, decl := range r.Members(class, true) {
+		m := decl.Method
+		if m == nil || own[strings.ToLower(decl.Name)] || !m.HasModifier("virtual") && !m.HasModifier("override") ||
+			m.HasModifier("final") || m.HasModifier("private") || m.HasModifier("static") {
+			continue
+		}
+		if n := len(m.Params); n > 0 && m.Params[n-1].Variadic {
+			continue
+		}
+		decls = append(decls, decl)
+	}
+	sort.Slice(decls, func(i, j int) bool { return strings.ToLower(decls[i].Name) < strings.ToLower(decls[j].Name) })
+	return decls
+}
+
+// OverrideStubs returns the edit that inserts into the class named class,
+// declared in the file of p at path, an override of each of the methods
+// Overridable lists whose names are given, or of all of them if none are.
+// Each override has the signature of the method it overrides and calls it
+// through Super, returning what it returns. The overrides go after the
+// class's last method, with their braces placed as the class's methods
+// place them.
+func OverrideStubs(p *project.Project, path, class string, methods ...string) ([]Edit, error) {
+	f := p.File(path)
+	if f == nil {
+		return nil, fmt.Errorf("refactor: no file %s", path)
+	}
+	wanted := map[string]bool{}
+	for _, name := range methods {
+		wanted[strings.ToLower(name)] = true
+	}
+	var stubs []string
+	found := map[string]bool{}
+	braces := allman(f.Tree, class)
+	for _, decl := range Overridable(p, class) {
+		if len(wanted) > 0 && !wanted[strings.ToLower(decl.Name)] {
+			continue
+		}
+		found[strings.ToLower(decl.Name)] = true
+		stubs = append(stubs, overrideStub(decl, braces))
+	}
+	for _, name := range methods {
+		if !found[strings.ToLower(name)] {
+			return nil, fmt.Errorf("refactor: %s inherits no virtual method %s to override", class, name)
+		}
+	}
+	if len(stubs) == 0 {
+		return nil, fmt.Errorf("refactor: %s inherits no virtual methods to override", class)
+	}
+	inserted, err := edit.InsertMethod(f.Tree, class, strings.Join(stubs, "\n"))
+	if err != nil {
+		return nil, err
+	}
+	var edits []Edit
+	for _, e := range inserted {
+		edits = append(edits, Edit{Path: f.Path, Range: e.Range, NewText: e.NewText})
+	}
+	return edits, nil
+}
+
+// overrideStub returns an override of decl, a method, that calls it, with
+// its opening brace on a line of its own if allman is set.
+func overrideStub(decl resolve.Declaration, allman bool) string {
+	m := decl.Method
+	var b strings.Builder
+	for _, mod := range m.Modifiers {
+		if overrideModifiers[mod] {
+			b.WriteString(mod + " ")
+		}
+	}
+	b.WriteString("override " + m.ReturnType + " " + m.Name + "(")
+	var args []string
+	for i, param := range m.Params {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		b.WriteString(strings.Join(append(append([]string{}, param.Modifiers...), param.Type, param.Name), " "))
+		if param.Default != "" {
+			b.WriteString(" = " + param.Default)
+		}
+		args = append(args, param.Name)
+	}
+	b.WriteString(")")
+	if m.Const {
+		b.WriteString(" const")
+	}
+	if allman {
+		b.WriteString("\n{\n")
+	} else {
+		b.WriteString(" {\n")
+	}
+	call := fmt.Sprintf("Super.%s(%s);", m.Name, strings.Join(args, ", "))
+	switch {
+	case m.HasModifier("abstract"):
+		// There is nothing to call.
+	case strings.EqualFold(m.ReturnType, "void"):
+		b.WriteString("\t" + call + "\n")
+	default:
+		b.WriteString("\treturn " + call + "\n")
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+// allman reports whether the methods of tree open their bodies on lines of
+// their own, judging by the first method of the class named class that has
+// a body, or by the first of the file if the class has none.
+func allman(tree *zscript.Tree, class string) bool {
+	var first, own *tree_sitter.Node
+	var v zscript.Visitor
+	v.On(zscript.NodeMethodDefinition, func(n *tree_sitter.Node) zscript.WalkAction {
+		if n.ChildByFieldName(zscript.FieldParameters) == nil || n.ChildByFieldName(zscript.FieldBody) == nil {
+			return zscript.WalkSkipChildren
+		}
+		if first == nil {
+			first = n
+		}
+		if c := n.Parent(); own == nil && c != nil && c.Kind() == zscript.NodeClassDefinition {
+			if name := c.ChildByFieldName(zscript.FieldName); name != nil && strings.EqualFold(name.Utf8Text(tree.Source), class) {
+				own = n
+			}
+		}
+		return zscript.WalkSkipChildren
+	})
+	zscript.Walk(tree.RootNode(), &v)
+	if own != nil {
+		first = own
+	}
+	if first == nil {
+		return false
+	}
+	params, body := first.ChildByFieldName(zscript.FieldParameters), first.ChildByFieldName(zscript.FieldBody)
+	return strings.Contains(string(tree.Source[params.EndByte():body.StartByte()]), "\n")
+}
@@ -0,0 +1,226 @@
+// Package refactor computes source edits for refactorings that span a
+// whole project, such as renaming a declaration and every use of it, or
+// that depend on what its names resolve to, such as extracting statements
+// into a method.
+package refactor
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+
+	zscript "github.com/jlcrochet/tree-sitter-zscript/bindings/go"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/hierarchy"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/project"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/resolve"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/symbols"
+)
+
+// Edit replaces a range of a file with new text.
+type Edit struct {
+	Path    string
+	Range   tree_sitter.Range
+	NewText string
+}
+
+var (
+	identifier = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+	labelName  = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)*$`)
+)
+
+// reserved holds the keywords that cannot be used as names.
+var reserved = map[string]bool{}
+
+func init() {
+	for _, word := range strings.Fields(`abstract action alignof array bool
+		break case class clearscope color const continue default do double
+		else enum extend extern false final float for foreach getclass if
+		in int int16 int8 internal invoker latent let long map mapiterator
+		meta mixin name native null out override play private protected
+		readonly replaces return self short signed sizeof sound spriteid
+		state statelabel static string struct super switch textureid
+		transient true ui uint uint16 uint8 unsigned var vararg vector2
+		vector3 vector4 virtual void voidptr while`) {
+		reserved[word] = true
+	}
+}
+
+// Rename returns the edits that rename decl, and every use of it in p, to
+// newName. Only identifiers are changed, never the contents of string
+// literals or comments. Renaming a method also renames the methods it
+// overrides and those that override it.
+func Rename(p *project.Project, decl resolve.Declaration, newName string) ([]Edit, error) {
+	valid := identifier
+	if decl.Kind == symbols.KindStateLabel {
+		valid = labelName
+	}
+	if !valid.MatchString(newName) || reserved[strings.ToLower(newName)] {
+		return nil, fmt.Errorf("refactor: %q is not a valid name", newName)
+	}
+	if newName == decl.Name {
+		return nil, nil
+	}
+
+	trees := make([]*zscript.Tree, len(p.Files))
+	for i, f := range p.Files {
+		trees[i] = f.Tree
+	}
+	tables := symbols.ExtractAll(0, trees...)
+	if !strings.EqualFold(newName, decl.Name) {
+		if err := checkConflict(tables, decl, newName); err != nil {
+			return nil, err
+		}
+	}
+
+	r := resolve.New(tables...)
+	seen := map[string]bool{}
+	var edits []Edit
+	decls, err := related(tables, decl)
+	if err != nil {
+		return nil, err
+	}
+	for _, d := range decls {
+		for _, ref := range r.References(d, trees...) {
+			key := fmt.Sprintf("%s\x00%d", strings.ToLower(ref.Path), ref.Range.StartByte)
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			edits = append(edits, Edit{Path: ref.Path, Range: ref.Range, NewText: newName})
+		}
+	}
+	sort.Slice(edits, func(i, j int) bool {
+		if edits[i].Path != edits[j].Path {
+			return edits[i].Path < edits[j].Path
+		}
+		return edits[i].Range.StartByte < edits[j].Range.StartByte
+	})
+	return edits, nil
+}
+
+// related returns decl and, for a method, every declaration of the same
+// method in the ancestors and subclasses of its class. It is an error to
+// rename an override of a method declared outside the project, such as an
+// engine virtual.
+func related(tables []*symbols.Table, decl resolve.Declaration) ([]resolve.Declaration, error) {
+	decls := []resolve.Declaration{decl}
+	if decl.Kind != symbols.KindMethod || decl.Owner == "" {
+		return decls, nil
+	}
+	h := hierarchy.Build(tables...)
+	paths := map[*symbols.Class]string{}
+	for _, t := range tables {
+		for _, c := range t.Classes {
+			paths[c] = t.Path
+		}
+	}
+	add := func(classes []*hierarchy.Class) bool {
+		found := false
+		for _, c := range classes {
+			for _, cd := range append([]*symbols.Class{c.Decl}, c.Extensions...) {
+				if cd == nil {
+					continue
+				}
+				if m := cd.Method(decl.Name); m != nil && !m.HasModifier("static") {
+					decls = append(decls, resolve.Declaration{Symbol: m.Symbol, Path: paths[cd], Type: m.ReturnType, Owner: cd.Name, Modifiers: m.Modifiers})
+					found = true
+				}
+			}
+		}
+		return found
+	}
+	if !add(h.Ancestors(decl.Owner)) && overrides(h.Class(decl.Owner), decl) {
+		return nil, fmt.Errorf("refactor: %s.%s overrides a method declared outside the project", decl.Owner, decl.Name)
+	}
+	add(h.Subclasses(decl.Owner))
+	return decls, nil
+}
+
+// overrides reports whether the declaration of decl in class c is marked
+// override.
+func overrides(c *hierarchy.Class, decl resolve.Declaration) bool {
+	if c == nil {
+		return false
+	}
+	for _, cd := range append([]*symbols.Class{c.Decl}, c.Extensions...) {
+		if cd == nil {
+			continue
+		}
+		if m := cd.Method(decl.Name); m != nil && m.NameRange.StartByte == decl.NameRange.StartByte {
+			return m.HasModifier("override")
+		}
+	}
+	return false
+}
+
+// checkConflict reports an error if newName is already declared where it
+// would clash with the renamed declaration.
+func checkConflict(tables []*symbols.Table, decl resolve.Declaration, newName string) error {
+	switch decl.Kind {
+	case symbols.KindClass, symbols.KindStruct, symbols.KindEnum:
+		for _, t := range tables {
+			for _, c := range t.Classes {
+				if strings.EqualFold(c.Name, newName) {
+					return fmt.Errorf("refactor: a class named %s already exists", c.Name)
+				}
+			}
+			for _, s := range t.Structs {
+				if strings.EqualFold(s.Name, newName) {
+					return fmt.Errorf("refactor: a struct named %s already exists", s.Name)
+				}
+			}
+			for _, e := range t.Enums {
+				if strings.EqualFold(e.Name, newName) {
+					return fmt.Errorf("refactor: an enum named %s already exists", e.Name)
+				}
+			}
+		}
+	case symbols.KindField, symbols.KindMethod, symbols.KindStateLabel:
+		if decl.Owner == "" {
+			return nil
+		}
+		h := hierarchy.Build(tables...)
+		lineage := append([]*hierarchy.Class{h.Class(decl.Owner)}, h.Ancestors(decl.Owner)...)
+		for _, c := range lineage {
+			if c == nil {
+				continue
+			}
+			for _, cd := range append([]*symbols.Class{c.Decl}, c.Extensions...) {
+				if cd == nil {
+					continue
+				}
+				if decl.Kind == symbols.KindStateLabel {
+					if cd.StateLabel(newName) != nil && strings.EqualFold(cd.Name, decl.Owner) {
+						return fmt.Errorf("refactor: %s already has a state label named %s", cd.Name, newName)
+					}
+				} else if cd.Field(newName) != nil || cd.Method(newName) != nil {
+					return fmt.Errorf("refactor: %s already has a member named %s", cd.Name, newName)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// Apply returns src with the edits for path applied. Edits must not
+// overlap.
+func Apply(src []byte, path string, edits []Edit) []byte {
+	var own []Edit
+	for _, e := range edits {
+		if e.Path == path {
+			own = append(own, e)
+		}
+	}
+	sort.Slice(own, func(i, j int) bool { return own[i].Range.StartByte < own[j].Range.StartByte })
+	var out []byte
+	last := uint(0)
+	for _, e := range own {
+		out = append(out, src[last:e.Range.StartByte]...)
+		out = append(out, e.NewText...)
+		last = e.Range.EndByte
+	}
+	return append(out, src[last:]...)
+}
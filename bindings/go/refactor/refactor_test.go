@@ -0,0 +1,265 @@
+package refactor_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/project"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/refactor"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/resolve"
+)
+
+const root = `#include "base.zs"
+class Imp : Base {
+	override void Fire() {
+		// Fire again
+		Super.Fire();
+		Console.Printf("Fire");
+	}
+	override void Tick() {}
+}
+`
+
+const base = `class Base : Actor {
+	int heat;
+	virtual void Fire() { heat++; }
+	void Cool() { self.heat = 0; }
+}
+`
+
+func load(t *testing.T) *project.Project {
+	t.Helper()
+	p, err := project.Load(context.Background(), fstest.MapFS{
+		"zscript.zs": {Data: []byte(root)},
+		"base.zs":    {Data: []byte(base)},
+	}, "zscript.zs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(p.Close)
+	return p
+}
+
+// declaration resolves the first use of name after marker in path.
+func declaration(t *testing.T, p *project.Project, path, marker, name string) resolve.Declaration {
+	t.Helper()
+	f := p.File(path)
+	offset := uint(strings.Index(string(f.Tree.Source), marker) + len(marker))
+	node := f.Tree.RootNode().NamedDescendantForByteRange(offset, offset)
+	decl, ok := resolve.ForProject(p).Resolve(f.Tree, node)
+	if !ok || decl.Name != name {
+		t.Fatalf("%s did not resolve", name)
+	}
+	return decl
+}
+
+func apply(p *project.Project, edits []refactor.Edit) map[string]string {
+	out := map[string]string{}
+	for _, f := range p.Files {
+		out[f.Path] = string(refactor.Apply(f.Tree.Source, f.Path, edits))
+	}
+	return out
+}
+
+func TestRenameMethod(t *testing.T) {
+	p := load(t)
+	edits, err := refactor.Rename(p, declaration(t, p, "zscript.zs", "Super.", "Fire"), "Shoot")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := apply(p, edits)
+	if want := strings.ReplaceAll(base, "Fire", "Shoot"); got["base.zs"] != want {
+		t.Errorf("base.zs =\n%s\nwant\n%s", got["base.zs"], want)
+	}
+	// The comment and the string literal keep the old name.
+	want := strings.Replace(strings.Replace(root, "void Fire", "void Shoot", 1), "Super.Fire", "Super.Shoot", 1)
+	if got["zscript.zs"] != want {
+		t.Errorf("zscript.zs =\n%s\nwant\n%s", got["zscript.zs"], want)
+	}
+}
+
+func TestRenameField(t *testing.T) {
+	p := load(t)
+	edits, err := refactor.Rename(p, declaration(t, p, "base.zs", "self.", "heat"), "temperature")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(edits) != 3 {
+		t.Fatalf("got %d edits: %+v", len(edits), edits)
+	}
+	if got, want := apply(p, edits)["base.zs"], strings.ReplaceAll(base, "heat", "temperature"); got != want {
+		t.Errorf("base.zs =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestRenameErrors(t *testing.T) {
+	p := load(t)
+	heat := declaration(t, p, "base.zs", "self.", "heat")
+	for _, tt := range []struct {
+		decl    resolve.Declaration
+		newName string
+		want    string
+	}{
+		{heat, "2hot", `refactor: "2hot" is not a valid name`},
+		{heat, "class", `refactor: "class" is not a valid name`},
+		{heat, "Cool", "refactor: Base already has a member named Cool"},
+		{declaration(t, p, "zscript.zs", "class Imp : ", "Base"), "Imp", "refactor: a class named Imp already exists"},
+		{declaration(t, p, "zscript.zs", "override void ", "Fire"), "Shoot", ""},
+		{declaration(t, p, "zscript.zs", "override void Fire() {\n\t\t// Fire again\n\t\tSuper.Fire();\n\t\tConsole.Printf(\"Fire\");\n\t}\n\toverride void ", "Tick"), "Update",
+			"refactor: Imp.Tick overrides a method declared outside the project"},
+	} {
+		_, err := refactor.Rename(p, tt.decl, tt.newName)
+		got := ""
+		if err != nil {
+			got = err.Error()
+		}
+		if got != tt.want {
+			t.Errorf("Rename(%s, %s) error = %q, want %q", tt.decl.Name, tt.newName, got, tt.want)
+		}
+	}
+}
+
+const extractable = `class Gun : Actor {
+	int ammo;
+	void Fire(int shots) {
+		let total = shots * 2;
+		int spent = 0;
+		for (int i = 0; i < total; i++) {
+			A_Log("bang");
+			spent++;
+		}
+		ammo -= spent;
+		if (ammo < 0) {
+			return;
+		}
+	}
+}
+`
+
+func extractProject(t *testing.T) *project.Project {
+	t.Helper()
+	p, err := project.Load(context.Background(), fstest.MapFS{"zscript.zs": {Data: []byte(extractable)}}, "zscript.zs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(p.Close)
+	return p
+}
+
+// span returns the offsets of the text from the start of from to the end
+// of to.
+func span(from, to string) (uint, uint) {
+	start := strings.Index(extractable, from)
+	end := strings.Index(extractable[start:], to) + start + len(to)
+	return uint(start), uint(end)
+}
+
+func TestExtractMethod(t *testing.T) {
+	p := extractProject(t)
+	start, end := span("\t\tfor (", "}\n")
+	edits, err := refactor.ExtractMethod(p, "zscript.zs", start, end, "Shoot")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `class Gun : Actor {
+	int ammo;
+	void Fire(int shots) {
+		let total = shots * 2;
+		int spent = 0;
+		Shoot(total, spent);
+		ammo -= spent;
+		if (ammo < 0) {
+			return;
+		}
+	}
+
+	void Shoot(int total, out int spent) {
+		for (int i = 0; i < total; i++) {
+			A_Log("bang");
+			spent++;
+		}
+	}
+}
+`
+	if got := refactor.Apply([]byte(extractable), "zscript.zs", edits); string(got) != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestExtractMethodErrors(t *testing.T) {
+	p := extractProject(t)
+	for _, tt := range []struct {
+		from, to, name, want string
+	}{
+		{"let total", "0;", "Count", "refactor: total is declared in the selection and used after it"},
+		{"ammo -=", "return;\n\t\t}", "Settle", "refactor: the selection returns at line 12"},
+		{"A_Log", "spent++;", "Fire", "refactor: Gun already has a member named Fire"},
+		{"A_Log", "spent++;", "for", `refactor: "for" is not a valid name`},
+		{"shots * 2", "spent = 0", "Part", "refactor: the selection is not a sequence of statements"},
+	} {
+		start, end := span(tt.from, tt.to)
+		_, err := refactor.ExtractMethod(p, "zscript.zs", start, end, tt.name)
+		got := ""
+		if err != nil {
+			got = err.Error()
+		}
+		if got != tt.want {
+			t.Errorf("%s..%s: error = %q, want %q", tt.from, tt.to, got, tt.want)
+		}
+	}
+}
+
+const overriding = `class Base : Actor {
+	virtual int Damage(int amount, Name type = 'None') const { return amount; }
+	virtual void Fire() {}
+	final virtual void Locked() {}
+}
+
+class Imp : Base
+{
+	override void Fire()
+	{
+	}
+}
+`
+
+func TestOverridable(t *testing.T) {
+	p, err := project.Load(context.Background(), fstest.MapFS{"zscript.zs": {Data: []byte(overriding)}}, "zscript.zs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+	names := map[string]string{}
+	for _, decl := range refactor.Overridable(p, "Imp") {
+		names[decl.Name] = decl.Owner
+	}
+	if names["Damage"] != "Base" || names["Tick"] != "Actor" || names["Fire"] != "" || names["Locked"] != "" {
+		t.Errorf("Overridable = %v", names)
+	}
+
+	edits, err := refactor.OverrideStubs(p, "zscript.zs", "Imp", "tick", "Damage")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := strings.TrimSuffix(overriding, "}\n") + `
+	override int Damage(int amount, Name type = 'None') const
+	{
+		return Super.Damage(amount, type);
+	}
+
+	override void Tick()
+	{
+		Super.Tick();
+	}
+}
+`
+	if got := string(refactor.Apply([]byte(overriding), "zscript.zs", edits)); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+
+	if _, err := refactor.OverrideStubs(p, "zscript.zs", "Imp", "Locked"); err == nil || err.Error() != "refactor: Imp inherits no virtual method Locked to override" {
+		t.Errorf("overriding a final method: %v", err)
+	}
+}
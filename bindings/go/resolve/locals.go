@@ -0,0 +1,105 @@
+package resolve
+
+import (
+	"strings"
+
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+
+	zscript "github.com/jlcrochet/tree-sitter-zscript/bindings/go"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/symbols"
+)
+
+// localCaptures maps the definition captures of queries/locals.scm that
+// declare locals to the kind they declare. Field, method and constant
+// definitions are resolved from the symbol tables instead.
+var localCaptures = map[string]symbols.Kind{
+	"local.definition.var":       symbols.KindLocal,
+	"local.definition.parameter": symbols.KindParameter,
+}
+
+// local resolves use to a local variable or parameter of the function
+// that contains it, following the scopes of queries/locals.scm. A
+// variable is in scope from its declaration to the end of the innermost
+// scope that contains it; a parameter, throughout its function.
+func local(tree *zscript.Tree, use *tree_sitter.Node) (Declaration, bool) {
+	fn := use.Parent()
+	for fn != nil && fn.Kind() != zscript.NodeMethodDefinition && fn.Kind() != zscript.NodeFunctionDefinition {
+		fn = fn.Parent()
+	}
+	if fn == nil {
+		return Declaration{}, false
+	}
+	name := use.Utf8Text(tree.Source)
+
+	var scopes []tree_sitter.Node
+	type definition struct {
+		node tree_sitter.Node
+		kind symbols.Kind
+	}
+	var defs []definition
+	for c := range zscript.MustQuery(string(zscript.LocalsQuery())).Captures(fn, tree.Source) {
+		if c.Name == "local.scope" {
+			scopes = append(scopes, c.Node)
+		} else if kind, ok := localCaptures[c.Name]; ok && strings.EqualFold(c.Node.Utf8Text(tree.Source), name) {
+			defs = append(defs, definition{c.Node, kind})
+		}
+	}
+
+	// innermost returns the smallest scope that contains n.
+	innermost := func(n *tree_sitter.Node) *tree_sitter.Node {
+		var best *tree_sitter.Node
+		for i := range scopes {
+			s := &scopes[i]
+			if s.StartByte() <= n.StartByte() && n.EndByte() <= s.EndByte() &&
+				(best == nil || s.EndByte()-s.StartByte() < best.EndByte()-best.StartByte()) {
+				best = s
+			}
+		}
+		return best
+	}
+
+	var found *definition
+	var foundScope *tree_sitter.Node
+	for i := range defs {
+		d := &defs[i]
+		if d.kind == symbols.KindLocal && d.node.StartByte() > use.StartByte() {
+			continue
+		}
+		scope := innermost(&d.node)
+		if scope == nil || use.StartByte() < scope.StartByte() || use.EndByte() > scope.EndByte() {
+			continue
+		}
+		// Prefer the declaration in the innermost scope, and among those
+		// the latest, which shadows earlier ones.
+		if found == nil || scope.StartByte() >= foundScope.StartByte() {
+			found, foundScope = d, scope
+		}
+	}
+	if found == nil {
+		return Declaration{}, false
+	}
+	decl := Declaration{
+		Symbol: symbols.Symbol{Name: found.node.Utf8Text(tree.Source), Kind: found.kind, NameRange: found.node.Range()},
+		Path:   tree.Path,
+	}
+	decl.Range = decl.NameRange
+	if d := declaringNode(&found.node); d != nil {
+		decl.Range = d.Range()
+		if typ := d.ChildByFieldName(zscript.FieldType); typ != nil {
+			decl.Type = typ.Utf8Text(tree.Source)
+		}
+	}
+	return decl, true
+}
+
+// declaringNode returns the declaration, parameter declaration or foreach
+// statement that declares the identifier n.
+func declaringNode(n *tree_sitter.Node) *tree_sitter.Node {
+	for p := n.Parent(); p != nil; p = p.Parent() {
+		switch p.Kind() {
+		case zscript.NodeDeclaration, zscript.NodeParameterDeclaration, zscript.NodeForeachStatement:
+			return p
+		}
+	}
+	return nil
+}
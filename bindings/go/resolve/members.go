@@ -0,0 +1,161 @@
+package resolve
+
+import (
+	"strings"
+
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/symbols"
+)
+
+// classMember returns the member of c named name. Path is left for the
+// caller to fill in.
+func classMember(c *symbols.Class, name string) (Declaration, bool) {
+	if f := c.Field(name); f != nil {
+		return fieldDeclaration(f, c.Name), true
+	}
+	if m := c.Method(name); m != nil {
+		return methodDeclaration(m, c.Name), true
+	}
+	for _, p := range c.Properties {
+		if strings.EqualFold(p.Name, name) {
+			return Declaration{Symbol: p.Symbol, Owner: c.Name}, true
+		}
+	}
+	for _, f := range c.FlagDefs {
+		if strings.EqualFold(f.Name, name) {
+			return Declaration{Symbol: f.Symbol, Type: "bool", Owner: c.Name}, true
+		}
+	}
+	if decl, ok := findConst(c.Consts, name, c.Name); ok {
+		return decl, true
+	}
+	if decl, ok := findEnumerator(c.Enums, name, c.Name); ok {
+		return decl, true
+	}
+	return findEnum(c.Enums, name, c.Name)
+}
+
+func structMember(s *symbols.Struct, name string) (Declaration, bool) {
+	for _, f := range s.Fields {
+		if strings.EqualFold(f.Name, name) {
+			return fieldDeclaration(f, s.Name), true
+		}
+	}
+	for _, m := range s.Methods {
+		if strings.EqualFold(m.Name, name) {
+			return methodDeclaration(m, s.Name), true
+		}
+	}
+	if decl, ok := findConst(s.Consts, name, s.Name); ok {
+		return decl, true
+	}
+	if decl, ok := findEnumerator(s.Enums, name, s.Name); ok {
+		return decl, true
+	}
+	return findEnum(s.Enums, name, s.Name)
+}
+
+func fieldDeclaration(f *symbols.Field, owner string) Declaration {
+	return Declaration{Symbol: f.Symbol, Type: f.Type, Owner: owner, Modifiers: f.Modifiers}
+}
+
+func methodDeclaration(m *symbols.Method, owner string) Declaration {
+	return Declaration{Symbol: m.Symbol, Type: m.ReturnType, Owner: owner, Modifiers: m.Modifiers, Method: m}
+}
+
+// Members returns the fields, methods, constants and enumerators of the
+// class or struct typ, with those it inherits. A member that a subclass
+// redeclares is listed once, from the subclass. If super is set, the
+// members declared by typ itself are left out.
+func (r *Resolver) Members(typ string, super bool) []Declaration {
+	var found []Declaration
+	seen := map[string]bool{}
+	add := func(decl Declaration, path string) {
+		if key := strings.ToLower(decl.Name); !seen[key] {
+			seen[key] = true
+			decl.Path = path
+			found = append(found, decl)
+		}
+	}
+	addAll := func(owner, path string, fields []*symbols.Field, methods []*symbols.Method, consts []*symbols.Const, enums []*symbols.Enum) {
+		for _, f := range fields {
+			add(fieldDeclaration(f, owner), path)
+		}
+		for _, m := range methods {
+			add(methodDeclaration(m, owner), path)
+		}
+		for _, c := range consts {
+			add(Declaration{Symbol: c.Symbol, Owner: owner}, path)
+		}
+		for _, e := range enums {
+			for _, m := range e.Members {
+				add(Declaration{Symbol: m.Symbol, Type: e.Name, Owner: owner}, path)
+			}
+		}
+	}
+	for i, decls := range r.lineage(typ) {
+		if i == 0 && super {
+			continue
+		}
+		for _, c := range decls {
+			addAll(c.Name, r.path[c], c.Fields, c.Methods, c.Consts, c.Enums)
+		}
+	}
+	decls, paths := r.structs(typ)
+	for i, s := range decls {
+		addAll(s.Name, paths[i], s.Fields, s.Methods, s.Consts, s.Enums)
+	}
+	return found
+}
+
+// StateLabels returns the state labels of class and its ancestors, those
+// of the nearest class first when several declare the same label. If super
+// is set, the labels of class itself are left out.
+func (r *Resolver) StateLabels(class string, super bool) []Declaration {
+	var found []Declaration
+	seen := map[string]bool{}
+	for i, decls := range r.lineage(class) {
+		if i == 0 && super {
+			continue
+		}
+		for _, c := range decls {
+			for _, l := range c.States {
+				if key := strings.ToLower(l.Name); !seen[key] {
+					seen[key] = true
+					found = append(found, Declaration{Symbol: l.Symbol, Path: r.path[c], Owner: c.Name})
+				}
+			}
+		}
+	}
+	return found
+}
+
+func findConst(consts []*symbols.Const, name, owner string) (Declaration, bool) {
+	for _, c := range consts {
+		if strings.EqualFold(c.Name, name) {
+			return Declaration{Symbol: c.Symbol, Owner: owner}, true
+		}
+	}
+	return Declaration{}, false
+}
+
+func findEnum(enums []*symbols.Enum, name, owner string) (Declaration, bool) {
+	for _, e := range enums {
+		if strings.EqualFold(e.Name, name) {
+			return Declaration{Symbol: e.Symbol, Owner: owner}, true
+		}
+	}
+	return Declaration{}, false
+}
+
+// findEnumerator returns the enumerator named name. Its type is the name
+// of its enum.
+func findEnumerator(enums []*symbols.Enum, name, owner string) (Declaration, bool) {
+	for _, e := range enums {
+		for _, m := range e.Members {
+			if strings.EqualFold(m.Name, name) {
+				return Declaration{Symbol: m.Symbol, Type: e.Name, Owner: owner}, true
+			}
+		}
+	}
+	return Declaration{}, false
+}
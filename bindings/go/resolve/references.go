@@ -0,0 +1,77 @@
+package resolve
+
+import (
+	"bytes"
+	"strings"
+
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+
+	zscript "github.com/jlcrochet/tree-sitter-zscript/bindings/go"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/symbols"
+)
+
+// Location is a range in a file.
+type Location struct {
+	Path  string
+	Range tree_sitter.Range
+}
+
+// References returns every identifier in trees that refers to decl,
+// including the name in the declaration itself, in the order of trees
+// and then of the source. A use counts if decl is among the candidates
+// ResolveAll returns for it, so members accessed through expressions of
+// unknown type are included.
+func (r *Resolver) References(decl Declaration, trees ...*zscript.Tree) []Location {
+	var refs []Location
+	local := decl.Kind == symbols.KindLocal || decl.Kind == symbols.KindParameter
+	needle := bytes.ToLower([]byte(decl.Name))
+	if decl.Kind == symbols.KindStateLabel {
+		// Qualified labels such as Death.Fire may be spaced out in the
+		// source, so search for their first part only.
+		first, _, _ := strings.Cut(decl.Name, ".")
+		needle = bytes.ToLower([]byte(first))
+	}
+
+	matches := func(tree *zscript.Tree, node *tree_sitter.Node) bool {
+		for _, d := range r.ResolveAll(tree, node) {
+			if d.Path == decl.Path && d.NameRange.StartByte == decl.NameRange.StartByte && d.Kind == decl.Kind {
+				return true
+			}
+		}
+		return false
+	}
+
+	for _, tree := range trees {
+		if local && tree.Path != decl.Path {
+			continue
+		}
+		if !bytes.Contains(bytes.ToLower(tree.Source), needle) {
+			continue
+		}
+		var v zscript.Visitor
+		if decl.Kind == symbols.KindStateLabel {
+			v.On(zscript.NodeStateLabelName, func(node *tree_sitter.Node) zscript.WalkAction {
+				text := strings.Join(strings.Fields(node.Utf8Text(tree.Source)), "")
+				if strings.EqualFold(text, decl.Name) && node.NamedChildCount() > 0 && matches(tree, node.NamedChild(0)) {
+					refs = append(refs, Location{Path: tree.Path, Range: node.Range()})
+				}
+				return zscript.WalkSkipChildren
+			})
+		} else {
+			visit := func(node *tree_sitter.Node) zscript.WalkAction {
+				if strings.EqualFold(node.Utf8Text(tree.Source), decl.Name) && matches(tree, node) {
+					refs = append(refs, Location{Path: tree.Path, Range: node.Range()})
+				}
+				return zscript.WalkContinue
+			}
+			v.On(zscript.NodeIdentifier, visit)
+			v.On(zscript.NodeFieldIdentifier, visit)
+			v.On(zscript.NodeTypeIdentifier, visit)
+			v.On(zscript.NodeStateLabelName, func(*tree_sitter.Node) zscript.WalkAction {
+				return zscript.WalkSkipChildren
+			})
+		}
+		zscript.Walk(tree.RootNode(), &v)
+	}
+	return refs
+}
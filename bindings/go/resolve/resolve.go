@@ -0,0 +1,437 @@
+// Package resolve binds identifiers to their declarations: local
+// variables and parameters by the scope rules of queries/locals.scm, and
+// class members, types, constants and state labels through the symbol
+// tables and class hierarchy of a project.
+package resolve
+
+import (
+	"strings"
+
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+
+	zscript "github.com/jlcrochet/tree-sitter-zscript/bindings/go"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/engine"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/hierarchy"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/project"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/symbols"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/version"
+)
+
+// Declaration is what an identifier refers to.
+type Declaration struct {
+	symbols.Symbol
+	// Path is the file that contains the declaration.
+	Path string
+	// Type is the declared type of a variable, parameter or field, or the
+	// return type of a method, as written; it is "" when not known.
+	Type string
+	// Owner is the class or struct that declares a member, or "".
+	Owner string
+	// Modifiers are the lowercased modifiers of a field or method.
+	Modifiers []string
+	// Method is the declaration of a method, with its parameters, or nil.
+	Method *symbols.Method
+}
+
+// Resolver resolves identifiers against the declarations of a set of
+// files.
+type Resolver struct {
+	tables    []*symbols.Table
+	hierarchy *hierarchy.Hierarchy
+	// path maps every class declaration to its file.
+	path map[*symbols.Class]string
+}
+
+// New returns a resolver for the files with the given symbol tables.
+func New(tables ...*symbols.Table) *Resolver {
+	r := &Resolver{tables: tables, hierarchy: hierarchy.Build(tables...), path: map[*symbols.Class]string{}}
+	for _, t := range tables {
+		for _, c := range t.Classes {
+			r.path[c] = t.Path
+		}
+	}
+	return r
+}
+
+// NewWithEngine is like New but also resolves against the built-in
+// classes and structs that package engine declares for version v, so that
+// members inherited from classes such as Actor, and Super calls into
+// them, resolve. Their declarations have the Path engine.Path. The game
+// classes of the engine, such as DoomImp, are known by their parents, so
+// that the members of a class deriving from one resolve too.
+func NewWithEngine(v version.Version, tables ...*symbols.Table) *Resolver {
+	r := New(append([]*symbols.Table{engine.TableFor(v)}, tables...)...)
+	r.hierarchy.AddParents(engine.GameClasses())
+	return r
+}
+
+// ForProject returns a resolver for the files of p and the engine
+// declarations for the version p declares.
+func ForProject(p *project.Project) *Resolver {
+	trees := make([]*zscript.Tree, len(p.Files))
+	for i, f := range p.Files {
+		trees[i] = f.Tree
+	}
+	return NewWithEngine(version.ForProject(p), symbols.ExtractAll(0, trees...)...)
+}
+
+// Resolve returns the declaration that node, an identifier in tree,
+// refers to. ok is false if the declaration cannot be found or, for
+// members accessed through an expression of unknown type, is ambiguous.
+func (r *Resolver) Resolve(tree *zscript.Tree, node *tree_sitter.Node) (decl Declaration, ok bool) {
+	decls := r.ResolveAll(tree, node)
+	if len(decls) != 1 {
+		return Declaration{}, false
+	}
+	return decls[0], true
+}
+
+// ResolveAll is like Resolve but returns every candidate when the
+// declaration is ambiguous, such as a member of an expression whose type
+// is unknown or a global declared in several files.
+func (r *Resolver) ResolveAll(tree *zscript.Tree, node *tree_sitter.Node) []Declaration {
+	if node == nil {
+		return nil
+	}
+	name := node.Utf8Text(tree.Source)
+	class := EnclosingType(node, tree.Source)
+
+	if label := ancestorOfKind(node, zscript.NodeStateLabelName); label != nil {
+		return r.stateLabel(label, class, tree.Source)
+	}
+
+	switch node.Kind() {
+	case zscript.NodeIdentifier:
+		if decl, ok := local(tree, node); ok {
+			return []Declaration{decl}
+		}
+		if decl, ok := r.member(class, name, false); ok {
+			return []Declaration{decl}
+		}
+		return r.globals(name)
+	case zscript.NodeFieldIdentifier:
+		parent := node.Parent()
+		if parent == nil || parent.Kind() != zscript.NodeFieldExpression {
+			return nil
+		}
+		receiver := parent.ChildByFieldName(zscript.FieldArgument)
+		if typ, super, ok := r.TypeOf(tree, receiver); ok {
+			if decl, ok := r.member(typ, name, super); ok {
+				return []Declaration{decl}
+			}
+			if r.declared(typ) {
+				return nil
+			}
+		}
+		// Members of an engine type, or of an expression whose type is
+		// not known, may be any member of that name.
+		return r.members(name)
+	case zscript.NodeTypeIdentifier:
+		return r.LookupType(class, name)
+	}
+	return nil
+}
+
+// Hierarchy returns the class hierarchy of the resolver's declarations.
+func (r *Resolver) Hierarchy() *hierarchy.Hierarchy {
+	return r.hierarchy
+}
+
+// lineage returns the declarations of the named class or struct followed
+// by those of its ancestors. Extensions come after the declaration they
+// extend.
+func (r *Resolver) lineage(name string) [][]*symbols.Class {
+	c := r.hierarchy.Class(name)
+	if c == nil {
+		return nil
+	}
+	var lineage [][]*symbols.Class
+	for _, c := range append([]*hierarchy.Class{c}, r.hierarchy.Ancestors(name)...) {
+		var decls []*symbols.Class
+		if c.Decl != nil {
+			decls = append(decls, c.Decl)
+		}
+		lineage = append(lineage, append(decls, c.Extensions...))
+	}
+	return lineage
+}
+
+// declared reports whether the named class or struct is declared in the
+// project.
+func (r *Resolver) declared(typ string) bool {
+	if c := r.hierarchy.Class(typ); c != nil && c.Defined() {
+		return true
+	}
+	decls, _ := r.structs(typ)
+	return len(decls) > 0
+}
+
+// structs returns the declarations of the named struct with the files
+// that contain them.
+func (r *Resolver) structs(name string) (decls []*symbols.Struct, paths []string) {
+	for _, t := range r.tables {
+		for _, s := range t.Structs {
+			if strings.EqualFold(s.Name, name) {
+				decls = append(decls, s)
+				paths = append(paths, t.Path)
+			}
+		}
+	}
+	return decls, paths
+}
+
+// member finds the member named name of the class or struct typ, looking
+// through its ancestors. If super is set, the class itself is skipped.
+func (r *Resolver) member(typ, name string, super bool) (Declaration, bool) {
+	if typ == "" {
+		return Declaration{}, false
+	}
+	for i, decls := range r.lineage(typ) {
+		if i == 0 && super {
+			continue
+		}
+		for _, c := range decls {
+			if decl, ok := classMember(c, name); ok {
+				decl.Path = r.path[c]
+				return decl, true
+			}
+		}
+	}
+	decls, paths := r.structs(typ)
+	for i, s := range decls {
+		if decl, ok := structMember(s, name); ok {
+			decl.Path = paths[i]
+			return decl, true
+		}
+	}
+	return Declaration{}, false
+}
+
+// members returns every member named name of any class or struct.
+func (r *Resolver) members(name string) []Declaration {
+	var found []Declaration
+	for _, t := range r.tables {
+		for _, c := range t.Classes {
+			if decl, ok := classMember(c, name); ok {
+				decl.Path = t.Path
+				found = append(found, decl)
+			}
+		}
+		for _, s := range t.Structs {
+			if decl, ok := structMember(s, name); ok {
+				decl.Path = t.Path
+				found = append(found, decl)
+			}
+		}
+	}
+	return found
+}
+
+// globals returns the top-level declarations named name: classes,
+// structs, enums, enumerators and constants.
+func (r *Resolver) globals(name string) []Declaration {
+	found := r.types(name)
+	for _, t := range r.tables {
+		if decl, ok := findConst(t.Consts, name, ""); ok {
+			decl.Path = t.Path
+			found = append(found, decl)
+		}
+		if decl, ok := findEnumerator(t.Enums, name, ""); ok {
+			decl.Path = t.Path
+			found = append(found, decl)
+		}
+	}
+	return found
+}
+
+// types returns the top-level classes, structs and enums named name.
+func (r *Resolver) types(name string) []Declaration {
+	var found []Declaration
+	for _, t := range r.tables {
+		for _, c := range t.Classes {
+			if !c.Extend && strings.EqualFold(c.Name, name) {
+				found = append(found, Declaration{Symbol: c.Symbol, Path: t.Path})
+			}
+		}
+		for _, s := range t.Structs {
+			if !s.Extend && strings.EqualFold(s.Name, name) {
+				found = append(found, Declaration{Symbol: s.Symbol, Path: t.Path})
+			}
+		}
+		if decl, ok := findEnum(t.Enums, name, ""); ok {
+			decl.Path = t.Path
+			found = append(found, decl)
+		}
+	}
+	return found
+}
+
+// Types returns the top-level classes, structs and enums of every file.
+func (r *Resolver) Types() []Declaration {
+	var found []Declaration
+	for _, t := range r.tables {
+		for _, c := range t.Classes {
+			if !c.Extend {
+				found = append(found, Declaration{Symbol: c.Symbol, Path: t.Path})
+			}
+		}
+		for _, s := range t.Structs {
+			if !s.Extend {
+				found = append(found, Declaration{Symbol: s.Symbol, Path: t.Path})
+			}
+		}
+		for _, e := range t.Enums {
+			found = append(found, Declaration{Symbol: e.Symbol, Path: t.Path})
+		}
+	}
+	return found
+}
+
+// LookupType returns the declarations of the type name used inside the
+// class or struct scope, which may be "": an enum nested in scope or its
+// ancestors, or else the top-level classes, structs and enums of that
+// name.
+func (r *Resolver) LookupType(scope, name string) []Declaration {
+	if decl, ok := r.nestedType(scope, name); ok {
+		return []Declaration{decl}
+	}
+	return r.types(name)
+}
+
+// nestedType finds an enum declared inside the named class, its
+// ancestors, or the named struct.
+func (r *Resolver) nestedType(typ, name string) (Declaration, bool) {
+	for _, decls := range r.lineage(typ) {
+		for _, c := range decls {
+			if decl, ok := findEnum(c.Enums, name, c.Name); ok {
+				decl.Path = r.path[c]
+				return decl, true
+			}
+		}
+	}
+	decls, paths := r.structs(typ)
+	for i, s := range decls {
+		if decl, ok := findEnum(s.Enums, name, s.Name); ok {
+			decl.Path = paths[i]
+			return decl, true
+		}
+	}
+	return Declaration{}, false
+}
+
+// stateLabel resolves a state label name, in a goto target or a label
+// definition, within the class hierarchy of class.
+func (r *Resolver) stateLabel(label *tree_sitter.Node, class string, source []byte) []Declaration {
+	name := strings.Join(strings.Fields(label.Utf8Text(source)), "")
+	super := false
+	if target := label.Parent(); target != nil && target.Kind() == zscript.NodeStateGotoTarget {
+		if qualifier := target.ChildByFieldName(zscript.FieldClass); qualifier != nil {
+			if q := qualifier.Utf8Text(source); strings.EqualFold(q, "super") {
+				super = true
+			} else {
+				class = q
+			}
+		}
+	}
+	for i, decls := range r.lineage(class) {
+		if i == 0 && super {
+			continue
+		}
+		for _, c := range decls {
+			if l := c.StateLabel(name); l != nil {
+				return []Declaration{{Symbol: l.Symbol, Path: r.path[c], Owner: c.Name}}
+			}
+		}
+	}
+	return nil
+}
+
+// TypeOf returns the class or struct that the expression receiver, in
+// tree, evaluates to, when that can be determined from declarations. super
+// is set for "Super", whose members are looked up from the parent class.
+func (r *Resolver) TypeOf(tree *zscript.Tree, receiver *tree_sitter.Node) (typ string, super, ok bool) {
+	if receiver == nil {
+		return "", false, false
+	}
+	switch receiver.Kind() {
+	case zscript.NodeSelfExpression:
+		typ = EnclosingType(receiver, tree.Source)
+		return typ, false, typ != ""
+	case zscript.NodeSuperExpression:
+		typ = EnclosingType(receiver, tree.Source)
+		return typ, true, typ != ""
+	case zscript.NodeIdentifier:
+		if decl, ok := r.Resolve(tree, receiver); ok {
+			if decl.Kind == symbols.KindClass || decl.Kind == symbols.KindStruct {
+				// A type name used for a static member access.
+				return decl.Name, false, true
+			}
+			return valueType(decl.Type)
+		}
+	case zscript.NodeFieldExpression:
+		if field := receiver.ChildByFieldName(zscript.FieldField); field != nil {
+			if decl, ok := r.Resolve(tree, field); ok {
+				return valueType(decl.Type)
+			}
+		}
+	case zscript.NodeParenthesizedExpression:
+		if receiver.NamedChildCount() == 1 {
+			return r.TypeOf(tree, receiver.NamedChild(0))
+		}
+	case zscript.NodeCallExpression:
+		fn := receiver.ChildByFieldName(zscript.FieldFunction)
+		if fn != nil && fn.Kind() == zscript.NodeFieldExpression {
+			fn = fn.ChildByFieldName(zscript.FieldField)
+		}
+		if decl, ok := r.Resolve(tree, fn); ok && decl.Method != nil {
+			return valueType(decl.Type)
+		}
+	}
+	return "", false, false
+}
+
+// valueType returns the class or struct named by a declared type, looking
+// through readonly<T>. Other parameterized and primitive types have no
+// members that can be resolved.
+func valueType(typ string) (string, bool, bool) {
+	typ = strings.Join(strings.Fields(typ), "")
+	if inner, ok := strings.CutPrefix(strings.ToLower(typ), "readonly<"); ok && strings.HasSuffix(inner, ">") {
+		typ = typ[len("readonly<") : len(typ)-1]
+	}
+	if typ == "" || strings.ContainsAny(typ, "<>[]") || primitive[strings.ToLower(typ)] {
+		return "", false, false
+	}
+	return typ, false, true
+}
+
+var primitive = map[string]bool{
+	"void": true, "bool": true, "int": true, "uint": true, "float": true,
+	"double": true, "string": true, "name": true, "sound": true,
+	"color": true, "vector2": true, "vector3": true, "vector4": true,
+	"state": true, "statelabel": true, "spriteid": true, "textureid": true,
+	"voidptr": true, "int8": true, "int16": true, "uint8": true,
+	"uint16": true, "let": true, "var": true,
+}
+
+func ancestorOfKind(node *tree_sitter.Node, kind string) *tree_sitter.Node {
+	for n := node; n != nil; n = n.Parent() {
+		if n.Kind() == kind {
+			return n
+		}
+	}
+	return nil
+}
+
+// EnclosingType returns the name of the innermost class or struct that
+// contains node, from the source of its tree, or "".
+func EnclosingType(node *tree_sitter.Node, source []byte) string {
+	for n := node.Parent(); n != nil; n = n.Parent() {
+		switch n.Kind() {
+		case zscript.NodeClassDefinition, zscript.NodeStructDefinition:
+			if name := n.ChildByFieldName(zscript.FieldName); name != nil {
+				return name.Utf8Text(source)
+			}
+		}
+	}
+	return ""
+}
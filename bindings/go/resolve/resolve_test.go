@@ -0,0 +1,290 @@
+package resolve_test
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	zscript "github.com/jlcrochet/tree-sitter-zscript/bindings/go"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/engine"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/resolve"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/symbols"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/version"
+)
+
+const base = `enum EMode { MODE_A, MODE_B }
+const LIMIT = 10;
+struct Stats { int kills; }
+class Base : Actor {
+	int health;
+	Stats stats;
+	enum EState { ST_IDLE }
+	virtual void Tick() {}
+	States {
+	Spawn:
+		TNT1 A 1;
+		Loop;
+	}
+}
+`
+
+const main = `class Imp : Base {
+	int count;
+	override void Tick(int amount) {
+		Super.Tick();
+		int count = health + amount;
+		for (int i = 0; i < count; i++) {
+			int count = i;
+			count++;
+		}
+		count = self.count + stats.kills + LIMIT + MODE_B + ST_IDLE;
+		Base b = self;
+		b.health = 0;
+		EState st;
+	}
+	States {
+	Spawn:
+		TNT1 A 1;
+		Goto Super::Spawn;
+	}
+}
+`
+
+func parse(t *testing.T, path, source string) *zscript.Tree {
+	t.Helper()
+	tree, err := zscript.Parse(context.Background(), []byte(source))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(tree.Close)
+	if tree.RootNode().HasError() {
+		t.Fatalf("%s: parse error: %s", path, tree.RootNode().ToSexp())
+	}
+	tree.Path = path
+	return tree
+}
+
+func TestResolve(t *testing.T) {
+	baseTree := parse(t, "base.zs", base)
+	mainTree := parse(t, "main.zs", main)
+	r := resolve.New(symbols.Extract(baseTree), symbols.Extract(mainTree))
+
+	tests := []struct {
+		// use is the identifier to resolve, given as its line and the
+		// text before it on that line.
+		line   uint
+		before string
+		name   string
+
+		kind  symbols.Kind
+		path  string
+		row   uint
+		typ   string
+		owner string
+	}{
+		{4, "\t\t", "Super", 0, "", 0, "", ""},
+		{4, "\t\tSuper.", "Tick", symbols.KindMethod, "base.zs", 7, "void", "Base"},
+		{5, "\t\tint count = ", "health", symbols.KindField, "base.zs", 4, "int", "Base"},
+		{5, "\t\tint count = health + ", "amount", symbols.KindParameter, "main.zs", 2, "int", ""},
+		{6, "\t\tfor (int i = 0; i < ", "count", symbols.KindLocal, "main.zs", 4, "int", ""},
+		{8, "\t\t\t", "count", symbols.KindLocal, "main.zs", 6, "int", ""},
+		{10, "\t\t", "count", symbols.KindLocal, "main.zs", 4, "int", ""},
+		{10, "\t\tcount = self.", "count", symbols.KindField, "main.zs", 1, "int", "Imp"},
+		{10, "\t\tcount = self.count + stats.", "kills", symbols.KindField, "base.zs", 2, "int", "Stats"},
+		{10, "\t\tcount = self.count + stats.kills + ", "LIMIT", symbols.KindConst, "base.zs", 1, "", ""},
+		{10, "\t\tcount = self.count + stats.kills + LIMIT + ", "MODE_B", symbols.KindEnumerator, "base.zs", 0, "EMode", ""},
+		{10, "\t\tcount = self.count + stats.kills + LIMIT + MODE_B + ", "ST_IDLE", symbols.KindEnumerator, "base.zs", 6, "EState", "Base"},
+		{11, "\t\t", "Base", symbols.KindClass, "base.zs", 3, "", ""},
+		{12, "\t\tb.", "health", symbols.KindField, "base.zs", 4, "int", "Base"},
+		{13, "\t\t", "EState", symbols.KindEnum, "base.zs", 6, "", "Base"},
+		{18, "\t\tGoto Super::", "Spawn", symbols.KindStateLabel, "base.zs", 9, "", "Base"},
+	}
+	lines := strings.Split(main, "\n")
+	for _, tt := range tests {
+		offset := uint(len(tt.before))
+		for _, l := range lines[:tt.line-1] {
+			offset += uint(len(l)) + 1
+		}
+		node := mainTree.RootNode().NamedDescendantForByteRange(offset, offset)
+		if got := node.Utf8Text(mainTree.Source); got != tt.name {
+			t.Fatalf("line %d: node at offset is %q, want %q", tt.line, got, tt.name)
+		}
+		decl, ok := r.Resolve(mainTree, node)
+		if tt.kind == 0 {
+			if ok {
+				t.Errorf("line %d: %s resolved to %+v", tt.line, tt.name, decl)
+			}
+			continue
+		}
+		if !ok {
+			t.Errorf("line %d: %s did not resolve", tt.line, tt.name)
+			continue
+		}
+		if decl.Kind != tt.kind || decl.Path != tt.path || decl.NameRange.StartPoint.Row != tt.row || decl.Type != tt.typ || decl.Owner != tt.owner {
+			t.Errorf("line %d: %s = %s %s:%d type %q owner %q; want %s %s:%d type %q owner %q", tt.line, tt.name,
+				decl.Kind, decl.Path, decl.NameRange.StartPoint.Row, decl.Type, decl.Owner,
+				tt.kind, tt.path, tt.row, tt.typ, tt.owner)
+		}
+	}
+}
+
+func TestResolveAmbiguous(t *testing.T) {
+	tree := parse(t, "a.zs", `class A { int x; } class B { int x; } class C { void F(Object o) { o.x = 1; } }`)
+	r := resolve.New(symbols.Extract(tree))
+	offset := uint(strings.Index(string(tree.Source), "o.x") + 2)
+	node := tree.RootNode().NamedDescendantForByteRange(offset, offset)
+	if decls := r.ResolveAll(tree, node); len(decls) != 2 || decls[0].Owner != "A" || decls[1].Owner != "B" {
+		t.Errorf("ResolveAll(x) = %+v", decls)
+	}
+	if decl, ok := r.Resolve(tree, node); ok {
+		t.Errorf("Resolve(x) = %+v", decl)
+	}
+}
+
+func TestReferences(t *testing.T) {
+	baseTree := parse(t, "base.zs", base)
+	mainTree := parse(t, "main.zs", main)
+	r := resolve.New(symbols.Extract(baseTree), symbols.Extract(mainTree))
+
+	find := func(tree *zscript.Tree, line uint, before, name string) resolve.Declaration {
+		t.Helper()
+		offset := uint(len(before))
+		for _, l := range strings.Split(string(tree.Source), "\n")[:line-1] {
+			offset += uint(len(l)) + 1
+		}
+		node := tree.RootNode().NamedDescendantForByteRange(offset, offset)
+		decl, ok := r.Resolve(tree, node)
+		if !ok || decl.Name != name {
+			t.Fatalf("%s:%d: %s did not resolve", tree.Path, line, name)
+		}
+		return decl
+	}
+	refs := func(decl resolve.Declaration) []string {
+		var locs []string
+		for _, l := range r.References(decl, baseTree, mainTree) {
+			locs = append(locs, fmt.Sprintf("%s:%d:%d", l.Path, l.Range.StartPoint.Row+1, l.Range.StartPoint.Column+1))
+		}
+		return locs
+	}
+
+	tests := []struct {
+		decl resolve.Declaration
+		want []string
+	}{
+		{find(mainTree, 12, "\t\tb.", "health"), []string{"base.zs:5:6", "main.zs:5:15", "main.zs:12:5"}},
+		{find(mainTree, 10, "\t\t", "count"), []string{"main.zs:5:7", "main.zs:6:23", "main.zs:10:3"}},
+		{find(mainTree, 11, "\t\t", "Base"), []string{"base.zs:4:7", "main.zs:1:13", "main.zs:11:3"}},
+		{find(mainTree, 18, "\t\tGoto Super::", "Spawn"), []string{"base.zs:10:2", "main.zs:18:15"}},
+		{find(mainTree, 4, "\t\tSuper.", "Tick"), []string{"base.zs:8:15", "main.zs:4:9"}},
+	}
+	for _, tt := range tests {
+		if got := refs(tt.decl); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("References(%s %s) = %q, want %q", tt.decl.Kind, tt.decl.Name, got, tt.want)
+		}
+	}
+}
+
+func TestResolveEngine(t *testing.T) {
+	tree := parse(t, "a.zs", `class Imp : Actor {
+	override void BeginPlay() {
+		Super.BeginPlay();
+		Inventory item = FindInventory("Clip");
+		item.Amount = Health;
+		A_StartSound("imp/sight");
+	}
+}
+`)
+	find := func(r *resolve.Resolver, needle string) (resolve.Declaration, bool) {
+		t.Helper()
+		offset := uint(strings.Index(string(tree.Source), needle))
+		return r.Resolve(tree, tree.RootNode().NamedDescendantForByteRange(offset, offset))
+	}
+	r := resolve.NewWithEngine(version.MustParse("4.12"), symbols.Extract(tree))
+	tests := []struct {
+		needle string
+		kind   symbols.Kind
+		owner  string
+	}{
+		{"BeginPlay();", symbols.KindMethod, "Actor"},
+		{"FindInventory", symbols.KindMethod, "Actor"},
+		{"Amount", symbols.KindField, "Inventory"},
+		{"Health;", symbols.KindField, "Actor"},
+		{"A_StartSound", symbols.KindMethod, "Actor"},
+	}
+	for _, tt := range tests {
+		decl, ok := find(r, tt.needle)
+		if !ok || decl.Kind != tt.kind || decl.Owner != tt.owner || decl.Path != engine.Path {
+			t.Errorf("%s resolved to %+v, %v", tt.needle, decl, ok)
+		}
+	}
+
+	old := resolve.NewWithEngine(version.MustParse("4.0"), symbols.Extract(tree))
+	if decl, ok := find(old, "A_StartSound"); ok {
+		t.Errorf("A_StartSound resolved for 4.0 to %+v", decl)
+	}
+	if _, ok := find(resolve.New(symbols.Extract(tree)), "Amount"); ok {
+		t.Error("Amount resolved without the engine")
+	}
+}
+
+func TestSignatureHelp(t *testing.T) {
+	source := `class Imp : Actor {
+	void Fire(int count, double spread = 1.5) {
+		Fire(count, FindInventory("Clip", true));
+		A_SpawnItemEx("Ball", flags: SXF_NOCHECKPOSITION);
+		Console.Printf("%d %d", 1, 2, 3);
+		if (count > 0) Fire(
+	}
+}
+`
+	tree, err := zscript.Parse(context.Background(), []byte(source))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tree.Close()
+	tree.Path = "imp.zs"
+	r := resolve.NewWithEngine(version.MustParse("4.12"), symbols.Extract(tree))
+
+	tests := []struct {
+		// The position is just after before.
+		before string
+		label  string
+		active int
+	}{
+		{"\t\tFire(", "void Imp.Fire(int count, double spread = 1.5)", 0},
+		{"Fire(count, ", "void Imp.Fire(int count, double spread = 1.5)", 1},
+		{`FindInventory("Clip", `, "Inventory Actor.FindInventory(class<Inventory> itemtype, bool subclass = false)", 1},
+		{`FindInventory("Clip", true)`, "void Imp.Fire(int count, double spread = 1.5)", 1},
+		{`A_SpawnItemEx("Ball", flags: SXF`, "", 8},
+		{`Printf("%d %d", 1, 2, `, "void Console.Printf(string fmt, ...)", 1},
+		{"if (count > 0) Fire(", "void Imp.Fire(int count, double spread = 1.5)", 0},
+		{"if (count", "", -1},
+		{"void Fire(int count", "", -1},
+		{`Fire(count, FindInventory("Clip", true));`, "", -1},
+	}
+	for _, tt := range tests {
+		offset := uint(strings.Index(source, tt.before) + len(tt.before))
+		help, ok := r.SignatureHelp(tree, offset)
+		if tt.active < 0 {
+			if ok {
+				t.Errorf("after %q: got %+v", tt.before, help.Signatures)
+			}
+			continue
+		}
+		if !ok || len(help.Signatures) != 1 {
+			t.Errorf("after %q: got %d signatures, %v", tt.before, len(help.Signatures), ok)
+			continue
+		}
+		sig := help.Signatures[0]
+		if tt.label != "" && sig.Label != tt.label || help.ActiveParameter != tt.active {
+			t.Errorf("after %q: got %q, parameter %d; want %q, parameter %d", tt.before, sig.Label, help.ActiveParameter, tt.label, tt.active)
+		}
+		for _, p := range sig.Params {
+			if !strings.Contains(sig.Label, p) {
+				t.Errorf("after %q: parameter %q is not in %q", tt.before, p, sig.Label)
+			}
+		}
+	}
+}
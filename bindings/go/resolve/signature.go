@@ -0,0 +1,162 @@
+package resolve
+
+import (
+	"strings"
+
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+
+	zscript "github.com/jlcrochet/tree-sitter-zscript/bindings/go"
+)
+
+// Signature describes a method that a call may invoke.
+type Signature struct {
+	Declaration
+	// Label is the signature as it would be declared, such as
+	// "void Actor.A_Stop()".
+	Label string
+	// Params are the labels of the parameters, such as "int flags = 0",
+	// each a substring of Label.
+	Params []string
+}
+
+// SignatureHelp is the signature help for a call site.
+type SignatureHelp struct {
+	// Signatures are the candidates, in the order ResolveAll returns
+	// them.
+	Signatures []Signature
+	// ActiveParameter is the index of the parameter the argument at the
+	// position is passed to. It may be past the end of the parameters of
+	// a signature.
+	ActiveParameter int
+}
+
+// SignatureHelp returns the signatures of the method called by the
+// innermost call whose argument list contains offset, a byte offset in
+// tree, and the argument offset is in. ok is false if offset is not inside
+// the arguments of a call to a known method.
+//
+// The call is found by scanning the tokens before offset for an
+// unmatched "(", so that a call still being typed, which does not parse,
+// is found too.
+func (r *Resolver) SignatureHelp(tree *zscript.Tree, offset uint) (help SignatureHelp, ok bool) {
+	leaves := tokensBefore(tree.RootNode(), offset)
+
+	depth, commas := 0, 0
+	var named string
+	open := -1
+scan:
+	for i := len(leaves) - 1; i >= 0; i-- {
+		switch leaves[i].Kind() {
+		case ")", "]":
+			depth++
+		case "(", "[":
+			if depth == 0 {
+				open = i
+				break scan
+			}
+			depth--
+		case ",":
+			if depth == 0 {
+				commas++
+			}
+		case ":":
+			// A named argument, as in "flags: 0", names the parameter
+			// directly.
+			if depth == 0 && commas == 0 && named == "" && i > 0 && leaves[i-1].Kind() == zscript.NodeIdentifier {
+				named = leaves[i-1].Utf8Text(tree.Source)
+			}
+		case ";", "{", "}":
+			if depth == 0 {
+				return SignatureHelp{}, false
+			}
+		}
+	}
+	if open < 1 || leaves[open].Kind() != "(" {
+		return SignatureHelp{}, false
+	}
+	fn := leaves[open-1]
+	if k := fn.Kind(); k != zscript.NodeIdentifier && k != zscript.NodeFieldIdentifier {
+		return SignatureHelp{}, false
+	}
+	if p := leaves[open].Parent(); p != nil && p.Kind() == zscript.NodeParameterList {
+		// The parameters of a method declaration.
+		return SignatureHelp{}, false
+	}
+
+	for _, decl := range r.ResolveAll(tree, fn) {
+		if decl.Method != nil {
+			help.Signatures = append(help.Signatures, SignatureOf(decl))
+		}
+	}
+	if len(help.Signatures) == 0 {
+		return SignatureHelp{}, false
+	}
+	help.ActiveParameter = commas
+	if m := help.Signatures[0].Method; named != "" {
+		for i, p := range m.Params {
+			if strings.EqualFold(p.Name, named) {
+				help.ActiveParameter = i
+			}
+		}
+	} else if n := len(m.Params); n > 0 && commas >= n && m.Params[n-1].Variadic {
+		help.ActiveParameter = n - 1
+	}
+	return help, true
+}
+
+// tokensBefore returns the leaves of the tree under root that end at or
+// before offset, in source order, leaving out comments.
+func tokensBefore(root *tree_sitter.Node, offset uint) []*tree_sitter.Node {
+	var leaves []*tree_sitter.Node
+	var v zscript.Visitor
+	v.Enter = func(node *tree_sitter.Node) zscript.WalkAction {
+		switch {
+		case node.StartByte() >= offset:
+			return zscript.WalkStop
+		case node.Kind() == zscript.NodeComment:
+			return zscript.WalkSkipChildren
+		case node.Kind() == zscript.NodeStringLiteral || node.ChildCount() == 0:
+			// Strings are tokens; their contents may hold parentheses.
+			if node.EndByte() <= offset && !node.IsMissing() {
+				leaves = append(leaves, node)
+			}
+			return zscript.WalkSkipChildren
+		}
+		return zscript.WalkContinue
+	}
+	zscript.Walk(root, &v)
+	return leaves
+}
+
+// SignatureOf returns the signature of decl, which must be a method.
+func SignatureOf(decl Declaration) Signature {
+	m := decl.Method
+	var b strings.Builder
+	if m.ReturnType != "" {
+		b.WriteString(m.ReturnType + " ")
+	}
+	if decl.Owner != "" {
+		b.WriteString(decl.Owner + ".")
+	}
+	b.WriteString(m.Name + "(")
+	var params []string
+	for i, p := range m.Params {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		label := "..."
+		if !p.Variadic {
+			label = strings.Join(append(append([]string{}, p.Modifiers...), p.Type, p.Name), " ")
+			if p.Default != "" {
+				label += " = " + p.Default
+			}
+		}
+		b.WriteString(label)
+		params = append(params, label)
+	}
+	b.WriteString(")")
+	if m.Const {
+		b.WriteString(" const")
+	}
+	return Signature{Declaration: decl, Label: b.String(), Params: params}
+}
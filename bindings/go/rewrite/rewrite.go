@@ -0,0 +1,256 @@
+// Package rewrite applies mechanical transformations to ZScript source.
+// A rule pairs a search pattern with a replacement that may use the
+// pattern's metavariables:
+//
+//	A_PlaySound($SND, $CHAN, $$$REST) => A_StartSound($SND, $CHAN, 0, $$$REST)
+//
+// Each match of the pattern is replaced by the replacement with every
+// $NAME and $$$NAME substituted by the text it matched. When a $$$NAME
+// matched nothing, a comma next to it is dropped as well, so the rule above
+// turns A_PlaySound("a", CHAN_BODY) into A_StartSound("a", CHAN_BODY, 0).
+//
+// Matches are rewritten in one pass. Where matches overlap, the one that
+// starts first wins, then the longer one, then the one of the earlier
+// rule; the others are left as they were, and running the rewrite again
+// reaches them.
+package rewrite
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	zscript "github.com/jlcrochet/tree-sitter-zscript/bindings/go"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/diff"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/project"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/refactor"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/search"
+)
+
+// Rule is a pattern and its replacement.
+type Rule struct {
+	Pattern     string
+	Replacement string
+}
+
+// Separator separates the pattern of a rule from its replacement in the
+// text read by ParseRules.
+const Separator = "=>"
+
+// ParseRules reads rules written one per line as "pattern => replacement".
+// Blank lines and lines starting with "//" are skipped.
+func ParseRules(text string) ([]Rule, error) {
+	var rules []Rule
+	scanner := bufio.NewScanner(strings.NewReader(text))
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "//") {
+			continue
+		}
+		pattern, replacement, ok := strings.Cut(text, Separator)
+		if !ok {
+			return nil, fmt.Errorf("rewrite: line %d: missing %q", line, Separator)
+		}
+		rules = append(rules, Rule{strings.TrimSpace(pattern), strings.TrimSpace(replacement)})
+	}
+	return rules, scanner.Err()
+}
+
+// Rewriter applies a list of rules.
+type Rewriter struct {
+	rules []rule
+}
+
+type rule struct {
+	pattern     *search.Pattern
+	replacement []segment
+}
+
+// segment is a piece of a replacement: literal text, or the metavariable
+// named name if it is not "".
+type segment struct {
+	text     string
+	name     string
+	sequence bool
+}
+
+var reference = regexp.MustCompile(`^\$(\$\$)?([A-Za-z_][A-Za-z0-9_]*)`)
+
+// New compiles rules. Every metavariable of a replacement must be bound by
+// its pattern.
+func New(rules ...Rule) (*Rewriter, error) {
+	rw := &Rewriter{}
+	for _, r := range rules {
+		p, err := search.Compile(r.Pattern)
+		if err != nil {
+			return nil, err
+		}
+		segments, err := parseReplacement(r.Replacement)
+		if err != nil {
+			return nil, err
+		}
+		bound := map[string]bool{}
+		for _, name := range p.Metavariables() {
+			bound[name] = true
+		}
+		for _, s := range segments {
+			if s.name != "" && !bound[s.name] {
+				return nil, fmt.Errorf("rewrite: %q uses $%s, which %q does not bind", r.Replacement, s.name, r.Pattern)
+			}
+		}
+		rw.rules = append(rw.rules, rule{p, segments})
+	}
+	return rw, nil
+}
+
+// parseReplacement splits a replacement into text and metavariables. As in
+// patterns, a "$" inside a string or name literal is text.
+func parseReplacement(replacement string) ([]segment, error) {
+	var segments []segment
+	var text strings.Builder
+	var quote byte
+	for i := 0; i < len(replacement); i++ {
+		c := replacement[i]
+		switch {
+		case quote != 0:
+			text.WriteByte(c)
+			if c == '\\' && i+1 < len(replacement) {
+				i++
+				text.WriteByte(replacement[i])
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+			text.WriteByte(c)
+		case c == '$':
+			m := reference.FindStringSubmatch(replacement[i:])
+			if m == nil || m[2] == "_" {
+				return nil, fmt.Errorf("rewrite: invalid metavariable at offset %d of %q", i, replacement)
+			}
+			segments = append(segments, segment{text: text.String()}, segment{name: m[2], sequence: m[1] != ""})
+			text.Reset()
+			i += len(m[0]) - 1
+		default:
+			text.WriteByte(c)
+		}
+	}
+	return append(segments, segment{text: text.String()}), nil
+}
+
+// File is a rewritten file.
+type File struct {
+	Path     string
+	Old, New []byte
+	// Edits are the replacements that turn Old into New.
+	Edits []refactor.Edit
+}
+
+// Diff returns the changes to the file as a unified diff with "a/" and
+// "b/" prefixes, as git writes them.
+func (f *File) Diff() []byte {
+	name := strings.TrimPrefix(filepath.ToSlash(f.Path), "/")
+	return diff.Unified("a/"+name, "b/"+name, f.Old, f.New)
+}
+
+// Project rewrites every file of p and returns those that changed.
+func (rw *Rewriter) Project(p *project.Project) ([]*File, error) {
+	var files []*File
+	for _, f := range p.Files {
+		file, err := rw.Tree(f.Tree)
+		if err != nil {
+			return nil, err
+		}
+		if file != nil {
+			files = append(files, file)
+		}
+	}
+	return files, nil
+}
+
+// Tree rewrites tree, returning nil if no rule matches. It is an error for
+// the rewritten source to have syntax errors that tree does not.
+func (rw *Rewriter) Tree(tree *zscript.Tree) (*File, error) {
+	var candidates []refactor.Edit
+	for _, r := range rw.rules {
+		for _, m := range r.pattern.Find(tree) {
+			candidates = append(candidates, refactor.Edit{Path: tree.Path, Range: m.Node.Range(), NewText: r.expand(&m, tree.Source)})
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		a, b := candidates[i].Range, candidates[j].Range
+		if a.StartByte != b.StartByte {
+			return a.StartByte < b.StartByte
+		}
+		return a.EndByte > b.EndByte
+	})
+	var edits []refactor.Edit
+	for _, e := range candidates {
+		if len(edits) == 0 || e.Range.StartByte >= edits[len(edits)-1].Range.EndByte {
+			edits = append(edits, e)
+		}
+	}
+	if len(edits) == 0 {
+		return nil, nil
+	}
+
+	f := &File{Path: tree.Path, Old: tree.Source, Edits: edits}
+	f.New = refactor.Apply(tree.Source, tree.Path, edits)
+	if !tree.RootNode().HasError() {
+		result, err := zscript.Parse(context.Background(), f.New)
+		if err != nil {
+			return nil, err
+		}
+		defer result.Close()
+		if result.RootNode().HasError() {
+			return nil, fmt.Errorf("rewrite: %s: the rewritten source has syntax errors", tree.Path)
+		}
+	}
+	return f, nil
+}
+
+// expand returns the replacement for m. The lines of the replacement after
+// the first are indented like the line the match starts on.
+func (r *rule) expand(m *search.Result, source []byte) string {
+	indent := lineIndent(source, m.Node.StartByte())
+	var b strings.Builder
+	dropComma := false
+	for i, s := range r.replacement {
+		if s.name != "" {
+			text := m.Text(s.name, source)
+			if s.sequence && text == "" {
+				// Drop the comma after the empty sequence, or else the
+				// one before it.
+				if strings.HasPrefix(strings.TrimLeft(r.replacement[i+1].text, " \t"), ",") {
+					dropComma = true
+				} else if before := strings.TrimRight(b.String(), " \t"); strings.HasSuffix(before, ",") {
+					b.Reset()
+					b.WriteString(strings.TrimSuffix(before, ","))
+				}
+			}
+			b.WriteString(text)
+			continue
+		}
+		text := s.text
+		if dropComma {
+			text = strings.TrimLeft(strings.TrimPrefix(strings.TrimLeft(text, " \t"), ","), " \t")
+			dropComma = false
+		}
+		b.WriteString(strings.ReplaceAll(text, "\n", "\n"+indent))
+	}
+	return b.String()
+}
+
+// lineIndent returns the whitespace that starts the line holding offset.
+func lineIndent(source []byte, offset uint) string {
+	start := strings.LastIndexByte(string(source[:offset]), '\n') + 1
+	end := start
+	for end < len(source) && (source[end] == ' ' || source[end] == '\t') {
+		end++
+	}
+	return string(source[start:end])
+}
@@ -0,0 +1,172 @@
+package rewrite_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	zscript "github.com/jlcrochet/tree-sitter-zscript/bindings/go"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/project"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/rewrite"
+)
+
+const root = `#include "imp.zs"
+class Base : Actor {
+	void Noise() { A_PlaySound("base/noise", CHAN_BODY); }
+}
+`
+
+const imp = `class Imp : Base {
+	void Fire() {
+		A_PlaySound("imp/fire", CHAN_WEAPON, 0.5, false);
+		if (health > 0)
+			A_PlaySound("imp/pain", CHAN_VOICE);
+	}
+}
+`
+
+func load(t *testing.T) *project.Project {
+	t.Helper()
+	p, err := project.Load(context.Background(), fstest.MapFS{
+		"zscript.zs": {Data: []byte(root)},
+		"imp.zs":     {Data: []byte(imp)},
+	}, "zscript.zs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(p.Close)
+	return p
+}
+
+func TestProject(t *testing.T) {
+	rules, err := rewrite.ParseRules(`
+// The sound API of GZDoom 4.
+A_PlaySound($SND, $CHAN, $$$REST) => A_StartSound($SND, $CHAN, 0, $$$REST)
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rw, err := rewrite.New(rules...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	files, err := rw.Project(load(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := map[string]string{}
+	for _, f := range files {
+		got[f.Path] = string(f.New)
+	}
+	want := map[string]string{
+		"zscript.zs": strings.Replace(root, `A_PlaySound("base/noise", CHAN_BODY)`, `A_StartSound("base/noise", CHAN_BODY, 0)`, 1),
+		"imp.zs": strings.NewReplacer(
+			`A_PlaySound("imp/fire", CHAN_WEAPON, 0.5, false)`, `A_StartSound("imp/fire", CHAN_WEAPON, 0, 0.5, false)`,
+			`A_PlaySound("imp/pain", CHAN_VOICE)`, `A_StartSound("imp/pain", CHAN_VOICE, 0)`,
+		).Replace(imp),
+	}
+	if len(got) != len(want) {
+		t.Fatalf("rewrote %d files, want %d", len(got), len(want))
+	}
+	for path, text := range want {
+		if got[path] != text {
+			t.Errorf("%s:\n%s\nwant:\n%s", path, got[path], text)
+		}
+	}
+
+	for _, f := range files {
+		if f.Path != "imp.zs" {
+			continue
+		}
+		diff := string(f.Diff())
+		wantDiff := `--- a/imp.zs
++++ b/imp.zs
+@@ -1,7 +1,7 @@
+ class Imp : Base {
+ 	void Fire() {
+-		A_PlaySound("imp/fire", CHAN_WEAPON, 0.5, false);
++		A_StartSound("imp/fire", CHAN_WEAPON, 0, 0.5, false);
+ 		if (health > 0)
+-			A_PlaySound("imp/pain", CHAN_VOICE);
++			A_StartSound("imp/pain", CHAN_VOICE, 0);
+ 	}
+ }
+`
+		if diff != wantDiff {
+			t.Errorf("diff:\n%s\nwant:\n%s", diff, wantDiff)
+		}
+	}
+}
+
+func TestMultilineReplacement(t *testing.T) {
+	rw, err := rewrite.New(rewrite.Rule{
+		Pattern:     `if ($C) $S;`,
+		Replacement: "if ($C)\n{\n\t$S\n}",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := load(t)
+	f, err := rw.Tree(p.File("imp.zs").Tree)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := strings.Replace(imp, "if (health > 0)\n\t\t\tA_PlaySound(\"imp/pain\", CHAN_VOICE);",
+		"if (health > 0)\n\t\t{\n\t\t\tA_PlaySound(\"imp/pain\", CHAN_VOICE);\n\t\t}", 1)
+	if string(f.New) != want {
+		t.Errorf("got:\n%s\nwant:\n%s", f.New, want)
+	}
+}
+
+func TestStates(t *testing.T) {
+	const source = `class Imp : Actor {
+	States {
+	See:
+		TROO A 4 A_PlaySound("imp/active");
+		TROO B 4 { A_PlaySound("imp/step"); }
+		Loop;
+	}
+}
+`
+	tree, err := zscript.Parse(context.Background(), []byte(source))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tree.Close()
+	rw, err := rewrite.New(rewrite.Rule{`A_PlaySound($S)`, `A_StartSound($S)`})
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := rw.Tree(tree)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := strings.ReplaceAll(source, "A_PlaySound", "A_StartSound"); string(f.New) != want {
+		t.Errorf("got:\n%s\nwant:\n%s", f.New, want)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	tests := []rewrite.Rule{
+		{`A_PlaySound($SND)`, `A_StartSound($CHAN)`},
+		{`A_PlaySound($_)`, `A_StartSound($_)`},
+		{`A_PlaySound(`, `A_StartSound()`},
+	}
+	for _, r := range tests {
+		if _, err := rewrite.New(r); err == nil {
+			t.Errorf("New(%q => %q) succeeded", r.Pattern, r.Replacement)
+		}
+	}
+	if _, err := rewrite.ParseRules("A_PlaySound($S)"); err == nil {
+		t.Error("ParseRules accepted a rule without a replacement")
+	}
+
+	rw, err := rewrite.New(rewrite.Rule{`A_PlaySound($$$ARGS)`, `A_StartSound($$$ARGS`})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := rw.Project(load(t)); err == nil {
+		t.Error("a rewrite that breaks the syntax succeeded")
+	}
+}
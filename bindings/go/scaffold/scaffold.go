@@ -0,0 +1,207 @@
+// Package scaffold generates the files of a new mod: a ZSCRIPT root lump
+// declaring the language version, an event handler registered in MAPINFO,
+// and an example actor, laid out as GZDoom loads them from a directory or
+// PK3.
+package scaffold
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/format"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/version"
+)
+
+// Config describes the mod to generate.
+type Config struct {
+	// Name is the mod's name, which prefixes its class names and names
+	// the directory of its scripts. It must be an identifier.
+	Name string
+	// Version is the ZScript version the root lump declares.
+	Version string
+	// EventHandler adds an event handler class and the MAPINFO lump that
+	// registers it.
+	EventHandler bool
+	// Actor adds an example actor with a Default block and states.
+	Actor bool
+	// Format is how the generated ZScript is formatted.
+	Format format.Options
+}
+
+// DefaultConfig returns the configuration of a mod named name with every
+// file and the default format.
+func DefaultConfig(name string) Config {
+	return Config{
+		Name:         name,
+		Version:      "4.10",
+		EventHandler: true,
+		Actor:        true,
+		Format:       format.DefaultOptions(),
+	}
+}
+
+var identifier = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// data is what the templates are executed with.
+type data struct {
+	Name, Version, Dir string
+	Handler, Actor     string
+	Includes           []string
+}
+
+var templates = template.Must(template.New("").Parse(`
+{{define "zscript.zs"}}version "{{.Version}}"
+{{range .Includes}}
+#include "{{.}}"{{end}}
+{{end}}
+
+{{define "handler.zs"}}// {{.Handler}} is registered in MAPINFO and receives the events of
+// every level.
+class {{.Handler}} : EventHandler
+{
+	override void WorldLoaded(WorldEvent e)
+	{
+		Console.Printf("{{.Name}} loaded %s", level.MapName);
+	}
+{{- if .Actor}}
+
+	override void WorldThingSpawned(WorldEvent e)
+	{
+		if (e.Thing is "{{.Actor}}")
+		{
+			Console.Printf("A {{.Actor}} has spawned");
+		}
+	}
+{{- end}}
+}
+{{end}}
+
+{{define "actor.zs"}}// {{.Actor}} is an example actor: summon it with "summon {{.Actor}}".
+class {{.Actor}} : Actor
+{
+	Default
+	{
+		Radius 16;
+		Height 56;
+		Health 100;
+		Speed 8;
+		Monster;
+		+FLOORCLIP;
+	}
+
+	States
+	{
+	Spawn:
+		POSS AB 10 A_Look;
+		Loop;
+	See:
+		POSS AABBCCDD 4 A_Chase;
+		Loop;
+	Death:
+		POSS H 5;
+		POSS I 5 A_Scream;
+		POSS J 5 A_NoBlocking;
+		POSS K -1;
+		Stop;
+	}
+}
+{{end}}
+
+{{define "mapinfo.txt"}}GameInfo
+{
+	AddEventHandlers = "{{.Handler}}"
+}
+{{end}}
+`))
+
+// Files returns the contents of the mod's files by slash-separated path,
+// relative to the mod's root directory.
+func (c Config) Files() (map[string][]byte, error) {
+	if !identifier.MatchString(c.Name) {
+		return nil, fmt.Errorf("scaffold: mod name %q is not an identifier", c.Name)
+	}
+	if _, err := version.Parse(c.Version); err != nil {
+		return nil, fmt.Errorf("scaffold: %w", err)
+	}
+	d := data{Name: c.Name, Version: c.Version, Dir: "zscript/" + strings.ToLower(c.Name)}
+	scripts := map[string]string{}
+	if c.EventHandler {
+		d.Handler = c.Name + "Handler"
+		scripts["handler.zs"] = d.Dir + "/handler.zs"
+	}
+	if c.Actor {
+		d.Actor = c.Name + "Monster"
+		scripts["actor.zs"] = d.Dir + "/actor.zs"
+	}
+	for _, p := range scripts {
+		d.Includes = append(d.Includes, p)
+	}
+	sort.Strings(d.Includes)
+	scripts["zscript.zs"] = "zscript.zs"
+
+	files := map[string][]byte{}
+	for name, p := range scripts {
+		var buf bytes.Buffer
+		if err := templates.ExecuteTemplate(&buf, name, d); err != nil {
+			return nil, err
+		}
+		src, err := format.Source(buf.Bytes(), c.Format)
+		if err != nil {
+			return nil, fmt.Errorf("scaffold: %s: %w", p, err)
+		}
+		files[p] = src
+	}
+	if c.EventHandler {
+		var buf bytes.Buffer
+		if err := templates.ExecuteTemplate(&buf, "mapinfo.txt", d); err != nil {
+			return nil, err
+		}
+		files["mapinfo.txt"] = buf.Bytes()
+	}
+	return files, nil
+}
+
+// ErrExist is returned by Write, wrapped with the path, when one of the
+// files to write already exists.
+var ErrExist = errors.New("scaffold: file already exists")
+
+// Write writes the mod's files under dir and returns their paths, sorted.
+// It writes nothing if any of the files already exists.
+func (c Config) Write(dir string) ([]string, error) {
+	files, err := c.Files()
+	if err != nil {
+		return nil, err
+	}
+	var paths []string
+	for p := range files {
+		full := filepath.Join(dir, filepath.FromSlash(p))
+		if _, err := os.Stat(full); err == nil {
+			return nil, fmt.Errorf("%w: %s", ErrExist, full)
+		} else if !errors.Is(err, fs.ErrNotExist) {
+			return nil, err
+		}
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	written := make([]string, len(paths))
+	for i, p := range paths {
+		full := filepath.Join(dir, filepath.FromSlash(p))
+		if err := os.MkdirAll(filepath.Join(dir, filepath.FromSlash(path.Dir(p))), 0o755); err != nil {
+			return nil, err
+		}
+		if err := os.WriteFile(full, files[p], 0o644); err != nil {
+			return nil, err
+		}
+		written[i] = full
+	}
+	return written, nil
+}
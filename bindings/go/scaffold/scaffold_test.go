@@ -0,0 +1,95 @@
+package scaffold_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/format"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/mapinfo"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/project"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/scaffold"
+)
+
+func TestWrite(t *testing.T) {
+	dir := t.TempDir()
+	paths, err := scaffold.DefaultConfig("Frob").Write(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var rel []string
+	for _, p := range paths {
+		r, _ := filepath.Rel(dir, p)
+		rel = append(rel, filepath.ToSlash(r))
+	}
+	want := []string{"mapinfo.txt", "zscript.zs", "zscript/frob/actor.zs", "zscript/frob/handler.zs"}
+	if !slices.Equal(rel, want) {
+		t.Errorf("wrote %q, want %q", rel, want)
+	}
+
+	p, err := project.LoadDir(context.Background(), dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+	if len(p.Files) != 3 || len(p.Diagnostics) != 0 {
+		t.Errorf("project has %d files and diagnostics %v", len(p.Files), p.Diagnostics)
+	}
+	for _, f := range p.Files {
+		if f.Tree.RootNode().HasError() {
+			t.Errorf("%s has syntax errors", f.Path)
+		}
+	}
+
+	src, err := os.ReadFile(filepath.Join(dir, "mapinfo.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	info, err := mapinfo.Parse("mapinfo.txt", src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if findings := mapinfo.Check(p, info); len(findings) != 0 {
+		t.Errorf("MAPINFO findings: %v", findings)
+	}
+
+	if _, err := scaffold.DefaultConfig("Frob").Write(dir); !errors.Is(err, scaffold.ErrExist) {
+		t.Errorf("second Write: got %v, want ErrExist", err)
+	}
+}
+
+func TestFiles(t *testing.T) {
+	c := scaffold.DefaultConfig("Frob")
+	c.EventHandler = false
+	c.Version = "4.12"
+	c.Format = format.Options{UseSpaces: true, IndentWidth: 2, BraceStyle: format.BraceNextLine}
+	files, err := c.Files()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := files["mapinfo.txt"]; ok {
+		t.Error("MAPINFO generated without an event handler")
+	}
+	root := string(files["zscript.zs"])
+	if !strings.HasPrefix(root, `version "4.12"`) || strings.Contains(root, "handler") {
+		t.Errorf("zscript.zs:\n%s", root)
+	}
+	if actor := string(files["zscript/frob/actor.zs"]); !strings.Contains(actor, "class FrobMonster : Actor\n{\n  Default\n") {
+		t.Errorf("actor.zs is not formatted as asked:\n%s", actor)
+	}
+
+	for _, name := range []string{"", "1up", "my mod"} {
+		if _, err := scaffold.DefaultConfig(name).Files(); err == nil {
+			t.Errorf("name %q accepted", name)
+		}
+	}
+	c = scaffold.DefaultConfig("Frob")
+	c.Version = "four"
+	if _, err := c.Files(); err == nil {
+		t.Error("invalid version accepted")
+	}
+}
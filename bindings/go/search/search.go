@@ -0,0 +1,479 @@
+// Package search finds code by structure rather than by text. A pattern is
+// a ZScript snippet, such as an expression, statement, member or state
+// line, that may contain metavariables:
+//
+//	$NAME  matches any single node and binds it to NAME
+//	$_     matches any single node without binding it
+//	$$$    matches any number of nodes, including none, in a list
+//	$$$NAME  is like $$$ and binds the nodes to NAME
+//
+// In a statement, "$S;" matches any statement, not only an expression.
+// A list, such as the arguments of a call, may hold only one $$$NAME but
+// any number of $$$. A metavariable used more than once must match the same text each time,
+// so "$X == $X" finds comparisons of an expression with itself. Otherwise a
+// pattern matches code with the same tree, ignoring whitespace, comments
+// and the case of identifiers, names and keywords:
+//
+//	A_StartSound($SND, $_)
+//
+// matches every call of A_StartSound with exactly two arguments, in code
+// or as the action of a state line, and binds the first to SND.
+//
+// Patterns are compiled into tree-sitter queries, so a Pattern can be run
+// over many files cheaply.
+package search
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+
+	zscript "github.com/jlcrochet/tree-sitter-zscript/bindings/go"
+)
+
+// Result is a match of a pattern.
+type Result struct {
+	// Path is the path of the tree the match was found in.
+	Path string
+	// Node is the node that matches the whole pattern.
+	Node tree_sitter.Node
+	// Bindings maps each named metavariable, without the "$", to the node
+	// it matched.
+	Bindings map[string]tree_sitter.Node
+	// Sequences maps each $$$NAME, without the "$$$", to the nodes it
+	// matched, which may be none.
+	Sequences map[string][]tree_sitter.Node
+}
+
+// Text returns the source text that the metavariable name matched in
+// source, the text of the tree searched. For a $$$NAME it is the text from
+// the first node to the last, or "" if there are none.
+func (r *Result) Text(name string, source []byte) string {
+	if node, ok := r.Bindings[name]; ok {
+		return node.Utf8Text(source)
+	}
+	nodes := r.Sequences[name]
+	if len(nodes) == 0 {
+		return ""
+	}
+	return string(source[nodes[0].StartByte():nodes[len(nodes)-1].EndByte()])
+}
+
+// Pattern is a compiled pattern.
+type Pattern struct {
+	source string
+	query  *zscript.CachedQuery
+	// literals maps the internal captures of leaves to the text they must
+	// have.
+	literals map[string]string
+	// empty holds the internal captures of nodes that must have no named
+	// children.
+	empty map[string]bool
+	// sequences maps the internal captures of lists with a $$$NAME to
+	// where it lies in them.
+	sequences map[string]sequence
+	// names holds the metavariable names, in order of first use.
+	names []string
+}
+
+// sequence locates a $$$NAME in a list: it matches the list items, the
+// named children without a field, except the first before and the last
+// after.
+type sequence struct {
+	name          string
+	before, after int
+}
+
+const (
+	// placeholder replaces "$" in a pattern so that it parses; the rest of
+	// the metavariable name follows it.
+	placeholder = "__zs_search_"
+	// repeat replaces "$$$"; the name of a $$$NAME follows it.
+	repeat = "__zs_repeat_"
+	// rootCapture and internalCapture use a "." so they cannot clash with
+	// metavariable names.
+	rootCapture     = "search.root"
+	internalCapture = "search.c"
+)
+
+var metavariable = regexp.MustCompile(`^\$([A-Za-z_][A-Za-z0-9_]*)`)
+
+// contexts are the snippets a pattern is parsed in, in order of
+// preference; "%s" stands for the pattern.
+var contexts = []string{
+	// An expression.
+	"class __zs_search { void __zs_search() { %s; } }",
+	// A statement.
+	"class __zs_search { void __zs_search() { %s\n} }",
+	// A class member.
+	"class __zs_search { %s\n}",
+	// A state line.
+	"class __zs_search : Actor { States { %s\n} }",
+	// A top-level declaration.
+	"%s\n",
+}
+
+// Compile compiles pattern.
+func Compile(pattern string) (*Pattern, error) {
+	text, err := replaceMetavariables(pattern)
+	if err != nil {
+		return nil, err
+	}
+	text = strings.TrimSpace(text)
+	if text == "" || strings.HasPrefix(text, repeat) && isIdentifier(text) {
+		return nil, fmt.Errorf("search: pattern %q matches nothing in particular", pattern)
+	}
+	for _, wrapper := range contexts {
+		prefix, _, _ := strings.Cut(wrapper, "%s")
+		source := []byte(fmt.Sprintf(wrapper, text))
+		tree, err := zscript.Parse(context.Background(), source)
+		if err != nil {
+			return nil, err
+		}
+		root := tree.RootNode()
+		start, end := uint(len(prefix)), uint(len(prefix)+len(text))
+		node := root.NamedDescendantForByteRange(start, end)
+		if root.HasError() || node == nil || node.StartByte() != start || node.EndByte() != end {
+			tree.Close()
+			continue
+		}
+		p := &Pattern{source: pattern, literals: map[string]string{}, empty: map[string]bool{}, sequences: map[string]sequence{}}
+		c := &compiler{p: p, source: source}
+		query := c.node(node, "") + " @" + rootCapture
+		tree.Close()
+		if c.err != nil {
+			return nil, c.err
+		}
+		if p.query, err = zscript.Query(query); err != nil {
+			return nil, fmt.Errorf("search: compiling %q: %w", pattern, err)
+		}
+		return p, nil
+	}
+	return nil, fmt.Errorf("search: %q is not an expression, statement, declaration or state line", pattern)
+}
+
+// replaceMetavariables replaces the metavariables of pattern, outside
+// string and name literals, with identifiers.
+func replaceMetavariables(pattern string) (string, error) {
+	var b strings.Builder
+	var quote byte
+	for i := 0; i < len(pattern); i++ {
+		c := pattern[i]
+		switch {
+		case quote != 0:
+			b.WriteByte(c)
+			if c == '\\' && i+1 < len(pattern) {
+				i++
+				b.WriteByte(pattern[i])
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+			b.WriteByte(c)
+		case strings.HasPrefix(pattern[i:], "$$$"):
+			b.WriteString(repeat)
+			i += 2
+			if m := metavariable.FindStringSubmatch(pattern[i:]); m != nil {
+				b.WriteString(m[1])
+				i += len(m[0]) - 1
+			}
+			// Before a closing brace, $$$ stands for statements.
+			if rest := strings.TrimSpace(pattern[i+1:]); strings.HasPrefix(rest, "}") {
+				b.WriteByte(';')
+			}
+		case c == '$':
+			m := metavariable.FindStringSubmatch(pattern[i:])
+			if m == nil {
+				return "", fmt.Errorf("search: invalid metavariable at offset %d of %q", i, pattern)
+			}
+			b.WriteString(placeholder + m[1])
+			i += len(m[0]) - 1
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String(), nil
+}
+
+// String returns the source of the pattern.
+func (p *Pattern) String() string {
+	return p.source
+}
+
+// Query returns the tree-sitter query the pattern was compiled to.
+func (p *Pattern) Query() string {
+	return p.query.Pattern
+}
+
+// Metavariables returns the names of the metavariables the pattern
+// binds, in order of first use.
+func (p *Pattern) Metavariables() []string {
+	return append([]string(nil), p.names...)
+}
+
+// Find returns the matches of p in tree, in source order. A node matched
+// in more than one way is returned once, with the first bindings found.
+func (p *Pattern) Find(tree *zscript.Tree) []Result {
+	var results []Result
+	seen := map[uintptr]bool{}
+	for m := range p.query.Matches(tree.RootNode(), tree.Source) {
+		r, ok := p.result(m, tree)
+		if !ok || seen[r.Node.Id()] {
+			continue
+		}
+		seen[r.Node.Id()] = true
+		results = append(results, r)
+	}
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Node.StartByte() < results[j].Node.StartByte()
+	})
+	return results
+}
+
+// result checks the text constraints of m and returns the match.
+func (p *Pattern) result(m *zscript.Match, tree *zscript.Tree) (Result, bool) {
+	r := Result{Path: tree.Path, Bindings: map[string]tree_sitter.Node{}, Sequences: map[string][]tree_sitter.Node{}}
+	for _, c := range m.Captures {
+		seq, isSequence := p.sequences[c.Name]
+		switch {
+		case c.Name == rootCapture:
+			r.Node = c.Node
+		case isSequence:
+			items := listItems(&c.Node)
+			if seq.before+seq.after > len(items) {
+				return Result{}, false
+			}
+			r.Sequences[seq.name] = items[seq.before : len(items)-seq.after]
+		case p.empty[c.Name]:
+			if namedChildren(&c.Node) > 0 {
+				return Result{}, false
+			}
+		case strings.HasPrefix(c.Name, internalCapture):
+			if !sameText(c.Node.Kind(), normalize(c.Node.Utf8Text(tree.Source)), p.literals[c.Name]) {
+				return Result{}, false
+			}
+		default:
+			if prev, ok := r.Bindings[c.Name]; ok {
+				if !sameText(c.Node.Kind(), normalize(prev.Utf8Text(tree.Source)), normalize(c.Node.Utf8Text(tree.Source))) {
+					return Result{}, false
+				}
+				continue
+			}
+			r.Bindings[c.Name] = c.Node
+		}
+	}
+	return r, true
+}
+
+// Match compiles pattern and returns its matches in tree.
+func Match(pattern string, tree *zscript.Tree) ([]Result, error) {
+	p, err := Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	return p.Find(tree), nil
+}
+
+// compiler translates the tree of a pattern into a query.
+type compiler struct {
+	p        *Pattern
+	source   []byte
+	captures int
+	err      error
+}
+
+func (c *compiler) capture() string {
+	c.captures++
+	return internalCapture + strconv.Itoa(c.captures)
+}
+
+// node returns the query for node, prefixed with field if it is not "".
+func (c *compiler) node(node *tree_sitter.Node, field string) string {
+	if field != "" {
+		field += ": "
+	}
+	text := node.Utf8Text(c.source)
+	if node.Kind() == zscript.NodeExpressionStatement {
+		// "$S;" stands for any statement.
+		text = strings.TrimSpace(strings.TrimSuffix(text, ";"))
+	}
+	if name, ok := strings.CutPrefix(text, placeholder); ok && isIdentifier(text) {
+		if name == "_" {
+			return field + "(_)"
+		}
+		c.use(name)
+		return field + "(_) @" + name
+	}
+	if node.ChildCount() == 0 {
+		capture := c.capture()
+		c.p.literals[capture] = normalize(text)
+		return fmt.Sprintf("%s(%s) @%s", field, node.Kind(), capture)
+	}
+
+	var parts []string
+	named, items, repeats := 0, 0, 0
+	var seq *sequence
+	anchor := true
+	cursor := node.Walk()
+	defer cursor.Close()
+	for ok := cursor.GotoFirstChild(); ok; ok = cursor.GotoNextSibling() {
+		child := cursor.Node()
+		childField := cursor.FieldName()
+		switch {
+		case child.Kind() == zscript.NodeComment:
+		case !child.IsNamed():
+			// Punctuation only separates the named children, but
+			// keywords and operators distinguish trees of the same kind.
+			if childField != "" || isWord(child.Kind()) {
+				parts = append(parts, fieldPrefix(childField)+strconv.Quote(child.Kind()))
+			}
+		default:
+			if name, ok := repeatName(child, c.source); ok {
+				repeats++
+				if name != "" {
+					seq = &sequence{name: name, before: items}
+					c.use(name)
+				}
+				anchor = false
+				continue
+			}
+			if anchor {
+				parts = append(parts, ".")
+			}
+			parts = append(parts, c.node(child, childField))
+			named++
+			if childField == "" {
+				items++
+			}
+			anchor = true
+		}
+	}
+	if named > 0 && anchor {
+		parts = append(parts, ".")
+	}
+	body := ""
+	if len(parts) > 0 {
+		body = " " + strings.Join(parts, " ")
+	}
+	query := "(" + node.Kind() + body + ")"
+	if isActionCall(node) {
+		// A call of a plain function also matches the action of a state
+		// line, which has the same fields.
+		query = "[" + query + " (" + zscript.NodeStateActionCall + body + ")]"
+	}
+	switch {
+	case seq != nil && repeats > 1:
+		if c.err == nil {
+			c.err = fmt.Errorf("search: $$$%s shares a list with another $$$", seq.name)
+		}
+	case seq != nil:
+		seq.after = items - seq.before
+		capture := c.capture()
+		c.p.sequences[capture] = *seq
+		query += " @" + capture
+	case named == 0 && repeats == 0:
+		capture := c.capture()
+		c.p.empty[capture] = true
+		query += " @" + capture
+	}
+	return field + query
+}
+
+// isActionCall reports whether node is a call that could also be written
+// as the action of a state line: one of a function named by an identifier.
+func isActionCall(node *tree_sitter.Node) bool {
+	if node.Kind() != zscript.NodeCallExpression {
+		return false
+	}
+	function := node.ChildByFieldName(zscript.FieldFunction)
+	return function != nil && function.Kind() == zscript.NodeIdentifier
+}
+
+// use records a metavariable name.
+func (c *compiler) use(name string) {
+	if !slices.Contains(c.p.names, name) {
+		c.p.names = append(c.p.names, name)
+	}
+}
+
+// repeatName reports whether node stands for a $$$ or $$$NAME, either by
+// itself or as a statement, and returns the name.
+func repeatName(node *tree_sitter.Node, source []byte) (string, bool) {
+	text := strings.TrimSuffix(strings.TrimSpace(node.Utf8Text(source)), ";")
+	text = strings.TrimSpace(text)
+	name, ok := strings.CutPrefix(text, repeat)
+	return name, ok && isIdentifier(text)
+}
+
+// listItems returns the named children of node that have no field name,
+// other than comments.
+func listItems(node *tree_sitter.Node) []tree_sitter.Node {
+	var items []tree_sitter.Node
+	cursor := node.Walk()
+	defer cursor.Close()
+	for ok := cursor.GotoFirstChild(); ok; ok = cursor.GotoNextSibling() {
+		child := cursor.Node()
+		if child.IsNamed() && child.Kind() != zscript.NodeComment && cursor.FieldName() == "" {
+			items = append(items, *child)
+		}
+	}
+	return items
+}
+
+func fieldPrefix(field string) string {
+	if field == "" {
+		return ""
+	}
+	return field + ": "
+}
+
+// namedChildren counts the named children of node other than comments.
+func namedChildren(node *tree_sitter.Node) int {
+	n := 0
+	for i := uint(0); i < node.NamedChildCount(); i++ {
+		if node.NamedChild(i).Kind() != zscript.NodeComment {
+			n++
+		}
+	}
+	return n
+}
+
+// sameText reports whether two texts of a node of the given kind are
+// equivalent; identifiers, names and keywords ignore case.
+func sameText(kind, a, b string) bool {
+	switch kind {
+	case zscript.NodeStringLiteral, zscript.NodeStringContent:
+		return a == b
+	}
+	return strings.EqualFold(a, b)
+}
+
+// normalize collapses the runs of whitespace in text to single spaces.
+func normalize(text string) string {
+	return strings.Join(strings.Fields(text), " ")
+}
+
+func isIdentifier(text string) bool {
+	for _, r := range text {
+		if !(r == '_' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9') {
+			return false
+		}
+	}
+	return text != ""
+}
+
+func isWord(kind string) bool {
+	for _, r := range kind {
+		if !(r == '_' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z') {
+			return false
+		}
+	}
+	return kind != ""
+}
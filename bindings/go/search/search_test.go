@@ -0,0 +1,152 @@
+package search_test
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+
+	zscript "github.com/jlcrochet/tree-sitter-zscript/bindings/go"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/search"
+)
+
+const source = `class Imp : Actor {
+	private int heat;
+	int count;
+
+	void Fire() {
+		A_StartSound("imp/fire", CHAN_WEAPON);
+		a_startsound("imp/fire" /* again */, CHAN_AUTO, CHANF_OVERLAP);
+		A_StartSound("imp/sight",
+			CHAN_VOICE);
+		if (heat == heat) return;
+		if (heat == count) return;
+		count = count + 1;
+		count = count - 1;
+		Console.Printf("$SND");
+	}
+
+	States {
+	Spawn:
+		TROO A 10 A_Look;
+		Loop;
+	See:
+		TROO B 4 A_StartSound("imp/active", CHAN_BODY);
+		Loop;
+	}
+}
+`
+
+func parse(t *testing.T) *zscript.Tree {
+	t.Helper()
+	tree, err := zscript.Parse(context.Background(), []byte(source))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(tree.Close)
+	return tree
+}
+
+// describe formats each match as its text followed by its bindings in
+// sorted order, with whitespace collapsed.
+func describe(tree *zscript.Tree, results []search.Result) []string {
+	var out []string
+	for _, r := range results {
+		s := strings.Join(strings.Fields(r.Node.Utf8Text(tree.Source)), " ")
+		var names []string
+		for name := range r.Bindings {
+			names = append(names, name)
+		}
+		slices.Sort(names)
+		for _, name := range names {
+			node := r.Bindings[name]
+			s += fmt.Sprintf(" $%s=%s", name, node.Utf8Text(tree.Source))
+		}
+		out = append(out, s)
+	}
+	return out
+}
+
+func TestMatch(t *testing.T) {
+	tree := parse(t)
+	tests := []struct {
+		pattern string
+		want    []string
+	}{
+		{`A_StartSound($SND, $_)`, []string{
+			`A_StartSound("imp/fire", CHAN_WEAPON) $SND="imp/fire"`,
+			`A_StartSound("imp/sight", CHAN_VOICE) $SND="imp/sight"`,
+			`A_StartSound("imp/active", CHAN_BODY) $SND="imp/active"`,
+		}},
+		{`A_StartSound("imp/fire", $$$)`, []string{
+			`A_StartSound("imp/fire", CHAN_WEAPON)`,
+			`a_startsound("imp/fire" /* again */, CHAN_AUTO, CHANF_OVERLAP)`,
+		}},
+		{`if ($X == $X) return;`, []string{`if (heat == heat) return; $X=heat`}},
+		{`$A + $B`, []string{`count + 1 $A=count $B=1`}},
+		{`count = $_;`, []string{`count = count + 1;`, `count = count - 1;`}},
+		{`private int $NAME;`, []string{`private int heat; $NAME=heat`}},
+		{`TROO A 10 $ACTION`, []string{`TROO A 10 A_Look; $ACTION=A_Look`}},
+		{`Console.Printf($$$)`, []string{`Console.Printf("$SND")`}},
+		{`"$SND"`, []string{`"$SND"`}},
+		{`A_StartSound()`, nil},
+	}
+	for _, test := range tests {
+		results, err := search.Match(test.pattern, tree)
+		if err != nil {
+			t.Errorf("%s: %v", test.pattern, err)
+			continue
+		}
+		if got := describe(tree, results); !slices.Equal(got, test.want) {
+			t.Errorf("%s matched:\n%s\nwant:\n%s", test.pattern, strings.Join(got, "\n"), strings.Join(test.want, "\n"))
+		}
+	}
+}
+
+func TestSequences(t *testing.T) {
+	tree := parse(t)
+	results, err := search.Match(`A_StartSound($SND, $$$REST)`, tree)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, r := range results {
+		got = append(got, r.Text("SND", tree.Source)+" "+r.Text("REST", tree.Source))
+	}
+	want := []string{
+		`"imp/fire" CHAN_WEAPON`,
+		`"imp/fire" CHAN_AUTO, CHANF_OVERLAP`,
+		`"imp/sight" CHAN_VOICE`,
+		`"imp/active" CHAN_BODY`,
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	results, err = search.Match(`void Fire() { $$$BODY }`, tree)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || len(results[0].Sequences["BODY"]) != 8 {
+		t.Errorf("void Fire() { $$$BODY } matched %d times", len(results))
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	for _, pattern := range []string{``, `$$$`, `$1`, `A_StartSound(`, `int x; int y;`, `f($$$A, $$$B)`} {
+		if _, err := search.Compile(pattern); err == nil {
+			t.Errorf("Compile(%q) succeeded", pattern)
+		}
+	}
+}
+
+func TestMetavariables(t *testing.T) {
+	p, err := search.Compile(`$A.Damage($B, $A, $_)`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := p.Metavariables(), []string{"A", "B"}; !slices.Equal(got, want) {
+		t.Errorf("Metavariables() = %v, want %v", got, want)
+	}
+}
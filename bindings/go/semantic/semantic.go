@@ -0,0 +1,238 @@
+// Package semantic classifies the identifiers of a ZScript file by what
+// they refer to, for editors that color code by role rather than syntax,
+// and encodes the result as Language Server Protocol semantic tokens.
+package semantic
+
+import (
+	"sort"
+	"strings"
+
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+
+	zscript "github.com/jlcrochet/tree-sitter-zscript/bindings/go"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/positions"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/resolve"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/symbols"
+)
+
+// Type is a token type. Its value is the index of its name in TokenTypes.
+type Type uint32
+
+const (
+	TypeClass Type = iota
+	TypeStruct
+	TypeEnum
+	TypeEnumMember
+	TypeProperty
+	TypeMethod
+	TypeFunction
+	TypeVariable
+	TypeParameter
+	TypeLabel
+)
+
+// TokenTypes is the legend of token type names, indexed by Type.
+var TokenTypes = []string{
+	TypeClass:      "class",
+	TypeStruct:     "struct",
+	TypeEnum:       "enum",
+	TypeEnumMember: "enumMember",
+	TypeProperty:   "property",
+	TypeMethod:     "method",
+	TypeFunction:   "function",
+	TypeVariable:   "variable",
+	TypeParameter:  "parameter",
+	TypeLabel:      "label",
+}
+
+func (t Type) String() string {
+	if int(t) < len(TokenTypes) {
+		return TokenTypes[t]
+	}
+	return "unknown"
+}
+
+// Modifiers is a set of token modifiers. Bit i stands for
+// TokenModifiers[i].
+type Modifiers uint32
+
+const (
+	ModDeclaration Modifiers = 1 << iota
+	ModReadonly
+	ModStatic
+	ModDeprecated
+	ModVirtual
+	// ModAction marks action functions: methods declared with the action
+	// modifier and functions called from states.
+	ModAction
+)
+
+// TokenModifiers is the legend of token modifier names, in bit order.
+var TokenModifiers = []string{"declaration", "readonly", "static", "deprecated", "virtual", "action"}
+
+// Has reports whether m includes every modifier in o.
+func (m Modifiers) Has(o Modifiers) bool {
+	return m&o == o
+}
+
+// Token is a classified identifier.
+type Token struct {
+	Range     tree_sitter.Range
+	Type      Type
+	Modifiers Modifiers
+}
+
+// Tokens classifies the identifiers of tree, resolving them with r, and
+// returns them in source order. Identifiers that do not resolve are
+// classified by their syntax when that is unambiguous, such as the type
+// name of an engine class or a call from a state, and omitted otherwise.
+func Tokens(r *resolve.Resolver, tree *zscript.Tree) []Token {
+	var tokens []Token
+	add := func(node *tree_sitter.Node, t Type, m Modifiers) {
+		if node.StartPosition().Row == node.EndPosition().Row {
+			tokens = append(tokens, Token{Range: node.Range(), Type: t, Modifiers: m})
+		}
+	}
+
+	var v zscript.Visitor
+	v.On(zscript.NodeStateLabelName, func(node *tree_sitter.Node) zscript.WalkAction {
+		m := Modifiers(0)
+		if node.Parent() != nil && node.Parent().Kind() == zscript.NodeStateLabel {
+			m = ModDeclaration
+		}
+		add(node, TypeLabel, m)
+		return zscript.WalkSkipChildren
+	})
+	visit := func(node *tree_sitter.Node) zscript.WalkAction {
+		if decl, ok := r.Resolve(tree, node); ok {
+			t, m := classify(decl)
+			if decl.Path == tree.Path && decl.NameRange.StartByte == node.StartByte() {
+				m |= ModDeclaration
+			}
+			if isStateCall(node) {
+				m |= ModAction
+			}
+			add(node, t, m)
+		} else if t, m, ok := bySyntax(node); ok {
+			add(node, t, m)
+		}
+		return zscript.WalkContinue
+	}
+	v.On(zscript.NodeIdentifier, visit)
+	v.On(zscript.NodeFieldIdentifier, visit)
+	v.On(zscript.NodeTypeIdentifier, visit)
+	zscript.Walk(tree.RootNode(), &v)
+
+	sort.SliceStable(tokens, func(i, j int) bool { return tokens[i].Range.StartByte < tokens[j].Range.StartByte })
+	return tokens
+}
+
+// classify returns the token type and modifiers for a declaration.
+func classify(decl resolve.Declaration) (Type, Modifiers) {
+	var m Modifiers
+	for _, mod := range decl.Modifiers {
+		switch mod {
+		case "static":
+			m |= ModStatic
+		case "deprecated":
+			m |= ModDeprecated
+		case "virtual", "override":
+			m |= ModVirtual
+		case "action":
+			m |= ModAction
+		case "readonly":
+			m |= ModReadonly
+		}
+	}
+	switch decl.Kind {
+	case symbols.KindClass:
+		return TypeClass, m
+	case symbols.KindStruct:
+		return TypeStruct, m
+	case symbols.KindEnum:
+		return TypeEnum, m
+	case symbols.KindEnumerator:
+		return TypeEnumMember, m | ModReadonly
+	case symbols.KindConst:
+		return TypeVariable, m | ModReadonly | ModStatic
+	case symbols.KindField, symbols.KindProperty, symbols.KindFlag:
+		return TypeProperty, m
+	case symbols.KindMethod:
+		return TypeMethod, m
+	case symbols.KindParameter:
+		return TypeParameter, m
+	case symbols.KindStateLabel:
+		return TypeLabel, m
+	}
+	return TypeVariable, m
+}
+
+// bySyntax classifies an identifier that does not resolve by where it
+// appears.
+func bySyntax(node *tree_sitter.Node) (Type, Modifiers, bool) {
+	if node.Kind() == zscript.NodeTypeIdentifier {
+		return TypeClass, 0, true
+	}
+	parent := node.Parent()
+	if parent == nil {
+		return 0, 0, false
+	}
+	switch parent.Kind() {
+	case zscript.NodeStateActionCall:
+		return TypeFunction, ModAction, true
+	case zscript.NodeCallExpression:
+		if fn := parent.ChildByFieldName(zscript.FieldFunction); fn != nil && fn.Id() == node.Id() {
+			return TypeFunction, 0, true
+		}
+	case zscript.NodeFieldExpression:
+		if grand := parent.Parent(); grand != nil && grand.Kind() == zscript.NodeCallExpression {
+			if fn := grand.ChildByFieldName(zscript.FieldFunction); fn != nil && fn.Id() == parent.Id() && node.Kind() == zscript.NodeFieldIdentifier {
+				return TypeMethod, 0, true
+			}
+		}
+	}
+	return 0, 0, false
+}
+
+// isStateCall reports whether node names the function called by a state.
+func isStateCall(node *tree_sitter.Node) bool {
+	parent := node.Parent()
+	return parent != nil && parent.Kind() == zscript.NodeStateActionCall
+}
+
+// Encode returns tokens in the relative format of the Language Server
+// Protocol's textDocument/semanticTokens response, with positions counted
+// in the units of enc. The tokens must be in source order.
+func Encode(tokens []Token, source []byte, enc zscript.PositionEncoding) []uint32 {
+	data := make([]uint32, 0, 5*len(tokens))
+	var prevLine, prevChar uint32
+	for _, t := range tokens {
+		start := t.Range.StartByte - uint(t.Range.StartPoint.Column)
+		line := uint32(t.Range.StartPoint.Row)
+		char := uint32(positions.Count(source[start:t.Range.StartByte], enc))
+		length := uint32(positions.Count(source[t.Range.StartByte:t.Range.EndByte], enc))
+		if line != prevLine {
+			prevChar = 0
+		}
+		data = append(data, line-prevLine, char-prevChar, length, uint32(t.Type), uint32(t.Modifiers))
+		prevLine, prevChar = line, char
+	}
+	return data
+}
+
+// Describe returns the names of the modifiers in m.
+func Describe(m Modifiers) []string {
+	var names []string
+	for i, name := range TokenModifiers {
+		if m&(1<<i) != 0 {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// String formats a token's type and modifiers, such as
+// "method.declaration.virtual".
+func (t Token) String() string {
+	return strings.Join(append([]string{t.Type.String()}, Describe(t.Modifiers)...), ".")
+}
@@ -0,0 +1,114 @@
+package semantic_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+
+	zscript "github.com/jlcrochet/tree-sitter-zscript/bindings/go"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/resolve"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/semantic"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/symbols"
+)
+
+const source = `class Base : Actor {
+	const MAXV = 3;
+	enum EMode { M_One }
+	int health;
+	deprecated void Old() {}
+	action void A_Boom() {}
+	override void Tick() {
+		int c = health + MAXV + M_One;
+		A_SpawnItemEx("Imp");
+		Old();
+	}
+	States {
+	Spawn:
+		TNT1 A 1 A_Boom;
+		TNT1 A 1 A_Chase();
+		Goto Spawn;
+	}
+}
+`
+
+func parse(t *testing.T) *zscript.Tree {
+	t.Helper()
+	tree, err := zscript.Parse(context.Background(), []byte(source))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(tree.Close)
+	if tree.RootNode().HasError() {
+		t.Fatalf("parse error: %s", tree.RootNode().ToSexp())
+	}
+	tree.Path = "base.zs"
+	return tree
+}
+
+func TestTokens(t *testing.T) {
+	tree := parse(t)
+	r := resolve.New(symbols.Extract(tree))
+
+	var got []string
+	for _, tok := range semantic.Tokens(r, tree) {
+		got = append(got, source[tok.Range.StartByte:tok.Range.EndByte]+" "+tok.String())
+	}
+	want := []string{
+		"Base class.declaration",
+		"Actor class",
+		"MAXV variable.declaration.readonly.static",
+		"EMode enum.declaration",
+		"M_One enumMember.declaration.readonly",
+		"health property.declaration",
+		"Old method.declaration.deprecated",
+		"A_Boom method.declaration.action",
+		"Tick method.declaration.virtual",
+		"c variable.declaration",
+		"health property",
+		"MAXV variable.readonly.static",
+		"M_One enumMember.readonly",
+		"A_SpawnItemEx function",
+		"Old method.deprecated",
+		"Spawn label.declaration",
+		"A_Boom method.action",
+		"A_Chase function.action",
+		"Spawn label",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("tokens =\n%q\nwant\n%q", got, want)
+	}
+}
+
+func TestEncode(t *testing.T) {
+	src := []byte("s = \"😀\"; x;\ny")
+	tokens := []semantic.Token{
+		{Range: span(0, 0, 0, 1), Type: semantic.TypeVariable},
+		{Range: span(0, 12, 12, 13), Type: semantic.TypeVariable, Modifiers: semantic.ModReadonly},
+		{Range: span(1, 0, 15, 16), Type: semantic.TypeParameter},
+	}
+	tests := []struct {
+		enc  zscript.PositionEncoding
+		want []uint32
+	}{
+		{zscript.EncodingUTF8, []uint32{0, 0, 1, 7, 0, 0, 12, 1, 7, 2, 1, 0, 1, 8, 0}},
+		{zscript.EncodingUTF16, []uint32{0, 0, 1, 7, 0, 0, 10, 1, 7, 2, 1, 0, 1, 8, 0}},
+		{zscript.EncodingUTF32, []uint32{0, 0, 1, 7, 0, 0, 9, 1, 7, 2, 1, 0, 1, 8, 0}},
+	}
+	for _, tt := range tests {
+		if got := semantic.Encode(tokens, src, tt.enc); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Encode(%v) = %v, want %v", tt.enc, got, tt.want)
+		}
+	}
+}
+
+// span returns a single-line range starting at the given row and column.
+func span(row, column, start, end uint) tree_sitter.Range {
+	return tree_sitter.Range{
+		StartByte:  start,
+		EndByte:    end,
+		StartPoint: tree_sitter.Point{Row: row, Column: column},
+		EndPoint:   tree_sitter.Point{Row: row, Column: column + end - start},
+	}
+}
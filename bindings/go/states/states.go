@@ -0,0 +1,344 @@
+// Package states interprets the States blocks of ZScript classes as
+// structured data: labels, states with their sprite, frames, duration,
+// modifiers and action, and the flow keywords that end each sequence.
+package states
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+
+	zscript "github.com/jlcrochet/tree-sitter-zscript/bindings/go"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/zscriptast"
+)
+
+// Block is a States block.
+type Block struct {
+	// Options are the names in the optional parenthesized list after
+	// States, such as Actor or Overlay.
+	Options []string
+	Labels  []*Label
+	Range   tree_sitter.Range
+}
+
+// Label is a state label and the states and flow that follow it up to the
+// next label.
+type Label struct {
+	// Name is the label as written, such as "Death.Fire".
+	Name      string
+	Range     tree_sitter.Range
+	NameRange tree_sitter.Range
+	States    []*State
+	// Flow ends the label's sequence, or is nil if execution falls
+	// through to the next label. A label with neither states nor flow is
+	// an alias for the next label.
+	Flow *Flow
+}
+
+// State is a state line. A line with several frame letters defines one
+// state per letter.
+type State struct {
+	// Sprite is the four-character sprite name, or "####" to keep the
+	// current sprite.
+	Sprite string
+	// Frames are the frame letters, such as "AB".
+	Frames string
+	// Duration is the source text of the duration in tics, such as "10",
+	// "-1" or "random(1, 3)".
+	Duration string
+	Bright   bool
+	Fast     bool
+	Slow     bool
+	NoDelay  bool
+	CanRaise bool
+	// Lights are the names given to Light modifiers.
+	Lights []string
+	// Offset holds the source text of the Offset modifier's coordinates,
+	// or nil.
+	Offset *[2]string
+	// Action is the action function, or nil.
+	Action *Action
+	Range  tree_sitter.Range
+}
+
+// Tics returns the duration as a number, if it is a literal.
+func (s *State) Tics() (int, bool) {
+	n, err := strconv.Atoi(s.Duration)
+	return n, err == nil
+}
+
+// Infinite reports whether the state lasts forever, that is, whether its
+// duration is -1.
+func (s *State) Infinite() bool {
+	n, ok := s.Tics()
+	return ok && n == -1
+}
+
+// Action is the action of a state: a function call or an anonymous
+// function.
+type Action struct {
+	// Name is the called function, or "" for an anonymous function.
+	Name string
+	// Args are the source text of the arguments, including the name of
+	// named arguments.
+	Args []string
+	// Anonymous is set for a braced block of statements.
+	Anonymous bool
+	Range     tree_sitter.Range
+}
+
+// FlowKind identifies a flow control keyword.
+type FlowKind int
+
+const (
+	FlowStop FlowKind = iota + 1
+	FlowLoop
+	FlowWait
+	FlowFail
+	FlowGoto
+)
+
+var flowNames = [...]string{
+	FlowStop: "stop",
+	FlowLoop: "loop",
+	FlowWait: "wait",
+	FlowFail: "fail",
+	FlowGoto: "goto",
+}
+
+func (k FlowKind) String() string {
+	if k > 0 && int(k) < len(flowNames) {
+		return flowNames[k]
+	}
+	return "unknown"
+}
+
+// Flow is a flow control statement.
+type Flow struct {
+	Kind FlowKind
+	// Class is the qualifier of a goto target, such as "Super" in
+	// "Goto Super::Spawn", or "".
+	Class string
+	// Label is the goto target label.
+	Label string
+	// Offset is the number of states to skip past Label.
+	Offset int
+	Range  tree_sitter.Range
+}
+
+// Target returns the goto target as written, such as "Super::Spawn+2".
+func (f *Flow) Target() string {
+	if f.Kind != FlowGoto {
+		return ""
+	}
+	target := f.Label
+	if f.Class != "" {
+		target = f.Class + "::" + target
+	}
+	if f.Offset != 0 {
+		target += "+" + strconv.Itoa(f.Offset)
+	}
+	return target
+}
+
+// Parse interprets a States block.
+func Parse(block zscriptast.StatesBlock) *Block {
+	b := &Block{Options: block.Options(), Range: block.Range()}
+	for _, l := range block.Labels() {
+		name := l.Field(zscript.FieldName)
+		label := &Label{
+			Name:      strings.Join(strings.Fields(name.Text()), ""),
+			Range:     l.Range(),
+			NameRange: name.Range(),
+		}
+		for _, item := range l.Field(zscript.FieldBody).NamedChildren() {
+			switch item.Kind() {
+			case zscript.NodeStateLine:
+				label.States = append(label.States, parseState(item))
+			case zscript.NodeStateFlow:
+				label.Flow = parseFlow(item)
+			}
+		}
+		b.Labels = append(b.Labels, label)
+	}
+	return b
+}
+
+// ForClass interprets every States block of class c.
+func ForClass(c zscriptast.ClassDecl) []*Block {
+	var blocks []*Block
+	for _, s := range c.States() {
+		blocks = append(blocks, Parse(s))
+	}
+	return blocks
+}
+
+func parseState(line zscriptast.Node) *State {
+	s := &State{Range: line.Range()}
+	if sprite, frames, ok := strings.Cut(line.FieldText(zscript.FieldSpriteFrames), " "); ok {
+		s.Sprite = strings.Trim(sprite, `"`)
+		s.Frames = strings.TrimSpace(strings.ReplaceAll(frames, "\t", " "))
+	}
+	s.Duration = strings.Join(strings.Fields(line.FieldText(zscript.FieldDuration)), " ")
+
+	for _, m := range line.ChildOfKind(zscript.NodeStateModifiers).NamedChildren() {
+		args := m.NamedChildren()
+		switch keyword(m) {
+		case "bright":
+			s.Bright = true
+		case "fast":
+			s.Fast = true
+		case "slow":
+			s.Slow = true
+		case "nodelay":
+			s.NoDelay = true
+		case "canraise":
+			s.CanRaise = true
+		case "light":
+			for _, a := range args {
+				s.Lights = append(s.Lights, zscriptast.StringValue(a))
+			}
+		case "offset":
+			if len(args) != 2 {
+				break
+			}
+			s.Offset = &[2]string{args[0].Text(), args[1].Text()}
+		}
+	}
+
+	if action := line.ChildOfKind(zscript.NodeStateAction); !action.IsZero() {
+		s.Action = parseAction(action)
+	}
+	return s
+}
+
+func parseAction(action zscriptast.Node) *Action {
+	a := &Action{Range: action.Range()}
+	if call := action.ChildOfKind(zscript.NodeStateActionCall); !call.IsZero() {
+		a.Name = call.FieldText(zscript.FieldFunction)
+		for _, arg := range call.Field(zscript.FieldArguments).NamedChildren() {
+			a.Args = append(a.Args, strings.Join(strings.Fields(arg.Text()), " "))
+		}
+	} else {
+		a.Anonymous = true
+	}
+	return a
+}
+
+func parseFlow(flow zscriptast.Node) *Flow {
+	f := &Flow{Range: flow.Range()}
+	switch keyword(flow) {
+	case "stop":
+		f.Kind = FlowStop
+	case "loop":
+		f.Kind = FlowLoop
+	case "wait":
+		f.Kind = FlowWait
+	case "fail":
+		f.Kind = FlowFail
+	case "goto":
+		f.Kind = FlowGoto
+	}
+	if target := flow.Field(zscript.FieldTarget); !target.IsZero() {
+		f.Class = target.FieldText(zscript.FieldClass)
+		if name := target.ChildOfKind(zscript.NodeStateLabelName); !name.IsZero() {
+			f.Label = strings.Join(strings.Fields(name.Text()), "")
+		}
+		if offset := target.ChildOfKind(zscript.NodeNumberLiteral); !offset.IsZero() {
+			f.Offset, _ = strconv.Atoi(offset.Text())
+		}
+	}
+	return f
+}
+
+// keyword returns the leading keyword of n, lowercased.
+func keyword(n zscriptast.Node) string {
+	text := n.Text()
+	end := strings.IndexFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r)
+	})
+	if end < 0 {
+		end = len(text)
+	}
+	return strings.ToLower(text[:end])
+}
+
+// Label returns the label named name, matched case-insensitively, or nil.
+func (b *Block) Label(name string) *Label {
+	for _, l := range b.Labels {
+		if strings.EqualFold(l.Name, name) {
+			return l
+		}
+	}
+	return nil
+}
+
+// Sequence returns the states that run from the named label, following
+// fall-through into later labels, and the flow that ends them. The flow is
+// nil if the sequence runs off the end of the block.
+func (b *Block) Sequence(name string) ([]*State, *Flow) {
+	var result []*State
+	found := false
+	for _, l := range b.Labels {
+		if !found && !strings.EqualFold(l.Name, name) {
+			continue
+		}
+		found = true
+		result = append(result, l.States...)
+		if l.Flow != nil {
+			return result, l.Flow
+		}
+	}
+	return result, nil
+}
+
+// Sprites returns the sprite names used by the given blocks, sorted and
+// without duplicates. The "####" placeholder is omitted.
+func Sprites(blocks ...*Block) []string {
+	seen := map[string]bool{}
+	for _, b := range blocks {
+		for _, l := range b.Labels {
+			for _, s := range l.States {
+				if s.Sprite != "" && s.Sprite != "####" {
+					seen[strings.ToUpper(s.Sprite)] = true
+				}
+			}
+		}
+	}
+	sprites := make([]string, 0, len(seen))
+	for s := range seen {
+		sprites = append(sprites, s)
+	}
+	sort.Strings(sprites)
+	return sprites
+}
+
+// SpriteFrames returns every sprite and frame combination used by the
+// given blocks, such as "POSSA", sorted and without duplicates. Frames of
+// the "####" placeholder and "#" frames are omitted.
+func SpriteFrames(blocks ...*Block) []string {
+	seen := map[string]bool{}
+	for _, b := range blocks {
+		for _, l := range b.Labels {
+			for _, s := range l.States {
+				if s.Sprite == "" || s.Sprite == "####" {
+					continue
+				}
+				for _, f := range s.Frames {
+					if f != '#' {
+						seen[strings.ToUpper(s.Sprite)+string(f)] = true
+					}
+				}
+			}
+		}
+	}
+	frames := make([]string, 0, len(seen))
+	for f := range seen {
+		frames = append(frames, f)
+	}
+	sort.Strings(frames)
+	return frames
+}
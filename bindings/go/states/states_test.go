@@ -0,0 +1,134 @@
+package states_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	zscript "github.com/jlcrochet/tree-sitter-zscript/bindings/go"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/states"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/zscriptast"
+)
+
+const source = `class Imp : Actor {
+	States {
+	Spawn:
+		TROO AB 10 A_Look;
+		Loop;
+	See:
+		TROO CD random(2, 4) A_Chase;
+		Loop;
+	Missile:
+		TROO E 8 Bright Light("IMPFIRE") Offset(1, -2) A_FaceTarget;
+		TROO F 6 { A_SpawnProjectile("DoomImpBall"); }
+		Goto See;
+	Death.Fire:
+	Death:
+		TROO I 8 Fast NoDelay;
+		#### # -1;
+		Stop;
+	Raise:
+		TROO J 8 A_Jump(256, "See", count: 2);
+		Goto Super::Spawn+1;
+	}
+}
+`
+
+func parse(t *testing.T) *states.Block {
+	t.Helper()
+	tree, err := zscript.Parse(context.Background(), []byte(source))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(tree.Close)
+	if tree.RootNode().HasError() {
+		t.Fatalf("parse error: %s", tree.RootNode().ToSexp())
+	}
+	blocks := states.ForClass(zscriptast.NewFile(tree.Tree, []byte(source)).Classes()[0])
+	if len(blocks) != 1 {
+		t.Fatalf("len(ForClass()) = %d", len(blocks))
+	}
+	return blocks[0]
+}
+
+func TestParse(t *testing.T) {
+	b := parse(t)
+	var names []string
+	for _, l := range b.Labels {
+		names = append(names, l.Name)
+	}
+	if want := []string{"Spawn", "See", "Missile", "Death.Fire", "Death", "Raise"}; !reflect.DeepEqual(names, want) {
+		t.Fatalf("labels = %q, want %q", names, want)
+	}
+
+	spawn := b.Label("spawn")
+	if len(spawn.States) != 1 || spawn.Flow.Kind != states.FlowLoop {
+		t.Fatalf("Spawn = %+v", spawn)
+	}
+	s := spawn.States[0]
+	if s.Sprite != "TROO" || s.Frames != "AB" || s.Action.Name != "A_Look" || s.Action.Args != nil {
+		t.Errorf("Spawn state = %+v", s)
+	}
+	if n, ok := s.Tics(); !ok || n != 10 {
+		t.Errorf("Tics() = %d, %v", n, ok)
+	}
+
+	if s := b.Label("See").States[0]; s.Duration != "random(2, 4)" {
+		t.Errorf("See duration = %q", s.Duration)
+	} else if _, ok := s.Tics(); ok {
+		t.Error("random duration has literal tics")
+	}
+
+	missile := b.Label("Missile")
+	s = missile.States[0]
+	if !s.Bright || !reflect.DeepEqual(s.Lights, []string{"IMPFIRE"}) || s.Offset == nil || *s.Offset != [2]string{"1", "-2"} {
+		t.Errorf("Missile modifiers = %+v", s)
+	}
+	if a := missile.States[1].Action; a == nil || !a.Anonymous || a.Name != "" {
+		t.Errorf("anonymous action = %+v", a)
+	}
+	if f := missile.Flow; f.Kind != states.FlowGoto || f.Label != "See" || f.Target() != "See" {
+		t.Errorf("Missile flow = %+v", f)
+	}
+
+	if alias := b.Label("Death.Fire"); len(alias.States) != 0 || alias.Flow != nil {
+		t.Errorf("Death.Fire = %+v", alias)
+	}
+	death := b.Label("Death")
+	if s := death.States[0]; !s.Fast || !s.NoDelay || s.Bright || s.Action != nil {
+		t.Errorf("Death modifiers = %+v", s)
+	}
+	if s := death.States[1]; s.Sprite != "####" || s.Frames != "#" || !s.Infinite() {
+		t.Errorf("Death placeholder = %+v", s)
+	}
+
+	raise := b.Label("Raise")
+	if a := raise.States[0].Action; !reflect.DeepEqual(a.Args, []string{"256", `"See"`, "count: 2"}) {
+		t.Errorf("Raise args = %q", a.Args)
+	}
+	if f := raise.Flow; f.Class != "Super" || f.Label != "Spawn" || f.Offset != 1 || f.Target() != "Super::Spawn+1" {
+		t.Errorf("Raise flow = %+v", f)
+	}
+}
+
+func TestSequence(t *testing.T) {
+	b := parse(t)
+	seq, flow := b.Sequence("death.fire")
+	if len(seq) != 2 || flow == nil || flow.Kind != states.FlowStop {
+		t.Errorf("Sequence(Death.Fire) = %v, %+v", seq, flow)
+	}
+	if seq, flow := b.Sequence("Pain"); seq != nil || flow != nil {
+		t.Errorf("Sequence(Pain) = %v, %+v", seq, flow)
+	}
+}
+
+func TestSprites(t *testing.T) {
+	b := parse(t)
+	if got := states.Sprites(b); !reflect.DeepEqual(got, []string{"TROO"}) {
+		t.Errorf("Sprites() = %q", got)
+	}
+	want := []string{"TROOA", "TROOB", "TROOC", "TROOD", "TROOE", "TROOF", "TROOI", "TROOJ"}
+	if got := states.SpriteFrames(b); !reflect.DeepEqual(got, want) {
+		t.Errorf("SpriteFrames() = %q", got)
+	}
+}
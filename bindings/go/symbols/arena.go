@@ -0,0 +1,142 @@
+package symbols
+
+import (
+	"strings"
+
+	zscript "github.com/jlcrochet/tree-sitter-zscript/bindings/go"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/zscriptast"
+)
+
+// slabSize is the number of values allocated at once for each kind of
+// symbol an Arena hands out.
+const slabSize = 256
+
+// Arena reduces allocation when extracting the tables of many files. The
+// symbols it returns are carved out of shared blocks rather than
+// allocated one by one, and names, types and modifier lists are interned,
+// so a name used in a thousand files is stored once. The syntax nodes
+// visited along the way come from a zscriptast.Arena that is reused from
+// one file to the next.
+//
+// Tables extracted with an arena keep its blocks alive: memory is only
+// released once every table from the arena is unreachable. An Arena must
+// not be used by several goroutines at once.
+type Arena struct {
+	nodes       *zscriptast.Arena
+	strings     map[string]string
+	modifierSet map[string][]string
+
+	fields      []Field
+	methods     []Method
+	consts      []Const
+	enumerators []Enumerator
+	labels      []StateLabel
+}
+
+// NewArena returns an empty arena.
+func NewArena() *Arena {
+	return &Arena{
+		nodes:       zscriptast.NewArena(),
+		strings:     map[string]string{},
+		modifierSet: map[string][]string{},
+	}
+}
+
+// Extract builds the symbol table for tree from the arena.
+func (a *Arena) Extract(tree *zscript.Tree) *Table {
+	return extract(a, tree)
+}
+
+// Strings returns the number of distinct strings interned so far.
+func (a *Arena) Strings() int {
+	return len(a.strings)
+}
+
+// alloc returns a pointer to a zero value in slab, starting a new block
+// when the current one is full. Earlier blocks stay reachable through the
+// pointers handed out from them.
+func alloc[T any](slab *[]T) *T {
+	if len(*slab) == cap(*slab) {
+		*slab = make([]T, 0, slabSize)
+	}
+	var zero T
+	*slab = append(*slab, zero)
+	return &(*slab)[len(*slab)-1]
+}
+
+func (a *Arena) newField() *Field {
+	if a == nil {
+		return &Field{}
+	}
+	return alloc(&a.fields)
+}
+
+func (a *Arena) newMethod() *Method {
+	if a == nil {
+		return &Method{}
+	}
+	return alloc(&a.methods)
+}
+
+func (a *Arena) newConst() *Const {
+	if a == nil {
+		return &Const{}
+	}
+	return alloc(&a.consts)
+}
+
+func (a *Arena) newEnumerator() *Enumerator {
+	if a == nil {
+		return &Enumerator{}
+	}
+	return alloc(&a.enumerators)
+}
+
+func (a *Arena) newStateLabel() *StateLabel {
+	if a == nil {
+		return &StateLabel{}
+	}
+	return alloc(&a.labels)
+}
+
+// text returns the source text of n. With an arena, the text is looked up
+// in the intern table straight from the source bytes, so a string is only
+// allocated the first time it is seen.
+func (a *Arena) text(n zscriptast.Node) string {
+	if a == nil || n.IsZero() {
+		return n.Text()
+	}
+	b := n.Source[n.Raw.StartByte():n.Raw.EndByte()]
+	if s, ok := a.strings[string(b)]; ok {
+		return s
+	}
+	s := string(b)
+	a.strings[s] = s
+	return s
+}
+
+// intern returns the canonical copy of s.
+func (a *Arena) intern(s string) string {
+	if a == nil {
+		return s
+	}
+	if c, ok := a.strings[s]; ok {
+		return c
+	}
+	a.strings[s] = s
+	return s
+}
+
+// modifiers returns a shared copy of a modifier list. Callers must not
+// modify the result.
+func (a *Arena) modifiers(list []string) []string {
+	if a == nil || len(list) == 0 {
+		return list
+	}
+	key := strings.Join(list, " ")
+	if c, ok := a.modifierSet[key]; ok {
+		return c
+	}
+	a.modifierSet[key] = list
+	return list
+}
@@ -0,0 +1,74 @@
+package symbols
+
+import "strings"
+
+// Identifiers in ZScript are case-insensitive, so all lookups fold case.
+
+// Class returns the first class named name, or nil.
+func (t *Table) Class(name string) *Class {
+	for _, c := range t.Classes {
+		if strings.EqualFold(c.Name, name) {
+			return c
+		}
+	}
+	return nil
+}
+
+// Struct returns the first struct named name, or nil.
+func (t *Table) Struct(name string) *Struct {
+	for _, s := range t.Structs {
+		if strings.EqualFold(s.Name, name) {
+			return s
+		}
+	}
+	return nil
+}
+
+// Method returns the method named name, or nil.
+func (c *Class) Method(name string) *Method {
+	for _, m := range c.Methods {
+		if strings.EqualFold(m.Name, name) {
+			return m
+		}
+	}
+	return nil
+}
+
+// Field returns the field named name, or nil.
+func (c *Class) Field(name string) *Field {
+	for _, f := range c.Fields {
+		if strings.EqualFold(f.Name, name) {
+			return f
+		}
+	}
+	return nil
+}
+
+// StateLabel returns the state label named name, or nil.
+func (c *Class) StateLabel(name string) *StateLabel {
+	for _, l := range c.States {
+		if strings.EqualFold(l.Name, name) {
+			return l
+		}
+	}
+	return nil
+}
+
+// HasModifier reports whether the method has the given modifier.
+func (m *Method) HasModifier(modifier string) bool {
+	return hasModifier(m.Modifiers, modifier)
+}
+
+// HasModifier reports whether the field has the given modifier.
+func (f *Field) HasModifier(modifier string) bool {
+	return hasModifier(f.Modifiers, modifier)
+}
+
+func hasModifier(modifiers []string, modifier string) bool {
+	for _, m := range modifiers {
+		if strings.EqualFold(m, modifier) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,124 @@
+package symbols
+
+import (
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+
+	zscript "github.com/jlcrochet/tree-sitter-zscript/bindings/go"
+)
+
+// OutlineItem is an entry of a document outline. Its fields mirror the
+// Language Server Protocol's DocumentSymbol.
+type OutlineItem struct {
+	Name string
+	// Detail is a short description: the parent class of a class, the
+	// type of a field, the value of a constant.
+	Detail         string
+	Kind           Kind
+	Range          tree_sitter.Range
+	SelectionRange tree_sitter.Range
+	Children       []OutlineItem
+}
+
+// lspKinds maps kinds to the Language Server Protocol's SymbolKind values.
+var lspKinds = [...]int{
+	KindClass:      5,
+	KindStruct:     23,
+	KindEnum:       10,
+	KindEnumerator: 22,
+	KindConst:      14,
+	KindField:      8,
+	KindMethod:     6,
+	KindProperty:   7,
+	KindFlag:       17,
+	KindStateLabel: 20,
+	KindLocal:      13,
+	KindParameter:  13,
+}
+
+// SymbolKind returns the Language Server Protocol SymbolKind for k. Flags
+// are booleans and state labels are keys.
+func (k Kind) SymbolKind() int {
+	if k > 0 && int(k) < len(lspKinds) {
+		return lspKinds[k]
+	}
+	return 13
+}
+
+// Outline returns the outline of tree. See Table.Outline.
+func Outline(tree *zscript.Tree) []OutlineItem {
+	return Extract(tree).Outline()
+}
+
+// Outline returns the declarations of t as a hierarchy: its types, with
+// their members as children, followed by the top-level enums and
+// constants.
+func (t *Table) Outline() []OutlineItem {
+	result := []OutlineItem{}
+	for _, c := range t.Classes {
+		detail := c.Parent
+		if c.Extend {
+			detail = "extend"
+		} else if c.Mixin {
+			detail = "mixin"
+		}
+		item := outlineItem(c.Symbol, detail)
+		item.Children = append(item.Children, outlineConsts(c.Consts)...)
+		item.Children = append(item.Children, outlineEnums(c.Enums)...)
+		item.Children = append(item.Children, outlineMembers(c.Fields, c.Methods)...)
+		for _, p := range c.Properties {
+			item.Children = append(item.Children, outlineItem(p.Symbol, ""))
+		}
+		for _, f := range c.FlagDefs {
+			item.Children = append(item.Children, outlineItem(f.Symbol, f.Field))
+		}
+		for _, l := range c.States {
+			item.Children = append(item.Children, outlineItem(l.Symbol, ""))
+		}
+		result = append(result, item)
+	}
+	for _, s := range t.Structs {
+		item := outlineItem(s.Symbol, "")
+		item.Children = append(item.Children, outlineConsts(s.Consts)...)
+		item.Children = append(item.Children, outlineEnums(s.Enums)...)
+		item.Children = append(item.Children, outlineMembers(s.Fields, s.Methods)...)
+		result = append(result, item)
+	}
+	result = append(result, outlineEnums(t.Enums)...)
+	result = append(result, outlineConsts(t.Consts)...)
+	return result
+}
+
+func outlineItem(s Symbol, detail string) OutlineItem {
+	return OutlineItem{Name: s.Name, Detail: detail, Kind: s.Kind, Range: s.Range, SelectionRange: s.NameRange}
+}
+
+func outlineEnums(enums []*Enum) []OutlineItem {
+	var result []OutlineItem
+	for _, e := range enums {
+		item := outlineItem(e.Symbol, e.BaseType)
+		for _, m := range e.Members {
+			item.Children = append(item.Children, outlineItem(m.Symbol, m.Value))
+		}
+		result = append(result, item)
+	}
+	return result
+}
+
+func outlineConsts(consts []*Const) []OutlineItem {
+	var result []OutlineItem
+	for _, c := range consts {
+		result = append(result, outlineItem(c.Symbol, c.Value))
+	}
+	return result
+}
+
+func outlineMembers(fields []*Field, methods []*Method) []OutlineItem {
+	var result []OutlineItem
+	for _, f := range fields {
+		result = append(result, outlineItem(f.Symbol, f.Type))
+	}
+	for _, m := range methods {
+		result = append(result, outlineItem(m.Symbol, m.ReturnType))
+	}
+	return result
+}
@@ -0,0 +1,446 @@
+// Package symbols extracts a structured symbol table from a parsed ZScript
+// file: classes, structs, enums, constants, and their members, with source
+// ranges.
+package symbols
+
+import (
+	"runtime"
+	"sync"
+
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+
+	zscript "github.com/jlcrochet/tree-sitter-zscript/bindings/go"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/zscriptast"
+)
+
+// Kind identifies what a symbol declares.
+type Kind int
+
+const (
+	KindClass Kind = iota + 1
+	KindStruct
+	KindEnum
+	KindEnumerator
+	KindConst
+	KindField
+	KindMethod
+	KindProperty
+	KindFlag
+	KindStateLabel
+	// Tables do not include local variables and parameters, but other
+	// packages that resolve names report them with these kinds.
+	KindLocal
+	KindParameter
+)
+
+var kindNames = [...]string{
+	KindClass:      "class",
+	KindStruct:     "struct",
+	KindEnum:       "enum",
+	KindEnumerator: "enumerator",
+	KindConst:      "const",
+	KindField:      "field",
+	KindMethod:     "method",
+	KindProperty:   "property",
+	KindFlag:       "flag",
+	KindStateLabel: "state label",
+	KindLocal:      "local",
+	KindParameter:  "parameter",
+}
+
+func (k Kind) String() string {
+	if k > 0 && int(k) < len(kindNames) {
+		return kindNames[k]
+	}
+	return "unknown"
+}
+
+// Symbol holds the information common to every declaration.
+type Symbol struct {
+	Name string
+	Kind Kind
+	// Range spans the whole declaration.
+	Range tree_sitter.Range
+	// NameRange spans the declared name.
+	NameRange tree_sitter.Range
+	// HasErrors is set when the declaration contains a syntax error, so
+	// that details such as its parameters or members may be incomplete.
+	HasErrors bool
+}
+
+// Table is the symbol table of a single file.
+type Table struct {
+	Path    string
+	Version string
+	// HasErrors is set when the file contains syntax errors. Declarations
+	// the parser could not recover are left out, as are those whose name
+	// is missing; the others are still listed.
+	HasErrors bool
+	Includes  []Include
+	Classes   []*Class
+	Structs   []*Struct
+	Enums     []*Enum
+	Consts    []*Const
+}
+
+// Include is an #include directive.
+type Include struct {
+	Path  string
+	Range tree_sitter.Range
+}
+
+// Class is a class definition. Extend and mixin classes are reported as
+// classes with the corresponding flag set.
+type Class struct {
+	Symbol
+	Parent   string
+	Replaces string
+	Extend   bool
+	Mixin    bool
+	Flags    []string
+	// Version is the version in a version("x") qualifier, or "".
+	Version    string
+	Mixins     []string
+	Fields     []*Field
+	Methods    []*Method
+	Consts     []*Const
+	Enums      []*Enum
+	Properties []*Property
+	FlagDefs   []*FlagDef
+	States     []*StateLabel
+}
+
+// Struct is a struct definition.
+type Struct struct {
+	Symbol
+	Extend bool
+	// Version is the version in a version("x") qualifier, or "".
+	Version string
+	Fields  []*Field
+	Methods []*Method
+	Consts  []*Const
+	Enums   []*Enum
+}
+
+// Enum is an enum definition.
+type Enum struct {
+	Symbol
+	BaseType string
+	Members  []*Enumerator
+}
+
+// Enumerator is a member of an enum.
+type Enumerator struct {
+	Symbol
+	// Value is the source text of the explicit value, or "".
+	Value string
+}
+
+// Const is a constant definition.
+type Const struct {
+	Symbol
+	Value string
+}
+
+// Field is a single variable declared by a field declaration.
+type Field struct {
+	Symbol
+	Type      string
+	Modifiers []string
+}
+
+// Method is a method definition or declaration.
+type Method struct {
+	Symbol
+	ReturnType string
+	Modifiers  []string
+	Params     []Param
+	Const      bool
+	// HasBody is false for native and abstract declarations.
+	HasBody bool
+}
+
+// Param is a method parameter.
+type Param struct {
+	Name      string
+	Type      string
+	Modifiers []string
+	// Default is the source text of the default value, or "".
+	Default  string
+	Variadic bool
+}
+
+// Property is a property definition.
+type Property struct {
+	Symbol
+	Fields []string
+}
+
+// FlagDef is a flagdef definition.
+type FlagDef struct {
+	Symbol
+	Field string
+	Bit   string
+}
+
+// StateLabel is a label in a States block.
+type StateLabel struct {
+	Symbol
+}
+
+// ExtractAll builds the symbol tables for trees, in the same order, using
+// up to workers goroutines. Zero or less means runtime.GOMAXPROCS(0).
+// Each worker allocates from its own Arena.
+func ExtractAll(workers int, trees ...*zscript.Tree) []*Table {
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	tables := make([]*Table, len(trees))
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < min(workers, len(trees)); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			a := NewArena()
+			for i := range next {
+				tables[i] = a.Extract(trees[i])
+			}
+		}()
+	}
+	for i := range trees {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+	return tables
+}
+
+// Extract builds the symbol table for tree.
+func Extract(tree *zscript.Tree) *Table {
+	return extract(nil, tree)
+}
+
+// extract builds the symbol table for tree, allocating from a if it is
+// not nil.
+func extract(a *Arena, tree *zscript.Tree) *Table {
+	file := zscriptast.NewFile(tree.Tree, tree.Source)
+	if a != nil {
+		file.Node = a.nodes.Wrap(file.Raw, tree.Source)
+		defer a.nodes.Reset()
+	}
+	table := &Table{Path: tree.Path, HasErrors: file.Raw.HasError()}
+
+	if v, ok := file.Version(); ok {
+		table.Version = v.Version()
+	}
+	for _, inc := range file.Includes() {
+		table.Includes = append(table.Includes, Include{Path: inc.Path(), Range: inc.Range()})
+	}
+	for _, n := range declarations(file.Node, zscript.NodeClassDefinition) {
+		if named(n) {
+			table.Classes = append(table.Classes, a.extractClass(zscriptast.ClassDecl{Node: n}))
+		}
+	}
+	for _, n := range declarations(file.Node, zscript.NodeStructDefinition) {
+		if named(n) {
+			table.Structs = append(table.Structs, a.extractStruct(zscriptast.StructDecl{Node: n}))
+		}
+	}
+	table.Enums = a.extractEnums(declarations(file.Node, zscript.NodeEnumDefinition))
+	table.Consts = a.extractConsts(declarations(file.Node, zscript.NodeConstDefinition))
+	return table
+}
+
+// declarations returns the children of n with the given kind, in source
+// order, together with those the parser recovered inside ERROR children
+// of n.
+func declarations(n zscriptast.Node, kind string) []zscriptast.Node {
+	var result []zscriptast.Node
+	var visit func(n zscriptast.Node)
+	visit = func(n zscriptast.Node) {
+		for _, child := range n.NamedChildren() {
+			switch {
+			case child.Kind() == kind:
+				result = append(result, child)
+			case child.Raw.IsError():
+				visit(child)
+			}
+		}
+	}
+	visit(n)
+	return result
+}
+
+// named reports whether the declaration n has a name. A declaration whose
+// name the parser had to insert is left out of the table.
+func named(n zscriptast.Node) bool {
+	return present(n.Field(zscript.FieldName))
+}
+
+func present(name zscriptast.Node) bool {
+	return !name.IsZero() && !name.Raw.IsMissing() && name.Raw.EndByte() > name.Raw.StartByte()
+}
+
+func (a *Arena) symbol(n zscriptast.Node, kind Kind) Symbol {
+	name := n.Field(zscript.FieldName)
+	return Symbol{
+		Name:      a.text(name),
+		Kind:      kind,
+		Range:     n.Range(),
+		NameRange: name.Range(),
+		HasErrors: n.Raw.HasError(),
+	}
+}
+
+func (a *Arena) extractClass(c zscriptast.ClassDecl) *Class {
+	class := &Class{
+		Symbol:   a.symbol(c.Node, KindClass),
+		Parent:   a.intern(c.Parent()),
+		Replaces: a.intern(c.Replaces()),
+		Extend:   c.IsExtend(),
+		Mixin:    c.IsMixin(),
+		Flags:    a.modifiers(c.Flags()),
+		Version:  a.intern(c.Version()),
+		Mixins:   c.Mixins(),
+		Fields:   a.extractFields(declarations(c.Node, zscript.NodeFieldDeclaration)),
+		Methods:  a.extractMethods(declarations(c.Node, zscript.NodeMethodDefinition)),
+		Consts:   a.extractConsts(declarations(c.Node, zscript.NodeConstDefinition)),
+		Enums:    a.extractEnums(declarations(c.Node, zscript.NodeEnumDefinition)),
+	}
+	for _, n := range declarations(c.Node, zscript.NodePropertyDefinition) {
+		if !named(n) {
+			continue
+		}
+		p := zscriptast.PropertyDecl{Node: n}
+		class.Properties = append(class.Properties, &Property{
+			Symbol: a.symbol(p.Node, KindProperty),
+			Fields: p.Fields(),
+		})
+	}
+	for _, n := range declarations(c.Node, zscript.NodeFlagDefinition) {
+		if !named(n) {
+			continue
+		}
+		f := zscriptast.FlagDecl{Node: n}
+		class.FlagDefs = append(class.FlagDefs, &FlagDef{
+			Symbol: a.symbol(f.Node, KindFlag),
+			Field:  a.intern(f.FieldName()),
+			Bit:    a.intern(f.Bit()),
+		})
+	}
+	for _, block := range c.States() {
+		for _, label := range block.Labels() {
+			if !named(label.Node) {
+				continue
+			}
+			l := a.newStateLabel()
+			l.Symbol = a.symbol(label.Node, KindStateLabel)
+			class.States = append(class.States, l)
+		}
+	}
+	return class
+}
+
+func (a *Arena) extractStruct(s zscriptast.StructDecl) *Struct {
+	return &Struct{
+		Symbol:  a.symbol(s.Node, KindStruct),
+		Extend:  s.IsExtend(),
+		Version: s.Version(),
+		Fields:  a.extractFields(declarations(s.Node, zscript.NodeFieldDeclaration)),
+		Methods: a.extractMethods(declarations(s.Node, zscript.NodeMethodDefinition)),
+		Consts:  a.extractConsts(declarations(s.Node, zscript.NodeConstDefinition)),
+		Enums:   a.extractEnums(declarations(s.Node, zscript.NodeEnumDefinition)),
+	}
+}
+
+func (a *Arena) extractEnums(decls []zscriptast.Node) []*Enum {
+	var enums []*Enum
+	for _, n := range decls {
+		if !named(n) {
+			continue
+		}
+		e := zscriptast.EnumDecl{Node: n}
+		enum := &Enum{Symbol: a.symbol(e.Node, KindEnum), BaseType: a.intern(e.BaseType())}
+		for _, m := range e.Members() {
+			if !named(m.Node) {
+				continue
+			}
+			member := a.newEnumerator()
+			member.Symbol = a.symbol(m.Node, KindEnumerator)
+			member.Value = a.text(m.Value())
+			enum.Members = append(enum.Members, member)
+		}
+		enums = append(enums, enum)
+	}
+	return enums
+}
+
+func (a *Arena) extractConsts(decls []zscriptast.Node) []*Const {
+	var consts []*Const
+	for _, n := range decls {
+		if !named(n) {
+			continue
+		}
+		c := zscriptast.ConstDecl{Node: n}
+		k := a.newConst()
+		k.Symbol = a.symbol(c.Node, KindConst)
+		k.Value = a.text(c.Value())
+		consts = append(consts, k)
+	}
+	return consts
+}
+
+func (a *Arena) extractFields(decls []zscriptast.Node) []*Field {
+	var fields []*Field
+	for _, n := range decls {
+		f := zscriptast.FieldDecl{Node: n}
+		typ := a.text(f.Field(zscript.FieldType))
+		modifiers := a.modifiers(f.Modifiers())
+		for _, d := range f.Declarators() {
+			name := zscriptast.DeclaratorName(d)
+			if !present(name) {
+				continue
+			}
+			field := a.newField()
+			field.Symbol = Symbol{
+				Name:      a.text(name),
+				Kind:      KindField,
+				Range:     f.Range(),
+				NameRange: name.Range(),
+				HasErrors: f.Raw.HasError(),
+			}
+			field.Type = typ
+			field.Modifiers = modifiers
+			fields = append(fields, field)
+		}
+	}
+	return fields
+}
+
+func (a *Arena) extractMethods(decls []zscriptast.Node) []*Method {
+	var methods []*Method
+	for _, n := range decls {
+		if !named(n) {
+			continue
+		}
+		m := zscriptast.MethodDecl{Node: n}
+		method := a.newMethod()
+		method.Symbol = a.symbol(m.Node, KindMethod)
+		method.ReturnType = a.text(m.Field(zscript.FieldType))
+		method.Modifiers = a.modifiers(m.Modifiers())
+		method.Const = m.IsConst()
+		method.HasBody = !m.Body().IsZero()
+		for _, p := range m.Parameters() {
+			method.Params = append(method.Params, Param{
+				Name:      a.intern(p.Name()),
+				Type:      a.text(p.Field(zscript.FieldType)),
+				Modifiers: a.modifiers(p.Modifiers()),
+				Default:   a.text(p.Default()),
+				Variadic:  p.IsVariadic(),
+			})
+		}
+		methods = append(methods, method)
+	}
+	return methods
+}
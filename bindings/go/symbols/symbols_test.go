@@ -0,0 +1,298 @@
+package symbols_test
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+
+	zscript "github.com/jlcrochet/tree-sitter-zscript/bindings/go"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/symbols"
+)
+
+const source = `version "4.10"
+#include "actors/imp.zs"
+
+const MAX_AMMO = 50;
+
+class MyImp : DoomImp replaces DoomImp {
+	int rage;
+	enum EMode { MODE_IDLE, MODE_ANGRY = 2 }
+	property Rage: rage;
+
+	override void Tick() { Super.Tick(); }
+	native int GetRage(bool clamp = true) const;
+
+	States {
+	Spawn:
+		TROO AB 10 A_Look;
+		Loop;
+	Death.Fire:
+		TROO I 5;
+		Stop;
+	}
+}
+
+struct Pair { int a, b; }
+`
+
+func TestExtract(t *testing.T) {
+	tree, err := zscript.Parse(context.Background(), []byte(source))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tree.Close()
+	tree.Path = "zscript.zs"
+
+	table := symbols.Extract(tree)
+	if table.Path != "zscript.zs" || table.Version != "4.10" {
+		t.Errorf("Path = %q, Version = %q", table.Path, table.Version)
+	}
+	if len(table.Includes) != 1 || table.Includes[0].Path != "actors/imp.zs" {
+		t.Errorf("Includes = %v", table.Includes)
+	}
+	if len(table.Consts) != 1 || table.Consts[0].Name != "MAX_AMMO" || table.Consts[0].Value != "50" {
+		t.Errorf("Consts = %v", table.Consts)
+	}
+
+	class := table.Class("myimp")
+	if class == nil {
+		t.Fatal("Class(\"myimp\") = nil")
+	}
+	if class.Kind != symbols.KindClass || class.Parent != "DoomImp" || class.Replaces != "DoomImp" {
+		t.Errorf("Kind = %v, Parent = %q, Replaces = %q", class.Kind, class.Parent, class.Replaces)
+	}
+	if class.NameRange.StartPoint.Row != 5 || class.NameRange.StartPoint.Column != 6 {
+		t.Errorf("NameRange = %v", class.NameRange)
+	}
+	if f := class.Field("RAGE"); f == nil || f.Type != "int" {
+		t.Errorf("Field(\"RAGE\") = %v", f)
+	}
+	if len(class.Enums) != 1 || len(class.Enums[0].Members) != 2 || class.Enums[0].Members[1].Value != "2" {
+		t.Errorf("Enums = %v", class.Enums)
+	}
+	if len(class.Properties) != 1 || !reflect.DeepEqual(class.Properties[0].Fields, []string{"rage"}) {
+		t.Errorf("Properties = %v", class.Properties)
+	}
+
+	tick := class.Method("Tick")
+	if tick == nil || !tick.HasModifier("override") || !tick.HasBody {
+		t.Errorf("Method(\"Tick\") = %+v", tick)
+	}
+	getRage := class.Method("GetRage")
+	if getRage == nil || getRage.HasBody || !getRage.Const || len(getRage.Params) != 1 || getRage.Params[0].Default != "true" {
+		t.Errorf("Method(\"GetRage\") = %+v", getRage)
+	}
+
+	var labels []string
+	for _, l := range class.States {
+		labels = append(labels, l.Name)
+	}
+	if !reflect.DeepEqual(labels, []string{"Spawn", "Death.Fire"}) {
+		t.Errorf("States = %v", labels)
+	}
+
+	pair := table.Struct("Pair")
+	if pair == nil || len(pair.Fields) != 2 || pair.Fields[1].Name != "b" {
+		t.Errorf("Struct(\"Pair\") = %+v", pair)
+	}
+}
+
+func TestOutline(t *testing.T) {
+	tree, err := zscript.Parse(context.Background(), []byte(source))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tree.Close()
+
+	var got []string
+	var walk func(items []symbols.OutlineItem, indent string)
+	walk = func(items []symbols.OutlineItem, indent string) {
+		for _, item := range items {
+			got = append(got, indent+item.Kind.String()+" "+item.Name+" "+item.Detail)
+			walk(item.Children, indent+"  ")
+		}
+	}
+	outline := symbols.Outline(tree)
+	walk(outline, "")
+	want := []string{
+		"class MyImp DoomImp",
+		"  enum EMode ",
+		"    enumerator MODE_IDLE ",
+		"    enumerator MODE_ANGRY 2",
+		"  field rage int",
+		"  method Tick void",
+		"  method GetRage int",
+		"  property Rage ",
+		"  state label Spawn ",
+		"  state label Death.Fire ",
+		"struct Pair ",
+		"  field a int",
+		"  field b int",
+		"const MAX_AMMO 50",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Outline() =\n%q\nwant\n%q", got, want)
+	}
+	if r := outline[0].SelectionRange; r.StartPoint.Row != 5 || r.StartPoint.Column != 6 {
+		t.Errorf("SelectionRange = %v", r)
+	}
+	if k := outline[0].Kind.SymbolKind(); k != 5 {
+		t.Errorf("SymbolKind() = %d, want 5", k)
+	}
+}
+
+func TestExtractAll(t *testing.T) {
+	var trees []*zscript.Tree
+	for i := 0; i < 10; i++ {
+		tree, err := zscript.Parse(context.Background(), []byte(source))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer tree.Close()
+		tree.Path = string(rune('a' + i))
+		trees = append(trees, tree)
+	}
+	tables := symbols.ExtractAll(3, trees...)
+	for i, table := range tables {
+		if table.Path != trees[i].Path || len(table.Classes) != 1 {
+			t.Errorf("tables[%d]: Path = %q, %d classes", i, table.Path, len(table.Classes))
+		}
+	}
+}
+
+func TestArena(t *testing.T) {
+	tree, err := zscript.Parse(context.Background(), []byte(source))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tree.Close()
+
+	a := symbols.NewArena()
+	for i := 0; i < 2; i++ {
+		if got, want := a.Extract(tree), symbols.Extract(tree); !reflect.DeepEqual(got, want) {
+			t.Fatalf("Arena.Extract() = %+v, want %+v", got, want)
+		}
+	}
+	n := a.Strings()
+	a.Extract(tree)
+	if a.Strings() != n {
+		t.Errorf("Strings() grew from %d to %d extracting the same file", n, a.Strings())
+	}
+}
+
+// largeSource returns a file of n classes, each with fields, methods and
+// states.
+func largeSource(n int) []byte {
+	var b strings.Builder
+	for i := 0; i < n; i++ {
+		fmt.Fprintf(&b, `class Monster%d : Actor {
+	int health, armor;
+	double speed;
+	const LIMIT = %d;
+	enum EMode { MODE_IDLE, MODE_ANGRY }
+	virtual void Tick() { Super.Tick(); }
+	action void A_Special(int amount, double spread = 1.0) {}
+	States {
+	Spawn:
+		TROO AB 10 A_Look;
+		Loop;
+	See:
+		TROO ABCD 3 A_Chase;
+		Loop;
+	}
+}
+`, i, i)
+	}
+	return []byte(b.String())
+}
+
+// BenchmarkExtract extracts the tables of a large project, keeping them
+// alive, and reports the heap they retain as well as the allocations made.
+func BenchmarkExtract(b *testing.B) {
+	const files = 20
+	var trees []*zscript.Tree
+	for i := 0; i < files; i++ {
+		tree, err := zscript.Parse(context.Background(), largeSource(100))
+		if err != nil {
+			b.Fatal(err)
+		}
+		defer tree.Close()
+		trees = append(trees, tree)
+	}
+
+	run := func(b *testing.B, extract func() func(*zscript.Tree) *symbols.Table) {
+		b.ReportAllocs()
+		var retained uint64
+		for i := 0; i < b.N; i++ {
+			var before, after runtime.MemStats
+			runtime.GC()
+			runtime.ReadMemStats(&before)
+			fn := extract()
+			tables := make([]*symbols.Table, len(trees))
+			for j, tree := range trees {
+				tables[j] = fn(tree)
+			}
+			runtime.GC()
+			runtime.ReadMemStats(&after)
+			retained += after.HeapAlloc - before.HeapAlloc
+			runtime.KeepAlive(tables)
+		}
+		b.ReportMetric(float64(retained)/float64(b.N), "retained-B/op")
+	}
+	b.Run("heap", func(b *testing.B) {
+		run(b, func() func(*zscript.Tree) *symbols.Table { return symbols.Extract })
+	})
+	b.Run("arena", func(b *testing.B) {
+		run(b, func() func(*zscript.Tree) *symbols.Table { return symbols.NewArena().Extract })
+	})
+}
+
+func TestExtractWithErrors(t *testing.T) {
+	const broken = `class Good : Actor {
+	int a;
+	void Half() { if ( }
+	void Fine() {}
+}
+)) garbage here
+class After : Actor { int b; }
+const C = ;
+struct S { int z; }
+`
+	tree, err := zscript.Parse(context.Background(), []byte(broken))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tree.Close()
+
+	table := symbols.Extract(tree)
+	if !table.HasErrors {
+		t.Error("HasErrors = false")
+	}
+	var names []string
+	for _, c := range table.Classes {
+		names = append(names, c.Name)
+	}
+	if !reflect.DeepEqual(names, []string{"Good", "After"}) {
+		t.Fatalf("classes = %v", names)
+	}
+	if len(table.Consts) != 1 || !table.Consts[0].HasErrors || table.Consts[0].Value != "" {
+		t.Errorf("Consts = %+v", table.Consts)
+	}
+	if s := table.Struct("S"); s == nil || s.HasErrors || len(s.Fields) != 1 {
+		t.Errorf("Struct(\"S\") = %+v", s)
+	}
+
+	good := table.Classes[0]
+	if !good.HasErrors || good.Field("a") == nil || good.Field("a").HasErrors {
+		t.Errorf("Good: HasErrors = %v, a = %+v", good.HasErrors, good.Field("a"))
+	}
+	if half := good.Method("Half"); half == nil || !half.HasErrors {
+		t.Errorf("Method(\"Half\") = %+v", half)
+	}
+	if after := table.Classes[1]; after.HasErrors || after.Field("b") == nil {
+		t.Errorf("After = %+v", after)
+	}
+}
@@ -0,0 +1,148 @@
+// Package tags writes the declarations of ZScript files as a tags file in
+// the extended format of Universal Ctags, which vim, emacs and other
+// editors use to jump to a symbol's definition without a language server.
+//
+// Each line holds a name, the file declaring it, a search pattern for the
+// declaring line, the kind as a single letter and the line number and
+// enclosing scope, as in:
+//
+//	Tick	zscript/imp.zs	/^	override void Tick()$/;"	m	line:12	class:Imp
+package tags
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	zscript "github.com/jlcrochet/tree-sitter-zscript/bindings/go"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/symbols"
+)
+
+// Tag is a declaration.
+type Tag struct {
+	Name string
+	Path string
+	Kind symbols.Kind
+	// Line is the 1-based line of the name, and Text the whole line.
+	Line int
+	Text string
+	// ScopeKind and Scope are the kind and name of the enclosing class,
+	// struct or enum, or zero and "" at the top level.
+	ScopeKind symbols.Kind
+	Scope     string
+}
+
+// Kinds maps the kinds of declarations to the letters the tags file gives
+// them, which editors show when listing matches.
+var Kinds = map[symbols.Kind]byte{
+	symbols.KindClass:      'c',
+	symbols.KindStruct:     's',
+	symbols.KindEnum:       'g',
+	symbols.KindEnumerator: 'e',
+	symbols.KindConst:      'd',
+	symbols.KindField:      'f',
+	symbols.KindMethod:     'm',
+	symbols.KindProperty:   'p',
+	symbols.KindFlag:       'F',
+	symbols.KindStateLabel: 'l',
+}
+
+// Extract returns the tags of the declarations in tree, in source order
+// within each scope.
+func Extract(tree *zscript.Tree) []Tag {
+	t := symbols.Extract(tree)
+	lines := strings.Split(string(tree.Source), "\n")
+	var tags []Tag
+	add := func(s symbols.Symbol, scopeKind symbols.Kind, scope string) {
+		row := int(s.NameRange.StartPoint.Row)
+		text := ""
+		if row < len(lines) {
+			text = strings.TrimSuffix(lines[row], "\r")
+		}
+		tags = append(tags, Tag{s.Name, tree.Path, s.Kind, row + 1, text, scopeKind, scope})
+	}
+	enums := func(enums []*symbols.Enum, scopeKind symbols.Kind, scope string) {
+		for _, e := range enums {
+			add(e.Symbol, scopeKind, scope)
+			for _, m := range e.Members {
+				add(m.Symbol, symbols.KindEnum, e.Name)
+			}
+		}
+	}
+	members := func(kind symbols.Kind, name string, consts []*symbols.Const, fields []*symbols.Field, methods []*symbols.Method) {
+		for _, c := range consts {
+			add(c.Symbol, kind, name)
+		}
+		for _, f := range fields {
+			add(f.Symbol, kind, name)
+		}
+		for _, m := range methods {
+			add(m.Symbol, kind, name)
+		}
+	}
+	for _, c := range t.Classes {
+		if !c.Extend {
+			add(c.Symbol, 0, "")
+		}
+		enums(c.Enums, symbols.KindClass, c.Name)
+		members(symbols.KindClass, c.Name, c.Consts, c.Fields, c.Methods)
+		for _, p := range c.Properties {
+			add(p.Symbol, symbols.KindClass, c.Name)
+		}
+		for _, f := range c.FlagDefs {
+			add(f.Symbol, symbols.KindClass, c.Name)
+		}
+		for _, l := range c.States {
+			add(l.Symbol, symbols.KindClass, c.Name)
+		}
+	}
+	for _, s := range t.Structs {
+		if !s.Extend {
+			add(s.Symbol, 0, "")
+		}
+		enums(s.Enums, symbols.KindStruct, s.Name)
+		members(symbols.KindStruct, s.Name, s.Consts, s.Fields, s.Methods)
+	}
+	enums(t.Enums, 0, "")
+	for _, c := range t.Consts {
+		add(c.Symbol, 0, "")
+	}
+	return tags
+}
+
+// Write writes tags to w as a sorted tags file, with the pseudo-tags that
+// describe its format first.
+func Write(w io.Writer, tags []Tag) error {
+	sorted := append([]Tag(nil), tags...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		a, b := sorted[i], sorted[j]
+		switch {
+		case a.Name != b.Name:
+			return a.Name < b.Name
+		case a.Path != b.Path:
+			return a.Path < b.Path
+		}
+		return a.Line < b.Line
+	})
+	bw := bufio.NewWriter(w)
+	fmt.Fprint(bw, "!_TAG_FILE_FORMAT\t2\t/extended format/\n")
+	fmt.Fprint(bw, "!_TAG_FILE_SORTED\t1\t/0=unsorted, 1=sorted, 2=foldcase/\n")
+	fmt.Fprint(bw, "!_TAG_FILE_ENCODING\tutf-8\t//\n")
+	fmt.Fprint(bw, "!_TAG_PROGRAM_NAME\tzscript\t//\n")
+	fmt.Fprint(bw, "!_TAG_PROGRAM_URL\thttps://github.com/jlcrochet/tree-sitter-zscript\t//\n")
+	for _, t := range sorted {
+		fmt.Fprintf(bw, "%s\t%s\t/^%s$/;\"\t%c\tline:%d", t.Name, t.Path, pattern(t.Text), Kinds[t.Kind], t.Line)
+		if t.Scope != "" {
+			fmt.Fprintf(bw, "\t%s:%s", t.ScopeKind, t.Scope)
+		}
+		bw.WriteByte('\n')
+	}
+	return bw.Flush()
+}
+
+// pattern escapes line for a search pattern.
+func pattern(line string) string {
+	return strings.NewReplacer(`\`, `\\`, `/`, `\/`).Replace(line)
+}
@@ -0,0 +1,63 @@
+package tags_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	zscript "github.com/jlcrochet/tree-sitter-zscript/bindings/go"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/tags"
+)
+
+const source = `class Imp : Actor
+{
+	const Speed = 8;
+	int anger;
+	property Anger: anger;
+	override void Tick() { Super.Tick(); }
+	States
+	{
+	Spawn:
+		TROO A 10;
+		Loop;
+	}
+}
+
+extend class Imp { void Rage() {} }
+
+enum EColor { COLOR_RED }
+struct Point { double x; }
+`
+
+func TestWrite(t *testing.T) {
+	tree, err := zscript.Parse(context.Background(), []byte(source))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tree.Close()
+	tree.Path = "zscript/imp.zs"
+	var sb strings.Builder
+	if err := tags.Write(&sb, tags.Extract(tree)); err != nil {
+		t.Fatal(err)
+	}
+	want := `!_TAG_FILE_FORMAT	2	/extended format/
+!_TAG_FILE_SORTED	1	/0=unsorted, 1=sorted, 2=foldcase/
+!_TAG_FILE_ENCODING	utf-8	//
+!_TAG_PROGRAM_NAME	zscript	//
+!_TAG_PROGRAM_URL	https://github.com/jlcrochet/tree-sitter-zscript	//
+Anger	zscript/imp.zs	/^	property Anger: anger;$/;"	p	line:5	class:Imp
+COLOR_RED	zscript/imp.zs	/^enum EColor { COLOR_RED }$/;"	e	line:17	enum:EColor
+EColor	zscript/imp.zs	/^enum EColor { COLOR_RED }$/;"	g	line:17
+Imp	zscript/imp.zs	/^class Imp : Actor$/;"	c	line:1
+Point	zscript/imp.zs	/^struct Point { double x; }$/;"	s	line:18
+Rage	zscript/imp.zs	/^extend class Imp { void Rage() {} }$/;"	m	line:15	class:Imp
+Spawn	zscript/imp.zs	/^	Spawn:$/;"	l	line:9	class:Imp
+Speed	zscript/imp.zs	/^	const Speed = 8;$/;"	d	line:3	class:Imp
+Tick	zscript/imp.zs	/^	override void Tick() { Super.Tick(); }$/;"	m	line:6	class:Imp
+anger	zscript/imp.zs	/^	int anger;$/;"	f	line:4	class:Imp
+x	zscript/imp.zs	/^struct Point { double x; }$/;"	f	line:18	struct:Point
+`
+	if got := sb.String(); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+}
@@ -0,0 +1,255 @@
+// Package translation parses GZDoom translation ranges, the strings given
+// to the Translation property that remap part of the palette:
+//
+//	"112:127=[255,0,0]:[128,0,0]"
+//
+// A range maps the palette indexes before the "=" to one of:
+//
+//	176:191         other palette indexes
+//	[r,g,b]:[r,g,b] a gradient of colors
+//	%[r,g,b]:[r,g,b] a desaturated gradient, with components from 0 to 2
+//	#[r,g,b]        a single color the range is blended with
+//	@amount[r,g,b]  a color the range is tinted with, by 0 to 100 percent
+//
+// GZDoom ignores a malformed translation instead of reporting it, so the
+// actor keeps its own colors.
+package translation
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Kind is what a range maps its palette indexes to.
+type Kind int
+
+const (
+	// Indexes maps to other palette indexes.
+	Indexes Kind = iota
+	// Gradient maps to a gradient between two colors.
+	Gradient
+	// Desaturated maps to a gradient between two colors applied to the
+	// desaturated palette.
+	Desaturated
+	// Colorize blends the range with one color.
+	Colorize
+	// Tint tints the range with one color by an amount.
+	Tint
+)
+
+func (k Kind) String() string {
+	switch k {
+	case Gradient:
+		return "gradient"
+	case Desaturated:
+		return "desaturated"
+	case Colorize:
+		return "colorize"
+	case Tint:
+		return "tint"
+	}
+	return "indexes"
+}
+
+// Color is a red, green and blue color. Components are from 0 to 255,
+// or from 0 to 2 in a desaturated range.
+type Color [3]float64
+
+// Range is a parsed translation range.
+type Range struct {
+	Kind Kind
+	// Start and End are the palette indexes the range remaps.
+	Start, End int
+	// ToStart and ToEnd are the indexes an Indexes range maps to.
+	ToStart, ToEnd int
+	// From and To are the ends of a gradient; Colorize and Tint use only
+	// From.
+	From, To Color
+	// Amount is the percentage of a Tint.
+	Amount int
+}
+
+// Error is a syntax or range error in a translation.
+type Error struct {
+	// Offset is the byte offset in the translation of the text in error.
+	Offset  int
+	Message string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("translation: offset %d: %s", e.Offset, e.Message)
+}
+
+// IsRange reports whether s is a translation range rather than the name
+// of a translation declared in a TRNSLATE lump or a standard translation
+// number.
+func IsRange(s string) bool {
+	return strings.ContainsAny(s, "=:")
+}
+
+// Parse parses the translation range s.
+func Parse(s string) (*Range, error) {
+	p := &parser{s: s}
+	r := &Range{}
+	var err error
+	if r.Start, err = p.index(); err != nil {
+		return nil, err
+	}
+	if err = p.expect(':'); err != nil {
+		return nil, err
+	}
+	if r.End, err = p.index(); err != nil {
+		return nil, err
+	}
+	if err = p.expect('='); err != nil {
+		return nil, err
+	}
+	switch p.peek() {
+	case '[':
+		r.Kind = Gradient
+		err = p.gradient(r, 255)
+	case '%':
+		p.pos++
+		r.Kind = Desaturated
+		err = p.gradient(r, 2)
+	case '#':
+		p.pos++
+		r.Kind = Colorize
+		r.From, err = p.color(255)
+	case '@':
+		p.pos++
+		r.Kind = Tint
+		start := p.skip()
+		var n float64
+		if n, err = p.number(); err == nil {
+			if r.Amount = int(n); n != float64(r.Amount) || n < 0 || n > 100 {
+				err = p.errorAt(start, "tint amount %s is not a whole number from 0 to 100", p.s[start:p.pos])
+			} else {
+				r.From, err = p.color(255)
+			}
+		}
+	default:
+		r.Kind = Indexes
+		if r.ToStart, err = p.index(); err == nil {
+			if err = p.expect(':'); err == nil {
+				r.ToEnd, err = p.index()
+			}
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	if p.skip() < len(s) {
+		return nil, p.errorAt(p.pos, "unexpected %q after the range", s[p.pos:])
+	}
+	return r, nil
+}
+
+type parser struct {
+	s   string
+	pos int
+}
+
+func (p *parser) errorAt(offset int, format string, args ...any) error {
+	return &Error{Offset: offset, Message: fmt.Sprintf(format, args...)}
+}
+
+// skip skips spaces and returns the new position.
+func (p *parser) skip() int {
+	for p.pos < len(p.s) && (p.s[p.pos] == ' ' || p.s[p.pos] == '\t') {
+		p.pos++
+	}
+	return p.pos
+}
+
+// peek returns the next byte after any spaces, or 0 at the end.
+func (p *parser) peek() byte {
+	if p.skip() == len(p.s) {
+		return 0
+	}
+	return p.s[p.pos]
+}
+
+func (p *parser) expect(c byte) error {
+	if p.peek() != c {
+		return p.unexpected(fmt.Sprintf("%q", c))
+	}
+	p.pos++
+	return nil
+}
+
+func (p *parser) unexpected(want string) error {
+	if p.skip() == len(p.s) {
+		return p.errorAt(p.pos, "expected %s at the end of the translation", want)
+	}
+	return p.errorAt(p.pos, "expected %s, found %q", want, p.s[p.pos])
+}
+
+func (p *parser) number() (float64, error) {
+	start := p.skip()
+	for p.pos < len(p.s) && strings.IndexByte("0123456789.", p.s[p.pos]) >= 0 {
+		p.pos++
+	}
+	if start == p.pos {
+		return 0, p.unexpected("a number")
+	}
+	n, err := strconv.ParseFloat(p.s[start:p.pos], 64)
+	if err != nil {
+		return 0, p.errorAt(start, "invalid number %q", p.s[start:p.pos])
+	}
+	return n, nil
+}
+
+// index parses a palette index.
+func (p *parser) index() (int, error) {
+	start := p.skip()
+	n, err := p.number()
+	if err != nil {
+		return 0, err
+	}
+	if n != float64(int(n)) || n < 0 || n > 255 {
+		return 0, p.errorAt(start, "palette index %s is not a whole number from 0 to 255", p.s[start:p.pos])
+	}
+	return int(n), nil
+}
+
+// color parses a bracketed color whose components are at most max.
+func (p *parser) color(max float64) (Color, error) {
+	var c Color
+	if err := p.expect('['); err != nil {
+		return c, err
+	}
+	for i := range c {
+		if i > 0 {
+			if err := p.expect(','); err != nil {
+				return c, err
+			}
+		}
+		start := p.skip()
+		n, err := p.number()
+		if err != nil {
+			return c, err
+		}
+		if n > max || max > 2 && n != float64(int(n)) {
+			if max > 2 {
+				return c, p.errorAt(start, "color component %s is not a whole number from 0 to 255", p.s[start:p.pos])
+			}
+			return c, p.errorAt(start, "desaturated color component %s is not from 0 to 2", p.s[start:p.pos])
+		}
+		c[i] = n
+	}
+	return c, p.expect(']')
+}
+
+func (p *parser) gradient(r *Range, max float64) error {
+	var err error
+	if r.From, err = p.color(max); err != nil {
+		return err
+	}
+	if err = p.expect(':'); err != nil {
+		return err
+	}
+	r.To, err = p.color(max)
+	return err
+}
@@ -0,0 +1,60 @@
+package translation_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/translation"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		s    string
+		want translation.Range
+	}{
+		{"112:127=176:191", translation.Range{Kind: translation.Indexes, Start: 112, End: 127, ToStart: 176, ToEnd: 191}},
+		{"112:127=[255,0,0]:[128,0,0]", translation.Range{Kind: translation.Gradient, Start: 112, End: 127,
+			From: translation.Color{255, 0, 0}, To: translation.Color{128, 0, 0}}},
+		{"0:255=%[0,0,0]:[1.5,0.75, 1]", translation.Range{Kind: translation.Desaturated, End: 255,
+			To: translation.Color{1.5, 0.75, 1}}},
+		{" 16 : 47 = #[64,255,64]", translation.Range{Kind: translation.Colorize, Start: 16, End: 47,
+			From: translation.Color{64, 255, 64}}},
+		{"16:47=@40[0,0,255]", translation.Range{Kind: translation.Tint, Start: 16, End: 47, Amount: 40,
+			From: translation.Color{0, 0, 255}}},
+	}
+	for _, tt := range tests {
+		got, err := translation.Parse(tt.s)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", tt.s, err)
+		} else if *got != tt.want {
+			t.Errorf("Parse(%q) = %+v, want %+v", tt.s, *got, tt.want)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		s      string
+		offset int
+		want   string
+	}{
+		{"112:127", 7, `translation: offset 7: expected '=' at the end of the translation`},
+		{"112:300=0:1", 4, `translation: offset 4: palette index 300 is not a whole number from 0 to 255`},
+		{"1:2=[255,0]:[0,0,0]", 10, `translation: offset 10: expected ',', found ']'`},
+		{"1:2=[256,0,0]:[0,0,0]", 5, `translation: offset 5: color component 256 is not a whole number from 0 to 255`},
+		{"1:2=%[0,3,0]:[0,0,0]", 8, `translation: offset 8: desaturated color component 3 is not from 0 to 2`},
+		{"1:2=@150[0,0,0]", 5, `translation: offset 5: tint amount 150 is not a whole number from 0 to 100`},
+		{"1:2=3:4,", 7, `translation: offset 7: unexpected "," after the range`},
+		{"1:2=x", 4, `translation: offset 4: expected a number, found 'x'`},
+	}
+	for _, tt := range tests {
+		_, err := translation.Parse(tt.s)
+		var e *translation.Error
+		if !errors.As(err, &e) || e.Offset != tt.offset || err.Error() != tt.want {
+			t.Errorf("Parse(%q): got %v, want %s", tt.s, err, tt.want)
+		}
+	}
+	if translation.IsRange("Ice") || !translation.IsRange("1:2=3:4") {
+		t.Error("IsRange does not tell names from ranges")
+	}
+}
@@ -0,0 +1,632 @@
+// Package types infers the static types of expressions from literals,
+// operators and declarations, including the engine's built-in classes and
+// structs, for completion, hover and lint rules that depend on what an
+// expression holds.
+package types
+
+import (
+	"strings"
+
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+
+	zscript "github.com/jlcrochet/tree-sitter-zscript/bindings/go"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/engine"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/project"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/resolve"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/symbols"
+)
+
+// Kind classifies a Type.
+type Kind int
+
+// The kinds of type. Integer types of every width are Int, and float is
+// Double.
+const (
+	Unknown Kind = iota
+	Void
+	Null
+	Bool
+	Int
+	Double
+	String
+	Name
+	Sound
+	Color
+	State
+	StateLabel
+	SpriteID
+	TextureID
+	Vector2
+	Vector3
+	Vector4
+	Quat
+	// Named is a class, struct or enum, by Type.Name.
+	Named
+	// Class is a class reference, class<Type.Name>.
+	Class
+	// Array is a dynamic or fixed-size array of Type.Elem.
+	Array
+	// Map maps Type.Key to Type.Elem.
+	Map
+)
+
+var kindNames = map[string]Kind{
+	"void": Void, "bool": Bool, "int": Int, "uint": Int, "int8": Int,
+	"uint8": Int, "int16": Int, "uint16": Int, "sbyte": Int, "byte": Int,
+	"short": Int, "ushort": Int, "double": Double, "float": Double,
+	"float64": Double, "string": String, "name": Name, "sound": Sound,
+	"color": Color, "state": State, "statelabel": StateLabel,
+	"spriteid": SpriteID, "textureid": TextureID, "vector2": Vector2,
+	"fvector2": Vector2, "vector3": Vector3, "fvector3": Vector3,
+	"vector4": Vector4, "fvector4": Vector4, "quat": Quat, "fquat": Quat,
+}
+
+// Type is the static type of an expression.
+type Type struct {
+	Kind Kind
+	// Name is the class, struct or enum of a Named or Class type, as
+	// declared.
+	Name string
+	// Key and Elem are the key type of a Map and the element type of an
+	// Array or a Map.
+	Key, Elem *Type
+}
+
+// Of returns the type of kind k.
+func Of(k Kind) Type {
+	return Type{Kind: k}
+}
+
+// String formats t as it would be declared, such as "int", "Actor",
+// "class<Actor>" or "array<vector3>". An Unknown type is "".
+func (t Type) String() string {
+	switch t.Kind {
+	case Unknown:
+		return ""
+	case Null:
+		return "null"
+	case Named:
+		return t.Name
+	case Class:
+		return "class<" + t.Name + ">"
+	case Array:
+		return "array<" + t.elem().String() + ">"
+	case Map:
+		return "map<" + t.key().String() + ", " + t.elem().String() + ">"
+	}
+	return kindStrings[t.Kind]
+}
+
+var kindStrings = [...]string{
+	Void: "void", Bool: "bool", Int: "int", Double: "double", String: "string",
+	Name: "name", Sound: "sound", Color: "color", State: "state",
+	StateLabel: "statelabel", SpriteID: "spriteid", TextureID: "textureid",
+	Vector2: "vector2", Vector3: "vector3", Vector4: "vector4", Quat: "quat",
+}
+
+func (t Type) elem() Type {
+	if t.Elem == nil {
+		return Type{}
+	}
+	return *t.Elem
+}
+
+func (t Type) key() Type {
+	if t.Key == nil {
+		return Type{}
+	}
+	return *t.Key
+}
+
+// IsNumeric reports whether t is Int or Double.
+func (t Type) IsNumeric() bool {
+	return t.Kind == Int || t.Kind == Double
+}
+
+// IsVector reports whether t is one of the vector types.
+func (t Type) IsVector() bool {
+	return t.Kind == Vector2 || t.Kind == Vector3 || t.Kind == Vector4
+}
+
+// Parse returns the type that a declaration writes as typ, such as
+// "int", "readonly<Actor>", "class<Inventory>" or "array<double>". Let and
+// var, whose type comes from an initializer, are Unknown, as is "".
+func Parse(typ string) Type {
+	typ = strings.Join(strings.Fields(typ), "")
+	lower := strings.ToLower(typ)
+	if i := strings.IndexByte(typ, '['); i > 0 && strings.HasSuffix(typ, "]") {
+		elem := Parse(typ[:i])
+		return Type{Kind: Array, Elem: &elem}
+	}
+	if open := strings.IndexByte(typ, '<'); open > 0 && strings.HasSuffix(typ, ">") {
+		args := typ[open+1 : len(typ)-1]
+		switch lower[:open] {
+		case "readonly":
+			return Parse(args)
+		case "class":
+			return Type{Kind: Class, Name: args}
+		case "array":
+			elem := Parse(args)
+			return Type{Kind: Array, Elem: &elem}
+		case "map", "mapiterator":
+			k, v := splitArgs(args)
+			key, elem := Parse(k), Parse(v)
+			return Type{Kind: Map, Key: &key, Elem: &elem}
+		}
+		return Type{}
+	}
+	switch lower {
+	case "", "let", "var":
+		return Type{}
+	case "class":
+		return Type{Kind: Class, Name: "Object"}
+	}
+	if k, ok := kindNames[lower]; ok {
+		return Type{Kind: k}
+	}
+	return Type{Kind: Named, Name: typ}
+}
+
+// splitArgs splits "K,V" at the comma outside angle brackets.
+func splitArgs(args string) (string, string) {
+	depth := 0
+	for i, r := range args {
+		switch r {
+		case '<':
+			depth++
+		case '>':
+			depth--
+		case ',':
+			if depth == 0 {
+				return args[:i], args[i+1:]
+			}
+		}
+	}
+	return args, ""
+}
+
+// Checker infers the types of expressions.
+type Checker struct {
+	r     *resolve.Resolver
+	trees func(path string) *zscript.Tree
+	// active holds the declarations whose types are being inferred from
+	// their values, to catch cycles.
+	active map[resolve.Location]bool
+}
+
+// New returns a checker that resolves names with r. trees returns the
+// parsed file at a path declarations record, or nil if it is not
+// available, in which case the types of its constants are not known. The
+// engine's declarations are always available.
+func New(r *resolve.Resolver, trees func(path string) *zscript.Tree) *Checker {
+	return &Checker{r: r, trees: trees, active: map[resolve.Location]bool{}}
+}
+
+// ForProject returns a checker for the files of p and the engine
+// declarations for the version p declares.
+func ForProject(p *project.Project) *Checker {
+	return New(resolve.ForProject(p), p.Tree)
+}
+
+// TypeOf returns the type of node, an expression in tree, resolving names
+// against the declarations of tree alone and those of the engine.
+func TypeOf(tree *zscript.Tree, node *tree_sitter.Node) Type {
+	r := resolve.NewWithEngine(engine.Version, symbols.Extract(tree))
+	return New(r, func(path string) *zscript.Tree {
+		if path == tree.Path {
+			return tree
+		}
+		return nil
+	}).TypeOf(tree, node)
+}
+
+// TypeOf returns the type of node, an expression in tree, or an Unknown
+// type if it cannot be inferred.
+func (c *Checker) TypeOf(tree *zscript.Tree, node *tree_sitter.Node) Type {
+	if node == nil {
+		return Type{}
+	}
+	switch node.Kind() {
+	case zscript.NodeNumberLiteral:
+		lit := strings.ToLower(text(tree, node))
+		hex := strings.Contains(lit, "0x")
+		if strings.Contains(lit, ".") || !hex && strings.ContainsAny(lit, "ef") || hex && strings.Contains(lit, "p") {
+			return Of(Double)
+		}
+		return Of(Int)
+	case zscript.NodeStringLiteral, zscript.NodeConcatenatedString:
+		return Of(String)
+	case zscript.NodeNameLiteral:
+		return Of(Name)
+	case zscript.NodeTrue, zscript.NodeFalse:
+		return Of(Bool)
+	case zscript.NodeNull:
+		return Of(Null)
+	case zscript.NodeVectorLiteral:
+		// The grammar may name the last of three components w, so they are
+		// counted instead.
+		n := 0
+		for i := uint(0); i < node.NamedChildCount(); i++ {
+			if node.NamedChild(i).Kind() != zscript.NodeComment {
+				n++
+			}
+		}
+		switch n {
+		case 4:
+			return Of(Vector4)
+		case 3:
+			return Of(Vector3)
+		}
+		return Of(Vector2)
+	case zscript.NodeParenthesizedExpression:
+		for i := uint(0); i < node.NamedChildCount(); i++ {
+			if n := node.NamedChild(i); n.Kind() != zscript.NodeComment {
+				return c.TypeOf(tree, n)
+			}
+		}
+	case zscript.NodeUnaryExpression:
+		if op := node.ChildByFieldName(zscript.FieldOperator); op != nil && op.Kind() == "!" {
+			return Of(Bool)
+		}
+		return c.TypeOf(tree, node.ChildByFieldName(zscript.FieldArgument))
+	case zscript.NodeUpdateExpression:
+		return c.TypeOf(tree, node.ChildByFieldName(zscript.FieldArgument))
+	case zscript.NodeBinaryExpression:
+		return c.binary(tree, node)
+	case zscript.NodeConditionalExpression:
+		a := c.TypeOf(tree, node.ChildByFieldName(zscript.FieldConsequence))
+		b := c.TypeOf(tree, node.ChildByFieldName(zscript.FieldAlternative))
+		switch {
+		case a.IsNumeric() && b.IsNumeric():
+			return arithmetic(a, b)
+		case a.Kind == Unknown || a.Kind == Null:
+			return b
+		}
+		return a
+	case zscript.NodeAssignmentExpression:
+		return c.TypeOf(tree, node.ChildByFieldName(zscript.FieldLeft))
+	case zscript.NodeCastExpression:
+		return c.named(Parse(text(tree, node.ChildByFieldName(zscript.FieldType))))
+	case zscript.NodeSizeofExpression, zscript.NodeAlignofExpression:
+		return Of(Int)
+	case zscript.NodeRandomExpression:
+		if fn := node.ChildByFieldName(zscript.FieldFunction); fn != nil && strings.HasPrefix(strings.ToLower(text(tree, fn)), "f") {
+			return Of(Double)
+		}
+		return Of(Int)
+	case zscript.NodeGetclassExpression:
+		return Type{Kind: Class, Name: "Object"}
+	case zscript.NodeStateExpression:
+		return Of(State)
+	case zscript.NodeTypeMemberExpression:
+		return Of(Double)
+	case zscript.NodeSelfExpression, zscript.NodeInvokerExpression:
+		if class := resolve.EnclosingType(node, tree.Source); class != "" {
+			return Type{Kind: Named, Name: class}
+		}
+	case zscript.NodeSuperExpression:
+		if class := resolve.EnclosingType(node, tree.Source); class != "" {
+			if h := c.r.Hierarchy().Class(class); h != nil && h.Parent != nil {
+				return Type{Kind: Named, Name: h.Parent.Name}
+			}
+		}
+	case zscript.NodeSubscriptExpression:
+		switch t := c.TypeOf(tree, node.ChildByFieldName(zscript.FieldArgument)); t.Kind {
+		case Array, Map:
+			return t.elem()
+		case String:
+			return Of(Int)
+		case Vector2, Vector3, Vector4:
+			return Of(Double)
+		}
+	case zscript.NodeIdentifier:
+		return c.identifier(tree, node)
+	case zscript.NodeFieldExpression:
+		return c.field(tree, node)
+	case zscript.NodeCallExpression:
+		return c.call(tree, node)
+	}
+	return Type{}
+}
+
+func (c *Checker) binary(tree *zscript.Tree, node *tree_sitter.Node) Type {
+	op := node.ChildByFieldName(zscript.FieldOperator)
+	if op == nil {
+		return Type{}
+	}
+	switch op.Kind() {
+	case "&&", "||", "==", "!=", "~==", "<", "<=", ">", ">=", "is":
+		return Of(Bool)
+	case "..":
+		return Of(String)
+	case "<>=", "&", "|", "^", "<<", ">>", ">>>":
+		return Of(Int)
+	}
+	l := c.TypeOf(tree, node.ChildByFieldName(zscript.FieldLeft))
+	r := c.TypeOf(tree, node.ChildByFieldName(zscript.FieldRight))
+	switch op.Kind() {
+	case "dot":
+		return Of(Double)
+	case "cross":
+		return Of(Vector3)
+	}
+	switch {
+	case l.IsVector():
+		return l
+	case r.IsVector():
+		return r
+	case l.IsNumeric() && r.IsNumeric():
+		if op.Kind() == "**" {
+			return Of(Double)
+		}
+		return arithmetic(l, r)
+	}
+	return Type{}
+}
+
+// arithmetic returns the type of an arithmetic operation on numbers.
+func arithmetic(a, b Type) Type {
+	if a.Kind == Double || b.Kind == Double {
+		return Of(Double)
+	}
+	return Of(Int)
+}
+
+// identifier returns the type of the variable, constant or enumerator an
+// identifier names.
+func (c *Checker) identifier(tree *zscript.Tree, node *tree_sitter.Node) Type {
+	decl, ok := c.r.Resolve(tree, node)
+	if !ok {
+		return Type{}
+	}
+	return c.declared(tree, decl)
+}
+
+// declared returns the type of a declaration that holds a value. The types
+// of constants and of variables declared with let come from their values.
+func (c *Checker) declared(tree *zscript.Tree, decl resolve.Declaration) Type {
+	switch decl.Kind {
+	case symbols.KindEnumerator:
+		return Type{Kind: Named, Name: decl.Type}
+	case symbols.KindClass, symbols.KindStruct, symbols.KindEnum:
+		// A type name, as the receiver of a static member.
+		return Type{Kind: Named, Name: decl.Name}
+	case symbols.KindMethod:
+		return Type{}
+	}
+	if t := c.named(Parse(decl.Type)); t.Kind != Unknown {
+		if decl.Kind == symbols.KindLocal && t.Kind != Array {
+			if n := nodeAt(tree, decl.NameRange); n != nil && n.Parent() != nil && n.Parent().Kind() == zscript.NodeArrayDeclarator {
+				return Type{Kind: Array, Elem: &t}
+			}
+		}
+		return t
+	}
+	source := tree
+	if decl.Path != tree.Path {
+		source = nil
+		if decl.Path == engine.Path {
+			source = engine.Tree()
+		} else if c.trees != nil {
+			source = c.trees(decl.Path)
+		}
+	}
+	if source == nil {
+		return Type{}
+	}
+	loc := resolve.Location{Path: decl.Path, Range: decl.NameRange}
+	if c.active[loc] {
+		return Type{}
+	}
+	c.active[loc] = true
+	defer delete(c.active, loc)
+	for n := nodeAt(source, decl.NameRange); n != nil; n = n.Parent() {
+		switch n.Kind() {
+		case zscript.NodeInitDeclarator, zscript.NodeConstDefinition:
+			return c.TypeOf(source, n.ChildByFieldName(zscript.FieldValue))
+		case zscript.NodeDeclaration, zscript.NodeSourceFile:
+			return Type{}
+		}
+	}
+	return Type{}
+}
+
+// named spells the name of a Named type as its declaration does. Types
+// that are not declared are left as they are.
+func (c *Checker) named(t Type) Type {
+	if t.Kind == Named {
+		for _, decl := range c.r.LookupType("", t.Name) {
+			t.Name = decl.Name
+			break
+		}
+	}
+	return t
+}
+
+// field returns the type of a member access.
+func (c *Checker) field(tree *zscript.Tree, node *tree_sitter.Node) Type {
+	receiver := node.ChildByFieldName(zscript.FieldArgument)
+	field := node.ChildByFieldName(zscript.FieldField)
+	if field == nil {
+		return Type{}
+	}
+	name := strings.ToLower(text(tree, field))
+	recv := c.TypeOf(tree, receiver)
+	if recv.IsVector() || recv.Kind == Quat {
+		switch len(name) {
+		case 1:
+			return Of(Double)
+		case 2:
+			return Of(Vector2)
+		case 3:
+			return Of(Vector3)
+		}
+	}
+	if decl, ok := c.member(tree, receiver, recv, field); ok {
+		return c.declared(tree, decl)
+	}
+	return Type{}
+}
+
+// member finds the member field of the value receiver, of type recv,
+// falling back on the resolver for receivers whose type is not known.
+func (c *Checker) member(tree *zscript.Tree, receiver *tree_sitter.Node, recv Type, field *tree_sitter.Node) (resolve.Declaration, bool) {
+	name := text(tree, field)
+	if recv.Kind == Named || recv.Kind == Class {
+		super := receiver != nil && receiver.Kind() == zscript.NodeSuperExpression
+		typ := recv.Name
+		if super {
+			typ = resolve.EnclosingType(receiver, tree.Source)
+		}
+		for _, decl := range c.r.Members(typ, super) {
+			if strings.EqualFold(decl.Name, name) {
+				return decl, true
+			}
+		}
+		return resolve.Declaration{}, false
+	}
+	if recv.Kind != Unknown {
+		return resolve.Declaration{}, false
+	}
+	return c.r.Resolve(tree, field)
+}
+
+// call returns the type a call evaluates to: the return type of a method,
+// the class of a cast such as Actor(mo) or of new("Imp"), or the result
+// of a built-in method of strings, arrays, maps and vectors.
+func (c *Checker) call(tree *zscript.Tree, node *tree_sitter.Node) Type {
+	fn := node.ChildByFieldName(zscript.FieldFunction)
+	args := node.ChildByFieldName(zscript.FieldArguments)
+	if fn == nil {
+		return Type{}
+	}
+	switch fn.Kind() {
+	case zscript.NodeIdentifier, zscript.NodeTypeIdentifier:
+		name := text(tree, fn)
+		if k, ok := kindNames[strings.ToLower(name)]; ok {
+			// int(x), name(s) and the like are conversions.
+			return Of(k)
+		}
+		if strings.EqualFold(name, "new") {
+			if args == nil || args.NamedChildCount() == 0 {
+				return Type{}
+			}
+			arg := args.NamedChild(0)
+			switch arg.Kind() {
+			case zscript.NodeStringLiteral, zscript.NodeNameLiteral:
+				return c.named(Type{Kind: Named, Name: strings.Trim(text(tree, arg), `"'`)})
+			}
+			if t := c.TypeOf(tree, arg); t.Kind == Class {
+				return Type{Kind: Named, Name: t.Name}
+			}
+			return Type{}
+		}
+		decls := c.r.ResolveAll(tree, fn)
+		if len(decls) == 0 {
+			decls = c.r.LookupType(resolve.EnclosingType(node, tree.Source), name)
+		}
+		if len(decls) == 0 {
+			return Type{}
+		}
+		decl := decls[0]
+		if decl.Kind == symbols.KindClass {
+			return Type{Kind: Named, Name: decl.Name}
+		}
+		if decl.Method != nil {
+			return c.returnType(decl)
+		}
+	case zscript.NodeFieldExpression:
+		receiver := fn.ChildByFieldName(zscript.FieldArgument)
+		field := fn.ChildByFieldName(zscript.FieldField)
+		if field == nil {
+			return Type{}
+		}
+		var recv Type
+		if receiver != nil && strings.EqualFold(text(tree, receiver), "string") {
+			// String.Format and the like are static.
+			recv = Of(String)
+		} else {
+			recv = c.TypeOf(tree, receiver)
+		}
+		if t, ok := builtin(recv, strings.ToLower(text(tree, field))); ok {
+			return t
+		}
+		if decl, ok := c.member(tree, receiver, recv, field); ok && decl.Method != nil {
+			return c.returnType(decl)
+		}
+	}
+	return Type{}
+}
+
+// returnType returns the type of the first value a method returns.
+func (c *Checker) returnType(decl resolve.Declaration) Type {
+	typ, _, _ := strings.Cut(decl.Method.ReturnType, ",")
+	return c.named(Parse(typ))
+}
+
+// builtin returns the result of a built-in method of a string, array, map
+// or vector.
+func builtin(recv Type, method string) (Type, bool) {
+	var methods map[string]Type
+	switch {
+	case recv.Kind == String:
+		methods = stringMethods
+	case recv.Kind == Array:
+		if method == "pop" {
+			return Of(Bool), true
+		}
+		methods = arrayMethods
+	case recv.Kind == Map:
+		switch method {
+		case "get", "getifexists":
+			return recv.elem(), true
+		}
+		methods = mapMethods
+	case recv.IsVector():
+		if method == "unit" {
+			return recv, true
+		}
+		methods = vectorMethods
+	}
+	t, ok := methods[method]
+	return t, ok
+}
+
+var (
+	stringMethods = map[string]Type{
+		"length": Of(Int), "codepointcount": Of(Int), "indexof": Of(Int),
+		"lastindexof": Of(Int), "rightindexof": Of(Int), "byteat": Of(Int),
+		"getnextcodepoint": Of(Int), "toint": Of(Int), "todouble": Of(Double),
+		"left": Of(String), "mid": Of(String), "filter": Of(String),
+		"format": Of(String), "makelower": Of(String), "makeupper": Of(String),
+		"charat": Of(String), "split": Of(Void), "replace": Of(Void),
+		"appendformat": Of(Void), "truncate": Of(Void), "remove": Of(Void),
+		"deletelastcharacter": Of(Void), "stripleft": Of(Void),
+		"stripright": Of(Void), "stripleftright": Of(Void),
+	}
+	arrayMethods = map[string]Type{
+		"size": Of(Int), "find": Of(Int), "push": Of(Int), "max": Of(Int),
+		"reserve": Of(Int), "delete": Of(Void), "insert": Of(Void),
+		"clear": Of(Void), "resize": Of(Void), "grow": Of(Void),
+		"shrinktofit": Of(Void), "copy": Of(Void), "move": Of(Void),
+		"append": Of(Void),
+	}
+	mapMethods = map[string]Type{
+		"checkkey": Of(Bool), "countused": Of(Int), "insert": Of(Void),
+		"insertnew": Of(Void), "remove": Of(Void), "clear": Of(Void),
+		"copy": Of(Void), "move": Of(Void), "swap": Of(Void),
+	}
+	vectorMethods = map[string]Type{
+		"length": Of(Double), "lengthsquared": Of(Double), "angle": Of(Double),
+		"sum": Of(Double), "plusz": Of(Vector3),
+	}
+)
+
+func text(tree *zscript.Tree, node *tree_sitter.Node) string {
+	return node.Utf8Text(tree.Source)
+}
+
+// nodeAt returns the smallest node of tree that spans r.
+func nodeAt(tree *zscript.Tree, r tree_sitter.Range) *tree_sitter.Node {
+	return tree.RootNode().NamedDescendantForByteRange(r.StartByte, r.EndByte)
+}
@@ -0,0 +1,137 @@
+package types_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+
+	zscript "github.com/jlcrochet/tree-sitter-zscript/bindings/go"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/types"
+)
+
+const source = `class Base : Actor {
+	enum EMode { M_Idle, M_Hunt }
+	const SPEED = 2.5;
+	EMode mode;
+	Array<Actor> targets;
+	Map<Name, int> counts;
+	class<Inventory> drop;
+	Vector3 home;
+	Base Leader() { return self; }
+}
+
+class Imp : Base {
+	void F(Actor mo, String tag) {
+		let inv = mo.FindInventory("Clip");
+		int spots[4];
+		let who = Leader();
+		X = 0;
+	}
+}
+`
+
+func TestTypeOf(t *testing.T) {
+	tests := []struct {
+		expr, want string
+	}{
+		{"1 + 2", "int"},
+		{"1 + 2.", "double"},
+		{"0x1F", "int"},
+		{"1e3", "double"},
+		{"7 / 2 * SPEED", "double"},
+		{"1 << 3", "int"},
+		{"health > 0 && mo != null", "bool"},
+		{"mo is 'Imp'", "bool"},
+		{`"a" .. 1`, "string"},
+		{"'Fire'", "name"},
+		{"(1, 2)", "vector2"},
+		{"(1, 2, 3) * 2", "vector3"},
+		{"home.xy", "vector2"},
+		{"home.z", "double"},
+		{"home.Length()", "double"},
+		{"(home - mo.pos).Unit()", "vector3"},
+		{"mode", "EMode"},
+		{"M_Hunt", "EMode"},
+		{"targets", "array<Actor>"},
+		{"targets[0]", "Actor"},
+		{"targets.Size()", "int"},
+		{"counts.Get('imp')", "int"},
+		{"drop", "class<Inventory>"},
+		{"new(drop)", "Inventory"},
+		{`new("Base")`, "Base"},
+		{"Base(mo)", "Base"},
+		{"inv", "Inventory"},
+		{"inv.Amount", "int"},
+		{"who.targets", "array<Actor>"},
+		{"spots", "array<int>"},
+		{"tag.Left(2)", "string"},
+		{"tag.Length()", "int"},
+		{`String.Format("%d", 1)`, "string"},
+		{"self", "Imp"},
+		{"Super.Leader()", "Base"},
+		{"mo.target", "Actor"},
+		{"mo.ResolveState('See')", "state"},
+		{"frandom(0, 1)", "double"},
+		{"random(0, 1)", "int"},
+		{"int(SPEED)", "int"},
+		{"health > 0 ? 1 : 2.", "double"},
+		{"nothing", ""},
+	}
+	for _, tt := range tests {
+		src := strings.Replace(source, "X = 0;", "X = "+tt.expr+";", 1)
+		tree, err := zscript.Parse(context.Background(), []byte(src))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := types.TypeOf(tree, assigned(tree)).String(); got != tt.want {
+			t.Errorf("TypeOf(%s) = %q, want %q", tt.expr, got, tt.want)
+		}
+		tree.Close()
+	}
+}
+
+// assigned returns the right side of the assignment to X.
+func assigned(tree *zscript.Tree) *tree_sitter.Node {
+	var found *tree_sitter.Node
+	var v zscript.Visitor
+	v.On(zscript.NodeAssignmentExpression, func(node *tree_sitter.Node) zscript.WalkAction {
+		if left := node.ChildByFieldName(zscript.FieldLeft); left != nil && left.Utf8Text(tree.Source) == "X" {
+			found = node.ChildByFieldName(zscript.FieldRight)
+			return zscript.WalkStop
+		}
+		return zscript.WalkContinue
+	})
+	zscript.Walk(tree.RootNode(), &v)
+	return found
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		typ  string
+		want types.Type
+	}{
+		{"int", types.Of(types.Int)},
+		{"uint8", types.Of(types.Int)},
+		{"float", types.Of(types.Double)},
+		{"FVector3", types.Of(types.Vector3)},
+		{"let", types.Type{}},
+		{"readonly< Actor >", types.Type{Kind: types.Named, Name: "Actor"}},
+		{"class", types.Type{Kind: types.Class, Name: "Object"}},
+	}
+	for _, tt := range tests {
+		if got := types.Parse(tt.typ); got != tt.want {
+			t.Errorf("Parse(%q) = %+v, want %+v", tt.typ, got, tt.want)
+		}
+	}
+	for typ, want := range map[string]string{
+		"Array<class<Actor> >":  "array<class<Actor>>",
+		"Map<Name, Array<int>>": "map<name, array<int>>",
+		"double[3]":             "array<double>",
+	} {
+		if got := types.Parse(typ).String(); got != want {
+			t.Errorf("Parse(%q) = %q, want %q", typ, got, want)
+		}
+	}
+}
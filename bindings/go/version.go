@@ -0,0 +1,71 @@
+package tree_sitter_zscript
+
+import (
+	"fmt"
+
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+)
+
+// Version returns the version of the grammar, such as "0.1.0", as written
+// in tree-sitter.json when the parser was generated. It is empty for a
+// parser generated by a tree-sitter CLI too old to record it.
+func Version() string {
+	m := GetLanguage().Metadata()
+	if m == nil {
+		return ""
+	}
+	return fmt.Sprintf("%d.%d.%d", m.MajorVersion, m.MinorVersion, m.PatchVersion)
+}
+
+// ABIVersion returns the ABI version of the generated parser, which
+// depends on the tree-sitter CLI that generated it.
+func ABIVersion() uint32 {
+	return GetLanguage().AbiVersion()
+}
+
+// NodeKindCount returns the number of node kinds of the grammar, named and
+// anonymous, including those used only inside the parser.
+func NodeKindCount() uint32 {
+	return GetLanguage().NodeKindCount()
+}
+
+// IncompatibleError is returned when the parser's ABI version is outside
+// the range the linked tree-sitter runtime supports.
+type IncompatibleError struct {
+	ABIVersion uint32
+	// MinABIVersion and MaxABIVersion are the range the runtime supports.
+	MinABIVersion, MaxABIVersion uint32
+}
+
+func (e *IncompatibleError) Error() string {
+	advice := "regenerate the parser with an older tree-sitter CLI or upgrade go-tree-sitter"
+	if e.ABIVersion < e.MinABIVersion {
+		advice = "regenerate the parser with a newer tree-sitter CLI or downgrade go-tree-sitter"
+	}
+	return fmt.Sprintf("zscript: parser ABI version %d is not supported by the tree-sitter runtime, which supports versions %d to %d; %s",
+		e.ABIVersion, e.MinABIVersion, e.MaxABIVersion, advice)
+}
+
+// CheckCompatibility returns an *IncompatibleError if the tree-sitter
+// runtime linked into the program cannot load the parser. Parse and the
+// other functions that create parsers return the same error.
+func CheckCompatibility() error {
+	abi := ABIVersion()
+	if abi < tree_sitter.MIN_COMPATIBLE_LANGUAGE_VERSION || abi > tree_sitter.LANGUAGE_VERSION {
+		return &IncompatibleError{abi, tree_sitter.MIN_COMPATIBLE_LANGUAGE_VERSION, tree_sitter.LANGUAGE_VERSION}
+	}
+	return nil
+}
+
+// newParser returns a parser for this grammar.
+func newParser() (*tree_sitter.Parser, error) {
+	if err := CheckCompatibility(); err != nil {
+		return nil, err
+	}
+	parser := tree_sitter.NewParser()
+	if err := parser.SetLanguage(GetLanguage()); err != nil {
+		parser.Close()
+		return nil, err
+	}
+	return parser, nil
+}
@@ -0,0 +1,324 @@
+// Package version reads the ZScript version a file declares and reports
+// code that needs a newer version than that: syntax introduced later, and
+// uses of classes and members declared with a later version("x")
+// qualifier.
+package version
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+
+	zscript "github.com/jlcrochet/tree-sitter-zscript/bindings/go"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/project"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/symbols"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/zscriptast"
+)
+
+// Version is a ZScript version.
+type Version struct {
+	Major, Minor, Revision int
+}
+
+// Default is the version GZDoom assumes for a project without a version
+// directive.
+var Default = Version{2, 3, 0}
+
+// Parse parses a version such as "4.10" or "4.10.0".
+func Parse(s string) (Version, error) {
+	parts := strings.Split(strings.TrimSpace(s), ".")
+	if len(parts) < 2 || len(parts) > 3 {
+		return Version{}, fmt.Errorf("version: invalid version %q", s)
+	}
+	var nums [3]int
+	for i, p := range parts {
+		n, err := strconv.Atoi(p)
+		if err != nil || n < 0 {
+			return Version{}, fmt.Errorf("version: invalid version %q", s)
+		}
+		nums[i] = n
+	}
+	return Version{nums[0], nums[1], nums[2]}, nil
+}
+
+// MustParse is like Parse but panics on error.
+func MustParse(s string) Version {
+	v, err := Parse(s)
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
+
+func (v Version) String() string {
+	if v.Revision != 0 {
+		return fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Revision)
+	}
+	return fmt.Sprintf("%d.%d", v.Major, v.Minor)
+}
+
+// Compare returns -1, 0 or +1 as v is older than, the same as, or newer
+// than w.
+func (v Version) Compare(w Version) int {
+	for _, d := range [...]int{v.Major - w.Major, v.Minor - w.Minor, v.Revision - w.Revision} {
+		switch {
+		case d < 0:
+			return -1
+		case d > 0:
+			return 1
+		}
+	}
+	return 0
+}
+
+// Of returns the version declared by tree's version directive. ok is
+// false if there is no directive or it does not hold a valid version.
+func Of(tree *zscript.Tree) (v Version, ok bool) {
+	directive, found := zscriptast.NewFile(tree.Tree, tree.Source).Version()
+	if !found {
+		return Version{}, false
+	}
+	v, err := Parse(directive.Version())
+	return v, err == nil
+}
+
+// ForProject returns the version that applies to every file of p. GZDoom
+// reads the directive only from the root lumps, so files included by
+// another file are not consulted. Default is returned if no root declares
+// a version.
+func ForProject(p *project.Project) Version {
+	included := map[string]bool{}
+	for _, f := range p.Files {
+		for _, inc := range f.Includes {
+			included[strings.ToLower(inc)] = true
+		}
+	}
+	for _, f := range p.Files {
+		if included[strings.ToLower(f.Path)] {
+			continue
+		}
+		if v, ok := Of(f.Tree); ok {
+			return v
+		}
+	}
+	return Default
+}
+
+// qualifier returns the version in a version("x") modifier, if any.
+func qualifier(modifiers []string) (Version, bool) {
+	for _, m := range modifiers {
+		if rest, ok := strings.CutPrefix(strings.ToLower(m), "version"); ok {
+			rest = strings.Trim(strings.TrimSpace(rest), `()"`)
+			if v, err := Parse(rest); err == nil {
+				return v, true
+			}
+		}
+	}
+	return Version{}, false
+}
+
+// Problem is a use of a feature newer than the declared version.
+type Problem struct {
+	Path    string
+	Range   tree_sitter.Range
+	Message string
+	// What is the feature: a name as it is spelled, a syntax such as
+	// "unsigned integer literal", or a version("x") qualifier.
+	What string
+	// Needs is the version that introduced the feature.
+	Needs Version
+}
+
+func (p Problem) String() string {
+	return fmt.Sprintf("%s:%d:%d: %s", p.Path, p.Range.StartPoint.Row+1, p.Range.StartPoint.Column+1, p.Message)
+}
+
+type feature struct {
+	what  string
+	since Version
+}
+
+// syntax maps node kinds to the version that introduced them, and
+// keywordTypes primitive types, as zscript.Features lists them.
+var syntax, keywordTypes = features()
+
+func features() (map[string]feature, map[string]Version) {
+	kinds, keywords := map[string]feature{}, map[string]Version{}
+	for _, f := range zscript.Features {
+		since := MustParse(f.Since)
+		if f.Text != "" {
+			keywords[strings.ToLower(f.Text)] = since
+		} else {
+			kinds[f.Kind] = feature{f.What, since}
+		}
+	}
+	return kinds, keywords
+}
+
+// Check reports the code in tree that needs a version newer than v. The
+// classes and members declared in tables, which should include those of
+// tree, are checked for version qualifiers; a name is reported only if
+// every declaration of it requires a newer version.
+func Check(tree *zscript.Tree, v Version, tables ...*symbols.Table) []Problem {
+	c := checker{tree: tree, version: v, types: map[string]Version{}, members: map[string]Version{}}
+	for _, t := range tables {
+		c.declare(t)
+	}
+
+	var w zscript.Visitor
+	w.On(zscript.NodeCallExpression, func(node *tree_sitter.Node) zscript.WalkAction {
+		fn := node.ChildByFieldName(zscript.FieldFunction)
+		if fn != nil && fn.Kind() == zscript.NodeIdentifier {
+			c.member(fn)
+		}
+		return zscript.WalkContinue
+	})
+	w.On(zscript.NodeStateActionCall, func(node *tree_sitter.Node) zscript.WalkAction {
+		c.member(node.ChildByFieldName(zscript.FieldFunction))
+		return zscript.WalkContinue
+	})
+	w.On(zscript.NodeFieldExpression, func(node *tree_sitter.Node) zscript.WalkAction {
+		c.member(node.ChildByFieldName(zscript.FieldField))
+		return zscript.WalkContinue
+	})
+	w.On(zscript.NodeTypeIdentifier, func(node *tree_sitter.Node) zscript.WalkAction {
+		if since, ok := c.types[strings.ToLower(c.text(node))]; ok && !c.declaration(node) {
+			c.require(node, since, c.text(node))
+		}
+		return zscript.WalkContinue
+	})
+	w.On(zscript.NodePrimitiveType, func(node *tree_sitter.Node) zscript.WalkAction {
+		if since, ok := keywordTypes[strings.ToLower(c.text(node))]; ok {
+			c.require(node, since, c.text(node))
+		}
+		return zscript.WalkContinue
+	})
+	w.On(zscript.NodeClassFlag, c.qualifier)
+	w.On(zscript.NodeStructFlag, c.qualifier)
+	w.On(zscript.NodeMemberModifier, c.qualifier)
+	for kind := range syntax {
+		w.On(kind, func(node *tree_sitter.Node) zscript.WalkAction {
+			c.require(node, syntax[kind].since, syntax[kind].what)
+			return zscript.WalkContinue
+		})
+	}
+	zscript.Walk(tree.RootNode(), &w)
+	return c.problems
+}
+
+// CheckProject checks every file of p against ForProject(p).
+func CheckProject(p *project.Project) []Problem {
+	v := ForProject(p)
+	trees := make([]*zscript.Tree, len(p.Files))
+	for i, f := range p.Files {
+		trees[i] = f.Tree
+	}
+	tables := symbols.ExtractAll(0, trees...)
+	var problems []Problem
+	for _, f := range p.Files {
+		problems = append(problems, Check(f.Tree, v, tables...)...)
+	}
+	return problems
+}
+
+type checker struct {
+	tree     *zscript.Tree
+	version  Version
+	types    map[string]Version
+	members  map[string]Version
+	problems []Problem
+}
+
+// declare records the versions of the classes, structs and members in t.
+// A name declared both with and without a qualifier, or with several,
+// keeps the oldest, so that only uses that cannot be satisfied are
+// reported.
+func (c *checker) declare(t *symbols.Table) {
+	record := func(m map[string]Version, name string, qualifiers ...string) {
+		key := strings.ToLower(name)
+		v, ok := qualifier(qualifiers)
+		if !ok {
+			v = Version{}
+		}
+		if old, seen := m[key]; !seen || v.Compare(old) < 0 {
+			m[key] = v
+		}
+	}
+	for _, cls := range t.Classes {
+		if !cls.Extend {
+			record(c.types, cls.Name, "version("+cls.Version+")")
+		}
+		for _, m := range cls.Methods {
+			record(c.members, m.Name, m.Modifiers...)
+		}
+		for _, f := range cls.Fields {
+			record(c.members, f.Name, f.Modifiers...)
+		}
+	}
+	for _, s := range t.Structs {
+		if !s.Extend {
+			record(c.types, s.Name, "version("+s.Version+")")
+		}
+		for _, m := range s.Methods {
+			record(c.members, m.Name, m.Modifiers...)
+		}
+		for _, f := range s.Fields {
+			record(c.members, f.Name, f.Modifiers...)
+		}
+	}
+}
+
+func (c *checker) text(node *tree_sitter.Node) string {
+	return node.Utf8Text(c.tree.Source)
+}
+
+func (c *checker) member(name *tree_sitter.Node) {
+	if name == nil {
+		return
+	}
+	if since, ok := c.members[strings.ToLower(c.text(name))]; ok {
+		c.require(name, since, c.text(name))
+	}
+}
+
+// declaration reports whether a type identifier is the name of the class
+// or struct being declared, rather than a use of it.
+func (c *checker) declaration(node *tree_sitter.Node) bool {
+	parent := node.Parent()
+	if parent == nil {
+		return false
+	}
+	switch parent.Kind() {
+	case zscript.NodeClassDefinition, zscript.NodeStructDefinition:
+		name := parent.ChildByFieldName(zscript.FieldName)
+		return name != nil && name.Id() == node.Id()
+	}
+	return false
+}
+
+// qualifier reports version("x") qualifiers newer than the file's version.
+func (c *checker) qualifier(node *tree_sitter.Node) zscript.WalkAction {
+	if since, ok := qualifier([]string{c.text(node)}); ok && since.Compare(c.version) > 0 {
+		c.report(node, c.text(node), since, "version(%q) is newer than the declared version %s", since.String(), c.version)
+	}
+	return zscript.WalkSkipChildren
+}
+
+// require reports what if since is newer than the file's version.
+func (c *checker) require(node *tree_sitter.Node, since Version, what string) {
+	if since.Compare(c.version) > 0 {
+		c.report(node, what, since, "%s requires version %s, but %s is declared", what, since, c.version)
+	}
+}
+
+func (c *checker) report(node *tree_sitter.Node, what string, needs Version, format string, args ...any) {
+	c.problems = append(c.problems, Problem{
+		Path:    c.tree.Path,
+		Range:   node.Range(),
+		Message: fmt.Sprintf(format, args...),
+		What:    what,
+		Needs:   needs,
+	})
+}
@@ -0,0 +1,119 @@
+package version_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"testing/fstest"
+
+	zscript "github.com/jlcrochet/tree-sitter-zscript/bindings/go"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/project"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/version"
+)
+
+func TestParse(t *testing.T) {
+	for _, tt := range []struct {
+		in   string
+		want version.Version
+		ok   bool
+	}{
+		{"4.10", version.Version{4, 10, 0}, true},
+		{"2.3.1", version.Version{2, 3, 1}, true},
+		{"4", version.Version{}, false},
+		{"4.x", version.Version{}, false},
+	} {
+		got, err := version.Parse(tt.in)
+		if got != tt.want || (err == nil) != tt.ok {
+			t.Errorf("Parse(%q) = %v, %v", tt.in, got, err)
+		}
+	}
+	if v := version.MustParse("4.10"); v.Compare(version.MustParse("4.9")) <= 0 || v.Compare(version.MustParse("4.10.0")) != 0 || v.String() != "4.10" {
+		t.Errorf("4.10 compares wrongly")
+	}
+}
+
+const lib = `class Base : Actor {
+	version("4.12") void NewThing() {}
+	void OldThing() {}
+}
+class Shiny version("4.11") {}
+`
+
+const main = `version "4.10"
+#include "lib.zs"
+class Imp : Base {
+	version("4.11") int extra;
+	void F() {
+		NewThing();
+		OldThing();
+		Map<int, int> m;
+		vector4 v;
+		foreach (x : list) {}
+		let s = new("Shiny");
+		Shiny t;
+	}
+}
+`
+
+func TestCheckProject(t *testing.T) {
+	fsys := fstest.MapFS{
+		"zscript.zs": {Data: []byte(main)},
+		"lib.zs":     {Data: []byte(lib)},
+	}
+	p, err := project.Load(context.Background(), fsys, "zscript.zs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+	if v := version.ForProject(p); v != (version.Version{4, 10, 0}) {
+		t.Fatalf("ForProject() = %v", v)
+	}
+	if v, ok := version.Of(p.File("lib.zs").Tree); ok {
+		t.Errorf("Of(lib.zs) = %v", v)
+	}
+
+	var got []string
+	for _, problem := range version.CheckProject(p) {
+		got = append(got, problem.String())
+	}
+	want := []string{
+		`lib.zs:2:2: version("4.12") is newer than the declared version 4.10`,
+		`lib.zs:5:13: version("4.11") is newer than the declared version 4.10`,
+		`zscript.zs:4:2: version("4.11") is newer than the declared version 4.10`,
+		`zscript.zs:6:3: NewThing requires version 4.12, but 4.10 is declared`,
+		`zscript.zs:9:3: vector4 requires version 4.11, but 4.10 is declared`,
+		`zscript.zs:12:3: Shiny requires version 4.11, but 4.10 is declared`,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("CheckProject() =\n%q\nwant\n%q", got, want)
+	}
+}
+
+func TestCheckSyntax(t *testing.T) {
+	tree, err := zscript.Parse(context.Background(), []byte(main))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tree.Close()
+	v, ok := version.Of(tree)
+	if !ok {
+		t.Fatal("no version")
+	}
+	if n := len(version.Check(tree, v)); n != 2 {
+		t.Errorf("4.10: %d problems, want 2", n)
+	}
+	downgraded := version.Check(tree, version.MustParse("4.9"))
+	var messages []string
+	for _, p := range downgraded {
+		messages = append(messages, p.Message)
+	}
+	want := []string{
+		`version("4.11") is newer than the declared version 4.9`,
+		"Map types requires version 4.10, but 4.9 is declared",
+		"vector4 requires version 4.11, but 4.9 is declared",
+		"foreach loops requires version 4.10, but 4.9 is declared",
+	}
+	if !reflect.DeepEqual(messages, want) {
+		t.Errorf("4.9: %q\nwant %q", messages, want)
+	}
+}
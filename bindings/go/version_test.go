@@ -0,0 +1,59 @@
+package tree_sitter_zscript_test
+
+import (
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+
+	tree_sitter_zscript "github.com/jlcrochet/tree-sitter-zscript/bindings/go"
+)
+
+func TestVersion(t *testing.T) {
+	data, err := os.ReadFile("../../tree-sitter.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var config struct {
+		Metadata struct {
+			Version string `json:"version"`
+		} `json:"metadata"`
+	}
+	if err := json.Unmarshal(data, &config); err != nil {
+		t.Fatal(err)
+	}
+	if got := tree_sitter_zscript.Version(); got != config.Metadata.Version {
+		t.Errorf("Version() = %q, tree-sitter.json has %q", got, config.Metadata.Version)
+	}
+
+	if abi := tree_sitter_zscript.ABIVersion(); abi < tree_sitter.MIN_COMPATIBLE_LANGUAGE_VERSION || abi > tree_sitter.LANGUAGE_VERSION {
+		t.Errorf("ABIVersion() = %d", abi)
+	}
+	if err := tree_sitter_zscript.CheckCompatibility(); err != nil {
+		t.Error(err)
+	}
+
+	lang := tree_sitter_zscript.GetLanguage()
+	count := tree_sitter_zscript.NodeKindCount()
+	if id := lang.IdForNodeKind(tree_sitter_zscript.NodeClassDefinition, true); id == 0 || uint32(id) >= count {
+		t.Errorf("class_definition has id %d of %d kinds", id, count)
+	}
+}
+
+func TestIncompatibleError(t *testing.T) {
+	for _, tt := range []struct {
+		abi  uint32
+		want string
+	}{
+		{12, "newer tree-sitter CLI"},
+		{99, "upgrade go-tree-sitter"},
+	} {
+		err := &tree_sitter_zscript.IncompatibleError{ABIVersion: tt.abi, MinABIVersion: 13, MaxABIVersion: 15}
+		msg := err.Error()
+		if !strings.Contains(msg, tt.want) || !strings.Contains(msg, "13 to 15") {
+			t.Errorf("ABI %d: %q", tt.abi, msg)
+		}
+	}
+}
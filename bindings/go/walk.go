@@ -0,0 +1,103 @@
+package tree_sitter_zscript
+
+import (
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+)
+
+// WalkAction tells Walk how to proceed after visiting a node.
+type WalkAction int
+
+const (
+	// WalkContinue descends into the node's children.
+	WalkContinue WalkAction = iota
+	// WalkSkipChildren moves on to the node's next sibling.
+	WalkSkipChildren
+	// WalkStop ends the walk.
+	WalkStop
+)
+
+// VisitFunc is called when Walk enters a node.
+type VisitFunc func(node *tree_sitter.Node) WalkAction
+
+// Visitor holds the callbacks for Walk. The zero Visitor visits every node
+// and does nothing.
+type Visitor struct {
+	// Enter, if set, is called for every node before any kind-specific
+	// callback.
+	Enter VisitFunc
+	// Leave, if set, is called for every node after its children have been
+	// visited or skipped. It is not called once the walk has been stopped.
+	Leave func(node *tree_sitter.Node)
+	// SkipExtras makes Walk leave out extra nodes, which are comments, and
+	// their children, calling neither Enter nor Leave for them.
+	SkipExtras bool
+
+	kinds map[string]VisitFunc
+}
+
+// On registers fn to be called when Walk enters a node of the given kind.
+// Kinds are matched against Node.Kind, so anonymous nodes such as keywords
+// can be registered too. It returns v to allow chaining.
+func (v *Visitor) On(kind string, fn VisitFunc) *Visitor {
+	if v.kinds == nil {
+		v.kinds = map[string]VisitFunc{}
+	}
+	v.kinds[kind] = fn
+	return v
+}
+
+func (v *Visitor) enter(node *tree_sitter.Node) WalkAction {
+	action := WalkContinue
+	if v.Enter != nil {
+		action = v.Enter(node)
+	}
+	if action != WalkStop {
+		if fn := v.kinds[node.Kind()]; fn != nil {
+			action = max(action, fn(node))
+		}
+	}
+	return action
+}
+
+func (v *Visitor) leave(node *tree_sitter.Node) {
+	if v.Leave != nil {
+		v.Leave(node)
+	}
+}
+
+// Walk visits node and its descendants in depth-first order, including
+// anonymous nodes and, unless v.SkipExtras is set, comments. It reports
+// whether the walk ran to completion rather than being stopped by a
+// callback.
+func Walk(node *tree_sitter.Node, v *Visitor) bool {
+	cursor := node.Walk()
+	defer cursor.Close()
+
+	depth := 0
+	for {
+		current := cursor.Node()
+		// The node Walk starts from is visited even if it is an extra.
+		if !v.SkipExtras || !current.IsExtra() || depth == 0 {
+			action := v.enter(current)
+			if action == WalkStop {
+				return false
+			}
+			if action == WalkContinue && cursor.GotoFirstChild() {
+				depth++
+				continue
+			}
+			v.leave(current)
+		}
+
+		for !cursor.GotoNextSibling() {
+			if depth == 0 || !cursor.GotoParent() {
+				return true
+			}
+			depth--
+			v.leave(cursor.Node())
+		}
+		if depth == 0 {
+			return true
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package tree_sitter_zscript_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+
+	tree_sitter_zscript "github.com/jlcrochet/tree-sitter-zscript/bindings/go"
+)
+
+const walkSource = `
+class A : Actor { void F() { G(); } }
+class B : A { void H() {} }
+`
+
+func TestWalk(t *testing.T) {
+	source := []byte(walkSource)
+	tree, err := tree_sitter_zscript.Parse(context.Background(), source)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tree.Close()
+
+	var classes, methods []string
+	entered, left := 0, 0
+	v := &tree_sitter_zscript.Visitor{
+		Enter: func(*tree_sitter.Node) tree_sitter_zscript.WalkAction {
+			entered++
+			return tree_sitter_zscript.WalkContinue
+		},
+		Leave: func(*tree_sitter.Node) { left++ },
+	}
+	v.OnClassDefinition(func(n *tree_sitter.Node) tree_sitter_zscript.WalkAction {
+		classes = append(classes, n.ChildByFieldName(tree_sitter_zscript.FieldName).Utf8Text(source))
+		return tree_sitter_zscript.WalkContinue
+	}).OnMethodDefinition(func(n *tree_sitter.Node) tree_sitter_zscript.WalkAction {
+		methods = append(methods, n.ChildByFieldName(tree_sitter_zscript.FieldName).Utf8Text(source))
+		return tree_sitter_zscript.WalkSkipChildren
+	}).OnCallExpression(func(*tree_sitter.Node) tree_sitter_zscript.WalkAction {
+		t.Error("Visited a call inside a skipped method")
+		return tree_sitter_zscript.WalkContinue
+	})
+
+	if !tree_sitter_zscript.Walk(tree.RootNode(), v) {
+		t.Error("Walk() = false, want true")
+	}
+	if !reflect.DeepEqual(classes, []string{"A", "B"}) || !reflect.DeepEqual(methods, []string{"F", "H"}) {
+		t.Errorf("classes = %v, methods = %v", classes, methods)
+	}
+	if entered != left {
+		t.Errorf("Entered %d nodes but left %d", entered, left)
+	}
+}
+
+func TestWalkStop(t *testing.T) {
+	tree, err := tree_sitter_zscript.Parse(context.Background(), []byte(walkSource))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tree.Close()
+
+	count := 0
+	v := (&tree_sitter_zscript.Visitor{}).OnClassDefinition(func(*tree_sitter.Node) tree_sitter_zscript.WalkAction {
+		count++
+		return tree_sitter_zscript.WalkStop
+	})
+	if tree_sitter_zscript.Walk(tree.RootNode(), v) {
+		t.Error("Walk() = true, want false")
+	}
+	if count != 1 {
+		t.Errorf("Visited %d classes after stopping", count)
+	}
+}
@@ -0,0 +1,153 @@
+// Package watch reports the ZScript files under a set of paths that are
+// added, changed or removed, so that a tool can check them again as they
+// are edited without rescanning the rest.
+//
+// The watcher polls: each scan compares the size and modification time
+// of every file with those of the scan before. That needs nothing outside
+// the standard library and behaves the same on every platform and file
+// system, including the network and container mounts where notification
+// APIs miss events.
+package watch
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DefaultInterval is the time between scans of a Watcher whose Interval
+// is zero.
+const DefaultInterval = 250 * time.Millisecond
+
+// Change is a file that was added, modified or removed.
+type Change struct {
+	Path    string
+	Removed bool
+}
+
+// Watcher scans a set of files and directories for changes.
+type Watcher struct {
+	// Interval is the time Run waits between scans.
+	Interval time.Duration
+	// Match reports whether a file found in a watched directory is
+	// watched. If it is nil, IsSource is used. Paths given to New are
+	// always watched.
+	Match func(path string) bool
+
+	paths []string
+	files map[string]stamp
+}
+
+type stamp struct {
+	size    int64
+	modTime time.Time
+}
+
+// New returns a watcher of paths, which may be files or directories.
+// Directories are searched recursively, skipping those whose names
+// begin with ".".
+func New(paths ...string) *Watcher {
+	return &Watcher{paths: paths}
+}
+
+// IsSource reports whether path names a ZScript source file: one with a
+// .zs, .zsc or .zc extension, or a lump named zscript other than the
+// configuration file zscript.toml.
+func IsSource(path string) bool {
+	name := strings.ToLower(filepath.Base(path))
+	switch filepath.Ext(name) {
+	case ".zs", ".zsc", ".zc":
+		return true
+	}
+	return name == "zscript" || strings.HasPrefix(name, "zscript.") && name != "zscript.toml"
+}
+
+// Scan returns the files that changed since the previous scan, sorted by
+// path. The first scan returns every file.
+func (w *Watcher) Scan() ([]Change, error) {
+	match := w.Match
+	if match == nil {
+		match = IsSource
+	}
+	files := map[string]stamp{}
+	for _, root := range w.paths {
+		err := filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+			if err != nil {
+				// A file removed during the scan, or a watched path that
+				// does not exist yet, has no files.
+				if os.IsNotExist(err) {
+					return nil
+				}
+				return err
+			}
+			if entry.IsDir() {
+				if path != root && strings.HasPrefix(entry.Name(), ".") {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if path != root && !match(path) {
+				return nil
+			}
+			info, err := entry.Info()
+			if err != nil {
+				if os.IsNotExist(err) {
+					return nil
+				}
+				return err
+			}
+			files[path] = stamp{info.Size(), info.ModTime()}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var changes []Change
+	for path, s := range files {
+		if old, ok := w.files[path]; !ok || old.size != s.size || !old.modTime.Equal(s.modTime) {
+			changes = append(changes, Change{Path: path})
+		}
+	}
+	for path := range w.files {
+		if _, ok := files[path]; !ok {
+			changes = append(changes, Change{Path: path, Removed: true})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Path < changes[j].Path })
+	w.files = files
+	return changes, nil
+}
+
+// Run scans until ctx is done, calling fn with the changes of each scan
+// that finds any, starting with every file. It returns ctx.Err() if ctx
+// ends it, and the first error of a scan or of fn otherwise.
+func (w *Watcher) Run(ctx context.Context, fn func([]Change) error) error {
+	interval := w.Interval
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		changes, err := w.Scan()
+		if err != nil {
+			return err
+		}
+		if len(changes) > 0 {
+			if err := fn(changes); err != nil {
+				return err
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
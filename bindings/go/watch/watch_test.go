@@ -0,0 +1,105 @@
+package watch_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/watch"
+)
+
+func write(t *testing.T, path, text string, mod time.Time) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(text), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	// Set the time explicitly, since writes within the file system's
+	// timestamp resolution would otherwise look unchanged.
+	if err := os.Chtimes(path, mod, mod); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func scan(t *testing.T, w *watch.Watcher, dir string) []string {
+	t.Helper()
+	changes, err := w.Scan()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, c := range changes {
+		rel, _ := filepath.Rel(dir, c.Path)
+		got = append(got, fmt.Sprintf("%s %v", filepath.ToSlash(rel), c.Removed))
+	}
+	return got
+}
+
+func equal(t *testing.T, got []string, want ...string) {
+	t.Helper()
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("changes = %q, want %q", got, want)
+	}
+}
+
+func TestScan(t *testing.T) {
+	dir := t.TempDir()
+	start := time.Now().Add(-time.Hour)
+	write(t, filepath.Join(dir, "zscript.zs"), "#include \"actors/imp.zs\"", start)
+	write(t, filepath.Join(dir, "actors", "imp.zs"), "class Imp {}", start)
+	write(t, filepath.Join(dir, "readme.txt"), "not ZScript", start)
+	write(t, filepath.Join(dir, ".git", "x.zs"), "hidden", start)
+
+	w := watch.New(dir)
+	equal(t, scan(t, w, dir), "actors/imp.zs false", "zscript.zs false")
+	equal(t, scan(t, w, dir))
+
+	write(t, filepath.Join(dir, "actors", "imp.zs"), "class Imp : Actor {}", start.Add(time.Minute))
+	write(t, filepath.Join(dir, "actors", "demon.zsc"), "class Demon {}", start)
+	if err := os.Remove(filepath.Join(dir, "zscript.zs")); err != nil {
+		t.Fatal(err)
+	}
+	equal(t, scan(t, w, dir), "actors/demon.zsc false", "actors/imp.zs false", "zscript.zs true")
+
+	// A file named directly is watched whatever its name.
+	notes := filepath.Join(dir, "readme.txt")
+	w = watch.New(notes, filepath.Join(dir, "missing"))
+	equal(t, scan(t, w, dir), "readme.txt false")
+}
+
+func TestRun(t *testing.T) {
+	dir := t.TempDir()
+	write(t, filepath.Join(dir, "a.zs"), "class A {}", time.Now().Add(-time.Hour))
+	w := watch.New(dir)
+	w.Interval = time.Millisecond
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var batches [][]watch.Change
+	err := w.Run(ctx, func(changes []watch.Change) error {
+		batches = append(batches, changes)
+		if len(batches) == 1 {
+			write(t, filepath.Join(dir, "a.zs"), "class A : Actor {}", time.Now())
+		} else {
+			cancel()
+		}
+		return nil
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Run() = %v", err)
+	}
+	if len(batches) != 2 || len(batches[1]) != 1 || filepath.Base(batches[1][0].Path) != "a.zs" {
+		t.Errorf("batches = %v", batches)
+	}
+
+	stop := errors.New("stop")
+	if err := watch.New(dir).Run(context.Background(), func([]watch.Change) error { return stop }); err != stop {
+		t.Errorf("Run() = %v, want the error of fn", err)
+	}
+}
@@ -0,0 +1,68 @@
+package zscriptast
+
+import (
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+)
+
+// arenaBlock is the number of values in each block of an Arena.
+const arenaBlock = 1024
+
+// Arena reduces allocation when walking many trees. The nodes derived from
+// one it wraps, through Field, NamedChildren and the accessors built on
+// them, share its blocks rather than being allocated one list at a time,
+// and the children of each node are only listed once, however many times
+// they are asked for.
+//
+// Reset makes the blocks available again; the nodes handed out before it
+// must not be used afterwards, and those handed out between two Resets
+// must belong to one tree. An Arena must not be used by several
+// goroutines at once.
+type Arena struct {
+	nodes    []Node
+	children map[uintptr][]Node
+}
+
+// NewArena returns an empty arena.
+func NewArena() *Arena {
+	return &Arena{children: map[uintptr][]Node{}}
+}
+
+// Wrap returns a Node for raw whose descendants are allocated from the
+// arena, or the zero Node if raw is nil.
+func (a *Arena) Wrap(raw *tree_sitter.Node, source []byte) Node {
+	if raw == nil {
+		return Node{}
+	}
+	return Node{Raw: raw, Source: source, arena: a}
+}
+
+// Reset reclaims the blocks of the arena for the next tree.
+func (a *Arena) Reset() {
+	a.nodes = a.nodes[:0]
+	clear(a.children)
+}
+
+// namedChildren lists the named children of n other than comments. The
+// result has no spare capacity, so appending to it copies it out of the
+// arena.
+func (a *Arena) namedChildren(n Node) []Node {
+	id := n.Raw.Id()
+	if children, ok := a.children[id]; ok {
+		return children
+	}
+	count := int(n.Raw.NamedChildCount())
+	if cap(a.nodes)-len(a.nodes) < count {
+		a.nodes = make([]Node, 0, max(arenaBlock, count))
+	}
+	start := len(a.nodes)
+	for i := 0; i < count; i++ {
+		child := n.Raw.NamedChild(uint(i))
+		if child.IsExtra() {
+			continue
+		}
+		a.nodes = append(a.nodes, Node{Raw: child, Source: n.Source, arena: a})
+	}
+	children := a.nodes[start:len(a.nodes):len(a.nodes)]
+	a.children[id] = children
+	return children
+}
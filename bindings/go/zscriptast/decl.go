@@ -0,0 +1,546 @@
+package zscriptast
+
+import (
+	"strings"
+
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+
+	zscript "github.com/jlcrochet/tree-sitter-zscript/bindings/go"
+)
+
+// File wraps a source_file node.
+type File struct{ Node }
+
+// NewFile wraps the root node of tree.
+func NewFile(tree *tree_sitter.Tree, source []byte) File {
+	return File{Wrap(tree.RootNode(), source)}
+}
+
+// Classes returns the top-level class definitions, including extend and
+// mixin classes.
+func (f File) Classes() []ClassDecl {
+	return wrapAll(f.ChildrenOfKind(zscript.NodeClassDefinition), func(n Node) ClassDecl { return ClassDecl{n} })
+}
+
+// Structs returns the top-level struct definitions.
+func (f File) Structs() []StructDecl {
+	return wrapAll(f.ChildrenOfKind(zscript.NodeStructDefinition), func(n Node) StructDecl { return StructDecl{n} })
+}
+
+// Enums returns the top-level enum definitions.
+func (f File) Enums() []EnumDecl {
+	return wrapAll(f.ChildrenOfKind(zscript.NodeEnumDefinition), func(n Node) EnumDecl { return EnumDecl{n} })
+}
+
+// Consts returns the top-level constant definitions.
+func (f File) Consts() []ConstDecl {
+	return wrapAll(f.ChildrenOfKind(zscript.NodeConstDefinition), func(n Node) ConstDecl { return ConstDecl{n} })
+}
+
+// Includes returns the #include directives.
+func (f File) Includes() []IncludeDirective {
+	return wrapAll(f.ChildrenOfKind(zscript.NodeIncludeDirective), func(n Node) IncludeDirective { return IncludeDirective{n} })
+}
+
+// Version returns the version directive, if the file has one.
+func (f File) Version() (VersionDirective, bool) {
+	n := f.ChildOfKind(zscript.NodeVersionDirective)
+	return VersionDirective{n}, !n.IsZero()
+}
+
+// IncludeDirective wraps an include_directive node.
+type IncludeDirective struct{ Node }
+
+// Path returns the included path with the quotes removed.
+func (d IncludeDirective) Path() string {
+	return StringValue(d.Field(zscript.FieldPath))
+}
+
+// VersionDirective wraps a version_directive node.
+type VersionDirective struct{ Node }
+
+// Version returns the declared version with the quotes removed.
+func (d VersionDirective) Version() string {
+	return StringValue(d.Field(zscript.FieldVersion))
+}
+
+// ClassDecl wraps a class_definition node.
+type ClassDecl struct{ Node }
+
+// Name returns the class name.
+func (c ClassDecl) Name() string {
+	return c.FieldText(zscript.FieldName)
+}
+
+// Parent returns the name of the parent class, or "" if none is given.
+func (c ClassDecl) Parent() string {
+	return c.ChildOfKind(zscript.NodeInheritanceSpecifier).FieldText(zscript.FieldParent)
+}
+
+// Replaces returns the name of the class this one replaces, or "".
+func (c ClassDecl) Replaces() string {
+	for _, flag := range c.ChildOfKind(zscript.NodeClassFlags).NamedChildren() {
+		if flag.firstToken() == "replaces" {
+			return flag.ChildOfKind(zscript.NodeTypeIdentifier).Text()
+		}
+	}
+	return ""
+}
+
+// IsExtend reports whether this is an "extend class" definition.
+func (c ClassDecl) IsExtend() bool {
+	return strings.EqualFold(c.ChildOfKind(zscript.NodeClassModifier).Text(), "extend")
+}
+
+// IsMixin reports whether this is a "mixin class" definition.
+func (c ClassDecl) IsMixin() bool {
+	return strings.EqualFold(c.ChildOfKind(zscript.NodeClassModifier).Text(), "mixin")
+}
+
+// Flags returns the lowercased leading keyword of each class flag, such as
+// "abstract", "play", or "replaces".
+func (c ClassDecl) Flags() []string {
+	var flags []string
+	for _, flag := range c.ChildOfKind(zscript.NodeClassFlags).NamedChildren() {
+		flags = append(flags, flag.firstToken())
+	}
+	return flags
+}
+
+// Version returns the version in the class's version("x") qualifier, or
+// "".
+func (c ClassDecl) Version() string {
+	return flagVersion(c.ChildOfKind(zscript.NodeClassFlags))
+}
+
+// HasFlag reports whether the class has the given flag.
+func (c ClassDecl) HasFlag(flag string) bool {
+	return containsFold(c.Flags(), flag)
+}
+
+// Fields returns the field declarations in the class body.
+func (c ClassDecl) Fields() []FieldDecl {
+	return fieldsOf(c.Node)
+}
+
+// Methods returns the method definitions in the class body.
+func (c ClassDecl) Methods() []MethodDecl {
+	return methodsOf(c.Node)
+}
+
+// Consts returns the constant definitions in the class body.
+func (c ClassDecl) Consts() []ConstDecl {
+	return wrapAll(c.ChildrenOfKind(zscript.NodeConstDefinition), func(n Node) ConstDecl { return ConstDecl{n} })
+}
+
+// Enums returns the enum definitions in the class body.
+func (c ClassDecl) Enums() []EnumDecl {
+	return wrapAll(c.ChildrenOfKind(zscript.NodeEnumDefinition), func(n Node) EnumDecl { return EnumDecl{n} })
+}
+
+// Defaults returns the Default blocks in the class body.
+func (c ClassDecl) Defaults() []DefaultBlock {
+	return wrapAll(c.ChildrenOfKind(zscript.NodeDefaultBlock), func(n Node) DefaultBlock { return DefaultBlock{n} })
+}
+
+// States returns the States blocks in the class body.
+func (c ClassDecl) States() []StatesBlock {
+	return wrapAll(c.ChildrenOfKind(zscript.NodeStatesBlock), func(n Node) StatesBlock { return StatesBlock{n} })
+}
+
+// Properties returns the property definitions in the class body.
+func (c ClassDecl) Properties() []PropertyDecl {
+	return wrapAll(c.ChildrenOfKind(zscript.NodePropertyDefinition), func(n Node) PropertyDecl { return PropertyDecl{n} })
+}
+
+// FlagDefs returns the flagdef definitions in the class body.
+func (c ClassDecl) FlagDefs() []FlagDecl {
+	return wrapAll(c.ChildrenOfKind(zscript.NodeFlagDefinition), func(n Node) FlagDecl { return FlagDecl{n} })
+}
+
+// Mixins returns the names of the mixins included by the class body.
+func (c ClassDecl) Mixins() []string {
+	var names []string
+	for _, mixin := range c.ChildrenOfKind(zscript.NodeMixinStatement) {
+		names = append(names, mixin.ChildOfKind(zscript.NodeTypeIdentifier).Text())
+	}
+	return names
+}
+
+// StructDecl wraps a struct_definition node.
+type StructDecl struct{ Node }
+
+// Name returns the struct name.
+func (s StructDecl) Name() string {
+	return s.FieldText(zscript.FieldName)
+}
+
+// IsExtend reports whether this is an "extend struct" definition.
+func (s StructDecl) IsExtend() bool {
+	return s.hasToken("extend")
+}
+
+// Version returns the version in the struct's version("x") qualifier, or
+// "".
+func (s StructDecl) Version() string {
+	return flagVersion(s.ChildOfKind(zscript.NodeStructFlags))
+}
+
+// Fields returns the field declarations in the struct body.
+func (s StructDecl) Fields() []FieldDecl {
+	return fieldsOf(s.Node)
+}
+
+// Methods returns the method definitions in the struct body.
+func (s StructDecl) Methods() []MethodDecl {
+	return methodsOf(s.Node)
+}
+
+// Consts returns the constant definitions in the struct body.
+func (s StructDecl) Consts() []ConstDecl {
+	return wrapAll(s.ChildrenOfKind(zscript.NodeConstDefinition), func(n Node) ConstDecl { return ConstDecl{n} })
+}
+
+// Enums returns the enum definitions in the struct body.
+func (s StructDecl) Enums() []EnumDecl {
+	return wrapAll(s.ChildrenOfKind(zscript.NodeEnumDefinition), func(n Node) EnumDecl { return EnumDecl{n} })
+}
+
+// flagVersion returns the version qualifier among class or struct flags.
+func flagVersion(flags Node) string {
+	for _, flag := range flags.NamedChildren() {
+		if strings.EqualFold(flag.firstToken(), "version") {
+			return StringValue(flag.ChildOfKind(zscript.NodeStringLiteral))
+		}
+	}
+	return ""
+}
+
+// EnumDecl wraps an enum_definition node.
+type EnumDecl struct{ Node }
+
+// Name returns the enum name.
+func (e EnumDecl) Name() string {
+	return e.FieldText(zscript.FieldName)
+}
+
+// BaseType returns the text of the underlying type, or "" if none is given.
+func (e EnumDecl) BaseType() string {
+	children := e.ChildOfKind(zscript.NodeEnumBaseType).NamedChildren()
+	if len(children) == 0 {
+		return ""
+	}
+	return children[0].Text()
+}
+
+// Members returns the enumerators in declaration order.
+func (e EnumDecl) Members() []Enumerator {
+	list := e.ChildOfKind(zscript.NodeEnumeratorList)
+	return wrapAll(list.ChildrenOfKind(zscript.NodeEnumerator), func(n Node) Enumerator { return Enumerator{n} })
+}
+
+// Enumerator wraps an enumerator node.
+type Enumerator struct{ Node }
+
+// Name returns the enumerator name.
+func (e Enumerator) Name() string {
+	return e.FieldText(zscript.FieldName)
+}
+
+// Value returns the explicit value expression, or the zero Node.
+func (e Enumerator) Value() Node {
+	return e.Field(zscript.FieldValue)
+}
+
+// ConstDecl wraps a const_definition node.
+type ConstDecl struct{ Node }
+
+// Name returns the constant name.
+func (c ConstDecl) Name() string {
+	return c.FieldText(zscript.FieldName)
+}
+
+// Value returns the value expression.
+func (c ConstDecl) Value() Node {
+	return c.Field(zscript.FieldValue)
+}
+
+// FieldDecl wraps a field_declaration node, which may declare several
+// variables of the same type.
+type FieldDecl struct{ Node }
+
+// Type returns the text of the declared type.
+func (f FieldDecl) Type() string {
+	return f.FieldText(zscript.FieldType)
+}
+
+// Modifiers returns the lowercased member modifiers.
+func (f FieldDecl) Modifiers() []string {
+	return keywords(f.ChildOfKind(zscript.NodeMemberModifiers))
+}
+
+// HasModifier reports whether the field has the given modifier.
+func (f FieldDecl) HasModifier(modifier string) bool {
+	return containsFold(f.Modifiers(), modifier)
+}
+
+// Declarators returns the declarator nodes.
+func (f FieldDecl) Declarators() []Node {
+	return childrenByField(f.Node, zscript.FieldDeclarator)
+}
+
+// Names returns the declared variable names.
+func (f FieldDecl) Names() []string {
+	var names []string
+	for _, d := range f.Declarators() {
+		names = append(names, DeclaratorName(d).Text())
+	}
+	return names
+}
+
+// MethodDecl wraps a method_definition node.
+type MethodDecl struct{ Node }
+
+// Name returns the method name.
+func (m MethodDecl) Name() string {
+	return m.FieldText(zscript.FieldName)
+}
+
+// ReturnType returns the text of the return type, or "" for methods
+// declared without one.
+func (m MethodDecl) ReturnType() string {
+	return m.FieldText(zscript.FieldType)
+}
+
+// Modifiers returns the lowercased member modifiers.
+func (m MethodDecl) Modifiers() []string {
+	return keywords(m.ChildOfKind(zscript.NodeMemberModifiers))
+}
+
+// HasModifier reports whether the method has the given modifier.
+func (m MethodDecl) HasModifier(modifier string) bool {
+	return containsFold(m.Modifiers(), modifier)
+}
+
+// IsConst reports whether the method is declared const.
+func (m MethodDecl) IsConst() bool {
+	return !m.ChildOfKind(zscript.NodeConstQualifier).IsZero()
+}
+
+// Parameters returns the declared parameters. A trailing "..." is reported
+// as a parameter whose IsVariadic method returns true.
+func (m MethodDecl) Parameters() []Parameter {
+	list := m.Field(zscript.FieldParameters)
+	return wrapAll(list.ChildrenOfKind(zscript.NodeParameterDeclaration), func(n Node) Parameter { return Parameter{n} })
+}
+
+// Body returns the method body, or the zero Node for declarations.
+func (m MethodDecl) Body() Node {
+	return m.Field(zscript.FieldBody)
+}
+
+// Parameter wraps a parameter_declaration node.
+type Parameter struct{ Node }
+
+// Name returns the parameter name, or "" if it is unnamed.
+func (p Parameter) Name() string {
+	return DeclaratorName(p.Field(zscript.FieldDeclarator)).Text()
+}
+
+// Type returns the text of the parameter type.
+func (p Parameter) Type() string {
+	return p.FieldText(zscript.FieldType)
+}
+
+// Default returns the default value expression, or the zero Node.
+func (p Parameter) Default() Node {
+	if n := p.Field(zscript.FieldDefault); !n.IsZero() {
+		return n
+	}
+	// "int x = 1" usually parses as an init_declarator.
+	if d := p.Field(zscript.FieldDeclarator); d.Kind() == zscript.NodeInitDeclarator {
+		return d.Field(zscript.FieldValue)
+	}
+	return Node{}
+}
+
+// Modifiers returns the lowercased in/out modifiers.
+func (p Parameter) Modifiers() []string {
+	mods := keywords(p.ChildOfKind(zscript.NodeParameterModifiers))
+	// "out int x" usually parses with out as a type_qualifier.
+	for _, q := range p.ChildrenOfKind(zscript.NodeTypeQualifier) {
+		if word := strings.ToLower(q.Text()); word == "in" || word == "out" {
+			mods = append(mods, word)
+		}
+	}
+	return mods
+}
+
+// IsVariadic reports whether this is a "..." parameter.
+func (p Parameter) IsVariadic() bool {
+	return p.hasToken("...")
+}
+
+// PropertyDecl wraps a property_definition node.
+type PropertyDecl struct{ Node }
+
+// Name returns the property name.
+func (p PropertyDecl) Name() string {
+	return p.FieldText(zscript.FieldName)
+}
+
+// Fields returns the names of the fields the property sets.
+func (p PropertyDecl) Fields() []string {
+	var names []string
+	for _, field := range childrenByField(p.Node, zscript.FieldField) {
+		names = append(names, field.Text())
+	}
+	return names
+}
+
+// FlagDecl wraps a flag_definition node.
+type FlagDecl struct{ Node }
+
+// Name returns the flag name.
+func (f FlagDecl) Name() string {
+	return f.FieldText(zscript.FieldName)
+}
+
+// FieldName returns the name of the backing field.
+func (f FlagDecl) FieldName() string {
+	return f.FieldText(zscript.FieldField)
+}
+
+// Bit returns the text of the bit number.
+func (f FlagDecl) Bit() string {
+	return f.FieldText(zscript.FieldBit)
+}
+
+// DefaultBlock wraps a default_block node.
+type DefaultBlock struct{ Node }
+
+// Properties returns the property assignments in the block.
+func (d DefaultBlock) Properties() []PropertyAssignment {
+	var props []PropertyAssignment
+	for _, item := range d.ChildrenOfKind(zscript.NodeDefaultProperty) {
+		if n := item.ChildOfKind(zscript.NodePropertyAssignment); !n.IsZero() {
+			props = append(props, PropertyAssignment{n})
+		}
+	}
+	return props
+}
+
+// Flags returns the flag statements in the block.
+func (d DefaultBlock) Flags() []FlagStatement {
+	var flags []FlagStatement
+	for _, item := range d.ChildrenOfKind(zscript.NodeDefaultProperty) {
+		if n := item.ChildOfKind(zscript.NodeFlagStatement); !n.IsZero() {
+			flags = append(flags, FlagStatement{n})
+		}
+	}
+	return flags
+}
+
+// PropertyAssignment wraps a property_assignment node.
+type PropertyAssignment struct{ Node }
+
+// Name returns the property name, including any "Class." prefix.
+func (p PropertyAssignment) Name() string {
+	return p.FieldText(zscript.FieldProperty)
+}
+
+// Values returns the value expressions.
+func (p PropertyAssignment) Values() []Node {
+	return childrenByField(p.Node, zscript.FieldValue)
+}
+
+// FlagStatement wraps a flag_statement node such as "+NOGRAVITY;".
+type FlagStatement struct{ Node }
+
+// Name returns the flag name, including any "Class." prefix.
+func (f FlagStatement) Name() string {
+	return f.FieldText(zscript.FieldFlag)
+}
+
+// Set reports whether the flag is set ("+") rather than cleared ("-").
+func (f FlagStatement) Set() bool {
+	return f.FieldText(zscript.FieldSign) == "+"
+}
+
+// StatesBlock wraps a states_block node.
+type StatesBlock struct{ Node }
+
+// Options returns the identifiers in the optional parenthesized scope list,
+// such as "actor" in "States(Actor)".
+func (s StatesBlock) Options() []string {
+	var options []string
+	for _, id := range s.ChildOfKind(zscript.NodeStatesOptions).NamedChildren() {
+		options = append(options, id.Text())
+	}
+	return options
+}
+
+// Labels returns the state labels in the block.
+func (s StatesBlock) Labels() []StateLabel {
+	return wrapAll(s.ChildrenOfKind(zscript.NodeStateLabel), func(n Node) StateLabel { return StateLabel{n} })
+}
+
+// StateLabel wraps a state_label node.
+type StateLabel struct{ Node }
+
+// Name returns the label name, e.g. "Spawn" or "Death.Fire".
+func (l StateLabel) Name() string {
+	return l.FieldText(zscript.FieldName)
+}
+
+// Body returns the state_body following the label, or the zero Node if the
+// label falls through to the next one.
+func (l StateLabel) Body() Node {
+	return l.Field(zscript.FieldBody)
+}
+
+// StringValue returns the contents of a string_literal node with the quotes
+// removed. Escape sequences are left as written.
+func StringValue(n Node) string {
+	text := n.Text()
+	if len(text) >= 2 && text[0] == '"' && text[len(text)-1] == '"' {
+		return text[1 : len(text)-1]
+	}
+	return text
+}
+
+func fieldsOf(n Node) []FieldDecl {
+	return wrapAll(n.ChildrenOfKind(zscript.NodeFieldDeclaration), func(n Node) FieldDecl { return FieldDecl{n} })
+}
+
+func methodsOf(n Node) []MethodDecl {
+	return wrapAll(n.ChildrenOfKind(zscript.NodeMethodDefinition), func(n Node) MethodDecl { return MethodDecl{n} })
+}
+
+func childrenByField(n Node, field string) []Node {
+	if n.Raw == nil {
+		return nil
+	}
+	cursor := n.Raw.Walk()
+	defer cursor.Close()
+	var children []Node
+	for _, child := range n.Raw.ChildrenByFieldName(field, cursor) {
+		// Fields wrapping a comma-separated list also tag the commas.
+		if !child.IsNamed() {
+			continue
+		}
+		children = append(children, n.wrap(&child))
+	}
+	return children
+}
+
+func wrapAll[T any](nodes []Node, wrap func(Node) T) []T {
+	if len(nodes) == 0 {
+		return nil
+	}
+	wrapped := make([]T, len(nodes))
+	for i, n := range nodes {
+		wrapped[i] = wrap(n)
+	}
+	return wrapped
+}
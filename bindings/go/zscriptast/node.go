@@ -0,0 +1,219 @@
+// Package zscriptast provides typed wrappers over the concrete syntax tree
+// produced by the ZScript grammar.
+//
+// The wrappers are thin: each one holds the underlying tree-sitter node and
+// the source it was parsed from, and exposes accessors for the fields of the
+// corresponding grammar rule. They do not own the tree, so the tree must
+// outlive any wrapper created from it.
+package zscriptast
+
+import (
+	"strings"
+	"sync"
+
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+
+	zscript "github.com/jlcrochet/tree-sitter-zscript/bindings/go"
+)
+
+// Node is the common base of all wrappers.
+type Node struct {
+	// Raw is the wrapped tree-sitter node.
+	Raw *tree_sitter.Node
+	// Source is the text the tree was parsed from.
+	Source []byte
+
+	arena *Arena
+}
+
+// Wrap returns a Node for raw, or the zero Node if raw is nil.
+func Wrap(raw *tree_sitter.Node, source []byte) Node {
+	if raw == nil {
+		return Node{}
+	}
+	return Node{Raw: raw, Source: source}
+}
+
+// wrap returns a Node for raw, a descendant of n, in the arena of n if it
+// has one.
+func (n Node) wrap(raw *tree_sitter.Node) Node {
+	if raw == nil {
+		return Node{}
+	}
+	return Node{Raw: raw, Source: n.Source, arena: n.arena}
+}
+
+// IsZero reports whether n does not wrap a node.
+func (n Node) IsZero() bool {
+	return n.Raw == nil
+}
+
+// Kind returns the node kind, or "" for the zero Node.
+func (n Node) Kind() string {
+	if n.Raw == nil {
+		return ""
+	}
+	return kindOf(n.Raw)
+}
+
+// kindNames holds the name of every node kind, indexed by kind id.
+// tree_sitter.Node.Kind allocates a new string on each call, which adds up
+// when wrappers filter children by kind.
+var kindNames = sync.OnceValue(func() []string {
+	language := zscript.GetLanguage()
+	names := make([]string, language.NodeKindCount())
+	for id := range names {
+		names[id] = language.NodeKindForId(uint16(id))
+	}
+	return names
+})
+
+// kindOf returns raw.Kind() without allocating.
+func kindOf(raw *tree_sitter.Node) string {
+	names := kindNames()
+	if id := int(raw.KindId()); id < len(names) {
+		return names[id]
+	}
+	return raw.Kind()
+}
+
+// Text returns the source text spanned by the node.
+func (n Node) Text() string {
+	if n.Raw == nil {
+		return ""
+	}
+	return n.Raw.Utf8Text(n.Source)
+}
+
+// Range returns the node's source range.
+func (n Node) Range() tree_sitter.Range {
+	if n.Raw == nil {
+		return tree_sitter.Range{}
+	}
+	return n.Raw.Range()
+}
+
+// Field returns the child stored in the given field.
+func (n Node) Field(name string) Node {
+	if n.Raw == nil {
+		return Node{}
+	}
+	return n.wrap(n.Raw.ChildByFieldName(name))
+}
+
+// FieldText returns the text of the child stored in the given field.
+func (n Node) FieldText(name string) string {
+	return n.Field(name).Text()
+}
+
+// NamedChildren returns the node's named children, excluding comments.
+func (n Node) NamedChildren() []Node {
+	if n.Raw == nil {
+		return nil
+	}
+	if n.arena != nil {
+		return n.arena.namedChildren(n)
+	}
+	count := n.Raw.NamedChildCount()
+	children := make([]Node, 0, count)
+	for i := uint(0); i < count; i++ {
+		child := n.Raw.NamedChild(i)
+		if child.IsExtra() {
+			continue
+		}
+		children = append(children, Wrap(child, n.Source))
+	}
+	return children
+}
+
+// ChildrenOfKind returns the named children with the given kind.
+func (n Node) ChildrenOfKind(kind string) []Node {
+	var children []Node
+	for _, child := range n.NamedChildren() {
+		if kindOf(child.Raw) == kind {
+			children = append(children, child)
+		}
+	}
+	return children
+}
+
+// ChildOfKind returns the first named child with the given kind.
+func (n Node) ChildOfKind(kind string) Node {
+	for _, child := range n.NamedChildren() {
+		if kindOf(child.Raw) == kind {
+			return child
+		}
+	}
+	return Node{}
+}
+
+// hasToken reports whether any direct child, named or anonymous, has the
+// given kind. Keywords are anonymous nodes named after their lowercase
+// spelling.
+func (n Node) hasToken(kind string) bool {
+	if n.Raw == nil {
+		return false
+	}
+	count := n.Raw.ChildCount()
+	for i := uint(0); i < count; i++ {
+		if kindOf(n.Raw.Child(i)) == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// firstToken returns the kind of the node's first child.
+func (n Node) firstToken() string {
+	if n.Raw == nil || n.Raw.ChildCount() == 0 {
+		return ""
+	}
+	return kindOf(n.Raw.Child(0))
+}
+
+// keywords returns the lowercased text of each named child of n, which is
+// how modifier lists are represented.
+func keywords(n Node) []string {
+	var words []string
+	for _, child := range n.NamedChildren() {
+		words = append(words, strings.ToLower(child.Text()))
+	}
+	return words
+}
+
+func containsFold(list []string, word string) bool {
+	for _, w := range list {
+		if strings.EqualFold(w, word) {
+			return true
+		}
+	}
+	return false
+}
+
+// DeclaratorName returns the identifier declared by a declarator node,
+// looking through array, init, pointer, and parenthesized declarators.
+func DeclaratorName(n Node) Node {
+	for !n.IsZero() {
+		switch n.Kind() {
+		case zscript.NodeIdentifier:
+			return n
+		case zscript.NodeParenthesizedDeclarator:
+			children := n.NamedChildren()
+			if len(children) == 0 {
+				return Node{}
+			}
+			n = children[0]
+		default:
+			n = n.Field(zscript.FieldDeclarator)
+		}
+	}
+	return Node{}
+}
+
+// String returns the node's S-expression, for debugging.
+func (n Node) String() string {
+	if n.Raw == nil {
+		return "()"
+	}
+	return n.Raw.ToSexp()
+}
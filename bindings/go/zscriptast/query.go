@@ -0,0 +1,36 @@
+package zscriptast
+
+import (
+	"iter"
+
+	zscript "github.com/jlcrochet/tree-sitter-zscript/bindings/go"
+)
+
+// Captures yields the name and node of every capture of q in n, in source
+// order.
+func Captures(q *zscript.CachedQuery, n Node) iter.Seq2[string, Node] {
+	return func(yield func(string, Node) bool) {
+		for c := range q.Captures(n.Raw, n.Source) {
+			if !yield(c.Name, n.wrap(&c.Node)) {
+				return
+			}
+		}
+	}
+}
+
+// CapturesOf yields the nodes captured under name as the wrapper type T.
+// The pattern should capture nodes of the kind T wraps, as in
+//
+//	q := zscript.MustQuery(`(class_definition) @class`)
+//	for c := range zscriptast.CapturesOf[zscriptast.ClassDecl](q, file.Node, "class") {
+//		fmt.Println(c.Name())
+//	}
+func CapturesOf[T ~struct{ Node }](q *zscript.CachedQuery, n Node, name string) iter.Seq[T] {
+	return func(yield func(T) bool) {
+		for captured, node := range Captures(q, n) {
+			if captured == name && !yield(T{node}) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,188 @@
+package zscriptast_test
+
+import (
+	"reflect"
+	"testing"
+
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+
+	zscript "github.com/jlcrochet/tree-sitter-zscript/bindings/go"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/zscriptast"
+)
+
+const source = `version "4.10"
+#include "zscript/weapons.zs"
+
+enum EColor : uint8 { Red, Green = 2, }
+
+class MyPlasmaRifle : Weapon replaces PlasmaRifle abstract {
+	int ammo, spare[2];
+	meta string Tag;
+
+	property Ammo: ammo;
+	flagdef Charged: ammo, 3;
+
+	Default {
+		Weapon.SlotNumber 6;
+		Health 100, 200;
+		+NOGRAVITY;
+		-SOLID;
+	}
+
+	override void Tick() { Super.Tick(); }
+	action void A_Fire(int count, double spread = 1.0) {}
+
+	States {
+	Spawn:
+		PLAS A -1;
+		Stop;
+	Fire:
+		PLAS B 3 A_Fire(1);
+		Loop;
+	}
+}
+
+extend struct Vec { int x; }
+`
+
+func parse(t *testing.T) zscriptast.File {
+	t.Helper()
+	parser := tree_sitter.NewParser()
+	t.Cleanup(parser.Close)
+	if err := parser.SetLanguage(tree_sitter.NewLanguage(zscript.Language())); err != nil {
+		t.Fatal(err)
+	}
+	tree := parser.Parse([]byte(source), nil)
+	t.Cleanup(tree.Close)
+	if tree.RootNode().HasError() {
+		t.Fatalf("parse error: %s", tree.RootNode().ToSexp())
+	}
+	return zscriptast.NewFile(tree, []byte(source))
+}
+
+func TestFile(t *testing.T) {
+	file := parse(t)
+
+	if v, ok := file.Version(); !ok || v.Version() != "4.10" {
+		t.Errorf("Version() = %q, %v", v.Version(), ok)
+	}
+	if includes := file.Includes(); len(includes) != 1 || includes[0].Path() != "zscript/weapons.zs" {
+		t.Errorf("Includes() = %v", includes)
+	}
+
+	enums := file.Enums()
+	if len(enums) != 1 {
+		t.Fatalf("len(Enums()) = %d", len(enums))
+	}
+	if got := enums[0].BaseType(); got != "uint8" {
+		t.Errorf("BaseType() = %q", got)
+	}
+	members := enums[0].Members()
+	if len(members) != 2 || members[1].Name() != "Green" || members[1].Value().Text() != "2" {
+		t.Errorf("Members() = %v", members)
+	}
+
+	structs := file.Structs()
+	if len(structs) != 1 || !structs[0].IsExtend() || structs[0].Name() != "Vec" {
+		t.Errorf("Structs() = %v", structs)
+	}
+}
+
+func TestClass(t *testing.T) {
+	classes := parse(t).Classes()
+	if len(classes) != 1 {
+		t.Fatalf("len(Classes()) = %d", len(classes))
+	}
+	class := classes[0]
+
+	if class.Name() != "MyPlasmaRifle" || class.Parent() != "Weapon" || class.Replaces() != "PlasmaRifle" {
+		t.Errorf("Name/Parent/Replaces = %q/%q/%q", class.Name(), class.Parent(), class.Replaces())
+	}
+	if !class.HasFlag("abstract") || class.IsExtend() {
+		t.Errorf("Flags() = %v", class.Flags())
+	}
+
+	fields := class.Fields()
+	if len(fields) != 2 {
+		t.Fatalf("len(Fields()) = %d", len(fields))
+	}
+	if got := fields[0].Names(); !reflect.DeepEqual(got, []string{"ammo", "spare"}) {
+		t.Errorf("Names() = %v", got)
+	}
+	if !fields[1].HasModifier("meta") || fields[1].Type() != "string" {
+		t.Errorf("Modifiers() = %v, Type() = %q", fields[1].Modifiers(), fields[1].Type())
+	}
+
+	methods := class.Methods()
+	if len(methods) != 2 {
+		t.Fatalf("len(Methods()) = %d", len(methods))
+	}
+	if !methods[0].HasModifier("override") || methods[0].ReturnType() != "void" || methods[0].Body().IsZero() {
+		t.Errorf("Tick: modifiers %v, type %q", methods[0].Modifiers(), methods[0].ReturnType())
+	}
+	params := methods[1].Parameters()
+	if len(params) != 2 || params[1].Name() != "spread" || params[1].Type() != "double" || params[1].Default().Text() != "1.0" {
+		t.Errorf("Parameters() = %v", params)
+	}
+
+	if props := class.Properties(); len(props) != 1 || props[0].Name() != "Ammo" || !reflect.DeepEqual(props[0].Fields(), []string{"ammo"}) {
+		t.Errorf("Properties() = %v", props)
+	}
+	if flags := class.FlagDefs(); len(flags) != 1 || flags[0].FieldName() != "ammo" || flags[0].Bit() != "3" {
+		t.Errorf("FlagDefs() = %v", flags)
+	}
+
+	defaults := class.Defaults()
+	if len(defaults) != 1 {
+		t.Fatalf("len(Defaults()) = %d", len(defaults))
+	}
+	props := defaults[0].Properties()
+	if len(props) != 2 || props[0].Name() != "Weapon.SlotNumber" || len(props[1].Values()) != 2 {
+		t.Errorf("Properties() = %v", props)
+	}
+	flags := defaults[0].Flags()
+	if len(flags) != 2 || flags[0].Name() != "NOGRAVITY" || !flags[0].Set() || flags[1].Set() {
+		t.Errorf("Flags() = %v", flags)
+	}
+
+	states := class.States()
+	if len(states) != 1 {
+		t.Fatalf("len(States()) = %d", len(states))
+	}
+	labels := states[0].Labels()
+	if len(labels) != 2 || labels[0].Name() != "Spawn" || labels[1].Name() != "Fire" || labels[1].Body().IsZero() {
+		t.Errorf("Labels() = %v", labels)
+	}
+}
+
+func TestCapturesOf(t *testing.T) {
+	file := parse(t)
+	q := zscript.MustQuery(`(method_definition) @method`)
+	var names []string
+	for m := range zscriptast.CapturesOf[zscriptast.MethodDecl](q, file.Node, "method") {
+		names = append(names, m.Name())
+	}
+	if want := []string{"Tick", "A_Fire"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("methods = %q, want %q", names, want)
+	}
+}
+
+func TestArena(t *testing.T) {
+	file := parse(t)
+	a := zscriptast.NewArena()
+	for range 2 {
+		class := zscriptast.ClassDecl{Node: a.Wrap(file.Raw, file.Source).ChildOfKind(zscript.NodeClassDefinition)}
+		if class.Name() != "MyPlasmaRifle" || len(class.Fields()) != 2 || len(class.Methods()) != 2 {
+			t.Errorf("class from the arena: %q, %d fields, %d methods", class.Name(), len(class.Fields()), len(class.Methods()))
+		}
+		children := class.NamedChildren()
+		if again := class.NamedChildren(); &again[0] != &children[0] {
+			t.Error("NamedChildren listed the children again")
+		}
+		// Appending must copy the list rather than write over the arena.
+		if cap(children) != len(children) {
+			t.Errorf("NamedChildren has %d spare slots", cap(children)-len(children))
+		}
+		a.Reset()
+	}
+}
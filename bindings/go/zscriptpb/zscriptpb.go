@@ -0,0 +1,284 @@
+// Package zscriptpb encodes parse results as Protocol Buffers, so that
+// services in other languages can read syntax trees, symbol tables and
+// syntax errors without linking the parser. The schema is Schema, the
+// zscript.proto file of this package; generate code for it with protoc
+// to decode the output.
+//
+// The encoding is deterministic: fields are written in the order of
+// their numbers, repeated fields in source order, and fields with zero
+// values are left out, as proto3 specifies. Only named nodes are
+// written, as Dump writes them.
+package zscriptpb
+
+import (
+	_ "embed"
+	"encoding/binary"
+	"io"
+
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+
+	zscript "github.com/jlcrochet/tree-sitter-zscript/bindings/go"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/symbols"
+)
+
+// Schema is the text of zscript.proto.
+//
+//go:embed zscript.proto
+var Schema string
+
+// File returns the encoded File message of tree: its tree, its symbol
+// table and its syntax errors.
+func File(tree *zscript.Tree) []byte {
+	var e encoder
+	e.string(1, tree.Path)
+	e.message(2, func(e *encoder) { e.node(tree.RootNode(), "", tree.Source) })
+	e.message(3, func(e *encoder) { e.table(symbols.Extract(tree)) })
+	for _, d := range zscript.Diagnostics(tree.Tree, tree.Source) {
+		e.message(4, func(e *encoder) { e.diagnostic(d) })
+	}
+	return e.buf
+}
+
+// Tree returns the encoded Node message of the root of tree.
+func Tree(tree *zscript.Tree) []byte {
+	var e encoder
+	e.node(tree.RootNode(), "", tree.Source)
+	return e.buf
+}
+
+// Table returns the encoded Table message of t.
+func Table(t *symbols.Table) []byte {
+	var e encoder
+	e.table(t)
+	return e.buf
+}
+
+// WriteDelimited writes msg to w preceded by its length as a varint, the
+// framing protobuf libraries read streams of messages with.
+func WriteDelimited(w io.Writer, msg []byte) error {
+	_, err := w.Write(append(binary.AppendUvarint(nil, uint64(len(msg))), msg...))
+	return err
+}
+
+// Wire types.
+const (
+	varint = 0
+	bytes  = 2
+)
+
+// encoder appends fields to buf in the protobuf wire format.
+type encoder struct {
+	buf []byte
+}
+
+func (e *encoder) tag(field, wire int) {
+	e.buf = binary.AppendUvarint(e.buf, uint64(field<<3|wire))
+}
+
+func (e *encoder) uint(field int, v uint64) {
+	if v != 0 {
+		e.tag(field, varint)
+		e.buf = binary.AppendUvarint(e.buf, v)
+	}
+}
+
+func (e *encoder) bool(field int, v bool) {
+	if v {
+		e.uint(field, 1)
+	}
+}
+
+func (e *encoder) string(field int, s string) {
+	if s != "" {
+		e.tag(field, bytes)
+		e.buf = binary.AppendUvarint(e.buf, uint64(len(s)))
+		e.buf = append(e.buf, s...)
+	}
+}
+
+func (e *encoder) strings(field int, ss []string) {
+	for _, s := range ss {
+		e.tag(field, bytes)
+		e.buf = binary.AppendUvarint(e.buf, uint64(len(s)))
+		e.buf = append(e.buf, s...)
+	}
+}
+
+// message writes the message that fn encodes. Unlike the other fields, it
+// is written even when empty, since the presence of a message is seen.
+func (e *encoder) message(field int, fn func(e *encoder)) {
+	var sub encoder
+	fn(&sub)
+	e.tag(field, bytes)
+	e.buf = binary.AppendUvarint(e.buf, uint64(len(sub.buf)))
+	e.buf = append(e.buf, sub.buf...)
+}
+
+func (e *encoder) point(field int, p tree_sitter.Point) {
+	e.message(field, func(e *encoder) {
+		e.uint(1, uint64(p.Row))
+		e.uint(2, uint64(p.Column))
+	})
+}
+
+func (e *encoder) rangeOf(field int, r tree_sitter.Range) {
+	e.message(field, func(e *encoder) {
+		e.uint(1, uint64(r.StartByte))
+		e.uint(2, uint64(r.EndByte))
+		e.point(3, r.StartPoint)
+		e.point(4, r.EndPoint)
+	})
+}
+
+func (e *encoder) node(n *tree_sitter.Node, field string, source []byte) {
+	e.string(1, n.Kind())
+	e.string(2, field)
+	e.rangeOf(3, n.Range())
+	if n.NamedChildCount() == 0 {
+		e.string(4, n.Utf8Text(source))
+	}
+	e.bool(5, n.IsError())
+	e.bool(6, n.IsMissing())
+	for i := uint(0); i < n.ChildCount(); i++ {
+		if child := n.Child(i); child.IsNamed() {
+			e.message(7, func(e *encoder) { e.node(child, n.FieldNameForChild(uint32(i)), source) })
+		}
+	}
+}
+
+func (e *encoder) diagnostic(d zscript.Diagnostic) {
+	e.rangeOf(1, d.Range)
+	e.uint(2, uint64(d.Severity))
+	e.string(3, d.Message)
+	e.string(4, d.Text)
+	e.strings(5, d.Expected)
+}
+
+func (e *encoder) table(t *symbols.Table) {
+	e.string(1, t.Path)
+	e.string(2, t.Version)
+	e.bool(3, t.HasErrors)
+	for _, inc := range t.Includes {
+		e.message(4, func(e *encoder) {
+			e.string(1, inc.Path)
+			e.rangeOf(2, inc.Range)
+		})
+	}
+	for _, c := range t.Classes {
+		e.message(5, func(e *encoder) { e.class(c) })
+	}
+	for _, s := range t.Structs {
+		e.message(6, func(e *encoder) { e.structure(s) })
+	}
+	for _, en := range t.Enums {
+		e.message(7, func(e *encoder) { e.enum(en) })
+	}
+	for _, c := range t.Consts {
+		e.message(8, func(e *encoder) { e.constant(c) })
+	}
+}
+
+func (e *encoder) symbol(s symbols.Symbol) {
+	e.message(1, func(e *encoder) {
+		e.string(1, s.Name)
+		e.rangeOf(2, s.Range)
+		e.rangeOf(3, s.NameRange)
+		e.bool(4, s.HasErrors)
+	})
+}
+
+func (e *encoder) class(c *symbols.Class) {
+	e.symbol(c.Symbol)
+	e.string(2, c.Parent)
+	e.string(3, c.Replaces)
+	e.bool(4, c.Extend)
+	e.bool(5, c.Mixin)
+	e.strings(6, c.Flags)
+	e.string(7, c.Version)
+	e.strings(8, c.Mixins)
+	for _, f := range c.Fields {
+		e.message(9, func(e *encoder) { e.field(f) })
+	}
+	for _, m := range c.Methods {
+		e.message(10, func(e *encoder) { e.method(m) })
+	}
+	for _, k := range c.Consts {
+		e.message(11, func(e *encoder) { e.constant(k) })
+	}
+	for _, en := range c.Enums {
+		e.message(12, func(e *encoder) { e.enum(en) })
+	}
+	for _, p := range c.Properties {
+		e.message(13, func(e *encoder) {
+			e.symbol(p.Symbol)
+			e.strings(2, p.Fields)
+		})
+	}
+	for _, f := range c.FlagDefs {
+		e.message(14, func(e *encoder) {
+			e.symbol(f.Symbol)
+			e.string(2, f.Field)
+			e.string(3, f.Bit)
+		})
+	}
+	for _, s := range c.States {
+		e.message(15, func(e *encoder) { e.symbol(s.Symbol) })
+	}
+}
+
+func (e *encoder) structure(s *symbols.Struct) {
+	e.symbol(s.Symbol)
+	e.bool(2, s.Extend)
+	e.string(3, s.Version)
+	for _, f := range s.Fields {
+		e.message(4, func(e *encoder) { e.field(f) })
+	}
+	for _, m := range s.Methods {
+		e.message(5, func(e *encoder) { e.method(m) })
+	}
+	for _, k := range s.Consts {
+		e.message(6, func(e *encoder) { e.constant(k) })
+	}
+	for _, en := range s.Enums {
+		e.message(7, func(e *encoder) { e.enum(en) })
+	}
+}
+
+func (e *encoder) enum(en *symbols.Enum) {
+	e.symbol(en.Symbol)
+	e.string(2, en.BaseType)
+	for _, m := range en.Members {
+		e.message(3, func(e *encoder) {
+			e.symbol(m.Symbol)
+			e.string(2, m.Value)
+		})
+	}
+}
+
+func (e *encoder) constant(c *symbols.Const) {
+	e.symbol(c.Symbol)
+	e.string(2, c.Value)
+}
+
+func (e *encoder) field(f *symbols.Field) {
+	e.symbol(f.Symbol)
+	e.string(2, f.Type)
+	e.strings(3, f.Modifiers)
+}
+
+func (e *encoder) method(m *symbols.Method) {
+	e.symbol(m.Symbol)
+	e.string(2, m.ReturnType)
+	e.strings(3, m.Modifiers)
+	for _, p := range m.Params {
+		e.message(4, func(e *encoder) {
+			e.string(1, p.Name)
+			e.string(2, p.Type)
+			e.strings(3, p.Modifiers)
+			e.string(4, p.Default)
+			e.bool(5, p.Variadic)
+		})
+	}
+	e.bool(5, m.Const)
+	e.bool(6, m.HasBody)
+}
@@ -0,0 +1,165 @@
+package zscriptpb_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"strings"
+	"testing"
+
+	zscript "github.com/jlcrochet/tree-sitter-zscript/bindings/go"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/symbols"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/zscriptpb"
+)
+
+// message is a decoded message: the values of each field number, as
+// uint64 for varints and []byte for length-delimited fields.
+type message map[uint64][]any
+
+func decode(t *testing.T, data []byte) message {
+	t.Helper()
+	m := message{}
+	for len(data) > 0 {
+		tag, n := binary.Uvarint(data)
+		if n <= 0 {
+			t.Fatalf("bad tag in %x", data)
+		}
+		data = data[n:]
+		switch tag & 7 {
+		case 0:
+			v, n := binary.Uvarint(data)
+			if n <= 0 {
+				t.Fatalf("bad varint in %x", data)
+			}
+			m[tag>>3] = append(m[tag>>3], v)
+			data = data[n:]
+		case 2:
+			size, n := binary.Uvarint(data)
+			if n <= 0 || uint64(len(data)-n) < size {
+				t.Fatalf("bad length in %x", data)
+			}
+			m[tag>>3] = append(m[tag>>3], data[n:n+int(size)])
+			data = data[n+int(size):]
+		default:
+			t.Fatalf("unexpected wire type %d", tag&7)
+		}
+	}
+	return m
+}
+
+func (m message) string(field uint64) string {
+	if len(m[field]) == 0 {
+		return ""
+	}
+	return string(m[field][0].([]byte))
+}
+
+func (m message) uint(field uint64) uint64 {
+	if len(m[field]) == 0 {
+		return 0
+	}
+	return m[field][0].(uint64)
+}
+
+func (m message) messages(t *testing.T, field uint64) []message {
+	var ms []message
+	for _, v := range m[field] {
+		ms = append(ms, decode(t, v.([]byte)))
+	}
+	return ms
+}
+
+func parse(t *testing.T, source string) *zscript.Tree {
+	t.Helper()
+	tree, err := zscript.Parse(context.Background(), []byte(source))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(tree.Close)
+	tree.Path = "a.zs"
+	return tree
+}
+
+func TestFile(t *testing.T) {
+	tree := parse(t, "class Foo : Actor { int x; void Bar(int a = 1) {} }\nclass Broken {")
+	file := decode(t, zscriptpb.File(tree))
+	if got := file.string(1); got != "a.zs" {
+		t.Errorf("path = %q", got)
+	}
+
+	root := file.messages(t, 2)[0]
+	if got := root.string(1); got != "source_file" {
+		t.Errorf("root kind = %q", got)
+	}
+	class := root.messages(t, 7)[0]
+	name := class.messages(t, 7)[0]
+	if name.string(1) != "type_identifier" || name.string(2) != "name" || name.string(4) != "Foo" {
+		t.Errorf("name = %q %q %q", name.string(1), name.string(2), name.string(4))
+	}
+	r := name.messages(t, 3)[0]
+	if r.uint(1) != 6 || r.uint(2) != 9 || r.messages(t, 3)[0].uint(2) != 6 {
+		t.Errorf("name range = %v", r)
+	}
+
+	table := file.messages(t, 3)[0]
+	if table.uint(3) != 1 {
+		t.Error("has_errors not set")
+	}
+	classes := table.messages(t, 5)
+	if len(classes) != 2 || classes[0].messages(t, 1)[0].string(1) != "Foo" || classes[0].string(2) != "Actor" {
+		t.Fatalf("classes = %v", classes)
+	}
+	method := classes[0].messages(t, 10)[0]
+	param := method.messages(t, 4)[0]
+	if method.uint(6) != 1 || param.string(1) != "a" || param.string(2) != "int" || param.string(4) != "1" {
+		t.Errorf("method = %v, param = %v", method, param)
+	}
+
+	diagnostics := file.messages(t, 4)
+	if len(diagnostics) == 0 || diagnostics[0].uint(2) != uint64(zscript.SeverityError) || diagnostics[0].string(3) == "" {
+		t.Errorf("diagnostics = %v", diagnostics)
+	}
+}
+
+func TestDeterministic(t *testing.T) {
+	source := "class Foo { enum E { A, B = 2 } const C = 3; States { Spawn: TNT1 A -1; Stop; } }"
+	a, b := zscriptpb.File(parse(t, source)), zscriptpb.File(parse(t, source))
+	if !bytes.Equal(a, b) {
+		t.Error("encodings differ")
+	}
+	table := decode(t, zscriptpb.Table(symbols.Extract(parse(t, source))))
+	class := table.messages(t, 5)[0]
+	members := class.messages(t, 12)[0].messages(t, 3)
+	if len(members) != 2 || members[1].string(2) != "2" {
+		t.Errorf("members = %v", members)
+	}
+	if states := class.messages(t, 15); len(states) != 1 || states[0].messages(t, 1)[0].string(1) != "Spawn" {
+		t.Errorf("states = %v", states)
+	}
+}
+
+func TestTree(t *testing.T) {
+	root := decode(t, zscriptpb.Tree(parse(t, "// c\nconst A = 1;")))
+	children := root.messages(t, 7)
+	if len(children) != 2 || children[0].string(1) != "comment" || children[1].string(1) != "const_definition" {
+		t.Errorf("children = %v", children)
+	}
+}
+
+func TestWriteDelimited(t *testing.T) {
+	var buf bytes.Buffer
+	if err := zscriptpb.WriteDelimited(&buf, []byte("abc")); err != nil {
+		t.Fatal(err)
+	}
+	if got := buf.String(); got != "\x03abc" {
+		t.Errorf("got %q", got)
+	}
+}
+
+func TestSchema(t *testing.T) {
+	for _, want := range []string{"message File", "message Node", "message Table", "repeated Node children = 7;"} {
+		if !strings.Contains(zscriptpb.Schema, want) {
+			t.Errorf("schema lacks %q", want)
+		}
+	}
+}
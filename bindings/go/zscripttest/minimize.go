@@ -0,0 +1,165 @@
+package zscripttest
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	zscript "github.com/jlcrochet/tree-sitter-zscript/bindings/go"
+)
+
+// Minimize returns the smallest input it can find, by delta debugging,
+// for which fails still reports true: it removes ever smaller runs of the
+// lines of input while the failure persists, and then of the tokens left,
+// words, runs of white space and single punctuation characters. The result
+// is 1-minimal in tokens: removing any one of them makes the failure go.
+// fails must report true for input itself, or input is returned as it is.
+func Minimize(input []byte, fails func([]byte) bool) []byte {
+	if !fails(input) {
+		return input
+	}
+	units := ddmin(lines(input), fails)
+	return bytes.Join(ddmin(tokens(bytes.Join(units, nil)), fails), nil)
+}
+
+// ddmin reduces units to a smaller sequence that still fails, following
+// Zeller's algorithm: it tries each of n chunks alone and then the rest
+// without each, doubling n when neither fails, until n passes the number
+// of units.
+func ddmin(units [][]byte, fails func([]byte) bool) [][]byte {
+	try := func(kept [][]byte) bool {
+		return fails(bytes.Join(kept, nil))
+	}
+	for n := 2; len(units) >= 2; {
+		chunks := split(units, n)
+		reduced := false
+		for i, chunk := range chunks {
+			if len(chunks) > 2 && try(chunk) {
+				units, n, reduced = chunk, 2, true
+				break
+			}
+			rest := make([][]byte, 0, len(units)-len(chunk))
+			for j, c := range chunks {
+				if j != i {
+					rest = append(rest, c...)
+				}
+			}
+			if try(rest) {
+				units, n, reduced = rest, max(n-1, 2), true
+				break
+			}
+		}
+		if !reduced {
+			if n >= len(units) {
+				break
+			}
+			n = min(2*n, len(units))
+		}
+	}
+	return units
+}
+
+// split divides units into n runs of nearly equal length.
+func split(units [][]byte, n int) [][][]byte {
+	n = min(n, len(units))
+	chunks := make([][][]byte, 0, n)
+	for i, start := 0, 0; i < n; i++ {
+		end := start + (len(units)-start)/(n-i)
+		chunks = append(chunks, units[start:end])
+		start = end
+	}
+	return chunks
+}
+
+// lines splits text into lines, each keeping its newline.
+func lines(text []byte) [][]byte {
+	var units [][]byte
+	for len(text) > 0 {
+		i := bytes.IndexByte(text, '\n') + 1
+		if i == 0 {
+			i = len(text)
+		}
+		units = append(units, text[:i])
+		text = text[i:]
+	}
+	return units
+}
+
+// tokens splits text into words, runs of white space, and the other
+// characters one by one.
+func tokens(text []byte) [][]byte {
+	var units [][]byte
+	for len(text) > 0 {
+		n := 1
+		switch class := charClass(text[0]); class {
+		case word, space:
+			for n < len(text) && charClass(text[n]) == class {
+				n++
+			}
+		}
+		units = append(units, text[:n])
+		text = text[n:]
+	}
+	return units
+}
+
+const (
+	other = iota
+	word
+	space
+)
+
+func charClass(c byte) int {
+	switch {
+	case c == '_' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= 0x80:
+		return word
+	case c == ' ' || c == '\t' || c == '\r' || c == '\n':
+		return space
+	}
+	return other
+}
+
+// SameSyntaxError returns a predicate for Minimize that reports whether a
+// source has a syntax error with the message of the first syntax error of
+// source, such as `unexpected "}"`, or any syntax error if source has
+// none. Requiring the same message keeps the minimized input failing the
+// way source does rather than in some simpler way.
+func SameSyntaxError(source []byte) func([]byte) bool {
+	want, _ := firstSyntaxError(source)
+	return func(candidate []byte) bool {
+		got, ok := firstSyntaxError(candidate)
+		return ok && (want == "" || got == want)
+	}
+}
+
+// firstSyntaxError returns the message of the first syntax error of
+// source. ok is false if it has none.
+func firstSyntaxError(source []byte) (message string, ok bool) {
+	tree, err := zscript.Parse(context.Background(), source)
+	if err != nil {
+		return "", false
+	}
+	defer tree.Close()
+	if !tree.RootNode().HasError() {
+		return "", false
+	}
+	for _, d := range zscript.Diagnostics(tree.Tree, source) {
+		if d.Severity == zscript.SeverityError {
+			return d.Message, true
+		}
+	}
+	return "", true
+}
+
+// CheckParses fails the test if source has a syntax error, reporting the
+// first error with the smallest input Minimize finds that has the same
+// error, to triage the bug from.
+func CheckParses(t testing.TB, source []byte) {
+	t.Helper()
+	message, ok := firstSyntaxError(source)
+	if !ok {
+		return
+	}
+	reproducer := Minimize(source, SameSyntaxError(source))
+	t.Errorf("syntax error: %s; smallest input with the error:\n%s", message, reproducer)
+}
@@ -0,0 +1,130 @@
+// Package zscripttest provides snapshot testing for tools built on the
+// ZScript bindings: it renders parse trees, or any other output, in a
+// canonical form and compares it with golden files checked in next to the
+// test's inputs.
+//
+// Importing the package registers the -zscripttest.update flag with the
+// test binary. Running
+//
+//	go test ./... -zscripttest.update
+//
+// writes the golden files from the current output instead of comparing
+// against them, so a deliberate change is reviewed as a diff of the golden
+// files. The flag is named after the package so that it does not clash
+// with an -update flag of the tests that import it; such tests can set
+// Update from their own flag instead.
+package zscripttest
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+
+	zscript "github.com/jlcrochet/tree-sitter-zscript/bindings/go"
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/diff"
+)
+
+// Update makes Golden write golden files instead of comparing against
+// them. The -zscripttest.update flag sets it.
+var Update bool
+
+func init() {
+	flag.BoolVar(&Update, "zscripttest.update", false, "write golden files instead of comparing against them")
+}
+
+// Updating reports whether golden files are being written, as Update
+// says.
+func Updating() bool {
+	return Update
+}
+
+// Parse parses source, failing the test if it cannot be parsed, and closes
+// the tree when the test ends.
+func Parse(t testing.TB, source []byte) *zscript.Tree {
+	t.Helper()
+	tree, err := zscript.Parse(context.Background(), source)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(tree.Close)
+	return tree
+}
+
+// Tree returns the canonical rendering of tree: the S-expression of
+// zscript.DumpSexp, one named node per line with its field name, kind,
+// range and, for leaves, text. Error and missing nodes are included.
+func Tree(tree *zscript.Tree) []byte {
+	return zscript.Dump(tree.Tree, tree.Source, zscript.DumpSexp)
+}
+
+// Golden compares got with the contents of the golden file at path,
+// failing the test with a unified diff if they differ. With Update set it
+// writes got to path instead, creating its directory.
+func Golden(t testing.TB, path string, got []byte) {
+	t.Helper()
+	if Update {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("%s does not exist; run the test with -zscripttest.update to create it", path)
+		return
+	}
+	if err != nil {
+		t.Fatal(err)
+		return
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("output differs from %s; run the test with -zscripttest.update to accept it:\n%s", path, diff.Unified(path, "got", want, got))
+	}
+}
+
+// GoldenTree parses source and compares its canonical rendering with the
+// golden file at path.
+func GoldenTree(t testing.TB, path string, source []byte) {
+	t.Helper()
+	Golden(t, path, Tree(Parse(t, source)))
+}
+
+// Run runs a subtest for each file matching the glob pattern, named after
+// the file's base name. Each subtest passes the file's contents to render
+// and compares the result with the golden file of the same path with
+// ".golden" appended. It fails the test if nothing matches pattern.
+func Run(t *testing.T, pattern string, render func(t *testing.T, path string, source []byte) []byte) {
+	t.Helper()
+	paths, err := filepath.Glob(pattern)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(paths) == 0 {
+		t.Fatalf("no files match %s", pattern)
+	}
+	for _, path := range paths {
+		t.Run(filepath.Base(path), func(t *testing.T) {
+			source, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			Golden(t, path+".golden", render(t, path, source))
+		})
+	}
+}
+
+// RunTrees is Run with the canonical rendering of each file's parse tree.
+func RunTrees(t *testing.T, pattern string) {
+	t.Helper()
+	Run(t, pattern, func(t *testing.T, _ string, source []byte) []byte {
+		return Tree(Parse(t, source))
+	})
+}
@@ -0,0 +1,92 @@
+package zscripttest_test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jlcrochet/tree-sitter-zscript/bindings/go/zscripttest"
+)
+
+func TestRunTrees(t *testing.T) {
+	zscripttest.RunTrees(t, "testdata/*.zs")
+}
+
+// recorder records the failures of a test instead of reporting them.
+type recorder struct {
+	*testing.T
+	failures []string
+}
+
+func (r *recorder) Helper() {}
+
+func (r *recorder) Errorf(format string, args ...any) {
+	r.failures = append(r.failures, fmt.Sprintf(format, args...))
+}
+
+func (r *recorder) Fatalf(format string, args ...any) {
+	r.Errorf(format, args...)
+}
+
+func TestGolden(t *testing.T) {
+	if zscripttest.Updating() {
+		t.Skip("compares against files written by the test")
+	}
+	path := filepath.Join(t.TempDir(), "tree.golden")
+	r := &recorder{T: t}
+	zscripttest.GoldenTree(r, path, []byte("const X = 1;"))
+	if len(r.failures) != 1 || !strings.Contains(r.failures[0], "run the test with -zscripttest.update to create it") {
+		t.Errorf("missing golden file: %q", r.failures)
+	}
+
+	tree := zscripttest.Tree(zscripttest.Parse(t, []byte("const X = 1;")))
+	if err := os.WriteFile(path, tree, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	r.failures = nil
+	zscripttest.GoldenTree(r, path, []byte("const X = 1;"))
+	zscripttest.GoldenTree(r, path, []byte("const X = 2;"))
+	if len(r.failures) != 1 || !strings.Contains(r.failures[0], `+    value: (number_literal [0:10-0:11] "2")`) {
+		t.Errorf("changed tree: %q", r.failures)
+	}
+}
+
+func TestMinimize(t *testing.T) {
+	// The input fails when it holds both "b" and "e".
+	fails := func(s []byte) bool { return strings.Contains(string(s), "b") && strings.Contains(string(s), "e") }
+	if got := string(zscripttest.Minimize([]byte("a\nb c\nd\ne f\n"), fails)); got != "be" {
+		t.Errorf("Minimize = %q, want %q", got, "be")
+	}
+	if got := string(zscripttest.Minimize([]byte("abc"), fails)); got != "abc" {
+		t.Errorf("Minimize of a passing input = %q", got)
+	}
+}
+
+func TestMinimizeSyntaxError(t *testing.T) {
+	source := []byte(`class Imp : Actor {
+	int health;
+	void Tick() {
+		health = 1;
+		A_Log("tick";
+	}
+	States {
+	Spawn:
+		TNT1 A 1;
+		Loop;
+	}
+}
+`)
+	got := string(zscripttest.Minimize(source, zscripttest.SameSyntaxError(source)))
+	if len(got) >= 30 || !strings.Contains(got, "A_Log(") {
+		t.Errorf("reproducer = %q", got)
+	}
+
+	r := &recorder{T: t}
+	zscripttest.CheckParses(r, source)
+	zscripttest.CheckParses(r, []byte("const X = 1;"))
+	if len(r.failures) != 1 || !strings.Contains(r.failures[0], got) {
+		t.Errorf("CheckParses: %q", r.failures)
+	}
+}
@@ -0,0 +1,11 @@
+// Package zscript exposes the grammar's query files so that the Go bindings
+// can embed them. Most users want the accessors in the bindings/go package
+// instead.
+package zscript
+
+import "embed"
+
+// Queries holds the .scm files from the queries directory.
+//
+//go:embed queries/*.scm
+var Queries embed.FS
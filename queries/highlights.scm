@@ -0,0 +1,259 @@
+; Keywords
+
+[
+  "class"
+  "struct"
+  "enum"
+  "const"
+  "extend"
+  "mixin"
+  "property"
+  "flagdef"
+  "default"
+  "states"
+  "version"
+  "#include"
+] @keyword
+
+[
+  "abstract"
+  "action"
+  "clearscope"
+  "deprecated"
+  "extern"
+  "final"
+  "in"
+  "internal"
+  "latent"
+  "meta"
+  "native"
+  "out"
+  "override"
+  "play"
+  "private"
+  "protected"
+  "readonly"
+  "replaces"
+  "static"
+  "transient"
+  "ui"
+  "vararg"
+  "virtual"
+] @keyword.modifier
+
+[
+  "if"
+  "else"
+  "switch"
+  "case"
+] @keyword.conditional
+
+[
+  "for"
+  "foreach"
+  "while"
+  "do"
+  "break"
+  "continue"
+] @keyword.repeat
+
+"return" @keyword.return
+
+[
+  "is"
+  "cross"
+  "dot"
+  "sizeof"
+  "alignof"
+] @keyword.operator
+
+; State flow control
+
+[
+  "loop"
+  "stop"
+  "wait"
+  "fail"
+  "goto"
+] @keyword.return
+
+[
+  "bright"
+  "fast"
+  "slow"
+  "nodelay"
+  "canraise"
+  "light"
+  "offset"
+] @attribute
+
+; Types
+
+(primitive_type) @type.builtin
+(sized_type_specifier) @type.builtin
+
+[
+  "array"
+  "map"
+  "mapiterator"
+] @type.builtin
+
+(class_type
+  "class" @type.builtin)
+
+(type_identifier) @type
+
+(class_definition
+  name: (type_identifier) @type.definition)
+
+(struct_definition
+  name: (type_identifier) @type.definition)
+
+(enum_definition
+  name: (type_identifier) @type.definition)
+
+; Functions
+
+(method_definition
+  name: (identifier) @function.method)
+
+(function_declarator
+  declarator: (identifier) @function)
+
+(call_expression
+  function: (identifier) @function.call)
+
+(call_expression
+  function: (field_expression
+    field: (field_identifier) @function.method.call))
+
+(state_action_call
+  function: (identifier) @function.call)
+
+(random_expression
+  function: _ @function.builtin)
+
+[
+  "getclass"
+  "resolvestate"
+] @function.builtin
+
+; Variables and members
+
+(super_expression) @variable.builtin
+(self_expression) @variable.builtin
+(invoker_expression) @variable.builtin
+
+(field_identifier) @property
+
+(property_definition
+  name: (identifier) @property)
+
+(property_identifier) @property
+
+(flag_definition
+  name: (identifier) @property)
+
+(flag_name) @constant
+
+(enumerator
+  name: (identifier) @constant)
+
+(const_definition
+  name: (identifier) @constant)
+
+(static_const_array
+  name: (identifier) @constant)
+
+(named_argument
+  name: (identifier) @variable.parameter)
+
+(parameter_declaration
+  declarator: (identifier) @variable.parameter)
+
+; States
+
+(state_label_name) @label
+
+(statement_identifier) @label
+
+(state_sprite_frames) @string.special
+
+; Literals
+
+(string_literal) @string
+(concatenated_string) @string
+(name_literal) @string.special.symbol
+(escape_sequence) @string.escape
+(number_literal) @number
+
+[
+  (true)
+  (false)
+] @boolean
+
+(null) @constant.builtin
+
+(comment) @comment
+
+; Punctuation
+
+[
+  "("
+  ")"
+  "["
+  "]"
+  "{"
+  "}"
+] @punctuation.bracket
+
+[
+  ","
+  ";"
+  ":"
+  "::"
+  "."
+] @punctuation.delimiter
+
+[
+  "="
+  "+="
+  "-="
+  "*="
+  "/="
+  "%="
+  "<<="
+  ">>="
+  ">>>="
+  "&="
+  "^="
+  "|="
+  "+"
+  "-"
+  "*"
+  "/"
+  "%"
+  "**"
+  "++"
+  "--"
+  "=="
+  "!="
+  "~=="
+  "<"
+  ">"
+  "<="
+  ">="
+  "<>="
+  "<<"
+  ">>"
+  ">>>"
+  "&&"
+  "||"
+  "!"
+  "&"
+  "|"
+  "^"
+  "~"
+  ".."
+  "?"
+] @operator
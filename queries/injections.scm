@@ -0,0 +1,41 @@
+((comment) @injection.content
+  (#set! injection.language "comment"))
+
+; Script names passed to ACS.
+((call_expression
+  function: (identifier) @_function
+  arguments: (argument_list . (string_literal) @injection.content))
+  (#any-of? @_function
+    "CallACS" "ACS_NamedExecute" "ACS_NamedExecuteAlways" "ACS_NamedExecuteWithResult"
+    "ACS_NamedSuspend" "ACS_NamedTerminate" "ACS_NamedLockedExecute" "ACS_NamedLockedExecuteDoor")
+  (#set! injection.language "acs"))
+
+((state_action_call
+  function: (identifier) @_function
+  arguments: (argument_list . (string_literal) @injection.content))
+  (#any-of? @_function
+    "CallACS" "ACS_NamedExecute" "ACS_NamedExecuteAlways" "ACS_NamedExecuteWithResult"
+    "ACS_NamedSuspend" "ACS_NamedTerminate" "ACS_NamedLockedExecute" "ACS_NamedLockedExecuteDoor")
+  (#set! injection.language "acs"))
+
+; Console commands.
+((call_expression
+  function: [
+    (identifier) @_function
+    (field_expression field: (field_identifier) @_function)
+  ]
+  arguments: (argument_list . (string_literal) @injection.content))
+  (#eq? @_function "ConsoleCommand")
+  (#set! injection.language "console"))
+
+; Format strings.
+((call_expression
+  function: (field_expression field: (field_identifier) @_function)
+  arguments: (argument_list . [(string_literal) (concatenated_string)] @injection.content))
+  (#any-of? @_function "Format" "Printf" "AppendFormat")
+  (#set! injection.language "printf"))
+
+; LANGUAGE lump references: strings starting with "$".
+((string_literal) @injection.content
+  (#match? @injection.content "^\"\\$")
+  (#set! injection.language "language"))
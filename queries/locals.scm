@@ -0,0 +1,54 @@
+; Scopes
+
+[
+  (class_definition)
+  (struct_definition)
+  (method_definition)
+  (function_definition)
+  (compound_statement)
+  (for_statement)
+  (foreach_statement)
+] @local.scope
+
+; Definitions
+
+(parameter_declaration
+  declarator: (identifier) @local.definition.parameter)
+
+(declaration
+  declarator: (identifier) @local.definition.var)
+
+(declaration
+  declarator: (init_declarator
+    declarator: (identifier) @local.definition.var))
+
+(declaration
+  declarator: (array_declarator
+    declarator: (identifier) @local.definition.var))
+
+(foreach_statement
+  variable: (identifier) @local.definition.var)
+
+(field_declaration
+  declarator: (identifier) @local.definition.field)
+
+(field_declaration
+  declarator: (init_declarator
+    declarator: (identifier) @local.definition.field))
+
+(field_declaration
+  declarator: (array_declarator
+    declarator: (identifier) @local.definition.field))
+
+(method_definition
+  name: (identifier) @local.definition.method)
+
+(const_definition
+  name: (identifier) @local.definition.constant)
+
+(enumerator
+  name: (identifier) @local.definition.constant)
+
+; References
+
+(identifier) @local.reference
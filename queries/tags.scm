@@ -0,0 +1,46 @@
+; Definitions
+
+(class_definition
+  name: (type_identifier) @name) @definition.class
+
+(struct_definition
+  name: (type_identifier) @name) @definition.class
+
+(enum_definition
+  name: (type_identifier) @name) @definition.enum
+
+(enumerator
+  name: (identifier) @name) @definition.constant
+
+(const_definition
+  name: (identifier) @name) @definition.constant
+
+(method_definition
+  name: (identifier) @name) @definition.method
+
+(field_declaration
+  declarator: [
+    (identifier) @name
+    (init_declarator
+      declarator: (identifier) @name)
+    (array_declarator
+      declarator: (identifier) @name)
+  ]) @definition.field
+
+(property_definition
+  name: (identifier) @name) @definition.property
+
+(flag_definition
+  name: (identifier) @name) @definition.constant
+
+; References
+
+(call_expression
+  function: (identifier) @name) @reference.call
+
+(call_expression
+  function: (field_expression
+    field: (field_identifier) @name)) @reference.call
+
+(inheritance_specifier
+  parent: (type_identifier) @name) @reference.class